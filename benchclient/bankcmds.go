@@ -71,6 +71,36 @@ func (cl *BenchClient) GetDepositAddress(asset string) (getDepositAddressReply *
 	return
 }
 
+// GetDepositAddresses calls the getdepositaddresses rpc command, getting count fresh deposit addresses
+func (cl *BenchClient) GetDepositAddresses(asset string, count uint64) (getDepositAddressesReply *cxrpc.GetDepositAddressesReply, err error) {
+
+	if cl.PrivKey == nil {
+		err = fmt.Errorf("Private key nonexistent, set or specify private key so the client can sign commands")
+		return
+	}
+
+	getDepositAddressesReply = new(cxrpc.GetDepositAddressesReply)
+	getDepositAddressesArgs := &cxrpc.GetDepositAddressesArgs{
+		Asset: asset,
+		Count: count,
+	}
+
+	// Sign asset and count
+	var compactSig []byte
+	if compactSig, err = koblitz.SignCompact(koblitz.S256(), cl.PrivKey, cxrpc.GetDepositAddressesSigHash(asset, count), false); err != nil {
+		return
+	}
+
+	// set signature in args
+	getDepositAddressesArgs.Signature = compactSig
+
+	if err = cl.Call("OpencxRPC.GetDepositAddresses", getDepositAddressesArgs, getDepositAddressesReply); err != nil {
+		return
+	}
+
+	return
+}
+
 // GetAllBalances get the balance for every token
 func (cl *BenchClient) GetAllBalances() (balances map[string]uint64, err error) {
 
@@ -119,9 +149,7 @@ func (cl *BenchClient) Withdraw(amount uint64, asset match.Asset, address string
 	}
 
 	// create e = hash(m)
-	sha3 := sha3.New256()
-	sha3.Write(withdrawArgs.Withdrawal.Serialize())
-	e := sha3.Sum(nil)
+	e := match.WithdrawalDomain.SigHash(withdrawArgs.Withdrawal.Serialize())
 
 	// Sign order
 	compactSig, err := koblitz.SignCompact(koblitz.S256(), cl.PrivKey, e, false)
@@ -159,9 +187,7 @@ func (cl *BenchClient) WithdrawLightning(amount uint64, asset match.Asset) (with
 	}
 
 	// create e = hash(m)
-	sha3 := sha3.New256()
-	sha3.Write(withdrawArgs.Withdrawal.Serialize())
-	e := sha3.Sum(nil)
+	e := match.WithdrawalDomain.SigHash(withdrawArgs.Withdrawal.Serialize())
 
 	// Sign order
 	compactSig, err := koblitz.SignCompact(koblitz.S256(), cl.PrivKey, e, false)
@@ -180,3 +206,15 @@ func (cl *BenchClient) WithdrawLightning(amount uint64, asset match.Asset) (with
 
 	return
 }
+
+// GetFeeAccount gets the settlement fee rate and the account fees are collected in
+func (cl *BenchClient) GetFeeAccount() (getFeeAccountReply *cxrpc.GetFeeAccountReply, err error) {
+	getFeeAccountReply = new(cxrpc.GetFeeAccountReply)
+	getFeeAccountArgs := new(cxrpc.GetFeeAccountArgs)
+
+	if err = cl.Call("OpencxRPC.GetFeeAccount", getFeeAccountArgs, getFeeAccountReply); err != nil {
+		return
+	}
+
+	return
+}
@@ -1,6 +1,8 @@
 package benchclient
 
 import (
+	"io"
+
 	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/cxrpc"
 )
@@ -11,11 +13,15 @@ type BenchClient struct {
 	port      uint16
 	RPCClient cxrpc.OpencxClient
 	PrivKey   *koblitz.PrivateKey
+	// Signer signs auction commands instead of PrivKey if it's set
+	Signer Signer
+	nonces *NonceSource
 }
 
 // SetupBenchClient creates a new BenchClient for use as an RPC Client
 func (cl *BenchClient) SetupBenchClient(server string, port uint16) (err error) {
 	cl.RPCClient = new(cxrpc.OpencxRPCClient)
+	cl.nonces = NewNonceSource(nil)
 	cl.hostname = server
 	cl.port = port
 
@@ -38,6 +44,7 @@ func (cl *BenchClient) SetupBenchNoiseClient(server string, port uint16) (err er
 
 	// Now that the key is set we can start doing stuff.
 	cl.RPCClient = noiseClient
+	cl.nonces = NewNonceSource(nil)
 	cl.hostname = server
 	cl.port = port
 
@@ -49,6 +56,13 @@ func (cl *BenchClient) SetupBenchNoiseClient(server string, port uint16) (err er
 	return
 }
 
+// SetEntropySource sets the entropy source used to generate auction order nonces. If entropy is nil then
+// crypto/rand is used. This also forgets about any nonces that have already been used.
+func (cl *BenchClient) SetEntropySource(entropy io.Reader) {
+	cl.nonces = NewNonceSource(entropy)
+	return
+}
+
 // Call calls a method from the rpc client
 func (cl *BenchClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
 	return cl.RPCClient.Call(serviceMethod, args, reply)
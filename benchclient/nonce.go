@@ -0,0 +1,79 @@
+package benchclient
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// maxTrackedAuctions is how many auctions we remember used nonces for, the oldest auction is forgotten
+	// once we start using nonces for a new one.
+	maxTrackedAuctions = 8
+	// nonceSpace is the number of distinct 2 byte nonces
+	nonceSpace = 1 << 16
+	// maxNonceAttempts is how many times we read from the entropy source before giving up, so a broken
+	// entropy source can't make us loop forever.
+	maxNonceAttempts = 16 * nonceSpace
+)
+
+// NonceSource generates auction order nonces from an entropy source, and makes sure that the same
+// nonce is never handed out twice for the same auction.
+type NonceSource struct {
+	entropy  io.Reader
+	used     map[[32]byte]map[[2]byte]bool
+	auctions [][32]byte
+	mtx      *sync.Mutex
+}
+
+// NewNonceSource creates a new nonce source that reads from entropy. If entropy is nil then
+// crypto/rand is used.
+func NewNonceSource(entropy io.Reader) (ns *NonceSource) {
+	if entropy == nil {
+		entropy = rand.Reader
+	}
+	ns = &NonceSource{
+		entropy: entropy,
+		used:    make(map[[32]byte]map[[2]byte]bool),
+		mtx:     new(sync.Mutex),
+	}
+	return
+}
+
+// NextNonce returns a nonce that has not yet been used for the auction with auctionID
+func (ns *NonceSource) NextNonce(auctionID [32]byte) (nonce [2]byte, err error) {
+	ns.mtx.Lock()
+	defer ns.mtx.Unlock()
+
+	usedNonces, found := ns.used[auctionID]
+	if !found {
+		// forget about the oldest auction if we're tracking too many
+		if len(ns.auctions) >= maxTrackedAuctions {
+			delete(ns.used, ns.auctions[0])
+			ns.auctions = ns.auctions[1:]
+		}
+		usedNonces = make(map[[2]byte]bool)
+		ns.used[auctionID] = usedNonces
+		ns.auctions = append(ns.auctions, auctionID)
+	}
+
+	if len(usedNonces) >= nonceSpace {
+		err = fmt.Errorf("Error getting nonce, all nonces have been used for auction %x", auctionID)
+		return
+	}
+
+	for i := 0; i < maxNonceAttempts; i++ {
+		if _, err = io.ReadFull(ns.entropy, nonce[:]); err != nil {
+			err = fmt.Errorf("Error reading entropy for nonce: %s", err)
+			return
+		}
+		if !usedNonces[nonce] {
+			usedNonces[nonce] = true
+			return
+		}
+	}
+
+	err = fmt.Errorf("Error getting nonce, entropy source keeps giving nonces that have been used for auction %x", auctionID)
+	return
+}
@@ -0,0 +1,57 @@
+package benchclient
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNextNonceNoCollisions(t *testing.T) {
+	var err error
+
+	ns := NewNonceSource(nil)
+
+	var auctionID [32]byte
+	seen := make(map[[2]byte]bool)
+	// Way past the birthday bound for 2 byte nonces, so random nonces alone would collide
+	for i := 0; i < 10000; i++ {
+		var nonce [2]byte
+		if nonce, err = ns.NextNonce(auctionID); err != nil {
+			t.Errorf("Error getting nonce %d: %s", i, err)
+			return
+		}
+		if seen[nonce] {
+			t.Errorf("Nonce %x was generated twice for the same auction", nonce)
+			return
+		}
+		seen[nonce] = true
+	}
+
+	return
+}
+
+func TestNextNonceExhausted(t *testing.T) {
+	var err error
+
+	// An entropy source that always gives the same bytes can only give one nonce per auction
+	ns := NewNonceSource(bytes.NewReader(make([]byte, 2*(maxNonceAttempts+2))))
+
+	var auctionID [32]byte
+	if _, err = ns.NextNonce(auctionID); err != nil {
+		t.Errorf("Error getting first nonce: %s", err)
+		return
+	}
+
+	if _, err = ns.NextNonce(auctionID); err == nil {
+		t.Errorf("Second nonce from constant entropy should have been rejected as a collision")
+		return
+	}
+
+	// A different auction should not care about nonces used in the first one
+	auctionID[0] = 1
+	if _, err = ns.NextNonce(auctionID); err != nil {
+		t.Errorf("Error getting nonce for new auction: %s", err)
+		return
+	}
+
+	return
+}
@@ -65,10 +65,8 @@ func (cl *BenchClient) OrderAsync(pubkey *koblitz.PublicKey, side string, pair s
 
 		newOrder.SetAmountWant(price)
 
-		// create e = hash(m)
-		sha3 := sha3.New256()
-		sha3.Write(newOrder.Serialize())
-		e := sha3.Sum(nil)
+		// create e = hash(domain || m)
+		e := match.LimitOrderDomain.SigHash(newOrder.Serialize())
 
 		// Sign order
 		var compactSig []byte
@@ -174,11 +172,12 @@ func (cl *BenchClient) GetPairs() (getPairsReply *cxrpc.GetPairsReply, err error
 	return
 }
 
-// AuctionOrderCommand submits an order synchronously. Uses asynchronous order function
-func (cl *BenchClient) AuctionOrderCommand(pubkey *koblitz.PublicKey, side string, pair string, amountHave uint64, price float64, t uint64, auctionID [32]byte) (reply *cxauctionrpc.SubmitPuzzledOrderReply, err error) {
+// AuctionOrderCommand submits an order synchronously. Uses asynchronous order function. The order puzzle
+// uses params, which should be the puzzle params of the auction.
+func (cl *BenchClient) AuctionOrderCommand(side string, pair string, amountHave uint64, price float64, params *match.PuzzleParams, auctionID [32]byte, network match.NetworkMagic) (reply *cxauctionrpc.SubmitPuzzledOrderReply, err error) {
 	errorChannel := make(chan error, 1)
 	replyChannel := make(chan *cxauctionrpc.SubmitPuzzledOrderReply, 1)
-	go cl.AuctionOrderAsync(pubkey, side, pair, amountHave, price, t, auctionID, replyChannel, errorChannel)
+	go cl.AuctionOrderAsync(side, pair, amountHave, price, params, auctionID, network, replyChannel, errorChannel)
 	// wait on either the reply or error, whichever comes first. If error is nil wait for reply. That's why the for loop is there. We don't care if the reply is nil, it shouldn't be, but that's sort of just so go-vet doesn't yell at us for having an unreachable return.
 	for reply == nil {
 		select {
@@ -195,55 +194,22 @@ func (cl *BenchClient) AuctionOrderCommand(pubkey *koblitz.PublicKey, side strin
 }
 
 // AuctionOrderAsync is supposed to be run in a separate goroutine, AuctionOrderCommand makes this synchronous however
-func (cl *BenchClient) AuctionOrderAsync(pubkey *koblitz.PublicKey, side string, pair string, amountHave uint64, price float64, t uint64, auctionID [32]byte, replyChan chan *cxauctionrpc.SubmitPuzzledOrderReply, errChan chan error) {
-
-	if cl.PrivKey == nil {
-		errChan <- fmt.Errorf("Private key nonexistent, set or specify private key so the client can sign commands")
-		return
-	}
+func (cl *BenchClient) AuctionOrderAsync(side string, pair string, amountHave uint64, price float64, params *match.PuzzleParams, auctionID [32]byte, network match.NetworkMagic, replyChan chan *cxauctionrpc.SubmitPuzzledOrderReply, errChan chan error) {
 
 	errChan <- func() (err error) {
 		// TODO: this can be refactored to look more like the rest of the code, it's just using channels and works really well so I don't want to mess with it rn
 		orderArgs := new(cxauctionrpc.SubmitPuzzledOrderArgs)
 		orderReply := new(cxauctionrpc.SubmitPuzzledOrderReply)
 
-		var newAuctionOrder match.AuctionOrder
-		copy(newAuctionOrder.Pubkey[:], pubkey.SerializeCompressed())
-		newAuctionOrder.Side = side
-
-		// check that the sides are correct
-		if newAuctionOrder.Side != "buy" && newAuctionOrder.Side != "sell" {
-			err = fmt.Errorf("AuctionOrder's side isn't buy or sell, try again")
+		var newAuctionOrder *match.AuctionOrder
+		if newAuctionOrder, err = cl.signedAuctionOrder(side, pair, amountHave, price, auctionID, network); err != nil {
 			return
 		}
 
-		// get the trading pair string from the shell input - third parameter
-		if err = newAuctionOrder.TradingPair.FromString(pair); err != nil {
-			err = fmt.Errorf("Error getting asset pair from string: \n%s", err)
-			return
-		}
-
-		newAuctionOrder.AmountHave = amountHave
-		newAuctionOrder.AuctionID = auctionID
-
-		newAuctionOrder.SetAmountWant(price)
+		logging.Infof("Order puzzle params: %s", params)
 
-		// create e = hash(m)
-		sha3 := sha3.New256()
-		sha3.Write(newAuctionOrder.SerializeSignable())
-		e := sha3.Sum(nil)
-
-		// Sign order
-		var compactSig []byte
-		if compactSig, err = koblitz.SignCompact(koblitz.S256(), cl.PrivKey, e, false); err != nil {
-			return
-		}
-
-		logging.Infof("Order time: %d", t)
-
-		newAuctionOrder.Signature = compactSig
 		var order *match.EncryptedAuctionOrder
-		if order, err = newAuctionOrder.TurnIntoEncryptedOrder(t); err != nil {
+		if order, err = newAuctionOrder.TurnIntoEncryptedOrderWithParams(params); err != nil {
 			err = fmt.Errorf("Error turning order into puzzle before submitting: %s", err)
 			return
 		}
@@ -265,3 +231,314 @@ func (cl *BenchClient) AuctionOrderAsync(pubkey *koblitz.PublicKey, side string,
 
 	return
 }
+
+// SubmitEncryptedAuctionOrder submits an encrypted order that's already been made. The reply says whether the
+// exchange accepted it, rather than it being an error if it didn't.
+func (cl *BenchClient) SubmitEncryptedAuctionOrder(order *match.EncryptedAuctionOrder) (reply *cxauctionrpc.SubmitReply, err error) {
+	reply = new(cxauctionrpc.SubmitReply)
+	args := new(cxauctionrpc.SubmitArgs)
+
+	if args.EncryptedOrderBytes, err = order.Serialize(); err != nil {
+		err = fmt.Errorf("Error when trying to serialize the order: %s", err)
+		return
+	}
+
+	if err = cl.Call("OpencxAuctionRPC.SubmitEncryptedAuctionOrder", args, reply); err != nil {
+		err = fmt.Errorf("Error calling 'SubmitEncryptedAuctionOrder' service method:\n%s", err)
+		return
+	}
+
+	return
+}
+
+// NextAuctionOrderCommand submits an order for the auction after the current one. The order is made for the
+// current auction with its puzzle params, and the exchange places it in the next auction once that starts.
+func (cl *BenchClient) NextAuctionOrderCommand(side string, pair string, amountHave uint64, price float64, params *match.PuzzleParams, auctionID [32]byte, network match.NetworkMagic) (reply *cxauctionrpc.SubmitPuzzledOrderReply, err error) {
+	reply = new(cxauctionrpc.SubmitPuzzledOrderReply)
+	orderArgs := &cxauctionrpc.SubmitPuzzledOrderArgs{
+		NextAuction: true,
+	}
+
+	var newAuctionOrder *match.AuctionOrder
+	if newAuctionOrder, err = cl.signedAuctionOrder(side, pair, amountHave, price, auctionID, network); err != nil {
+		return
+	}
+
+	var order *match.EncryptedAuctionOrder
+	if order, err = newAuctionOrder.TurnIntoEncryptedOrderWithParams(params); err != nil {
+		err = fmt.Errorf("Error turning order into puzzle before submitting: %s", err)
+		return
+	}
+
+	if orderArgs.EncryptedOrderBytes, err = order.Serialize(); err != nil {
+		err = fmt.Errorf("Error when trying to serialize the order: %s", err)
+		return
+	}
+
+	if err = cl.Call("OpencxAuctionRPC.SubmitPuzzledOrder", orderArgs, reply); err != nil {
+		err = fmt.Errorf("Error calling 'SubmitPuzzledOrder' service method:\n%s", err)
+		return
+	}
+
+	return
+}
+
+// ContinuousOrderCommand submits an unencrypted auction order to an exchange in continuous matching mode,
+// where it will be matched as soon as it arrives.
+func (cl *BenchClient) ContinuousOrderCommand(side string, pair string, amountHave uint64, price float64, auctionID [32]byte, network match.NetworkMagic) (reply *cxauctionrpc.SubmitContinuousOrderReply, err error) {
+
+	var newAuctionOrder *match.AuctionOrder
+	if newAuctionOrder, err = cl.signedAuctionOrder(side, pair, amountHave, price, auctionID, network); err != nil {
+		return
+	}
+
+	reply = new(cxauctionrpc.SubmitContinuousOrderReply)
+	orderArgs := &cxauctionrpc.SubmitContinuousOrderArgs{
+		OrderBytes: newAuctionOrder.Serialize(),
+	}
+
+	if err = cl.Call("OpencxAuctionRPC.SubmitContinuousOrder", orderArgs, reply); err != nil {
+		err = fmt.Errorf("Error calling 'SubmitContinuousOrder' service method:\n%s", err)
+		return
+	}
+
+	return
+}
+
+// UnsafePlaintextOrderCommand submits a batch auction order without a timelock puzzle. This reveals the
+// order to the exchange right away, so it should only be used for testing against an exchange running in
+// the unsafe no-puzzle mode.
+func (cl *BenchClient) UnsafePlaintextOrderCommand(side string, pair string, amountHave uint64, price float64, auctionID [32]byte, network match.NetworkMagic) (reply *cxauctionrpc.SubmitUnsafePlaintextOrderReply, err error) {
+
+	var newAuctionOrder *match.AuctionOrder
+	if newAuctionOrder, err = cl.signedAuctionOrder(side, pair, amountHave, price, auctionID, network); err != nil {
+		return
+	}
+
+	reply = new(cxauctionrpc.SubmitUnsafePlaintextOrderReply)
+	orderArgs := &cxauctionrpc.SubmitUnsafePlaintextOrderArgs{
+		OrderBytes: newAuctionOrder.Serialize(),
+	}
+
+	if err = cl.Call("OpencxAuctionRPC.SubmitUnsafePlaintextOrder", orderArgs, reply); err != nil {
+		err = fmt.Errorf("Error calling 'SubmitUnsafePlaintextOrder' service method:\n%s", err)
+		return
+	}
+
+	return
+}
+
+// signedAuctionOrder creates an auction order for the current auction, and signs it with the client's signer
+func (cl *BenchClient) signedAuctionOrder(side string, pair string, amountHave uint64, price float64, auctionID [32]byte, network match.NetworkMagic) (newAuctionOrder *match.AuctionOrder, err error) {
+	var signer Signer
+	if signer, err = cl.signer(); err != nil {
+		return
+	}
+
+	newAuctionOrder = new(match.AuctionOrder)
+	newAuctionOrder.Pubkey = signer.PubKey()
+	newAuctionOrder.Side = side
+
+	// check that the sides are correct
+	if newAuctionOrder.Side != "buy" && newAuctionOrder.Side != "sell" {
+		err = fmt.Errorf("AuctionOrder's side isn't buy or sell, try again")
+		return
+	}
+
+	// get the trading pair string from the shell input - third parameter
+	if err = newAuctionOrder.TradingPair.FromString(pair); err != nil {
+		err = fmt.Errorf("Error getting asset pair from string: \n%s", err)
+		return
+	}
+
+	newAuctionOrder.AmountHave = amountHave
+	newAuctionOrder.AuctionID = auctionID
+	newAuctionOrder.Network = network
+
+	if cl.nonces == nil {
+		err = fmt.Errorf("Nonce source nonexistent, set up the client or set an entropy source before sending auction orders")
+		return
+	}
+	if newAuctionOrder.Nonce, err = cl.nonces.NextNonce(auctionID); err != nil {
+		err = fmt.Errorf("Error generating nonce for auction order: %s", err)
+		return
+	}
+
+	newAuctionOrder.SetAmountWant(price)
+
+	// Sign order
+	if newAuctionOrder.Signature, err = signer.Sign(newAuctionOrder.SigHash()); err != nil {
+		err = fmt.Errorf("Error signing auction order: %s", err)
+		return
+	}
+
+	return
+}
+
+// CancelAllOrders cancels all of the client's pending orders in the auction with auctionID
+func (cl *BenchClient) CancelAllOrders(auctionID [32]byte) (cancelAllReply *cxauctionrpc.CancelAllOrdersReply, err error) {
+
+	var signer Signer
+	if signer, err = cl.signer(); err != nil {
+		return
+	}
+
+	cancelAllReply = new(cxauctionrpc.CancelAllOrdersReply)
+	cancelAllArgs := &cxauctionrpc.CancelAllOrdersArgs{
+		AuctionID: auctionID,
+	}
+
+	// create e = hash(m)
+	sha3 := sha3.New256()
+	sha3.Write(cancelAllArgs.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	// Sign cancel
+	if cancelAllArgs.Signature, err = signer.Sign(e); err != nil {
+		err = fmt.Errorf("Error signing cancel all: %s", err)
+		return
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.CancelAllOrders", cancelAllArgs, cancelAllReply); err != nil {
+		err = fmt.Errorf("Error calling 'CancelAllOrders' service method:\n%s", err)
+		return
+	}
+
+	return
+}
+
+// CancelEverything cancels all of the client's pending orders, including the ones queued for the auction after
+// the one with auctionID, which should be the current auction
+func (cl *BenchClient) CancelEverything(auctionID [32]byte) (cancelEverythingReply *cxauctionrpc.CancelEverythingReply, err error) {
+
+	var signer Signer
+	if signer, err = cl.signer(); err != nil {
+		return
+	}
+
+	cancelEverythingReply = new(cxauctionrpc.CancelEverythingReply)
+	cancelEverythingArgs := &cxauctionrpc.CancelEverythingArgs{
+		AuctionID: auctionID,
+	}
+
+	// create e = hash(m)
+	sha3 := sha3.New256()
+	sha3.Write(cancelEverythingArgs.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	// Sign cancel
+	if cancelEverythingArgs.Signature, err = signer.Sign(e); err != nil {
+		err = fmt.Errorf("Error signing cancel everything: %s", err)
+		return
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.CancelEverything", cancelEverythingArgs, cancelEverythingReply); err != nil {
+		err = fmt.Errorf("Error calling 'CancelEverything' service method:\n%s", err)
+		return
+	}
+
+	return
+}
+
+// RequestNonce asks the server to assign a fresh nonce for the client's orders in the auction with auctionID
+func (cl *BenchClient) RequestNonce(auctionID [32]byte) (requestNonceReply *cxauctionrpc.RequestNonceReply, err error) {
+
+	var signer Signer
+	if signer, err = cl.signer(); err != nil {
+		return
+	}
+
+	requestNonceReply = new(cxauctionrpc.RequestNonceReply)
+	requestNonceArgs := &cxauctionrpc.RequestNonceArgs{
+		AuctionID: auctionID,
+	}
+
+	// create e = hash(m)
+	sha3 := sha3.New256()
+	sha3.Write(requestNonceArgs.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	// Sign request
+	if requestNonceArgs.Signature, err = signer.Sign(e); err != nil {
+		err = fmt.Errorf("Error signing nonce request: %s", err)
+		return
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.RequestNonce", requestNonceArgs, requestNonceReply); err != nil {
+		err = fmt.Errorf("Error calling 'RequestNonce' service method:\n%s", err)
+		return
+	}
+
+	return
+}
+
+// GetReturnedOrders gets the client's orders that didn't match in past auctions, and why. auctionID should be
+// the current auction.
+func (cl *BenchClient) GetReturnedOrders(auctionID [32]byte) (getReturnedReply *cxauctionrpc.GetReturnedOrdersReply, err error) {
+
+	var signer Signer
+	if signer, err = cl.signer(); err != nil {
+		return
+	}
+
+	getReturnedReply = new(cxauctionrpc.GetReturnedOrdersReply)
+	getReturnedArgs := &cxauctionrpc.GetReturnedOrdersArgs{
+		AuctionID: auctionID,
+	}
+
+	// create e = hash(m)
+	sha3 := sha3.New256()
+	sha3.Write(getReturnedArgs.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	// Sign request
+	if getReturnedArgs.Signature, err = signer.Sign(e); err != nil {
+		err = fmt.Errorf("Error signing returned orders request: %s", err)
+		return
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetReturnedOrders", getReturnedArgs, getReturnedReply); err != nil {
+		err = fmt.Errorf("Error calling 'GetReturnedOrders' service method:\n%s", err)
+		return
+	}
+
+	return
+}
+
+// GetAdminMetrics gets the exchange's detailed internal metrics. The client's key has to be one of the exchange's
+// admin pubkeys, and auctionID should be the current auction.
+func (cl *BenchClient) GetAdminMetrics(auctionID [32]byte) (getAdminMetricsReply *cxauctionrpc.GetAdminMetricsReply, err error) {
+
+	var signer Signer
+	if signer, err = cl.signer(); err != nil {
+		return
+	}
+
+	getAdminMetricsReply = new(cxauctionrpc.GetAdminMetricsReply)
+	getAdminMetricsArgs := &cxauctionrpc.GetAdminMetricsArgs{
+		AuctionID: auctionID,
+	}
+
+	// create e = hash(m)
+	sha3 := sha3.New256()
+	sha3.Write(getAdminMetricsArgs.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	// Sign request
+	if getAdminMetricsArgs.Signature, err = signer.Sign(e); err != nil {
+		err = fmt.Errorf("Error signing admin metrics request: %s", err)
+		return
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetAdminMetrics", getAdminMetricsArgs, getAdminMetricsReply); err != nil {
+		err = fmt.Errorf("Error calling 'GetAdminMetrics' service method:\n%s", err)
+		return
+	}
+
+	return
+}
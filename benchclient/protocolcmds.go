@@ -1,6 +1,9 @@
 package benchclient
 
-import "github.com/mit-dci/opencx/cxauctionrpc"
+import (
+	"github.com/mit-dci/opencx/cxauctionrpc"
+	"github.com/mit-dci/opencx/match"
+)
 
 // GetPublicParameters returns the public parameters like the auction time and current auction ID
 func (cl *BenchClient) GetPublicParameters() (getPublicParametersReply *cxauctionrpc.GetPublicParametersReply, err error) {
@@ -14,3 +17,107 @@ func (cl *BenchClient) GetPublicParameters() (getPublicParametersReply *cxauctio
 
 	return
 }
+
+// GetPairPublicParameters returns the public parameters for a pair's auction, which is the exchange's auction
+// unless the pair runs its own
+func (cl *BenchClient) GetPairPublicParameters(pair match.Pair) (getPublicParametersReply *cxauctionrpc.GetPublicParametersReply, err error) {
+	getPublicParametersReply = new(cxauctionrpc.GetPublicParametersReply)
+	getPublicParametersArgs := &cxauctionrpc.GetPublicParametersArgs{
+		Pair: &pair,
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetPublicParameters", getPublicParametersArgs, getPublicParametersReply); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetAuctionStats returns the stats for a pair's auction, like the buy and sell volume imbalance
+func (cl *BenchClient) GetAuctionStats(pair match.Pair, auctionID [32]byte) (getAuctionStatsReply *cxauctionrpc.GetAuctionStatsReply, err error) {
+	getAuctionStatsReply = new(cxauctionrpc.GetAuctionStatsReply)
+	getAuctionStatsArgs := &cxauctionrpc.GetAuctionStatsArgs{
+		AuctionID: auctionID,
+		Pair:      pair,
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetAuctionStats", getAuctionStatsArgs, getAuctionStatsReply); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetOrderBookDepth returns the cumulative depth of a pair's auction order book, with at most levels points
+// per side if levels is more than zero
+func (cl *BenchClient) GetOrderBookDepth(pair match.Pair, auctionID [32]byte, levels int) (getOrderBookDepthReply *cxauctionrpc.GetOrderBookDepthReply, err error) {
+	getOrderBookDepthReply = new(cxauctionrpc.GetOrderBookDepthReply)
+	getOrderBookDepthArgs := &cxauctionrpc.GetOrderBookDepthArgs{
+		AuctionID: auctionID,
+		Pair:      pair,
+		Levels:    levels,
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetOrderBookDepth", getOrderBookDepthArgs, getOrderBookDepthReply); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetSignedTime returns the exchange's current time, signed by the exchange's key
+func (cl *BenchClient) GetSignedTime() (getSignedTimeReply *cxauctionrpc.GetSignedTimeReply, err error) {
+	getSignedTimeReply = new(cxauctionrpc.GetSignedTimeReply)
+	getSignedTimeArgs := new(cxauctionrpc.GetSignedTimeArgs)
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetSignedTime", getSignedTimeArgs, getSignedTimeReply); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetInterimCommitment returns the latest commitment to the puzzles in the current auction
+func (cl *BenchClient) GetInterimCommitment() (getInterimCommitmentReply *cxauctionrpc.GetInterimCommitmentReply, err error) {
+	getInterimCommitmentReply = new(cxauctionrpc.GetInterimCommitmentReply)
+	getInterimCommitmentArgs := new(cxauctionrpc.GetInterimCommitmentArgs)
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetInterimCommitment", getInterimCommitmentArgs, getInterimCommitmentReply); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetCommitmentHistory returns the final commitment roots of the last numAuctions auctions, most recent first
+func (cl *BenchClient) GetCommitmentHistory(numAuctions uint64) (getCommitmentHistoryReply *cxauctionrpc.GetCommitmentHistoryReply, err error) {
+	getCommitmentHistoryReply = new(cxauctionrpc.GetCommitmentHistoryReply)
+	getCommitmentHistoryArgs := &cxauctionrpc.GetCommitmentHistoryArgs{
+		NumAuctions: numAuctions,
+	}
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetCommitmentHistory", getCommitmentHistoryArgs, getCommitmentHistoryReply); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetHealth returns whether or not the exchange is still advancing auctions
+func (cl *BenchClient) GetHealth() (getHealthReply *cxauctionrpc.GetHealthReply, err error) {
+	getHealthReply = new(cxauctionrpc.GetHealthReply)
+	getHealthArgs := new(cxauctionrpc.GetHealthArgs)
+
+	// Actually use the RPC Client to call the method
+	if err = cl.Call("OpencxAuctionRPC.GetHealth", getHealthArgs, getHealthReply); err != nil {
+		return
+	}
+
+	return
+}
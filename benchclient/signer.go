@@ -0,0 +1,75 @@
+package benchclient
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// Signer signs on behalf of the client, so the client doesn't need to hold the private key itself.
+// This lets signing be delegated to something external, like a hardware wallet or an HSM.
+type Signer interface {
+	// Sign signs the hash, returning a compact signature that the exchange can recover the public key from
+	Sign(hash []byte) ([]byte, error)
+	// PubKey returns the compressed public key that the signer signs for
+	PubKey() [33]byte
+}
+
+// PrivKeySigner is the default signer, it signs with a private key kept in memory
+type PrivKeySigner struct {
+	privkey *koblitz.PrivateKey
+}
+
+// NewPrivKeySigner creates a signer that signs with privkey
+func NewPrivKeySigner(privkey *koblitz.PrivateKey) (signer *PrivKeySigner, err error) {
+	if privkey == nil {
+		err = fmt.Errorf("Cannot create a signer without a private key")
+		return
+	}
+	signer = &PrivKeySigner{
+		privkey: privkey,
+	}
+	return
+}
+
+// Sign creates a compact signature of hash with the private key
+func (s *PrivKeySigner) Sign(hash []byte) (sig []byte, err error) {
+	if sig, err = koblitz.SignCompact(koblitz.S256(), s.privkey, hash, false); err != nil {
+		err = fmt.Errorf("Error signing with private key: %s", err)
+		return
+	}
+	return
+}
+
+// PubKey returns the compressed public key for the private key
+func (s *PrivKeySigner) PubKey() (pubkey [33]byte) {
+	copy(pubkey[:], s.privkey.PubKey().SerializeCompressed())
+	return
+}
+
+// SetSigner sets an external signer that auction commands will be signed with instead of the private key
+func (cl *BenchClient) SetSigner(signer Signer) {
+	cl.Signer = signer
+	return
+}
+
+// signer gets what the client should sign auction commands with. An external signer is used if one
+// is set, otherwise we sign with the private key.
+func (cl *BenchClient) signer() (signer Signer, err error) {
+	if cl.Signer != nil {
+		signer = cl.Signer
+		return
+	}
+
+	if cl.PrivKey == nil {
+		err = fmt.Errorf("Private key and signer nonexistent, set or specify private key or set a signer so the client can sign commands")
+		return
+	}
+
+	if signer, err = NewPrivKeySigner(cl.PrivKey); err != nil {
+		err = fmt.Errorf("Error creating signer from private key: %s", err)
+		return
+	}
+
+	return
+}
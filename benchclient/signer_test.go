@@ -0,0 +1,121 @@
+package benchclient
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// mockExternalSigner acts like a hardware wallet, the client never gets to see the private key
+type mockExternalSigner struct {
+	privkey *koblitz.PrivateKey
+	signed  [][]byte
+	refuse  bool
+}
+
+func (m *mockExternalSigner) Sign(hash []byte) (sig []byte, err error) {
+	if m.refuse {
+		err = fmt.Errorf("user rejected signing on device")
+		return
+	}
+	m.signed = append(m.signed, hash)
+	return koblitz.SignCompact(koblitz.S256(), m.privkey, hash, false)
+}
+
+func (m *mockExternalSigner) PubKey() (pubkey [33]byte) {
+	copy(pubkey[:], m.privkey.PubKey().SerializeCompressed())
+	return
+}
+
+func TestExternalSignerSignsAuctionOrder(t *testing.T) {
+	var err error
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key for external signer: %s", err)
+		return
+	}
+
+	mockSigner := &mockExternalSigner{privkey: privkey}
+
+	// The client has no private key, only the external signer
+	cl := new(BenchClient)
+	cl.SetEntropySource(nil)
+	cl.SetSigner(mockSigner)
+
+	var auctionID [32]byte
+	var order *match.AuctionOrder
+	if order, err = cl.signedAuctionOrder("buy", "regtest/litereg", 1000, 1.0, auctionID, match.TestnetMagic); err != nil {
+		t.Errorf("Error creating auction order with external signer: %s", err)
+		return
+	}
+
+	if len(mockSigner.signed) != 1 {
+		t.Errorf("External signer should have signed once, signed %d times", len(mockSigner.signed))
+		return
+	}
+
+	if order.Pubkey != mockSigner.PubKey() {
+		t.Errorf("Order pubkey %x should be the external signer's pubkey %x", order.Pubkey, mockSigner.PubKey())
+		return
+	}
+
+	e := order.SigHash()
+
+	if !bytes.Equal(mockSigner.signed[0], e) {
+		t.Errorf("External signer was asked to sign %x, but the order hash is %x", mockSigner.signed[0], e)
+		return
+	}
+
+	var recoveredPubkey *koblitz.PublicKey
+	if recoveredPubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), order.Signature, e); err != nil {
+		t.Errorf("Error recovering pubkey from externally signed order: %s", err)
+		return
+	}
+
+	if !bytes.Equal(recoveredPubkey.SerializeCompressed(), order.Pubkey[:]) {
+		t.Errorf("Pubkey recovered from signature doesn't match the order pubkey")
+		return
+	}
+
+	// If the device refuses to sign, so does the client
+	mockSigner.refuse = true
+	if _, err = cl.signedAuctionOrder("buy", "regtest/litereg", 1000, 1.0, auctionID, match.TestnetMagic); err == nil {
+		t.Errorf("Creating an order should fail when the external signer refuses to sign")
+		return
+	}
+
+	return
+}
+
+func TestDefaultSignerUsesPrivKey(t *testing.T) {
+	var err error
+
+	cl := new(BenchClient)
+	if _, err = cl.signer(); err == nil {
+		t.Errorf("Getting a signer without a private key or external signer should fail")
+		return
+	}
+
+	if cl.PrivKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key for default signer: %s", err)
+		return
+	}
+
+	var signer Signer
+	if signer, err = cl.signer(); err != nil {
+		t.Errorf("Error getting default signer: %s", err)
+		return
+	}
+
+	pubkey := signer.PubKey()
+	if !bytes.Equal(pubkey[:], cl.PrivKey.PubKey().SerializeCompressed()) {
+		t.Errorf("Default signer pubkey should be the client's pubkey")
+		return
+	}
+
+	return
+}
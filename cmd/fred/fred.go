@@ -1,20 +1,28 @@
 package main
 
 import (
+	"encoding/hex"
 	"log"
+	"math/big"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/mit-dci/lit/crypto/koblitz"
 
 	flags "github.com/jessevdk/go-flags"
+	"github.com/mit-dci/opencx/crypto/timelockencoders"
 	"github.com/mit-dci/opencx/cxauctionrpc"
 	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/cxdb"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
 	"github.com/mit-dci/opencx/cxdb/cxdbsql"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+	"github.com/mit-dci/opencx/util"
 )
 
 type fredConfig struct {
@@ -25,8 +33,13 @@ type fredConfig struct {
 	FredHomeDir string `long:"dir" description:"Location of the root directory relative to home directory"`
 
 	// stuff for ports
-	Rpcport uint16 `short:"p" long:"rpcport" description:"Set RPC port to connect to"`
-	Rpchost string `long:"rpchost" description:"Set RPC host to listen to"`
+	Rpcport  uint16 `short:"p" long:"rpcport" description:"Set RPC port to connect to"`
+	Rpchost  string `long:"rpchost" description:"Set RPC host to listen to"`
+	HTTPPort uint16 `long:"httpport" description:"Port to serve the JSON HTTP gateway on, on the RPC host. 0 means there is no HTTP gateway"`
+
+	// stuff for the rpc listener
+	ListenBacklog int           `long:"listenbacklog" description:"Most RPC connections that can be waiting to be accepted. 0 means the OS default"`
+	KeepAlive     time.Duration `long:"keepalive" description:"TCP keep-alive period for RPC connections, like 30s. 0 means the default, negative turns keep-alive off"`
 
 	// logging and debug parameters
 	LogLevel []bool `short:"v" description:"Set verbosity level to verbose (-v), very verbose (-vv) or very very verbose (-vvv)"`
@@ -64,13 +77,58 @@ type fredConfig struct {
 	LightningSupport bool `long:"lightning" description:"Whether or not to support lightning on the exchange"`
 
 	// database information
+	DBType     string `long:"dbtype" description:"Type of database to store everything in, either mysql or postgres"`
 	DBUsername string `long:"dbuser" description:"database username"`
 	DBPassword string `long:"dbpassword" description:"database password"`
 	DBHost     string `long:"dbhost" description:"Host for the database connection"`
-	DBPort     uint16 `long:"dbport" description:"Port for the database connection"`
+	DBPort     uint16 `long:"dbport" description:"Port for the database connection. 0 means the default port for the database type"`
 
 	// Auction server options
-	AuctionTime uint64 `long:"auctiontime" description:"Time it should take to generate a timelock puzzle protected order"`
+	AuctionTime        uint64        `long:"auctiontime" description:"Time it should take to generate a timelock puzzle protected order"`
+	AuctionDuration    time.Duration `long:"auctionduration" description:"How long auctions last, and how long it should take to solve a timelock puzzle protected order, like 30s. If set, auctiontime is calibrated from how fast this machine squares"`
+	PriceBands         []string      `long:"priceband" description:"Absolute clearing price band for a pair, like asset1/asset2:min:max. Can be set more than once"`
+	OraclePrices       []string      `long:"oracleprice" description:"Static oracle reserve price for a pair, like asset1/asset2:price. Can be set more than once"`
+	OracleURL          string        `long:"oracleurl" description:"URL of an HTTP price oracle to get reserve prices from instead of static prices"`
+	OracleTimeout      time.Duration `long:"oracletimeout" description:"How long to wait for the HTTP price oracle, like 5s"`
+	OracleDeviation    float64       `long:"oracledeviation" description:"How far from the oracle reserve price auctions can clear, as a fraction of it. Auctions that clear further away are voided"`
+	Network            string        `long:"network" description:"Network the exchange runs on, orders must be signed for it. Can be mainnet, testnet, or regtest"`
+	Mode               string        `long:"mode" description:"How orders are matched. batch uses timelock puzzles and batch auctions, continuous matches unencrypted orders as they arrive"`
+	MaxAuctions        uint64        `long:"maxauctions" description:"Maximum number of pair auctions that can run at the same time, the rest wait in line. 0 means no limit"`
+	VerifyWorkers      int           `long:"verifyworkers" description:"Number of workers verifying the signatures of solved orders. 0 means GOMAXPROCS"`
+	NextAuctionWindow  time.Duration `long:"nextauctionwindow" description:"How long before an auction ends that orders for the next auction are accepted, like 10s. 0 means never"`
+	MaxPendingSolves   int           `long:"maxpendingsolves" description:"Maximum number of orders waiting to be solved. 0 means no limit"`
+	SolveEviction      string        `long:"solveeviction" description:"What to do with new orders when the solve queue is full. reject rejects them, droplowest drops the lowest priority waiting order"`
+	SolvePriority      string        `long:"solvepriority" description:"Order to solve waiting orders in. fifo solves them as they came in, fee solves the ones with the highest priority fee first"`
+	SolveStart         string        `long:"solvestart" description:"When orders start being solved. arrival solves them as they come in, trigger waits for the auction to close or solvetriggercount orders, whichever is first"`
+	SolveTriggerCount  int           `long:"solvetriggercount" description:"Number of orders in an auction that starts solving them with the trigger solve start. 0 means only the auction closing starts solving"`
+	NonceScope         string        `long:"noncescope" description:"What a pubkey's order nonces have to be unique within. auction makes them unique across every pair in an auction, pair only on each pair"`
+	MaxCiphertextCache int           `long:"maxciphertextcache" description:"Maximum number of puzzled orders remembered in an auction, so exact duplicates of them are dropped"`
+	WatchdogInterval   time.Duration `long:"watchdoginterval" description:"How often to check that the auction clock is still ticking, like 10s. 0 means never"`
+	WatchdogGrace      time.Duration `long:"watchdoggrace" description:"How long past the end of an auction the auction clock can be before it's considered stalled"`
+	WatchdogRestart    bool          `long:"watchdogrestart" description:"Restart the auction clock if it stalls"`
+	AdminPubkeys       []string      `long:"adminpubkey" description:"Hex encoded compressed pubkey that is allowed to get admin metrics. Can be set more than once"`
+	AdminInterval      time.Duration `long:"adminmetricsinterval" description:"How long each admin has to wait between getting admin metrics, like 1s"`
+	BatchTimeout       time.Duration `long:"batchtimeout" description:"How long the auction clock waits for an auction's batch to be processed before moving on, like 30s. 0 means no deadline"`
+	CommitmentInterval time.Duration `long:"commitmentinterval" description:"How often a new interim commitment to the current auction is published for clients, like 5s"`
+	PricePrecision     uint          `long:"priceprecision" description:"Number of decimal places clearing prices are formatted with for clients"`
+	ParamCacheTTL      time.Duration `long:"paramcachettl" description:"How long public parameters are cached for polling clients, like 1s. They're always refreshed when the auction changes. 0 means no caching"`
+	MaxDepthLevels     int           `long:"maxdepthlevels" description:"Most price levels on each side of order book depth given to clients, the rest are aggregated into the last level"`
+	MaxAmountRatio     uint64        `long:"maxamountratio" description:"Most either amount in an order can be compared to the other, orders with a bigger ratio are rejected. 0 means no limit"`
+	MaxAuctionPubkeys  int           `long:"maxauctionpubkeys" description:"Most distinct pubkeys that can have orders in a single auction, orders from any other pubkey are rejected once it's full. 0 means no limit"`
+	RejectionAlert     uint64        `long:"rejectionalert" description:"Warn when this many orders are rejected for the same reason in a single auction. 0 means never"`
+	RevealPolicy       string        `long:"revealpolicy" description:"Which keys of an auction's orders are revealed once it's over. all reveals every order's key, matched only reveals the keys of orders that filled"`
+	RevealGrace        time.Duration `long:"revealgrace" description:"How long after an auction closes its keys wait for orders that are still being solved, like 10s, so they're revealed together. 0 means keys are revealed as soon as it closes"`
+	NoSignResults      bool          `long:"nosignresults" description:"Don't sign auction results with the exchange key"`
+	NoAutoClear        bool          `long:"noautoclear" description:"Don't clear auctions on the auction clock, so they have to be cleared some other way"`
+	PairAuctions       bool          `long:"pairauctions" description:"Run an independent auction for every pair, each with its own auction ID and deadline, instead of one auction for all of them"`
+	OrderLogDir        string        `long:"orderlogdir" description:"Directory in the fred directory that accepted encrypted orders are logged in, so the open auction is replayed after a restart. Empty means orders aren't logged"`
+	ShutdownTimeout    time.Duration `long:"shutdowntimeout" description:"How long stopping the exchange waits for orders being solved to be placed, like 30s. 0 means wait until they are"`
+
+	// Debugging options
+	HeapProfileThreshold uint64 `long:"heapprofilethreshold" description:"Write a heap profile to the fred directory when the heap uses more than this many bytes. 0 means never"`
+
+	// Testing only, never set this on a real exchange
+	UnsafeNoPuzzle bool `long:"unsafe-no-puzzle-testing-only" description:"UNSAFE, FOR LOCAL TESTING ONLY. Accept batch orders without timelock puzzles, which makes the exchange not front-running resistant. Refused on mainnet"`
 }
 
 var (
@@ -92,13 +150,20 @@ var (
 	defaultLightningSupport = true
 
 	// default database stuff
+	defaultDBType     = "mysql"
 	defaultDBUsername = "opencx"
 	defaultDBPassword = "testpass"
 	defaultDBHost     = "localhost"
-	defaultDBPort     = uint16(3306)
+	defaultDBPort     = uint16(0)
 
 	// default auction options
 	defaultAuctionTime = uint64(30000)
+	defaultNetwork     = "testnet"
+	defaultMode        = "batch"
+
+	// default for how long to wait for an HTTP price oracle, and how far from its price auctions can clear
+	defaultOracleTimeout   = 5 * time.Second
+	defaultOracleDeviation = 0.1
 )
 
 // newConfigParser returns a new command line flags parser.
@@ -111,27 +176,55 @@ func main() {
 	var err error
 
 	conf := fredConfig{
-		FredHomeDir:      defaultFredHomeDirName,
-		Rpcport:          defaultRpcport,
-		Rpchost:          defaultRpchost,
-		MaxPeers:         defaultMaxPeers,
-		MinPeerPort:      defaultMinPeerPort,
-		Lithost:          defaultLithost,
-		Litport:          defaultLitport,
-		AuthenticatedRPC: defaultAuthenticatedRPC,
-		LightningSupport: defaultLightningSupport,
-		DBUsername:       defaultDBUsername,
-		DBPassword:       defaultDBPassword,
-		DBHost:           defaultDBHost,
-		DBPort:           defaultDBPort,
-		AuctionTime:      defaultAuctionTime,
+		FredHomeDir:        defaultFredHomeDirName,
+		Rpcport:            defaultRpcport,
+		Rpchost:            defaultRpchost,
+		MaxPeers:           defaultMaxPeers,
+		MinPeerPort:        defaultMinPeerPort,
+		Lithost:            defaultLithost,
+		Litport:            defaultLitport,
+		AuthenticatedRPC:   defaultAuthenticatedRPC,
+		LightningSupport:   defaultLightningSupport,
+		DBType:             defaultDBType,
+		DBUsername:         defaultDBUsername,
+		DBPassword:         defaultDBPassword,
+		DBHost:             defaultDBHost,
+		DBPort:             defaultDBPort,
+		AuctionTime:        defaultAuctionTime,
+		Network:            defaultNetwork,
+		Mode:               defaultMode,
+		OracleTimeout:      defaultOracleTimeout,
+		OracleDeviation:    defaultOracleDeviation,
+		PricePrecision:     cxauctionserver.DefaultPricePrecision,
+		ParamCacheTTL:      cxauctionrpc.DefaultParamCacheTTL,
+		MaxDepthLevels:     cxauctionserver.DefaultMaxDepthLevels,
+		SolveEviction:      cxauctionserver.RejectNew.String(),
+		SolvePriority:      cxauctionserver.SolveFIFO.String(),
+		SolveStart:         cxauctionserver.SolveOnArrival.String(),
+		NonceScope:         match.NoncePerAuction.String(),
+		RevealPolicy:       cxauctionserver.RevealAll.String(),
+		MaxCiphertextCache: cxauctionserver.DefaultMaxCiphertextCache,
+		OrderLogDir:        defaultOrderLogDir,
+		ShutdownTimeout:    cxauctionserver.DefaultShutdownTimeout,
+		CommitmentInterval: cxauctionserver.DefaultCommitmentInterval,
+		WatchdogGrace:      cxauctionserver.DefaultWatchdogGrace,
+		AdminInterval:      cxauctionserver.DefaultAdminMetricsInterval,
 	}
 
 	// Check and load config params
 	key := opencxSetup(&conf)
 
+	var dialect cxdbsql.Dialect
+	if dialect, err = cxdbsql.DialectFromString(conf.DBType); err != nil {
+		logging.Fatalf("Error getting database type: \n%s", err)
+	}
+
+	if conf.DBPort == 0 {
+		conf.DBPort = dialect.DefaultPort()
+	}
+
 	var db *cxdbsql.DB
-	if db, err = cxdbsql.CreateDBConnection(conf.DBUsername, conf.DBPassword, conf.DBHost, conf.DBPort); err != nil {
+	if db, err = cxdbsql.CreateDialectDBConnection(dialect, conf.DBUsername, conf.DBPassword, conf.DBHost, conf.DBPort); err != nil {
 		logging.Fatalf("Error initializing Database: \n%s", err)
 	}
 
@@ -143,16 +236,263 @@ func main() {
 		log.Fatalf("Error setting up sql client: \n%s", err)
 	}
 
+	if conf.AuctionDuration > 0 {
+		var squaringsPerSecond uint64
+		if squaringsPerSecond, err = timelockencoders.CalibrateSquaringsPerSecond(); err != nil {
+			logging.Fatalf("Error calibrating auction time: \n%s", err)
+		}
+		conf.AuctionTime = timelockencoders.SquaringsForDuration(conf.AuctionDuration)
+		logging.Infof("Calibrated %d squarings per second, auction time for %s is %d", squaringsPerSecond, conf.AuctionDuration, conf.AuctionTime)
+	}
+
 	// Anyways, here's where we set the server
 	var fredServer *cxauctionserver.OpencxAuctionServer
 	if fredServer, err = cxauctionserver.InitServer(db, 100, conf.AuctionTime); err != nil {
 		logging.Fatalf("Error initializing server: \n%s", err)
 	}
 
+	// The auction time is now squarings, so auctions need to be told how long they last
+	if conf.AuctionDuration > 0 {
+		if err = fredServer.SetAuctionDuration(conf.AuctionDuration); err != nil {
+			logging.Fatalf("Error setting auction duration: \n%s", err)
+		}
+	}
+
+	var network match.NetworkMagic
+	if network, err = match.NetworkMagicFromString(conf.Network); err != nil {
+		logging.Fatalf("Error parsing network: \n%s", err)
+	}
+	fredServer.SetNetwork(network)
+
+	var mode cxauctionserver.MatchingMode
+	if mode, err = cxauctionserver.MatchingModeFromString(conf.Mode); err != nil {
+		logging.Fatalf("Error parsing matching mode: \n%s", err)
+	}
+
+	// Continuous orders are matched in memory with price-time priority
+	var continuousStore cxdb.OpencxStore
+	if mode == cxauctionserver.ContinuousMatching {
+		matchingDB := new(cxdbmemory.CXDBMatchingOnly)
+		if err = matchingDB.SetupClient(coinList); err != nil {
+			logging.Fatalf("Error setting up continuous matching store: \n%s", err)
+		}
+		continuousStore = matchingDB
+	}
+
+	if err = fredServer.SetMatchingMode(mode, continuousStore); err != nil {
+		logging.Fatalf("Error setting matching mode: \n%s", err)
+	}
+	logging.Infof("Matching orders in %s mode", mode)
+
+	if conf.UnsafeNoPuzzle {
+		if err = fredServer.UnsafeEnableNoPuzzle(); err != nil {
+			logging.Fatalf("Error enabling unsafe no-puzzle mode: \n%s", err)
+		}
+	}
+
+	fredServer.SetMaxConcurrentAuctions(conf.MaxAuctions)
+
+	var evictionPolicy cxauctionserver.EvictionPolicy
+	if evictionPolicy, err = cxauctionserver.EvictionPolicyFromString(conf.SolveEviction); err != nil {
+		logging.Fatalf("Error parsing solve eviction policy: \n%s", err)
+	}
+
+	if err = fredServer.SetMaxPendingSolves(conf.MaxPendingSolves, evictionPolicy); err != nil {
+		logging.Fatalf("Error setting max pending solves: \n%s", err)
+	}
+
+	var solvePriority cxauctionserver.SolvePriority
+	if solvePriority, err = cxauctionserver.SolvePriorityFromString(conf.SolvePriority); err != nil {
+		logging.Fatalf("Error parsing solve priority: \n%s", err)
+	}
+
+	if err = fredServer.SetSolvePriority(solvePriority); err != nil {
+		logging.Fatalf("Error setting solve priority: \n%s", err)
+	}
+
+	var nonceScope match.NonceScope
+	if nonceScope, err = match.NonceScopeFromString(conf.NonceScope); err != nil {
+		logging.Fatalf("Error parsing nonce scope: \n%s", err)
+	}
+
+	if err = fredServer.SetNonceScope(nonceScope); err != nil {
+		logging.Fatalf("Error setting nonce scope: \n%s", err)
+	}
+
+	var solveStart cxauctionserver.SolveStart
+	if solveStart, err = cxauctionserver.SolveStartFromString(conf.SolveStart); err != nil {
+		logging.Fatalf("Error parsing solve start: \n%s", err)
+	}
+
+	if err = fredServer.SetSolveStart(solveStart, conf.SolveTriggerCount); err != nil {
+		logging.Fatalf("Error setting solve start: \n%s", err)
+	}
+
+	var revealPolicy cxauctionserver.RevealPolicy
+	if revealPolicy, err = cxauctionserver.RevealPolicyFromString(conf.RevealPolicy); err != nil {
+		logging.Fatalf("Error parsing reveal policy: \n%s", err)
+	}
+
+	if err = fredServer.SetRevealPolicy(revealPolicy); err != nil {
+		logging.Fatalf("Error setting reveal policy: \n%s", err)
+	}
+
+	if err = fredServer.SetRevealGrace(conf.RevealGrace); err != nil {
+		logging.Fatalf("Error setting reveal grace period: \n%s", err)
+	}
+
+	if err = fredServer.SetMaxCiphertextCache(conf.MaxCiphertextCache); err != nil {
+		logging.Fatalf("Error setting max ciphertext cache: \n%s", err)
+	}
+
+	if err = fredServer.SetNextAuctionWindow(conf.NextAuctionWindow); err != nil {
+		logging.Fatalf("Error setting next auction window: \n%s", err)
+	}
+
+	if err = fredServer.SetWatchdogGrace(conf.WatchdogGrace); err != nil {
+		logging.Fatalf("Error setting watchdog grace period: \n%s", err)
+	}
+
+	var adminPubkeys []*koblitz.PublicKey
+	for _, adminString := range conf.AdminPubkeys {
+		var adminBytes []byte
+		if adminBytes, err = hex.DecodeString(adminString); err != nil {
+			logging.Fatalf("Error decoding admin pubkey: \n%s", err)
+		}
+
+		var adminPubkey *koblitz.PublicKey
+		if adminPubkey, err = koblitz.ParsePubKey(adminBytes, koblitz.S256()); err != nil {
+			logging.Fatalf("Error parsing admin pubkey: \n%s", err)
+		}
+		adminPubkeys = append(adminPubkeys, adminPubkey)
+	}
+	fredServer.SetAdminPubkeys(adminPubkeys)
+
+	if err = fredServer.SetAdminMetricsInterval(conf.AdminInterval); err != nil {
+		logging.Fatalf("Error setting admin metrics interval: \n%s", err)
+	}
+
+	if err = fredServer.SetBatchTimeout(conf.BatchTimeout); err != nil {
+		logging.Fatalf("Error setting batch timeout: \n%s", err)
+	}
+
+	if conf.WatchdogInterval > 0 {
+		if err = fredServer.StartWatchdog(conf.WatchdogInterval, conf.WatchdogRestart); err != nil {
+			logging.Fatalf("Error starting auction clock watchdog: \n%s", err)
+		}
+	}
+
+	if err = fredServer.SetCommitmentInterval(conf.CommitmentInterval); err != nil {
+		logging.Fatalf("Error setting commitment interval: \n%s", err)
+	}
+
+	if err = fredServer.SetPricePrecision(conf.PricePrecision); err != nil {
+		logging.Fatalf("Error setting price precision: \n%s", err)
+	}
+
+	if err = fredServer.SetMaxDepthLevels(conf.MaxDepthLevels); err != nil {
+		logging.Fatalf("Error setting max depth levels: \n%s", err)
+	}
+
+	fredServer.SetMaxAmountRatio(conf.MaxAmountRatio)
+	if err = fredServer.SetMaxAuctionPubkeys(conf.MaxAuctionPubkeys); err != nil {
+		logging.Fatalf("Error setting max auction pubkeys: \n%s", err)
+	}
+	fredServer.SetRejectionAlertThreshold(conf.RejectionAlert)
+
+	if err = fredServer.SetVerifyWorkers(conf.VerifyWorkers); err != nil {
+		logging.Fatalf("Error setting verify workers: \n%s", err)
+	}
+
+	// Sign things like the server time with the exchange key, so clients can check them
+	signingKey, _ := koblitz.PrivKeyFromBytes(koblitz.S256(), key[:])
+	if err = fredServer.SetSigningKey(signingKey); err != nil {
+		logging.Fatalf("Error setting signing key: \n%s", err)
+	}
+	fredServer.SetSignResults(!conf.NoSignResults)
+
+	// Set the price bands for any pairs that have them
+	for _, bandString := range conf.PriceBands {
+		var pair *match.Pair
+		var band *match.PriceBand
+		if pair, band, err = match.ParsePairPriceBand(bandString); err != nil {
+			logging.Fatalf("Error parsing price band: \n%s", err)
+		}
+
+		if err = fredServer.SetPriceBand(*pair, band); err != nil {
+			logging.Fatalf("Error setting price band: \n%s", err)
+		}
+	}
+
+	// Reserve prices come from an HTTP oracle if there is one, otherwise from the static prices
+	var oracle match.Oracle
+	if conf.OracleURL != "" {
+		if oracle, err = match.NewHTTPOracle(conf.OracleURL, conf.OracleTimeout); err != nil {
+			logging.Fatalf("Error creating HTTP oracle: \n%s", err)
+		}
+	} else if len(conf.OraclePrices) > 0 {
+		staticOracle := match.NewStaticOracle()
+		for _, priceString := range conf.OraclePrices {
+			var pair *match.Pair
+			var price *big.Rat
+			if pair, price, err = match.ParsePairOraclePrice(priceString); err != nil {
+				logging.Fatalf("Error parsing oracle price: \n%s", err)
+			}
+
+			if err = staticOracle.SetPrice(*pair, price); err != nil {
+				logging.Fatalf("Error setting oracle price: \n%s", err)
+			}
+		}
+		oracle = staticOracle
+	}
+
+	if oracle != nil {
+		if err = fredServer.SetOracle(oracle, conf.OracleDeviation); err != nil {
+			logging.Fatalf("Error setting oracle: \n%s", err)
+		}
+	}
+
+	var pairs []*match.Pair
+	if pairs, err = match.GenerateAssetPairs(coinList); err != nil {
+		logging.Fatalf("Error generating pairs: \n%s", err)
+	}
+
+	if !conf.NoAutoClear {
+		fredServer.SetClearingPairs(pairs)
+	}
+
+	// Pick up the auction that was open when we last stopped, once everything it uses is set
+	if conf.OrderLogDir != "" {
+		if err = fredServer.SetOrderLog(filepath.Join(conf.FredHomeDir, conf.OrderLogDir)); err != nil {
+			logging.Fatalf("Error setting order log: \n%s", err)
+		}
+
+		var replayed int
+		if replayed, err = fredServer.ReplayOrderLog(pairs); err != nil {
+			logging.Fatalf("Error replaying order log: \n%s", err)
+		}
+		if replayed > 0 {
+			logging.Infof("Replayed %d orders from the order log", replayed)
+		}
+	}
+
+	if conf.PairAuctions {
+		for _, pair := range pairs {
+			if err = fredServer.StartPairAuction(pair, conf.AuctionTime); err != nil {
+				logging.Fatalf("Error starting pair auction: \n%s", err)
+			}
+		}
+	}
+
 	// Register RPC Commands and set server
 	rpc1 := new(cxauctionrpc.OpencxAuctionRPC)
 	rpc1.OffButton = make(chan bool, 1)
 	rpc1.Server = fredServer
+	rpc1.ListenerConfig = util.ListenerConfig{
+		Backlog:   conf.ListenBacklog,
+		KeepAlive: conf.KeepAlive,
+	}
+	rpc1.ParamCacheTTL = conf.ParamCacheTTL
 
 	// SIGINT and SIGTERM and SIGQUIT handler for CTRL-c, KILL, CTRL-/, etc.
 	go func() {
@@ -165,8 +505,15 @@ func main() {
 			signal := <-sigs
 			logging.Infof("Received %s signal, Stopping server gracefully...", signal.String())
 
-			// send off button to off button
-			rpc1.OffButton <- true
+			// finish solving the orders we have before the listeners stop and fred exits
+			if unsolved, err := fredServer.Shutdown(conf.ShutdownTimeout); err != nil {
+				logging.Errorf("Error waiting for orders to be solved: \n%s", err)
+			} else if len(unsolved) > 0 {
+				logging.Errorf("Stopping with %d orders not solved, they are still in the puzzle book", len(unsolved))
+			}
+
+			// close the off button so every listener stops
+			close(rpc1.OffButton)
 
 			return
 		}
@@ -184,11 +531,34 @@ func main() {
 		go cxauctionrpc.NoiseListenAsync(doneChan, privkey, rpc1, conf.Rpchost, conf.Rpcport)
 	}
 
-	go func() {
-		log.Println(http.ListenAndServe("localhost:6060", nil))
-	}()
+	httpDoneChan := make(chan bool, 1)
+	if conf.HTTPPort != 0 {
+		logging.Infof(" === will start to listen on http ===")
+		go cxauctionrpc.HTTPListenAsync(httpDoneChan, rpc1, conf.Rpchost, conf.HTTPPort)
+	} else {
+		httpDoneChan <- true
+	}
+
+	var pprofServer *http.Server
+	if pprofServer, _, err = startPprofServer(defaultPprofAddr); err != nil {
+		logging.Fatalf("Error starting pprof server: \n%s", err)
+	}
+
+	if conf.HeapProfileThreshold != 0 {
+		var watcher *heapWatcher
+		if watcher, err = newHeapWatcher(conf.FredHomeDir, conf.HeapProfileThreshold); err != nil {
+			logging.Fatalf("Error creating heap watcher: \n%s", err)
+		}
+		watcher.start(defaultHeapCheckInterval)
+		logging.Infof("Writing a heap profile to %s when the heap uses more than %d bytes", conf.FredHomeDir, conf.HeapProfileThreshold)
+	}
 
 	<-doneChan
+	<-httpDoneChan
+
+	if err = stopPprofServer(pprofServer); err != nil {
+		logging.Errorf("Error stopping pprof server: \n%s", err)
+	}
 
 	return
 }
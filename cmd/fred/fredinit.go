@@ -24,6 +24,7 @@ var (
 	defaultConfigFilename = "fred.conf"
 	defaultLogFilename    = "dblog.txt"
 	defaultKeyFileName    = "privkey.hex"
+	defaultOrderLogDir    = "orderlog"
 )
 
 // createDefaultConfigFile creates a config file  -- only call this if the
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+)
+
+// defaultHeapCheckInterval is how often the heap is checked against the heap profile threshold
+var defaultHeapCheckInterval = 10 * time.Second
+
+// heapWatcher writes a heap profile when the heap grows past a threshold, so memory problems can be
+// looked at after the fact. Only one profile is written each time the heap crosses the threshold, it
+// has to go back under before another one is written.
+type heapWatcher struct {
+	// dir is the directory profiles are written to
+	dir string
+	// threshold is how many bytes the heap can use before a profile is written
+	threshold uint64
+	// heapInUse returns how many bytes the heap is using
+	heapInUse func() uint64
+
+	// over is whether the heap was over the threshold the last time it was checked
+	over bool
+	mtx  *sync.Mutex
+}
+
+// newHeapWatcher returns a heap watcher that writes heap profiles to dir when the heap uses more than
+// threshold bytes
+func newHeapWatcher(dir string, threshold uint64) (hw *heapWatcher, err error) {
+	if threshold == 0 {
+		err = fmt.Errorf("Heap profile threshold must be positive")
+		return
+	}
+
+	hw = &heapWatcher{
+		dir:       dir,
+		threshold: threshold,
+		heapInUse: runtimeHeapInUse,
+		mtx:       new(sync.Mutex),
+	}
+	return
+}
+
+// runtimeHeapInUse returns how many bytes the heap is using, according to the runtime
+func runtimeHeapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+// start checks the heap every interval in a goroutine
+func (hw *heapWatcher) start(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if _, err := hw.check(); err != nil {
+				logging.Errorf("Error checking heap: %s", err)
+			}
+		}
+	}()
+	return
+}
+
+// check writes a heap profile if the heap just went over the threshold, returning the path of the
+// profile. If no profile was written the path is empty.
+func (hw *heapWatcher) check() (path string, err error) {
+	inUse := hw.heapInUse()
+
+	hw.mtx.Lock()
+	defer hw.mtx.Unlock()
+
+	if inUse <= hw.threshold {
+		hw.over = false
+		return
+	}
+
+	if hw.over {
+		return
+	}
+	hw.over = true
+
+	if path, err = hw.write(); err != nil {
+		err = fmt.Errorf("Error writing heap profile with %d bytes in use: %s", inUse, err)
+		return
+	}
+
+	logging.Warnf("Heap is using %d bytes, over the threshold of %d, wrote heap profile to %s", inUse, hw.threshold, path)
+	return
+}
+
+// write writes a heap profile to a new file in the profile directory
+func (hw *heapWatcher) write() (path string, err error) {
+	path = filepath.Join(hw.dir, fmt.Sprintf("heap-%s.pprof", time.Now().Format("20060102-150405.000000000")))
+
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+		return
+	}
+
+	if err = pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return
+	}
+
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestHeapWatcherWritesProfile(t *testing.T) {
+	var err error
+
+	var dir string
+	if dir, err = ioutil.TempDir("", "fredheap"); err != nil {
+		t.Errorf("Error creating profile directory: %s", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err = newHeapWatcher(dir, 0); err == nil {
+		t.Errorf("Heap watcher with no threshold should not be created")
+		return
+	}
+
+	var hw *heapWatcher
+	if hw, err = newHeapWatcher(dir, 1000); err != nil {
+		t.Errorf("Error creating heap watcher: %s", err)
+		return
+	}
+
+	// Mock the heap so we decide when it crosses the threshold
+	var inUse uint64 = 500
+	hw.heapInUse = func() uint64 { return inUse }
+
+	var path string
+	if path, err = hw.check(); err != nil || path != "" {
+		t.Errorf("No profile should be written under the threshold, got %q, %v", path, err)
+		return
+	}
+
+	inUse = 2000
+	if path, err = hw.check(); err != nil {
+		t.Errorf("Error checking heap over the threshold: %s", err)
+		return
+	}
+
+	var info os.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		t.Errorf("Heap profile should have been written to %q: %s", path, err)
+		return
+	}
+
+	if info.Size() == 0 {
+		t.Errorf("Heap profile should not be empty")
+		return
+	}
+
+	// Staying over the threshold shouldn't keep writing profiles
+	if path, err = hw.check(); err != nil || path != "" {
+		t.Errorf("Only one profile should be written while the heap stays over the threshold, got %q, %v", path, err)
+		return
+	}
+
+	// Going back under and over again writes another one
+	inUse = 500
+	if _, err = hw.check(); err != nil {
+		t.Errorf("Error checking heap under the threshold: %s", err)
+		return
+	}
+
+	inUse = 3000
+	if path, err = hw.check(); err != nil || path == "" {
+		t.Errorf("Profile should be written when the heap crosses the threshold again, got %q, %v", path, err)
+		return
+	}
+
+	var files []os.FileInfo
+	if files, err = ioutil.ReadDir(dir); err != nil {
+		t.Errorf("Error reading profile directory: %s", err)
+		return
+	}
+
+	if len(files) != 2 {
+		t.Errorf("Expected 2 heap profiles, got %d", len(files))
+		return
+	}
+
+	return
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+)
+
+var (
+	defaultPprofAddr = "localhost:6060"
+
+	// pprofShutdownTimeout is how long we wait for pprof requests to finish when stopping
+	pprofShutdownTimeout = 5 * time.Second
+)
+
+// startPprofServer starts serving pprof on addr in a goroutine. The server should be stopped with
+// stopPprofServer, which frees up the listener.
+func startPprofServer(addr string) (pprofServer *http.Server, listener net.Listener, err error) {
+	if listener, err = net.Listen("tcp", addr); err != nil {
+		err = fmt.Errorf("Error listening for pprof server: %s", err)
+		return
+	}
+
+	// net/http/pprof registers its handlers on the default mux
+	pprofServer = &http.Server{
+		Handler: http.DefaultServeMux,
+	}
+
+	logging.Infof("Running pprof server on %s", listener.Addr().String())
+	go func() {
+		if serveErr := pprofServer.Serve(listener); serveErr != nil && serveErr != http.ErrServerClosed {
+			logging.Errorf("Error serving pprof: %s", serveErr)
+		}
+	}()
+
+	return
+}
+
+// stopPprofServer gracefully shuts down the pprof server, closing its listener
+func stopPprofServer(pprofServer *http.Server) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pprofShutdownTimeout)
+	defer cancel()
+
+	if err = pprofServer.Shutdown(ctx); err != nil {
+		err = fmt.Errorf("Error shutting down pprof server: %s", err)
+		return
+	}
+
+	return
+}
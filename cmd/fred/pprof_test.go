@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestPprofServerStartStop(t *testing.T) {
+	var err error
+
+	var pprofServer *http.Server
+	var listener net.Listener
+	if pprofServer, listener, err = startPprofServer("localhost:0"); err != nil {
+		t.Errorf("Error starting pprof server: %s", err)
+		return
+	}
+	addr := listener.Addr().String()
+
+	var resp *http.Response
+	if resp, err = http.Get("http://" + addr + "/debug/pprof/"); err != nil {
+		t.Errorf("Error getting pprof index: %s", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected pprof index to return %d, got %d", http.StatusOK, resp.StatusCode)
+		return
+	}
+
+	if err = stopPprofServer(pprofServer); err != nil {
+		t.Errorf("Error stopping pprof server: %s", err)
+		return
+	}
+
+	// The port should be free again, so we should be able to start another one on it
+	if pprofServer, _, err = startPprofServer(addr); err != nil {
+		t.Errorf("Port %s was not released after stopping pprof server: %s", addr, err)
+		return
+	}
+
+	if err = stopPprofServer(pprofServer); err != nil {
+		t.Errorf("Error stopping restarted pprof server: %s", err)
+		return
+	}
+
+	return
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/mit-dci/lit/lnutil"
 	"github.com/mit-dci/opencx/cxrpc"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
 )
 
 var registerCommand = &Command{
@@ -28,7 +29,7 @@ func (cl *ocxClient) Register(args []string) (err error) {
 	}
 
 	var sig []byte
-	if sig, err = cl.SignBytes([]byte(regStringReply.RegistrationString)); err != nil {
+	if sig, err = cl.SignBytes(match.RegistrationDomain, []byte(regStringReply.RegistrationString)); err != nil {
 		return
 	}
 
@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"strconv"
 
-	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/lit/lnutil"
 	"github.com/mit-dci/opencx/cxauctionrpc"
+	"github.com/mit-dci/opencx/cxauctionserver"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
 )
 
 var placeAuctionOrderCommand = &Command{
@@ -19,6 +20,33 @@ var placeAuctionOrderCommand = &Command{
 	ShortDescription: fmt.Sprintf("%s\n", "Place a front-running resistant order on the exchange."),
 }
 
+var cancelAllOrdersCommand = &Command{
+	Format: fmt.Sprintf("%s\n", lnutil.Red("cancelallorders")),
+	Description: fmt.Sprintf("%s\n%s\n",
+		"Cancel all of your pending front-running resistant orders in the current auction.",
+		"Orders that the exchange hasn't finished solving yet can't be cancelled.",
+	),
+	ShortDescription: fmt.Sprintf("%s\n", "Cancel all of your pending auction orders."),
+}
+
+var cancelEverythingCommand = &Command{
+	Format: fmt.Sprintf("%s\n", lnutil.Red("canceleverything")),
+	Description: fmt.Sprintf("%s\n%s\n",
+		"Cancel all of your pending front-running resistant orders, including the ones for the next auction.",
+		"Orders that the exchange hasn't finished solving yet are dropped once they're solved.",
+	),
+	ShortDescription: fmt.Sprintf("%s\n", "Cancel all of your pending auction orders, in every auction."),
+}
+
+var getReturnedOrdersCommand = &Command{
+	Format: fmt.Sprintf("%s\n", lnutil.Red("getreturnedorders")),
+	Description: fmt.Sprintf("%s\n%s\n",
+		"Get your auction orders that didn't match, and the reason each one didn't match.",
+		"Each returned order is only given back once.",
+	),
+	ShortDescription: fmt.Sprintf("%s\n", "Get your unmatched auction orders."),
+}
+
 // OrderCommand submits an order (for now)
 func (cl *ocxClient) AuctionOrderCommand(args []string) (err error) {
 	if err = cl.UnlockKey(); err != nil {
@@ -40,20 +68,33 @@ func (cl *ocxClient) AuctionOrderCommand(args []string) (err error) {
 		return
 	}
 
-	var pubkey *koblitz.PublicKey
-	if pubkey, err = cl.RetrievePublicKey(); err != nil {
+	// The pair might run its own auction, so get the params for its auction
+	var tradingPair match.Pair
+	if err = tradingPair.FromString(pair); err != nil {
+		err = fmt.Errorf("Error parsing pair, please enter something valid: \n%s", err)
 		return
 	}
 
 	var paramreply *cxauctionrpc.GetPublicParametersReply
-	if paramreply, err = cl.RPCClient.GetPublicParameters(); err != nil {
+	if paramreply, err = cl.RPCClient.GetPairPublicParameters(tradingPair); err != nil {
 		err = fmt.Errorf("Error getting public parameters before placing auction order: %s", err)
 		return
 	}
 
+	// In continuous mode there's no puzzle, the order gets matched as soon as the exchange gets it
+	if paramreply.MatchingMode == cxauctionserver.ContinuousMatching {
+		var reply *cxauctionrpc.SubmitContinuousOrderReply
+		if reply, err = cl.RPCClient.ContinuousOrderCommand(side, pair, amountHave, price, paramreply.AuctionID, paramreply.Network); err != nil {
+			return
+		}
+
+		logging.Infof("Successfully placed continuous order %s", reply.OrderID)
+		return
+	}
+
 	// we ignore reply because there's nothing in it and we don't use it
 	// var reply *cxauctionrpc.SubmitPuzzledOrderReply
-	if _, err = cl.RPCClient.AuctionOrderCommand(pubkey, side, pair, amountHave, price, paramreply.AuctionTime, paramreply.AuctionID); err != nil {
+	if _, err = cl.RPCClient.AuctionOrderCommand(side, pair, amountHave, price, &paramreply.PuzzleParams, paramreply.AuctionID, paramreply.Network); err != nil {
 		return
 	}
 
@@ -61,3 +102,72 @@ func (cl *ocxClient) AuctionOrderCommand(args []string) (err error) {
 
 	return
 }
+
+// CancelAllOrders cancels all of the user's pending orders in the current auction
+func (cl *ocxClient) CancelAllOrders(args []string) (err error) {
+	if err = cl.UnlockKey(); err != nil {
+		logging.Fatalf("Could not unlock key! Fatal!")
+	}
+
+	var paramreply *cxauctionrpc.GetPublicParametersReply
+	if paramreply, err = cl.RPCClient.GetPublicParameters(); err != nil {
+		err = fmt.Errorf("Error getting public parameters before cancelling orders: %s", err)
+		return
+	}
+
+	var reply *cxauctionrpc.CancelAllOrdersReply
+	if reply, err = cl.RPCClient.CancelAllOrders(paramreply.AuctionID); err != nil {
+		return
+	}
+
+	logging.Infof("Successfully cancelled %d orders", len(reply.CancelledOrders))
+
+	return
+}
+
+// CancelEverything cancels all of the user's pending orders, including the ones for the next auction
+func (cl *ocxClient) CancelEverything(args []string) (err error) {
+	if err = cl.UnlockKey(); err != nil {
+		logging.Fatalf("Could not unlock key! Fatal!")
+	}
+
+	var paramreply *cxauctionrpc.GetPublicParametersReply
+	if paramreply, err = cl.RPCClient.GetPublicParameters(); err != nil {
+		err = fmt.Errorf("Error getting public parameters before cancelling everything: %s", err)
+		return
+	}
+
+	var reply *cxauctionrpc.CancelEverythingReply
+	if reply, err = cl.RPCClient.CancelEverything(paramreply.AuctionID); err != nil {
+		return
+	}
+
+	logging.Infof("Successfully cancelled everything, including %d orders in the current auction", len(reply.CancelledOrders))
+
+	return
+}
+
+// GetReturnedOrders gets the user's auction orders that didn't match, and prints why
+func (cl *ocxClient) GetReturnedOrders(args []string) (err error) {
+	if err = cl.UnlockKey(); err != nil {
+		logging.Fatalf("Could not unlock key! Fatal!")
+	}
+
+	var paramreply *cxauctionrpc.GetPublicParametersReply
+	if paramreply, err = cl.RPCClient.GetPublicParameters(); err != nil {
+		err = fmt.Errorf("Error getting public parameters before getting returned orders: %s", err)
+		return
+	}
+
+	var reply *cxauctionrpc.GetReturnedOrdersReply
+	if reply, err = cl.RPCClient.GetReturnedOrders(paramreply.AuctionID); err != nil {
+		return
+	}
+
+	for _, returnedOrder := range reply.ReturnedOrders {
+		logging.Infof("Order %s %d %s at price %f returned: %s", returnedOrder.Order.Side, returnedOrder.Order.AmountHave, returnedOrder.Order.TradingPair.PrettyString(), returnedOrder.Order.OrderbookPrice, returnedOrder.Reason)
+	}
+	logging.Infof("Got %d returned orders", len(reply.ReturnedOrders))
+
+	return
+}
@@ -8,8 +8,6 @@ import (
 	"github.com/mit-dci/lit/btcutil/hdkeychain"
 	"github.com/mit-dci/lit/coinparam"
 
-	"golang.org/x/crypto/sha3"
-
 	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/lit/portxo"
 
@@ -18,6 +16,7 @@ import (
 	flags "github.com/jessevdk/go-flags"
 	"github.com/mit-dci/lit/lnutil"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
 )
 
 type ocxClient struct {
@@ -160,12 +159,10 @@ func (cl *ocxClient) UnlockKey() (err error) {
 
 // SignBytes is used in the register method because that's an interactive process.
 // BenchClient shouldn't be responsible for interactive stuff, just providing a good
-// Go API for the RPC methods the exchange offers.
-func (cl *ocxClient) SignBytes(bytes []byte) (signature []byte, err error) {
+// Go API for the RPC methods the exchange offers. The bytes are signed in domain.
+func (cl *ocxClient) SignBytes(domain match.SigDomain, bytes []byte) (signature []byte, err error) {
 
-	sha := sha3.New256()
-	sha.Write(bytes)
-	e := sha.Sum(nil)
+	e := domain.SigHash(bytes)
 
 	if signature, err = koblitz.SignCompact(koblitz.S256(), cl.RPCClient.PrivKey, e, false); err != nil {
 		logging.Errorf("Failed to sign bytes.")
@@ -199,6 +199,42 @@ func (cl *ocxClient) parseCommands(commands []string) error {
 			return fmt.Errorf("Error placing auction order: \n%s", err)
 		}
 	}
+	if cmd == "cancelallorders" {
+		if getHelpForCommand(cancelAllOrdersCommand, args) {
+			return nil
+		}
+		if len(args) != 0 {
+			return fmt.Errorf("Don't specify arguments please")
+		}
+
+		if err := cl.CancelAllOrders(args); err != nil {
+			return fmt.Errorf("Error cancelling all orders: \n%s", err)
+		}
+	}
+	if cmd == "canceleverything" {
+		if getHelpForCommand(cancelEverythingCommand, args) {
+			return nil
+		}
+		if len(args) != 0 {
+			return fmt.Errorf("Don't specify arguments please")
+		}
+
+		if err := cl.CancelEverything(args); err != nil {
+			return fmt.Errorf("Error cancelling everything: \n%s", err)
+		}
+	}
+	if cmd == "getreturnedorders" {
+		if getHelpForCommand(getReturnedOrdersCommand, args) {
+			return nil
+		}
+		if len(args) != 0 {
+			return fmt.Errorf("Don't specify arguments please")
+		}
+
+		if err := cl.GetReturnedOrders(args); err != nil {
+			return fmt.Errorf("Error getting returned orders: \n%s", err)
+		}
+	}
 	return nil
 }
 
@@ -212,7 +248,7 @@ func (cl *ocxClient) Help(textArgs []string) error {
 	if len(textArgs) == 0 {
 
 		fmt.Fprintf(color.Output, lnutil.Header("Commands:\n"))
-		listofCommands := []*Command{helpCommand, registerCommand, getBalanceCommand, getDepositAddressCommand, getAllBalancesCommand, withdrawCommand, litWithdrawCommand, getLitConnectionCommand, placeOrderCommand, getPriceCommand, viewOrderbookCommand, cancelOrderCommand, getPairsCommand, placeAuctionOrderCommand}
+		listofCommands := []*Command{helpCommand, registerCommand, getBalanceCommand, getDepositAddressCommand, getAllBalancesCommand, withdrawCommand, litWithdrawCommand, getLitConnectionCommand, placeOrderCommand, getPriceCommand, viewOrderbookCommand, cancelOrderCommand, getPairsCommand, placeAuctionOrderCommand, cancelAllOrdersCommand, cancelEverythingCommand, getReturnedOrdersCommand}
 		printHelp(listofCommands)
 		return nil
 	}
@@ -1,18 +1,23 @@
 package main
 
 import (
+	"encoding/hex"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/mit-dci/lit/crypto/koblitz"
 
 	flags "github.com/jessevdk/go-flags"
+	"github.com/mit-dci/opencx/cxdb"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
 	"github.com/mit-dci/opencx/cxdb/cxdbsql"
 	"github.com/mit-dci/opencx/cxrpc"
 	"github.com/mit-dci/opencx/cxserver"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
 	"github.com/mit-dci/opencx/util"
 )
 
@@ -27,6 +32,10 @@ type opencxConfig struct {
 	Rpcport uint16 `short:"p" long:"rpcport" description:"Set RPC port to connect to"`
 	Rpchost string `long:"rpchost" description:"Set RPC host to listen to"`
 
+	// stuff for the rpc listener
+	ListenBacklog int           `long:"listenbacklog" description:"Most RPC connections that can be waiting to be accepted. 0 means the OS default"`
+	KeepAlive     time.Duration `long:"keepalive" description:"TCP keep-alive period for RPC connections, like 30s. 0 means the default, negative turns keep-alive off"`
+
 	// logging and debug parameters
 	LogLevel []bool `short:"v" description:"Set verbosity level to verbose (-v), very verbose (-vv) or very very verbose (-vvv)"`
 
@@ -67,6 +76,17 @@ type opencxConfig struct {
 	DBPassword string `long:"dbpassword" description:"database password"`
 	DBHost     string `long:"dbhost" description:"Host for the database connection"`
 	DBPort     uint16 `long:"dbport" description:"Port for the database connection"`
+
+	// whether deposit addresses are reused
+	AddressPolicy string `long:"addresspolicy" description:"Deposit address policy. reuse gives users the same address every time, fresh gives a new address for every deposit"`
+
+	// settlement fees
+	FeeRate    uint64   `long:"feerate" description:"Fee in basis points taken out of every amount credited during settlement"`
+	FeeAccount string   `long:"feeaccount" description:"Hex encoded compressed pubkey of the account fees are collected in. Defaults to the exchange key"`
+	FeeAssets  []string `long:"feeasset" description:"Which asset of a pair fees are taken in, like asset1/asset2:base or asset1/asset2:quote. Pairs that aren't set take fees in the asset received. Can be set more than once"`
+
+	// matching-only mode for benchmarking
+	MatchingOnly bool `long:"matchingonly" description:"Only run matching in memory, without persistence or settlement. Used for benchmarking the matching engine"`
 }
 
 var (
@@ -92,6 +112,9 @@ var (
 	defaultDBPassword = "testpass"
 	defaultDBHost     = "localhost"
 	defaultDBPort     = uint16(3306)
+
+	// how often to log throughput metrics in matching-only mode
+	defaultMetricsInterval = 10 * time.Second
 )
 
 // newConfigParser returns a new command line flags parser.
@@ -117,35 +140,109 @@ func main() {
 		DBPassword:       defaultDBPassword,
 		DBHost:           defaultDBHost,
 		DBPort:           defaultDBPort,
+		AddressPolicy:    cxserver.ReuseAddress.String(),
 	}
 
 	// Check and load config params
 	key := opencxSetup(&conf)
 
-	var db *cxdbsql.DB
-	if db, err = cxdbsql.CreateDBConnection(conf.DBUsername, conf.DBPassword, conf.DBHost, conf.DBPort); err != nil {
-		logging.Fatalf("Error initializing Database: \n%s", err)
-	}
-
 	// Generate the coin list based on the parameters we know
 	coinList := generateCoinList(&conf)
 
-	// Setup DB Client
-	if err = db.SetupClient(coinList); err != nil {
-		log.Fatalf("Error setting up sql client: \n%s", err)
+	// Nobody could deposit to an exchange without wallets, so don't start one
+	if !conf.MatchingOnly && len(coinList) == 0 {
+		logging.Fatalf("Error starting exchange, enable a coin: \n%s", cxserver.ErrNoWallets)
 	}
 
-	// defer the db closing to when we stop
-	defer db.DBHandler.Close()
+	var store cxdb.OpencxStore
+	if conf.MatchingOnly {
+		// Nothing gets persisted or settled, so there's no database and no lightning
+		logging.Infof("Running in matching-only mode, orders will not be persisted or settled")
+		conf.LightningSupport = false
+
+		matchingDB := new(cxdbmemory.CXDBMatchingOnly)
+		if err = matchingDB.SetupClient(coinList); err != nil {
+			logging.Fatalf("Error setting up matching-only store: \n%s", err)
+		}
+
+		// log throughput metrics periodically, and once more when we stop
+		go func() {
+			for range time.Tick(defaultMetricsInterval) {
+				logging.Infof("Matching metrics: %s", matchingDB.Metrics().String())
+			}
+		}()
+		defer func() {
+			logging.Infof("Final matching metrics: %s", matchingDB.Metrics().String())
+		}()
+
+		store = matchingDB
+	} else {
+		var db *cxdbsql.DB
+		if db, err = cxdbsql.CreateDBConnection(conf.DBUsername, conf.DBPassword, conf.DBHost, conf.DBPort); err != nil {
+			logging.Fatalf("Error initializing Database: \n%s", err)
+		}
+
+		// Setup DB Client
+		if err = db.SetupClient(coinList); err != nil {
+			log.Fatalf("Error setting up sql client: \n%s", err)
+		}
+
+		// defer the db closing to when we stop
+		defer db.DBHandler.Close()
+
+		store = db
+	}
 
 	// Anyways, here's where we set the server
-	ocxServer := cxserver.InitServer(db, conf.OpencxHomeDir, conf.Rpcport, coinList)
+	ocxServer := cxserver.InitServer(store, conf.OpencxHomeDir, conf.Rpcport, coinList)
+
+	var addressPolicy cxserver.AddressPolicy
+	if addressPolicy, err = cxserver.AddressPolicyFromString(conf.AddressPolicy); err != nil {
+		logging.Fatalf("Error parsing address policy: \n%s", err)
+	}
+
+	if err = ocxServer.SetAddressPolicy(addressPolicy); err != nil {
+		logging.Fatalf("Error setting address policy: \n%s", err)
+	}
 
 	// Check that the private key exists and if it does, load it
 	if err = ocxServer.SetupServerKeys(key); err != nil {
 		logging.Fatalf("Error setting up server keys: \n%s", err)
 	}
 
+	if conf.FeeRate != 0 || conf.FeeAccount != "" {
+		var feeAccount *koblitz.PublicKey
+		if conf.FeeAccount == "" {
+			_, feeAccount = koblitz.PrivKeyFromBytes(koblitz.S256(), key[:])
+		} else {
+			var feeAccountBytes []byte
+			if feeAccountBytes, err = hex.DecodeString(conf.FeeAccount); err != nil {
+				logging.Fatalf("Error decoding fee account: \n%s", err)
+			}
+			if feeAccount, err = koblitz.ParsePubKey(feeAccountBytes, koblitz.S256()); err != nil {
+				logging.Fatalf("Error parsing fee account pubkey: \n%s", err)
+			}
+		}
+
+		if err = ocxServer.SetFeeAccount(conf.FeeRate, feeAccount); err != nil {
+			logging.Fatalf("Error setting fee account: \n%s", err)
+		}
+		logging.Infof("Collecting a %d basis point fee in account %x", conf.FeeRate, feeAccount.SerializeCompressed())
+
+		for _, feeAssetString := range conf.FeeAssets {
+			var pair *match.Pair
+			var feeAsset match.FeeAsset
+			if pair, feeAsset, err = match.ParsePairFeeAsset(feeAssetString); err != nil {
+				logging.Fatalf("Error parsing fee asset: \n%s", err)
+			}
+
+			if err = ocxServer.SetPairFeeAsset(pair, feeAsset); err != nil {
+				logging.Fatalf("Error setting fee asset: \n%s", err)
+			}
+			logging.Infof("Taking fees for %s in the %s asset", pair.PrettyString(), feeAsset)
+		}
+	}
+
 	if conf.LightningSupport {
 		// start the lit node for the exchange
 		if err = ocxServer.SetupLitNode(key, "lit", "http://hubris.media.mit.edu:46580", "", ""); err != nil {
@@ -200,6 +297,10 @@ func main() {
 	rpc1 := new(cxrpc.OpencxRPC)
 	rpc1.OffButton = make(chan bool, 1)
 	rpc1.Server = ocxServer
+	rpc1.ListenerConfig = util.ListenerConfig{
+		Backlog:   conf.ListenBacklog,
+		KeepAlive: conf.KeepAlive,
+	}
 
 	// SIGINT and SIGTERM and SIGQUIT handler for CTRL-c, KILL, CTRL-/, etc.
 	go func() {
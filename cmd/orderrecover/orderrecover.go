@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/mit-dci/opencx/logging"
+)
+
+type recoverConfig struct {
+	OrderFile string `long:"orders" short:"o" required:"true" description:"File with one hex serialized encrypted auction order per line"`
+	KeyFile   string `long:"keys" short:"k" required:"true" description:"File with the hex key for the order on the same line of the orders file, or - if the key isn't known"`
+
+	// Verify checks the signatures of recovered orders
+	Verify bool `long:"verify" description:"Check the signature of every recovered order"`
+}
+
+func main() {
+	var err error
+
+	conf := new(recoverConfig)
+	if _, err = flags.NewParser(conf, flags.Default).Parse(); err != nil {
+		os.Exit(1)
+	}
+
+	var orderFile *os.File
+	if orderFile, err = os.Open(conf.OrderFile); err != nil {
+		logging.Fatalf("Error opening orders file: %s", err)
+	}
+	defer orderFile.Close()
+
+	var keyFile *os.File
+	if keyFile, err = os.Open(conf.KeyFile); err != nil {
+		logging.Fatalf("Error opening keys file: %s", err)
+	}
+	defer keyFile.Close()
+
+	var recovered []*recoveredOrder
+	if recovered, err = recoverOrders(orderFile, keyFile); err != nil {
+		logging.Fatalf("Error recovering orders: %s", err)
+	}
+
+	for _, result := range recovered {
+		fmt.Println(result)
+		if conf.Verify && result.Err == nil {
+			var valid bool
+			if valid, err = result.Order.VerifySignature(); err != nil {
+				fmt.Printf("%d: invalid signature: %s\n", result.Line, err)
+			} else if !valid {
+				fmt.Printf("%d: invalid signature: not made by pubkey %x\n", result.Line, result.Order.Pubkey)
+			}
+		}
+	}
+
+	return
+}
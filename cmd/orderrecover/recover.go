@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+// unknownKey is what goes in the keys file for an order whose key hasn't been revealed
+const unknownKey = "-"
+
+// recoveredOrder is the result of decrypting one of the orders in the dump
+type recoveredOrder struct {
+	// Line is the line of the dump the order was on, starting from 1
+	Line  int
+	Order *match.AuctionOrder
+	// Err is why the order couldn't be recovered, if it couldn't be
+	Err error
+}
+
+// String is the tostring function for a recovered order
+func (r *recoveredOrder) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%d: could not recover order: %s", r.Line, r.Err)
+	}
+	return fmt.Sprintf("%d: %s", r.Line, r.Order)
+}
+
+// readHexLines reads every non empty line of r, trimming whitespace
+func readHexLines(r io.Reader) (lines []string, err error) {
+	scanner := bufio.NewScanner(r)
+	// serialized orders with big puzzles can be longer than the default max line
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err = scanner.Err(); err != nil {
+		err = fmt.Errorf("Error reading lines: %s", err)
+		return
+	}
+
+	return
+}
+
+// recoverOrders decrypts a dump of encrypted auction orders with their revealed keys, without solving any
+// puzzles. orderDump has one hex serialized encrypted auction order per line, and keyDump has the hex key for
+// the order on the same line, or a - if that key isn't known. Orders that can't be decrypted are returned with
+// the reason, so one bad order doesn't stop the rest from being recovered.
+func recoverOrders(orderDump io.Reader, keyDump io.Reader) (recovered []*recoveredOrder, err error) {
+	var orderLines []string
+	if orderLines, err = readHexLines(orderDump); err != nil {
+		err = fmt.Errorf("Error reading order dump: %s", err)
+		return
+	}
+
+	var keyLines []string
+	if keyLines, err = readHexLines(keyDump); err != nil {
+		err = fmt.Errorf("Error reading keys: %s", err)
+		return
+	}
+
+	if len(keyLines) != len(orderLines) {
+		err = fmt.Errorf("There are %d orders but %d keys, there should be a key or %s for every order", len(orderLines), len(keyLines), unknownKey)
+		return
+	}
+
+	for i, orderHex := range orderLines {
+		result := &recoveredOrder{Line: i + 1}
+		result.Order, result.Err = recoverOrder(orderHex, keyLines[i])
+		recovered = append(recovered, result)
+	}
+
+	return
+}
+
+// recoverOrder decrypts a single hex serialized encrypted order with a hex key
+func recoverOrder(orderHex string, keyHex string) (order *match.AuctionOrder, err error) {
+	if keyHex == unknownKey {
+		err = fmt.Errorf("Key for order is not known")
+		return
+	}
+
+	var encOrderBytes []byte
+	if encOrderBytes, err = hex.DecodeString(orderHex); err != nil {
+		err = fmt.Errorf("Error decoding encrypted order hex: %s", err)
+		return
+	}
+
+	var key []byte
+	if key, err = hex.DecodeString(keyHex); err != nil {
+		err = fmt.Errorf("Error decoding key hex: %s", err)
+		return
+	}
+
+	encOrder := new(match.EncryptedAuctionOrder)
+	if err = encOrder.Deserialize(encOrderBytes); err != nil {
+		err = fmt.Errorf("Error deserializing encrypted order: %s", err)
+		return
+	}
+
+	var orderBytes []byte
+	if orderBytes, err = encOrder.DecryptWithKey(key); err != nil {
+		err = fmt.Errorf("Error decrypting order: %s", err)
+		return
+	}
+
+	order = new(match.AuctionOrder)
+	if err = order.Deserialize(orderBytes); err != nil {
+		err = fmt.Errorf("Error deserializing decrypted order, the key is probably wrong: %s", err)
+		order = nil
+		return
+	}
+
+	// The wrong key can still decrypt to something that deserializes, but it won't have a real side
+	if !order.IsBuySide() && !order.IsSellSide() {
+		err = fmt.Errorf("Decrypted order has side %q, the key is probably wrong", order.Side)
+		order = nil
+		return
+	}
+
+	return
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+var testRecoverOrder = &match.AuctionOrder{
+	Side: "sell",
+	TradingPair: match.Pair{
+		AssetWant: match.BTCTest,
+		AssetHave: match.VTCTest,
+	},
+	AmountHave: 200000,
+	AmountWant: 1000,
+	Nonce:      [2]byte{0x0a, 0x0b},
+	AuctionID:  [32]byte{0xde, 0xad, 0xbe, 0xef},
+	Network:    match.TestnetMagic,
+	Signature:  make([]byte, 65),
+}
+
+// encryptTestOrder encrypts the test order and solves the puzzle once to get the key, like the key that the
+// exchange would reveal after an auction. It returns the hex encrypted order and the hex key.
+func encryptTestOrder(t *testing.T) (orderHex string, keyHex string) {
+	var err error
+
+	var encOrder *match.EncryptedAuctionOrder
+	if encOrder, err = testRecoverOrder.TurnIntoEncryptedOrder(1000); err != nil {
+		t.Fatalf("Error encrypting test order: %s", err)
+	}
+
+	var key []byte
+	if key, err = encOrder.OrderPuzzle.Solve(); err != nil {
+		t.Fatalf("Error solving test order puzzle for key: %s", err)
+	}
+
+	var raw []byte
+	if raw, err = encOrder.Serialize(); err != nil {
+		t.Fatalf("Error serializing encrypted test order: %s", err)
+	}
+
+	orderHex = hex.EncodeToString(raw)
+	keyHex = hex.EncodeToString(key)
+	return
+}
+
+func TestRecoverOrders(t *testing.T) {
+	var err error
+
+	orderHex, keyHex := encryptTestOrder(t)
+	wrongKey := hex.EncodeToString(make([]byte, 16))
+
+	// The same order three times, with the right key, an unknown key, and the wrong key
+	orderDump := strings.Join([]string{orderHex, orderHex, orderHex}, "\n")
+	keyDump := strings.Join([]string{keyHex, unknownKey, wrongKey}, "\n")
+
+	var recovered []*recoveredOrder
+	if recovered, err = recoverOrders(strings.NewReader(orderDump), strings.NewReader(keyDump)); err != nil {
+		t.Errorf("Error recovering orders: %s", err)
+		return
+	}
+
+	if len(recovered) != 3 {
+		t.Errorf("Recovered %d orders, expected 3", len(recovered))
+		return
+	}
+
+	if recovered[0].Err != nil {
+		t.Errorf("Order with the revealed key should be recovered: %s", recovered[0].Err)
+		return
+	}
+
+	if !bytes.Equal(recovered[0].Order.Serialize(), testRecoverOrder.Serialize()) {
+		t.Errorf("Recovered order %s does not match the original %s", recovered[0].Order, testRecoverOrder)
+		return
+	}
+
+	if recovered[1].Err == nil {
+		t.Errorf("Order with an unknown key should not be recovered")
+		return
+	}
+
+	if recovered[2].Err == nil {
+		t.Errorf("Order with the wrong key should not be recovered")
+		return
+	}
+
+	for i, result := range recovered {
+		if result.Line != i+1 {
+			t.Errorf("Recovered order %d says it's on line %d", i, result.Line)
+			return
+		}
+	}
+
+	return
+}
+
+func TestRecoverOrdersMissingKeys(t *testing.T) {
+	orderHex, keyHex := encryptTestOrder(t)
+
+	orderDump := orderHex + "\n" + orderHex + "\n"
+	if _, err := recoverOrders(strings.NewReader(orderDump), strings.NewReader(keyHex)); err == nil {
+		t.Errorf("Recovering should fail when there aren't keys for every order")
+		return
+	}
+
+	return
+}
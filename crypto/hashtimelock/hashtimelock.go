@@ -63,6 +63,18 @@ func (ht *HashTimelock) Solve() (answer []byte, err error) {
 	return
 }
 
+// AnswerSizeRange returns the size of the answer, which is always the size of the hash function output
+func (ht *HashTimelock) AnswerSizeRange() (min int, max int, err error) {
+	if ht.hashFunction == nil {
+		err = fmt.Errorf("Hash timelock has no hash function, cannot find answer size")
+		return
+	}
+
+	min = ht.hashFunction.Size()
+	max = min
+	return
+}
+
 // Serialize turns the hash timelock puzzle into something that can be sent over the wire
 func (ht *HashTimelock) Serialize() (raw []byte, err error) {
 	var b bytes.Buffer
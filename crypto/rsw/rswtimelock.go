@@ -4,6 +4,7 @@ package rsw
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/gob"
@@ -220,6 +221,67 @@ func (pz *PuzzleRSW) SolveGMPCkXOR() (answer []byte, err error) {
 	return new(gmpbig.Int).Xor(new(gmpbig.Int).SetBytes(pz.CK.Bytes()), new(gmpbig.Int).ExpSquare(new(gmpbig.Int).SetBytes(pz.A.Bytes()), new(gmpbig.Int).SetBytes(pz.T.Bytes()), new(gmpbig.Int).SetBytes(pz.N.Bytes()))).Bytes(), nil
 }
 
+// squaringsPerCheck is how many squarings SolveContext does between checking if it should stop
+const squaringsPerCheck = 1 << 14
+
+// SolveContext solves the puzzle like Solve, but squares in chunks so it can check in between whether ctx is
+// done. If it is, solving stops and the context's error is returned.
+func (pz *PuzzleRSW) SolveContext(ctx context.Context) (answer []byte, err error) {
+	return pz.solveChunks(ctx, big.NewInt(squaringsPerCheck), nil)
+}
+
+// SolveWithProgress solves the puzzle like SolveContext, but squares in chunks of every squarings, calling
+// progress with how many squarings are done and t after each one. ctx is checked between chunks.
+func (pz *PuzzleRSW) SolveWithProgress(ctx context.Context, every uint64, progress func(done uint64, total uint64)) (answer []byte, err error) {
+	if every == 0 {
+		err = fmt.Errorf("Cannot report progress every 0 squarings")
+		return
+	}
+
+	if pz.T == nil || !pz.T.IsUint64() {
+		err = fmt.Errorf("Puzzle t is missing or too big to report progress on")
+		return
+	}
+
+	return pz.solveChunks(ctx, new(big.Int).SetUint64(every), progress)
+}
+
+// solveChunks solves the puzzle by squaring chunk times at once, checking whether ctx is done and
+// calling progress, if it isn't nil, between chunks
+func (pz *PuzzleRSW) solveChunks(ctx context.Context, chunk *big.Int, progress func(done uint64, total uint64)) (answer []byte, err error) {
+	if pz.N == nil || pz.A == nil || pz.T == nil || pz.CK == nil {
+		err = fmt.Errorf("Puzzle is missing n, a, t, or ck, cannot solve")
+		return
+	}
+
+	gmpn := new(gmpbig.Int).SetBytes(pz.N.Bytes())
+	b := new(gmpbig.Int).SetBytes(pz.A.Bytes())
+	remaining := new(big.Int).Set(pz.T)
+	for remaining.Sign() > 0 {
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("Stopped solving puzzle with %s squarings left: %s", remaining, ctx.Err())
+			return
+		default:
+		}
+
+		// a^(2^(x+y)) = (a^(2^x))^(2^y), so the squarings can be split up
+		steps := chunk
+		if remaining.Cmp(chunk) < 0 {
+			steps = remaining
+		}
+		b = new(gmpbig.Int).ExpSquare(b, new(gmpbig.Int).SetBytes(steps.Bytes()), gmpn)
+		remaining.Sub(remaining, steps)
+
+		if progress != nil {
+			progress(new(big.Int).Sub(pz.T, remaining).Uint64(), pz.T.Uint64())
+		}
+	}
+
+	answer = new(gmpbig.Int).Xor(new(gmpbig.Int).SetBytes(pz.CK.Bytes()), b).Bytes()
+	return
+}
+
 // func (pz *PuzzleRSW) SolveDanGMPCkXOR() (answer []byte, err error) {
 // 	// One line and doesn't use all the memory
 // 	return new(danbig.Int).Xor(new(danbig.Int).SetBytes(pz.CK.Bytes()), new(danbig.Int).ExpSquare(new(danbig.Int).SetBytes(pz.A.Bytes()), new(danbig.Int).SetBytes(pz.T.Bytes()), new(danbig.Int).SetBytes(pz.N.Bytes()))).Bytes(), nil
@@ -237,6 +299,28 @@ func (pz *PuzzleRSW) SolveGMPCkADD() (answer []byte, err error) {
 	return new(gmpbig.Int).Sub(gmpck, new(gmpbig.Int).ExpSquare(gmpa, gmpt, gmpn)).Bytes(), nil
 }
 
+// AnswerSizeRange bounds the size of the answer without solving the puzzle. The answer is C_k ⊕ b, and
+// b < n, so if C_k is longer than n then the top bytes of C_k are the top bytes of the answer.
+// Otherwise the answer is at most as long as n.
+func (pz *PuzzleRSW) AnswerSizeRange() (min int, max int, err error) {
+	if pz.N == nil || pz.A == nil || pz.T == nil || pz.CK == nil {
+		err = fmt.Errorf("Puzzle is missing n, a, t, or ck, cannot find answer size")
+		return
+	}
+
+	nLen := len(pz.N.Bytes())
+	ckLen := len(pz.CK.Bytes())
+	if ckLen > nLen {
+		min = ckLen
+		max = ckLen
+		return
+	}
+
+	min = 0
+	max = nLen
+	return
+}
+
 // Serialize turns the RSW puzzle into something that can be sent over the wire
 func (pz *PuzzleRSW) Serialize() (raw []byte, err error) {
 	var b bytes.Buffer
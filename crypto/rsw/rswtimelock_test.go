@@ -2,6 +2,7 @@ package rsw
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"runtime"
@@ -352,3 +353,35 @@ func BenchmarkConcurrentManyMillions2048A2(b *testing.B) {
 
 // 	return
 // }
+
+func TestSolveContextMatchesSolve(t *testing.T) {
+	key := make([]byte, 32)
+	copy(key[:], []byte("opencxsolvecontext"))
+	rswTimelock, err := New2048A2(key)
+	if err != nil {
+		t.Fatalf("There was an error creating a new timelock puzzle: %s", err)
+	}
+
+	// Not a multiple of the chunk size, so the last chunk is a partial one
+	puzzle, expectedAns, err := rswTimelock.SetupTimelockPuzzle(3*squaringsPerCheck + 17)
+	if err != nil {
+		t.Fatalf("There was an error setting up the timelock puzzle: %s\n", err)
+	}
+
+	puzzleAns, err := puzzle.(*PuzzleRSW).SolveContext(context.Background())
+	if err != nil {
+		t.Fatalf("Error solving puzzle with context: %s\n", err)
+	}
+	if !bytes.Equal(puzzleAns, expectedAns) {
+		t.Fatalf("Answer solved with context did not equal puzzle. Expected %x, got %x\n", expectedAns, puzzleAns)
+	}
+
+	// Once the context is done it should stop right away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = puzzle.(*PuzzleRSW).SolveContext(ctx); err == nil {
+		t.Fatalf("Solving with a cancelled context should fail")
+	}
+
+	return
+}
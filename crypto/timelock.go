@@ -1,5 +1,7 @@
 package crypto
 
+import "context"
+
 // Timelock is an interface that all timelock implementations should conform to.
 type Timelock interface {
 	// SetupTimelockPuzzle sends key k to the future in time t, returning a puzzle and an answer, or fails
@@ -13,3 +15,26 @@ type Puzzle interface {
 	// Serialize turns the puzzle into something that's able to be sent over the wire
 	Serialize() (raw []byte, err error)
 }
+
+// AnswerSizer is implemented by puzzles that can bound the size of their answer without being solved,
+// so a puzzle can be checked against whatever the answer is going to be used for before spending time
+// solving it.
+type AnswerSizer interface {
+	// AnswerSizeRange returns the smallest and largest possible size of the answer, in bytes
+	AnswerSizeRange() (min int, max int, err error)
+}
+
+// ContextSolver is implemented by puzzles that can stop solving part way through, when ctx is done, instead
+// of only returning once the puzzle is solved.
+type ContextSolver interface {
+	// SolveContext solves the puzzle like Solve, but returns an error soon after ctx is done
+	SolveContext(ctx context.Context) (answer []byte, err error)
+}
+
+// ProgressSolver is implemented by puzzles that can report how far along solving them is. Puzzles like
+// RSW are a fixed number of sequential steps, so the progress says how long is left.
+type ProgressSolver interface {
+	// SolveWithProgress solves the puzzle like SolveContext, calling progress with the number of steps
+	// done and the total number of steps after every `every` steps, and after the last one
+	SolveWithProgress(ctx context.Context, every uint64, progress func(done uint64, total uint64)) (answer []byte, err error)
+}
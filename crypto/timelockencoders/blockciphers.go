@@ -1,6 +1,7 @@
 package timelockencoders
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -15,6 +16,21 @@ import (
 	"github.com/mit-dci/opencx/crypto/rsw"
 )
 
+// These are the key sizes, in bytes, that each of the block ciphers will accept
+var (
+	RC5KeySizes = []int{16}
+	RC6KeySizes = []int{16}
+	AESKeySizes = []int{16, 24, 32}
+)
+
+// These are the block sizes of each of the block ciphers, the ciphertext always starts with an IV
+// of this size.
+const (
+	RC5BlockSize = 8
+	RC6BlockSize = 16
+	AESBlockSize = 16
+)
+
 func createSHAPuzzle(t uint64, key []byte) (puzzle crypto.Puzzle, anskey []byte, err error) {
 	// Set up what the puzzle will encrypt
 	var timelock crypto.Timelock
@@ -114,6 +130,95 @@ func SolvePuzzleRC5(ciphertext []byte, puzzle crypto.Puzzle) (message []byte, er
 		return
 	}
 
+	if message, err = DecryptRC5(ciphertext, key); err != nil {
+		err = fmt.Errorf("Error decrypting puzzle ciphertext: %s", err)
+		return
+	}
+
+	return
+}
+
+// SolvePuzzleContext solves the puzzle, returning an error soon after ctx is done. Puzzles that aren't a
+// crypto.ContextSolver can't be stopped, so they keep solving in the background, but this still returns.
+func SolvePuzzleContext(ctx context.Context, puzzle crypto.Puzzle) (answer []byte, err error) {
+	if puzzle == nil {
+		err = fmt.Errorf("Puzzle cannot be nil, what are you solving")
+		return
+	}
+
+	if solver, ok := puzzle.(crypto.ContextSolver); ok {
+		return solver.SolveContext(ctx)
+	}
+
+	type solveResult struct {
+		answer []byte
+		err    error
+	}
+	resChan := make(chan solveResult, 1)
+	go func() {
+		var res solveResult
+		res.answer, res.err = puzzle.Solve()
+		resChan <- res
+	}()
+
+	select {
+	case res := <-resChan:
+		answer, err = res.answer, res.err
+	case <-ctx.Done():
+		err = fmt.Errorf("Stopped waiting for puzzle to be solved: %s", ctx.Err())
+	}
+	return
+}
+
+// SolvePuzzleRC5Context solves the timelock puzzle and decrypts the ciphertext using RC5 like SolvePuzzleRC5,
+// but stops solving when ctx is done
+func SolvePuzzleRC5Context(ctx context.Context, ciphertext []byte, puzzle crypto.Puzzle) (message []byte, err error) {
+	var key []byte
+	if key, err = SolvePuzzleContext(ctx, puzzle); err != nil {
+		err = fmt.Errorf("Error solving auction puzzle: %s", err)
+		return
+	}
+
+	if message, err = DecryptRC5(ciphertext, key); err != nil {
+		err = fmt.Errorf("Error decrypting puzzle ciphertext: %s", err)
+		return
+	}
+
+	return
+}
+
+// SolvePuzzleRC5WithProgress solves the timelock puzzle and decrypts the ciphertext using RC5 like
+// SolvePuzzleRC5Context, calling progress with how many steps of the puzzle are done and the total
+// every `every` steps. Puzzles that aren't a crypto.ProgressSolver only report progress once, as 1 of
+// 1 steps, when they are solved.
+func SolvePuzzleRC5WithProgress(ctx context.Context, ciphertext []byte, puzzle crypto.Puzzle, every uint64, progress func(done uint64, total uint64)) (message []byte, err error) {
+	if puzzle == nil {
+		err = fmt.Errorf("Puzzle cannot be nil, what are you solving")
+		return
+	}
+
+	var key []byte
+	if solver, ok := puzzle.(crypto.ProgressSolver); ok {
+		key, err = solver.SolveWithProgress(ctx, every, progress)
+	} else if key, err = SolvePuzzleContext(ctx, puzzle); err == nil && progress != nil {
+		progress(1, 1)
+	}
+	if err != nil {
+		err = fmt.Errorf("Error solving auction puzzle: %s", err)
+		return
+	}
+
+	if message, err = DecryptRC5(ciphertext, key); err != nil {
+		err = fmt.Errorf("Error decrypting puzzle ciphertext: %s", err)
+		return
+	}
+
+	return
+}
+
+// DecryptRC5 decrypts the ciphertext using RC5 with a key that's already known, like the answer to a puzzle
+// that has already been solved.
+func DecryptRC5(ciphertext []byte, key []byte) (message []byte, err error) {
 	var RC5Cipher cipher.Block
 	if RC5Cipher, err = rc5.New(key); err != nil {
 		err = fmt.Errorf("Could not create new rc5 cipher for puzzle: %s", err)
@@ -213,6 +318,17 @@ func SolvePuzzleRC6(ciphertext []byte, puzzle crypto.Puzzle) (message []byte, er
 		return
 	}
 
+	if message, err = DecryptRC6(ciphertext, key); err != nil {
+		err = fmt.Errorf("Error decrypting puzzle ciphertext: %s", err)
+		return
+	}
+
+	return
+}
+
+// DecryptRC6 decrypts the ciphertext using RC6 with a key that's already known, like the answer to a puzzle
+// that has already been solved.
+func DecryptRC6(ciphertext []byte, key []byte) (message []byte, err error) {
 	var RC6Cipher cipher.Block
 	if RC6Cipher, err = rc6.New(key); err != nil {
 		err = fmt.Errorf("Could not create new rc6 cipher for puzzle: %s", err)
@@ -337,6 +453,17 @@ func SolvePuzzleAES(ciphertext []byte, puzzle crypto.Puzzle) (message []byte, er
 		return
 	}
 
+	if message, err = DecryptAES(ciphertext, key); err != nil {
+		err = fmt.Errorf("Error decrypting puzzle ciphertext: %s", err)
+		return
+	}
+
+	return
+}
+
+// DecryptAES decrypts the ciphertext using AES with a key that's already known, like the answer to a puzzle
+// that has already been solved.
+func DecryptAES(ciphertext []byte, key []byte) (message []byte, err error) {
 	var AESCipher cipher.Block
 	if AESCipher, err = aes.NewCipher(key); err != nil {
 		err = fmt.Errorf("Could not create new aes cipher for puzzle: %s", err)
@@ -2,6 +2,7 @@ package timelockencoders
 
 import (
 	"bytes"
+	"context"
 	"testing"
 )
 
@@ -77,6 +78,51 @@ func TestRSWRC5(t *testing.T) {
 	return
 }
 
+func TestRSWRC5WithProgress(t *testing.T) {
+	message := make([]byte, 32)
+	copy(message, []byte("RSW96 with a progress bar"))
+	ciphertext, puzzle, err := CreateRSW2048A2PuzzleRC5(1000, message)
+	if err != nil {
+		t.Fatalf("Error creating puzzle: %s", err)
+	}
+
+	var fractions []float64
+	newMessage, err := SolvePuzzleRC5WithProgress(context.Background(), ciphertext, puzzle, 64, func(done uint64, total uint64) {
+		if total != 1000 {
+			t.Errorf("Progress should be out of 1000 squarings, got %d", total)
+		}
+		fractions = append(fractions, float64(done)/float64(total))
+	})
+	if err != nil {
+		t.Fatalf("Error solving puzzle: %s", err)
+	}
+
+	if !bytes.Equal(newMessage, message) {
+		t.Fatalf("Messages not equal")
+	}
+
+	// 1000 squarings in chunks of 64 is 15 full chunks and one of 40
+	if len(fractions) != 16 {
+		t.Fatalf("Progress should have been reported 16 times, got %d", len(fractions))
+	}
+
+	for i := 1; i < len(fractions); i++ {
+		if fractions[i] <= fractions[i-1] {
+			t.Fatalf("Progress should always increase, went from %f to %f", fractions[i-1], fractions[i])
+		}
+	}
+
+	if fractions[len(fractions)-1] != 1.0 {
+		t.Fatalf("Progress should end at 1.0, ended at %f", fractions[len(fractions)-1])
+	}
+
+	if _, err = SolvePuzzleRC5WithProgress(context.Background(), ciphertext, puzzle, 0, nil); err == nil {
+		t.Fatalf("Reporting progress every 0 squarings should fail")
+	}
+
+	return
+}
+
 // TestRSWRC5ManyN8_T100000 tests 8 concurrent orders with a 100000 time to solve
 func TestRSWRC5ManyN8_T100000(t *testing.T) {
 	solveRSWRC5Concurrent(uint64(100000), uint64(8), t)
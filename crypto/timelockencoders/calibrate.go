@@ -0,0 +1,88 @@
+package timelockencoders
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	gmpbig "github.com/Rjected/gmp"
+)
+
+const (
+	// calibrationTime is how long CalibrateSquaringsPerSecond squares for. Longer is more accurate, but
+	// slower to start up with.
+	calibrationTime = 500 * time.Millisecond
+	// calibrationChunk is how many squarings are done between checking how long calibration has taken
+	calibrationChunk = 1 << 10
+	// calibrationModulusBytes is the size of the modulus squared mod, the same as the 2048 bit puzzles
+	calibrationModulusBytes = 256
+)
+
+// DefaultSquaringsPerSecond is a rough estimate of how many modular squarings a modern machine does in a
+// second with a 2048 bit modulus. It's used by SquaringsForDuration until the host has been calibrated.
+const DefaultSquaringsPerSecond = 500000
+
+var (
+	// calibratedSquarings is the squarings per second from the last calibration, 0 if there hasn't been one
+	calibratedSquarings uint64
+	calibrateMtx        = new(sync.Mutex)
+)
+
+// CalibrateSquaringsPerSecond benchmarks modular squaring on this host, the same way RSW puzzles are solved,
+// and returns how many squarings it does in a second. The result is also what SquaringsForDuration uses
+// from then on.
+func CalibrateSquaringsPerSecond() (squaringsPerSecond uint64, err error) {
+	// Any odd modulus of the right size squares just as fast as a real one
+	modulus := make([]byte, calibrationModulusBytes)
+	if _, err = rand.Read(modulus); err != nil {
+		err = fmt.Errorf("Error creating modulus to calibrate squarings: %s", err)
+		return
+	}
+	modulus[0] |= 0x80
+	modulus[len(modulus)-1] |= 0x01
+
+	gmpn := new(gmpbig.Int).SetBytes(modulus)
+	chunk := new(gmpbig.Int).SetBytes(big.NewInt(calibrationChunk).Bytes())
+	b := new(gmpbig.Int).SetBytes([]byte{2})
+
+	var squarings uint64
+	var elapsed time.Duration
+	start := time.Now()
+	for elapsed < calibrationTime {
+		b = new(gmpbig.Int).ExpSquare(b, chunk, gmpn)
+		squarings += calibrationChunk
+		elapsed = time.Since(start)
+	}
+
+	if squaringsPerSecond = uint64(float64(squarings) / elapsed.Seconds()); squaringsPerSecond == 0 {
+		err = fmt.Errorf("Calibration did %d squarings in %s, which is less than one a second", squarings, elapsed)
+		return
+	}
+
+	calibrateMtx.Lock()
+	calibratedSquarings = squaringsPerSecond
+	calibrateMtx.Unlock()
+
+	return
+}
+
+// SquaringsForDuration returns the puzzle time t that takes about d to solve on this host. It uses the
+// last calibration, or DefaultSquaringsPerSecond if CalibrateSquaringsPerSecond hasn't been called.
+func SquaringsForDuration(d time.Duration) (t uint64) {
+	calibrateMtx.Lock()
+	squaringsPerSecond := calibratedSquarings
+	calibrateMtx.Unlock()
+
+	if squaringsPerSecond == 0 {
+		squaringsPerSecond = DefaultSquaringsPerSecond
+	}
+
+	if d <= 0 {
+		return
+	}
+
+	t = uint64(d.Seconds() * float64(squaringsPerSecond))
+	return
+}
@@ -0,0 +1,68 @@
+package timelockencoders
+
+import (
+	"testing"
+	"time"
+)
+
+// calibrationRuns is how many calibrations are averaged, so one run slowed down by something else on the host
+// doesn't make calibration look unstable
+const calibrationRuns = 3
+
+// averageCalibration calibrates calibrationRuns times, and returns the average and the last calibration
+func averageCalibration() (average uint64, last uint64, err error) {
+	var total uint64
+	for i := 0; i < calibrationRuns; i++ {
+		if last, err = CalibrateSquaringsPerSecond(); err != nil {
+			return
+		}
+		total += last
+	}
+
+	average = total / calibrationRuns
+	return
+}
+
+func TestCalibrateSquaringsPerSecond(t *testing.T) {
+	var err error
+
+	var first, second, last uint64
+	if first, _, err = averageCalibration(); err != nil {
+		t.Errorf("Error calibrating squarings: %s", err)
+		return
+	}
+
+	// Anything doing 2048 bit squarings is going to be somewhere in here
+	if first < 1000 || first > 1000000000 {
+		t.Errorf("Calibration should give a plausible number of squarings per second, got %d", first)
+		return
+	}
+
+	if second, last, err = averageCalibration(); err != nil {
+		t.Errorf("Error calibrating squarings a second time: %s", err)
+		return
+	}
+
+	// The same host should calibrate about the same twice in a row, with plenty of room for a busy host
+	if second < first/4 || second > first*4 {
+		t.Errorf("Calibration should be stable, got %d then %d squarings per second", first, second)
+		return
+	}
+
+	if squarings := SquaringsForDuration(30 * time.Second); squarings != 30*last {
+		t.Errorf("30 seconds should be 30 times the last calibration of %d squarings per second, got %d", last, squarings)
+		return
+	}
+
+	if squarings := SquaringsForDuration(500 * time.Millisecond); squarings != last/2 {
+		t.Errorf("Half a second should be half the last calibration of %d squarings per second, got %d", last, squarings)
+		return
+	}
+
+	if squarings := SquaringsForDuration(0); squarings != 0 {
+		t.Errorf("No time should be no squarings, got %d", squarings)
+		return
+	}
+
+	return
+}
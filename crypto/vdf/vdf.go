@@ -0,0 +1,329 @@
+// Package vdf is an implementation of timelock puzzles that are solved by evaluating a verifiable delay function.
+// Like RSW96, solving a puzzle takes t sequential modular squarings, but the solution also comes with a
+// Wesolowski proof that it's right, which can be checked with a couple of small exponentiations instead of
+// doing the squarings again.
+package vdf
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"math/big"
+
+	gmpbig "github.com/Rjected/gmp"
+
+	"github.com/mit-dci/opencx/crypto"
+)
+
+const (
+	// challengeBits is how many bits the prime challenge for the proof has
+	challengeBits = 128
+
+	// challengePrimeRounds is how many Miller-Rabin rounds a challenge has to pass to be a prime
+	challengePrimeRounds = 20
+
+	// squaringsPerCheck is how many squarings SolveContext does between checking if it should stop
+	squaringsPerCheck = 1 << 14
+)
+
+// TimelockVDF generates puzzles that can then only be solved by evaluating the VDF, which is repeated squarings
+type TimelockVDF struct {
+	rsaKeyBits int
+	key        []byte
+	p          *big.Int
+	q          *big.Int
+	a          *big.Int
+}
+
+// PuzzleVDF is the puzzle that can be solved by evaluating the VDF y = a^(2^t) (mod n). The key is hidden as
+// C_k = y ⊕ k.
+type PuzzleVDF struct {
+	N  *big.Int
+	A  *big.Int
+	T  *big.Int
+	CK *big.Int
+}
+
+// Solution is the output of the VDF for a puzzle, y = a^(2^t) (mod n), along with the Wesolowski proof that it
+// is, π = a^⌊2^t / ℓ⌋ (mod n), where ℓ is a prime challenge derived from the puzzle and y.
+type Solution struct {
+	Y     *big.Int
+	Proof *big.Int
+}
+
+// New creates a new TimelockVDF with p and q generated as per crypto/rsa, and an input a as well as number of
+// bits for the RSA key size. The key is also set here.
+func New(key []byte, a int64, rsaKeyBits int) (timelock crypto.Timelock, err error) {
+	tl := new(TimelockVDF)
+	tl.rsaKeyBits = rsaKeyBits
+
+	var rsaPrivKey *rsa.PrivateKey
+	if rsaPrivKey, err = rsa.GenerateMultiPrimeKey(rand.Reader, 2, tl.rsaKeyBits); err != nil {
+		err = fmt.Errorf("Could not generate primes for RSA: %s", err)
+		return
+	}
+	if len(rsaPrivKey.Primes) != 2 {
+		err = fmt.Errorf("RSA privkey has %d primes, the VDF needs exactly p and q", len(rsaPrivKey.Primes))
+		return
+	}
+
+	tl.p = new(big.Int).Set(rsaPrivKey.Primes[0])
+	tl.q = new(big.Int).Set(rsaPrivKey.Primes[1])
+	tl.a = big.NewInt(a)
+	tl.key = key
+
+	timelock = tl
+	return
+}
+
+// New2048A2 creates a new TimelockVDF with a 2048 bit modulus and a base of 2
+func New2048A2(key []byte) (tl crypto.Timelock, err error) {
+	return New(key, 2, 2048)
+}
+
+// SetupTimelockPuzzle sets up a puzzle that hides the key for t squarings. Knowing p and q, the output of the
+// VDF can be found quickly as a^(2^t (mod ϕ(n))) (mod n), so setting up doesn't take as long as solving.
+func (tl *TimelockVDF) SetupTimelockPuzzle(t uint64) (puzzle crypto.Puzzle, answer []byte, err error) {
+	if tl.p == nil || tl.q == nil || tl.a == nil {
+		err = fmt.Errorf("Must set up p, q, and a to set up a puzzle")
+		return
+	}
+
+	n := new(big.Int).Mul(tl.p, tl.q)
+	ϕ := new(big.Int).Mul(new(big.Int).Sub(tl.p, big.NewInt(1)), new(big.Int).Sub(tl.q, big.NewInt(1)))
+	bigT := new(big.Int).SetUint64(t)
+
+	// y = a^(2^t (mod ϕ(n))) (mod n)
+	e := new(big.Int).Exp(big.NewInt(2), bigT, ϕ)
+	y := new(big.Int).Exp(tl.a, e, n)
+
+	ck := new(big.Int).Xor(y, new(big.Int).SetBytes(tl.key))
+	puzzle = &PuzzleVDF{
+		N:  n,
+		A:  new(big.Int).Set(tl.a),
+		T:  bigT,
+		CK: ck,
+	}
+
+	answer = new(big.Int).Xor(ck, y).Bytes()
+	return
+}
+
+// Solve solves the puzzle by evaluating the VDF. The proof is thrown away, use SolveWithProof to keep it.
+func (pz *PuzzleVDF) Solve() (answer []byte, err error) {
+	answer, _, err = pz.solveChunks(context.Background(), squaringsPerCheck)
+	return
+}
+
+// SolveContext solves the puzzle like Solve, but squares in chunks so it can check in between whether ctx is
+// done. If it is, solving stops and the context's error is returned.
+func (pz *PuzzleVDF) SolveContext(ctx context.Context) (answer []byte, err error) {
+	answer, _, err = pz.solveChunks(ctx, squaringsPerCheck)
+	return
+}
+
+// SolveWithProof solves the puzzle by evaluating the VDF, and also returns the output along with a proof that
+// it's right, so anyone can check the puzzle was solved without solving it again
+func (pz *PuzzleVDF) SolveWithProof() (answer []byte, solution *Solution, err error) {
+	return pz.solveChunks(context.Background(), squaringsPerCheck)
+}
+
+// solveChunks evaluates the VDF and computes the proof, squaring chunk times at once and checking whether ctx
+// is done in between
+func (pz *PuzzleVDF) solveChunks(ctx context.Context, chunk uint64) (answer []byte, solution *Solution, err error) {
+	if err = pz.checkComplete(); err != nil {
+		return
+	}
+
+	if !pz.T.IsUint64() {
+		err = fmt.Errorf("Puzzle t is too big to solve")
+		return
+	}
+	t := pz.T.Uint64()
+
+	// y = a^(2^t) (mod n)
+	gmpn := new(gmpbig.Int).SetBytes(pz.N.Bytes())
+	gmpy := new(gmpbig.Int).SetBytes(pz.A.Bytes())
+	for remaining := t; remaining > 0; {
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("Stopped solving puzzle with %d squarings left: %s", remaining, ctx.Err())
+			return
+		default:
+		}
+
+		steps := chunk
+		if remaining < chunk {
+			steps = remaining
+		}
+		gmpy = new(gmpbig.Int).ExpSquare(gmpy, new(gmpbig.Int).SetUint64(steps), gmpn)
+		remaining -= steps
+	}
+
+	solution = &Solution{
+		Y: new(big.Int).SetBytes(gmpy.Bytes()),
+	}
+	l := pz.challenge(solution.Y)
+
+	// π = a^⌊2^t / ℓ⌋ (mod n). The quotient is t bits long, so instead of computing it, its bits are found by
+	// long division of 2^t by ℓ a chunk at a time, and raised into π as they're found.
+	gmpπ := gmpbig.NewInt(1)
+	r := big.NewInt(1)
+	for remaining := t; remaining > 0; {
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("Stopped proving puzzle solution with %d squarings left: %s", remaining, ctx.Err())
+			return
+		default:
+		}
+
+		steps := chunk
+		if remaining < chunk {
+			steps = remaining
+		}
+
+		// The next steps bits of the quotient, and what's left over for the rest of them
+		quotient, rest := new(big.Int).QuoRem(new(big.Int).Lsh(r, uint(steps)), l, new(big.Int))
+		r = rest
+
+		// π = π^(2^steps) * a^quotient (mod n)
+		gmpπ = new(gmpbig.Int).ExpSquare(gmpπ, new(gmpbig.Int).SetUint64(steps), gmpn)
+		aq := new(big.Int).Exp(pz.A, quotient, pz.N)
+		gmpπ.Mul(gmpπ, new(gmpbig.Int).SetBytes(aq.Bytes()))
+		gmpπ.Mod(gmpπ, gmpn)
+		remaining -= steps
+	}
+	solution.Proof = new(big.Int).SetBytes(gmpπ.Bytes())
+
+	answer = new(big.Int).Xor(pz.CK, solution.Y).Bytes()
+	return
+}
+
+// Verify checks the proof that solution is the output of the VDF for this puzzle, and returns the answer if it
+// is. This takes a couple of exponentiations with small exponents rather than t squarings. A proof only shows
+// that the output is right, whoever knows the factors of n could have found it without doing the squarings.
+func (pz *PuzzleVDF) Verify(solution *Solution) (answer []byte, err error) {
+	if err = pz.checkComplete(); err != nil {
+		return
+	}
+
+	if solution == nil || solution.Y == nil || solution.Proof == nil {
+		err = fmt.Errorf("Solution is missing y or the proof, cannot verify")
+		return
+	}
+
+	if solution.Y.Sign() <= 0 || solution.Y.Cmp(pz.N) >= 0 || solution.Proof.Sign() <= 0 || solution.Proof.Cmp(pz.N) >= 0 {
+		err = fmt.Errorf("Solution y and proof have to be between 0 and n, invalid solution")
+		return
+	}
+
+	l := pz.challenge(solution.Y)
+
+	// π^ℓ * a^(2^t mod ℓ) = a^(ℓ⌊2^t / ℓ⌋ + 2^t mod ℓ) = a^(2^t) = y (mod n)
+	r := new(big.Int).Exp(big.NewInt(2), pz.T, l)
+	check := new(big.Int).Exp(solution.Proof, l, pz.N)
+	check.Mul(check, new(big.Int).Exp(pz.A, r, pz.N))
+	check.Mod(check, pz.N)
+	if check.Cmp(solution.Y) != 0 {
+		err = fmt.Errorf("Proof does not verify, y is not the output of the VDF for this puzzle")
+		return
+	}
+
+	answer = new(big.Int).Xor(pz.CK, solution.Y).Bytes()
+	return
+}
+
+// challenge derives the prime ℓ for the proof from the puzzle and the output of the VDF, by hashing them with a
+// counter until the hash is a prime. The top bit is always set, so ℓ is always challengeBits long.
+func (pz *PuzzleVDF) challenge(y *big.Int) (l *big.Int) {
+	var intBytes [8]byte
+	for counter := uint64(0); ; counter++ {
+		h := sha256.New()
+		h.Write([]byte("opencx-vdf-challenge"))
+		for _, x := range []*big.Int{pz.N, pz.A, pz.T, y} {
+			xBytes := x.Bytes()
+			binary.BigEndian.PutUint64(intBytes[:], uint64(len(xBytes)))
+			h.Write(intBytes[:])
+			h.Write(xBytes)
+		}
+		binary.BigEndian.PutUint64(intBytes[:], counter)
+		h.Write(intBytes[:])
+
+		l = new(big.Int).SetBytes(h.Sum(nil)[:challengeBits/8])
+		l.SetBit(l, challengeBits-1, 1)
+		if l.ProbablyPrime(challengePrimeRounds) {
+			return
+		}
+	}
+}
+
+// checkComplete returns an error if the puzzle is missing any of its values
+func (pz *PuzzleVDF) checkComplete() (err error) {
+	if pz.N == nil || pz.A == nil || pz.T == nil || pz.CK == nil {
+		err = fmt.Errorf("Puzzle is missing n, a, t, or ck")
+		return
+	}
+
+	if pz.N.Sign() <= 0 {
+		err = fmt.Errorf("Puzzle n has to be positive")
+		return
+	}
+
+	return
+}
+
+// AnswerSizeRange bounds the size of the answer without solving the puzzle. The answer is C_k ⊕ y, and
+// y < n, so if C_k is longer than n then the top bytes of C_k are the top bytes of the answer.
+// Otherwise the answer is at most as long as n.
+func (pz *PuzzleVDF) AnswerSizeRange() (min int, max int, err error) {
+	if err = pz.checkComplete(); err != nil {
+		err = fmt.Errorf("Cannot find answer size: %s", err)
+		return
+	}
+
+	nLen := len(pz.N.Bytes())
+	ckLen := len(pz.CK.Bytes())
+	if ckLen > nLen {
+		min = ckLen
+		max = ckLen
+		return
+	}
+
+	min = 0
+	max = nLen
+	return
+}
+
+// Serialize turns the VDF puzzle into something that can be sent over the wire
+func (pz *PuzzleVDF) Serialize() (raw []byte, err error) {
+	var b bytes.Buffer
+
+	// register puzzleVDF interface
+	gob.Register(PuzzleVDF{})
+
+	if err = gob.NewEncoder(&b).Encode(pz); err != nil {
+		err = fmt.Errorf("Error encoding puzzle: %s", err)
+		return
+	}
+
+	raw = b.Bytes()
+	return
+}
+
+// Deserialize turns bytes from the wire back into the VDF puzzle
+func (pz *PuzzleVDF) Deserialize(raw []byte) (err error) {
+	// register puzzleVDF interface
+	gob.Register(PuzzleVDF{})
+
+	if err = gob.NewDecoder(bytes.NewBuffer(raw)).Decode(pz); err != nil {
+		err = fmt.Errorf("Error decoding puzzle: %s", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,206 @@
+package vdf
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/mit-dci/opencx/crypto"
+)
+
+// testPuzzle creates a small puzzle for key that's quick to solve
+func testPuzzle(key []byte, t uint64) (puzzle *PuzzleVDF, answer []byte, err error) {
+	var timelock crypto.Timelock
+	if timelock, err = New(key, 2, 1024); err != nil {
+		return
+	}
+
+	var setupPuzzle crypto.Puzzle
+	if setupPuzzle, answer, err = timelock.SetupTimelockPuzzle(t); err != nil {
+		return
+	}
+
+	puzzle = setupPuzzle.(*PuzzleVDF)
+	return
+}
+
+func TestSolve(t *testing.T) {
+	var err error
+
+	key := []byte("!!! secret < 32 bytes !!!")
+	var puzzle *PuzzleVDF
+	var expectedAns []byte
+	if puzzle, expectedAns, err = testPuzzle(key, 50000); err != nil {
+		t.Errorf("Error creating puzzle: %s", err)
+		return
+	}
+
+	if !bytes.Equal(expectedAns, key) {
+		t.Errorf("Setting up the puzzle should give the key as the answer, got %x", expectedAns)
+		return
+	}
+
+	var answer []byte
+	if answer, err = puzzle.Solve(); err != nil {
+		t.Errorf("Error solving puzzle: %s", err)
+		return
+	}
+
+	if !bytes.Equal(answer, expectedAns) {
+		t.Errorf("Solving the puzzle gave %x, expected %x", answer, expectedAns)
+		return
+	}
+
+	// Solving with a proof has to give the same answer, and the output of the VDF
+	var provedAns []byte
+	var solution *Solution
+	if provedAns, solution, err = puzzle.SolveWithProof(); err != nil {
+		t.Errorf("Error solving puzzle with proof: %s", err)
+		return
+	}
+
+	if !bytes.Equal(provedAns, expectedAns) {
+		t.Errorf("Solving the puzzle with a proof gave %x, expected %x", provedAns, expectedAns)
+		return
+	}
+
+	expectedY := new(big.Int).Exp(puzzle.A, new(big.Int).Lsh(big.NewInt(1), uint(puzzle.T.Uint64())), puzzle.N)
+	if solution.Y.Cmp(expectedY) != 0 {
+		t.Errorf("Solution y is not a^(2^t) (mod n)")
+		return
+	}
+
+	return
+}
+
+func TestVerifyAccepts(t *testing.T) {
+	var err error
+
+	key := []byte("verifiable delay")
+	var puzzle *PuzzleVDF
+	if puzzle, _, err = testPuzzle(key, 50000); err != nil {
+		t.Errorf("Error creating puzzle: %s", err)
+		return
+	}
+
+	var solution *Solution
+	if _, solution, err = puzzle.SolveWithProof(); err != nil {
+		t.Errorf("Error solving puzzle with proof: %s", err)
+		return
+	}
+
+	var answer []byte
+	if answer, err = puzzle.Verify(solution); err != nil {
+		t.Errorf("Proof for a solved puzzle should verify: %s", err)
+		return
+	}
+
+	if !bytes.Equal(answer, key) {
+		t.Errorf("Verifying the solution gave %x, expected %x", answer, key)
+		return
+	}
+
+	// A puzzle with no squarings is its own base
+	var zeroPuzzle *PuzzleVDF
+	if zeroPuzzle, _, err = testPuzzle(key, 0); err != nil {
+		t.Errorf("Error creating puzzle with no squarings: %s", err)
+		return
+	}
+
+	if _, solution, err = zeroPuzzle.SolveWithProof(); err != nil {
+		t.Errorf("Error solving puzzle with no squarings: %s", err)
+		return
+	}
+
+	if _, err = zeroPuzzle.Verify(solution); err != nil {
+		t.Errorf("Proof for a puzzle with no squarings should verify: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestVerifyRejectsTamperedProof(t *testing.T) {
+	var err error
+
+	var puzzle *PuzzleVDF
+	if puzzle, _, err = testPuzzle([]byte("verifiable delay"), 50000); err != nil {
+		t.Errorf("Error creating puzzle: %s", err)
+		return
+	}
+
+	var solution *Solution
+	if _, solution, err = puzzle.SolveWithProof(); err != nil {
+		t.Errorf("Error solving puzzle with proof: %s", err)
+		return
+	}
+
+	tamperedProof := &Solution{
+		Y:     solution.Y,
+		Proof: new(big.Int).Add(solution.Proof, big.NewInt(1)),
+	}
+	if _, err = puzzle.Verify(tamperedProof); err == nil {
+		t.Errorf("Solution with a tampered proof should not verify")
+		return
+	}
+
+	tamperedY := &Solution{
+		Y:     new(big.Int).Add(solution.Y, big.NewInt(1)),
+		Proof: solution.Proof,
+	}
+	if _, err = puzzle.Verify(tamperedY); err == nil {
+		t.Errorf("Solution with a tampered output should not verify")
+		return
+	}
+
+	// The proof is for this puzzle, so it shouldn't verify for one with fewer squarings
+	fewerSquarings := *puzzle
+	fewerSquarings.T = new(big.Int).Sub(puzzle.T, big.NewInt(1))
+	if _, err = fewerSquarings.Verify(solution); err == nil {
+		t.Errorf("Solution should not verify for a puzzle with a different t")
+		return
+	}
+
+	if _, err = puzzle.Verify(&Solution{Y: solution.Y, Proof: puzzle.N}); err == nil {
+		t.Errorf("Solution with a proof that isn't less than n should not verify")
+		return
+	}
+
+	return
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	var err error
+
+	key := []byte("verifiable delay")
+	var puzzle *PuzzleVDF
+	if puzzle, _, err = testPuzzle(key, 1000); err != nil {
+		t.Errorf("Error creating puzzle: %s", err)
+		return
+	}
+
+	var raw []byte
+	if raw, err = puzzle.Serialize(); err != nil {
+		t.Errorf("Error serializing puzzle: %s", err)
+		return
+	}
+
+	decPuzzle := new(PuzzleVDF)
+	if err = decPuzzle.Deserialize(raw); err != nil {
+		t.Errorf("Error deserializing puzzle: %s", err)
+		return
+	}
+
+	var answer []byte
+	if answer, err = decPuzzle.Solve(); err != nil {
+		t.Errorf("Error solving deserialized puzzle: %s", err)
+		return
+	}
+
+	if !bytes.Equal(answer, key) {
+		t.Errorf("Deserialized puzzle solved to %x, expected %x", answer, key)
+		return
+	}
+
+	return
+}
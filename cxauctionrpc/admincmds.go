@@ -0,0 +1,63 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxauctionserver"
+)
+
+// GetAdminMetricsArgs holds the args for the getadminmetrics command
+type GetAdminMetricsArgs struct {
+	// AuctionID is the current auction, so the request can't be replayed later
+	AuctionID [32]byte
+	// Signature is a compact signature of SerializeSignable by an admin pubkey, so we can do pubkey recovery
+	Signature []byte
+}
+
+// GetAdminMetricsReply holds the reply for the getadminmetrics command
+type GetAdminMetricsReply struct {
+	Metrics *cxauctionserver.AdminMetrics
+}
+
+// SerializeSignable serializes what should be signed to get admin metrics
+func (args *GetAdminMetricsArgs) SerializeSignable() (buf []byte) {
+	buf = append(buf, []byte("opencx-getadminmetrics")...)
+	buf = append(buf, args.AuctionID[:]...)
+	return
+}
+
+// GetAdminMetrics gets the detailed internal metrics of the exchange, like queue depths and the state of each
+// pair. Only admins can get these, and only so often.
+func (cl *OpencxAuctionRPC) GetAdminMetrics(args GetAdminMetricsArgs, reply *GetAdminMetricsReply) (err error) {
+
+	// e = h(getadminmetrics || auctionID)
+	sha3 := sha3.New256()
+	sha3.Write(args.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	var pubkey *koblitz.PublicKey
+	if pubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), args.Signature, e); err != nil {
+		err = fmt.Errorf("Error verifying admin metrics request, invalid signature: \n%s", err)
+		return
+	}
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = cl.Server.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for admin metrics: \n%s", err)
+		return
+	}
+
+	if args.AuctionID != currentAuctionID {
+		err = fmt.Errorf("Admin metrics request was signed for auction %x, not the current auction %x", args.AuctionID, currentAuctionID)
+		return
+	}
+
+	if reply.Metrics, err = cl.Server.AdminMetrics(pubkey); err != nil {
+		err = fmt.Errorf("Error getting admin metrics: \n%s", err)
+		return
+	}
+
+	return
+}
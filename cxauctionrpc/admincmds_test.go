@@ -0,0 +1,108 @@
+package cxauctionrpc
+
+import (
+	"testing"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+)
+
+// signAdminMetricsArgs creates admin metrics args for auctionID signed by privkey
+func signAdminMetricsArgs(auctionID [32]byte, privkey *koblitz.PrivateKey) (args GetAdminMetricsArgs, err error) {
+	args.AuctionID = auctionID
+
+	sha3 := sha3.New256()
+	sha3.Write(args.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	args.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, e, false)
+	return
+}
+
+func TestGetAdminMetricsOnlyAdmins(t *testing.T) {
+	var err error
+
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		t.Errorf("Error setting up db client: %s", err)
+		return
+	}
+
+	var s *cxauctionserver.OpencxAuctionServer
+	if s, err = cxauctionserver.InitServer(testDB, 100, 100000); err != nil {
+		t.Errorf("Error initializing server: %s", err)
+		return
+	}
+	cl := &OpencxAuctionRPC{Server: s}
+
+	var adminKey, userKey *koblitz.PrivateKey
+	if adminKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating admin key: %s", err)
+		return
+	}
+	if userKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating user key: %s", err)
+		return
+	}
+	s.SetAdminPubkeys([]*koblitz.PublicKey{adminKey.PubKey()})
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	var args GetAdminMetricsArgs
+	if args, err = signAdminMetricsArgs(auctionID, userKey); err != nil {
+		t.Errorf("Error signing user request: %s", err)
+		return
+	}
+
+	if err = cl.GetAdminMetrics(args, new(GetAdminMetricsReply)); err == nil {
+		t.Errorf("Pubkeys that aren't admins should not get admin metrics")
+		return
+	}
+
+	if args, err = signAdminMetricsArgs(auctionID, adminKey); err != nil {
+		t.Errorf("Error signing admin request: %s", err)
+		return
+	}
+
+	reply := new(GetAdminMetricsReply)
+	if err = cl.GetAdminMetrics(args, reply); err != nil {
+		t.Errorf("Admin should get admin metrics: %s", err)
+		return
+	}
+
+	if reply.Metrics == nil || !reply.Metrics.Health.Healthy {
+		t.Errorf("Admin metrics should say a fresh exchange is healthy")
+		return
+	}
+
+	// Asking again right away is too often
+	if err = cl.GetAdminMetrics(args, new(GetAdminMetricsReply)); err == nil {
+		t.Errorf("Admin metrics should be rate limited")
+		return
+	}
+
+	// A request for some other auction could be a replay
+	if args, err = signAdminMetricsArgs([32]byte{0x01}, adminKey); err != nil {
+		t.Errorf("Error signing old admin request: %s", err)
+		return
+	}
+
+	if err = s.SetAdminMetricsInterval(0); err != nil {
+		t.Errorf("Error turning off admin rate limit: %s", err)
+		return
+	}
+
+	if err = cl.GetAdminMetrics(args, new(GetAdminMetricsReply)); err == nil {
+		t.Errorf("Admin metrics request for an auction that isn't the current one should fail")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,112 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// CancelAllOrdersArgs holds the args for the cancelallorders command
+type CancelAllOrdersArgs struct {
+	// AuctionID is the auction to cancel orders in, it should be the current auction
+	AuctionID [32]byte
+	// Signature is a compact signature of SerializeSignable, so we can do pubkey recovery
+	Signature []byte
+}
+
+// CancelAllOrdersReply holds the reply for the cancelallorders command
+type CancelAllOrdersReply struct {
+	CancelledOrders []*match.AuctionOrder
+}
+
+// SerializeSignable serializes what should be signed to cancel all orders. The auction ID is
+// included so the cancel can't be replayed in another auction.
+func (args *CancelAllOrdersArgs) SerializeSignable() (buf []byte) {
+	buf = append(buf, []byte("opencx-cancelall")...)
+	buf = append(buf, args.AuctionID[:]...)
+	return
+}
+
+// CancelAllOrders cancels all of the pending orders for the pubkey that signed the request
+func (cl *OpencxAuctionRPC) CancelAllOrders(args CancelAllOrdersArgs, reply *CancelAllOrdersReply) (err error) {
+
+	// e = h(cancelall || auctionID)
+	sha3 := sha3.New256()
+	sha3.Write(args.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	var pubkey *koblitz.PublicKey
+	if pubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), args.Signature, e); err != nil {
+		err = fmt.Errorf("Error verifying cancel all, invalid signature: \n%s", err)
+		return
+	}
+
+	if reply.CancelledOrders, err = cl.Server.CancelAllOrders(pubkey, args.AuctionID); err != nil {
+		err = fmt.Errorf("Error cancelling all orders: \n%s", err)
+		return
+	}
+
+	for _, order := range reply.CancelledOrders {
+		if err = order.SetOrderbookPrice(); err != nil {
+			err = fmt.Errorf("Error setting orderbook price for cancelled order: \n%s", err)
+			return
+		}
+	}
+
+	return
+}
+
+// CancelEverythingArgs holds the args for the canceleverything command
+type CancelEverythingArgs struct {
+	// AuctionID is the current auction, it's signed so the cancel can't be replayed later
+	AuctionID [32]byte
+	// Signature is a compact signature of SerializeSignable, so we can do pubkey recovery
+	Signature []byte
+}
+
+// CancelEverythingReply holds the reply for the canceleverything command
+type CancelEverythingReply struct {
+	// CancelledOrders are the orders that were removed from the current auction. Orders that were still
+	// being solved, or were queued for the next auction, are cancelled too but aren't returned.
+	CancelledOrders []*match.AuctionOrder
+}
+
+// SerializeSignable serializes what should be signed to cancel everything. It's different from cancel all so
+// a signed cancel all can't be used to cancel everything.
+func (args *CancelEverythingArgs) SerializeSignable() (buf []byte) {
+	buf = append(buf, []byte("opencx-canceleverything")...)
+	buf = append(buf, args.AuctionID[:]...)
+	return
+}
+
+// CancelEverything cancels all of the pending orders for the pubkey that signed the request, including the
+// ones queued for the next auction and the ones still being solved
+func (cl *OpencxAuctionRPC) CancelEverything(args CancelEverythingArgs, reply *CancelEverythingReply) (err error) {
+
+	// e = h(canceleverything || auctionID)
+	sha3 := sha3.New256()
+	sha3.Write(args.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	var pubkey *koblitz.PublicKey
+	if pubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), args.Signature, e); err != nil {
+		err = fmt.Errorf("Error verifying cancel everything, invalid signature: \n%s", err)
+		return
+	}
+
+	if reply.CancelledOrders, err = cl.Server.CancelEverything(pubkey, args.AuctionID); err != nil {
+		err = fmt.Errorf("Error cancelling everything: \n%s", err)
+		return
+	}
+
+	for _, order := range reply.CancelledOrders {
+		if err = order.SetOrderbookPrice(); err != nil {
+			err = fmt.Errorf("Error setting orderbook price for cancelled order: \n%s", err)
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,49 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+// GetInterimCommitmentArgs holds the args for the getinterimcommitment command
+type GetInterimCommitmentArgs struct {
+	// empty
+}
+
+// GetInterimCommitmentReply holds the reply for the getinterimcommitment command
+type GetInterimCommitmentReply struct {
+	Commitment *match.InterimCommitment
+}
+
+// GetInterimCommitment gets the latest commitment to the puzzles in the current auction, so clients can check
+// that their puzzle is in before the auction closes
+func (cl *OpencxAuctionRPC) GetInterimCommitment(args GetInterimCommitmentArgs, reply *GetInterimCommitmentReply) (err error) {
+	if reply.Commitment, err = cl.Server.InterimCommitment(); err != nil {
+		err = fmt.Errorf("Error getting interim commitment: \n%s", err)
+		return
+	}
+
+	return
+}
+
+// GetCommitmentHistoryArgs holds the args for the getcommitmenthistory command
+type GetCommitmentHistoryArgs struct {
+	NumAuctions uint64
+}
+
+// GetCommitmentHistoryReply holds the reply for the getcommitmenthistory command
+type GetCommitmentHistoryReply struct {
+	Roots []*match.AuctionRoot
+}
+
+// GetCommitmentHistory gets the final commitment roots of the last few auctions, most recent first, so
+// watchdogs can archive them
+func (cl *OpencxAuctionRPC) GetCommitmentHistory(args GetCommitmentHistoryArgs, reply *GetCommitmentHistoryReply) (err error) {
+	if reply.Roots, err = cl.Server.CommitmentHistory(args.NumAuctions); err != nil {
+		err = fmt.Errorf("Error getting commitment history: \n%s", err)
+		return
+	}
+
+	return
+}
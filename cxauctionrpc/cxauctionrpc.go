@@ -1,9 +1,26 @@
 package cxauctionrpc
 
-import "github.com/mit-dci/opencx/cxauctionserver"
+import (
+	"sync"
+	"time"
+
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/util"
+)
 
 // OpencxAuctionRPC is a listener for RPC commands
 type OpencxAuctionRPC struct {
-	Server    *cxauctionserver.OpencxAuctionServer
+	Server *cxauctionserver.OpencxAuctionServer
+	// OffButton stops the listeners. Sending on it stops one listener, closing it stops all of them.
 	OffButton chan bool
+
+	// ListenerConfig is the backlog and keep-alive the RPC listeners are set up with
+	ListenerConfig util.ListenerConfig
+
+	// ParamCacheTTL is how long a public parameters reply is cached for. The cache is also invalidated
+	// as soon as the auction changes. 0 means replies aren't cached.
+	ParamCacheTTL time.Duration
+	paramCache    *GetPublicParametersReply
+	paramCachedAt time.Time
+	paramCacheMtx sync.Mutex
 }
@@ -0,0 +1,27 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/cxauctionserver"
+)
+
+// GetHealthArgs holds the args for the gethealth command
+type GetHealthArgs struct {
+	// empty
+}
+
+// GetHealthReply holds the reply for the gethealth command
+type GetHealthReply struct {
+	Health cxauctionserver.AuctionHealth
+}
+
+// GetHealth gets whether or not the exchange is still advancing auctions, so it can be used as a readiness check
+func (cl *OpencxAuctionRPC) GetHealth(args GetHealthArgs, reply *GetHealthReply) (err error) {
+	if reply.Health, err = cl.Server.Health(); err != nil {
+		err = fmt.Errorf("Error getting auction health: \n%s", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,82 @@
+package cxauctionrpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// GetHistoricalOrdersArgs holds the args for the gethistoricalorders command
+type GetHistoricalOrdersArgs struct {
+	Pair match.Pair
+	// NumAuctions is how many of the auctions that closed last to get orders from
+	NumAuctions uint64
+	// AuctionID is the current auction, so the request can't be replayed later
+	AuctionID [32]byte
+	// Signature is a compact signature of SerializeSignable by an admin pubkey, so we can do pubkey recovery
+	Signature []byte
+}
+
+// GetHistoricalOrdersReply holds the reply for the gethistoricalorders command
+type GetHistoricalOrdersReply struct {
+	// Orders are the historical orders as newline delimited JSON, read them with match.ReadHistoricalOrders
+	Orders []byte
+}
+
+// SerializeSignable serializes what should be signed to get historical orders
+func (args *GetHistoricalOrdersArgs) SerializeSignable() (buf []byte) {
+	var numBytes [8]byte
+	binary.BigEndian.PutUint64(numBytes[:], args.NumAuctions)
+
+	buf = append(buf, []byte("opencx-gethistoricalorders")...)
+	buf = append(buf, args.Pair.Serialize()...)
+	buf = append(buf, numBytes[:]...)
+	buf = append(buf, args.AuctionID[:]...)
+	return
+}
+
+// GetHistoricalOrders exports the decrypted orders for a pair from past auctions, and how much they filled,
+// for backtesting. Only admins can get these.
+func (cl *OpencxAuctionRPC) GetHistoricalOrders(args GetHistoricalOrdersArgs, reply *GetHistoricalOrdersReply) (err error) {
+
+	// e = h(gethistoricalorders || pair || numAuctions || auctionID)
+	sha3 := sha3.New256()
+	sha3.Write(args.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	var pubkey *koblitz.PublicKey
+	if pubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), args.Signature, e); err != nil {
+		err = fmt.Errorf("Error verifying historical orders request, invalid signature: \n%s", err)
+		return
+	}
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = cl.Server.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for historical orders: \n%s", err)
+		return
+	}
+
+	if args.AuctionID != currentAuctionID {
+		err = fmt.Errorf("Historical orders request was signed for auction %x, not the current auction %x", args.AuctionID, currentAuctionID)
+		return
+	}
+
+	var orders []*match.HistoricalOrder
+	if orders, err = cl.Server.HistoricalOrders(pubkey, &args.Pair, args.NumAuctions); err != nil {
+		err = fmt.Errorf("Error getting historical orders: \n%s", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = match.WriteHistoricalOrders(&buf, orders); err != nil {
+		err = fmt.Errorf("Error exporting historical orders: \n%s", err)
+		return
+	}
+	reply.Orders = buf.Bytes()
+
+	return
+}
@@ -0,0 +1,189 @@
+package cxauctionrpc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+	"github.com/mit-dci/opencx/match"
+)
+
+// signHistoricalOrdersArgs creates historical orders args for auctionID signed by privkey
+func signHistoricalOrdersArgs(pair match.Pair, numAuctions uint64, auctionID [32]byte, privkey *koblitz.PrivateKey) (args GetHistoricalOrdersArgs, err error) {
+	args.Pair = pair
+	args.NumAuctions = numAuctions
+	args.AuctionID = auctionID
+
+	sha3 := sha3.New256()
+	sha3.Write(args.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	args.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, e, false)
+	return
+}
+
+func TestGetHistoricalOrders(t *testing.T) {
+	var err error
+
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		t.Errorf("Error setting up db client: %s", err)
+		return
+	}
+
+	var s *cxauctionserver.OpencxAuctionServer
+	if s, err = cxauctionserver.InitServer(testDB, 100, uint64(time.Hour/time.Microsecond)); err != nil {
+		t.Errorf("Error initializing server: %s", err)
+		return
+	}
+	cl := &OpencxAuctionRPC{Server: s}
+
+	var adminKey, userKey *koblitz.PrivateKey
+	if adminKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating admin key: %s", err)
+		return
+	}
+	if userKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating user key: %s", err)
+		return
+	}
+	s.SetAdminPubkeys([]*koblitz.PublicKey{adminKey.PubKey()})
+
+	pair := match.Pair{
+		AssetWant: match.BTC,
+		AssetHave: match.VTCTest,
+	}
+
+	// Two auctions with a buy and a sell each, only the first one is cleared
+	var placed []*match.AuctionOrder
+	var firstAuctionID [32]byte
+	for i := 0; i < 2; i++ {
+		var auctionID [32]byte
+		if auctionID, err = s.CurrentAuctionID(); err != nil {
+			t.Errorf("Error getting current auction: %s", err)
+			return
+		}
+		if i == 0 {
+			firstAuctionID = auctionID
+		}
+
+		for j, side := range []string{"buy", "sell"} {
+			order := &match.AuctionOrder{
+				Side:        side,
+				TradingPair: pair,
+				AmountHave:  1000,
+				AmountWant:  1000,
+				AuctionID:   auctionID,
+				Nonce:       [2]byte{byte(j)},
+			}
+			copy(order.Pubkey[:], userKey.PubKey().SerializeCompressed())
+			if order.Signature, err = koblitz.SignCompact(koblitz.S256(), userKey, order.SigHash(), false); err != nil {
+				t.Errorf("Error signing order: %s", err)
+				return
+			}
+			if err = testDB.PlaceAuctionOrder(order); err != nil {
+				t.Errorf("Error placing order: %s", err)
+				return
+			}
+			placed = append(placed, order)
+		}
+
+		if err = s.CommitOrdersNewAuction(); err != nil {
+			t.Errorf("Error committing orders: %s", err)
+			return
+		}
+	}
+
+	if _, err = s.ClearPairAuction(&pair, firstAuctionID); err != nil {
+		t.Errorf("Error clearing auction: %s", err)
+		return
+	}
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction: %s", err)
+		return
+	}
+
+	var args GetHistoricalOrdersArgs
+	if args, err = signHistoricalOrdersArgs(pair, 2, currentAuctionID, userKey); err != nil {
+		t.Errorf("Error signing user request: %s", err)
+		return
+	}
+
+	if err = cl.GetHistoricalOrders(args, new(GetHistoricalOrdersReply)); err == nil {
+		t.Errorf("Users that aren't admins should not get historical orders")
+		return
+	}
+
+	if args, err = signHistoricalOrdersArgs(pair, 2, currentAuctionID, adminKey); err != nil {
+		t.Errorf("Error signing admin request: %s", err)
+		return
+	}
+
+	reply := new(GetHistoricalOrdersReply)
+	if err = cl.GetHistoricalOrders(args, reply); err != nil {
+		t.Errorf("Error getting historical orders: %s", err)
+		return
+	}
+
+	if lines := bytes.Count(reply.Orders, []byte("\n")); lines != len(placed) {
+		t.Errorf("Export should have one line for each of the %d orders, got %d", len(placed), lines)
+		return
+	}
+
+	var orders []*match.HistoricalOrder
+	if orders, err = match.ReadHistoricalOrders(bytes.NewReader(reply.Orders)); err != nil {
+		t.Errorf("Error reading historical orders: %s", err)
+		return
+	}
+
+	if len(orders) != len(placed) {
+		t.Errorf("Expected %d historical orders, got %d", len(placed), len(orders))
+		return
+	}
+
+	for _, historical := range orders {
+		var original *match.AuctionOrder
+		for _, order := range placed {
+			if order.Hash() == historical.Order.Hash() {
+				original = order
+			}
+		}
+
+		if original == nil || historical.AuctionID != original.AuctionID {
+			t.Errorf("Historical order was not one of the placed orders, or is in the wrong auction")
+			return
+		}
+
+		var valid bool
+		if valid, err = historical.Order.VerifySignature(); err != nil || !valid {
+			t.Errorf("Historical order signature should still verify after being exported: %v", err)
+			return
+		}
+
+		// Only the cleared auction has fills, and both of its orders fill completely
+		if historical.AuctionID == firstAuctionID {
+			if historical.Fill == nil || historical.Fill.AmountFilled != 1000 {
+				t.Errorf("Order in cleared auction should have filled 1000, got %v", historical.Fill)
+				return
+			}
+		} else if historical.Fill != nil {
+			t.Errorf("Order in auction that wasn't cleared should not have a fill")
+			return
+		}
+	}
+
+	// Oldest auction first
+	if orders[0].AuctionID != firstAuctionID || orders[0].ClosedAtUnix > orders[len(orders)-1].ClosedAtUnix {
+		t.Errorf("Historical orders should start with the oldest auction")
+		return
+	}
+
+	return
+}
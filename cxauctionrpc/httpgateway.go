@@ -0,0 +1,151 @@
+package cxauctionrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+	"github.com/mit-dci/opencx/util"
+)
+
+const (
+	// maxHTTPRequestBytes is the biggest request body the HTTP gateway will read. Puzzled orders are a few
+	// kilobytes, so this leaves plenty of room.
+	maxHTTPRequestBytes = 1 << 20
+	// httpShutdownTimeout is how long we wait for HTTP requests to finish when the off button is pressed
+	httpShutdownTimeout = 5 * time.Second
+)
+
+// httpError is the body of every HTTP gateway reply that failed
+type httpError struct {
+	Error string `json:"error"`
+}
+
+// HTTPHandler returns a handler that serves some of the RPC commands as JSON over HTTP, for clients that can't
+// speak net/rpc. GET /auction/params is GetPublicParameters, with the pair in the pair query parameter if
+// there is one, and POST /auction/order is SubmitPuzzledOrder. The args and replies are the same as the RPC commands, encoded as JSON.
+func (cl *OpencxAuctionRPC) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auction/params", cl.handleHTTPParams)
+	mux.HandleFunc("/auction/order", cl.handleHTTPOrder)
+	return mux
+}
+
+// handleHTTPParams serves the public parameters
+func (cl *OpencxAuctionRPC) handleHTTPParams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("Public parameters must be requested with GET, not %s", r.Method))
+		return
+	}
+
+	var args GetPublicParametersArgs
+	if pairString := r.URL.Query().Get("pair"); pairString != "" {
+		args.Pair = new(match.Pair)
+		if err := args.Pair.FromString(pairString); err != nil {
+			writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("Error parsing pair: %s", err))
+			return
+		}
+	}
+
+	reply := new(GetPublicParametersReply)
+	if err := cl.GetPublicParameters(args, reply); err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeHTTPReply(w, reply)
+}
+
+// handleHTTPOrder submits a puzzled order
+func (cl *OpencxAuctionRPC) handleHTTPOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("Orders must be submitted with POST, not %s", r.Method))
+		return
+	}
+
+	var args SubmitPuzzledOrderArgs
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHTTPRequestBytes)).Decode(&args); err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("Error decoding order: %s", err))
+		return
+	}
+
+	// The order is what the client got wrong if it's rejected
+	reply := new(SubmitPuzzledOrderReply)
+	if err := cl.SubmitPuzzledOrder(args, reply); err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeHTTPReply(w, reply)
+}
+
+// writeHTTPReply writes reply as JSON
+func writeHTTPReply(w http.ResponseWriter, reply interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reply); err != nil {
+		logging.Errorf("Error writing HTTP gateway reply: %s", err)
+	}
+}
+
+// writeHTTPError writes err as JSON with the status code
+func writeHTTPError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if encodeErr := json.NewEncoder(w).Encode(&httpError{Error: err.Error()}); encodeErr != nil {
+		logging.Errorf("Error writing HTTP gateway error: %s", encodeErr)
+	}
+}
+
+// HTTPListen is a synchronous version of HTTPListenAsync
+func HTTPListen(rpc1 *OpencxAuctionRPC, host string, port uint16) {
+
+	doneChan := make(chan bool, 1)
+	go HTTPListenAsync(doneChan, rpc1, host, port)
+	<-doneChan
+
+	return
+}
+
+// HTTPListenAsync serves the HTTP gateway on host and port, with the backlog and keep-alive from the rpc's
+// ListenerConfig, until the off button is pressed
+func HTTPListenAsync(doneChan chan bool, rpc1 *OpencxAuctionRPC, host string, port uint16) {
+	var err error
+
+	logging.Infof("Starting HTTP gateway")
+	serverAddr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	var listener net.Listener
+	if listener, err = util.ListenTCP(serverAddr, rpc1.ListenerConfig); err != nil {
+		logging.Fatal("listen error:", err)
+	}
+	logging.Infof("Running HTTP gateway on %s\n", listener.Addr().String())
+
+	httpServer := &http.Server{
+		Handler: rpc1.HTTPHandler(),
+	}
+	go func() {
+		if serveErr := httpServer.Serve(listener); serveErr != nil && serveErr != http.ErrServerClosed {
+			logging.Errorf("Error serving HTTP gateway: %s", serveErr)
+		}
+	}()
+
+	OffButtonShutdownHTTP(rpc1, httpServer)
+	doneChan <- true
+	return
+}
+
+// OffButtonShutdownHTTP waits for the off button to gracefully shut down the HTTP server, which closes its listener
+func OffButtonShutdownHTTP(rpc1 *OpencxAuctionRPC, httpServer *http.Server) {
+	<-rpc1.OffButton
+	logging.Infof("Got stop request, shutting down HTTP gateway")
+
+	ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logging.Errorf("Error shutting down HTTP gateway: \n%s", err)
+	}
+}
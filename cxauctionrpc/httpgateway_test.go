@@ -0,0 +1,208 @@
+package cxauctionrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+	"github.com/mit-dci/opencx/match"
+)
+
+// initHTTPGateway starts an HTTP gateway for a new exchange, with auctions long enough that the clock won't
+// start a new one during a test
+func initHTTPGateway(t *testing.T) (cl *OpencxAuctionRPC, gateway *httptest.Server, ok bool) {
+	var err error
+
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		t.Errorf("Error setting up db client: %s", err)
+		return
+	}
+
+	var s *cxauctionserver.OpencxAuctionServer
+	if s, err = cxauctionserver.InitServer(testDB, 100, uint64(time.Hour/time.Microsecond)); err != nil {
+		t.Errorf("Error initializing server: %s", err)
+		return
+	}
+
+	cl = &OpencxAuctionRPC{Server: s}
+	gateway = httptest.NewServer(cl.HTTPHandler())
+	ok = true
+	return
+}
+
+func TestHTTPGatewayParams(t *testing.T) {
+	var err error
+
+	cl, gateway, ok := initHTTPGateway(t)
+	if !ok {
+		return
+	}
+	defer gateway.Close()
+
+	var resp *http.Response
+	if resp, err = http.Get(gateway.URL + "/auction/params"); err != nil {
+		t.Errorf("Error getting params from HTTP gateway: %s", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Getting params should succeed, got status %d", resp.StatusCode)
+		return
+	}
+
+	reply := new(GetPublicParametersReply)
+	if err = json.NewDecoder(resp.Body).Decode(reply); err != nil {
+		t.Errorf("Error decoding params from HTTP gateway: %s", err)
+		return
+	}
+
+	rpcReply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{}, rpcReply); err != nil {
+		t.Errorf("Error getting public parameters: %s", err)
+		return
+	}
+
+	if reply.AuctionID != rpcReply.AuctionID || reply.PuzzleParams != rpcReply.PuzzleParams || reply.Network != rpcReply.Network {
+		t.Errorf("HTTP gateway params should be the same as the RPC params")
+		return
+	}
+
+	if resp, err = http.Post(gateway.URL+"/auction/params", "application/json", nil); err != nil {
+		t.Errorf("Error posting to params on HTTP gateway: %s", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Params should only be served for GET, got status %d for POST", resp.StatusCode)
+		return
+	}
+
+	return
+}
+
+func TestHTTPGatewayOrder(t *testing.T) {
+	var err error
+
+	cl, gateway, ok := initHTTPGateway(t)
+	if !ok {
+		return
+	}
+	defer gateway.Close()
+
+	// Orders for the next auction aren't solved until it starts, so the exchange doesn't spend the test solving it
+	if err = cl.Server.SetNextAuctionWindow(2 * time.Hour); err != nil {
+		t.Errorf("Error setting next auction window: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	var params match.PuzzleParams
+	if auctionID, params, err = cl.Server.CurrentPuzzleParams(); err != nil {
+		t.Errorf("Error getting puzzle params: %s", err)
+		return
+	}
+
+	order := &match.AuctionOrder{
+		Side:       "buy",
+		AmountHave: 10000,
+		AmountWant: 20000,
+		AuctionID:  auctionID,
+		TradingPair: match.Pair{
+			AssetWant: match.BTC,
+			AssetHave: match.VTCTest,
+		},
+	}
+	copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+	if order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, order.SigHash(), false); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	var encOrder *match.EncryptedAuctionOrder
+	if encOrder, err = order.TurnIntoEncryptedOrderWithParams(&params); err != nil {
+		t.Errorf("Error encrypting order: %s", err)
+		return
+	}
+
+	args := SubmitPuzzledOrderArgs{NextAuction: true}
+	if args.EncryptedOrderBytes, err = encOrder.Serialize(); err != nil {
+		t.Errorf("Error serializing encrypted order: %s", err)
+		return
+	}
+
+	var body []byte
+	if body, err = json.Marshal(args); err != nil {
+		t.Errorf("Error encoding order: %s", err)
+		return
+	}
+
+	var resp *http.Response
+	if resp, err = http.Post(gateway.URL+"/auction/order", "application/json", bytes.NewReader(body)); err != nil {
+		t.Errorf("Error posting order to HTTP gateway: %s", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Posting a valid order should succeed, got status %d", resp.StatusCode)
+		return
+	}
+
+	var metrics cxauctionserver.RejectionMetrics
+	if metrics, err = cl.Server.RejectionMetrics(); err != nil {
+		t.Errorf("Error getting rejection metrics: %s", err)
+		return
+	}
+
+	if metrics.Accepted != 1 || len(metrics.Rejected) != 0 {
+		t.Errorf("Order posted to the HTTP gateway should have been accepted, got %d accepted and rejections {%s}", metrics.Accepted, metrics.Rejected)
+		return
+	}
+
+	// An order that isn't an order is the client's fault, and says why
+	if resp, err = http.Post(gateway.URL+"/auction/order", "application/json", bytes.NewReader([]byte(`{"EncryptedOrderBytes": "AAAA"}`))); err != nil {
+		t.Errorf("Error posting bad order to HTTP gateway: %s", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Posting a bad order should be a bad request, got status %d", resp.StatusCode)
+		return
+	}
+
+	var replyErr httpError
+	if err = json.NewDecoder(resp.Body).Decode(&replyErr); err != nil || replyErr.Error == "" {
+		t.Errorf("Bad order reply should have the error in it")
+		return
+	}
+
+	if resp, err = http.Get(gateway.URL + "/auction/order"); err != nil {
+		t.Errorf("Error getting order from HTTP gateway: %s", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Orders should only be accepted with POST, got status %d for GET", resp.StatusCode)
+		return
+	}
+
+	return
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/cxnoise"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/util"
 )
 
 // NoiseListen is a synchronous version of RPCListenAsync
@@ -20,7 +21,7 @@ func NoiseListen(rpc1 *OpencxAuctionRPC, privkey *koblitz.PrivateKey, host strin
 	return
 }
 
-// NoiseListenAsync listens on socket host and port
+// NoiseListenAsync listens on socket host and port, with the backlog and keep-alive from the rpc's ListenerConfig
 func NoiseListenAsync(doneChan chan bool, privkey *koblitz.PrivateKey, rpc1 *OpencxAuctionRPC, host string, port uint16) {
 	var err error
 
@@ -36,7 +37,7 @@ func NoiseListenAsync(doneChan chan bool, privkey *koblitz.PrivateKey, rpc1 *Ope
 	logging.Infof("Starting RPC Server over noise protocol")
 	// Start RPC Server
 	var listener net.Listener
-	if listener, err = cxnoise.NewListener(privkey, int(port)); err != nil {
+	if listener, err = cxnoise.NewListenerWithConfig(privkey, int(port), rpc1.ListenerConfig); err != nil {
 		logging.Fatal("listen error:", err)
 	}
 	logging.Infof("Running RPC-Noise server on %s\n", listener.Addr().String())
@@ -59,7 +60,7 @@ func RPCListen(rpc1 *OpencxAuctionRPC, host string, port uint16) {
 	return
 }
 
-// RPCListenAsync listens on socket host and port
+// RPCListenAsync listens on socket host and port, with the backlog and keep-alive from the rpc's ListenerConfig
 func RPCListenAsync(doneChan chan bool, rpc1 *OpencxAuctionRPC, host string, port uint16) {
 	var err error
 
@@ -73,7 +74,7 @@ func RPCListenAsync(doneChan chan bool, rpc1 *OpencxAuctionRPC, host string, por
 	// Start RPC Server
 	serverAddr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
 	var listener net.Listener
-	if listener, err = net.Listen("tcp", serverAddr); err != nil {
+	if listener, err = util.ListenTCP(serverAddr, rpc1.ListenerConfig); err != nil {
 		logging.Fatal("listen error:", err)
 	}
 	logging.Infof("Running RPC server on %s\n", listener.Addr().String())
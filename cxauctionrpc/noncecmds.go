@@ -0,0 +1,62 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// RequestNonceArgs holds the args for the requestnonce command
+type RequestNonceArgs struct {
+	// AuctionID is the auction the nonce is for, it should be the current auction
+	AuctionID [32]byte
+	// Signature is a compact signature of SerializeSignable, so we can do pubkey recovery
+	Signature []byte
+}
+
+// RequestNonceReply holds the reply for the requestnonce command
+type RequestNonceReply struct {
+	Nonce     [2]byte
+	AuctionID [32]byte
+}
+
+// SerializeSignable serializes what should be signed to request a nonce
+func (args *RequestNonceArgs) SerializeSignable() (buf []byte) {
+	buf = append(buf, []byte("opencx-requestnonce")...)
+	buf = append(buf, args.AuctionID[:]...)
+	return
+}
+
+// RequestNonce assigns a fresh nonce in the current auction to the pubkey that signed the request
+func (cl *OpencxAuctionRPC) RequestNonce(args RequestNonceArgs, reply *RequestNonceReply) (err error) {
+
+	// e = h(requestnonce || auctionID)
+	sha3 := sha3.New256()
+	sha3.Write(args.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	var pubkey *koblitz.PublicKey
+	if pubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), args.Signature, e); err != nil {
+		err = fmt.Errorf("Error verifying nonce request, invalid signature: \n%s", err)
+		return
+	}
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = cl.Server.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for nonce request: \n%s", err)
+		return
+	}
+
+	if currentAuctionID != args.AuctionID {
+		err = fmt.Errorf("Error requesting nonce, auction %x is not the current auction %x", args.AuctionID, currentAuctionID)
+		return
+	}
+
+	if reply.Nonce, reply.AuctionID, err = cl.Server.RequestNonce(pubkey); err != nil {
+		err = fmt.Errorf("Error requesting nonce: \n%s", err)
+		return
+	}
+
+	return
+}
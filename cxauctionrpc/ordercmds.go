@@ -11,6 +11,12 @@ import (
 type SubmitPuzzledOrderArgs struct {
 	// Use the serialize method on match.EncryptedAuctionOrder
 	EncryptedOrderBytes []byte
+	// NextAuction queues the order for the auction after the current one. The order should still be made
+	// for the current auction, since the next auction ID isn't known yet.
+	NextAuction bool
+	// PriorityFee is what the submitter will pay to have the order solved sooner. The exchange can't check it
+	// until the order is solved, it's only used to decide which orders to solve first.
+	PriorityFee uint64
 }
 
 // SubmitPuzzledOrderReply holds the reply for the submitpuzzledorder command
@@ -29,10 +35,123 @@ func (cl *OpencxAuctionRPC) SubmitPuzzledOrder(args SubmitPuzzledOrderArgs, repl
 		return
 	}
 
-	if err = cl.Server.PlacePuzzledOrder(order); err != nil {
+	if args.NextAuction {
+		if err = cl.Server.QueueNextAuctionOrderWithFee(order, args.PriorityFee); err != nil {
+			err = fmt.Errorf("Error queueing order for next auction while submitting order: \n%s", err)
+			return
+		}
+		return
+	}
+
+	if err = cl.Server.PlacePuzzledOrderWithFee(order, args.PriorityFee); err != nil {
 		err = fmt.Errorf("Error placing order while submitting order: \n%s", err)
 		return
 	}
 
 	return
 }
+
+// SubmitArgs holds the args for the submitencryptedauctionorder command
+type SubmitArgs struct {
+	// Use the serialize method on match.EncryptedAuctionOrder
+	EncryptedOrderBytes []byte
+}
+
+// SubmitReply holds the reply for the submitencryptedauctionorder command
+type SubmitReply struct {
+	// Accepted is true if the order was placed in the current auction. If it wasn't, Reason says why.
+	Accepted bool
+	Reason   string
+	// OrderID is the ID of the encrypted order, so the client can tell which of its orders this was
+	OrderID [32]byte
+}
+
+// SubmitEncryptedAuctionOrder submits an encrypted order to the current auction. Unlike SubmitPuzzledOrder,
+// an order the exchange won't take isn't an error, the reply just says it was rejected and why. The order's
+// IntendedAuction has to be the current auction of the order's pair, which is the exchange's auction unless the
+// pair runs its own, and its puzzle has to use that auction's puzzle params. Orders that don't are rejected
+// before they're stored.
+func (cl *OpencxAuctionRPC) SubmitEncryptedAuctionOrder(args SubmitArgs, reply *SubmitReply) (err error) {
+
+	order := new(match.EncryptedAuctionOrder)
+	if err = order.Deserialize(args.EncryptedOrderBytes); err != nil {
+		err = fmt.Errorf("Error deserializing encrypted order: %s", err)
+		return
+	}
+
+	if reply.OrderID, err = order.OrderID(); err != nil {
+		err = fmt.Errorf("Error getting encrypted order ID: %s", err)
+		return
+	}
+
+	if placeErr := cl.Server.PlacePuzzledOrder(order); placeErr != nil {
+		logging.Infof("Rejected encrypted order %x: %s", reply.OrderID, placeErr)
+		reply.Reason = placeErr.Error()
+		return
+	}
+
+	reply.Accepted = true
+	logging.Infof("Accepted encrypted order %x for auction %x", reply.OrderID, order.IntendedAuction)
+
+	return
+}
+
+// SubmitUnsafePlaintextOrderArgs holds the args for the submitunsafeplaintextorder command
+type SubmitUnsafePlaintextOrderArgs struct {
+	// Use the serialize method on match.AuctionOrder
+	OrderBytes []byte
+}
+
+// SubmitUnsafePlaintextOrderReply holds the reply for the submitunsafeplaintextorder command
+type SubmitUnsafePlaintextOrderReply struct {
+	// empty
+}
+
+// SubmitUnsafePlaintextOrder submits a batch order without a timelock puzzle. This is only accepted if
+// the exchange has been started in the unsafe no-puzzle testing mode.
+func (cl *OpencxAuctionRPC) SubmitUnsafePlaintextOrder(args SubmitUnsafePlaintextOrderArgs, reply *SubmitUnsafePlaintextOrderReply) (err error) {
+
+	order := new(match.AuctionOrder)
+	if err = order.Deserialize(args.OrderBytes); err != nil {
+		err = fmt.Errorf("Error deserializing plaintext order: %s", err)
+		return
+	}
+
+	if err = cl.Server.PlaceUnsafePlaintextOrder(order); err != nil {
+		err = fmt.Errorf("Error placing order while submitting plaintext order: \n%s", err)
+		return
+	}
+
+	return
+}
+
+// SubmitContinuousOrderArgs holds the args for the submitcontinuousorder command
+type SubmitContinuousOrderArgs struct {
+	// Use the serialize method on match.AuctionOrder
+	OrderBytes []byte
+}
+
+// SubmitContinuousOrderReply holds the reply for the submitcontinuousorder command
+type SubmitContinuousOrderReply struct {
+	OrderID string
+}
+
+// SubmitContinuousOrder submits an unencrypted order, which is matched right away if the exchange is in
+// continuous matching mode
+func (cl *OpencxAuctionRPC) SubmitContinuousOrder(args SubmitContinuousOrderArgs, reply *SubmitContinuousOrderReply) (err error) {
+
+	order := new(match.AuctionOrder)
+	if err = order.Deserialize(args.OrderBytes); err != nil {
+		err = fmt.Errorf("Error deserializing continuous order: %s", err)
+		return
+	}
+
+	if reply.OrderID, err = cl.Server.PlaceContinuousOrder(order); err != nil {
+		err = fmt.Errorf("Error placing order while submitting continuous order: \n%s", err)
+		return
+	}
+
+	logging.Infof("User %x submitted continuous OrderID %s", order.Pubkey, reply.OrderID)
+
+	return
+}
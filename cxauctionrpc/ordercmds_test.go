@@ -0,0 +1,166 @@
+package cxauctionrpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+	"github.com/mit-dci/opencx/match"
+)
+
+// newTestEncryptedOrderArgs creates submit args for an order signed for and intended for auctionID
+func newTestEncryptedOrderArgs(auctionID [32]byte, params *match.PuzzleParams) (args SubmitArgs, encOrder *match.EncryptedAuctionOrder, err error) {
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		return
+	}
+
+	order := &match.AuctionOrder{
+		Side:       "buy",
+		AmountHave: 10000,
+		AmountWant: 20000,
+		AuctionID:  auctionID,
+		TradingPair: match.Pair{
+			AssetWant: match.BTC,
+			AssetHave: match.VTCTest,
+		},
+	}
+	copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+	if order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, order.SigHash(), false); err != nil {
+		return
+	}
+
+	if encOrder, err = order.TurnIntoEncryptedOrderWithParams(params); err != nil {
+		return
+	}
+
+	args.EncryptedOrderBytes, err = encOrder.Serialize()
+	return
+}
+
+func TestSubmitEncryptedAuctionOrder(t *testing.T) {
+	var err error
+
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		t.Errorf("Error setting up db client: %s", err)
+		return
+	}
+
+	// long auctions so the auction doesn't change during the test
+	var s *cxauctionserver.OpencxAuctionServer
+	if s, err = cxauctionserver.InitServer(testDB, 100, uint64(time.Hour/time.Microsecond)); err != nil {
+		t.Errorf("Error initializing server: %s", err)
+		return
+	}
+	cl := &OpencxAuctionRPC{Server: s}
+
+	var auctionID [32]byte
+	var params match.PuzzleParams
+	if auctionID, params, err = s.CurrentPuzzleParams(); err != nil {
+		t.Errorf("Error getting puzzle params: %s", err)
+		return
+	}
+
+	// An order for another auction is rejected, but that's not an error
+	var args SubmitArgs
+	var encOrder *match.EncryptedAuctionOrder
+	if args, encOrder, err = newTestEncryptedOrderArgs([32]byte{0xff}, &params); err != nil {
+		t.Errorf("Error creating wrong auction order: %s", err)
+		return
+	}
+
+	reply := new(SubmitReply)
+	if err = cl.SubmitEncryptedAuctionOrder(args, reply); err != nil {
+		t.Errorf("Error submitting wrong auction order: %s", err)
+		return
+	}
+
+	if reply.Accepted || reply.Reason == "" {
+		t.Errorf("Order for the wrong auction should be rejected with a reason")
+		return
+	}
+
+	var expectedID [32]byte
+	if expectedID, err = encOrder.OrderID(); err != nil {
+		t.Errorf("Error getting order ID: %s", err)
+		return
+	}
+
+	if reply.OrderID != expectedID {
+		t.Errorf("Rejected order should still have its ID in the reply, expected %x got %x", expectedID, reply.OrderID)
+		return
+	}
+
+	// It's rejected before it's stored or committed to, and counted as being for the wrong auction
+	var puzzles []*match.EncryptedAuctionOrder
+	for _, bookID := range [][32]byte{auctionID, [32]byte{0xff}} {
+		if puzzles, err = testDB.ViewAuctionPuzzleBook(bookID); err != nil {
+			t.Errorf("Error viewing puzzle book: %s", err)
+			return
+		}
+
+		if len(puzzles) != 0 {
+			t.Errorf("Order for the wrong auction should not be stored, found %d puzzles in auction %x", len(puzzles), bookID)
+			return
+		}
+	}
+
+	var metrics cxauctionserver.RejectionMetrics
+	if metrics, err = s.RejectionMetrics(); err != nil {
+		t.Errorf("Error getting rejection metrics: %s", err)
+		return
+	}
+
+	if metrics.Rejected[cxauctionserver.RejectedWrongAuction] != 1 {
+		t.Errorf("Order for the wrong auction should be counted as a wrong-auction rejection, got {%s}", metrics.Rejected)
+		return
+	}
+
+	if args, encOrder, err = newTestEncryptedOrderArgs(auctionID, &params); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+
+	reply = new(SubmitReply)
+	if err = cl.SubmitEncryptedAuctionOrder(args, reply); err != nil {
+		t.Errorf("Error submitting order: %s", err)
+		return
+	}
+
+	if !reply.Accepted || reply.Reason != "" {
+		t.Errorf("Order for the current auction should be accepted, got rejected: %s", reply.Reason)
+		return
+	}
+
+	if expectedID, err = encOrder.OrderID(); err != nil {
+		t.Errorf("Error getting order ID: %s", err)
+		return
+	}
+
+	if reply.OrderID != expectedID {
+		t.Errorf("Accepted order should have its ID in the reply, expected %x got %x", expectedID, reply.OrderID)
+		return
+	}
+
+	if puzzles, err = testDB.ViewAuctionPuzzleBook(auctionID); err != nil {
+		t.Errorf("Error viewing puzzle book: %s", err)
+		return
+	}
+
+	if len(puzzles) != 1 {
+		t.Errorf("Only the accepted order should be in the puzzle book, found %d puzzles", len(puzzles))
+		return
+	}
+
+	// Bytes that aren't an order don't have an ID, so they're an error
+	if err = cl.SubmitEncryptedAuctionOrder(SubmitArgs{EncryptedOrderBytes: []byte{0x01, 0x02}}, new(SubmitReply)); err == nil {
+		t.Errorf("Submitting bytes that aren't an encrypted order should fail")
+		return
+	}
+
+	return
+}
@@ -1,10 +1,19 @@
 package cxauctionrpc
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/match"
+)
 
 // GetPublicParametersArgs holds the args for the getpublicparameters command
 type GetPublicParametersArgs struct {
-	// empty
+	// Pair is the pair to get the auction of, since pairs can run their own auctions. If it's nil, or the
+	// pair doesn't run its own auction, the params are for the exchange's auction.
+	Pair *match.Pair
 }
 
 // GetPublicParametersReply holds the reply for the getpublicparameters command
@@ -14,17 +23,164 @@ type GetPublicParametersReply struct {
 	// take any less than this, and can actually verify that the exchange isn't running it
 	// for extra time.
 	AuctionTime uint64
+	// NextAuctionTime is when the auction after this one is scheduled to open. Orders that
+	// won't make it in before then should wait and use the auction ID of the next auction.
+	NextAuctionTime time.Time
+	// PuzzleParams are the parameters that order puzzles for this auction have to use. They're new
+	// for every auction.
+	PuzzleParams match.PuzzleParams
+	// NextAuctionWindow is how long before the next auction that orders for it are accepted. If this is 0
+	// then orders are only accepted for the current auction.
+	NextAuctionWindow time.Duration
+	// Network is the network magic that orders need to be signed with for this exchange
+	Network match.NetworkMagic
+	// MatchingMode is how the exchange matches orders. In batch mode orders are submitted as
+	// puzzles, in continuous mode they're submitted unencrypted.
+	MatchingMode cxauctionserver.MatchingMode
+	// UnsafeNoPuzzle is true if the exchange accepts batch orders without puzzles. This is only for
+	// testing, clients should never send plaintext orders to an exchange they don't control.
+	UnsafeNoPuzzle bool
+	// Pair is the pair the params were asked for, or nil if they weren't asked for a pair
+	Pair *match.Pair
+	// Signature is a compact signature of SerializeSignable by the exchange's key, so clients can check the
+	// params came from the exchange. It's empty if the exchange has no signing key. This was added after the
+	// other fields, and clients that don't know about it just ignore it.
+	Signature []byte
+}
+
+// SerializeSignable serializes what the exchange signs in the public parameters, which is every field
+// except the signature
+func (reply *GetPublicParametersReply) SerializeSignable() (buf []byte) {
+	var intBytes [8]byte
+	putUint64 := func(i uint64) {
+		binary.BigEndian.PutUint64(intBytes[:], i)
+		buf = append(buf, intBytes[:]...)
+	}
+
+	buf = append(buf, []byte("opencx-publicparameters")...)
+	buf = append(buf, reply.AuctionID[:]...)
+	putUint64(reply.AuctionTime)
+	putUint64(uint64(reply.NextAuctionTime.UnixNano()))
+	putUint64(uint64(reply.PuzzleParams.A))
+	putUint64(reply.PuzzleParams.T)
+	putUint64(uint64(reply.PuzzleParams.ModulusBits))
+	putUint64(uint64(reply.NextAuctionWindow))
+	putUint64(uint64(reply.Network))
+	buf = append(buf, byte(reply.MatchingMode))
+	if reply.UnsafeNoPuzzle {
+		buf = append(buf, 0x01)
+	} else {
+		buf = append(buf, 0x00)
+	}
+	// Params that weren't asked for a pair are signed the same as before pairs could run their own auctions
+	if reply.Pair != nil {
+		buf = append(buf, reply.Pair.Serialize()...)
+	}
+	return
 }
 
-// GetPublicParameters gets public parameters from the exchange, like time and auctionID
+// Verify checks that the public parameters were signed by the exchange with pubkey
+func (reply *GetPublicParametersReply) Verify(pubkey [33]byte) (err error) {
+	if len(reply.Signature) == 0 {
+		err = fmt.Errorf("Public parameters are not signed")
+		return
+	}
+
+	if err = cxauctionserver.VerifyServerSignature(reply.SerializeSignable(), reply.Signature, pubkey); err != nil {
+		err = fmt.Errorf("Error verifying public parameters: \n%s", err)
+		return
+	}
+	return
+}
+
+// DefaultParamCacheTTL is how long public parameters replies are cached for by default
+const DefaultParamCacheTTL = time.Second
+
+// GetPublicParameters gets public parameters from the exchange, like time and auctionID. If a pair is given,
+// the auction ID and times are for that pair's auction. Clients poll this a lot, so the reply for the exchange's
+// auction is cached for ParamCacheTTL, or until the auction changes.
 func (cl *OpencxAuctionRPC) GetPublicParameters(args GetPublicParametersArgs, reply *GetPublicParametersReply) (err error) {
-	if reply.AuctionID, err = cl.Server.CurrentAuctionID(); err != nil {
-		err = fmt.Errorf("Error getting public param auction id: %s", err)
+	if args.Pair != nil {
+		err = cl.publicParameters(args.Pair, reply)
+		return
+	}
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = cl.Server.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for public params: %s", err)
+		return
+	}
+
+	cl.paramCacheMtx.Lock()
+	defer cl.paramCacheMtx.Unlock()
+
+	if cl.paramCache != nil && cl.paramCache.AuctionID == currentAuctionID && time.Since(cl.paramCachedAt) < cl.ParamCacheTTL {
+		*reply = *cl.paramCache
+		return
+	}
+
+	if err = cl.publicParameters(nil, reply); err != nil {
+		return
+	}
+
+	if cl.ParamCacheTTL > 0 {
+		cachedReply := *reply
+		cl.paramCache = &cachedReply
+		cl.paramCachedAt = time.Now()
+	}
+
+	return
+}
+
+// publicParameters gets the public parameters for pair's auction from the server state. If pair is nil they're
+// for the exchange's auction.
+func (cl *OpencxAuctionRPC) publicParameters(pair *match.Pair, reply *GetPublicParametersReply) (err error) {
+	reply.Pair = pair
+
+	// Get these all at once so they describe the same auction
+	if reply.AuctionID, reply.AuctionTime, reply.NextAuctionTime, err = cl.Server.CurrentPairAuctionState(pair); err != nil {
+		err = fmt.Errorf("Error getting public param auction state: %s", err)
+		return
+	}
+
+	var paramsAuctionID [32]byte
+	if paramsAuctionID, reply.PuzzleParams, err = cl.Server.CurrentPairPuzzleParams(pair); err != nil {
+		err = fmt.Errorf("Error getting public param puzzle params: %s", err)
+		return
+	}
+
+	if paramsAuctionID != reply.AuctionID {
+		err = fmt.Errorf("Auction changed from %x to %x while getting public params, try again", reply.AuctionID, paramsAuctionID)
+		return
+	}
+
+	if reply.NextAuctionWindow, err = cl.Server.NextAuctionWindow(); err != nil {
+		err = fmt.Errorf("Error getting public param next auction window: %s", err)
+		return
+	}
+
+	if reply.Network, err = cl.Server.Network(); err != nil {
+		err = fmt.Errorf("Error getting public param network: %s", err)
+		return
+	}
+
+	if reply.MatchingMode, err = cl.Server.MatchingMode(); err != nil {
+		err = fmt.Errorf("Error getting public param matching mode: %s", err)
+		return
+	}
+
+	if reply.UnsafeNoPuzzle, err = cl.Server.UnsafeNoPuzzle(); err != nil {
+		err = fmt.Errorf("Error getting public param unsafe no puzzle: %s", err)
+		return
+	}
+
+	// Exchanges without a signing key still give out unsigned params
+	if _, keyErr := cl.Server.SigningPubkey(); keyErr != nil {
 		return
 	}
 
-	if reply.AuctionTime, err = cl.Server.CurrentAuctionTime(); err != nil {
-		err = fmt.Errorf("Error getting public param auction time: %s", err)
+	if reply.Signature, err = cl.Server.Sign(reply.SerializeSignable()); err != nil {
+		err = fmt.Errorf("Error signing public params: %s", err)
 		return
 	}
 
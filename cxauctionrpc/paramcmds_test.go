@@ -0,0 +1,310 @@
+package cxauctionrpc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestPublicParametersCacheInvalidatedOnNewAuction(t *testing.T) {
+	var err error
+
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		t.Errorf("Error setting up db client: %s", err)
+		return
+	}
+
+	var s *cxauctionserver.OpencxAuctionServer
+	if s, err = cxauctionserver.InitServer(testDB, 100, 100); err != nil {
+		t.Errorf("Error initializing server: %s", err)
+		return
+	}
+
+	// a long TTL so only the auction changing can invalidate the cache
+	cl := &OpencxAuctionRPC{
+		Server:        s,
+		ParamCacheTTL: time.Hour,
+	}
+
+	firstReply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{}, firstReply); err != nil {
+		t.Errorf("Error getting public parameters: %s", err)
+		return
+	}
+
+	// the network changing doesn't touch the cache, so the cached reply should still be given out
+	s.SetNetwork(match.RegtestMagic)
+	cachedReply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{}, cachedReply); err != nil {
+		t.Errorf("Error getting cached public parameters: %s", err)
+		return
+	}
+
+	if cachedReply.Network != firstReply.Network || cachedReply.AuctionID != firstReply.AuctionID {
+		t.Errorf("Public parameters should have come from the cache, got network %x for auction %x", cachedReply.Network, cachedReply.AuctionID)
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error advancing to a new auction: %s", err)
+		return
+	}
+
+	var newAuctionID [32]byte
+	if newAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting new auction ID: %s", err)
+		return
+	}
+
+	newReply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{}, newReply); err != nil {
+		t.Errorf("Error getting public parameters for the new auction: %s", err)
+		return
+	}
+
+	if newReply.AuctionID != newAuctionID || newReply.AuctionID == firstReply.AuctionID {
+		t.Errorf("Public parameters should be for new auction %x, got %x", newAuctionID, newReply.AuctionID)
+		return
+	}
+
+	if newReply.Network != match.RegtestMagic {
+		t.Errorf("Public parameters should have been recomputed with the new network, got %x", newReply.Network)
+		return
+	}
+
+	return
+}
+
+// initSignedParamsRPC creates an rpc whose server signs with privkey, and doesn't cache params
+func initSignedParamsRPC(privkey *koblitz.PrivateKey) (cl *OpencxAuctionRPC, err error) {
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		return
+	}
+
+	var s *cxauctionserver.OpencxAuctionServer
+	if s, err = cxauctionserver.InitServer(testDB, 100, 100); err != nil {
+		return
+	}
+
+	if privkey != nil {
+		if err = s.SetSigningKey(privkey); err != nil {
+			return
+		}
+	}
+
+	cl = &OpencxAuctionRPC{Server: s}
+	return
+}
+
+func TestPublicParametersSigned(t *testing.T) {
+	var err error
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating signing key: %s", err)
+		return
+	}
+
+	var cl *OpencxAuctionRPC
+	if cl, err = initSignedParamsRPC(privkey); err != nil {
+		t.Errorf("Error initializing rpc: %s", err)
+		return
+	}
+
+	reply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{}, reply); err != nil {
+		t.Errorf("Error getting public parameters: %s", err)
+		return
+	}
+
+	var pubkey [33]byte
+	copy(pubkey[:], privkey.PubKey().SerializeCompressed())
+	if err = reply.Verify(pubkey); err != nil {
+		t.Errorf("Signed public parameters should verify: %s", err)
+		return
+	}
+
+	// Changing any of the params should break the signature
+	reply.AuctionTime++
+	if err = reply.Verify(pubkey); err == nil {
+		t.Errorf("Public parameters that were changed after signing should not verify")
+		return
+	}
+
+	// Exchanges without a key still give out params, they just aren't signed
+	if cl, err = initSignedParamsRPC(nil); err != nil {
+		t.Errorf("Error initializing rpc without signing key: %s", err)
+		return
+	}
+
+	unsignedReply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{}, unsignedReply); err != nil {
+		t.Errorf("Exchange without a signing key should still give out public parameters: %s", err)
+		return
+	}
+
+	if len(unsignedReply.Signature) != 0 || unsignedReply.Verify(pubkey) == nil {
+		t.Errorf("Public parameters from an exchange without a signing key should not be signed")
+		return
+	}
+
+	return
+}
+
+func TestPublicParametersForPair(t *testing.T) {
+	var err error
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating signing key: %s", err)
+		return
+	}
+
+	var cl *OpencxAuctionRPC
+	if cl, err = initSignedParamsRPC(privkey); err != nil {
+		t.Errorf("Error initializing rpc: %s", err)
+		return
+	}
+
+	vtcPair := match.Pair{AssetWant: match.BTC, AssetHave: match.VTCTest}
+	ltcPair := match.Pair{AssetWant: match.BTC, AssetHave: match.LTCTest}
+	if err = cl.Server.StartPairAuction(&vtcPair, uint64(time.Hour/time.Microsecond)); err != nil {
+		t.Errorf("Error starting pair auction: %s", err)
+		return
+	}
+
+	var pairAuctionID [32]byte
+	var pairDeadline time.Time
+	if pairAuctionID, _, pairDeadline, err = cl.Server.CurrentPairAuctionState(&vtcPair); err != nil {
+		t.Errorf("Error getting pair auction state: %s", err)
+		return
+	}
+
+	reply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{Pair: &vtcPair}, reply); err != nil {
+		t.Errorf("Error getting public parameters for %s: %s", vtcPair.PrettyString(), err)
+		return
+	}
+
+	if reply.AuctionID != pairAuctionID || !reply.NextAuctionTime.Equal(pairDeadline) {
+		t.Errorf("Public parameters for %s should be for its auction %x, got %x", vtcPair.PrettyString(), pairAuctionID, reply.AuctionID)
+		return
+	}
+
+	var pubkey [33]byte
+	copy(pubkey[:], privkey.PubKey().SerializeCompressed())
+	if err = reply.Verify(pubkey); err != nil {
+		t.Errorf("Signed public parameters for a pair should verify: %s", err)
+		return
+	}
+
+	// The signature covers the pair, so they can't be passed off as another pair's
+	reply.Pair = &ltcPair
+	if err = reply.Verify(pubkey); err == nil {
+		t.Errorf("Public parameters for one pair should not verify as another's")
+		return
+	}
+
+	// Pairs that don't run their own auction are in the exchange's, which keeps ticking
+	exchangeReply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{Pair: &ltcPair}, exchangeReply); err != nil {
+		t.Errorf("Error getting public parameters for %s: %s", ltcPair.PrettyString(), err)
+		return
+	}
+
+	if exchangeReply.AuctionID == pairAuctionID || exchangeReply.AuctionTime != 100 {
+		t.Errorf("Public parameters for %s should be for the exchange's auction, got %x", ltcPair.PrettyString(), exchangeReply.AuctionID)
+		return
+	}
+
+	return
+}
+
+// oldGetPublicParametersReply is the public parameters reply from before it was signed, like an old
+// client would decode it into
+type oldGetPublicParametersReply struct {
+	AuctionID         [32]byte
+	AuctionTime       uint64
+	NextAuctionTime   time.Time
+	PuzzleParams      match.PuzzleParams
+	NextAuctionWindow time.Duration
+	Network           match.NetworkMagic
+	MatchingMode      cxauctionserver.MatchingMode
+	UnsafeNoPuzzle    bool
+}
+
+func TestPublicParametersOldClientCompat(t *testing.T) {
+	var err error
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating signing key: %s", err)
+		return
+	}
+
+	var cl *OpencxAuctionRPC
+	if cl, err = initSignedParamsRPC(privkey); err != nil {
+		t.Errorf("Error initializing rpc: %s", err)
+		return
+	}
+
+	reply := new(GetPublicParametersReply)
+	if err = cl.GetPublicParameters(GetPublicParametersArgs{}, reply); err != nil {
+		t.Errorf("Error getting public parameters: %s", err)
+		return
+	}
+
+	// net/rpc sends replies with gob, which is what old clients decode with
+	var buf bytes.Buffer
+	if err = gob.NewEncoder(&buf).Encode(reply); err != nil {
+		t.Errorf("Error encoding signed public parameters: %s", err)
+		return
+	}
+
+	oldReply := new(oldGetPublicParametersReply)
+	if err = gob.NewDecoder(&buf).Decode(oldReply); err != nil {
+		t.Errorf("Old client should be able to decode signed public parameters: %s", err)
+		return
+	}
+
+	if oldReply.AuctionID != reply.AuctionID ||
+		oldReply.AuctionTime != reply.AuctionTime ||
+		!oldReply.NextAuctionTime.Equal(reply.NextAuctionTime) ||
+		oldReply.PuzzleParams != reply.PuzzleParams ||
+		oldReply.NextAuctionWindow != reply.NextAuctionWindow ||
+		oldReply.Network != reply.Network ||
+		oldReply.MatchingMode != reply.MatchingMode ||
+		oldReply.UnsafeNoPuzzle != reply.UnsafeNoPuzzle {
+		t.Errorf("Old client decoded different public parameters than were sent: %+v, expected %+v", oldReply, reply)
+		return
+	}
+
+	// A new client talking to an old exchange just gets no signature
+	buf.Reset()
+	if err = gob.NewEncoder(&buf).Encode(oldReply); err != nil {
+		t.Errorf("Error encoding old public parameters: %s", err)
+		return
+	}
+
+	newReply := new(GetPublicParametersReply)
+	if err = gob.NewDecoder(&buf).Decode(newReply); err != nil {
+		t.Errorf("New client should be able to decode old public parameters: %s", err)
+		return
+	}
+
+	if len(newReply.Signature) != 0 || newReply.AuctionID != reply.AuctionID {
+		t.Errorf("Old public parameters should decode with no signature and the same auction %x, got auction %x", reply.AuctionID, newReply.AuctionID)
+		return
+	}
+
+	return
+}
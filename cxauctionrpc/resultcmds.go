@@ -0,0 +1,79 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/match"
+	"github.com/mit-dci/opencx/util"
+)
+
+// GetAuctionResultArgs holds the args for the getauctionresult command
+type GetAuctionResultArgs struct {
+	AuctionID [32]byte
+	Pair      match.Pair
+}
+
+// GetAuctionResultReply holds the reply for the getauctionresult command
+type GetAuctionResultReply struct {
+	// Result is signed by the exchange if it has a signing key, check it with Result.Verify
+	Result *cxauctionserver.AuctionResult
+}
+
+// GetAuctionResult gets the clearing price and commitment root of a pair's auction, signed by the exchange
+func (cl *OpencxAuctionRPC) GetAuctionResult(args GetAuctionResultArgs, reply *GetAuctionResultReply) (err error) {
+	if reply.Result, err = cl.Server.AuctionResult(&args.Pair, args.AuctionID); err != nil {
+		err = fmt.Errorf("Error getting auction result: \n%s", err)
+		return
+	}
+
+	return
+}
+
+// GetAuctionResultsArgs holds the args for the getauctionresults command
+type GetAuctionResultsArgs struct {
+	AuctionID [32]byte
+	// Offset and Limit are the page of fills to get, a Limit of zero gets every fill after Offset
+	Offset uint64
+	Limit  uint64
+}
+
+// GetAuctionResultsReply holds the reply for the getauctionresults command
+type GetAuctionResultsReply struct {
+	// Fills has how much each order in the auction filled, and the clearing price of its pair
+	Fills []*match.AuctionFill
+	// TotalCount is how many fills the auction has, across every page
+	TotalCount uint64
+	// Clearings has the exact clearing price of each pair in the auction, or that the pair didn't match
+	Clearings []*match.AuctionClearing
+	// NoMatch is true if nothing matched in any pair of the auction, so there is no clearing price. This is
+	// an explicit result, an auction that isn't known or hasn't been cleared is an error instead.
+	NoMatch bool
+}
+
+// GetAuctionResults gets a page of how much each order filled in an auction that has been cleared. Results are
+// stored, so clients that connect after the auction is over can still get them.
+func (cl *OpencxAuctionRPC) GetAuctionResults(args GetAuctionResultsArgs, reply *GetAuctionResultsReply) (err error) {
+	if reply.Clearings, err = cl.Server.AuctionClearings(args.AuctionID); err != nil {
+		err = fmt.Errorf("Error getting auction results: \n%s", err)
+		return
+	}
+
+	reply.NoMatch = true
+	for _, clearing := range reply.Clearings {
+		if !clearing.NoMatch {
+			reply.NoMatch = false
+		}
+	}
+
+	if reply.Fills, err = cl.Server.AuctionFills(args.AuctionID); err != nil {
+		err = fmt.Errorf("Error getting auction results: \n%s", err)
+		return
+	}
+
+	reply.TotalCount = uint64(len(reply.Fills))
+	start, end := util.PageBounds(len(reply.Fills), args.Offset, args.Limit)
+	reply.Fills = reply.Fills[start:end]
+
+	return
+}
@@ -0,0 +1,260 @@
+package cxauctionrpc
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxauctionserver"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestGetAuctionResults(t *testing.T) {
+	var err error
+
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		t.Errorf("Error setting up db client: %s", err)
+		return
+	}
+
+	var s *cxauctionserver.OpencxAuctionServer
+	if s, err = cxauctionserver.InitServer(testDB, 100, uint64(time.Hour/time.Microsecond)); err != nil {
+		t.Errorf("Error initializing server: %s", err)
+		return
+	}
+	cl := &OpencxAuctionRPC{Server: s}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction: %s", err)
+		return
+	}
+
+	pair := match.Pair{
+		AssetWant: match.BTC,
+		AssetHave: match.VTCTest,
+	}
+
+	// 10000 buy volume and 5000 sell volume at 2, so both buys fill half
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	orders := []*match.AuctionOrder{
+		{Side: "buy", AmountHave: 2000, AmountWant: 4000, Nonce: [2]byte{0x00}},
+		{Side: "buy", AmountHave: 3000, AmountWant: 6000, Nonce: [2]byte{0x01}},
+		{Side: "sell", AmountHave: 5000, AmountWant: 2500, Nonce: [2]byte{0x02}},
+	}
+	for _, order := range orders {
+		order.TradingPair = pair
+		order.AuctionID = auctionID
+		copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+		if order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, order.SigHash(), false); err != nil {
+			t.Errorf("Error signing order: %s", err)
+			return
+		}
+		if err = testDB.PlaceAuctionOrder(order); err != nil {
+			t.Errorf("Error placing order: %s", err)
+			return
+		}
+	}
+
+	if _, err = s.ClearPairAuction(&pair, auctionID); err == nil {
+		t.Errorf("An auction that is still running should not be cleared")
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error committing orders: %s", err)
+		return
+	}
+
+	reply := new(GetAuctionResultsReply)
+	if err = cl.GetAuctionResults(GetAuctionResultsArgs{AuctionID: auctionID}, reply); err == nil {
+		t.Errorf("Getting results for an auction that hasn't been cleared should fail")
+		return
+	}
+
+	if _, err = s.ClearPairAuction(&pair, auctionID); err != nil {
+		t.Errorf("Error clearing auction: %s", err)
+		return
+	}
+
+	if _, err = s.ClearPairAuction(&pair, auctionID); err == nil {
+		t.Errorf("Clearing the same auction twice should fail")
+		return
+	}
+
+	reply = new(GetAuctionResultsReply)
+	if err = cl.GetAuctionResults(GetAuctionResultsArgs{AuctionID: auctionID}, reply); err != nil {
+		t.Errorf("Error getting auction results: %s", err)
+		return
+	}
+
+	if len(reply.Fills) != len(orders) {
+		t.Errorf("There should be a fill for every order, expected %d got %d", len(orders), len(reply.Fills))
+		return
+	}
+
+	expected := make(map[[32]byte]uint64)
+	expected[orders[0].Hash()] = 2000
+	expected[orders[1].Hash()] = 3000
+	expected[orders[2].Hash()] = 5000
+	for _, fill := range reply.Fills {
+		if fill.ClearingPrice == nil || fill.ClearingPrice.Cmp(big.NewRat(2, 1)) != 0 {
+			t.Errorf("Clearing price should be exactly 2, got %f", fill.ClearingPriceFloat())
+			return
+		}
+
+		if amount, found := expected[fill.OrderHash]; !found || fill.AmountFilled != amount {
+			t.Errorf("Wrong fill for order: %s", fill)
+			return
+		}
+
+		if fill.Partial() != (fill.Side == "buy") {
+			t.Errorf("Only the buys should be partially filled: %s", fill)
+			return
+		}
+	}
+
+	if reply.TotalCount != uint64(len(orders)) {
+		t.Errorf("Total count should be %d, got %d", len(orders), reply.TotalCount)
+		return
+	}
+
+	if reply.NoMatch || len(reply.Clearings) != 1 || reply.Clearings[0].ClearingPrice.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("Auction should have matched at exactly 2, got %v", reply.Clearings)
+		return
+	}
+
+	// Paging one fill at a time gets the same fills in the same order, and then an empty page
+	var paged []*match.AuctionFill
+	for offset := uint64(0); offset <= uint64(len(orders)); offset++ {
+		page := new(GetAuctionResultsReply)
+		if err = cl.GetAuctionResults(GetAuctionResultsArgs{AuctionID: auctionID, Offset: offset, Limit: 1}, page); err != nil {
+			t.Errorf("Error getting page of auction results at offset %d: %s", offset, err)
+			return
+		}
+
+		if page.TotalCount != uint64(len(orders)) {
+			t.Errorf("Total count should be %d on every page, got %d", len(orders), page.TotalCount)
+			return
+		}
+		paged = append(paged, page.Fills...)
+	}
+
+	if len(paged) != len(reply.Fills) {
+		t.Errorf("Paging should get all %d fills, got %d", len(reply.Fills), len(paged))
+		return
+	}
+
+	for i := range paged {
+		if paged[i].OrderHash != reply.Fills[i].OrderHash {
+			t.Errorf("Fill %d from paging is out of order", i)
+			return
+		}
+	}
+
+	// An auction that never happened has no results
+	if err = cl.GetAuctionResults(GetAuctionResultsArgs{AuctionID: [32]byte{0xff}}, new(GetAuctionResultsReply)); err == nil {
+		t.Errorf("Getting results for an unknown auction should fail")
+		return
+	}
+
+	return
+}
+
+func TestGetAuctionResultsNoMatch(t *testing.T) {
+	var err error
+
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		t.Errorf("Error setting up db client: %s", err)
+		return
+	}
+
+	var s *cxauctionserver.OpencxAuctionServer
+	if s, err = cxauctionserver.InitServer(testDB, 100, uint64(time.Hour/time.Microsecond)); err != nil {
+		t.Errorf("Error initializing server: %s", err)
+		return
+	}
+	cl := &OpencxAuctionRPC{Server: s}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction: %s", err)
+		return
+	}
+
+	pair := match.Pair{
+		AssetWant: match.BTC,
+		AssetHave: match.VTCTest,
+	}
+
+	// The buy pays 1 and the sell asks 2, so the book doesn't cross
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	orders := []*match.AuctionOrder{
+		{Side: "buy", AmountHave: 1000, AmountWant: 1000, Nonce: [2]byte{0x00}},
+		{Side: "sell", AmountHave: 1000, AmountWant: 500, Nonce: [2]byte{0x01}},
+	}
+	for _, order := range orders {
+		order.TradingPair = pair
+		order.AuctionID = auctionID
+		copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+		if order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, order.SigHash(), false); err != nil {
+			t.Errorf("Error signing order: %s", err)
+			return
+		}
+		if err = testDB.PlaceAuctionOrder(order); err != nil {
+			t.Errorf("Error placing order: %s", err)
+			return
+		}
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error committing orders: %s", err)
+		return
+	}
+
+	if _, err = s.ClearPairAuction(&pair, auctionID); err != nil {
+		t.Errorf("Error clearing auction: %s", err)
+		return
+	}
+
+	// Nothing matching is an explicit result, not an error
+	reply := new(GetAuctionResultsReply)
+	if err = cl.GetAuctionResults(GetAuctionResultsArgs{AuctionID: auctionID}, reply); err != nil {
+		t.Errorf("Error getting results of auction that didn't match: %s", err)
+		return
+	}
+
+	if !reply.NoMatch {
+		t.Errorf("Auction that doesn't cross should be an explicit no match")
+		return
+	}
+
+	if len(reply.Clearings) != 1 || !reply.Clearings[0].NoMatch || reply.Clearings[0].ClearingPrice != nil {
+		t.Errorf("Pair that doesn't cross should have no clearing price, got %v", reply.Clearings)
+		return
+	}
+
+	for _, fill := range reply.Fills {
+		if fill.AmountFilled != 0 {
+			t.Errorf("Nothing should fill in an auction that doesn't cross: %s", fill)
+			return
+		}
+	}
+
+	return
+}
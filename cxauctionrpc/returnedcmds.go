@@ -0,0 +1,58 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// GetReturnedOrdersArgs holds the args for the getreturnedorders command
+type GetReturnedOrdersArgs struct {
+	// AuctionID is the current auction, so the request can't be replayed later
+	AuctionID [32]byte
+	// Signature is a compact signature of SerializeSignable, so we can do pubkey recovery
+	Signature []byte
+}
+
+// GetReturnedOrdersReply holds the reply for the getreturnedorders command
+type GetReturnedOrdersReply struct {
+	ReturnedOrders []*match.ReturnedOrder
+}
+
+// SerializeSignable serializes what should be signed to get returned orders
+func (args *GetReturnedOrdersArgs) SerializeSignable() (buf []byte) {
+	buf = append(buf, []byte("opencx-getreturnedorders")...)
+	buf = append(buf, args.AuctionID[:]...)
+	return
+}
+
+// GetReturnedOrders gets the orders placed by the pubkey that signed the request that didn't match, and why
+func (cl *OpencxAuctionRPC) GetReturnedOrders(args GetReturnedOrdersArgs, reply *GetReturnedOrdersReply) (err error) {
+
+	// e = h(getreturnedorders || auctionID)
+	sha3 := sha3.New256()
+	sha3.Write(args.SerializeSignable())
+	e := sha3.Sum(nil)
+
+	var pubkey *koblitz.PublicKey
+	if pubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), args.Signature, e); err != nil {
+		err = fmt.Errorf("Error verifying returned orders request, invalid signature: \n%s", err)
+		return
+	}
+
+	if reply.ReturnedOrders, err = cl.Server.GetReturnedOrders(pubkey, args.AuctionID); err != nil {
+		err = fmt.Errorf("Error getting returned orders: \n%s", err)
+		return
+	}
+
+	for _, returnedOrder := range reply.ReturnedOrders {
+		if err = returnedOrder.Order.SetOrderbookPrice(); err != nil {
+			err = fmt.Errorf("Error setting orderbook price for returned order: \n%s", err)
+			return
+		}
+	}
+
+	return
+}
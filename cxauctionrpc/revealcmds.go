@@ -0,0 +1,32 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/cxauctionserver"
+)
+
+// RevealAuctionKeysArgs holds the args for the revealauctionkeys command
+type RevealAuctionKeysArgs struct {
+	AuctionID [32]byte
+}
+
+// RevealAuctionKeysReply holds the reply for the revealauctionkeys command
+type RevealAuctionKeysReply struct {
+	// Keys decrypt the orders placed in the auction, which of them are revealed depends on the exchange's reveal
+	// policy
+	Keys []*cxauctionserver.RevealedKey
+}
+
+// RevealAuctionKeys gets the keys of the orders in an auction that is over, so anyone can decrypt them without
+// solving their puzzles. Depending on the exchange's reveal policy, these are the keys of every order or only
+// the ones that matched. If some of the orders are still being solved, this fails until they are or the
+// exchange's reveal grace period is over, so all of the keys are revealed together.
+func (cl *OpencxAuctionRPC) RevealAuctionKeys(args RevealAuctionKeysArgs, reply *RevealAuctionKeysReply) (err error) {
+	if reply.Keys, err = cl.Server.RevealAuctionKeys(args.AuctionID); err != nil {
+		err = fmt.Errorf("Error revealing auction keys: \n%s", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,77 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+// GetAuctionStatsArgs holds the args for the getauctionstats command
+type GetAuctionStatsArgs struct {
+	AuctionID [32]byte
+	Pair      match.Pair
+}
+
+// GetAuctionStatsReply holds the reply for the getauctionstats command
+type GetAuctionStatsReply struct {
+	Imbalance *match.AuctionImbalance
+	// ClearingPrice is the clearing price formatted with the exchange's price precision, along with
+	// the exact value it was rounded from. This is nil if nothing matched, since the clearing price is undefined.
+	ClearingPrice *match.FormattedPrice
+}
+
+// GetAuctionStats gets the stats for a pair's auction, like the buy and sell volume imbalance at the clearing price
+func (cl *OpencxAuctionRPC) GetAuctionStats(args GetAuctionStatsArgs, reply *GetAuctionStatsReply) (err error) {
+	if reply.Imbalance, err = cl.Server.AuctionImbalance(&args.Pair, args.AuctionID); err != nil {
+		err = fmt.Errorf("Error getting auction stats: \n%s", err)
+		return
+	}
+
+	if reply.Imbalance.NoMatch {
+		return
+	}
+
+	var precision uint
+	if precision, err = cl.Server.PricePrecision(); err != nil {
+		err = fmt.Errorf("Error getting price precision for auction stats: \n%s", err)
+		return
+	}
+
+	clearingPrice := new(big.Rat)
+	if clearingPrice.SetFloat64(reply.Imbalance.ClearingPrice) == nil {
+		err = fmt.Errorf("Clearing price %f is not finite", reply.Imbalance.ClearingPrice)
+		return
+	}
+
+	if reply.ClearingPrice, err = match.FormatPrice(clearingPrice, precision); err != nil {
+		err = fmt.Errorf("Error formatting clearing price: \n%s", err)
+		return
+	}
+
+	return
+}
+
+// GetOrderBookDepthArgs holds the args for the getorderbookdepth command
+type GetOrderBookDepthArgs struct {
+	AuctionID [32]byte
+	Pair      match.Pair
+	// Levels is the most points to return on each side of the book, 0 means every price
+	Levels int
+}
+
+// GetOrderBookDepthReply holds the reply for the getorderbookdepth command
+type GetOrderBookDepthReply struct {
+	Depth *match.DepthChart
+}
+
+// GetOrderBookDepth gets the cumulative depth of a pair's auction order book, for plotting. This is only
+// available for auctions whose orders have been revealed.
+func (cl *OpencxAuctionRPC) GetOrderBookDepth(args GetOrderBookDepthArgs, reply *GetOrderBookDepthReply) (err error) {
+	if reply.Depth, err = cl.Server.OrderBookDepth(&args.Pair, args.AuctionID, args.Levels); err != nil {
+		err = fmt.Errorf("Error getting order book depth: \n%s", err)
+		return
+	}
+
+	return
+}
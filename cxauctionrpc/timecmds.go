@@ -0,0 +1,54 @@
+package cxauctionrpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/cxauctionserver"
+)
+
+// GetSignedTimeArgs holds the args for the getsignedtime command
+type GetSignedTimeArgs struct {
+	// empty
+}
+
+// GetSignedTimeReply holds the reply for the getsignedtime command
+type GetSignedTimeReply struct {
+	// Timestamp is the server's time in unix nanoseconds
+	Timestamp int64
+	// Signature is a compact signature of the timestamp by the server's key
+	Signature []byte
+	// Pubkey is the server's key. Clients should verify against a pubkey they already trust for the
+	// exchange, not this one.
+	Pubkey [33]byte
+}
+
+// Time returns the server's signed time
+func (reply *GetSignedTimeReply) Time() (serverTime time.Time) {
+	serverTime = time.Unix(0, reply.Timestamp)
+	return
+}
+
+// Verify checks that the signed time was signed by the exchange with pubkey
+func (reply *GetSignedTimeReply) Verify(pubkey [33]byte) (err error) {
+	if err = cxauctionserver.VerifySignedTime(reply.Timestamp, reply.Signature, pubkey); err != nil {
+		err = fmt.Errorf("Error verifying signed time: \n%s", err)
+		return
+	}
+	return
+}
+
+// GetSignedTime gets the server's current time signed by its key, so clients can audit auction timing
+func (cl *OpencxAuctionRPC) GetSignedTime(args GetSignedTimeArgs, reply *GetSignedTimeReply) (err error) {
+	if reply.Pubkey, err = cl.Server.SigningPubkey(); err != nil {
+		err = fmt.Errorf("Error getting server pubkey for signed time: \n%s", err)
+		return
+	}
+
+	if reply.Timestamp, reply.Signature, err = cl.Server.SignedTime(); err != nil {
+		err = fmt.Errorf("Error getting signed time: \n%s", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,199 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// DefaultAdminMetricsInterval is how often each admin can get admin metrics by default
+const DefaultAdminMetricsInterval = time.Second
+
+// AdminMetrics are the detailed internal metrics of the exchange, which are only given to admins
+type AdminMetrics struct {
+	// Health is whether or not the auction clock is still advancing auctions
+	Health AuctionHealth
+	// CurrentPuzzles is the number of puzzles submitted to the current auction
+	CurrentPuzzles int
+	// QueuedOrders is the number of orders queued for the next auction
+	QueuedOrders int
+	// PendingSolves is the number of orders waiting to be solved, EvictedSolves and RejectedSolves are how
+	// many orders the solve queue has evicted and rejected
+	PendingSolves  int
+	EvictedSolves  uint64
+	RejectedSolves uint64
+	// MaxAuctions is the most pair auctions that can run at once, ActiveAuctions is how many are running,
+	// and QueuedAuctions is how many are waiting to run
+	MaxAuctions    uint64
+	ActiveAuctions uint64
+	QueuedAuctions uint64
+	// Verify are the signature verification throughput metrics
+	Verify match.VerifyMetrics
+	// Rejections are how many submitted orders were accepted, and how many orders were rejected for each reason
+	Rejections RejectionMetrics
+	// Pairs is the state of every pair that has a price band or recorded stats
+	Pairs []*PairAdminMetrics
+}
+
+// PairAdminMetrics is the state of a single pair
+type PairAdminMetrics struct {
+	Pair match.Pair
+	// PriceBand is the price band clearing prices for the pair must be in, if there is one
+	PriceBand *match.PriceBand
+	// LastImbalance is the imbalance of the most recent recorded auction for the pair, if there is one
+	LastImbalance *match.AuctionImbalance
+}
+
+// SetAdminPubkeys sets the pubkeys that are allowed to get admin metrics, replacing the ones that were set before
+func (s *OpencxAuctionServer) SetAdminPubkeys(pubkeys []*koblitz.PublicKey) {
+	admins := make(map[[33]byte]bool)
+	for _, pubkey := range pubkeys {
+		var pubkeyBytes [33]byte
+		copy(pubkeyBytes[:], pubkey.SerializeCompressed())
+		admins[pubkeyBytes] = true
+	}
+
+	s.adminMtx.Lock()
+	s.adminPubkeys = admins
+	s.adminMtx.Unlock()
+
+	logging.Infof("Set %d admin pubkeys", len(admins))
+	return
+}
+
+// IsAdmin returns whether or not pubkey is an admin
+func (s *OpencxAuctionServer) IsAdmin(pubkey *koblitz.PublicKey) (admin bool) {
+	var pubkeyBytes [33]byte
+	copy(pubkeyBytes[:], pubkey.SerializeCompressed())
+
+	s.adminMtx.Lock()
+	admin = s.adminPubkeys[pubkeyBytes]
+	s.adminMtx.Unlock()
+	return
+}
+
+// SetAdminMetricsInterval sets how long each admin has to wait between getting admin metrics. Zero means
+// admins can get them as often as they want.
+func (s *OpencxAuctionServer) SetAdminMetricsInterval(interval time.Duration) (err error) {
+	if interval < 0 {
+		err = fmt.Errorf("Admin metrics interval cannot be negative, got %s", interval)
+		return
+	}
+
+	s.adminMtx.Lock()
+	s.adminInterval = interval
+	s.adminMtx.Unlock()
+	return
+}
+
+// AdminMetrics returns the detailed internal metrics of the exchange, if pubkey is an admin that hasn't
+// gotten them too recently.
+func (s *OpencxAuctionServer) AdminMetrics(pubkey *koblitz.PublicKey) (metrics *AdminMetrics, err error) {
+	if err = s.allowAdminCall(pubkey); err != nil {
+		return
+	}
+
+	metrics = new(AdminMetrics)
+	if metrics.Health, err = s.Health(); err != nil {
+		err = fmt.Errorf("Error getting health for admin metrics: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for admin metrics: %s", err)
+		return
+	}
+
+	var puzzles []*match.EncryptedAuctionOrder
+	s.dbLock.Lock()
+	if puzzles, err = s.OpencxDB.ViewAuctionPuzzleBook(auctionID); err != nil {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Error getting puzzle book for admin metrics: %s", err)
+		return
+	}
+	s.dbLock.Unlock()
+	metrics.CurrentPuzzles = len(puzzles)
+
+	s.queuedMtx.Lock()
+	metrics.QueuedOrders = len(s.queuedOrders[auctionID])
+	s.queuedMtx.Unlock()
+
+	metrics.PendingSolves, metrics.EvictedSolves, metrics.RejectedSolves = s.solves.status()
+	metrics.MaxAuctions, metrics.ActiveAuctions, metrics.QueuedAuctions = s.auctionSlots.status()
+
+	if metrics.Verify, err = s.VerifyMetrics(); err != nil {
+		err = fmt.Errorf("Error getting verify metrics for admin metrics: %s", err)
+		return
+	}
+
+	if metrics.Rejections, err = s.RejectionMetrics(); err != nil {
+		err = fmt.Errorf("Error getting rejection metrics for admin metrics: %s", err)
+		return
+	}
+
+	metrics.Pairs = s.pairAdminMetrics()
+	return
+}
+
+// allowAdminCall returns an error if pubkey isn't an admin, or if it got admin metrics less than the admin
+// metrics interval ago. Otherwise the call is counted for rate limiting.
+func (s *OpencxAuctionServer) allowAdminCall(pubkey *koblitz.PublicKey) (err error) {
+	var pubkeyBytes [33]byte
+	copy(pubkeyBytes[:], pubkey.SerializeCompressed())
+
+	s.adminMtx.Lock()
+	defer s.adminMtx.Unlock()
+
+	if !s.adminPubkeys[pubkeyBytes] {
+		err = fmt.Errorf("Pubkey %x is not an admin", pubkeyBytes)
+		return
+	}
+
+	now := time.Now()
+	if lastCall, found := s.adminCalls[pubkeyBytes]; found && now.Sub(lastCall) < s.adminInterval {
+		err = fmt.Errorf("Admin metrics can only be gotten every %s, try again in %s", s.adminInterval, s.adminInterval-now.Sub(lastCall))
+		return
+	}
+	s.adminCalls[pubkeyBytes] = now
+
+	return
+}
+
+// pairAdminMetrics returns the state of every pair that has a price band or recorded stats, sorted by pair
+func (s *OpencxAuctionServer) pairAdminMetrics() (pairs []*PairAdminMetrics) {
+	pairMetrics := make(map[match.Pair]*PairAdminMetrics)
+	getPair := func(pair match.Pair) *PairAdminMetrics {
+		if _, found := pairMetrics[pair]; !found {
+			pairMetrics[pair] = &PairAdminMetrics{Pair: pair}
+			pairs = append(pairs, pairMetrics[pair])
+		}
+		return pairMetrics[pair]
+	}
+
+	s.auctionMtx.RLock()
+	for pair, band := range s.priceBands {
+		bandCopy := *band
+		getPair(pair).PriceBand = &bandCopy
+	}
+	s.auctionMtx.RUnlock()
+
+	// go through the auctions in the order they were recorded, so the most recent imbalance is kept
+	s.statsMtx.Lock()
+	for _, auctionID := range s.statsAuctions {
+		for pair, imbalance := range s.auctionImbalances[auctionID] {
+			imbalanceCopy := *imbalance
+			getPair(pair).LastImbalance = &imbalanceCopy
+		}
+	}
+	s.statsMtx.Unlock()
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Pair.String() < pairs[j].Pair.String()
+	})
+	return
+}
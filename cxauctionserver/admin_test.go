@@ -0,0 +1,78 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestAdminMetricsPairs(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestAdminMetricsPairs: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	if _, err = s.AdminMetrics(privkey.PubKey()); err == nil {
+		t.Errorf("Admin metrics should not be given out before any admins are set")
+		return
+	}
+
+	s.SetAdminPubkeys([]*koblitz.PublicKey{privkey.PubKey()})
+	if !s.IsAdmin(privkey.PubKey()) {
+		t.Errorf("Pubkey should be an admin after being set as one")
+		return
+	}
+
+	if err = s.SetAdminMetricsInterval(-time.Second); err == nil {
+		t.Errorf("Setting a negative admin metrics interval should fail")
+		return
+	}
+
+	if err = s.SetAdminMetricsInterval(0); err != nil {
+		t.Errorf("Error setting admin metrics interval: %s", err)
+		return
+	}
+
+	pair := match.Pair{AssetWant: match.BTC, AssetHave: match.VTCTest}
+	if err = s.SetPriceBand(pair, &match.PriceBand{MinPrice: 1, MaxPrice: 2}); err != nil {
+		t.Errorf("Error setting price band: %s", err)
+		return
+	}
+
+	var metrics *AdminMetrics
+	if metrics, err = s.AdminMetrics(privkey.PubKey()); err != nil {
+		t.Errorf("Error getting admin metrics: %s", err)
+		return
+	}
+
+	if len(metrics.Pairs) != 1 || metrics.Pairs[0].Pair != pair || metrics.Pairs[0].PriceBand.MaxPrice != 2 {
+		t.Errorf("Admin metrics should have the pair with the price band")
+		return
+	}
+
+	// With no rate limit, admins can ask as often as they want
+	if _, err = s.AdminMetrics(privkey.PubKey()); err != nil {
+		t.Errorf("Admin metrics should not be rate limited with no interval: %s", err)
+		return
+	}
+
+	// Removing the admin takes away access
+	s.SetAdminPubkeys(nil)
+	if _, err = s.AdminMetrics(privkey.PubKey()); err == nil {
+		t.Errorf("Pubkeys that are no longer admins should not get admin metrics")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,18 @@
+package cxauctionserver
+
+// SetMaxAmountRatio sets the most that either amount in an order can be compared to the other. Orders with
+// a bigger ratio have an absurd price, and are rejected when they're validated. 0 means no limit, which is
+// the default.
+func (s *OpencxAuctionServer) SetMaxAmountRatio(maxRatio uint64) {
+	s.auctionMtx.Lock()
+	s.maxAmountRatio = maxRatio
+	s.auctionMtx.Unlock()
+}
+
+// MaxAmountRatio gets the most that either amount in an order can be compared to the other
+func (s *OpencxAuctionServer) MaxAmountRatio() (maxRatio uint64, err error) {
+	s.auctionMtx.RLock()
+	maxRatio = s.maxAmountRatio
+	s.auctionMtx.RUnlock()
+	return
+}
@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/cxdb"
 	"github.com/mit-dci/opencx/logging"
 	"github.com/mit-dci/opencx/match"
@@ -16,19 +18,242 @@ type OpencxAuctionServer struct {
 	dbLock       *sync.Mutex
 	orderChannel chan *match.OrderPuzzleResult
 
-	// auction params -- we'll store them in here for now
-	auctionID [32]byte
-	t         uint64
+	// auction params -- we'll store them in here for now. auctionMtx protects these, since
+	// they get read by RPC calls while the auction clock is changing them.
+	auctionID    [32]byte
+	auctionStart time.Time
+	t            uint64
+	network      match.NetworkMagic
+	auctionMtx   *sync.RWMutex
+
+	// puzzleParams are the parameters puzzles for the current auction have to use, they're new for
+	// every auction. auctionMtx protects this.
+	puzzleParams *match.PuzzleParams
+
+	// auctionDuration is how long auctions last on the wall clock, if it's separate from the auction time.
+	// auctionMtx protects this.
+	auctionDuration time.Duration
+
+	// mode is how orders are matched. In continuous mode, orders are matched as they come in, in
+	// continuousStore. auctionMtx protects these too.
+	mode            MatchingMode
+	continuousStore cxdb.OpencxStore
+
+	// unsafeNoPuzzle lets batch orders be submitted without a puzzle. This is only for testing.
+	unsafeNoPuzzle bool
+
+	// signingKey is the key the server signs things like its time with. auctionMtx protects this.
+	signingKey *koblitz.PrivateKey
+	// unsignedResults is true if auction results aren't signed even though there's a signing key. auctionMtx
+	// protects this.
+	unsignedResults bool
+
+	// pricePrecision is how many decimal places prices are formatted with for clients
+	pricePrecision uint
+
+	// maxAmountRatio is the most either amount in an order can be compared to the other, 0 means no
+	// limit. auctionMtx protects this.
+	maxAmountRatio uint64
+
+	// maxDepthLevels is the most price levels on each side of the depth charts given to clients. auctionMtx protects this.
+	maxDepthLevels int
+
+	// priceBands are the absolute price bands that clearing prices must be in, per pair
+	priceBands map[match.Pair]*match.PriceBand
+
+	// oracle is where reserve prices come from, and oracleDeviation is how far from the reserve price, as a
+	// fraction of it, clearing prices can be. auctionMtx protects these.
+	oracle          match.Oracle
+	oracleDeviation float64
+
+	// batchVerifier verifies the signatures of solved orders. auctionMtx protects this.
+	batchVerifier *match.BatchVerifier
+
+	// commitment is the running commitment to the puzzles in the current auction, and published is the last
+	// interim commitment that was published for it. commitMtx protects these.
+	commitment         *match.CommitmentChain
+	published          *match.InterimCommitment
+	commitmentInterval time.Duration
+	commitMtx          *sync.Mutex
+
+	// solves are the orders waiting to be solved
+	solves *solveQueue
+
+	// auctionSlots limits how many pair auctions run at once
+	auctionSlots *auctionScheduler
+
+	// returnedOrders are the orders that didn't match in an auction, per pubkey, waiting for the user
+	// to get them. returnedMtx protects this.
+	returnedOrders map[[33]byte][]*match.ReturnedOrder
+	returnedMtx    *sync.Mutex
+
+	// auctionImbalances are the buy and sell imbalances per pair of the last few auctions, statsAuctions
+	// is the order they were recorded in. statsMtx protects these.
+	auctionImbalances map[[32]byte]map[match.Pair]*match.AuctionImbalance
+	statsAuctions     [][32]byte
+	statsMtx          *sync.Mutex
+
+	// orderKeys are the keys of the solved orders placed in each of the last few auctions, and when each of
+	// them closed, kept so the keys can be revealed once the auction is over. keyAuctions is the order they
+	// were kept in, and revealGrace is how long after an auction closes its keys wait for orders that are
+	// still being solved. revealPolicy is which of the keys are revealed. keysMtx protects these.
+	orderKeys    map[[32]byte]*auctionKeys
+	keyAuctions  [][32]byte
+	revealGrace  time.Duration
+	revealPolicy RevealPolicy
+	keysMtx      *sync.Mutex
+
+	// queuedOrders are the orders for the next auction, per auction they were queued during. queuedInto is the
+	// auction that the orders queued during an auction were placed in, and queuedAuctions is the order they
+	// were placed in. nextAuctionWindow is how long before an auction ends that orders can be queued.
+	// queuedMtx protects these.
+	queuedOrders      map[[32]byte][]*solveItem
+	queuedInto        map[[32]byte][32]byte
+	queuedAuctions    [][32]byte
+	nextAuctionWindow time.Duration
+	queuedMtx         *sync.Mutex
+
+	// lastTick is when the auction clock last finished a tick, and clockGen is incremented every time the
+	// watchdog restarts the clock. watchdogGrace is how long past the end of an auction the clock can be
+	// before it's considered stalled. batchTimeout is how long the clock waits for a batch, batchRunning is
+	// whether one is still being processed, and batchTimeouts is how many timed out. healthMtx protects these.
+	lastTick        time.Time
+	clockGen        uint64
+	clockRestarts   uint64
+	watchdogGrace   time.Duration
+	watchdogStarted bool
+	batchTimeout    time.Duration
+	batchRunning    bool
+	batchTimeouts   uint64
+	healthMtx       *sync.Mutex
+
+	// adminPubkeys are the pubkeys allowed to get admin metrics, and adminCalls is when each of them last
+	// got them, so they can only get them every adminInterval. adminMtx protects these.
+	adminPubkeys  map[[33]byte]bool
+	adminCalls    map[[33]byte]time.Time
+	adminInterval time.Duration
+	adminMtx      *sync.Mutex
+
+	// placedOrders are the IDs of the solved orders placed in each auction, so an order that is submitted
+	// again isn't placed twice. dbLock protects this.
+	placedOrders map[[32]byte]map[[32]byte]bool
+	// placedNonces are the nonces used by each pubkey in each auction, so a pubkey can't use a nonce twice
+	// in an auction even with different orders. nonceScope is what the nonces of new auctions have to be
+	// unique within. dbLock protects these.
+	placedNonces map[[32]byte]*match.NonceSet
+	nonceScope   match.NonceScope
+	// placedPubkeys are the pubkeys with orders placed in each auction, and maxPubkeys is the most there can be
+	// in an auction, 0 meaning no limit. dbLock protects these.
+	placedPubkeys map[[32]byte]map[[33]byte]bool
+	maxPubkeys    int
+	// cancelledEverything are the pubkeys that cancelled everything during each auction. Orders they signed
+	// for that auction that are still being solved, or are queued for the next one, are dropped instead of
+	// placed. dbLock protects this.
+	cancelledEverything map[[32]byte]map[[33]byte]bool
+
+	// clearingPairs are the pairs the auction clock clears. An auction is cleared on the tick after the one that
+	// closed it, so its orders have had an auction's time to be solved. lastClosed is the auction the clock
+	// closed last, which it clears next. clearMtx protects these.
+	clearingPairs []*match.Pair
+	lastClosed    [32]byte
+	hasLastClosed bool
+	clearMtx      *sync.Mutex
+
+	// pairAuctions are the auctions of the pairs that run their own, independently of the exchange's auction
+	// and each other. pairAuctionIDs is the pair that each of their open auctions, and closed auctions that
+	// haven't been cleared yet, is for. stopPairClocks is closed when the server shuts down, which stops their
+	// clocks, and pairClocksStopped is whether it has been. pairMtx protects these.
+	pairAuctions      map[match.Pair]*pairAuction
+	pairAuctionIDs    map[[32]byte]match.Pair
+	stopPairClocks    chan struct{}
+	pairClocksStopped bool
+	pairMtx           *sync.Mutex
+
+	// orderLog is where accepted encrypted orders are written before the auction closes, so they can be
+	// replayed after a restart. It's nil if orders aren't logged. dbLock protects this.
+	orderLog *orderLog
+
+	// ciphertexts are the hashes of the puzzled orders sent in the current auction, so exact duplicates
+	// can be dropped before they're stored and solved
+	ciphertexts *ciphertextCache
+
+	// rejections counts the orders that were rejected, by why they were rejected
+	rejections *rejectionCounter
+
+	// assignedNonces are the nonces the server has handed out in nonceAuctionID, per pubkey.
+	// nonceMtx protects these.
+	assignedNonces map[[33]byte]*nonceCounter
+	nonceAuctionID [32]byte
+	nonceMtx       *sync.Mutex
 }
 
 // InitServer creates a new server
 func InitServer(db cxdb.OpencxAuctionStore, orderChanSize uint64, standardAuctionTime uint64) (server *OpencxAuctionServer, err error) {
 	logging.Infof("Starting an auction with auction time %d", standardAuctionTime)
 	server = &OpencxAuctionServer{
-		OpencxDB:     db,
-		dbLock:       new(sync.Mutex),
-		orderChannel: make(chan *match.OrderPuzzleResult, orderChanSize),
-		t:            standardAuctionTime,
+		OpencxDB:       db,
+		dbLock:         new(sync.Mutex),
+		auctionMtx:     new(sync.RWMutex),
+		orderChannel:   make(chan *match.OrderPuzzleResult, orderChanSize),
+		t:              standardAuctionTime,
+		network:        match.TestnetMagic,
+		mode:           BatchMatching,
+		priceBands:     make(map[match.Pair]*match.PriceBand),
+		pricePrecision: DefaultPricePrecision,
+		maxDepthLevels: DefaultMaxDepthLevels,
+		auctionSlots:   newAuctionScheduler(0),
+		solves:         newSolveQueue(0, RejectNew),
+
+		commitmentInterval: DefaultCommitmentInterval,
+		commitMtx:          new(sync.Mutex),
+
+		returnedOrders: make(map[[33]byte][]*match.ReturnedOrder),
+		returnedMtx:    new(sync.Mutex),
+
+		auctionImbalances: make(map[[32]byte]map[match.Pair]*match.AuctionImbalance),
+		statsMtx:          new(sync.Mutex),
+
+		orderKeys: make(map[[32]byte]*auctionKeys),
+		keysMtx:   new(sync.Mutex),
+
+		queuedOrders: make(map[[32]byte][]*solveItem),
+		queuedInto:   make(map[[32]byte][32]byte),
+		queuedMtx:    new(sync.Mutex),
+
+		watchdogGrace: DefaultWatchdogGrace,
+		healthMtx:     new(sync.Mutex),
+
+		adminPubkeys:  make(map[[33]byte]bool),
+		adminCalls:    make(map[[33]byte]time.Time),
+		adminInterval: DefaultAdminMetricsInterval,
+		adminMtx:      new(sync.Mutex),
+
+		placedOrders:        make(map[[32]byte]map[[32]byte]bool),
+		placedNonces:        make(map[[32]byte]*match.NonceSet),
+		placedPubkeys:       make(map[[32]byte]map[[33]byte]bool),
+		cancelledEverything: make(map[[32]byte]map[[33]byte]bool),
+		ciphertexts:         newCiphertextCache(DefaultMaxCiphertextCache),
+		rejections:          newRejectionCounter(),
+
+		clearMtx: new(sync.Mutex),
+
+		pairAuctions:   make(map[match.Pair]*pairAuction),
+		pairAuctionIDs: make(map[[32]byte]match.Pair),
+		stopPairClocks: make(chan struct{}),
+		pairMtx:        new(sync.Mutex),
+
+		assignedNonces: make(map[[33]byte]*nonceCounter),
+		nonceMtx:       new(sync.Mutex),
+	}
+
+	if server.batchVerifier, err = match.NewBatchVerifier(0); err != nil {
+		err = fmt.Errorf("Error creating batch verifier for initializing server: %s", err)
+		return
+	}
+
+	if server.puzzleParams, err = match.NewPuzzleParams(standardAuctionTime, match.DefaultModulusBits); err != nil {
+		err = fmt.Errorf("Error creating puzzle params for initializing server: %s", err)
+		return
 	}
 
 	// Set auctionID to something random
@@ -36,6 +261,12 @@ func InitServer(db cxdb.OpencxAuctionStore, orderChanSize uint64, standardAuctio
 		err = fmt.Errorf("Error getting random auction ID for initializing server: %s", err)
 		return
 	}
+	server.auctionStart = time.Now()
+	server.lastTick = server.auctionStart
+	server.commitment = match.NewCommitmentChain(server.auctionID)
+
+	// Start solving orders as they're queued
+	server.startSolveWorkers()
 
 	// Start the solved order handler (TODO: is this the right place to put this?)
 	go server.AuctionOrderHandler(server.orderChannel)
@@ -48,12 +279,121 @@ func InitServer(db cxdb.OpencxAuctionStore, orderChanSize uint64, standardAuctio
 
 // CurrentAuctionID gets the current auction ID
 func (s *OpencxAuctionServer) CurrentAuctionID() (currentAuctionID [32]byte, err error) {
+	s.auctionMtx.RLock()
 	currentAuctionID = s.auctionID
+	s.auctionMtx.RUnlock()
 	return
 }
 
 // CurrentAuctionTime gets the current auction time
 func (s *OpencxAuctionServer) CurrentAuctionTime() (currentAuctionTime uint64, err error) {
+	s.auctionMtx.RLock()
 	currentAuctionTime = s.t
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// NextAuctionTime gets the time that the next auction is scheduled to open. The ID of the next
+// auction is a commitment to the orders in the current one, so it can't be known until then.
+func (s *OpencxAuctionServer) NextAuctionTime() (nextAuctionTime time.Time, err error) {
+	s.auctionMtx.RLock()
+	nextAuctionTime = s.nextAuctionTime()
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// CurrentAuctionDeadline gets the time that the current auction is scheduled to close. That's when the auction
+// clock commits to its puzzles and opens the next auction, so it's the same as NextAuctionTime.
+func (s *OpencxAuctionServer) CurrentAuctionDeadline() (deadline time.Time, err error) {
+	s.auctionMtx.RLock()
+	deadline = s.nextAuctionTime()
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// CurrentAuctionState gets the current auction ID, auction time, and next auction time all at once,
+// so they are guaranteed to describe the same auction even if a new one starts in between.
+func (s *OpencxAuctionServer) CurrentAuctionState() (currentAuctionID [32]byte, currentAuctionTime uint64, nextAuctionTime time.Time, err error) {
+	s.auctionMtx.RLock()
+	currentAuctionID = s.auctionID
+	currentAuctionTime = s.t
+	nextAuctionTime = s.nextAuctionTime()
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// CurrentPuzzleParams gets the puzzle parameters for the current auction, and the ID of the auction they're for
+func (s *OpencxAuctionServer) CurrentPuzzleParams() (currentAuctionID [32]byte, params match.PuzzleParams, err error) {
+	s.auctionMtx.RLock()
+	currentAuctionID = s.auctionID
+	params = *s.puzzleParams
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// SetNetwork sets the network magic that orders must be signed for. This defaults to the testnet magic.
+func (s *OpencxAuctionServer) SetNetwork(network match.NetworkMagic) {
+	s.auctionMtx.Lock()
+	s.network = network
+	s.auctionMtx.Unlock()
+	return
+}
+
+// Network gets the network magic that orders must be signed for
+func (s *OpencxAuctionServer) Network() (network match.NetworkMagic, err error) {
+	s.auctionMtx.RLock()
+	network = s.network
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// nextAuctionTime calculates the next auction time, the caller should be holding auctionMtx
+func (s *OpencxAuctionServer) nextAuctionTime() time.Time {
+	return s.auctionStart.Add(s.auctionLength(s.t))
+}
+
+// auctionLength is how long an auction whose puzzles take auctionTime squarings lasts on the wall clock. With
+// an auction duration, that's how long auctionTime squarings take at the rate the exchange's auction does them.
+// Otherwise it's auctionTime microseconds. The caller should be holding auctionMtx.
+func (s *OpencxAuctionServer) auctionLength(auctionTime uint64) time.Duration {
+	if s.auctionDuration == 0 || s.t == 0 {
+		return time.Duration(auctionTime) * time.Microsecond
+	}
+
+	return time.Duration(float64(s.auctionDuration) * float64(auctionTime) / float64(s.t))
+}
+
+// CurrentAuctionLength gets how long the current auction lasts on the wall clock
+func (s *OpencxAuctionServer) CurrentAuctionLength() (length time.Duration, err error) {
+	s.auctionMtx.RLock()
+	length = s.auctionLength(s.t)
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// SetAuctionDuration sets how long auctions last on the wall clock, separately from the auction time, which is
+// how many squarings their puzzles take. Without a duration an auction lasts its auction time in microseconds,
+// which is only how long solving takes on a machine that does a million squarings a second. A duration of 0
+// goes back to that, which is the default. The current auction starts over with the new duration, and pair
+// auctions started after this last as long as their auction time takes at the same rate.
+func (s *OpencxAuctionServer) SetAuctionDuration(duration time.Duration) (err error) {
+	if duration < 0 {
+		err = fmt.Errorf("Auction duration cannot be negative, got %s", duration)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.auctionDuration = duration
+	s.auctionStart = time.Now()
+	s.auctionMtx.Unlock()
+
+	// The clock is already waiting out the old duration, so a new clock takes over like when the watchdog
+	// restarts it
+	s.healthMtx.Lock()
+	s.clockGen++
+	s.lastTick = time.Now()
+	s.healthMtx.Unlock()
+
+	go s.AuctionClock()
 	return
 }
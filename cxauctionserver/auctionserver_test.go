@@ -2,6 +2,8 @@ package cxauctionserver
 
 import (
 	"fmt"
+	"testing"
+	"time"
 
 	"github.com/mit-dci/lit/coinparam"
 	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
@@ -36,3 +38,100 @@ func initTestServer() (s *OpencxAuctionServer, err error) {
 
 	return
 }
+
+func TestNextAuctionTime(t *testing.T) {
+	var err error
+
+	before := time.Now()
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestNextAuctionTime: %s", err)
+		return
+	}
+
+	var nextAuctionTime time.Time
+	if nextAuctionTime, err = s.NextAuctionTime(); err != nil {
+		t.Errorf("Error getting next auction time: %s", err)
+		return
+	}
+
+	// The next auction should open one auction time after this one started
+	auctionDuration := time.Duration(testStandardAuctionTime) * time.Microsecond
+	if nextAuctionTime.Before(before.Add(auctionDuration)) {
+		t.Errorf("Next auction opens at %s, before the current auction could have finished", nextAuctionTime)
+		return
+	}
+
+	if nextAuctionTime.After(time.Now().Add(auctionDuration)) {
+		t.Errorf("Next auction opens at %s, more than one auction time from now", nextAuctionTime)
+		return
+	}
+
+	return
+}
+
+// TestConcurrentAuctionStateReads should be run with the race detector. It reads the auction state
+// from many goroutines while new auctions are being created.
+func TestConcurrentAuctionStateReads(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestConcurrentAuctionStateReads: %s", err)
+		return
+	}
+
+	numReaders := 8
+	numTransitions := 50
+
+	stopChan := make(chan bool)
+	errChan := make(chan error, numReaders)
+	for i := 0; i < numReaders; i++ {
+		go func() {
+			for {
+				select {
+				case <-stopChan:
+					errChan <- nil
+					return
+				default:
+				}
+				var auctionTime uint64
+				var nextAuctionTime time.Time
+				var stateErr error
+				if _, auctionTime, nextAuctionTime, stateErr = s.CurrentAuctionState(); stateErr != nil {
+					errChan <- stateErr
+					return
+				}
+				if auctionTime != testStandardAuctionTime {
+					errChan <- fmt.Errorf("Read auction time %d but it should always be %d", auctionTime, testStandardAuctionTime)
+					return
+				}
+				if nextAuctionTime.IsZero() {
+					errChan <- fmt.Errorf("Read a zero next auction time")
+					return
+				}
+				if _, stateErr = s.CurrentAuctionID(); stateErr != nil {
+					errChan <- stateErr
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < numTransitions; i++ {
+		if err = s.CommitOrdersNewAuction(); err != nil {
+			t.Errorf("Error creating new auction while reading concurrently: %s", err)
+			break
+		}
+	}
+	close(stopChan)
+
+	for i := 0; i < numReaders; i++ {
+		if err = <-errChan; err != nil {
+			t.Errorf("Error reading auction state concurrently: %s", err)
+		}
+	}
+
+	return
+}
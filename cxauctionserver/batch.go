@@ -0,0 +1,100 @@
+package cxauctionserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+)
+
+var (
+	// ErrBatchTimeout is returned when processing a batch takes longer than the batch timeout. The batch
+	// keeps running in the background, but the auction clock stops waiting for it.
+	ErrBatchTimeout = errors.New("batch processing timed out")
+	// ErrBatchStillRunning is returned when a batch can't start because one that timed out hasn't finished yet
+	ErrBatchStillRunning = errors.New("previous batch is still running")
+)
+
+// SetBatchTimeout sets how long processing a batch, like committing to the orders in an auction and creating the
+// next one, can take before the auction clock stops waiting for it and moves on. Zero means there is no deadline,
+// which is the default.
+func (s *OpencxAuctionServer) SetBatchTimeout(timeout time.Duration) (err error) {
+	if timeout < 0 {
+		err = fmt.Errorf("Batch timeout cannot be negative, got %s", timeout)
+		return
+	}
+
+	s.healthMtx.Lock()
+	s.batchTimeout = timeout
+	s.healthMtx.Unlock()
+	return
+}
+
+// BatchTimeout returns how long processing a batch can take before the auction clock moves on, zero means
+// there is no deadline.
+func (s *OpencxAuctionServer) BatchTimeout() (timeout time.Duration) {
+	s.healthMtx.Lock()
+	timeout = s.batchTimeout
+	s.healthMtx.Unlock()
+	return
+}
+
+// runBatch runs process, waiting at most the batch timeout for it. If the deadline passes, ErrBatchTimeout is
+// returned and process is left to finish on its own. No other batch is started until it does, so a stalled batch
+// can't pile up more work behind it.
+func (s *OpencxAuctionServer) runBatch(process func() error) (err error) {
+	s.healthMtx.Lock()
+	if s.batchRunning {
+		s.healthMtx.Unlock()
+		err = ErrBatchStillRunning
+		return
+	}
+	s.batchRunning = true
+	timeout := s.batchTimeout
+	s.healthMtx.Unlock()
+
+	// abandoned is set if we stopped waiting for the batch, healthMtx protects it
+	abandoned := false
+	doneChan := make(chan error, 1)
+	go func() {
+		var processErr error
+		defer func() {
+			if r := recover(); r != nil {
+				processErr = fmt.Errorf("Panic while processing batch: %v", r)
+			}
+
+			s.healthMtx.Lock()
+			s.batchRunning = false
+			late := abandoned
+			s.healthMtx.Unlock()
+
+			if late {
+				if processErr != nil {
+					logging.Errorf("Batch that timed out failed: %s", processErr)
+				} else {
+					logging.Infof("Batch that timed out finished")
+				}
+			}
+			doneChan <- processErr
+		}()
+		processErr = process()
+	}()
+
+	if timeout == 0 {
+		err = <-doneChan
+		return
+	}
+
+	select {
+	case err = <-doneChan:
+	case <-time.After(timeout):
+		s.healthMtx.Lock()
+		abandoned = true
+		s.batchTimeouts++
+		s.healthMtx.Unlock()
+		err = ErrBatchTimeout
+	}
+
+	return
+}
@@ -0,0 +1,142 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/opencx/cxdb"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+)
+
+// slowAuctionStore is an auction store whose NewAuction stalls until release is closed, like a stuck db
+type slowAuctionStore struct {
+	cxdb.OpencxAuctionStore
+	release chan struct{}
+}
+
+// NewAuction waits for release before creating the new auction
+func (db *slowAuctionStore) NewAuction(auctionID [32]byte) (height uint64, err error) {
+	<-db.release
+	return db.OpencxAuctionStore.NewAuction(auctionID)
+}
+
+// initSlowAuctionServer creates a server on a slow auction store, with auctions long enough that the clock
+// won't tick during a test
+func initSlowAuctionServer() (s *OpencxAuctionServer, store *slowAuctionStore, err error) {
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient(testCoins); err != nil {
+		err = fmt.Errorf("Error setting up db client for tests: %s", err)
+		return
+	}
+
+	store = &slowAuctionStore{
+		OpencxAuctionStore: testDB,
+		release:            make(chan struct{}),
+	}
+	if s, err = InitServer(store, testOrderChanSize, uint64(time.Hour/time.Microsecond)); err != nil {
+		err = fmt.Errorf("Error initializing server for tests: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestBatchTimeoutSlowSettlement(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	var store *slowAuctionStore
+	if s, store, err = initSlowAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestBatchTimeoutSlowSettlement: %s", err)
+		return
+	}
+
+	if err = s.SetBatchTimeout(-time.Second); err == nil {
+		t.Errorf("Setting a negative batch timeout should fail")
+		return
+	}
+
+	if err = s.SetBatchTimeout(50 * time.Millisecond); err != nil {
+		t.Errorf("Error setting batch timeout: %s", err)
+		return
+	}
+
+	// The tick should give up on the stalled batch and still finish
+	doneChan := make(chan time.Time, 1)
+	go s.auctionTick(doneChan)
+	select {
+	case <-doneChan:
+	case <-time.After(5 * time.Second):
+		close(store.release)
+		t.Errorf("Auction tick should have moved on after the batch timeout")
+		return
+	}
+
+	var health AuctionHealth
+	if health, err = s.Health(); err != nil {
+		close(store.release)
+		t.Errorf("Error getting health: %s", err)
+		return
+	}
+
+	if health.BatchTimeouts != 1 {
+		close(store.release)
+		t.Errorf("One batch should have timed out, got %d", health.BatchTimeouts)
+		return
+	}
+
+	// Another batch shouldn't start while the stalled one is still going
+	if err = s.runBatch(func() error { return nil }); err != ErrBatchStillRunning {
+		close(store.release)
+		t.Errorf("Batch should not start while a timed out one is still running, got %v", err)
+		return
+	}
+
+	// Once the db gets unstuck the stalled batch finishes, and batches can run again
+	close(store.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for err = s.runBatch(func() error { return nil }); err == ErrBatchStillRunning && time.Now().Before(deadline); err = s.runBatch(func() error { return nil }) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err != nil {
+		t.Errorf("Batch should run once the stalled one finished, got %v", err)
+		return
+	}
+
+	return
+}
+
+func TestBatchWithoutTimeoutWaits(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestBatchWithoutTimeoutWaits: %s", err)
+		return
+	}
+
+	if s.BatchTimeout() != 0 {
+		t.Errorf("There should be no batch timeout by default, got %s", s.BatchTimeout())
+		return
+	}
+
+	// Without a deadline the batch's own result comes back, however long it takes
+	batchErr := fmt.Errorf("settlement failed")
+	if err = s.runBatch(func() error {
+		time.Sleep(20 * time.Millisecond)
+		return batchErr
+	}); err != batchErr {
+		t.Errorf("Batch without a timeout should return its own error, got %v", err)
+		return
+	}
+
+	// Panics are returned as errors instead of taking the server down
+	if err = s.runBatch(func() error { panic("settlement panicked") }); err == nil {
+		t.Errorf("Panicking batch should return an error")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,168 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/opencx/match"
+)
+
+// DefaultMaxCiphertextCache is how many puzzled orders are remembered in an auction by default, so exact
+// duplicates of them can be dropped
+const DefaultMaxCiphertextCache = 1 << 16
+
+// duplicateCiphertextError is the error for a puzzled order that is dropped because it was already sent
+type duplicateCiphertextError struct {
+	hash      [32]byte
+	auctionID [32]byte
+}
+
+// Error returns the hash of the duplicate order and the auction it was sent in
+func (e *duplicateCiphertextError) Error() string {
+	return fmt.Sprintf("Puzzled order %x was already sent in auction %x", e.hash, e.auctionID)
+}
+
+// ciphertextCache remembers the hashes of the serialized puzzled orders submitted in an auction, so an order
+// that is sent again isn't stored and solved twice. It remembers at most max hashes, forgetting the oldest
+// once it's full, so spam can't make it grow without bound.
+type ciphertextCache struct {
+	auctionID [32]byte
+	seen      map[[32]byte]bool
+	// hashes are in the order they were added, so the oldest can be forgotten first
+	hashes [][32]byte
+	max    int
+	mtx    *sync.Mutex
+}
+
+// newCiphertextCache creates an empty ciphertext cache that remembers at most max hashes
+func newCiphertextCache(max int) (cc *ciphertextCache) {
+	cc = &ciphertextCache{
+		seen: make(map[[32]byte]bool),
+		max:  max,
+		mtx:  new(sync.Mutex),
+	}
+	return
+}
+
+// hashCiphertext returns the hash of the serialized puzzled order
+func hashCiphertext(order *match.EncryptedAuctionOrder) (hash [32]byte, err error) {
+	var raw []byte
+	if raw, err = order.Serialize(); err != nil {
+		err = fmt.Errorf("Error serializing puzzled order to hash it: %s", err)
+		return
+	}
+
+	sha3 := sha3.New256()
+	sha3.Write(raw)
+	copy(hash[:], sha3.Sum(nil))
+	return
+}
+
+// add remembers hash as submitted in auctionID, returning false if it already was. Hashes from other
+// auctions are forgotten, since duplicates only matter within an auction.
+func (cc *ciphertextCache) add(auctionID [32]byte, hash [32]byte) (added bool) {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+
+	if cc.auctionID != auctionID {
+		cc.seen = make(map[[32]byte]bool)
+		cc.hashes = nil
+		cc.auctionID = auctionID
+	}
+
+	if cc.seen[hash] {
+		return
+	}
+
+	for len(cc.hashes) >= cc.max {
+		delete(cc.seen, cc.hashes[0])
+		cc.hashes = cc.hashes[1:]
+	}
+
+	cc.seen[hash] = true
+	cc.hashes = append(cc.hashes, hash)
+	added = true
+	return
+}
+
+// remove forgets hash, so an order that was added but then couldn't be queued can be sent again
+func (cc *ciphertextCache) remove(auctionID [32]byte, hash [32]byte) {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+
+	if cc.auctionID != auctionID || !cc.seen[hash] {
+		return
+	}
+
+	delete(cc.seen, hash)
+	for i, cached := range cc.hashes {
+		if cached == hash {
+			cc.hashes = append(cc.hashes[:i], cc.hashes[i+1:]...)
+			break
+		}
+	}
+}
+
+// setMax sets the most hashes remembered, forgetting the oldest ones if there are already more
+func (cc *ciphertextCache) setMax(max int) {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+
+	cc.max = max
+	for len(cc.hashes) > cc.max {
+		delete(cc.seen, cc.hashes[0])
+		cc.hashes = cc.hashes[1:]
+	}
+}
+
+// getMax returns the most hashes remembered
+func (cc *ciphertextCache) getMax() (max int) {
+	cc.mtx.Lock()
+	max = cc.max
+	cc.mtx.Unlock()
+	return
+}
+
+// SetMaxCiphertextCache sets how many puzzled orders are remembered in an auction to drop duplicates of.
+// Once that many have been sent, the oldest are forgotten, and duplicates of them aren't dropped.
+func (s *OpencxAuctionServer) SetMaxCiphertextCache(max int) (err error) {
+	if max < 1 {
+		err = fmt.Errorf("Ciphertext cache has to hold at least 1 order, got %d", max)
+		return
+	}
+
+	s.ciphertexts.setMax(max)
+	return
+}
+
+// MaxCiphertextCache returns how many puzzled orders are remembered in an auction to drop duplicates of
+func (s *OpencxAuctionServer) MaxCiphertextCache() (max int, err error) {
+	max = s.ciphertexts.getMax()
+	return
+}
+
+// dedupCiphertext returns an error if the exact same puzzled order was already sent in the current auction,
+// otherwise it's remembered. The returned function forgets it again, for if the order isn't accepted after all.
+func (s *OpencxAuctionServer) dedupCiphertext(order *match.EncryptedAuctionOrder) (forget func(), err error) {
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID to check for duplicate puzzled order: %s", err)
+		return
+	}
+
+	var hash [32]byte
+	if hash, err = hashCiphertext(order); err != nil {
+		return
+	}
+
+	if !s.ciphertexts.add(auctionID, hash) {
+		err = &duplicateCiphertextError{hash: hash, auctionID: auctionID}
+		return
+	}
+
+	forget = func() {
+		s.ciphertexts.remove(auctionID, hash)
+	}
+	return
+}
@@ -0,0 +1,161 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestDuplicateCiphertextSolvedOnce(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestDuplicateCiphertextSolvedOnce: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	var encOrder *match.EncryptedAuctionOrder
+	if order, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+		t.Errorf("Error creating puzzled order: %s", err)
+		return
+	}
+
+	if err = s.PlacePuzzledOrder(encOrder); err != nil {
+		t.Errorf("Error placing puzzled order: %s", err)
+		return
+	}
+
+	// The same ciphertext is a duplicate, even if it's a different copy of it
+	duplicate := *encOrder
+	for _, dup := range []*match.EncryptedAuctionOrder{encOrder, &duplicate} {
+		if err = s.PlacePuzzledOrder(dup); err == nil {
+			t.Errorf("Placing the same puzzled order twice in an auction should fail")
+			return
+		}
+	}
+
+	var puzzles []*match.EncryptedAuctionOrder
+	if puzzles, err = s.OpencxDB.ViewAuctionPuzzleBook(order.AuctionID); err != nil {
+		t.Errorf("Error viewing puzzle book: %s", err)
+		return
+	}
+
+	if len(puzzles) != 1 {
+		t.Errorf("Duplicate puzzled orders should not be stored, found %d puzzles", len(puzzles))
+		return
+	}
+
+	// Wait for it to be solved and placed
+	deadline := time.Now().Add(time.Minute)
+	for {
+		_, buyOrders, _ := s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, order.AuctionID)
+		if len(buyOrders) == 1 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Errorf("Puzzled order was never placed in the order book")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// If it were solved more than once, placing it again would be rejected as a duplicate too
+	time.Sleep(100 * time.Millisecond)
+	expected := RejectionCounts{
+		RejectedDuplicate: 2,
+	}
+	if !checkRejections(s, 1, expected, expected, t) {
+		return
+	}
+
+	// It's only a duplicate within an auction
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	var forget func()
+	if forget, err = s.dedupCiphertext(encOrder); err != nil {
+		t.Errorf("Puzzled order sent in the last auction should not be a duplicate in a new one: %s", err)
+		return
+	}
+
+	// Forgetting it lets it be sent again
+	forget()
+	if _, err = s.dedupCiphertext(encOrder); err != nil {
+		t.Errorf("Forgotten puzzled order should not be a duplicate: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestCiphertextCacheMax(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestCiphertextCacheMax: %s", err)
+		return
+	}
+
+	if err = s.SetMaxCiphertextCache(0); err == nil {
+		t.Errorf("Setting a ciphertext cache that holds nothing should fail")
+		return
+	}
+
+	if err = s.SetMaxCiphertextCache(2); err != nil {
+		t.Errorf("Error setting max ciphertext cache: %s", err)
+		return
+	}
+
+	var max int
+	if max, err = s.MaxCiphertextCache(); err != nil || max != 2 {
+		t.Errorf("Max ciphertext cache should be 2, got %d", max)
+		return
+	}
+
+	auctionID := [32]byte{0x01}
+	cc := s.ciphertexts
+	for _, add := range []struct {
+		hash  byte
+		added bool
+	}{
+		{0xaa, true},
+		{0xbb, true},
+		{0xaa, false},
+		// the cache is full, so 0xaa is forgotten
+		{0xcc, true},
+		{0xbb, false},
+		{0xaa, true},
+	} {
+		if cc.add(auctionID, [32]byte{add.hash}) != add.added {
+			t.Errorf("Adding %x should return %t", add.hash, add.added)
+			return
+		}
+	}
+
+	// Shrinking the cache forgets the oldest
+	if err = s.SetMaxCiphertextCache(1); err != nil {
+		t.Errorf("Error setting max ciphertext cache: %s", err)
+		return
+	}
+
+	if cc.add(auctionID, [32]byte{0xaa}) || !cc.add(auctionID, [32]byte{0xcc}) {
+		t.Errorf("Shrinking the ciphertext cache should keep only the newest hash")
+		return
+	}
+
+	return
+}
@@ -5,18 +5,34 @@ import (
 	"time"
 
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
 )
 
-// AuctionClock should be run in a goroutine and just commit to puzzles after some time
+// AuctionClock should be run in a goroutine and just commit to puzzles after some time. It stops if the
+// watchdog restarts the clock in another goroutine.
 func (s *OpencxAuctionServer) AuctionClock() {
 	logging.Infof("Starting Auction Clock!")
 
+	generation := s.clockGeneration()
+
+	// If the clock panics, the watchdog will see that it stopped ticking
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Errorf("Auction clock stopped, it panicked: %v", r)
+		}
+	}()
+
 	// We make the variables here because we don't want to fill up our memory with stuff in the loop
 	doneChan := make(chan time.Time, 1)
 	var tickDone time.Time
+	var err error
 
-	// afterTick is how we call the auction tick
+	// afterTick is how we call the auction tick, unless the clock has been restarted since it was scheduled
 	afterTick := func() {
+		if s.clockGeneration() != generation {
+			doneChan <- time.Now()
+			return
+		}
 		s.auctionTick(doneChan)
 	}
 
@@ -31,14 +47,22 @@ func (s *OpencxAuctionServer) AuctionClock() {
 		logging.Debugf("MEMORY STATS BEFORE: %d heap allocated, %d allocated", m.HeapAlloc, m.Alloc)
 		logging.Infof("Auction clock tick!")
 
-		// TODO: configurable time, work out schedule, base it on the AuctionTime option
-		time.AfterFunc(time.Duration(s.t)*time.Microsecond, afterTick)
+		var auctionLength time.Duration
+		if auctionLength, err = s.CurrentAuctionLength(); err != nil {
+			logging.Fatalf("Error getting auction length for auction clock: %s", err)
+		}
+		time.AfterFunc(auctionLength, afterTick)
 
 		logging.Infof("Waiting for tick")
 
 		// retrieve the tick from the channel
 		tickDone = <-doneChan
 
+		if !s.recordTick(generation, tickDone) {
+			logging.Infof("Auction clock was restarted, stopping the old one")
+			return
+		}
+
 		logging.Infof("Tick done at %s", tickDone.String())
 		logging.Debugf("MEMORY STATS AFTER: %d heap allocated, %d allocated", m.HeapAlloc, m.Alloc)
 	}
@@ -53,10 +77,85 @@ func (s *OpencxAuctionServer) auctionTick(doneChan chan time.Time) {
 	defer func() {
 		doneChan <- time.Now()
 	}()
-	if err = s.CommitOrdersNewAuction(); err != nil {
+
+	var closingAuctionID [32]byte
+	if closingAuctionID, err = s.CurrentAuctionID(); err != nil {
+		logging.Errorf("Error getting auction ID to close: %s", err)
+		return
+	}
+
+	if err = s.runBatch(s.CommitOrdersNewAuction); err == ErrBatchTimeout || err == ErrBatchStillRunning {
+		// The orders are still in the auction, they'll be committed to once the batch gets through
+		logging.Errorf("Gave up waiting for auction batch after %s, moving on: %s", s.BatchTimeout(), err)
+		return
+	} else if err != nil {
 		// TODO: What should happen in this case? How can we prevent this case?
 		logging.Fatalf("Exchange commitment failed!!! Fatal error: %s", err)
 	}
 
+	s.clearLastClosed(closingAuctionID)
+
+	var verifyMetrics match.VerifyMetrics
+	if verifyMetrics, err = s.VerifyMetrics(); err != nil {
+		logging.Errorf("Error getting verification metrics: %s", err)
+		return
+	}
+	logging.Infof("Signature verification: %s", verifyMetrics)
+
+	return
+}
+
+// SetClearingPairs sets the pairs that the auction clock clears. Each auction is cleared for every pair on the
+// tick after the one that closed it, so the orders in it have had an auction's time to be solved. No pairs
+// means the clock doesn't clear auctions, which is the default.
+func (s *OpencxAuctionServer) SetClearingPairs(pairs []*match.Pair) {
+	s.clearMtx.Lock()
+	s.clearingPairs = pairs
+	s.clearMtx.Unlock()
+	return
+}
+
+// clearLastClosed clears the auction that was closed before closedAuctionID for every clearing pair, and
+// remembers closedAuctionID to clear next time
+func (s *OpencxAuctionServer) clearLastClosed(closedAuctionID [32]byte) {
+	s.clearMtx.Lock()
+	clearingID, hasClearing := s.lastClosed, s.hasLastClosed
+	s.lastClosed = closedAuctionID
+	s.hasLastClosed = true
+	pairs := s.clearingPairs
+	s.clearMtx.Unlock()
+
+	if !hasClearing || len(pairs) == 0 {
+		return
+	}
+
+	// Pairs that run their own auctions clear them on their own clock
+	var exchangePairs []*match.Pair
+	for _, pair := range pairs {
+		if _, ownAuction := s.runsOwnAuction(pair); !ownAuction {
+			exchangePairs = append(exchangePairs, pair)
+		}
+	}
+
+	if err := s.RunPairAuctions(exchangePairs, func(pair *match.Pair) (err error) {
+		_, err = s.ClearPairAuction(pair, clearingID)
+		return
+	}); err != nil {
+		logging.Errorf("Error clearing auction %x: %s", clearingID, err)
+	}
+
+	return
+}
+
+// isClearingPair returns true if pair is one of the pairs the auction clock clears
+func (s *OpencxAuctionServer) isClearingPair(pair *match.Pair) (clearing bool) {
+	s.clearMtx.Lock()
+	for _, clearingPair := range s.clearingPairs {
+		if *clearingPair == *pair {
+			clearing = true
+			break
+		}
+	}
+	s.clearMtx.Unlock()
 	return
 }
@@ -0,0 +1,210 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestAuctionDeadlineAdvances(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestAuctionDeadlineAdvances: %s", err)
+		return
+	}
+
+	// Watch the clock roll over to a few new auctions
+	deadlines := make(map[[32]byte]time.Time)
+	var lastDeadline time.Time
+	giveUp := time.Now().Add(10 * time.Second)
+	for len(deadlines) < 3 {
+		if time.Now().After(giveUp) {
+			t.Errorf("Only saw %d auctions, the auction clock should have opened more", len(deadlines))
+			return
+		}
+
+		var auctionID [32]byte
+		var deadline time.Time
+		if auctionID, _, deadline, err = s.CurrentAuctionState(); err != nil {
+			t.Errorf("Error getting current auction state: %s", err)
+			return
+		}
+
+		if seen, found := deadlines[auctionID]; found {
+			if !seen.Equal(deadline) {
+				t.Errorf("Deadline of auction %x changed from %s to %s", auctionID, seen, deadline)
+				return
+			}
+		} else {
+			if !deadline.After(lastDeadline) {
+				t.Errorf("Deadline %s of new auction %x should be after the last deadline %s", deadline, auctionID, lastDeadline)
+				return
+			}
+			deadlines[auctionID] = deadline
+			lastDeadline = deadline
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var deadline time.Time
+	if deadline, err = s.CurrentAuctionDeadline(); err != nil {
+		t.Errorf("Error getting current auction deadline: %s", err)
+		return
+	}
+
+	if deadline.Before(lastDeadline) {
+		t.Errorf("Current auction deadline %s is before one that was already seen, %s", deadline, lastDeadline)
+		return
+	}
+
+	return
+}
+
+func TestAuctionClockClearsClosedAuction(t *testing.T) {
+	var err error
+
+	// The long auction time keeps the clock from ticking on its own, so we can tick it
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestAuctionClockClearsClosedAuction: %s", err)
+		return
+	}
+
+	var pairs []*match.Pair
+	if pairs, err = match.GenerateAssetPairs(testCoins); err != nil {
+		t.Errorf("Error generating pairs: %s", err)
+		return
+	}
+	s.SetClearingPairs(pairs)
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	for _, side := range []string{"buy", "sell"} {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(side, 1000, 2.0, privkey); err != nil {
+			t.Errorf("Error creating %s order: %s", side, err)
+			return
+		}
+
+		order.AuctionID = auctionID
+		if err = signTestOrder(order, privkey); err != nil {
+			t.Errorf("Error signing %s order: %s", side, err)
+			return
+		}
+
+		if err = s.placeSolvedOrder(order, auctionID); err != nil {
+			t.Errorf("Error placing %s order: %s", side, err)
+			return
+		}
+	}
+
+	// The tick that closes the auction leaves its orders time to be solved
+	doneChan := make(chan time.Time, 2)
+	s.auctionTick(doneChan)
+	if _, err = s.AuctionFills(auctionID); err == nil {
+		t.Errorf("Auction should not be cleared on the tick that closes it")
+		return
+	}
+
+	// The next one clears it
+	s.auctionTick(doneChan)
+	var fills []*match.AuctionFill
+	if fills, err = s.AuctionFills(auctionID); err != nil {
+		t.Errorf("Auction should be cleared on the tick after it closes: %s", err)
+		return
+	}
+
+	if len(fills) != 2 {
+		t.Errorf("Cleared auction should have 2 fills, got %d", len(fills))
+		return
+	}
+
+	return
+}
+
+func TestAuctionDurationSetsClock(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestAuctionDurationSetsClock: %s", err)
+		return
+	}
+
+	if err = s.SetAuctionDuration(-time.Second); err == nil {
+		t.Errorf("Setting a negative auction duration should fail")
+		return
+	}
+
+	// Much longer than the auction time in microseconds
+	duration := 10 * time.Duration(testStandardAuctionTime) * time.Microsecond
+	if err = s.SetAuctionDuration(duration); err != nil {
+		t.Errorf("Error setting auction duration: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	var auctionTime uint64
+	var deadline time.Time
+	if auctionID, auctionTime, deadline, err = s.CurrentAuctionState(); err != nil {
+		t.Errorf("Error getting current auction state: %s", err)
+		return
+	}
+
+	// The puzzles don't change, just how long the auction lasts
+	if auctionTime != testStandardAuctionTime {
+		t.Errorf("Auction duration should not change the auction time, expected %d got %d", testStandardAuctionTime, auctionTime)
+		return
+	}
+
+	if untilDeadline := time.Until(deadline); untilDeadline <= duration/2 || untilDeadline > duration {
+		t.Errorf("Auction should close in about %s, but closes in %s", duration, untilDeadline)
+		return
+	}
+
+	// Well past the auction time in microseconds, but before the duration is up
+	time.Sleep(3 * time.Duration(testStandardAuctionTime) * time.Microsecond)
+
+	var currentID [32]byte
+	if currentID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	if currentID != auctionID {
+		t.Errorf("Auction should last its duration, not its auction time in microseconds")
+		return
+	}
+
+	// Then the clock closes it once the duration is up
+	giveUp := time.Now().Add(10 * duration)
+	for currentID == auctionID {
+		if time.Now().After(giveUp) {
+			t.Errorf("Auction clock should close the auction once its duration is up")
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		if currentID, err = s.CurrentAuctionID(); err != nil {
+			t.Errorf("Error getting current auction ID: %s", err)
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,106 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+const (
+	// DefaultCommitmentInterval is how often a new interim commitment is published by default
+	DefaultCommitmentInterval = 5 * time.Second
+
+	// MaxCommitmentHistory is the most auction roots that can be gotten at once
+	MaxCommitmentHistory = 1000
+)
+
+// SetCommitmentInterval sets how often a new interim commitment to the current auction is published. Clients
+// asking more often than this get the last one that was published.
+func (s *OpencxAuctionServer) SetCommitmentInterval(interval time.Duration) (err error) {
+	if interval < 0 {
+		err = fmt.Errorf("Commitment interval cannot be negative, got %s", interval)
+		return
+	}
+
+	s.commitMtx.Lock()
+	s.commitmentInterval = interval
+	s.commitMtx.Unlock()
+	return
+}
+
+// InterimCommitment gets the latest published commitment to the puzzles in the current auction, so clients
+// can confirm that their puzzle is in before the auction closes. A new one is published if the last one is
+// older than the commitment interval.
+func (s *OpencxAuctionServer) InterimCommitment() (interim *match.InterimCommitment, err error) {
+	s.commitMtx.Lock()
+	defer s.commitMtx.Unlock()
+
+	// closeCommitment clears the published commitment, so it's always for the current auction
+	if s.published == nil || time.Since(s.published.Time) >= s.commitmentInterval {
+		s.published = s.commitment.Snapshot()
+	}
+
+	interim = s.published
+	return
+}
+
+// placePuzzle places a puzzle in the current auction and adds it to the auction's commitment. Puzzles for the
+// current auction of a pair that runs its own go in that auction's commitment. The caller should be holding dbLock.
+func (s *OpencxAuctionServer) placePuzzle(order *match.EncryptedAuctionOrder) (err error) {
+	if err = s.OpencxDB.PlaceAuctionPuzzle(order); err != nil {
+		err = fmt.Errorf("Error placing puzzle: %s", err)
+		return
+	}
+
+	s.pairMtx.Lock()
+	auction, pairAuction := s.openPairAuction(order.IntendedAuction)
+	if pairAuction {
+		err = auction.commitment.Add(order)
+	}
+	s.pairMtx.Unlock()
+
+	if !pairAuction {
+		s.commitMtx.Lock()
+		err = s.commitment.Add(order)
+		s.commitMtx.Unlock()
+	}
+	if err != nil {
+		err = fmt.Errorf("Error adding puzzle to commitment: %s", err)
+		return
+	}
+
+	return
+}
+
+// CommitmentHistory gets the final commitment roots of the last numAuctions auctions that closed, most recent
+// first, so watchdogs can archive them.
+func (s *OpencxAuctionServer) CommitmentHistory(numAuctions uint64) (roots []*match.AuctionRoot, err error) {
+	if numAuctions == 0 || numAuctions > MaxCommitmentHistory {
+		err = fmt.Errorf("Can only get the roots of between 1 and %d auctions, got %d", MaxCommitmentHistory, numAuctions)
+		return
+	}
+
+	s.dbLock.Lock()
+	roots, err = s.OpencxDB.ViewAuctionRoots(numAuctions)
+	s.dbLock.Unlock()
+	if err != nil {
+		err = fmt.Errorf("Error getting auction roots: %s", err)
+		return
+	}
+
+	return
+}
+
+// closeCommitment closes the commitment to the current auction, and starts a new one for the auction with the
+// closing commitment as its ID. It returns the final root of the closed auction, and the closing commitment.
+// The caller should be holding dbLock.
+func (s *OpencxAuctionServer) closeCommitment() (auctionRoot *match.AuctionRoot, closing [32]byte) {
+	s.commitMtx.Lock()
+	auctionRoot = s.commitment.Close()
+	closing = auctionRoot.Closing()
+	s.commitment = match.NewCommitmentChain(closing)
+	s.published = nil
+	s.commitMtx.Unlock()
+	return
+}
@@ -0,0 +1,207 @@
+package cxauctionserver
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestInterimCommitmentExtendsToNewAuctionID(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestInterimCommitmentExtendsToNewAuctionID: %s", err)
+		return
+	}
+
+	// always publish a fresh commitment
+	if err = s.SetCommitmentInterval(0); err != nil {
+		t.Errorf("Error setting commitment interval: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var interim *match.InterimCommitment
+	var puzzleHashes [][32]byte
+	for i := 0; i < 4; i++ {
+		var encOrder *match.EncryptedAuctionOrder
+		if _, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+			t.Errorf("Error creating test order: %s", err)
+			return
+		}
+
+		s.dbLock.Lock()
+		err = s.placePuzzle(encOrder)
+		s.dbLock.Unlock()
+		if err != nil {
+			t.Errorf("Error placing puzzle: %s", err)
+			return
+		}
+
+		var puzzleHash [32]byte
+		if puzzleHash, err = match.PuzzleHash(encOrder); err != nil {
+			t.Errorf("Error hashing puzzle: %s", err)
+			return
+		}
+		puzzleHashes = append(puzzleHashes, puzzleHash)
+
+		// take the interim commitment halfway through the auction
+		if i == 1 {
+			if interim, err = s.InterimCommitment(); err != nil {
+				t.Errorf("Error getting interim commitment: %s", err)
+				return
+			}
+		}
+	}
+
+	if !interim.Includes(puzzleHashes[0]) || !interim.Includes(puzzleHashes[1]) {
+		t.Errorf("Interim commitment should include the puzzles placed before it")
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error committing orders: %s", err)
+		return
+	}
+
+	var newAuctionID [32]byte
+	if newAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting new auction ID: %s", err)
+		return
+	}
+
+	if err = interim.VerifyClosing(puzzleHashes[2:], newAuctionID); err != nil {
+		t.Errorf("New auction ID does not extend the interim commitment: %s", err)
+		return
+	}
+
+	// the next interim commitment should be for the new auction, with nothing in it
+	var nextInterim *match.InterimCommitment
+	if nextInterim, err = s.InterimCommitment(); err != nil {
+		t.Errorf("Error getting interim commitment for new auction: %s", err)
+		return
+	}
+
+	if nextInterim.AuctionID != newAuctionID || len(nextInterim.PuzzleHashes) != 0 {
+		t.Errorf("Interim commitment after a new auction should be empty and for the new auction")
+		return
+	}
+
+	return
+}
+
+func TestCommitmentHistory(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestCommitmentHistory: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// auction i gets i puzzles, auctionIDs[i+1] is the ID the auction closed into
+	numAuctions := 3
+	var auctionIDs [][32]byte
+	for i := 0; i < numAuctions; i++ {
+		var auctionID [32]byte
+		if auctionID, err = s.CurrentAuctionID(); err != nil {
+			t.Errorf("Error getting auction ID: %s", err)
+			return
+		}
+		auctionIDs = append(auctionIDs, auctionID)
+
+		for j := 0; j < i; j++ {
+			var encOrder *match.EncryptedAuctionOrder
+			if _, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+				t.Errorf("Error creating test order: %s", err)
+				return
+			}
+
+			s.dbLock.Lock()
+			err = s.placePuzzle(encOrder)
+			s.dbLock.Unlock()
+			if err != nil {
+				t.Errorf("Error placing puzzle: %s", err)
+				return
+			}
+		}
+
+		if err = s.CommitOrdersNewAuction(); err != nil {
+			t.Errorf("Error committing orders: %s", err)
+			return
+		}
+	}
+
+	var lastAuctionID [32]byte
+	if lastAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting auction ID: %s", err)
+		return
+	}
+	auctionIDs = append(auctionIDs, lastAuctionID)
+
+	var roots []*match.AuctionRoot
+	if roots, err = s.CommitmentHistory(10); err != nil {
+		t.Errorf("Error getting commitment history: %s", err)
+		return
+	}
+
+	if len(roots) != numAuctions {
+		t.Errorf("Expected roots for %d auctions, got %d", numAuctions, len(roots))
+		return
+	}
+
+	// most recent first
+	for i, root := range roots {
+		auction := numAuctions - 1 - i
+		if root.AuctionID != auctionIDs[auction] || root.NumPuzzles != uint64(auction) {
+			t.Errorf("Root %d should be for auction %x with %d puzzles, got auction %x with %d puzzles", i, auctionIDs[auction], auction, root.AuctionID, root.NumPuzzles)
+			return
+		}
+
+		if root.Closing() != auctionIDs[auction+1] {
+			t.Errorf("Root for auction %x should close into the next auction ID %x, got %x", root.AuctionID, auctionIDs[auction+1], root.Closing())
+			return
+		}
+	}
+
+	// an auction with no puzzles has the auction ID as its root
+	if roots[numAuctions-1].Root != auctionIDs[0] {
+		t.Errorf("Root of an auction with no puzzles should be its auction ID")
+		return
+	}
+
+	if roots, err = s.CommitmentHistory(2); err != nil {
+		t.Errorf("Error getting commitment history: %s", err)
+		return
+	}
+
+	if len(roots) != 2 || roots[0].AuctionID != auctionIDs[2] || roots[1].AuctionID != auctionIDs[1] {
+		t.Errorf("Commitment history should only have the last 2 auctions")
+		return
+	}
+
+	if _, err = s.CommitmentHistory(0); err == nil {
+		t.Errorf("Getting the roots of no auctions should fail")
+		return
+	}
+
+	if _, err = s.CommitmentHistory(MaxCommitmentHistory + 1); err == nil {
+		t.Errorf("Getting the roots of more than %d auctions should fail", MaxCommitmentHistory)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,141 @@
+package cxauctionserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// ClearPairAuction clears a pair's auction once it has closed, and stores how much each of its orders filled
+// so clients can get their fills after the auction is over. A pair's auction can only be cleared once. If the
+// clearing price is outside of the pair's price bands, the auction is voided and an error is returned.
+func (s *OpencxAuctionServer) ClearPairAuction(pair *match.Pair, auctionID [32]byte) (fills []*match.AuctionFill, err error) {
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for clearing: %s", err)
+		return
+	}
+
+	if currentAuctionID == auctionID {
+		err = fmt.Errorf("Auction %x is still running, it can only be cleared once it closes", auctionID)
+		return
+	}
+
+	if pairAuctionID, ownAuction := s.runsOwnAuction(pair); ownAuction && pairAuctionID == auctionID {
+		err = fmt.Errorf("Auction %x for %s is still running, it can only be cleared once it closes", auctionID, pair.PrettyString())
+		return
+	}
+
+	var stored []*match.AuctionClearing
+	if stored, err = s.OpencxDB.ViewAuctionClearings(auctionID); err != nil {
+		err = fmt.Errorf("Error viewing clearings to check if auction was cleared: %s", err)
+		return
+	}
+
+	for _, clearing := range stored {
+		if clearing.Pair == *pair {
+			err = fmt.Errorf("Auction %x has already been cleared for %s", auctionID, pair.PrettyString())
+			return
+		}
+	}
+
+	var sellOrders, buyOrders []*match.AuctionOrder
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(pair, auctionID); err != nil {
+		err = fmt.Errorf("Error viewing auction order book for clearing: %s", err)
+		return
+	}
+
+	orders := append(sellOrders, buyOrders...)
+	clearing := &match.AuctionClearing{
+		AuctionID: auctionID,
+		Pair:      *pair,
+	}
+	if fills, clearing.ClearingPrice, err = match.ComputeAuctionFills(auctionID, orders); err != nil {
+		err = fmt.Errorf("Error computing auction fills: %s", err)
+		return
+	}
+	clearing.NoMatch = clearing.ClearingPrice == nil
+
+	// Fills keep the exact clearing price, price bands and stats use it as a float
+	var clearingPrice float64
+	if !clearing.NoMatch {
+		clearingPrice, _ = clearing.ClearingPrice.Float64()
+	}
+
+	// An auction that clears outside of the pair's price bands is voided, so nothing is stored and none of
+	// its orders fill. A book that doesn't cross has no clearing price to check.
+	if !clearing.NoMatch {
+		if err = s.CheckClearingPrice(pair, clearingPrice); err != nil {
+			fills = nil
+			logging.Warnf("Voided auction %x for %s: %s", auctionID, pair.PrettyString(), err)
+			err = fmt.Errorf("Auction %x for %s was voided, none of its orders filled: %s", auctionID, pair.PrettyString(), err)
+			return
+		}
+	}
+
+	var imbalance *match.AuctionImbalance
+	if imbalance, err = match.ComputeImbalance(*pair, orders, clearingPrice); err != nil {
+		fills = nil
+		err = fmt.Errorf("Error computing imbalance for clearing: %s", err)
+		return
+	}
+
+	if err = s.OpencxDB.PlaceAuctionFills(clearing, fills); err != nil {
+		err = fmt.Errorf("Error storing auction fills: %s", err)
+		return
+	}
+
+	// The imbalance is stamped with now, which is when the auction cleared
+	s.keepAuctionImbalance(auctionID, imbalance)
+
+	// What the buys got has to be what the sells gave, otherwise there's an accounting bug
+	if balanceErr := match.CheckFillsBalance(fills); balanceErr != nil {
+		logging.Warnf("Alert: clearing auction %x for %s failed its self-check: %s", auctionID, pair.PrettyString(), balanceErr)
+	}
+
+	logging.Infof("Cleared auction %x for %s with %d orders", auctionID, clearing, len(fills))
+
+	return
+}
+
+// AuctionFills gets how much each order filled in an auction that has been cleared, along with the clearing
+// price of its pair. Fills are stored, so they can be gotten any time after the auction clears. An auction that
+// cleared without any orders has no fills, which isn't an error, see AuctionClearings for whether it matched.
+func (s *OpencxAuctionServer) AuctionFills(auctionID [32]byte) (fills []*match.AuctionFill, err error) {
+	if _, err = s.AuctionClearings(auctionID); err != nil {
+		return
+	}
+
+	s.dbLock.Lock()
+	fills, err = s.OpencxDB.ViewAuctionFills(auctionID)
+	s.dbLock.Unlock()
+	if err != nil {
+		err = fmt.Errorf("Error viewing auction fills: %s", err)
+		return
+	}
+
+	return
+}
+
+// AuctionClearings gets the result of clearing each pair in an auction that has been cleared, which is either
+// the exact clearing price or an explicit no match if the pair's book didn't cross.
+func (s *OpencxAuctionServer) AuctionClearings(auctionID [32]byte) (clearings []*match.AuctionClearing, err error) {
+	s.dbLock.Lock()
+	clearings, err = s.OpencxDB.ViewAuctionClearings(auctionID)
+	s.dbLock.Unlock()
+	if err != nil {
+		err = fmt.Errorf("Error viewing auction clearings: %s", err)
+		return
+	}
+
+	if len(clearings) == 0 {
+		err = fmt.Errorf("Auction %x is unknown or hasn't been cleared", auctionID)
+		return
+	}
+
+	return
+}
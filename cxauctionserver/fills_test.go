@@ -0,0 +1,273 @@
+package cxauctionserver
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// placeTestClearingOrders places a buy at buyPrice and a sell at 2 in auctionID, and returns their pair. They
+// cross at 2 if buyPrice is 2.
+func placeTestClearingOrders(s *OpencxAuctionServer, auctionID [32]byte, buyPrice float64) (pair match.Pair, err error) {
+	for _, submission := range []struct {
+		side       string
+		amountHave uint64
+		price      float64
+	}{
+		{"buy", 1000, buyPrice},
+		{"sell", 2000, 2.0},
+	} {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			return
+		}
+
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(submission.side, submission.amountHave, submission.price, privkey); err != nil {
+			return
+		}
+		order.AuctionID = auctionID
+		if err = signTestOrder(order, privkey); err != nil {
+			return
+		}
+
+		if err = s.OpencxDB.PlaceAuctionOrder(order); err != nil {
+			return
+		}
+		pair = order.TradingPair
+	}
+
+	return
+}
+
+func TestClearOutOfBandAuction(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestClearOutOfBandAuction: %s", err)
+		return
+	}
+
+	closedAuctionID := [32]byte{0x01}
+	var pair match.Pair
+	if pair, err = placeTestClearingOrders(s, closedAuctionID, 2.0); err != nil {
+		t.Errorf("Error placing orders: %s", err)
+		return
+	}
+
+	if err = s.SetPriceBand(pair, &match.PriceBand{MinPrice: 3.0, MaxPrice: 4.0}); err != nil {
+		t.Errorf("Error setting price band: %s", err)
+		return
+	}
+
+	var fills []*match.AuctionFill
+	if fills, err = s.ClearPairAuction(&pair, closedAuctionID); err == nil {
+		t.Errorf("Auction clearing at 2 should be voided by the price band [3, 4]")
+		return
+	}
+
+	if len(fills) != 0 {
+		t.Errorf("Voided auction should not return fills, got %d", len(fills))
+		return
+	}
+
+	var stored []*match.AuctionFill
+	if stored, err = s.OpencxDB.ViewAuctionFills(closedAuctionID); err != nil {
+		t.Errorf("Error viewing stored fills: %s", err)
+		return
+	}
+
+	if len(stored) != 0 {
+		t.Errorf("Voided auction should not store fills, got %d", len(stored))
+		return
+	}
+
+	// Inside the band the same auction clears
+	if err = s.SetPriceBand(pair, &match.PriceBand{MinPrice: 1.0, MaxPrice: 3.0}); err != nil {
+		t.Errorf("Error setting price band: %s", err)
+		return
+	}
+
+	if fills, err = s.ClearPairAuction(&pair, closedAuctionID); err != nil {
+		t.Errorf("Error clearing auction inside the price band: %s", err)
+		return
+	}
+
+	if len(fills) != 2 {
+		t.Errorf("Auction inside the price band should have 2 fills, got %d", len(fills))
+		return
+	}
+
+	return
+}
+
+func TestClearOutsideOracleReserve(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestClearOutsideOracleReserve: %s", err)
+		return
+	}
+
+	closedAuctionID := [32]byte{0x01}
+	var pair match.Pair
+	if pair, err = placeTestClearingOrders(s, closedAuctionID, 2.0); err != nil {
+		t.Errorf("Error placing orders: %s", err)
+		return
+	}
+
+	// The auction clears at 2, more than 10% away from the reserve price
+	oracle := &mockOracle{price: big.NewRat(3, 1)}
+	if err = s.SetOracle(oracle, 0.1); err != nil {
+		t.Errorf("Error setting oracle: %s", err)
+		return
+	}
+
+	if _, err = s.ClearPairAuction(&pair, closedAuctionID); err == nil {
+		t.Errorf("Auction clearing at 2 should be voided by the oracle reserve price 3")
+		return
+	}
+
+	var stored []*match.AuctionFill
+	if stored, err = s.OpencxDB.ViewAuctionFills(closedAuctionID); err != nil {
+		t.Errorf("Error viewing stored fills: %s", err)
+		return
+	}
+
+	if len(stored) != 0 {
+		t.Errorf("Auction voided by the oracle should not store fills, got %d", len(stored))
+		return
+	}
+
+	// Once the reserve price is close to the clearing price the auction clears
+	oracle.price = big.NewRat(21, 10)
+	if _, err = s.ClearPairAuction(&pair, closedAuctionID); err != nil {
+		t.Errorf("Error clearing auction near the oracle reserve price: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestClearRecordsImbalance(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestClearRecordsImbalance: %s", err)
+		return
+	}
+
+	closedAuctionID := [32]byte{0x01}
+	var pair match.Pair
+	if pair, err = placeTestClearingOrders(s, closedAuctionID, 2.0); err != nil {
+		t.Errorf("Error placing orders: %s", err)
+		return
+	}
+
+	beforeClearing := time.Now().Unix()
+	if _, err = s.ClearPairAuction(&pair, closedAuctionID); err != nil {
+		t.Errorf("Error clearing auction: %s", err)
+		return
+	}
+	afterClearing := time.Now().Unix()
+
+	var imbalance *match.AuctionImbalance
+	if imbalance, err = s.AuctionImbalance(&pair, closedAuctionID); err != nil {
+		t.Errorf("Clearing should record the auction's imbalance: %s", err)
+		return
+	}
+
+	if imbalance.NoMatch || imbalance.ClearingPrice != 2 {
+		t.Errorf("Recorded imbalance should be at the clearing price 2, got %s", imbalance)
+		return
+	}
+
+	if imbalance.ClearedAtUnix < beforeClearing || imbalance.ClearedAtUnix > afterClearing {
+		t.Errorf("Auction should have cleared between %d and %d, got %d", beforeClearing, afterClearing, imbalance.ClearedAtUnix)
+		return
+	}
+
+	return
+}
+
+func TestClearNoMatchAuction(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestClearNoMatchAuction: %s", err)
+		return
+	}
+
+	// The buy at 1 doesn't reach the sell at 2, so the book doesn't cross
+	closedAuctionID := [32]byte{0x01}
+	var pair match.Pair
+	if pair, err = placeTestClearingOrders(s, closedAuctionID, 1.0); err != nil {
+		t.Errorf("Error placing orders: %s", err)
+		return
+	}
+
+	if _, err = s.AuctionClearings(closedAuctionID); err == nil {
+		t.Errorf("Auction that hasn't been cleared should not have a clearing result")
+		return
+	}
+
+	if _, err = s.ClearPairAuction(&pair, closedAuctionID); err != nil {
+		t.Errorf("Error clearing auction that doesn't cross: %s", err)
+		return
+	}
+
+	var clearings []*match.AuctionClearing
+	if clearings, err = s.AuctionClearings(closedAuctionID); err != nil {
+		t.Errorf("Error getting clearings of auction that didn't match: %s", err)
+		return
+	}
+
+	if len(clearings) != 1 || !clearings[0].NoMatch || clearings[0].ClearingPrice != nil {
+		t.Errorf("Auction that doesn't cross should have an explicit no match result, got %v", clearings)
+		return
+	}
+
+	var fills []*match.AuctionFill
+	if fills, err = s.AuctionFills(closedAuctionID); err != nil {
+		t.Errorf("Error getting fills of auction that didn't match: %s", err)
+		return
+	}
+
+	for _, fill := range fills {
+		if fill.AmountFilled != 0 || fill.ClearingPrice != nil {
+			t.Errorf("Nothing should fill in an auction that doesn't cross: %s", fill)
+			return
+		}
+	}
+
+	// An auction without any orders is a no match too, not an error
+	emptyAuctionID := [32]byte{0x02}
+	if _, err = s.ClearPairAuction(&pair, emptyAuctionID); err != nil {
+		t.Errorf("Error clearing empty auction: %s", err)
+		return
+	}
+
+	if _, err = s.AuctionFills(emptyAuctionID); err != nil {
+		t.Errorf("Cleared auction without fills should not be an error: %s", err)
+		return
+	}
+
+	if clearings, err = s.AuctionClearings(emptyAuctionID); err != nil || len(clearings) != 1 || !clearings[0].NoMatch {
+		t.Errorf("Empty auction should have an explicit no match result, got %v, %v", clearings, err)
+		return
+	}
+
+	if _, err = s.ClearPairAuction(&pair, emptyAuctionID); err == nil {
+		t.Errorf("Clearing an empty auction twice should fail")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,164 @@
+package cxauctionserver
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// TestMemoryStoreAuctionFlow runs orders from users with balances through an auction on the memory store,
+// then pays out the fills, with no database running.
+func TestMemoryStoreAuctionFlow(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestMemoryStoreAuctionFlow: %s", err)
+		return
+	}
+
+	var buyKey, sellKey *koblitz.PrivateKey
+	if buyKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating buyer key: %s", err)
+		return
+	}
+	if sellKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating seller key: %s", err)
+		return
+	}
+
+	// The pair wants BTC and has VTC, so the buyer has VTC and the seller has BTC
+	wantCoin := &coinparam.BitcoinParams
+	haveCoin := &coinparam.VertcoinTestNetParams
+	addressMap := map[*coinparam.Params]string{wantCoin: "", haveCoin: ""}
+	for _, deposit := range []struct {
+		key    *koblitz.PrivateKey
+		coin   *coinparam.Params
+		amount uint64
+	}{
+		{buyKey, haveCoin, 1500},
+		{sellKey, wantCoin, 2500},
+	} {
+		if err = s.OpencxDB.RegisterUser(deposit.key.PubKey(), addressMap); err != nil {
+			t.Errorf("Error registering user: %s", err)
+			return
+		}
+		if err = s.OpencxDB.AddToBalance(deposit.key.PubKey(), deposit.amount, deposit.coin); err != nil {
+			t.Errorf("Error depositing: %s", err)
+			return
+		}
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	// They cross at a price of 2 BTC per VTC
+	var buyOrder, sellOrder *match.AuctionOrder
+	if buyOrder, err = newTestContinuousOrder("buy", 1000, 2.0, buyKey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+	if sellOrder, err = newTestContinuousOrder("sell", 2000, 2.0, sellKey); err != nil {
+		t.Errorf("Error creating sell order: %s", err)
+		return
+	}
+
+	for _, submission := range []struct {
+		order *match.AuctionOrder
+		key   *koblitz.PrivateKey
+	}{
+		{buyOrder, buyKey},
+		{sellOrder, sellKey},
+	} {
+		submission.order.AuctionID = auctionID
+		if err = signTestOrder(submission.order, submission.key); err != nil {
+			t.Errorf("Error signing order: %s", err)
+			return
+		}
+		if err = s.placeSolvedOrder(submission.order, auctionID); err != nil {
+			t.Errorf("Error placing order: %s", err)
+			return
+		}
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error closing auction: %s", err)
+		return
+	}
+
+	var fills []*match.AuctionFill
+	if fills, err = s.ClearPairAuction(&buyOrder.TradingPair, auctionID); err != nil {
+		t.Errorf("Error clearing auction: %s", err)
+		return
+	}
+
+	if len(fills) != 2 {
+		t.Errorf("Both orders should have a fill, got %d", len(fills))
+		return
+	}
+
+	// Fills are in BTC. Buyers get the BTC and pay VTC at the clearing price, sellers the other way around.
+	for _, fill := range fills {
+		if fill.AmountFilled != 2000 || fill.ClearingPrice.Cmp(big.NewRat(2, 1)) != 0 {
+			t.Errorf("Both orders should fill 2000 at 2, got %s", fill)
+			return
+		}
+
+		var pubkey *koblitz.PublicKey
+		if pubkey, err = koblitz.ParsePubKey(fill.Pubkey[:], koblitz.S256()); err != nil {
+			t.Errorf("Error parsing fill pubkey: %s", err)
+			return
+		}
+
+		paid := new(big.Int).Quo(new(big.Int).Mul(new(big.Int).SetUint64(fill.AmountFilled), fill.ClearingPrice.Denom()), fill.ClearingPrice.Num()).Uint64()
+		gaveCoin, gave, gotCoin, got := haveCoin, paid, wantCoin, fill.AmountFilled
+		if fill.Side == "sell" {
+			gaveCoin, gave, gotCoin, got = wantCoin, fill.AmountFilled, haveCoin, paid
+		}
+
+		if err = s.OpencxDB.Withdraw(pubkey, gaveCoin, gave); err != nil {
+			t.Errorf("Error taking what the %s order gave: %s", fill.Side, err)
+			return
+		}
+		if err = s.OpencxDB.AddToBalance(pubkey, got, gotCoin); err != nil {
+			t.Errorf("Error paying what the %s order got: %s", fill.Side, err)
+			return
+		}
+	}
+
+	for _, expected := range []struct {
+		name    string
+		key     *koblitz.PrivateKey
+		coin    *coinparam.Params
+		balance uint64
+	}{
+		{"buyer BTC", buyKey, wantCoin, 2000},
+		{"buyer VTC", buyKey, haveCoin, 500},
+		{"seller BTC", sellKey, wantCoin, 500},
+		{"seller VTC", sellKey, haveCoin, 1000},
+	} {
+		var balance uint64
+		if balance, err = s.OpencxDB.GetBalance(expected.key.PubKey(), expected.coin); err != nil {
+			t.Errorf("Error getting %s balance: %s", expected.name, err)
+			return
+		}
+		if balance != expected.balance {
+			t.Errorf("Expected %s balance of %d, got %d", expected.name, expected.balance, balance)
+			return
+		}
+	}
+
+	// The buyer can't spend VTC it no longer has
+	if err = s.OpencxDB.Withdraw(buyKey.PubKey(), haveCoin, 501); err == nil {
+		t.Errorf("Withdrawing more than what's left after settling should fail")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,219 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// maxQueuedAuctions is how many auctions we remember the next auction of, so queued orders that are solved
+// late still go in the auction they were queued for
+const maxQueuedAuctions = 8
+
+// SetNextAuctionWindow sets how long before the current auction closes that orders for the next auction are
+// accepted. A window of 0, the default, means orders for the next auction are never accepted.
+func (s *OpencxAuctionServer) SetNextAuctionWindow(window time.Duration) (err error) {
+	if window < 0 {
+		err = fmt.Errorf("Next auction window cannot be negative, got %s", window)
+		return
+	}
+
+	s.queuedMtx.Lock()
+	s.nextAuctionWindow = window
+	s.queuedMtx.Unlock()
+	return
+}
+
+// NextAuctionWindow gets how long before the current auction closes that orders for the next auction are accepted
+func (s *OpencxAuctionServer) NextAuctionWindow() (window time.Duration, err error) {
+	s.queuedMtx.Lock()
+	window = s.nextAuctionWindow
+	s.queuedMtx.Unlock()
+	return
+}
+
+// QueueNextAuctionOrder queues a timelock encrypted order for the auction after the current one. The ID of the
+// next auction is a commitment to the orders in the current one, so it can't be known yet. Instead, the order
+// should be signed for the current auction and use its puzzle params, and it gets placed in the next auction as
+// soon as that starts.
+func (s *OpencxAuctionServer) QueueNextAuctionOrder(order *match.EncryptedAuctionOrder) (err error) {
+	return s.QueueNextAuctionOrderWithFee(order, 0)
+}
+
+// QueueNextAuctionOrderWithFee queues a timelock encrypted order for the next auction, like QueueNextAuctionOrder.
+// priorityFee is used to decide when to solve it once it's placed, like in PlacePuzzledOrderWithFee.
+func (s *OpencxAuctionServer) QueueNextAuctionOrderWithFee(order *match.EncryptedAuctionOrder, priorityFee uint64) (err error) {
+
+	// count the rejection after every lock is released
+	var rejection RejectionReason
+	defer func() {
+		if rejection != "" {
+			s.countRejection(rejection)
+		} else if err == nil {
+			s.rejections.accept()
+		}
+	}()
+
+	var mode MatchingMode
+	if mode, err = s.MatchingMode(); err != nil {
+		err = fmt.Errorf("Error getting matching mode for next auction order: \n%s", err)
+		return
+	}
+
+	if mode != BatchMatching {
+		rejection = RejectedWrongMode
+		err = fmt.Errorf("Exchange is in %s matching mode, next auction orders are only accepted in batch mode", mode)
+		return
+	}
+
+	if err = order.VerifyPuzzle(); err != nil {
+		rejection = RejectedBadPuzzle
+		err = fmt.Errorf("Error verifying next auction order: \n%s", err)
+		return
+	}
+
+	// Hold the db lock so the auction can't change until the order is queued
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	var auctionID [32]byte
+	var params match.PuzzleParams
+	if auctionID, params, err = s.CurrentPuzzleParams(); err != nil {
+		err = fmt.Errorf("Error getting puzzle params for next auction order: %s", err)
+		return
+	}
+
+	if order.IntendedAuction != auctionID {
+		rejection = RejectedWrongAuction
+		err = fmt.Errorf("Next auction orders must be made during the current auction %x, not %x", auctionID, order.IntendedAuction)
+		return
+	}
+
+	// The puzzle params for the next auction don't exist yet, so it has to use the current ones
+	if err = params.CheckPuzzle(order.OrderPuzzle); err != nil {
+		rejection = RejectedBadPuzzle
+		err = fmt.Errorf("Next auction order does not use the current puzzle params: %s", err)
+		return
+	}
+
+	var nextAuctionTime time.Time
+	if nextAuctionTime, err = s.NextAuctionTime(); err != nil {
+		err = fmt.Errorf("Error getting next auction time for next auction order: %s", err)
+		return
+	}
+
+	s.queuedMtx.Lock()
+	defer s.queuedMtx.Unlock()
+
+	// Queued orders only live in memory, so they'd be lost if they were taken while shutting down
+	if s.solves.isClosed() {
+		err = errSolveQueueClosed
+		return
+	}
+
+	if s.nextAuctionWindow == 0 {
+		rejection = RejectedOutsideWindow
+		err = fmt.Errorf("Exchange does not accept orders for the next auction")
+		return
+	}
+
+	if untilNext := time.Until(nextAuctionTime); untilNext > s.nextAuctionWindow {
+		rejection = RejectedOutsideWindow
+		err = fmt.Errorf("Next auction starts in %s, orders for it are only accepted %s before it starts", untilNext, s.nextAuctionWindow)
+		return
+	}
+
+	// Exact duplicates are dropped, so the same puzzle isn't solved twice
+	var forgetCiphertext func()
+	if forgetCiphertext, err = s.dedupCiphertext(order); err != nil {
+		if _, duplicate := err.(*duplicateCiphertextError); duplicate {
+			rejection = RejectedDuplicate
+		}
+		err = fmt.Errorf("Error checking for duplicate next auction order: %s", err)
+		return
+	}
+
+	// Log it first, since queued orders are otherwise only in memory until the auction closes
+	if err = s.logOrder(auctionID, loggedQueued, order, priorityFee); err != nil {
+		forgetCiphertext()
+		return
+	}
+
+	s.queuedOrders[auctionID] = append(s.queuedOrders[auctionID], &solveItem{
+		order:       order,
+		priorityFee: priorityFee,
+	})
+
+	logging.Infof("Queued order for the auction after %x", auctionID)
+
+	return
+}
+
+// releaseQueuedOrders places the orders queued during the auction with prevAuctionID in the auction with
+// newAuctionID, and starts solving them. The caller should be holding dbLock.
+func (s *OpencxAuctionServer) releaseQueuedOrders(prevAuctionID [32]byte, newAuctionID [32]byte) (err error) {
+	s.queuedMtx.Lock()
+	queued := s.queuedOrders[prevAuctionID]
+	delete(s.queuedOrders, prevAuctionID)
+
+	// remember where these went, forgetting about the oldest auction if we're tracking too many
+	if len(s.queuedAuctions) >= maxQueuedAuctions {
+		delete(s.queuedInto, s.queuedAuctions[0])
+		s.queuedAuctions = s.queuedAuctions[1:]
+	}
+	s.queuedInto[prevAuctionID] = newAuctionID
+	s.queuedAuctions = append(s.queuedAuctions, prevAuctionID)
+	s.queuedMtx.Unlock()
+
+	for _, item := range queued {
+		order := item.order
+		// The server is the one putting it in this auction now
+		order.IntendedAuction = newAuctionID
+
+		// Like any other order, it's only placed if there's room to solve it
+		placeErr := s.queueSolve(order, item.priorityFee, func() (err error) {
+			if err = s.logOrder(newAuctionID, loggedPuzzle, order, item.priorityFee); err != nil {
+				return
+			}
+			if err = s.placePuzzle(order); err != nil {
+				err = fmt.Errorf("Error placing queued order in new auction: %s", err)
+				return
+			}
+			return
+		})
+		if placeErr != nil {
+			logging.Errorf("Dropping next auction order: %s", placeErr)
+		}
+	}
+
+	if len(queued) > 0 {
+		logging.Infof("Placed %d queued orders in auction %x", len(queued), newAuctionID)
+	}
+
+	return
+}
+
+// queuedAuction returns the auction that orders queued during auctionID were placed in, if there is one
+func (s *OpencxAuctionServer) queuedAuction(auctionID [32]byte) (nextAuctionID [32]byte, found bool) {
+	s.queuedMtx.Lock()
+	nextAuctionID, found = s.queuedInto[auctionID]
+	s.queuedMtx.Unlock()
+	return
+}
+
+// placementAuction returns the auction a solved order goes in. This is the auction it was signed for, unless
+// it was queued during that auction for the next one.
+func (s *OpencxAuctionServer) placementAuction(decryptedOrder *match.AuctionOrder, encryptedOrder *match.EncryptedAuctionOrder) (auctionID [32]byte) {
+	auctionID = decryptedOrder.AuctionID
+	if encryptedOrder == nil || encryptedOrder.IntendedAuction == decryptedOrder.AuctionID {
+		return
+	}
+
+	if nextAuctionID, found := s.queuedAuction(decryptedOrder.AuctionID); found && nextAuctionID == encryptedOrder.IntendedAuction {
+		auctionID = nextAuctionID
+	}
+
+	return
+}
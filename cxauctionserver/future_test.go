@@ -0,0 +1,490 @@
+package cxauctionserver
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+	"github.com/mit-dci/opencx/match"
+)
+
+// initLongAuctionServer initializes a server whose auctions are long enough that the clock won't start a new
+// one during a test, with puzzle params that are quick to solve.
+func initLongAuctionServer() (s *OpencxAuctionServer, err error) {
+	testDB := new(cxdbmemory.CXDBMemory)
+	if err = testDB.SetupClient(testCoins); err != nil {
+		err = fmt.Errorf("Error setting up db client for tests: %s", err)
+		return
+	}
+
+	if s, err = InitServer(testDB, testOrderChanSize, uint64(time.Hour/time.Microsecond)); err != nil {
+		err = fmt.Errorf("Error initializing server for tests: %s", err)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.puzzleParams = &match.PuzzleParams{
+		A:           s.puzzleParams.A,
+		T:           1000,
+		ModulusBits: 1024,
+	}
+	s.auctionMtx.Unlock()
+	return
+}
+
+// newTestNextAuctionOrder creates an encrypted order signed for the current auction with its puzzle params
+func newTestNextAuctionOrder(s *OpencxAuctionServer, privkey *koblitz.PrivateKey) (order *match.AuctionOrder, encOrder *match.EncryptedAuctionOrder, err error) {
+	var auctionID [32]byte
+	var params match.PuzzleParams
+	if auctionID, params, err = s.CurrentPuzzleParams(); err != nil {
+		return
+	}
+
+	if order, err = newTestContinuousOrder("buy", 10000, 2.0, privkey); err != nil {
+		return
+	}
+
+	order.AuctionID = auctionID
+	if err = signTestOrder(order, privkey); err != nil {
+		return
+	}
+
+	encOrder, err = order.TurnIntoEncryptedOrderWithParams(&params)
+	return
+}
+
+func TestNextAuctionOrderPlacedInNextAuction(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestNextAuctionOrderPlacedInNextAuction: %s", err)
+		return
+	}
+
+	if err = s.SetNextAuctionWindow(2 * time.Hour); err != nil {
+		t.Errorf("Error setting next auction window: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	var encOrder *match.EncryptedAuctionOrder
+	if order, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+		t.Errorf("Error creating next auction order: %s", err)
+		return
+	}
+
+	if err = s.QueueNextAuctionOrder(encOrder); err != nil {
+		t.Errorf("Error queueing next auction order: %s", err)
+		return
+	}
+
+	// It shouldn't be in the current auction
+	var puzzles []*match.EncryptedAuctionOrder
+	if puzzles, err = s.OpencxDB.ViewAuctionPuzzleBook(order.AuctionID); err != nil {
+		t.Errorf("Error viewing puzzle book: %s", err)
+		return
+	}
+
+	if len(puzzles) != 0 {
+		t.Errorf("Queued order should not be in the current auction, found %d puzzles", len(puzzles))
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	var nextAuctionID [32]byte
+	if nextAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting next auction ID: %s", err)
+		return
+	}
+
+	if puzzles, err = s.OpencxDB.ViewAuctionPuzzleBook(nextAuctionID); err != nil {
+		t.Errorf("Error viewing next auction puzzle book: %s", err)
+		return
+	}
+
+	if len(puzzles) != 1 {
+		t.Errorf("Queued order should be in the next auction puzzle book, found %d puzzles", len(puzzles))
+		return
+	}
+
+	// Wait for it to be solved and placed in the next auction's order book
+	deadline := time.Now().Add(time.Minute)
+	for {
+		_, buyOrders, _ := s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, nextAuctionID)
+		if len(buyOrders) == 1 {
+			if !bytes.Equal(buyOrders[0].Pubkey[:], order.Pubkey[:]) || buyOrders[0].AmountHave != order.AmountHave {
+				t.Errorf("Order in the next auction is not the queued order")
+				return
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Errorf("Queued order was never placed in the next auction order book")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return
+}
+
+func TestNextAuctionOrderRejected(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestNextAuctionOrderRejected: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var encOrder *match.EncryptedAuctionOrder
+	if _, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+		t.Errorf("Error creating next auction order: %s", err)
+		return
+	}
+
+	// The default is to not accept orders for the next auction
+	if err = s.QueueNextAuctionOrder(encOrder); err == nil {
+		t.Errorf("Next auction orders should not be accepted without a window")
+		return
+	}
+
+	// The next auction is an hour away, so this is too early
+	if err = s.SetNextAuctionWindow(time.Minute); err != nil {
+		t.Errorf("Error setting next auction window: %s", err)
+		return
+	}
+
+	if err = s.QueueNextAuctionOrder(encOrder); err == nil {
+		t.Errorf("Next auction orders should not be accepted before the window")
+		return
+	}
+
+	if err = s.SetNextAuctionWindow(2 * time.Hour); err != nil {
+		t.Errorf("Error setting next auction window: %s", err)
+		return
+	}
+
+	wrongAuction := *encOrder
+	wrongAuction.IntendedAuction = [32]byte{0xff}
+	if err = s.QueueNextAuctionOrder(&wrongAuction); err == nil {
+		t.Errorf("Next auction orders made for an auction other than the current one should not be accepted")
+		return
+	}
+
+	if err = s.SetNextAuctionWindow(-time.Second); err == nil {
+		t.Errorf("Setting a negative next auction window should fail")
+		return
+	}
+
+	return
+}
+
+func TestSolvedOrderAuctionMismatch(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestSolvedOrderAuctionMismatch: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	if order, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+
+	// Sent for a different auction than the one it was signed for, and never queued
+	mismatched := &match.OrderPuzzleResult{
+		Encrypted: &match.EncryptedAuctionOrder{IntendedAuction: [32]byte{0xff}},
+		Auction:   order,
+	}
+	if _, _, err = s.checkSolvedOrder(mismatched); match.PuzzleFailureReason(err) != match.PuzzleAuctionMismatch {
+		t.Errorf("Order sent for a different auction should fail with %s, got %v", match.PuzzleAuctionMismatch, err)
+		return
+	}
+
+	// It should be counted with the batch and not placed
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{mismatched})
+	if match.PuzzleFailureReason(mismatched.Err) != match.PuzzleAuctionMismatch {
+		t.Errorf("Mismatched order result should have the %s reason, got %v", match.PuzzleAuctionMismatch, mismatched.Err)
+		return
+	}
+
+	var sellOrders, buyOrders []*match.AuctionOrder
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, order.AuctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(sellOrders) != 0 || len(buyOrders) != 0 {
+		t.Errorf("Mismatched order should not be placed, got %d sell and %d buy", len(sellOrders), len(buyOrders))
+		return
+	}
+
+	// The same order sent for the auction it was signed for is fine
+	matched := &match.OrderPuzzleResult{
+		Encrypted: &match.EncryptedAuctionOrder{IntendedAuction: order.AuctionID},
+		Auction:   order,
+	}
+	var signedFor [32]byte
+	if _, signedFor, err = s.checkSolvedOrder(matched); err != nil {
+		t.Errorf("Order sent for the auction it was signed for should be valid: %s", err)
+		return
+	}
+
+	if signedFor != order.AuctionID {
+		t.Errorf("Order that wasn't queued should have to be signed for auction %x, got %x", order.AuctionID, signedFor)
+		return
+	}
+
+	return
+}
+
+func TestSolvedOrderReplayedIntoOtherAuction(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestSolvedOrderReplayedIntoOtherAuction: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	if order, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+
+	signedAuction := [32]byte{0xaa}
+	otherAuction := [32]byte{0xbb}
+	order.AuctionID = signedAuction
+	if err = signTestOrder(order, privkey); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	// The order is sent again for another auction, with its auction ID changed to match. Everything but the
+	// signature lines up, so only the signature can catch it.
+	replayed := order.Clone()
+	replayed.AuctionID = otherAuction
+	replayedResult := &match.OrderPuzzleResult{
+		Encrypted: &match.EncryptedAuctionOrder{IntendedAuction: otherAuction},
+		Auction:   replayed,
+	}
+
+	var signedFor [32]byte
+	if _, signedFor, err = s.checkSolvedOrder(replayedResult); err != nil {
+		t.Errorf("Replayed order should only be caught by its signature, got %s", err)
+		return
+	}
+
+	if err = replayed.VerifySignatureForAuction(signedFor); err == nil {
+		t.Errorf("Replayed order should not verify for auction %x", signedFor)
+		return
+	}
+
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{replayedResult})
+
+	// An order really signed for the other auction is placed, and should be the only one there
+	var honest *match.AuctionOrder
+	if honest, err = newTestContinuousOrder("sell", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+
+	honest.AuctionID = otherAuction
+	if err = signTestOrder(honest, privkey); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{
+		Encrypted: &match.EncryptedAuctionOrder{IntendedAuction: otherAuction},
+		Auction:   honest,
+	}})
+
+	var sellOrders, buyOrders []*match.AuctionOrder
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, otherAuction); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 0 || len(sellOrders) != 1 {
+		t.Errorf("Only the order signed for the auction should be placed in it, got %d sell and %d buy", len(sellOrders), len(buyOrders))
+		return
+	}
+
+	return
+}
+
+func TestCancelEverythingCancelsNextAuctionOrders(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestCancelEverythingCancelsNextAuctionOrders: %s", err)
+		return
+	}
+
+	if err = s.SetNextAuctionWindow(2 * time.Hour); err != nil {
+		t.Errorf("Error setting next auction window: %s", err)
+		return
+	}
+
+	var callerKey, otherKey *koblitz.PrivateKey
+	if callerKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating caller key: %s", err)
+		return
+	}
+	if otherKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating other key: %s", err)
+		return
+	}
+
+	// Both pubkeys queue an order for the next auction
+	var callerOrder, otherOrder *match.AuctionOrder
+	var callerEncOrder, otherEncOrder *match.EncryptedAuctionOrder
+	if callerOrder, callerEncOrder, err = newTestNextAuctionOrder(s, callerKey); err != nil {
+		t.Errorf("Error creating next auction order: %s", err)
+		return
+	}
+	if otherOrder, otherEncOrder, err = newTestNextAuctionOrder(s, otherKey); err != nil {
+		t.Errorf("Error creating next auction order: %s", err)
+		return
+	}
+	for _, encOrder := range []*match.EncryptedAuctionOrder{callerEncOrder, otherEncOrder} {
+		if err = s.QueueNextAuctionOrder(encOrder); err != nil {
+			t.Errorf("Error queueing next auction order: %s", err)
+			return
+		}
+	}
+
+	// The caller also has an order in the current auction
+	var currentOrder *match.AuctionOrder
+	if currentOrder, err = newTestContinuousOrder("sell", 5000, 2.0, callerKey); err != nil {
+		t.Errorf("Error creating current auction order: %s", err)
+		return
+	}
+	currentOrder.AuctionID = callerOrder.AuctionID
+	if err = signTestOrder(currentOrder, callerKey); err != nil {
+		t.Errorf("Error signing current auction order: %s", err)
+		return
+	}
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: currentOrder}})
+
+	var cancelled []*match.AuctionOrder
+	if cancelled, err = s.CancelEverything(callerKey.PubKey(), callerOrder.AuctionID); err != nil {
+		t.Errorf("Error cancelling everything: %s", err)
+		return
+	}
+
+	if len(cancelled) != 1 || cancelled[0].AmountHave != currentOrder.AmountHave {
+		t.Errorf("Should have cancelled the order in the current auction, instead cancelled %d orders", len(cancelled))
+		return
+	}
+
+	if _, err = s.CancelEverything(callerKey.PubKey(), [32]byte{0xff}); err == nil {
+		t.Errorf("Cancelling everything for an auction other than the current one should fail")
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	var nextAuctionID [32]byte
+	if nextAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting next auction ID: %s", err)
+		return
+	}
+
+	// Wait for the other pubkey's queued order to be solved and placed
+	deadline := time.Now().Add(time.Minute)
+	for {
+		_, buyOrders, _ := s.OpencxDB.ViewAuctionOrderBook(&otherOrder.TradingPair, nextAuctionID)
+		if len(buyOrders) > 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Errorf("Other pubkey's queued order was never placed in the next auction order book")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// However the caller's queued order gets solved, it shouldn't be placed
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Encrypted: callerEncOrder, Auction: callerOrder}})
+
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&otherOrder.TradingPair, nextAuctionID); err != nil {
+		t.Errorf("Error viewing next auction order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 1 || buyOrders[0].Pubkey != otherOrder.Pubkey {
+		t.Errorf("Only the other pubkey's queued order should be in the next auction, found %d orders", len(buyOrders))
+		return
+	}
+
+	// Orders the caller signs for the next auction are placed like normal
+	var newOrder *match.AuctionOrder
+	if newOrder, err = newTestContinuousOrder("buy", 7000, 2.0, callerKey); err != nil {
+		t.Errorf("Error creating new order: %s", err)
+		return
+	}
+	newOrder.AuctionID = nextAuctionID
+	if err = signTestOrder(newOrder, callerKey); err != nil {
+		t.Errorf("Error signing new order: %s", err)
+		return
+	}
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: newOrder}})
+
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&newOrder.TradingPair, nextAuctionID); err != nil {
+		t.Errorf("Error viewing next auction order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 2 {
+		t.Errorf("Order signed after cancelling everything should be placed, expected 2 orders got %d", len(buyOrders))
+		return
+	}
+
+	return
+}
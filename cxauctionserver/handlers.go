@@ -1,29 +1,273 @@
 package cxauctionserver
 
 import (
+	"fmt"
+
 	"github.com/mit-dci/opencx/logging"
 	"github.com/mit-dci/opencx/match"
 )
 
-// AuctionOrderHandler tries to receive solved orders and when it does, it validates them and sends them to be processed
+// maxSolvedBatchSize is the most solved orders that get validated together
+const maxSolvedBatchSize = 1024
+
+// AuctionOrderHandler tries to receive solved orders and when it does, it validates them and sends them to be processed.
+// Whatever orders have been solved by the time one is received get validated with it, so their signatures can be
+// verified as a batch.
 func (s *OpencxAuctionServer) AuctionOrderHandler(orderResultChannel chan *match.OrderPuzzleResult) {
 	// We can reuse these, do not put them in the infinite loop
 	var receivedOrder *match.OrderPuzzleResult
-	var err error
+	var batch []*match.OrderPuzzleResult
 	for {
 		receivedOrder = <-orderResultChannel
+		batch = append(batch[:0], receivedOrder)
+
+	drain:
+		for len(batch) < maxSolvedBatchSize {
+			select {
+			case receivedOrder = <-orderResultChannel:
+				batch = append(batch, receivedOrder)
+			default:
+				break drain
+			}
+		}
+
+		s.handleSolvedOrders(batch)
+
+		// Only now are the results persisted, so shutting down can stop waiting for them
+		for _, result := range batch {
+			s.solves.done(result.Encrypted)
+		}
+	}
+}
+
+// handleSolvedOrders validates a batch of solved orders and places the valid ones in the auction
+func (s *OpencxAuctionServer) handleSolvedOrders(results []*match.OrderPuzzleResult) {
+	var err error
+
+	var orders []*match.AuctionOrder
+	var solved []*match.OrderPuzzleResult
+	var placeIn [][32]byte
+	var signedFor [][32]byte
+	for _, receivedOrder := range results {
 		if receivedOrder.Err != nil {
 			logging.Errorf("Error came in with order solving result: %s", receivedOrder.Err)
+			s.countPuzzleFailure(match.PuzzleFailureReason(receivedOrder.Err))
 			// if there was an error, don't process the order
 			continue
 		}
 
-		if err = s.validateOrder(receivedOrder.Auction, receivedOrder.Encrypted); err != nil {
+		var placement, signedAuction [32]byte
+		if placement, signedAuction, err = s.checkSolvedOrder(receivedOrder); err != nil {
 			logging.Errorf("Error validating order: %s", err)
+			// keep the reason with the result so it gets counted with the rest of the batch
+			if reason := match.PuzzleFailureReason(err); reason != "" {
+				receivedOrder.Err = err
+				s.countPuzzleFailure(reason)
+			} else {
+				s.countRejection(RejectedInvalid)
+			}
+			continue
+		}
+
+		orders = append(orders, receivedOrder.Auction)
+		solved = append(solved, receivedOrder)
+		placeIn = append(placeIn, placement)
+		signedFor = append(signedFor, signedAuction)
+	}
+
+	if failures := match.TallyPuzzleFailures(results); len(failures) > 0 {
+		logging.Errorf("Orders failed in batch of %d solved orders: %s", len(results), failures)
+	}
+
+	if len(orders) == 0 {
+		return
+	}
+
+	var verifier *match.BatchVerifier
+	if verifier, err = s.verifier(); err != nil {
+		logging.Errorf("Error getting verifier for solved orders: %s", err)
+		return
+	}
+
+	var valid []bool
+	// The signature has to cover the auction the order is going in, so it can't be replayed into another one
+	if valid, err = verifier.VerifyForAuctions(orders, signedFor); err != nil {
+		logging.Errorf("Error verifying signatures of solved orders: %s", err)
+		return
+	}
+
+	for i, order := range orders {
+		if !valid[i] {
+			logging.Errorf("Error validating order: invalid signature for order placed by %x", order.Pubkey)
+			s.countRejection(RejectedInvalid)
+			continue
+		}
+
+		logging.Infof("Order valid! Order placed by %x", order.Pubkey)
+
+		// Orders queued for the next auction were signed for the one before it
+		if placeIn[i] != order.AuctionID {
+			order = order.Clone()
+			order.AuctionID = placeIn[i]
+		}
+
+		// Now that it's valid it's pending in the auction
+		if err = s.placeSolvedOrder(order, signedFor[i]); err != nil {
+			logging.Errorf("Error placing solved order: %s", err)
 			continue
 		}
 
-		logging.Infof("Order valid! Order placed by %x", receivedOrder.Auction.Pubkey)
+		if err = s.keepOrderKey(order, solved[i]); err != nil {
+			logging.Errorf("Error keeping key of placed order: %s", err)
+			continue
+		}
+	}
+
+	return
+}
+
+// checkSolvedOrder validates the fields of a solved order and finds the auction it should be placed in, and
+// the auction its signature has to be for. Those are the same unless the order was queued for the next
+// auction. If checking the order panics, the panic is returned as an error so one bad order doesn't take
+// down the batch.
+func (s *OpencxAuctionServer) checkSolvedOrder(result *match.OrderPuzzleResult) (placeIn [32]byte, signedFor [32]byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Panic while validating solved order: %v", r)
+		}
+	}()
+
+	if err = s.validateOrderFields(result.Auction, result.Encrypted); err != nil {
+		return
+	}
+
+	placeIn = s.placementAuction(result.Auction, result.Encrypted)
 
+	// The order has to be for the auction it was sent in, or one it was queued for
+	if result.Encrypted != nil && placeIn != result.Encrypted.IntendedAuction {
+		err = match.NewPuzzleResultError(match.PuzzleAuctionMismatch, "Order was sent for auction %x but was signed for auction %x", result.Encrypted.IntendedAuction, result.Auction.AuctionID)
+		return
+	}
+
+	// Pairs that run their own auctions only take orders in them, and their auctions only take their orders
+	if err = s.checkPairAuction(result.Auction, placeIn); err != nil {
+		return
+	}
+
+	// Only orders queued during the auction before can be signed for a different auction than they go in
+	signedFor = placeIn
+	if nextAuctionID, found := s.queuedAuction(result.Auction.AuctionID); found && nextAuctionID == placeIn {
+		signedFor = result.Auction.AuctionID
+	}
+
+	return
+}
+
+// placeSolvedOrder places a valid solved order in its auction, unless the same order has already been placed
+// in it, or its pubkey has already used its nonce in the auction, or cancelled everything during signedFor,
+// the auction the order was signed for. If placing the order panics, the panic is returned as an error and
+// the db lock is still released.
+func (s *OpencxAuctionServer) placeSolvedOrder(order *match.AuctionOrder, signedFor [32]byte) (err error) {
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	err = s.placeSolvedOrderLocked(order, signedFor)
+	return
+}
+
+// placeSolvedOrderLocked places a valid solved order like placeSolvedOrder. The caller should be holding dbLock.
+func (s *OpencxAuctionServer) placeSolvedOrderLocked(order *match.AuctionOrder, signedFor [32]byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Panic while placing solved order: %v", r)
+		}
+	}()
+
+	if s.cancelledEverything[signedFor][order.Pubkey] {
+		err = fmt.Errorf("Order placed by %x was cancelled when it cancelled everything in auction %x", order.Pubkey, signedFor)
+		return
+	}
+
+	var orderID [32]byte
+	if orderID, err = order.OrderID(); err != nil {
+		err = fmt.Errorf("Error getting ID of solved order: %s", err)
+		return
+	}
+
+	placed, found := s.placedOrders[order.AuctionID]
+	if !found {
+		placed = make(map[[32]byte]bool)
+		s.placedOrders[order.AuctionID] = placed
+	}
+
+	if placed[orderID] {
+		s.countRejection(RejectedDuplicate)
+		err = fmt.Errorf("Order %x has already been placed in auction %x", orderID, order.AuctionID)
+		return
+	}
+
+	nonces, found := s.placedNonces[order.AuctionID]
+	if !found {
+		nonces = match.NewScopedNonceSet(s.nonceScope)
+		s.placedNonces[order.AuctionID] = nonces
+	}
+
+	if err = nonces.Check(order); err != nil {
+		if nonces.Used(order) {
+			s.countRejection(RejectedDuplicate)
+		} else {
+			s.countRejection(RejectedRateLimited)
+		}
+		err = fmt.Errorf("Error checking nonce of solved order: %s", err)
+		return
+	}
+
+	pubkeys, found := s.placedPubkeys[order.AuctionID]
+	if !found {
+		pubkeys = make(map[[33]byte]bool)
+		s.placedPubkeys[order.AuctionID] = pubkeys
+	}
+
+	// Pubkeys that are already in the auction can keep placing orders once it's full
+	if s.maxPubkeys > 0 && !pubkeys[order.Pubkey] && len(pubkeys) >= s.maxPubkeys {
+		s.countRejection(RejectedRateLimited)
+		err = fmt.Errorf("Auction %x already has orders from %d pubkeys, the most it can have, so %x can't place any", order.AuctionID, len(pubkeys), order.Pubkey)
+		return
+	}
+
+	if err = s.OpencxDB.PlaceAuctionOrder(order); err != nil {
+		return
+	}
+
+	placed[orderID] = true
+	pubkeys[order.Pubkey] = true
+	if err = nonces.Add(order); err != nil {
+		err = fmt.Errorf("Error adding nonce of solved order: %s", err)
+		return
+	}
+	return
+}
+
+// forgetPlacedOrders forgets the orders placed in every auction but auctionID, so they don't pile up as
+// auctions close. auctionID should be the auction that just closed, since orders in it are still being solved
+// and placed. Pair auctions forget theirs once they're cleared. The caller should be holding dbLock.
+func (s *OpencxAuctionServer) forgetPlacedOrders(auctionID [32]byte) {
+	s.pairMtx.Lock()
+	defer s.pairMtx.Unlock()
+
+	for placedIn := range s.placedOrders {
+		if _, pairAuction := s.pairAuctionIDs[placedIn]; placedIn != auctionID && !pairAuction {
+			delete(s.placedOrders, placedIn)
+		}
+	}
+	for placedIn := range s.placedNonces {
+		if _, pairAuction := s.pairAuctionIDs[placedIn]; placedIn != auctionID && !pairAuction {
+			delete(s.placedNonces, placedIn)
+		}
+	}
+	for placedIn := range s.placedPubkeys {
+		if _, pairAuction := s.pairAuctionIDs[placedIn]; placedIn != auctionID && !pairAuction {
+			delete(s.placedPubkeys, placedIn)
+		}
 	}
 }
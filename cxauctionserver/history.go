@@ -0,0 +1,63 @@
+package cxauctionserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// HistoricalOrders gets the decrypted orders for a pair from the last numAuctions auctions that closed, oldest
+// auction first, along with how much they filled if the auction was cleared. This is the order flow that
+// matching algorithms can be backtested on, so only admins can get it.
+func (s *OpencxAuctionServer) HistoricalOrders(pubkey *koblitz.PublicKey, pair *match.Pair, numAuctions uint64) (orders []*match.HistoricalOrder, err error) {
+	if !s.IsAdmin(pubkey) {
+		err = fmt.Errorf("Pubkey %x is not an admin, only admins can get historical orders", pubkey.SerializeCompressed())
+		return
+	}
+
+	var roots []*match.AuctionRoot
+	if roots, err = s.CommitmentHistory(numAuctions); err != nil {
+		err = fmt.Errorf("Error getting past auctions for historical orders: %s", err)
+		return
+	}
+
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	// roots are most recent first
+	for i := len(roots) - 1; i >= 0; i-- {
+		var sellOrders, buyOrders []*match.AuctionOrder
+		if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(pair, roots[i].AuctionID); err != nil {
+			err = fmt.Errorf("Error viewing order book of auction %x for historical orders: %s", roots[i].AuctionID, err)
+			return
+		}
+
+		var fills []*match.AuctionFill
+		if fills, err = s.OpencxDB.ViewAuctionFills(roots[i].AuctionID); err != nil {
+			err = fmt.Errorf("Error viewing fills of auction %x for historical orders: %s", roots[i].AuctionID, err)
+			return
+		}
+
+		fillsByOrder := make(map[[32]byte]*match.AuctionFill)
+		for _, fill := range fills {
+			fillsByOrder[fill.OrderHash] = fill
+		}
+
+		for _, order := range append(sellOrders, buyOrders...) {
+			historical := &match.HistoricalOrder{
+				Version:      match.HistoricalOrderVersion,
+				AuctionID:    roots[i].AuctionID,
+				ClosedAtUnix: roots[i].Time.UnixNano(),
+				Order:        order.Clone(),
+			}
+			if fill, found := fillsByOrder[order.Hash()]; found {
+				fillCopy := *fill
+				historical.Fill = &fillCopy
+			}
+			orders = append(orders, historical)
+		}
+	}
+
+	return
+}
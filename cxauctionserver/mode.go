@@ -0,0 +1,107 @@
+package cxauctionserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/cxdb"
+	"github.com/mit-dci/opencx/match"
+)
+
+// MatchingMode is how the exchange matches orders
+type MatchingMode uint8
+
+const (
+	// BatchMatching takes timelock encrypted orders and matches them in batch auctions. This is the
+	// default, and is what makes the exchange front-running resistant.
+	BatchMatching MatchingMode = iota
+	// ContinuousMatching takes unencrypted orders and matches them as they arrive with price-time
+	// priority. This is faster, but gives up front-running resistance.
+	ContinuousMatching
+)
+
+// String returns the name of the matching mode
+func (m MatchingMode) String() string {
+	switch m {
+	case BatchMatching:
+		return "batch"
+	case ContinuousMatching:
+		return "continuous"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(m))
+}
+
+// MatchingModeFromString parses a matching mode from its name
+func MatchingModeFromString(name string) (mode MatchingMode, err error) {
+	switch name {
+	case "batch":
+		mode = BatchMatching
+	case "continuous":
+		mode = ContinuousMatching
+	default:
+		err = fmt.Errorf("Unknown matching mode %s, must be batch or continuous", name)
+	}
+	return
+}
+
+// SetMatchingMode sets how the exchange matches orders. In continuous mode, continuousStore is the
+// price-time matcher that orders are placed in as they arrive.
+func (s *OpencxAuctionServer) SetMatchingMode(mode MatchingMode, continuousStore cxdb.OpencxStore) (err error) {
+	if mode != BatchMatching && mode != ContinuousMatching {
+		err = fmt.Errorf("Cannot set unknown matching mode %s", mode)
+		return
+	}
+
+	if mode == ContinuousMatching && continuousStore == nil {
+		err = fmt.Errorf("Continuous matching needs a store to match orders in")
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.mode = mode
+	s.continuousStore = continuousStore
+	s.auctionMtx.Unlock()
+	return
+}
+
+// MatchingMode gets how the exchange matches orders
+func (s *OpencxAuctionServer) MatchingMode() (mode MatchingMode, err error) {
+	s.auctionMtx.RLock()
+	mode = s.mode
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// PlaceContinuousOrder places an unencrypted order, which is matched as soon as it arrives. This is only
+// allowed in continuous mode.
+func (s *OpencxAuctionServer) PlaceContinuousOrder(order *match.AuctionOrder) (orderID string, err error) {
+	s.auctionMtx.RLock()
+	mode := s.mode
+	continuousStore := s.continuousStore
+	s.auctionMtx.RUnlock()
+
+	if mode != ContinuousMatching {
+		err = fmt.Errorf("Exchange is in %s matching mode, unencrypted orders are only accepted in continuous mode", mode)
+		return
+	}
+
+	// there's no puzzle, so there's no encrypted order to check against
+	if err = s.validateOrder(order, nil); err != nil {
+		err = fmt.Errorf("Error validating continuous order: %s", err)
+		return
+	}
+
+	limitOrder := &match.LimitOrder{
+		Pubkey:      order.Pubkey,
+		Side:        order.Side,
+		TradingPair: order.TradingPair,
+		AmountHave:  order.AmountHave,
+		AmountWant:  order.AmountWant,
+	}
+
+	if orderID, err = continuousStore.PlaceOrder(limitOrder); err != nil {
+		err = fmt.Errorf("Error placing continuous order: %s", err)
+		return
+	}
+
+	return
+}
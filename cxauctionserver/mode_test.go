@@ -0,0 +1,125 @@
+package cxauctionserver
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+	"github.com/mit-dci/opencx/match"
+)
+
+// newTestContinuousOrder creates a signed order for the pair generated from testCoins
+func newTestContinuousOrder(side string, amountHave uint64, price float64, privkey *koblitz.PrivateKey) (order *match.AuctionOrder, err error) {
+	order = &match.AuctionOrder{
+		Side:       side,
+		AmountHave: amountHave,
+		Network:    match.TestnetMagic,
+		TradingPair: match.Pair{
+			AssetWant: match.BTC,
+			AssetHave: match.VTCTest,
+		},
+	}
+	if err = order.SetAmountWant(price); err != nil {
+		return
+	}
+
+	err = signTestOrder(order, privkey)
+	return
+}
+
+func TestContinuousModeMatchesUnencrypted(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestContinuousModeMatchesUnencrypted: %s", err)
+		return
+	}
+
+	matchingDB := new(cxdbmemory.CXDBMatchingOnly)
+	if err = matchingDB.SetupClient(testCoins); err != nil {
+		t.Errorf("Error setting up continuous matching store: %s", err)
+		return
+	}
+
+	if err = s.SetMatchingMode(ContinuousMatching, matchingDB); err != nil {
+		t.Errorf("Error setting continuous matching mode: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var buyOrder *match.AuctionOrder
+	if buyOrder, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	var sellOrder *match.AuctionOrder
+	if sellOrder, err = newTestContinuousOrder("sell", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating sell order: %s", err)
+		return
+	}
+
+	if _, err = s.PlaceContinuousOrder(buyOrder); err != nil {
+		t.Errorf("Error placing continuous buy order: %s", err)
+		return
+	}
+
+	if metrics := matchingDB.Metrics(); metrics.Fills != 0 {
+		t.Errorf("A single order should not be matched, got %s", metrics.String())
+		return
+	}
+
+	if _, err = s.PlaceContinuousOrder(sellOrder); err != nil {
+		t.Errorf("Error placing continuous sell order: %s", err)
+		return
+	}
+
+	// The sell order should be matched as soon as it arrives, without waiting for an auction
+	if metrics := matchingDB.Metrics(); metrics.Fills != 1 || metrics.OrdersFilled != 2 {
+		t.Errorf("Crossing continuous orders should be matched right away, got %s", metrics.String())
+		return
+	}
+
+	// Puzzles shouldn't be accepted in continuous mode
+	if err = s.PlacePuzzledOrder(testEncryptedOrder); err == nil {
+		t.Errorf("Puzzled orders should be rejected in continuous mode")
+		return
+	}
+
+	return
+}
+
+func TestBatchModeRejectsUnencrypted(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestBatchModeRejectsUnencrypted: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var buyOrder *match.AuctionOrder
+	if buyOrder, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	if _, err = s.PlaceContinuousOrder(buyOrder); err == nil {
+		t.Errorf("Unencrypted orders should be rejected in batch mode")
+		return
+	}
+
+	return
+}
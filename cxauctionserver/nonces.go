@@ -0,0 +1,105 @@
+package cxauctionserver
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// SetNonceScope sets what a pubkey's nonces have to be unique within. With match.NoncePerPair, a pubkey can
+// use the same nonce on different pairs in an auction. Auctions that already have orders placed in them keep
+// the scope they had.
+func (s *OpencxAuctionServer) SetNonceScope(scope match.NonceScope) (err error) {
+	if scope != match.NoncePerAuction && scope != match.NoncePerPair {
+		err = fmt.Errorf("Cannot set unknown nonce scope %s", scope)
+		return
+	}
+
+	s.dbLock.Lock()
+	s.nonceScope = scope
+	s.dbLock.Unlock()
+	return
+}
+
+// NonceScope returns what a pubkey's nonces have to be unique within
+func (s *OpencxAuctionServer) NonceScope() (scope match.NonceScope, err error) {
+	s.dbLock.Lock()
+	scope = s.nonceScope
+	s.dbLock.Unlock()
+	return
+}
+
+// SetMaxAuctionPubkeys sets the most distinct pubkeys that can have orders in a single auction, so the size of
+// an auction's batch is bounded. Once an auction has orders from that many pubkeys, orders from any other
+// pubkey are rejected, but the pubkeys already in it can keep placing orders. 0 means no limit, which is the
+// default.
+func (s *OpencxAuctionServer) SetMaxAuctionPubkeys(maxPubkeys int) (err error) {
+	if maxPubkeys < 0 {
+		err = fmt.Errorf("Max pubkeys per auction cannot be negative, got %d", maxPubkeys)
+		return
+	}
+
+	s.dbLock.Lock()
+	s.maxPubkeys = maxPubkeys
+	s.dbLock.Unlock()
+	return
+}
+
+// MaxAssignedNonces is the most nonces a pubkey can be assigned in a single auction
+const MaxAssignedNonces = 64
+
+// nonceCounter is the nonces assigned to a pubkey in an auction. They're handed out one after the other,
+// starting from a random nonce, so count nonces from start have been assigned.
+type nonceCounter struct {
+	start uint16
+	count uint16
+}
+
+// RequestNonce assigns a fresh nonce to pubkey for the current auction. The server remembers the
+// nonces it has assigned, so the same nonce is never given to a pubkey twice in an auction. They're
+// unique across pairs, so they can be used with either nonce scope. A pubkey can be assigned at most
+// MaxAssignedNonces nonces in an auction.
+func (s *OpencxAuctionServer) RequestNonce(pubkey *koblitz.PublicKey) (nonce [2]byte, auctionID [32]byte, err error) {
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for nonce request: %s", err)
+		return
+	}
+
+	// Only used if this is the pubkey's first nonce in the auction, but it's gotten before locking
+	var start [2]byte
+	if _, err = rand.Read(start[:]); err != nil {
+		err = fmt.Errorf("Error getting random nonce: %s", err)
+		return
+	}
+
+	s.nonceMtx.Lock()
+	defer s.nonceMtx.Unlock()
+
+	// Nonces only have to be unique within an auction, so forget the old ones once it's over
+	if s.nonceAuctionID != auctionID {
+		s.assignedNonces = make(map[[33]byte]*nonceCounter)
+		s.nonceAuctionID = auctionID
+	}
+
+	var pubkeyBytes [33]byte
+	copy(pubkeyBytes[:], pubkey.SerializeCompressed())
+
+	assigned, found := s.assignedNonces[pubkeyBytes]
+	if !found {
+		assigned = &nonceCounter{start: binary.BigEndian.Uint16(start[:])}
+		s.assignedNonces[pubkeyBytes] = assigned
+	}
+
+	if assigned.count >= MaxAssignedNonces {
+		err = fmt.Errorf("Error assigning nonce, %x has already been assigned %d nonces in auction %x, the most it can have", pubkeyBytes, assigned.count, auctionID)
+		return
+	}
+
+	// The count is less than 2^16, so the nonces wrap around without repeating
+	binary.BigEndian.PutUint16(nonce[:], assigned.start+assigned.count)
+	assigned.count++
+	return
+}
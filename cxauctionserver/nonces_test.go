@@ -0,0 +1,170 @@
+package cxauctionserver
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestRequestNonceDistinct(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestRequestNonceDistinct: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	seen := make(map[[2]byte]bool)
+	for i := 0; i < MaxAssignedNonces; i++ {
+		var nonce [2]byte
+		if nonce, _, err = s.RequestNonce(privkey.PubKey()); err != nil {
+			t.Errorf("Error requesting nonce %d: %s", i, err)
+			return
+		}
+		if seen[nonce] {
+			t.Errorf("Nonce %x was assigned twice", nonce)
+			return
+		}
+		seen[nonce] = true
+	}
+
+	// Once a pubkey has been assigned as many nonces as it can have, it doesn't get any more
+	if _, _, err = s.RequestNonce(privkey.PubKey()); err == nil {
+		t.Errorf("Pubkey should not be assigned more than %d nonces in an auction", MaxAssignedNonces)
+		return
+	}
+
+	// Other pubkeys still get nonces
+	var otherPrivkey *koblitz.PrivateKey
+	if otherPrivkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	if _, _, err = s.RequestNonce(otherPrivkey.PubKey()); err != nil {
+		t.Errorf("Error requesting nonce for another pubkey: %s", err)
+		return
+	}
+
+	// Pubkeys get nonces again in the next auction
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error closing auction: %s", err)
+		return
+	}
+
+	if _, _, err = s.RequestNonce(privkey.PubKey()); err != nil {
+		t.Errorf("Error requesting nonce in the next auction: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestMaxAuctionPubkeys(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestMaxAuctionPubkeys: %s", err)
+		return
+	}
+
+	if err = s.SetMaxAuctionPubkeys(-1); err == nil {
+		t.Errorf("Setting a negative max pubkeys per auction should fail")
+		return
+	}
+
+	if err = s.SetMaxAuctionPubkeys(2); err != nil {
+		t.Errorf("Error setting max pubkeys per auction: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	var privkeys []*koblitz.PrivateKey
+	for i := 0; i < 3; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+		privkeys = append(privkeys, privkey)
+	}
+
+	placeOrder := func(privkey *koblitz.PrivateKey, nonce byte, auctionID [32]byte) (err error) {
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder("buy", 1000, 2.0, privkey); err != nil {
+			return
+		}
+
+		order.AuctionID = auctionID
+		order.Nonce = [2]byte{0x00, nonce}
+		if err = signTestOrder(order, privkey); err != nil {
+			return
+		}
+
+		err = s.placeSolvedOrder(order, auctionID)
+		return
+	}
+
+	// The first two pubkeys fill up the auction
+	for i, privkey := range privkeys[:2] {
+		if err = placeOrder(privkey, 0, auctionID); err != nil {
+			t.Errorf("Error placing order from pubkey %d: %s", i, err)
+			return
+		}
+	}
+
+	if err = placeOrder(privkeys[2], 0, auctionID); err == nil {
+		t.Errorf("Order from a new pubkey should be rejected once the auction has the most pubkeys it can")
+		return
+	}
+
+	// Pubkeys already in the auction can keep placing orders
+	if err = placeOrder(privkeys[0], 1, auctionID); err != nil {
+		t.Errorf("Pubkey already in the full auction should still be able to place orders: %s", err)
+		return
+	}
+
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&match.Pair{AssetWant: match.BTC, AssetHave: match.VTCTest}, auctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 3 {
+		t.Errorf("Full auction should have 3 orders from its 2 pubkeys, got %d", len(buyOrders))
+		return
+	}
+
+	// The limit is per auction, so the pubkey that was left out can join the next one
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	var newAuctionID [32]byte
+	if newAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting new auction ID: %s", err)
+		return
+	}
+
+	if err = placeOrder(privkeys[2], 0, newAuctionID); err != nil {
+		t.Errorf("Pubkey that was left out of the last auction should be able to place orders in the new one: %s", err)
+		return
+	}
+
+	return
+}
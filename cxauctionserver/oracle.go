@@ -0,0 +1,74 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// SetOracle sets the oracle whose prices clearing prices are checked against. An auction that clears more than
+// maxDeviation away from the oracle price, as a fraction of the oracle price, is voided. A nil oracle turns
+// this off.
+func (s *OpencxAuctionServer) SetOracle(oracle match.Oracle, maxDeviation float64) (err error) {
+	if maxDeviation < 0 || maxDeviation > 1 {
+		err = fmt.Errorf("Max deviation from the oracle price must be between 0 and 1, got %f", maxDeviation)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.oracle = oracle
+	s.oracleDeviation = maxDeviation
+	s.auctionMtx.Unlock()
+
+	if oracle != nil {
+		logging.Infof("Voiding auctions that clear more than %f away from the oracle price", maxDeviation)
+	}
+
+	return
+}
+
+// ReservePrice gets the oracle price for pair, which is the reserve price that clearing prices are
+// checked against
+func (s *OpencxAuctionServer) ReservePrice(pair *match.Pair) (price *big.Rat, err error) {
+	s.auctionMtx.RLock()
+	oracle := s.oracle
+	s.auctionMtx.RUnlock()
+
+	if oracle == nil {
+		err = fmt.Errorf("No oracle set, there are no reserve prices")
+		return
+	}
+
+	if price, err = oracle.Price(*pair); err != nil {
+		err = fmt.Errorf("Error getting reserve price from oracle: %s", err)
+		return
+	}
+
+	return
+}
+
+// oracleBand gets the price band around the oracle price for pair. The band is nil if there is no oracle.
+func (s *OpencxAuctionServer) oracleBand(pair *match.Pair) (band *match.PriceBand, err error) {
+	s.auctionMtx.RLock()
+	oracle := s.oracle
+	maxDeviation := s.oracleDeviation
+	s.auctionMtx.RUnlock()
+
+	if oracle == nil {
+		return
+	}
+
+	var reservePrice *big.Rat
+	if reservePrice, err = s.ReservePrice(pair); err != nil {
+		return
+	}
+
+	if band, err = match.OracleBand(reservePrice, maxDeviation); err != nil {
+		err = fmt.Errorf("Error creating price band from oracle price: %s", err)
+		return
+	}
+
+	return
+}
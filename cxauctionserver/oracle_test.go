@@ -0,0 +1,131 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+// mockOracle gives the same price for every pair, or an error if it's down
+type mockOracle struct {
+	price *big.Rat
+	down  bool
+}
+
+// Price returns the mock price
+func (mo *mockOracle) Price(pair match.Pair) (price *big.Rat, err error) {
+	if mo.down {
+		err = fmt.Errorf("Mock oracle is down")
+		return
+	}
+	price = new(big.Rat).Set(mo.price)
+	return
+}
+
+func TestOracleReservePrice(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestOracleReservePrice: %s", err)
+		return
+	}
+
+	pair := match.Pair{
+		AssetWant: match.BTCReg,
+		AssetHave: match.LTCReg,
+	}
+
+	// Without an oracle there's no reserve price, and any price is fine
+	if _, err = s.ReservePrice(&pair); err == nil {
+		t.Errorf("There should be no reserve price without an oracle")
+		return
+	}
+
+	if err = s.CheckClearingPrice(&pair, 1000.0); err != nil {
+		t.Errorf("Any clearing price should be fine without an oracle: %s", err)
+		return
+	}
+
+	oracle := &mockOracle{price: big.NewRat(2, 1)}
+	if err = s.SetOracle(oracle, 2); err == nil {
+		t.Errorf("Should not be able to set an oracle with a deviation over 1")
+		return
+	}
+
+	if err = s.SetOracle(oracle, 0.05); err != nil {
+		t.Errorf("Error setting oracle: %s", err)
+		return
+	}
+
+	var reservePrice *big.Rat
+	if reservePrice, err = s.ReservePrice(&pair); err != nil {
+		t.Errorf("Error getting reserve price: %s", err)
+		return
+	}
+
+	if reservePrice.Cmp(oracle.price) != 0 {
+		t.Errorf("Reserve price should be the oracle price %s, got %s", oracle.price.RatString(), reservePrice.RatString())
+		return
+	}
+
+	for _, price := range []float64{1.95, 2.0, 2.05} {
+		if err = s.CheckClearingPrice(&pair, price); err != nil {
+			t.Errorf("Clearing price %f should be within 5%% of the oracle price: %s", price, err)
+			return
+		}
+	}
+
+	for _, price := range []float64{1.8, 2.2} {
+		if err = s.CheckClearingPrice(&pair, price); err == nil {
+			t.Errorf("Clearing price %f should be too far from the oracle price and void the auction", price)
+			return
+		}
+	}
+
+	// The band follows the oracle when its price moves
+	oracle.price = big.NewRat(4, 1)
+	if err = s.CheckClearingPrice(&pair, 2.0); err == nil {
+		t.Errorf("Clearing price 2 should be too far from the new oracle price 4")
+		return
+	}
+
+	if err = s.CheckClearingPrice(&pair, 4.1); err != nil {
+		t.Errorf("Clearing price 4.1 should be within 5%% of the new oracle price: %s", err)
+		return
+	}
+
+	// If the oracle is down we can't tell if the price is sane, so the auction is voided
+	oracle.down = true
+	if err = s.CheckClearingPrice(&pair, 4.0); err == nil {
+		t.Errorf("Clearing price should be rejected when the oracle is down")
+		return
+	}
+
+	// Static price bands still apply along with the oracle
+	oracle.down = false
+	if err = s.SetPriceBand(pair, &match.PriceBand{MinPrice: 3.0, MaxPrice: 4.05}); err != nil {
+		t.Errorf("Error setting price band: %s", err)
+		return
+	}
+
+	if err = s.CheckClearingPrice(&pair, 4.1); err == nil {
+		t.Errorf("Clearing price 4.1 is within the oracle band but outside of the static band, it should be rejected")
+		return
+	}
+
+	// Removing the oracle turns the reserve price off
+	if err = s.SetOracle(nil, 0); err != nil {
+		t.Errorf("Error removing oracle: %s", err)
+		return
+	}
+
+	if err = s.CheckClearingPrice(&pair, 3.0); err != nil {
+		t.Errorf("Clearing price in the static band should be fine without an oracle: %s", err)
+		return
+	}
+
+	return
+}
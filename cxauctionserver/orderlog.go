@@ -0,0 +1,401 @@
+package cxauctionserver
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// orderLogSuffix is the extension of order log files, which are named after the hex auction ID
+const orderLogSuffix = ".orders"
+
+const (
+	// loggedPuzzle is a puzzled order placed in the puzzle book of the log's auction
+	loggedPuzzle byte = iota
+	// loggedQueued is an order queued during the log's auction for the next one
+	loggedQueued
+)
+
+// loggedOrder is an order read back from an order log
+type loggedOrder struct {
+	kind        byte
+	priorityFee uint64
+	order       *match.EncryptedAuctionOrder
+}
+
+// orderLog appends the encrypted orders of each open auction to a file in dir before they're accepted, so they
+// aren't lost if fred stops before the auction closes. Each record is a kind byte, the 8 byte priority fee, the
+// 8 byte length of the serialized order, and the serialized order.
+type orderLog struct {
+	dir string
+	mtx *sync.Mutex
+}
+
+// newOrderLog creates an order log in dir, creating dir if it doesn't exist
+func newOrderLog(dir string) (ol *orderLog, err error) {
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		err = fmt.Errorf("Error creating order log directory: %s", err)
+		return
+	}
+
+	ol = &orderLog{
+		dir: dir,
+		mtx: new(sync.Mutex),
+	}
+	return
+}
+
+// path returns the path of the log for auctionID
+func (ol *orderLog) path(auctionID [32]byte) string {
+	return filepath.Join(ol.dir, hex.EncodeToString(auctionID[:])+orderLogSuffix)
+}
+
+// append adds an order to the log for auctionID, and only returns once it's synced to disk
+func (ol *orderLog) append(auctionID [32]byte, kind byte, order *match.EncryptedAuctionOrder, priorityFee uint64) (err error) {
+	var raw []byte
+	if raw, err = order.Serialize(); err != nil {
+		err = fmt.Errorf("Error serializing order for order log: %s", err)
+		return
+	}
+
+	record := make([]byte, 17, 17+len(raw))
+	record[0] = kind
+	binary.BigEndian.PutUint64(record[1:9], priorityFee)
+	binary.BigEndian.PutUint64(record[9:17], uint64(len(raw)))
+	record = append(record, raw...)
+
+	ol.mtx.Lock()
+	defer ol.mtx.Unlock()
+
+	var logFile *os.File
+	if logFile, err = os.OpenFile(ol.path(auctionID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err != nil {
+		err = fmt.Errorf("Error opening order log: %s", err)
+		return
+	}
+	defer logFile.Close()
+
+	if _, err = logFile.Write(record); err != nil {
+		err = fmt.Errorf("Error writing to order log: %s", err)
+		return
+	}
+
+	if err = logFile.Sync(); err != nil {
+		err = fmt.Errorf("Error syncing order log: %s", err)
+		return
+	}
+
+	return
+}
+
+// read returns the orders in the log for auctionID. If fred stopped in the middle of writing the last one,
+// it's ignored, since it was never accepted.
+func (ol *orderLog) read(auctionID [32]byte) (orders []*loggedOrder, err error) {
+	ol.mtx.Lock()
+	defer ol.mtx.Unlock()
+
+	var logFile *os.File
+	if logFile, err = os.Open(ol.path(auctionID)); err != nil {
+		err = fmt.Errorf("Error opening order log: %s", err)
+		return
+	}
+	defer logFile.Close()
+
+	reader := bufio.NewReader(logFile)
+	header := make([]byte, 17)
+	for {
+		if _, err = io.ReadFull(reader, header); err == io.EOF {
+			err = nil
+			return
+		} else if err != nil {
+			break
+		}
+
+		raw := make([]byte, binary.BigEndian.Uint64(header[9:17]))
+		if _, err = io.ReadFull(reader, raw); err != nil {
+			break
+		}
+
+		logged := &loggedOrder{
+			kind:        header[0],
+			priorityFee: binary.BigEndian.Uint64(header[1:9]),
+			order:       new(match.EncryptedAuctionOrder),
+		}
+		if err = logged.order.Deserialize(raw); err != nil {
+			err = fmt.Errorf("Error deserializing order %d in order log: %s", len(orders), err)
+			return
+		}
+		orders = append(orders, logged)
+	}
+
+	if err == io.ErrUnexpectedEOF {
+		logging.Errorf("Order log for auction %x ends in the middle of an order, ignoring it", auctionID)
+		err = nil
+		return
+	}
+
+	err = fmt.Errorf("Error reading order log: %s", err)
+	return
+}
+
+// remove deletes the log for auctionID, once its orders are committed to
+func (ol *orderLog) remove(auctionID [32]byte) (err error) {
+	ol.mtx.Lock()
+	defer ol.mtx.Unlock()
+
+	if err = os.Remove(ol.path(auctionID)); err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("Error removing order log: %s", err)
+		return
+	}
+
+	err = nil
+	return
+}
+
+// auctions returns the auctions that have a log, from the least to the most recently written to
+func (ol *orderLog) auctions() (auctionIDs [][32]byte, err error) {
+	ol.mtx.Lock()
+	defer ol.mtx.Unlock()
+
+	var files []os.FileInfo
+	if files, err = ioutil.ReadDir(ol.dir); err != nil {
+		err = fmt.Errorf("Error reading order log directory: %s", err)
+		return
+	}
+
+	modTimes := make(map[[32]byte]time.Time)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, orderLogSuffix) {
+			continue
+		}
+
+		var idBytes []byte
+		if idBytes, err = hex.DecodeString(strings.TrimSuffix(name, orderLogSuffix)); err != nil || len(idBytes) != 32 {
+			logging.Errorf("Ignoring file %s in order log directory, it isn't named after an auction", name)
+			err = nil
+			continue
+		}
+
+		var auctionID [32]byte
+		copy(auctionID[:], idBytes)
+		auctionIDs = append(auctionIDs, auctionID)
+		modTimes[auctionID] = file.ModTime()
+	}
+
+	sort.SliceStable(auctionIDs, func(i, j int) bool {
+		return modTimes[auctionIDs[i]].Before(modTimes[auctionIDs[j]])
+	})
+	return
+}
+
+// SetOrderLog makes the server log every encrypted order it accepts to a file per auction in dir, so the
+// orders of the open auction can be replayed with ReplayOrderLog if fred restarts before it closes.
+func (s *OpencxAuctionServer) SetOrderLog(dir string) (err error) {
+	var ol *orderLog
+	if ol, err = newOrderLog(dir); err != nil {
+		return
+	}
+
+	s.dbLock.Lock()
+	s.orderLog = ol
+	s.dbLock.Unlock()
+	return
+}
+
+// logOrder writes an order to the log of auctionID if there's an order log. The caller should be holding dbLock.
+func (s *OpencxAuctionServer) logOrder(auctionID [32]byte, kind byte, order *match.EncryptedAuctionOrder, priorityFee uint64) (err error) {
+	if s.orderLog == nil {
+		return
+	}
+
+	if err = s.orderLog.append(auctionID, kind, order, priorityFee); err != nil {
+		err = fmt.Errorf("Error logging order for auction %x: %s", auctionID, err)
+		return
+	}
+
+	return
+}
+
+// forgetOrderLog removes the log of an auction whose orders have been committed to, if there's an order log.
+// The caller should be holding dbLock.
+func (s *OpencxAuctionServer) forgetOrderLog(auctionID [32]byte) {
+	if s.orderLog == nil {
+		return
+	}
+
+	if err := s.orderLog.remove(auctionID); err != nil {
+		logging.Errorf("Error removing order log of committed auction %x: %s", auctionID, err)
+	}
+	return
+}
+
+// ReplayOrderLog resumes the auction that was open when fred stopped, from the order log set with SetOrderLog.
+// The puzzled orders in its log are put back in its puzzle book and solved again, and the orders queued for
+// the next auction are queued again. The auction starts over with a new commitment chain. Orders already in
+// the order books of pairs are remembered, so they aren't placed twice if the db kept them. If logs are left
+// for more than one auction, the most recent one is resumed and the rest are left alone.
+func (s *OpencxAuctionServer) ReplayOrderLog(pairs []*match.Pair) (replayed int, err error) {
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	if s.orderLog == nil {
+		err = fmt.Errorf("No order log set, cannot replay orders")
+		return
+	}
+
+	var auctionIDs [][32]byte
+	if auctionIDs, err = s.orderLog.auctions(); err != nil {
+		return
+	}
+
+	if len(auctionIDs) == 0 {
+		return
+	}
+
+	resumed := auctionIDs[len(auctionIDs)-1]
+	for _, stale := range auctionIDs[:len(auctionIDs)-1] {
+		logging.Errorf("Order log for auction %x was left before the last open auction, not replaying it", stale)
+	}
+
+	var logged []*loggedOrder
+	if logged, err = s.orderLog.read(resumed); err != nil {
+		err = fmt.Errorf("Error reading order log of auction %x: %s", resumed, err)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.auctionID = resumed
+	s.auctionStart = time.Now()
+	s.auctionMtx.Unlock()
+
+	s.commitMtx.Lock()
+	s.commitment = match.NewCommitmentChain(resumed)
+	s.published = nil
+	s.commitMtx.Unlock()
+
+	s.solves.newAuction(resumed)
+
+	// The db might have kept the puzzles and orders, so don't put them in twice
+	var stored []*match.EncryptedAuctionOrder
+	if stored, err = s.OpencxDB.ViewAuctionPuzzleBook(resumed); err != nil {
+		err = fmt.Errorf("Error getting puzzle book of resumed auction: %s", err)
+		return
+	}
+
+	storedHashes := make(map[[32]byte]bool)
+	for _, puzzle := range stored {
+		var hash [32]byte
+		if hash, err = hashCiphertext(puzzle); err != nil {
+			return
+		}
+		storedHashes[hash] = true
+	}
+
+	if err = s.rememberPlacedOrders(resumed, pairs); err != nil {
+		return
+	}
+
+	for _, entry := range logged {
+		order := entry.order
+
+		// Duplicates were dropped before they were logged, so this is only the cache catching up
+		if _, err = s.dedupCiphertext(order); err != nil {
+			logging.Errorf("Skipping logged order: %s", err)
+			err = nil
+			continue
+		}
+
+		if entry.kind == loggedQueued {
+			s.queuedMtx.Lock()
+			s.queuedOrders[resumed] = append(s.queuedOrders[resumed], &solveItem{
+				order:       order,
+				priorityFee: entry.priorityFee,
+			})
+			s.queuedMtx.Unlock()
+			replayed++
+			continue
+		}
+
+		var hash [32]byte
+		if hash, err = hashCiphertext(order); err != nil {
+			return
+		}
+
+		if queueErr := s.queueSolve(order, entry.priorityFee, func() (err error) {
+			if !storedHashes[hash] {
+				err = s.placePuzzle(order)
+				return
+			}
+
+			s.commitMtx.Lock()
+			err = s.commitment.Add(order)
+			s.commitMtx.Unlock()
+			return
+		}); queueErr != nil {
+			logging.Errorf("Error replaying logged order: %s", queueErr)
+			continue
+		}
+		replayed++
+	}
+
+	logging.Infof("Resumed auction %x with %d orders from the order log", resumed, replayed)
+	return
+}
+
+// rememberPlacedOrders marks the orders in the order books of auctionID as placed, so solving them again
+// doesn't place them twice, and their pubkeys count towards the auction's pubkey limit. The caller should be holding dbLock.
+func (s *OpencxAuctionServer) rememberPlacedOrders(auctionID [32]byte, pairs []*match.Pair) (err error) {
+	placed, found := s.placedOrders[auctionID]
+	if !found {
+		placed = make(map[[32]byte]bool)
+		s.placedOrders[auctionID] = placed
+	}
+
+	nonces, found := s.placedNonces[auctionID]
+	if !found {
+		nonces = match.NewScopedNonceSet(s.nonceScope)
+		s.placedNonces[auctionID] = nonces
+	}
+
+	pubkeys, found := s.placedPubkeys[auctionID]
+	if !found {
+		pubkeys = make(map[[33]byte]bool)
+		s.placedPubkeys[auctionID] = pubkeys
+	}
+
+	for _, pair := range pairs {
+		var sellOrders []*match.AuctionOrder
+		var buyOrders []*match.AuctionOrder
+		if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(pair, auctionID); err != nil {
+			err = fmt.Errorf("Error getting %s order book of resumed auction: %s", pair.String(), err)
+			return
+		}
+
+		for _, order := range append(sellOrders, buyOrders...) {
+			var orderID [32]byte
+			if orderID, err = order.OrderID(); err != nil {
+				err = fmt.Errorf("Error getting ID of placed order: %s", err)
+				return
+			}
+			placed[orderID] = true
+			pubkeys[order.Pubkey] = true
+			if addErr := nonces.Add(order); addErr != nil {
+				logging.Errorf("Order book of resumed auction has orders with reused nonces: %s", addErr)
+			}
+		}
+	}
+
+	return
+}
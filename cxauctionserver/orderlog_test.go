@@ -0,0 +1,193 @@
+package cxauctionserver
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestOrderLogReplay(t *testing.T) {
+	var err error
+
+	var dir string
+	if dir, err = ioutil.TempDir("", "orderlog"); err != nil {
+		t.Errorf("Error creating order log directory: %s", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestOrderLogReplay: %s", err)
+		return
+	}
+
+	if err = s.SetOrderLog(dir); err != nil {
+		t.Errorf("Error setting order log: %s", err)
+		return
+	}
+
+	if err = s.SetNextAuctionWindow(2 * time.Hour); err != nil {
+		t.Errorf("Error setting next auction window: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	numPuzzles := 3
+	var order *match.AuctionOrder
+	var encOrder *match.EncryptedAuctionOrder
+	for i := 0; i <= numPuzzles; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+
+		if order, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+			t.Errorf("Error creating puzzled order: %s", err)
+			return
+		}
+
+		// The last one is for the next auction
+		if i == numPuzzles {
+			err = s.QueueNextAuctionOrder(encOrder)
+		} else {
+			err = s.PlacePuzzledOrder(encOrder)
+		}
+		if err != nil {
+			t.Errorf("Error submitting order %d: %s", i, err)
+			return
+		}
+	}
+
+	// Stopping in the middle of writing an order shouldn't lose the ones before it
+	var logFile *os.File
+	if logFile, err = os.OpenFile(s.orderLog.path(auctionID), os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		t.Errorf("Error opening order log: %s", err)
+		return
+	}
+	if _, err = logFile.Write([]byte{loggedPuzzle, 0x00, 0x01}); err != nil {
+		t.Errorf("Error writing torn order to order log: %s", err)
+		return
+	}
+	logFile.Close()
+
+	// Restart with an empty db, so everything comes from the log
+	var restarted *OpencxAuctionServer
+	if restarted, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init restarted test server: %s", err)
+		return
+	}
+
+	if err = restarted.SetOrderLog(dir); err != nil {
+		t.Errorf("Error setting order log of restarted server: %s", err)
+		return
+	}
+
+	var pairs []*match.Pair
+	if pairs, err = match.GenerateAssetPairs(testCoins); err != nil {
+		t.Errorf("Error generating pairs: %s", err)
+		return
+	}
+
+	var replayed int
+	if replayed, err = restarted.ReplayOrderLog(pairs); err != nil {
+		t.Errorf("Error replaying order log: %s", err)
+		return
+	}
+
+	if replayed != numPuzzles+1 {
+		t.Errorf("Replayed %d orders, expected %d", replayed, numPuzzles+1)
+		return
+	}
+
+	var resumedID [32]byte
+	if resumedID, err = restarted.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting resumed auction ID: %s", err)
+		return
+	}
+
+	if resumedID != auctionID {
+		t.Errorf("Restarted server should resume auction %x, got %x", auctionID, resumedID)
+		return
+	}
+
+	var puzzles []*match.EncryptedAuctionOrder
+	if puzzles, err = restarted.OpencxDB.ViewAuctionPuzzleBook(auctionID); err != nil {
+		t.Errorf("Error viewing puzzle book: %s", err)
+		return
+	}
+
+	if len(puzzles) != numPuzzles {
+		t.Errorf("Resumed auction should have %d puzzles, got %d", numPuzzles, len(puzzles))
+		return
+	}
+
+	restarted.queuedMtx.Lock()
+	numQueued := len(restarted.queuedOrders[auctionID])
+	restarted.queuedMtx.Unlock()
+	if numQueued != 1 {
+		t.Errorf("Resumed auction should have 1 order queued for the next one, got %d", numQueued)
+		return
+	}
+
+	// The puzzles are solved again and placed
+	deadline := time.Now().Add(time.Minute)
+	for {
+		_, buyOrders, _ := restarted.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, auctionID)
+		if len(buyOrders) == numPuzzles {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Errorf("Replayed puzzles were never placed in the order book, got %d of %d", len(buyOrders), numPuzzles)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Once the auction closes its log is done, and the queued order is logged in the new auction
+	if err = restarted.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	var newAuctionID [32]byte
+	if newAuctionID, err = restarted.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting new auction ID: %s", err)
+		return
+	}
+
+	var logged [][32]byte
+	if logged, err = restarted.orderLog.auctions(); err != nil {
+		t.Errorf("Error listing order logs: %s", err)
+		return
+	}
+
+	if len(logged) != 1 || logged[0] != newAuctionID {
+		t.Errorf("Only the new auction should have an order log, got %d logs", len(logged))
+		return
+	}
+
+	var newOrders []*loggedOrder
+	if newOrders, err = restarted.orderLog.read(newAuctionID); err != nil {
+		t.Errorf("Error reading new auction order log: %s", err)
+		return
+	}
+
+	if len(newOrders) != 1 || newOrders[0].kind != loggedPuzzle {
+		t.Errorf("New auction log should have the released queued order as a puzzle, got %d orders", len(newOrders))
+		return
+	}
+
+	return
+}
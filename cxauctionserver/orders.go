@@ -2,63 +2,221 @@ package cxauctionserver
 
 import (
 	"fmt"
+	"time"
 
-	"github.com/btcsuite/golangcrypto/sha3"
 	"github.com/mit-dci/lit/crypto/koblitz"
-	"github.com/mit-dci/opencx/crypto/rsw"
-	"github.com/mit-dci/opencx/crypto/timelockencoders"
 	"github.com/mit-dci/opencx/logging"
 	"github.com/mit-dci/opencx/match"
 )
 
 // PlacePuzzledOrder places a timelock encrypted order. It also starts to decrypt the order in a goroutine.
 func (s *OpencxAuctionServer) PlacePuzzledOrder(order *match.EncryptedAuctionOrder) (err error) {
+	return s.PlacePuzzledOrderWithFee(order, 0)
+}
+
+// PlacePuzzledOrderWithFee places a timelock encrypted order, like PlacePuzzledOrder. priorityFee is the fee the
+// submitter says they'll pay to have the order solved sooner. The fee is hidden in the puzzle until it's solved,
+// so this is only a hint for what to solve first when the exchange is solving by fee.
+func (s *OpencxAuctionServer) PlacePuzzledOrderWithFee(order *match.EncryptedAuctionOrder, priorityFee uint64) (err error) {
 
 	logging.Infof("Got a new puzzle for auction %x", order.IntendedAuction)
 
-	// Placing an auction puzzle is how the exchange will then recall and commit to a set of puzzles.
+	// count the rejection after every lock is released
+	var rejection RejectionReason
+	defer func() {
+		if rejection != "" {
+			s.countRejection(rejection)
+		} else if err == nil {
+			s.rejections.accept()
+		}
+	}()
+
+	var mode MatchingMode
+	if mode, err = s.MatchingMode(); err != nil {
+		err = fmt.Errorf("Error getting matching mode for puzzled order: \n%s", err)
+		return
+	}
+
+	if mode != BatchMatching {
+		rejection = RejectedWrongMode
+		err = fmt.Errorf("Exchange is in %s matching mode, puzzled orders are only accepted in batch mode", mode)
+		return
+	}
+
+	// Reject orders that could never be decrypted before they get committed to
+	if err = order.VerifyPuzzle(); err != nil {
+		rejection = RejectedBadPuzzle
+		err = fmt.Errorf("Error verifying puzzled order: \n%s", err)
+		return
+	}
+
+	// Hold the db lock so the auction can't change between checking the order is for it and placing it
 	s.dbLock.Lock()
-	if err = s.OpencxDB.PlaceAuctionPuzzle(order); err != nil {
-		s.dbLock.Unlock()
-		err = fmt.Errorf("Error placing puzzled order: \n%s", err)
+	defer s.dbLock.Unlock()
+
+	// Orders for another auction, or with puzzles that don't use the auction's params, are never committed to
+	if rejection, err = s.validateEncryptedOrder(order); err != nil {
+		err = fmt.Errorf("Error validating puzzled order: \n%s", err)
 		return
 	}
-	s.dbLock.Unlock()
 
-	if err = s.validateEncryptedOrder(order); err != nil {
-		logging.Errorf("Error validating order: %s", err)
+	// Exact duplicates are dropped before they're stored, so the same puzzle isn't solved twice
+	var forgetCiphertext func()
+	if forgetCiphertext, err = s.dedupCiphertext(order); err != nil {
+		if _, duplicate := err.(*duplicateCiphertextError); duplicate {
+			rejection = RejectedDuplicate
+		}
+		err = fmt.Errorf("Error checking for duplicate puzzled order: \n%s", err)
+		return
 	}
 
-	go s.solveOrderIntoResChan(order)
+	// Placing an auction puzzle is how the exchange will then recall and commit to a set of puzzles. It's only
+	// placed if there's room to solve it, so the exchange doesn't commit to orders it won't solve.
+	if err = s.queueSolve(order, priorityFee, func() (err error) {
+		// Log it first, so it isn't lost if we stop before the auction closes. Pair auctions aren't replayed.
+		if !s.isOpenPairAuction(order.IntendedAuction) {
+			var currentAuctionID [32]byte
+			if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+				err = fmt.Errorf("Error getting current auction ID to log puzzled order: \n%s", err)
+				return
+			}
+			if err = s.logOrder(currentAuctionID, loggedPuzzle, order, priorityFee); err != nil {
+				return
+			}
+		}
+		if err = s.placePuzzle(order); err != nil {
+			err = fmt.Errorf("Error placing puzzled order: \n%s", err)
+			return
+		}
+		return
+	}); err != nil {
+		forgetCiphertext()
+		if _, full := err.(*solveQueueFullError); full {
+			rejection = RejectedRateLimited
+		}
+		err = fmt.Errorf("Error queueing puzzled order to be solved: \n%s", err)
+		return
+	}
 
 	return
 }
 
 // solveOrderIntoResChan solves the order puzzle and puts it in to the server's order channel.
 func (s *OpencxAuctionServer) solveOrderIntoResChan(eOrder *match.EncryptedAuctionOrder) {
+	s.orderChannel <- solveOrder(eOrder)
+	return
+}
+
+// solveOrder solves the order puzzle. If solving panics, the panic is put in the result as an error so one bad
+// puzzle doesn't take down a solve worker.
+func solveOrder(eOrder *match.EncryptedAuctionOrder) (result *match.OrderPuzzleResult) {
 	var err error
-	result := new(match.OrderPuzzleResult)
+	result = new(match.OrderPuzzleResult)
 	result.Encrypted = eOrder
 
+	defer func() {
+		if r := recover(); r != nil {
+			result.Auction = nil
+			result.Err = match.NewPuzzleResultError(match.PuzzleDecryptFailed, "Panic solving puzzle for auction order server solve: %v", r)
+		}
+	}()
+
 	var orderBytes []byte
-	if orderBytes, err = timelockencoders.SolvePuzzleRC5(eOrder.OrderCiphertext, eOrder.OrderPuzzle); err != nil {
-		result.Err = fmt.Errorf("Error solving RC5 puzzle for auction order server solve: %s", err)
-		s.orderChannel <- result
+	if result.Key, result.Solution, orderBytes, err = eOrder.SolveWithProof(); err != nil {
+		result.Err = match.NewPuzzleResultError(match.PuzzleDecryptFailed, "Error solving puzzle for auction order server solve: %s", err)
 		return
 	}
 
 	result.Auction = new(match.AuctionOrder)
 	if err = result.Auction.Deserialize(orderBytes); err != nil {
-		result.Err = fmt.Errorf("Error deserializing order from puzzle for server: %s", err)
-		s.orderChannel <- result
+		result.Err = match.NewPuzzleResultError(match.PuzzleDeserializeFailed, "Error deserializing order from puzzle for server: %s", err)
+		return
+	}
+
+	return
+}
+
+// CancelAllOrders cancels all of the pending orders placed by pubkey in the auction with the given auction ID, which must be
+// the current auction. Orders that are still being solved can't be attributed to a pubkey yet, so they aren't cancelled.
+func (s *OpencxAuctionServer) CancelAllOrders(pubkey *koblitz.PublicKey, auctionID [32]byte) (cancelled []*match.AuctionOrder, err error) {
+
+	// Lock the db so a new auction doesn't get created while we cancel
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction id for cancel: %s", err)
+		return
+	}
+
+	if currentAuctionID != auctionID {
+		err = fmt.Errorf("Can only cancel orders in the current auction %x, not %x", currentAuctionID, auctionID)
+		return
+	}
+
+	if cancelled, err = s.OpencxDB.CancelAuctionOrders(pubkey, auctionID); err != nil {
+		err = fmt.Errorf("Error cancelling auction orders: %s", err)
+		return
+	}
+
+	logging.Infof("Cancelled %d orders placed by %x in auction %x", len(cancelled), pubkey.SerializeCompressed(), auctionID)
+
+	return
+}
+
+// CancelEverything cancels all of the pending orders placed by pubkey, wherever they are going. Orders in the
+// current auction, which must have auctionID, are removed. Orders that are still being solved, including the
+// ones queued for the next auction, can't be attributed to a pubkey yet, so instead any of them that pubkey
+// signed for the current auction are dropped once they're solved.
+func (s *OpencxAuctionServer) CancelEverything(pubkey *koblitz.PublicKey, auctionID [32]byte) (cancelled []*match.AuctionOrder, err error) {
+
+	// Lock the db so a new auction doesn't get created, and no orders get placed, while we cancel
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction id for cancel everything: %s", err)
 		return
 	}
 
-	s.orderChannel <- result
+	if currentAuctionID != auctionID {
+		err = fmt.Errorf("Can only cancel everything during the current auction %x, not %x", currentAuctionID, auctionID)
+		return
+	}
+
+	var pubkeyBytes [33]byte
+	copy(pubkeyBytes[:], pubkey.SerializeCompressed())
+
+	cancelledSigners, found := s.cancelledEverything[auctionID]
+	if !found {
+		cancelledSigners = make(map[[33]byte]bool)
+		s.cancelledEverything[auctionID] = cancelledSigners
+	}
+	cancelledSigners[pubkeyBytes] = true
+
+	if cancelled, err = s.OpencxDB.CancelAuctionOrders(pubkey, auctionID); err != nil {
+		err = fmt.Errorf("Error cancelling auction orders: %s", err)
+		return
+	}
+
+	logging.Infof("Cancelled everything placed by %x, %d orders in auction %x", pubkeyBytes, len(cancelled), auctionID)
 
 	return
 }
 
+// forgetCancelledEverything forgets who cancelled everything in every auction but auctionID. Orders signed for
+// auctionID can still be solved into the auction after it, but orders for auctions before it can't be placed
+// anymore. The caller should be holding dbLock.
+func (s *OpencxAuctionServer) forgetCancelledEverything(auctionID [32]byte) {
+	for cancelledIn := range s.cancelledEverything {
+		if cancelledIn != auctionID {
+			delete(s.cancelledEverything, cancelledIn)
+		}
+	}
+}
+
 // CommitOrdersNewAuction commits to a set of decypted orders and changes the auction ID.
 // TODO: figure out how to broadcast these, and where to store them, if we need to store them
 func (s *OpencxAuctionServer) CommitOrdersNewAuction() (err error) {
@@ -74,7 +232,7 @@ func (s *OpencxAuctionServer) CommitOrdersNewAuction() (err error) {
 		return
 	}
 
-	// Then get the puzzles
+	// Then get the puzzles, to make sure the commitment has all of them
 	var puzzles []*match.EncryptedAuctionOrder
 	if puzzles, err = s.OpencxDB.ViewAuctionPuzzleBook(auctionID); err != nil {
 		s.dbLock.Unlock()
@@ -82,29 +240,68 @@ func (s *OpencxAuctionServer) CommitOrdersNewAuction() (err error) {
 		return
 	}
 
-	sha3 := sha3.New256()
-	// Add the current auction ID to be hashed
-	sha3.Write(auctionID[:])
-	// Then find the hash of the orders + the previous hash
-	for _, pz := range puzzles {
-		var pzRaw []byte
-		if pzRaw, err = pz.Serialize(); err != nil {
-			err = fmt.Errorf("Error serializing puzzle for commitment: %s", err)
-			return
-		}
-		sha3.Write(pzRaw)
+	// Set the new auction ID to the closing commitment to the puzzles. Clients that were given interim
+	// commitments can check that this extends them. TODO: figure out if signing the puzzles instead is
+	// a good idea, and if the dependence on the previous commitment is a good idea.
+	auctionRoot, newAuctionID := s.closeCommitment()
+	if auctionRoot.NumPuzzles != uint64(len(puzzles)) {
+		logging.Errorf("Commitment to auction %x has %d puzzles but the puzzle book has %d", auctionID, auctionRoot.NumPuzzles, len(puzzles))
+	}
+
+	// Keep the root so watchdogs can get it after the auction is gone
+	if err = s.OpencxDB.PlaceAuctionRoot(auctionRoot); err != nil {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Error storing root of auction %x: %s", auctionID, err)
+		return
+	}
+
+	var auctionTime uint64
+	if auctionTime, err = s.CurrentAuctionTime(); err != nil {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Error getting auction time for new puzzle params: %s", err)
+		return
 	}
 
-	// Set the new auction ID to the hash of the orders. TODO: figure out if signing the puzzles
-	// instead is a good idea, and if the dependence on the previous commitment is a good idea.
-	copy(s.auctionID[:], sha3.Sum(nil))
+	// Every auction gets new puzzle params, so solving one auction's puzzles doesn't help with the next
+	var newPuzzleParams *match.PuzzleParams
+	if newPuzzleParams, err = match.NewPuzzleParams(auctionTime, match.DefaultModulusBits); err != nil {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Error creating puzzle params for new auction: %s", err)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.auctionID = newAuctionID
+	s.auctionStart = time.Now()
+	s.puzzleParams = newPuzzleParams
+	s.auctionMtx.Unlock()
 
 	var height uint64
-	if height, err = s.OpencxDB.NewAuction(s.auctionID); err != nil {
+	if height, err = s.OpencxDB.NewAuction(newAuctionID); err != nil {
+		s.dbLock.Unlock()
 		err = fmt.Errorf("Error updating auction in DB while committing orders and creating new auction: %s", err)
 		return
 	}
 
+	// Keys of the closed auction are revealed once its orders are solved, or the reveal grace period is over
+	s.markAuctionClosed(auctionID)
+
+	// Closing the auction starts solving its orders if they were being held
+	s.solves.newAuction(newAuctionID)
+
+	// The orders in the auction that just closed are still being solved, so only the ones before it are forgotten
+	s.forgetPlacedOrders(auctionID)
+	s.forgetCancelledEverything(auctionID)
+
+	if err = s.releaseQueuedOrders(auctionID, newAuctionID); err != nil {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Error releasing queued orders into new auction: %s", err)
+		return
+	}
+
+	// The puzzles are committed to and the queued orders are logged in the new auction, so the log is done
+	s.forgetOrderLog(auctionID)
+
 	// Unlock!
 	s.dbLock.Unlock()
 
@@ -113,18 +310,33 @@ func (s *OpencxAuctionServer) CommitOrdersNewAuction() (err error) {
 	return
 }
 
-// validateOrder is how the server checks that an order is valid, and checks out with its corresponding encrypted order
-func (s *OpencxAuctionServer) validateEncryptedOrder(order *match.EncryptedAuctionOrder) (err error) {
+// validateEncryptedOrder checks that an encrypted order is for the current auction, or the current auction of a
+// pair, and that its puzzle uses that auction's puzzle params. If it's invalid, rejection is why.
+func (s *OpencxAuctionServer) validateEncryptedOrder(order *match.EncryptedAuctionOrder) (rejection RejectionReason, err error) {
 
-	var rswPuzzle *rsw.PuzzleRSW
-	var ok bool
-	if rswPuzzle, ok = order.OrderPuzzle.(*rsw.PuzzleRSW); !ok {
-		err = fmt.Errorf("Puzzle could not be converted to RSW puzzle, invalid encrypted order")
+	var auctionID [32]byte
+	var params match.PuzzleParams
+	if auctionID, params, err = s.CurrentPuzzleParams(); err != nil {
+		err = fmt.Errorf("Error getting puzzle params to validate encrypted order: %s", err)
 		return
 	}
 
-	if uint64(rswPuzzle.T.Int64()) != s.t {
-		err = fmt.Errorf("The time to solve the puzzle is not correct, invalid encrypted order")
+	// Orders for pairs that run their own auctions use the puzzle params of the pair's auction
+	s.pairMtx.Lock()
+	if auction, pairAuction := s.openPairAuction(order.IntendedAuction); pairAuction {
+		auctionID, params = auction.auctionID, *auction.puzzleParams
+	}
+	s.pairMtx.Unlock()
+
+	if order.IntendedAuction != auctionID {
+		rejection = RejectedWrongAuction
+		err = fmt.Errorf("Order is for auction %x, not the current auction %x or the current auction of a pair, invalid encrypted order", order.IntendedAuction, auctionID)
+		return
+	}
+
+	if err = params.CheckPuzzle(order.OrderPuzzle); err != nil {
+		rejection = RejectedBadPuzzle
+		err = fmt.Errorf("Puzzle does not use the auction's puzzle params, invalid encrypted order: %s", err)
 		return
 	}
 
@@ -133,39 +345,51 @@ func (s *OpencxAuctionServer) validateEncryptedOrder(order *match.EncryptedAucti
 
 // validateOrder is how the server checks that an order is valid, and checks out with its corresponding encrypted order
 func (s *OpencxAuctionServer) validateOrder(decryptedOrder *match.AuctionOrder, encryptedOrder *match.EncryptedAuctionOrder) (err error) {
+	if err = s.validateOrderFields(decryptedOrder, encryptedOrder); err != nil {
+		return
+	}
 
-	logging.Infof("Validating order by pubkey %x", decryptedOrder.Pubkey)
-
-	if _, err = decryptedOrder.Price(); err != nil {
-		err = fmt.Errorf("Orders with an indeterminable price are invalid: %s", err)
+	var valid bool
+	if valid, err = decryptedOrder.VerifySignature(); err != nil {
+		err = fmt.Errorf("Error verifying order signature: %s", err)
 		return
 	}
 
-	if !decryptedOrder.IsBuySide() && !decryptedOrder.IsSellSide() {
-		err = fmt.Errorf("Orders that aren't buy or sell side are invalid: %s", err)
+	if !valid {
+		err = fmt.Errorf("Order signature was not made by pubkey %x", decryptedOrder.Pubkey)
 		return
 	}
 
-	// We could use pub key hashes here but there might not be any reason for it
-	var orderPublicKey *koblitz.PublicKey
-	if orderPublicKey, err = koblitz.ParsePubKey(decryptedOrder.Pubkey[:], koblitz.S256()); err != nil {
-		err = fmt.Errorf("Orders with a public key that cannot be parsed are invalid: %s", err)
+	return
+}
+
+// validateOrderFields does every check in validateOrder except for verifying the signature, so signatures
+// can be verified in batches.
+func (s *OpencxAuctionServer) validateOrderFields(decryptedOrder *match.AuctionOrder, encryptedOrder *match.EncryptedAuctionOrder) (err error) {
+
+	logging.Infof("Validating order by pubkey %x", decryptedOrder.Pubkey)
+
+	if err = decryptedOrder.Validate(); err != nil {
+		err = fmt.Errorf("Malformed order is invalid: %s", err)
 		return
 	}
 
-	// e = h(asset)
-	sha3 := sha3.New256()
-	sha3.Write(decryptedOrder.SerializeSignable())
-	e := sha3.Sum(nil)
+	var network match.NetworkMagic
+	if network, err = s.Network(); err != nil {
+		err = fmt.Errorf("Error getting network to validate order: %s", err)
+		return
+	}
 
-	var recoveredPublickey *koblitz.PublicKey
-	if recoveredPublickey, _, err = koblitz.RecoverCompact(koblitz.S256(), decryptedOrder.Signature, e); err != nil {
-		err = fmt.Errorf("Orders whose signature cannot be verified with pubkey recovery are invalid: %s", err)
+	if decryptedOrder.Network != network {
+		err = fmt.Errorf("Order was signed for network %x but this exchange is on network %x", decryptedOrder.Network, network)
 		return
 	}
 
-	if !recoveredPublickey.IsEqual(orderPublicKey) {
-		err = fmt.Errorf("Recovered public key %x does not equal to pubkey %x in order", recoveredPublickey.SerializeCompressed(), orderPublicKey.SerializeCompressed())
+	s.auctionMtx.RLock()
+	maxRatio := s.maxAmountRatio
+	s.auctionMtx.RUnlock()
+	if err = decryptedOrder.CheckAmountRatio(maxRatio); err != nil {
+		err = fmt.Errorf("Order has an absurd price: %s", err)
 		return
 	}
 
@@ -1,9 +1,12 @@
 package cxauctionserver
 
 import (
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/crypto/timelockencoders"
 	"github.com/mit-dci/opencx/match"
 )
 
@@ -16,6 +19,7 @@ var (
 		AmountWant: 100000,
 		AmountHave: 10000,
 		Side:       "buy",
+		Network:    match.TestnetMagic,
 		TradingPair: match.Pair{
 			AssetWant: match.Asset(6),
 			AssetHave: match.Asset(8),
@@ -54,3 +58,274 @@ func TestMemPlacePuzzledOrder(t *testing.T) {
 
 	return
 }
+
+// placeTestOrder places an unencrypted order in the server's db for the pubkey and auction
+func placeTestOrder(s *OpencxAuctionServer, pubkey *koblitz.PublicKey, auctionID [32]byte) (err error) {
+	order := &match.AuctionOrder{
+		AuctionID:   auctionID,
+		AmountWant:  100000,
+		AmountHave:  10000,
+		Side:        "buy",
+		TradingPair: testAuctionOrder.TradingPair,
+	}
+	copy(order.Pubkey[:], pubkey.SerializeCompressed())
+	err = s.OpencxDB.PlaceAuctionOrder(order)
+	return
+}
+
+func TestCancelAllOrders(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestCancelAllOrders: %s", err)
+		return
+	}
+
+	var callerKey, otherKey *koblitz.PrivateKey
+	if callerKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating caller key: %s", err)
+		return
+	}
+	if otherKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating other key: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction id: %s", err)
+		return
+	}
+
+	// The caller has orders in this auction and an old one, the other pubkey only has orders in this one
+	oldAuctionID := [32]byte{0xde, 0xad, 0xbe, 0xef}
+	for _, placement := range []struct {
+		key       *koblitz.PrivateKey
+		auctionID [32]byte
+	}{
+		{callerKey, auctionID},
+		{callerKey, auctionID},
+		{callerKey, oldAuctionID},
+		{otherKey, auctionID},
+	} {
+		if err = placeTestOrder(s, placement.key.PubKey(), placement.auctionID); err != nil {
+			t.Errorf("Error placing test order: %s", err)
+			return
+		}
+	}
+
+	var cancelled []*match.AuctionOrder
+	if cancelled, err = s.CancelAllOrders(callerKey.PubKey(), auctionID); err != nil {
+		t.Errorf("Error cancelling orders: %s", err)
+		return
+	}
+
+	if len(cancelled) != 2 {
+		t.Errorf("Should have cancelled 2 orders, instead cancelled %d", len(cancelled))
+		return
+	}
+
+	var callerPubkey [33]byte
+	copy(callerPubkey[:], callerKey.PubKey().SerializeCompressed())
+	for _, order := range cancelled {
+		if order.Pubkey != callerPubkey {
+			t.Errorf("Cancelled an order that the caller did not place")
+			return
+		}
+		if order.AuctionID != auctionID {
+			t.Errorf("Cancelled an order that was not pending in the current auction")
+			return
+		}
+	}
+
+	// The other pubkey's order should still be in the book
+	var sellOrders, buyOrders []*match.AuctionOrder
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&testAuctionOrder.TradingPair, auctionID); err != nil {
+		t.Errorf("Error viewing order book after cancel: %s", err)
+		return
+	}
+	if len(sellOrders)+len(buyOrders) != 1 {
+		t.Errorf("Should have 1 order left in the auction after cancel, instead have %d", len(sellOrders)+len(buyOrders))
+		return
+	}
+
+	// And the caller's order in the old auction should not have been touched
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&testAuctionOrder.TradingPair, oldAuctionID); err != nil {
+		t.Errorf("Error viewing old order book after cancel: %s", err)
+		return
+	}
+	if len(sellOrders)+len(buyOrders) != 1 {
+		t.Errorf("Should have 1 order left in the old auction after cancel, instead have %d", len(sellOrders)+len(buyOrders))
+		return
+	}
+
+	// Cancelling in a past auction should not work
+	if _, err = s.CancelAllOrders(callerKey.PubKey(), oldAuctionID); err == nil {
+		t.Errorf("Should not be able to cancel orders in an auction that isn't current")
+		return
+	}
+
+	return
+}
+
+// signTestOrder sets the pubkey of the order and signs it with privkey
+func signTestOrder(order *match.AuctionOrder, privkey *koblitz.PrivateKey) (err error) {
+	copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+
+	order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, order.SigHash(), false)
+	return
+}
+
+func TestValidateOrderRejectsHighS(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestValidateOrderRejectsHighS: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	lowSOrder := *testAuctionOrder
+	if err = signTestOrder(&lowSOrder, privkey); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	// Signing always gives us the low S signature, which should be valid
+	if err = s.validateOrder(&lowSOrder, testEncryptedOrder); err != nil {
+		t.Errorf("Low S signature should be valid: %s", err)
+		return
+	}
+
+	// (R, N - S) is also a valid signature, with the other recovery id
+	highSOrder := lowSOrder
+	highSOrder.Signature = make([]byte, len(lowSOrder.Signature))
+	copy(highSOrder.Signature, lowSOrder.Signature)
+	highSOrder.Signature[0] ^= 1
+	sigS := new(big.Int).SetBytes(lowSOrder.Signature[33:])
+	highS := new(big.Int).Sub(koblitz.S256().Params().N, sigS).Bytes()
+	copy(highSOrder.Signature[33:], make([]byte, 32))
+	copy(highSOrder.Signature[65-len(highS):], highS)
+
+	if err = s.validateOrder(&highSOrder, testEncryptedOrder); err == nil {
+		t.Errorf("High S signature should be rejected as malleable")
+		return
+	}
+
+	return
+}
+
+func TestValidateOrderRejectsWrongNetwork(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestValidateOrderRejectsWrongNetwork: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	mainnetOrder := *testAuctionOrder
+	mainnetOrder.Network = match.MainnetMagic
+	if err = signTestOrder(&mainnetOrder, privkey); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	// The test server is on testnet, so a mainnet order should not be valid
+	if err = s.validateOrder(&mainnetOrder, testEncryptedOrder); err == nil {
+		t.Errorf("Order signed for mainnet should be rejected by a testnet exchange")
+		return
+	}
+
+	s.SetNetwork(match.MainnetMagic)
+	if err = s.validateOrder(&mainnetOrder, testEncryptedOrder); err != nil {
+		t.Errorf("Order signed for mainnet should be valid on a mainnet exchange: %s", err)
+		return
+	}
+
+	// Changing the network after signing should invalidate the signature
+	mainnetOrder.Network = match.TestnetMagic
+	s.SetNetwork(match.TestnetMagic)
+	if err = s.validateOrder(&mainnetOrder, testEncryptedOrder); err == nil {
+		t.Errorf("Order with network changed after signing should be rejected")
+		return
+	}
+
+	return
+}
+
+func TestSolveOrderFailureReasons(t *testing.T) {
+	// Solving panics on a nil order, which should be counted as a failed decrypt
+	if reason := match.PuzzleFailureReason(solveOrder(nil).Err); reason != match.PuzzleDecryptFailed {
+		t.Errorf("Panicking solve should fail with %s, got %q", match.PuzzleDecryptFailed, reason)
+		return
+	}
+
+	// A puzzle that doesn't have an order in it
+	garbage := &match.EncryptedAuctionOrder{CipherType: match.CipherRC5}
+	var err error
+	if garbage.OrderCiphertext, garbage.OrderPuzzle, err = timelockencoders.CreateRSW2048A2PuzzleRC5(1000, []byte("definitely not an auction order")); err != nil {
+		t.Errorf("Error creating puzzle for garbage order: %s", err)
+		return
+	}
+
+	var result *match.OrderPuzzleResult
+	if result = solveOrder(garbage); match.PuzzleFailureReason(result.Err) != match.PuzzleDeserializeFailed {
+		t.Errorf("Solving a puzzle that isn't an order should fail with %s, got %v", match.PuzzleDeserializeFailed, result.Err)
+		return
+	}
+
+	return
+}
+
+func TestValidateOrderMaxAmountRatio(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestValidateOrderMaxAmountRatio: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// wants 10 times what it has
+	order := *testAuctionOrder
+	if err = signTestOrder(&order, privkey); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	for _, maxRatio := range []uint64{0, 100, 10} {
+		s.SetMaxAmountRatio(maxRatio)
+		if err = s.validateOrder(&order, testEncryptedOrder); err != nil {
+			t.Errorf("Order with a ratio of 10 should be valid with a max ratio of %d: %s", maxRatio, err)
+			return
+		}
+	}
+
+	s.SetMaxAmountRatio(9)
+	if err = s.validateOrder(&order, testEncryptedOrder); err == nil {
+		t.Errorf("Order with a ratio of 10 should be rejected with a max ratio of 9")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,332 @@
+package cxauctionserver
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// pairAuction is the state of an auction that a pair runs on its own clock, independently of the exchange's
+// auction. Like the exchange's auction, each one's ID is the closing commitment to the puzzles in the one
+// before it, and it has its own puzzle params. length is how long each one lasts on the wall clock. lastClosed
+// is the auction it closed last, which is cleared on its next tick.
+type pairAuction struct {
+	auctionID     [32]byte
+	auctionStart  time.Time
+	t             uint64
+	length        time.Duration
+	puzzleParams  *match.PuzzleParams
+	commitment    *match.CommitmentChain
+	lastClosed    [32]byte
+	hasLastClosed bool
+}
+
+// newPairAuction creates the first auction for a pair, with a random ID
+func newPairAuction(auctionTime uint64, length time.Duration) (auction *pairAuction, err error) {
+	auction = &pairAuction{
+		auctionStart: time.Now(),
+		t:            auctionTime,
+		length:       length,
+	}
+
+	if _, err = rand.Read(auction.auctionID[:]); err != nil {
+		err = fmt.Errorf("Error getting random auction ID for pair auction: %s", err)
+		return
+	}
+
+	if auction.puzzleParams, err = match.NewPuzzleParams(auctionTime, match.DefaultModulusBits); err != nil {
+		err = fmt.Errorf("Error creating puzzle params for pair auction: %s", err)
+		return
+	}
+
+	auction.commitment = match.NewCommitmentChain(auction.auctionID)
+	return
+}
+
+// deadline is when the auction is scheduled to close
+func (auction *pairAuction) deadline() time.Time {
+	return auction.auctionStart.Add(auction.length)
+}
+
+// StartPairAuction gives pair its own auction, with its own auction ID and deadline, and starts a clock that
+// closes it every auctionTime, on the wall clock like the exchange's auction. From then on, orders for pair
+// have to be signed for its auction instead of the exchange's. If pair is one of the clearing pairs, each of
+// its auctions is cleared on the tick after the one that closed it. Orders in pair auctions aren't written to
+// the order log. The clock stops when the server shuts down, and no pair auctions can be started after that.
+func (s *OpencxAuctionServer) StartPairAuction(pair *match.Pair, auctionTime uint64) (err error) {
+	s.auctionMtx.RLock()
+	length := s.auctionLength(auctionTime)
+	s.auctionMtx.RUnlock()
+
+	var auction *pairAuction
+	if auction, err = newPairAuction(auctionTime, length); err != nil {
+		err = fmt.Errorf("Error starting auction for %s: %s", pair.PrettyString(), err)
+		return
+	}
+
+	s.pairMtx.Lock()
+	if s.pairClocksStopped {
+		s.pairMtx.Unlock()
+		err = fmt.Errorf("Cannot start auction for %s, the server is shutting down", pair.PrettyString())
+		return
+	}
+	if _, found := s.pairAuctions[*pair]; found {
+		s.pairMtx.Unlock()
+		err = fmt.Errorf("%s already runs its own auction", pair.PrettyString())
+		return
+	}
+	s.pairAuctions[*pair] = auction
+	s.pairAuctionIDs[auction.auctionID] = *pair
+	s.pairMtx.Unlock()
+
+	logging.Infof("Starting auction %x for %s with auction time %d", auction.auctionID, pair.PrettyString(), auctionTime)
+
+	go s.pairAuctionClock(*pair)
+
+	return
+}
+
+// CurrentPairAuctionState gets the current auction ID, auction time, and next auction time of pair's auction
+// all at once, like CurrentAuctionState. If pair is nil or doesn't run its own auction, this is the exchange's
+// auction.
+func (s *OpencxAuctionServer) CurrentPairAuctionState(pair *match.Pair) (currentAuctionID [32]byte, currentAuctionTime uint64, nextAuctionTime time.Time, err error) {
+	if pair != nil {
+		s.pairMtx.Lock()
+		auction, found := s.pairAuctions[*pair]
+		if found {
+			currentAuctionID = auction.auctionID
+			currentAuctionTime = auction.t
+			nextAuctionTime = auction.deadline()
+		}
+		s.pairMtx.Unlock()
+
+		if found {
+			return
+		}
+	}
+
+	return s.CurrentAuctionState()
+}
+
+// CurrentPairPuzzleParams gets the puzzle parameters for pair's current auction, and the ID of the auction
+// they're for. If pair is nil or doesn't run its own auction, these are the exchange's.
+func (s *OpencxAuctionServer) CurrentPairPuzzleParams(pair *match.Pair) (currentAuctionID [32]byte, params match.PuzzleParams, err error) {
+	if pair != nil {
+		s.pairMtx.Lock()
+		auction, found := s.pairAuctions[*pair]
+		if found {
+			currentAuctionID = auction.auctionID
+			params = *auction.puzzleParams
+		}
+		s.pairMtx.Unlock()
+
+		if found {
+			return
+		}
+	}
+
+	return s.CurrentPuzzleParams()
+}
+
+// CommitPairAuction closes pair's auction, committing to the puzzles in it, and opens the next one with the
+// closing commitment as its ID, like CommitOrdersNewAuction does for the exchange's auction. It returns the
+// ID of the auction it closed.
+func (s *OpencxAuctionServer) CommitPairAuction(pair *match.Pair) (closedAuctionID [32]byte, err error) {
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	s.pairMtx.Lock()
+	auction, found := s.pairAuctions[*pair]
+	if found {
+		closedAuctionID = auction.auctionID
+	}
+	s.pairMtx.Unlock()
+
+	if !found {
+		err = fmt.Errorf("%s doesn't run its own auction", pair.PrettyString())
+		return
+	}
+
+	// Puzzles are placed while holding dbLock, so none can come in between this and closing the commitment
+	var puzzles []*match.EncryptedAuctionOrder
+	if puzzles, err = s.OpencxDB.ViewAuctionPuzzleBook(closedAuctionID); err != nil {
+		err = fmt.Errorf("Error getting puzzle book of %s auction for commit: %s", pair.PrettyString(), err)
+		return
+	}
+
+	// Every auction gets new puzzle params, so solving one auction's puzzles doesn't help with the next
+	var newPuzzleParams *match.PuzzleParams
+	if newPuzzleParams, err = match.NewPuzzleParams(auction.t, match.DefaultModulusBits); err != nil {
+		err = fmt.Errorf("Error creating puzzle params for new %s auction: %s", pair.PrettyString(), err)
+		return
+	}
+
+	s.pairMtx.Lock()
+	auctionRoot := auction.commitment.Close()
+	newAuctionID := auctionRoot.Closing()
+	auction.commitment = match.NewCommitmentChain(newAuctionID)
+	auction.auctionID = newAuctionID
+	auction.auctionStart = time.Now()
+	auction.puzzleParams = newPuzzleParams
+	s.pairAuctionIDs[newAuctionID] = *pair
+	s.pairMtx.Unlock()
+
+	if auctionRoot.NumPuzzles != uint64(len(puzzles)) {
+		logging.Errorf("Commitment to %s auction %x has %d puzzles but the puzzle book has %d", pair.PrettyString(), closedAuctionID, auctionRoot.NumPuzzles, len(puzzles))
+	}
+
+	// Keys of the closed auction are revealed once its orders are solved, or the reveal grace period is over
+	s.markAuctionClosed(closedAuctionID)
+
+	// Keep the root so watchdogs can get it after the auction is gone
+	if err = s.OpencxDB.PlaceAuctionRoot(auctionRoot); err != nil {
+		err = fmt.Errorf("Error storing root of %s auction %x: %s", pair.PrettyString(), closedAuctionID, err)
+		return
+	}
+
+	logging.Infof("Closed %s auction %x, opened %x", pair.PrettyString(), closedAuctionID, newAuctionID)
+
+	return
+}
+
+// pairAuctionClock should be run in a goroutine, it closes pair's auction every time its deadline passes. It
+// returns once the server shuts down.
+func (s *OpencxAuctionServer) pairAuctionClock(pair match.Pair) {
+	var err error
+	var deadline time.Time
+	for {
+		if _, _, deadline, err = s.CurrentPairAuctionState(&pair); err != nil {
+			logging.Errorf("Error getting deadline of %s auction, stopping its clock: %s", pair.PrettyString(), err)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(deadline))
+		select {
+		case <-s.stopPairClocks:
+			timer.Stop()
+			logging.Infof("Stopping clock of %s auction, the server is shutting down", pair.PrettyString())
+			return
+		case <-timer.C:
+		}
+
+		s.pairAuctionTick(&pair)
+	}
+}
+
+// stopPairAuctionClocks stops the clocks of every pair auction, so no more of their auctions close. Calling it
+// more than once is fine.
+func (s *OpencxAuctionServer) stopPairAuctionClocks() {
+	s.pairMtx.Lock()
+	if !s.pairClocksStopped {
+		s.pairClocksStopped = true
+		close(s.stopPairClocks)
+	}
+	s.pairMtx.Unlock()
+	return
+}
+
+// pairAuctionTick closes pair's auction, and clears the one it closed on the last tick if pair is one of the
+// clearing pairs. Its orders have had an auction's time to be solved by then.
+func (s *OpencxAuctionServer) pairAuctionTick(pair *match.Pair) {
+	var err error
+
+	var closedAuctionID [32]byte
+	if closedAuctionID, err = s.CommitPairAuction(pair); err != nil {
+		logging.Errorf("Error closing auction for %s: %s", pair.PrettyString(), err)
+		return
+	}
+
+	s.pairMtx.Lock()
+	auction := s.pairAuctions[*pair]
+	clearingID, hasClearing := auction.lastClosed, auction.hasLastClosed
+	auction.lastClosed = closedAuctionID
+	auction.hasLastClosed = true
+	s.pairMtx.Unlock()
+
+	if !hasClearing {
+		return
+	}
+
+	if s.isClearingPair(pair) {
+		if err = s.RunPairAuctions([]*match.Pair{pair}, func(pair *match.Pair) (err error) {
+			_, err = s.ClearPairAuction(pair, clearingID)
+			return
+		}); err != nil {
+			logging.Errorf("Error clearing %s auction %x: %s", pair.PrettyString(), clearingID, err)
+		}
+	}
+
+	s.forgetPairAuction(clearingID)
+
+	return
+}
+
+// forgetPairAuction forgets a pair auction that was cleared, along with the orders placed in it. Orders for it
+// that are solved after this aren't placed.
+func (s *OpencxAuctionServer) forgetPairAuction(auctionID [32]byte) {
+	s.dbLock.Lock()
+	delete(s.placedOrders, auctionID)
+	delete(s.placedNonces, auctionID)
+	delete(s.placedPubkeys, auctionID)
+	s.pairMtx.Lock()
+	delete(s.pairAuctionIDs, auctionID)
+	s.pairMtx.Unlock()
+	s.dbLock.Unlock()
+	return
+}
+
+// runsOwnAuction returns true if pair runs its own auction, and the ID of its current auction if it does
+func (s *OpencxAuctionServer) runsOwnAuction(pair *match.Pair) (currentAuctionID [32]byte, ownAuction bool) {
+	s.pairMtx.Lock()
+	var auction *pairAuction
+	if auction, ownAuction = s.pairAuctions[*pair]; ownAuction {
+		currentAuctionID = auction.auctionID
+	}
+	s.pairMtx.Unlock()
+	return
+}
+
+// openPairAuction returns the pair auction with auctionID if it's still open. The caller should be holding pairMtx.
+func (s *OpencxAuctionServer) openPairAuction(auctionID [32]byte) (auction *pairAuction, found bool) {
+	var pair match.Pair
+	if pair, found = s.pairAuctionIDs[auctionID]; !found {
+		return
+	}
+
+	auction = s.pairAuctions[pair]
+	found = auction.auctionID == auctionID
+	return
+}
+
+// isOpenPairAuction returns true if auctionID is the current auction of a pair that runs its own auction
+func (s *OpencxAuctionServer) isOpenPairAuction(auctionID [32]byte) (open bool) {
+	s.pairMtx.Lock()
+	_, open = s.openPairAuction(auctionID)
+	s.pairMtx.Unlock()
+	return
+}
+
+// checkPairAuction checks that an order going in placeIn is for the pair placeIn is an auction for, and that
+// orders for pairs that run their own auctions only go in them
+func (s *OpencxAuctionServer) checkPairAuction(order *match.AuctionOrder, placeIn [32]byte) (err error) {
+	s.pairMtx.Lock()
+	defer s.pairMtx.Unlock()
+
+	if auctionPair, found := s.pairAuctionIDs[placeIn]; found {
+		if auctionPair != order.TradingPair {
+			err = match.NewPuzzleResultError(match.PuzzleAuctionMismatch, "Order for %s was signed for auction %x, which is for %s", order.TradingPair.PrettyString(), placeIn, auctionPair.PrettyString())
+			return
+		}
+		return
+	}
+
+	if _, ownAuction := s.pairAuctions[order.TradingPair]; ownAuction {
+		err = match.NewPuzzleResultError(match.PuzzleAuctionMismatch, "%s runs its own auctions, but the order was signed for auction %x", order.TradingPair.PrettyString(), placeIn)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,211 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// newTestPairAuctionOrder creates an encrypted order for pair, signed for the pair's current auction with its puzzle params
+func newTestPairAuctionOrder(s *OpencxAuctionServer, pair match.Pair, side string, privkey *koblitz.PrivateKey) (order *match.AuctionOrder, encOrder *match.EncryptedAuctionOrder, err error) {
+	var auctionID [32]byte
+	var params match.PuzzleParams
+	if auctionID, params, err = s.CurrentPairPuzzleParams(&pair); err != nil {
+		return
+	}
+
+	if order, err = newTestContinuousOrder(side, 1000, 2.0, privkey); err != nil {
+		return
+	}
+
+	order.TradingPair = pair
+	order.AuctionID = auctionID
+	if err = signTestOrder(order, privkey); err != nil {
+		return
+	}
+
+	encOrder, err = order.TurnIntoEncryptedOrderWithParams(&params)
+	return
+}
+
+func TestPairAuctionsClearSeparately(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestPairAuctionsClearSeparately: %s", err)
+		return
+	}
+
+	vtcPair := match.Pair{AssetWant: match.BTC, AssetHave: match.VTCTest}
+	ltcPair := match.Pair{AssetWant: match.BTC, AssetHave: match.LTCTest}
+	pairs := []*match.Pair{&vtcPair, &ltcPair}
+	s.SetClearingPairs(pairs)
+
+	// The long auction time keeps the pair clocks from ticking on their own, so we can tick them
+	for _, pair := range pairs {
+		if err = s.StartPairAuction(pair, uint64(time.Hour/time.Microsecond)); err != nil {
+			t.Errorf("Error starting auction for %s: %s", pair.PrettyString(), err)
+			return
+		}
+
+		s.pairMtx.Lock()
+		s.pairAuctions[*pair].puzzleParams = &match.PuzzleParams{
+			A:           s.pairAuctions[*pair].puzzleParams.A,
+			T:           1000,
+			ModulusBits: 1024,
+		}
+		s.pairMtx.Unlock()
+	}
+
+	if err = s.StartPairAuction(&vtcPair, uint64(time.Hour/time.Microsecond)); err == nil {
+		t.Errorf("Starting a second auction for %s should fail", vtcPair.PrettyString())
+		return
+	}
+
+	var exchangeAuctionID, vtcAuctionID, ltcAuctionID [32]byte
+	if exchangeAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	var ltcDeadline time.Time
+	if vtcAuctionID, _, _, err = s.CurrentPairAuctionState(&vtcPair); err != nil {
+		t.Errorf("Error getting %s auction state: %s", vtcPair.PrettyString(), err)
+		return
+	}
+	if ltcAuctionID, _, ltcDeadline, err = s.CurrentPairAuctionState(&ltcPair); err != nil {
+		t.Errorf("Error getting %s auction state: %s", ltcPair.PrettyString(), err)
+		return
+	}
+
+	if vtcAuctionID == ltcAuctionID || vtcAuctionID == exchangeAuctionID || ltcAuctionID == exchangeAuctionID {
+		t.Errorf("Every pair should have its own auction, got %x and %x with the exchange's %x", vtcAuctionID, ltcAuctionID, exchangeAuctionID)
+		return
+	}
+
+	// A buy and a sell that cross in each pair's auction
+	for _, pair := range pairs {
+		for _, side := range []string{"buy", "sell"} {
+			var privkey *koblitz.PrivateKey
+			if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+				t.Errorf("Error creating key: %s", err)
+				return
+			}
+
+			var encOrder *match.EncryptedAuctionOrder
+			if _, encOrder, err = newTestPairAuctionOrder(s, *pair, side, privkey); err != nil {
+				t.Errorf("Error creating %s order for %s: %s", side, pair.PrettyString(), err)
+				return
+			}
+
+			if _, err = s.validateEncryptedOrder(encOrder); err != nil {
+				t.Errorf("Order for the current auction of %s should be valid: %s", pair.PrettyString(), err)
+				return
+			}
+
+			if err = s.PlacePuzzledOrder(encOrder); err != nil {
+				t.Errorf("Error placing %s order for %s: %s", side, pair.PrettyString(), err)
+				return
+			}
+		}
+	}
+
+	// Orders can only go in the auctions of their own pair
+	ltcOrder := &match.AuctionOrder{TradingPair: ltcPair}
+	if err = s.checkPairAuction(ltcOrder, vtcAuctionID); err == nil {
+		t.Errorf("Order for %s should not go in the auction for %s", ltcPair.PrettyString(), vtcPair.PrettyString())
+		return
+	}
+	if err = s.checkPairAuction(ltcOrder, exchangeAuctionID); err == nil {
+		t.Errorf("Order for %s should not go in the exchange's auction, the pair runs its own", ltcPair.PrettyString())
+		return
+	}
+	if err = s.checkPairAuction(ltcOrder, ltcAuctionID); err != nil {
+		t.Errorf("Order for %s should go in its own auction: %s", ltcPair.PrettyString(), err)
+		return
+	}
+
+	for _, pair := range pairs {
+		auctionID, _ := s.runsOwnAuction(pair)
+		deadline := time.Now().Add(time.Minute)
+		for {
+			sellOrders, buyOrders, _ := s.OpencxDB.ViewAuctionOrderBook(pair, auctionID)
+			if len(sellOrders) == 1 && len(buyOrders) == 1 {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Errorf("Orders for %s were never placed in its auction, got %d sells and %d buys", pair.PrettyString(), len(sellOrders), len(buyOrders))
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Only the VTC auction closes, and then clears on its next tick
+	s.pairAuctionTick(&vtcPair)
+	if _, err = s.AuctionFills(vtcAuctionID); err == nil {
+		t.Errorf("Auction for %s should not be cleared on the tick that closes it", vtcPair.PrettyString())
+		return
+	}
+
+	s.pairAuctionTick(&vtcPair)
+	var fills []*match.AuctionFill
+	if fills, err = s.AuctionFills(vtcAuctionID); err != nil {
+		t.Errorf("Auction for %s should be cleared on the tick after it closes: %s", vtcPair.PrettyString(), err)
+		return
+	}
+
+	if len(fills) != 2 {
+		t.Errorf("Cleared %s auction should have 2 fills, got %d", vtcPair.PrettyString(), len(fills))
+		return
+	}
+
+	// The LTC auction is still open, and didn't move
+	var ltcNowID [32]byte
+	var ltcNowDeadline time.Time
+	if ltcNowID, _, ltcNowDeadline, err = s.CurrentPairAuctionState(&ltcPair); err != nil {
+		t.Errorf("Error getting %s auction state: %s", ltcPair.PrettyString(), err)
+		return
+	}
+
+	if ltcNowID != ltcAuctionID || !ltcNowDeadline.Equal(ltcDeadline) {
+		t.Errorf("Auction for %s should not change when %s's does", ltcPair.PrettyString(), vtcPair.PrettyString())
+		return
+	}
+
+	if _, err = s.AuctionFills(ltcAuctionID); err == nil {
+		t.Errorf("Auction for %s should not be cleared when %s's is", ltcPair.PrettyString(), vtcPair.PrettyString())
+		return
+	}
+
+	if _, err = s.ClearPairAuction(&ltcPair, ltcAuctionID); err == nil {
+		t.Errorf("Auction for %s should not be cleared while it's still running", ltcPair.PrettyString())
+		return
+	}
+
+	// Then the LTC auction clears on its own
+	s.pairAuctionTick(&ltcPair)
+	s.pairAuctionTick(&ltcPair)
+	if fills, err = s.AuctionFills(ltcAuctionID); err != nil {
+		t.Errorf("Auction for %s should be cleared on the tick after it closes: %s", ltcPair.PrettyString(), err)
+		return
+	}
+
+	if len(fills) != 2 {
+		t.Errorf("Cleared %s auction should have 2 fills, got %d", ltcPair.PrettyString(), len(fills))
+		return
+	}
+
+	for _, fill := range fills {
+		if fill.Pair != ltcPair {
+			t.Errorf("Auction for %s has a fill for %s", ltcPair.PrettyString(), fill.Pair.PrettyString())
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,32 @@
+package cxauctionserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+// DefaultPricePrecision is the number of decimal places prices are formatted with by default
+const DefaultPricePrecision = uint(8)
+
+// SetPricePrecision sets the number of decimal places that prices like the clearing price are
+// formatted with for clients
+func (s *OpencxAuctionServer) SetPricePrecision(precision uint) (err error) {
+	if precision > match.MaxPricePrecision {
+		err = fmt.Errorf("Price precision %d is more than the max of %d decimal places", precision, match.MaxPricePrecision)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.pricePrecision = precision
+	s.auctionMtx.Unlock()
+	return
+}
+
+// PricePrecision gets the number of decimal places that prices are formatted with for clients
+func (s *OpencxAuctionServer) PricePrecision() (precision uint, err error) {
+	s.auctionMtx.RLock()
+	precision = s.pricePrecision
+	s.auctionMtx.RUnlock()
+	return
+}
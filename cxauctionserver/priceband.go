@@ -0,0 +1,57 @@
+package cxauctionserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// SetPriceBand sets the absolute price band that clearing prices for the pair must be in. If an auction for
+// the pair clears outside of the band, the auction is voided.
+func (s *OpencxAuctionServer) SetPriceBand(pair match.Pair, band *match.PriceBand) (err error) {
+	if band.MinPrice < 0 {
+		err = fmt.Errorf("Minimum price for price band cannot be negative")
+		return
+	}
+
+	if band.MinPrice > band.MaxPrice {
+		err = fmt.Errorf("Minimum price %f for price band cannot be greater than maximum price %f", band.MinPrice, band.MaxPrice)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.priceBands[pair] = band
+	s.auctionMtx.Unlock()
+
+	logging.Infof("Set price band for %s to %s", pair.PrettyString(), band)
+
+	return
+}
+
+// CheckClearingPrice checks the clearing price for a pair against the pair's price band, if there is one, and
+// against the band around the oracle's reserve price, if there is an oracle. If the clearing price is outside
+// of either band, or the oracle can't give a price, this returns an error and the auction should be voided.
+func (s *OpencxAuctionServer) CheckClearingPrice(pair *match.Pair, clearingPrice float64) (err error) {
+	s.auctionMtx.RLock()
+	band, found := s.priceBands[*pair]
+	s.auctionMtx.RUnlock()
+
+	if found && !band.Contains(clearingPrice) {
+		err = fmt.Errorf("Clearing price %f for %s is outside of price band %s, voiding auction", clearingPrice, pair.PrettyString(), band)
+		return
+	}
+
+	var reserveBand *match.PriceBand
+	if reserveBand, err = s.oracleBand(pair); err != nil {
+		err = fmt.Errorf("Error checking clearing price %f for %s against the oracle, voiding auction: %s", clearingPrice, pair.PrettyString(), err)
+		return
+	}
+
+	if reserveBand != nil && !reserveBand.Contains(clearingPrice) {
+		err = fmt.Errorf("Clearing price %f for %s is outside of the oracle price band %s, voiding auction", clearingPrice, pair.PrettyString(), reserveBand)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,59 @@
+package cxauctionserver
+
+import (
+	"testing"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestCheckClearingPrice(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestCheckClearingPrice: %s", err)
+		return
+	}
+
+	bandedPair := match.Pair{
+		AssetWant: match.BTCReg,
+		AssetHave: match.LTCReg,
+	}
+	unbandedPair := match.Pair{
+		AssetWant: match.BTCReg,
+		AssetHave: match.VTCReg,
+	}
+
+	if err = s.SetPriceBand(bandedPair, &match.PriceBand{MinPrice: 0.95, MaxPrice: 1.05}); err != nil {
+		t.Errorf("Error setting price band: %s", err)
+		return
+	}
+
+	for _, price := range []float64{0.95, 1.00, 1.05} {
+		if err = s.CheckClearingPrice(&bandedPair, price); err != nil {
+			t.Errorf("Clearing price %f should be inside the band: %s", price, err)
+			return
+		}
+	}
+
+	for _, price := range []float64{0.0, 0.94, 1.06, 100.0} {
+		if err = s.CheckClearingPrice(&bandedPair, price); err == nil {
+			t.Errorf("Clearing price %f should be outside the band and void the auction", price)
+			return
+		}
+	}
+
+	// Pairs without a band should accept any price
+	if err = s.CheckClearingPrice(&unbandedPair, 100.0); err != nil {
+		t.Errorf("Pair without a price band should accept any clearing price: %s", err)
+		return
+	}
+
+	// Inverted bands make no sense
+	if err = s.SetPriceBand(unbandedPair, &match.PriceBand{MinPrice: 2, MaxPrice: 1}); err == nil {
+		t.Errorf("Should not be able to set a price band with min greater than max")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,131 @@
+package cxauctionserver
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestPuzzleParamsChangeEveryAuction(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestPuzzleParamsChangeEveryAuction: %s", err)
+		return
+	}
+
+	seenBases := make(map[int64]bool)
+	seenAuctions := make(map[[32]byte]bool)
+	for i := 0; i < 8; i++ {
+		var auctionID [32]byte
+		var params match.PuzzleParams
+		if auctionID, params, err = s.CurrentPuzzleParams(); err != nil {
+			t.Errorf("Error getting puzzle params: %s", err)
+			return
+		}
+
+		if seenAuctions[auctionID] {
+			t.Errorf("Auction %x was advertised twice", auctionID)
+			return
+		}
+		seenAuctions[auctionID] = true
+
+		if seenBases[params.A] {
+			t.Errorf("Puzzle base %d was advertised for more than one auction", params.A)
+			return
+		}
+		seenBases[params.A] = true
+
+		if params.T != testStandardAuctionTime {
+			t.Errorf("Puzzle params had time %d, expected the auction time %d", params.T, testStandardAuctionTime)
+			return
+		}
+
+		if params.ModulusBits != match.DefaultModulusBits {
+			t.Errorf("Puzzle params had modulus size %d, expected %d", params.ModulusBits, match.DefaultModulusBits)
+			return
+		}
+
+		if err = s.CommitOrdersNewAuction(); err != nil {
+			t.Errorf("Error committing orders for new auction: %s", err)
+			return
+		}
+	}
+
+	return
+}
+
+func TestPuzzleWithPreviousParamsRejected(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestPuzzleWithPreviousParamsRejected: %s", err)
+		return
+	}
+
+	var oldParams match.PuzzleParams
+	if _, oldParams, err = s.CurrentPuzzleParams(); err != nil {
+		t.Errorf("Error getting puzzle params: %s", err)
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error committing orders for new auction: %s", err)
+		return
+	}
+
+	// Keep the new auction's base, but make its puzzles quick to solve like the last auction's
+	s.auctionMtx.Lock()
+	s.puzzleParams = &match.PuzzleParams{
+		A:           s.puzzleParams.A,
+		T:           oldParams.T,
+		ModulusBits: oldParams.ModulusBits,
+	}
+	s.auctionMtx.Unlock()
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	var encOrder *match.EncryptedAuctionOrder
+	if order, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+		t.Errorf("Error creating puzzled order: %s", err)
+		return
+	}
+
+	// The same order for the new auction, but with a puzzle made with the last auction's params
+	var oldEncOrder *match.EncryptedAuctionOrder
+	if oldEncOrder, err = order.TurnIntoEncryptedOrderWithParams(&oldParams); err != nil {
+		t.Errorf("Error creating puzzled order with old params: %s", err)
+		return
+	}
+
+	if err = s.PlacePuzzledOrder(oldEncOrder); err == nil {
+		t.Errorf("Puzzle made with the last auction's params should be rejected")
+		return
+	}
+
+	var puzzles []*match.EncryptedAuctionOrder
+	if puzzles, err = s.OpencxDB.ViewAuctionPuzzleBook(order.AuctionID); err != nil {
+		t.Errorf("Error viewing puzzle book: %s", err)
+		return
+	}
+
+	if len(puzzles) != 0 {
+		t.Errorf("Puzzle made with the last auction's params should not be stored, found %d puzzles", len(puzzles))
+		return
+	}
+
+	if err = s.PlacePuzzledOrder(encOrder); err != nil {
+		t.Errorf("Puzzle made with the new auction's params should be accepted: %s", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,178 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// RejectionReason is why a submitted order was rejected, so rejections can be counted by why they happened
+type RejectionReason string
+
+const (
+	// RejectedWrongMode means the order isn't accepted in the exchange's matching mode
+	RejectedWrongMode RejectionReason = "wrong-mode"
+	// RejectedBadPuzzle means the puzzle can't be solved, or doesn't use the auction's puzzle params
+	RejectedBadPuzzle RejectionReason = "bad-puzzle"
+	// RejectedWrongAuction means the order isn't for the auction it was sent for
+	RejectedWrongAuction RejectionReason = "wrong-auction"
+	// RejectedOutsideWindow means the order came in outside of the window that orders for its auction are
+	// accepted in, like an order for the next auction sent too early
+	RejectedOutsideWindow RejectionReason = "outside-window"
+	// RejectedRateLimited means the solve queue was full, so there was no room to solve the order
+	RejectedRateLimited RejectionReason = "rate-limited"
+	// RejectedInvalid means the solved order is malformed, or isn't validly signed
+	RejectedInvalid RejectionReason = "invalid"
+	// RejectedDuplicate means the solved order was already placed in the auction, or the exact same puzzled
+	// order was already sent in it
+	RejectedDuplicate RejectionReason = "duplicate"
+)
+
+// String returns the name of the rejection reason
+func (r RejectionReason) String() string {
+	return string(r)
+}
+
+// RejectionCounts is how many orders were rejected for each reason
+type RejectionCounts map[RejectionReason]uint64
+
+// String returns the counts for every reason, sorted so it reads the same every time
+func (c RejectionCounts) String() string {
+	var counts []string
+	for reason, count := range c {
+		counts = append(counts, fmt.Sprintf("%s: %d", reason, count))
+	}
+	sort.Strings(counts)
+	return strings.Join(counts, ", ")
+}
+
+// RejectionMetrics are how many submitted orders were accepted and how many were rejected. Orders can be
+// accepted when they're submitted and rejected once they're solved, so rejections aren't just the submissions
+// that weren't accepted.
+type RejectionMetrics struct {
+	// Accepted is how many submitted orders were accepted to be solved
+	Accepted uint64
+	// Rejected is how many orders were rejected for each reason since the exchange started, and
+	// RejectedInAuction is how many were rejected in the current auction
+	Rejected          RejectionCounts
+	RejectedInAuction RejectionCounts
+}
+
+// rejectionCounter counts rejected orders by reason, in total and in the current auction. When alertThreshold
+// orders have been rejected for a reason in an auction, an alert is logged. An alert threshold of 0 means
+// there are no alerts.
+type rejectionCounter struct {
+	accepted       uint64
+	total          RejectionCounts
+	inAuction      RejectionCounts
+	auctionID      [32]byte
+	alertThreshold uint64
+	mtx            *sync.Mutex
+}
+
+// newRejectionCounter creates a rejection counter with nothing counted and no alerts
+func newRejectionCounter() (rc *rejectionCounter) {
+	rc = &rejectionCounter{
+		total:     make(RejectionCounts),
+		inAuction: make(RejectionCounts),
+		mtx:       new(sync.Mutex),
+	}
+	return
+}
+
+// reject counts an order rejected for reason in auctionID
+func (rc *rejectionCounter) reject(reason RejectionReason, auctionID [32]byte) {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+
+	if rc.auctionID != auctionID {
+		rc.inAuction = make(RejectionCounts)
+		rc.auctionID = auctionID
+	}
+
+	rc.total[reason]++
+	rc.inAuction[reason]++
+
+	// only alert once per reason per auction
+	if rc.alertThreshold > 0 && rc.inAuction[reason] == rc.alertThreshold {
+		logging.Warnf("Alert: %d orders rejected as %s in auction %x", rc.inAuction[reason], reason, auctionID)
+	}
+}
+
+// accept counts an accepted order
+func (rc *rejectionCounter) accept() {
+	rc.mtx.Lock()
+	rc.accepted++
+	rc.mtx.Unlock()
+}
+
+// metrics returns a copy of the counts. Counts for an auction other than auctionID are from an auction that
+// is over, so nothing has been rejected in auctionID yet.
+func (rc *rejectionCounter) metrics(auctionID [32]byte) (metrics RejectionMetrics) {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+
+	metrics.Accepted = rc.accepted
+	metrics.Rejected = make(RejectionCounts, len(rc.total))
+	for reason, count := range rc.total {
+		metrics.Rejected[reason] = count
+	}
+
+	metrics.RejectedInAuction = make(RejectionCounts)
+	if rc.auctionID == auctionID {
+		for reason, count := range rc.inAuction {
+			metrics.RejectedInAuction[reason] = count
+		}
+	}
+
+	return
+}
+
+// SetRejectionAlertThreshold sets how many orders have to be rejected for the same reason in an auction for
+// an alert to be logged. 0 means there are no alerts, which is the default.
+func (s *OpencxAuctionServer) SetRejectionAlertThreshold(threshold uint64) {
+	s.rejections.mtx.Lock()
+	s.rejections.alertThreshold = threshold
+	s.rejections.mtx.Unlock()
+}
+
+// RejectionMetrics returns how many submitted orders were accepted, and how many orders were rejected for
+// each reason
+func (s *OpencxAuctionServer) RejectionMetrics() (metrics RejectionMetrics, err error) {
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for rejection metrics: %s", err)
+		return
+	}
+
+	metrics = s.rejections.metrics(auctionID)
+	return
+}
+
+// countRejection counts an order rejected for reason in the current auction
+func (s *OpencxAuctionServer) countRejection(reason RejectionReason) {
+	auctionID, err := s.CurrentAuctionID()
+	if err != nil {
+		logging.Errorf("Error getting current auction ID to count %s rejection: %s", reason, err)
+		return
+	}
+
+	s.rejections.reject(reason, auctionID)
+}
+
+// countPuzzleFailure counts the rejection of a solved order that failed for reason. Puzzles that were
+// cancelled by the exchange weren't rejected, so they aren't counted.
+func (s *OpencxAuctionServer) countPuzzleFailure(reason match.PuzzleFailure) {
+	switch reason {
+	case match.PuzzleDecryptFailed:
+		s.countRejection(RejectedBadPuzzle)
+	case match.PuzzleDeserializeFailed:
+		s.countRejection(RejectedInvalid)
+	case match.PuzzleAuctionMismatch:
+		s.countRejection(RejectedWrongAuction)
+	}
+}
@@ -0,0 +1,213 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// checkRejections checks that the rejection metrics have exactly the expected counts
+func checkRejections(s *OpencxAuctionServer, accepted uint64, rejected RejectionCounts, inAuction RejectionCounts, t *testing.T) (ok bool) {
+	metrics, err := s.RejectionMetrics()
+	if err != nil {
+		t.Errorf("Error getting rejection metrics: %s", err)
+		return
+	}
+
+	if metrics.Accepted != accepted {
+		t.Errorf("Expected %d accepted orders, got %d", accepted, metrics.Accepted)
+		return
+	}
+
+	for _, counts := range []struct {
+		name     string
+		expected RejectionCounts
+		actual   RejectionCounts
+	}{
+		{"total", rejected, metrics.Rejected},
+		{"auction", inAuction, metrics.RejectedInAuction},
+	} {
+		if len(counts.actual) != len(counts.expected) {
+			t.Errorf("Expected %s rejections {%s}, got {%s}", counts.name, counts.expected, counts.actual)
+			return
+		}
+		for reason, count := range counts.expected {
+			if counts.actual[reason] != count {
+				t.Errorf("Expected %s rejections {%s}, got {%s}", counts.name, counts.expected, counts.actual)
+				return
+			}
+		}
+	}
+
+	ok = true
+	return
+}
+
+func TestRejectionCounters(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestRejectionCounters: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var encOrder *match.EncryptedAuctionOrder
+	if _, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+		t.Errorf("Error creating next auction order: %s", err)
+		return
+	}
+
+	// No window, and then the next auction is an hour away, so both are outside the window
+	if err = s.QueueNextAuctionOrder(encOrder); err == nil {
+		t.Errorf("Next auction orders should not be accepted without a window")
+		return
+	}
+	if err = s.SetNextAuctionWindow(time.Minute); err != nil {
+		t.Errorf("Error setting next auction window: %s", err)
+		return
+	}
+	if err = s.QueueNextAuctionOrder(encOrder); err == nil {
+		t.Errorf("Next auction orders should not be accepted before the window")
+		return
+	}
+
+	if err = s.SetNextAuctionWindow(2 * time.Hour); err != nil {
+		t.Errorf("Error setting next auction window: %s", err)
+		return
+	}
+	wrongAuction := *encOrder
+	wrongAuction.IntendedAuction = [32]byte{0xff}
+	if err = s.QueueNextAuctionOrder(&wrongAuction); err == nil {
+		t.Errorf("Next auction orders made for an auction other than the current one should not be accepted")
+		return
+	}
+
+	if err = s.QueueNextAuctionOrder(encOrder); err != nil {
+		t.Errorf("Next auction order in the window should be accepted: %s", err)
+		return
+	}
+
+	expected := RejectionCounts{
+		RejectedOutsideWindow: 2,
+		RejectedWrongAuction:  1,
+	}
+	if !checkRejections(s, 1, expected, expected, t) {
+		return
+	}
+
+	// Solved orders are rejected after they're accepted
+	var order *match.AuctionOrder
+	if order, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+	tampered := order.Clone()
+	tampered.AmountHave++
+
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{
+		{Auction: order},
+		{Auction: order.Clone()},
+		{Auction: tampered},
+		{Encrypted: &match.EncryptedAuctionOrder{IntendedAuction: [32]byte{0xff}}, Auction: order.Clone()},
+		{Err: match.NewPuzzleResultError(match.PuzzleDecryptFailed, "bad ciphertext")},
+		{Err: match.NewPuzzleResultError(match.PuzzleDeserializeFailed, "bad order")},
+		// the exchange stopped solving, the order wasn't rejected
+		{Err: match.NewPuzzleResultError(match.PuzzleCancelled, "shutting down")},
+	})
+
+	expected = RejectionCounts{
+		RejectedOutsideWindow: 2,
+		RejectedWrongAuction:  2,
+		RejectedDuplicate:     1,
+		RejectedInvalid:       2,
+		RejectedBadPuzzle:     1,
+	}
+	if !checkRejections(s, 1, expected, expected, t) {
+		return
+	}
+
+	// Rejections in the current auction start over in the next one, the totals don't
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error committing orders: %s", err)
+		return
+	}
+
+	if !checkRejections(s, 1, expected, RejectionCounts{}, t) {
+		return
+	}
+
+	return
+}
+
+func TestPuzzledOrderRejectionCounters(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestPuzzledOrderRejectionCounters: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	var encOrder *match.EncryptedAuctionOrder
+	if order, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+		t.Errorf("Error creating puzzled order: %s", err)
+		return
+	}
+
+	wrongAuction := *encOrder
+	wrongAuction.IntendedAuction = [32]byte{0xff}
+	if err = s.PlacePuzzledOrder(&wrongAuction); err == nil {
+		t.Errorf("Puzzled order for an auction other than the current one should not be accepted")
+		return
+	}
+
+	var params match.PuzzleParams
+	if _, params, err = s.CurrentPuzzleParams(); err != nil {
+		t.Errorf("Error getting puzzle params: %s", err)
+		return
+	}
+
+	// A puzzle with a base other than the auction's
+	params.A++
+	var wrongParams *match.EncryptedAuctionOrder
+	if wrongParams, err = order.TurnIntoEncryptedOrderWithParams(&params); err != nil {
+		t.Errorf("Error creating puzzled order with the wrong params: %s", err)
+		return
+	}
+
+	if err = s.PlacePuzzledOrder(wrongParams); err == nil {
+		t.Errorf("Puzzled order that doesn't use the auction's puzzle params should not be accepted")
+		return
+	}
+
+	if err = s.PlacePuzzledOrder(encOrder); err != nil {
+		t.Errorf("Puzzled order for the current auction should be accepted: %s", err)
+		return
+	}
+
+	expected := RejectionCounts{
+		RejectedWrongAuction: 1,
+		RejectedBadPuzzle:    1,
+	}
+	if !checkRejections(s, 1, expected, expected, t) {
+		return
+	}
+
+	return
+}
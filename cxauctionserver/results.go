@@ -0,0 +1,139 @@
+package cxauctionserver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+// AuctionResult is the published result of a pair's auction: the clearing price, and the commitment root of the
+// puzzles the auction was made of. If the exchange has a signing key the result is signed, so clients and
+// watchdogs can check it came from the exchange.
+type AuctionResult struct {
+	AuctionID     [32]byte   `json:"auctionid"`
+	Pair          match.Pair `json:"pair"`
+	ClearingPrice float64    `json:"clearingprice"`
+	// NoMatch is true if the book didn't cross, so there is no clearing price
+	NoMatch       bool   `json:"nomatch"`
+	BuyVolume     uint64 `json:"buyvolume"`
+	SellVolume    uint64 `json:"sellvolume"`
+	ClearedAtUnix int64  `json:"clearedatunix"`
+	// Root and NumPuzzles are the final commitment to the puzzles in the auction
+	Root       [32]byte `json:"root"`
+	NumPuzzles uint64   `json:"numpuzzles"`
+	// Signature is a compact signature of SerializeSignable by the exchange's key. It's empty if the
+	// exchange has no signing key.
+	Signature []byte `json:"signature"`
+}
+
+// SerializeSignable serializes what the exchange signs in an auction result, which is every field except
+// the signature
+func (ar *AuctionResult) SerializeSignable() (buf []byte) {
+	var intBytes [8]byte
+	putUint64 := func(i uint64) {
+		binary.BigEndian.PutUint64(intBytes[:], i)
+		buf = append(buf, intBytes[:]...)
+	}
+
+	buf = append(buf, []byte("opencx-auctionresult")...)
+	buf = append(buf, ar.AuctionID[:]...)
+	buf = append(buf, ar.Pair.Serialize()...)
+	putUint64(math.Float64bits(ar.ClearingPrice))
+	if ar.NoMatch {
+		buf = append(buf, 0x01)
+	} else {
+		buf = append(buf, 0x00)
+	}
+	putUint64(ar.BuyVolume)
+	putUint64(ar.SellVolume)
+	putUint64(uint64(ar.ClearedAtUnix))
+	buf = append(buf, ar.Root[:]...)
+	putUint64(ar.NumPuzzles)
+	return
+}
+
+// Verify checks that the auction result was signed by the exchange with pubkey
+func (ar *AuctionResult) Verify(pubkey [33]byte) (err error) {
+	if len(ar.Signature) == 0 {
+		err = fmt.Errorf("Auction result is not signed")
+		return
+	}
+
+	if err = VerifyServerSignature(ar.SerializeSignable(), ar.Signature, pubkey); err != nil {
+		err = fmt.Errorf("Error verifying auction result: %s", err)
+		return
+	}
+	return
+}
+
+// SetSignResults sets whether auction results are signed with the signing key. They are by default, but
+// signing every result can be turned off if nobody checks them.
+func (s *OpencxAuctionServer) SetSignResults(sign bool) {
+	s.auctionMtx.Lock()
+	s.unsignedResults = !sign
+	s.auctionMtx.Unlock()
+}
+
+// SignResults gets whether auction results are signed with the signing key
+func (s *OpencxAuctionServer) SignResults() (sign bool) {
+	s.auctionMtx.RLock()
+	sign = !s.unsignedResults
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// AuctionResult gets the result of a pair's auction, from its recorded imbalance and its commitment root. The
+// auction has to have closed and cleared. The result is signed if the exchange has a signing key, unless
+// signing results has been turned off.
+func (s *OpencxAuctionServer) AuctionResult(pair *match.Pair, auctionID [32]byte) (result *AuctionResult, err error) {
+	var imbalance *match.AuctionImbalance
+	if imbalance, err = s.AuctionImbalance(pair, auctionID); err != nil {
+		err = fmt.Errorf("Error getting clearing price for auction result: %s", err)
+		return
+	}
+
+	var roots []*match.AuctionRoot
+	if roots, err = s.CommitmentHistory(MaxCommitmentHistory); err != nil {
+		err = fmt.Errorf("Error getting commitment root for auction result: %s", err)
+		return
+	}
+
+	var auctionRoot *match.AuctionRoot
+	for _, root := range roots {
+		if root.AuctionID == auctionID {
+			auctionRoot = root
+			break
+		}
+	}
+
+	if auctionRoot == nil {
+		err = fmt.Errorf("No commitment root for auction %x, it hasn't closed or is too old", auctionID)
+		return
+	}
+
+	result = &AuctionResult{
+		AuctionID:     auctionID,
+		Pair:          *pair,
+		ClearingPrice: imbalance.ClearingPrice,
+		NoMatch:       imbalance.NoMatch,
+		BuyVolume:     imbalance.BuyVolume,
+		SellVolume:    imbalance.SellVolume,
+		ClearedAtUnix: imbalance.ClearedAtUnix,
+		Root:          auctionRoot.Root,
+		NumPuzzles:    auctionRoot.NumPuzzles,
+	}
+
+	// Exchanges without a signing key still give out unsigned results
+	if _, keyErr := s.SigningPubkey(); keyErr != nil || !s.SignResults() {
+		return
+	}
+
+	if result.Signature, err = s.Sign(result.SerializeSignable()); err != nil {
+		err = fmt.Errorf("Error signing auction result: %s", err)
+		return
+	}
+
+	return
+}
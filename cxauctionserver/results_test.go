@@ -0,0 +1,243 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestAuctionResultSignature(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestAuctionResultSignature: %s", err)
+		return
+	}
+
+	var signingKey *koblitz.PrivateKey
+	if signingKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating signing key: %s", err)
+		return
+	}
+
+	if err = s.SetSigningKey(signingKey); err != nil {
+		t.Errorf("Error setting signing key: %s", err)
+		return
+	}
+
+	var pubkey [33]byte
+	if pubkey, err = s.SigningPubkey(); err != nil {
+		t.Errorf("Error getting signing pubkey: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, _, err = s.CurrentPuzzleParams(); err != nil {
+		t.Errorf("Error getting current auction: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var pair match.Pair
+	for _, side := range []string{"buy", "sell"} {
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(side, 1000, 1.0, privkey); err != nil {
+			t.Errorf("Error creating %s order: %s", side, err)
+			return
+		}
+		order.AuctionID = auctionID
+		if err = signTestOrder(order, privkey); err != nil {
+			t.Errorf("Error signing %s order: %s", side, err)
+			return
+		}
+		if err = s.OpencxDB.PlaceAuctionOrder(order); err != nil {
+			t.Errorf("Error placing %s order: %s", side, err)
+			return
+		}
+		pair = order.TradingPair
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error committing orders: %s", err)
+		return
+	}
+
+	// Clearing the auction is what records its result
+	if _, err = s.AuctionResult(&pair, auctionID); err == nil {
+		t.Errorf("Auction that hasn't been cleared should not have a result")
+		return
+	}
+
+	if _, err = s.ClearPairAuction(&pair, auctionID); err != nil {
+		t.Errorf("Error clearing auction: %s", err)
+		return
+	}
+
+	var result *AuctionResult
+	if result, err = s.AuctionResult(&pair, auctionID); err != nil {
+		t.Errorf("Error getting auction result: %s", err)
+		return
+	}
+
+	if result.ClearingPrice != 1.0 || result.BuyVolume != 1000 || result.SellVolume != 1000 {
+		t.Errorf("Auction result should clear 1000 each way at 1.0, got price %f buy %d sell %d", result.ClearingPrice, result.BuyVolume, result.SellVolume)
+		return
+	}
+
+	if err = result.Verify(pubkey); err != nil {
+		t.Errorf("Auction result should verify against the server pubkey: %s", err)
+		return
+	}
+
+	// Changing any part of the result should make it not verify
+	tampered := *result
+	tampered.ClearingPrice = 1.5
+	if err = tampered.Verify(pubkey); err == nil {
+		t.Errorf("Auction result with a tampered clearing price should not verify")
+		return
+	}
+
+	tampered = *result
+	tampered.Root[0] ^= 0xff
+	if err = tampered.Verify(pubkey); err == nil {
+		t.Errorf("Auction result with a tampered root should not verify")
+		return
+	}
+
+	tampered = *result
+	tampered.Pair.AssetWant, tampered.Pair.AssetHave = result.Pair.AssetHave, result.Pair.AssetWant
+	if err = tampered.Verify(pubkey); err == nil {
+		t.Errorf("Auction result with a tampered pair should not verify")
+		return
+	}
+
+	var otherPubkey [33]byte
+	copy(otherPubkey[:], privkey.PubKey().SerializeCompressed())
+	if err = result.Verify(otherPubkey); err == nil {
+		t.Errorf("Auction result should not verify against a different pubkey")
+		return
+	}
+
+	// Turning signing off gives unsigned results
+	s.SetSignResults(false)
+	if result, err = s.AuctionResult(&pair, auctionID); err != nil {
+		t.Errorf("Error getting unsigned auction result: %s", err)
+		return
+	}
+
+	if len(result.Signature) != 0 {
+		t.Errorf("Auction result should not be signed when signing results is off")
+		return
+	}
+
+	if err = result.Verify(pubkey); err == nil {
+		t.Errorf("Unsigned auction result should not verify")
+		return
+	}
+
+	return
+}
+
+func TestClockClearedAuctionResult(t *testing.T) {
+	var err error
+
+	// The long auction time keeps the clock from ticking on its own, so we can tick it
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestClockClearedAuctionResult: %s", err)
+		return
+	}
+
+	var signingKey *koblitz.PrivateKey
+	if signingKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating signing key: %s", err)
+		return
+	}
+
+	if err = s.SetSigningKey(signingKey); err != nil {
+		t.Errorf("Error setting signing key: %s", err)
+		return
+	}
+
+	var pubkey [33]byte
+	if pubkey, err = s.SigningPubkey(); err != nil {
+		t.Errorf("Error getting signing pubkey: %s", err)
+		return
+	}
+
+	var pairs []*match.Pair
+	if pairs, err = match.GenerateAssetPairs(testCoins); err != nil {
+		t.Errorf("Error generating pairs: %s", err)
+		return
+	}
+	s.SetClearingPairs(pairs)
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	var pair match.Pair
+	for _, side := range []string{"buy", "sell"} {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(side, 1000, 1.0, privkey); err != nil {
+			t.Errorf("Error creating %s order: %s", side, err)
+			return
+		}
+
+		order.AuctionID = auctionID
+		if err = signTestOrder(order, privkey); err != nil {
+			t.Errorf("Error signing %s order: %s", side, err)
+			return
+		}
+
+		if err = s.placeSolvedOrder(order, auctionID); err != nil {
+			t.Errorf("Error placing %s order: %s", side, err)
+			return
+		}
+		pair = order.TradingPair
+	}
+
+	// The first tick closes the auction and the next one clears it
+	doneChan := make(chan time.Time, 2)
+	s.auctionTick(doneChan)
+	s.auctionTick(doneChan)
+
+	var result *AuctionResult
+	if result, err = s.AuctionResult(&pair, auctionID); err != nil {
+		t.Errorf("Auction cleared by the clock should have a result: %s", err)
+		return
+	}
+
+	if result.NoMatch || result.ClearingPrice != 1.0 || result.BuyVolume != 1000 || result.SellVolume != 1000 {
+		t.Errorf("Auction result should clear 1000 each way at 1.0, got price %f buy %d sell %d", result.ClearingPrice, result.BuyVolume, result.SellVolume)
+		return
+	}
+
+	if result.ClearedAtUnix == 0 {
+		t.Errorf("Auction result should be stamped with when it cleared")
+		return
+	}
+
+	if err = result.Verify(pubkey); err != nil {
+		t.Errorf("Auction result should verify against the server pubkey: %s", err)
+		return
+	}
+
+	return
+}
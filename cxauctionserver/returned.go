@@ -0,0 +1,63 @@
+package cxauctionserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// ReturnUnmatchedOrders is called after an auction for a pair, with the orders that didn't match and the
+// clearing price. A clearing price of zero means the auction didn't clear. Each order is held with the
+// reason it didn't match, until the user that placed it gets their returned orders.
+func (s *OpencxAuctionServer) ReturnUnmatchedOrders(unmatched []*match.AuctionOrder, clearingPrice float64) (err error) {
+	// The unmatched orders can still be in the order book, and returned orders get changed before they're
+	// handed back, so hold on to copies
+	clones := make([]*match.AuctionOrder, len(unmatched))
+	for i, order := range unmatched {
+		clones[i] = order.Clone()
+	}
+
+	var returned []*match.ReturnedOrder
+	if returned, err = match.ReturnUnmatchedOrders(clones, clearingPrice); err != nil {
+		err = fmt.Errorf("Error working out why orders didn't match: %s", err)
+		return
+	}
+
+	s.returnedMtx.Lock()
+	for _, returnedOrder := range returned {
+		s.returnedOrders[returnedOrder.Order.Pubkey] = append(s.returnedOrders[returnedOrder.Order.Pubkey], returnedOrder)
+	}
+	s.returnedMtx.Unlock()
+
+	logging.Infof("Returned %d unmatched orders", len(returned))
+
+	return
+}
+
+// GetReturnedOrders gets the orders placed by pubkey that didn't match, and why. Once they've been gotten
+// they're forgotten, so each returned order is only handed back once. auctionID must be the current auction,
+// so a request can't be replayed later.
+func (s *OpencxAuctionServer) GetReturnedOrders(pubkey *koblitz.PublicKey, auctionID [32]byte) (returned []*match.ReturnedOrder, err error) {
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction id for returned orders: %s", err)
+		return
+	}
+
+	if currentAuctionID != auctionID {
+		err = fmt.Errorf("Can only get returned orders in the current auction %x, not %x", currentAuctionID, auctionID)
+		return
+	}
+
+	var pubkeyBytes [33]byte
+	copy(pubkeyBytes[:], pubkey.SerializeCompressed())
+
+	s.returnedMtx.Lock()
+	returned = s.returnedOrders[pubkeyBytes]
+	delete(s.returnedOrders, pubkeyBytes)
+	s.returnedMtx.Unlock()
+
+	return
+}
@@ -0,0 +1,93 @@
+package cxauctionserver
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestGetReturnedOrders(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestGetReturnedOrders: %s", err)
+		return
+	}
+
+	var callerKey, otherKey *koblitz.PrivateKey
+	if callerKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating caller key: %s", err)
+		return
+	}
+	if otherKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating other key: %s", err)
+		return
+	}
+
+	var callerOrder, otherOrder *match.AuctionOrder
+	if callerOrder, err = newTestContinuousOrder("buy", 1000, 0.5, callerKey); err != nil {
+		t.Errorf("Error creating caller order: %s", err)
+		return
+	}
+	if otherOrder, err = newTestContinuousOrder("sell", 1000, 2.0, otherKey); err != nil {
+		t.Errorf("Error creating other order: %s", err)
+		return
+	}
+
+	if err = s.ReturnUnmatchedOrders([]*match.AuctionOrder{callerOrder, otherOrder}, 1.0); err != nil {
+		t.Errorf("Error returning unmatched orders: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	// Getting returned orders for an auction that isn't current should not work
+	wrongAuctionID := auctionID
+	wrongAuctionID[0]++
+	if _, err = s.GetReturnedOrders(callerKey.PubKey(), wrongAuctionID); err == nil {
+		t.Errorf("Should not be able to get returned orders for an auction that isn't current")
+		return
+	}
+
+	var returned []*match.ReturnedOrder
+	if returned, err = s.GetReturnedOrders(callerKey.PubKey(), auctionID); err != nil {
+		t.Errorf("Error getting returned orders: %s", err)
+		return
+	}
+
+	if len(returned) != 1 || !bytes.Equal(returned[0].Order.Serialize(), callerOrder.Serialize()) {
+		t.Errorf("Caller should have gotten back only their own order, got %d orders", len(returned))
+		return
+	}
+
+	// The server holds on to a copy, so handing it back can't change the order the caller still has
+	if returned[0].Order == callerOrder {
+		t.Errorf("Returned order should be a copy of the unmatched order, not the same order")
+		return
+	}
+
+	if returned[0].Reason != match.ReturnPriceTooLow {
+		t.Errorf("Caller's order should have been returned as %s, got %s", match.ReturnPriceTooLow, returned[0].Reason)
+		return
+	}
+
+	// Orders are only handed back once
+	if returned, err = s.GetReturnedOrders(callerKey.PubKey(), auctionID); err != nil {
+		t.Errorf("Error getting returned orders again: %s", err)
+		return
+	}
+
+	if len(returned) != 0 {
+		t.Errorf("Returned orders should only be handed back once, got %d orders the second time", len(returned))
+		return
+	}
+
+	return
+}
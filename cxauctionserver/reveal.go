@@ -0,0 +1,230 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/crypto/vdf"
+	"github.com/mit-dci/opencx/match"
+)
+
+// maxStoredAuctionKeys is how many auctions we keep order keys for, the oldest auction is forgotten once
+// keys for a new one are kept.
+const maxStoredAuctionKeys = 64
+
+// RevealPolicy is which keys are revealed once an auction is over
+type RevealPolicy uint8
+
+const (
+	// RevealAll reveals the key of every order placed in the auction. This is the default, so anyone can
+	// decrypt every order and check the auction was run fairly.
+	RevealAll RevealPolicy = iota
+	// RevealMatchedOnly only reveals the keys of the orders that filled in the auction, so orders that didn't
+	// match stay private. The auction has to be cleared before its keys can be revealed.
+	RevealMatchedOnly
+)
+
+// String returns the name of the reveal policy
+func (p RevealPolicy) String() string {
+	switch p {
+	case RevealAll:
+		return "all"
+	case RevealMatchedOnly:
+		return "matched"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(p))
+}
+
+// RevealPolicyFromString parses a reveal policy from its name
+func RevealPolicyFromString(name string) (policy RevealPolicy, err error) {
+	switch name {
+	case "all":
+		policy = RevealAll
+	case "matched":
+		policy = RevealMatchedOnly
+	default:
+		err = fmt.Errorf("Unknown reveal policy %s, must be all or matched", name)
+	}
+	return
+}
+
+// RevealedKey is the key that decrypts an order placed in an auction, so anyone can decrypt the order with
+// EncryptedAuctionOrder.DecryptWithKey instead of solving its puzzle
+type RevealedKey struct {
+	// EncryptedOrderID is the ID of the encrypted order the key decrypts, from EncryptedAuctionOrder.OrderID
+	EncryptedOrderID [32]byte `json:"encryptedorderid"`
+	// OrderHash is the hash of the order it decrypts to, the same as in the order's fill
+	OrderHash [32]byte `json:"orderhash"`
+	Key       []byte   `json:"key"`
+	// Solution is the VDF output and proof for orders with a VDF puzzle, which can be checked with the
+	// order's vdf.PuzzleVDF.Verify to show the puzzle was solved. It's nil for other puzzles.
+	Solution *vdf.Solution `json:"solution,omitempty"`
+}
+
+// auctionKeys are the keys kept for the orders placed in an auction, and when the auction closed. closedAt is
+// zero until it closes.
+type auctionKeys struct {
+	keys     []*RevealedKey
+	closedAt time.Time
+}
+
+// SetRevealPolicy sets which keys are revealed once an auction is over
+func (s *OpencxAuctionServer) SetRevealPolicy(policy RevealPolicy) (err error) {
+	if policy != RevealAll && policy != RevealMatchedOnly {
+		err = fmt.Errorf("Cannot set unknown reveal policy %s", policy)
+		return
+	}
+
+	s.keysMtx.Lock()
+	s.revealPolicy = policy
+	s.keysMtx.Unlock()
+	return
+}
+
+// RevealPolicy gets which keys are revealed once an auction is over
+func (s *OpencxAuctionServer) RevealPolicy() (policy RevealPolicy, err error) {
+	s.keysMtx.Lock()
+	policy = s.revealPolicy
+	s.keysMtx.Unlock()
+	return
+}
+
+// SetRevealGrace sets how long after an auction closes its keys wait for orders in it that are still being
+// solved, so the keys are revealed all together instead of only the ones solved so far. Keys are revealed once
+// every order in the auction is solved or the grace period is over, whichever is first. Zero means keys are
+// revealed as soon as the auction closes, which is the default.
+func (s *OpencxAuctionServer) SetRevealGrace(grace time.Duration) (err error) {
+	if grace < 0 {
+		err = fmt.Errorf("Reveal grace period cannot be negative, got %s", grace)
+		return
+	}
+
+	s.keysMtx.Lock()
+	s.revealGrace = grace
+	s.keysMtx.Unlock()
+	return
+}
+
+// keysFor gets the keys kept for auctionID, forgetting the oldest auction if we're storing too many to keep
+// a new one. The caller should be holding keysMtx.
+func (s *OpencxAuctionServer) keysFor(auctionID [32]byte) (kept *auctionKeys) {
+	var found bool
+	if kept, found = s.orderKeys[auctionID]; found {
+		return
+	}
+
+	if len(s.keyAuctions) >= maxStoredAuctionKeys {
+		delete(s.orderKeys, s.keyAuctions[0])
+		s.keyAuctions = s.keyAuctions[1:]
+	}
+
+	kept = new(auctionKeys)
+	s.orderKeys[auctionID] = kept
+	s.keyAuctions = append(s.keyAuctions, auctionID)
+	return
+}
+
+// markAuctionClosed records when an auction closed, which is when its reveal grace period starts
+func (s *OpencxAuctionServer) markAuctionClosed(auctionID [32]byte) {
+	s.keysMtx.Lock()
+	s.keysFor(auctionID).closedAt = time.Now()
+	s.keysMtx.Unlock()
+	return
+}
+
+// keepOrderKey keeps the key that a placed order was solved with, so it can be revealed once the order's
+// auction is over
+func (s *OpencxAuctionServer) keepOrderKey(order *match.AuctionOrder, result *match.OrderPuzzleResult) (err error) {
+	if result.Encrypted == nil || len(result.Key) == 0 {
+		return
+	}
+
+	revealed := &RevealedKey{
+		OrderHash: order.Hash(),
+		Key:       result.Key,
+		Solution:  result.Solution,
+	}
+	if revealed.EncryptedOrderID, err = result.Encrypted.OrderID(); err != nil {
+		err = fmt.Errorf("Error getting ID of encrypted order to keep its key: %s", err)
+		return
+	}
+
+	s.keysMtx.Lock()
+	kept := s.keysFor(order.AuctionID)
+	kept.keys = append(kept.keys, revealed)
+	s.keysMtx.Unlock()
+	return
+}
+
+// RevealAuctionKeys gets the keys of the orders placed in an auction that is over, following the reveal
+// policy. With RevealMatchedOnly, only keys of the orders that filled are revealed, so the auction has to have
+// been cleared. If orders in the auction are still being solved and the reveal grace period isn't over, the
+// keys aren't ready yet and an error is returned right away, so the caller can try again later.
+func (s *OpencxAuctionServer) RevealAuctionKeys(auctionID [32]byte) (keys []*RevealedKey, err error) {
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction for revealing keys: %s", err)
+		return
+	}
+
+	// Revealing keys while the auction is running would let anyone read the orders in it
+	if auctionID == currentAuctionID || s.isOpenPairAuction(auctionID) {
+		err = fmt.Errorf("Auction %x is still running, its keys can't be revealed until it's over", auctionID)
+		return
+	}
+
+	// Orders are only done being solved once their keys are kept, so check them before getting the keys
+	unsolved := s.solves.unsolvedFor(auctionID)
+
+	s.keysMtx.Lock()
+	grace := s.revealGrace
+	policy := s.revealPolicy
+	kept, found := s.orderKeys[auctionID]
+	var closedAt time.Time
+	if found {
+		closedAt = kept.closedAt
+		keys = make([]*RevealedKey, len(kept.keys))
+		copy(keys, kept.keys)
+	}
+	s.keysMtx.Unlock()
+
+	if !found {
+		err = fmt.Errorf("No keys are kept for auction %x, it's unknown or was forgotten", auctionID)
+		return
+	}
+
+	// Keys are revealed all together, so they wait for the orders that are still being solved
+	if waited := time.Since(closedAt); unsolved > 0 && waited < grace {
+		keys = nil
+		err = fmt.Errorf("Keys of auction %x aren't ready, %d of its orders are still being solved. Try again in %s", auctionID, unsolved, grace-waited)
+		return
+	}
+
+	if policy == RevealAll {
+		return
+	}
+
+	var fills []*match.AuctionFill
+	if fills, err = s.AuctionFills(auctionID); err != nil {
+		keys = nil
+		err = fmt.Errorf("Only keys of matched orders are revealed, so auction %x has to be cleared first: %s", auctionID, err)
+		return
+	}
+
+	matched := make(map[[32]byte]bool)
+	for _, fill := range fills {
+		if fill.AmountFilled > 0 {
+			matched[fill.OrderHash] = true
+		}
+	}
+
+	var matchedKeys []*RevealedKey
+	for _, key := range keys {
+		if matched[key.OrderHash] {
+			matchedKeys = append(matchedKeys, key)
+		}
+	}
+	keys = matchedKeys
+
+	return
+}
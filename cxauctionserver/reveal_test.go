@@ -0,0 +1,504 @@
+package cxauctionserver
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/crypto/vdf"
+	"github.com/mit-dci/opencx/match"
+)
+
+// placeTestRevealOrders places a buy and a sell that cross at 2 in the current auction, and a buy at 1 that
+// doesn't match, then waits for them to be solved and placed. It returns the encrypted orders by their ID,
+// and the hash of the order that doesn't match.
+func placeTestRevealOrders(s *OpencxAuctionServer) (auctionID [32]byte, encOrders map[[32]byte]*match.EncryptedAuctionOrder, unmatchedHash [32]byte, err error) {
+	var params match.PuzzleParams
+	if auctionID, params, err = s.CurrentPuzzleParams(); err != nil {
+		return
+	}
+
+	encOrders = make(map[[32]byte]*match.EncryptedAuctionOrder)
+	for _, submission := range []struct {
+		side       string
+		amountHave uint64
+		price      float64
+	}{
+		{"buy", 1000, 2.0},
+		{"sell", 2000, 2.0},
+		{"buy", 1000, 1.0},
+	} {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			return
+		}
+
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(submission.side, submission.amountHave, submission.price, privkey); err != nil {
+			return
+		}
+		order.AuctionID = auctionID
+		if err = signTestOrder(order, privkey); err != nil {
+			return
+		}
+		if submission.price == 1.0 {
+			unmatchedHash = order.Hash()
+		}
+
+		var encOrder *match.EncryptedAuctionOrder
+		if encOrder, err = order.TurnIntoEncryptedOrderWithParams(&params); err != nil {
+			return
+		}
+
+		var encID [32]byte
+		if encID, err = encOrder.OrderID(); err != nil {
+			return
+		}
+		encOrders[encID] = encOrder
+
+		if err = s.PlacePuzzledOrder(encOrder); err != nil {
+			return
+		}
+	}
+
+	// Keys are kept right after the orders are placed
+	deadline := time.Now().Add(time.Minute)
+	for {
+		var kept int
+		s.keysMtx.Lock()
+		if auctionKeys, found := s.orderKeys[auctionID]; found {
+			kept = len(auctionKeys.keys)
+		}
+		s.keysMtx.Unlock()
+		if kept == len(encOrders) {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			err = fmt.Errorf("Keys of %d orders were never kept, got %d", len(encOrders), kept)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// checkRevealedKeys checks that every revealed key decrypts its encrypted order to the order it says it does
+func checkRevealedKeys(keys []*RevealedKey, encOrders map[[32]byte]*match.EncryptedAuctionOrder) (err error) {
+	for _, key := range keys {
+		encOrder, found := encOrders[key.EncryptedOrderID]
+		if !found {
+			err = fmt.Errorf("Revealed key for unknown encrypted order %x", key.EncryptedOrderID)
+			return
+		}
+
+		var orderBytes []byte
+		if orderBytes, err = encOrder.DecryptWithKey(key.Key); err != nil {
+			err = fmt.Errorf("Revealed key doesn't decrypt its order: %s", err)
+			return
+		}
+
+		order := new(match.AuctionOrder)
+		if err = order.Deserialize(orderBytes); err != nil {
+			err = fmt.Errorf("Error deserializing order decrypted with revealed key: %s", err)
+			return
+		}
+
+		if order.Hash() != key.OrderHash {
+			err = fmt.Errorf("Revealed key decrypts to order %x, not %x", order.Hash(), key.OrderHash)
+			return
+		}
+	}
+	return
+}
+
+func TestRevealAllKeys(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestRevealAllKeys: %s", err)
+		return
+	}
+
+	if err = s.SetRevealPolicy(RevealAll); err != nil {
+		t.Errorf("Error setting reveal policy: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	var encOrders map[[32]byte]*match.EncryptedAuctionOrder
+	if auctionID, encOrders, _, err = placeTestRevealOrders(s); err != nil {
+		t.Errorf("Error placing orders: %s", err)
+		return
+	}
+
+	if _, err = s.RevealAuctionKeys(auctionID); err == nil {
+		t.Errorf("Keys should not be revealed while the auction is running")
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error closing auction: %s", err)
+		return
+	}
+
+	// Every key is revealed once the auction is over, even before it's cleared
+	var keys []*RevealedKey
+	if keys, err = s.RevealAuctionKeys(auctionID); err != nil {
+		t.Errorf("Error revealing keys: %s", err)
+		return
+	}
+
+	if len(keys) != len(encOrders) {
+		t.Errorf("Every key should be revealed, got %d of %d", len(keys), len(encOrders))
+		return
+	}
+
+	if err = checkRevealedKeys(keys, encOrders); err != nil {
+		t.Errorf("Error checking revealed keys: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestRevealMatchedOnlyKeys(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestRevealMatchedOnlyKeys: %s", err)
+		return
+	}
+
+	if err = s.SetRevealPolicy(RevealMatchedOnly); err != nil {
+		t.Errorf("Error setting reveal policy: %s", err)
+		return
+	}
+
+	var auctionID, unmatchedHash [32]byte
+	var encOrders map[[32]byte]*match.EncryptedAuctionOrder
+	if auctionID, encOrders, unmatchedHash, err = placeTestRevealOrders(s); err != nil {
+		t.Errorf("Error placing orders: %s", err)
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error closing auction: %s", err)
+		return
+	}
+
+	// Which orders matched isn't known until the auction is cleared
+	if _, err = s.RevealAuctionKeys(auctionID); err == nil {
+		t.Errorf("Keys of matched orders should not be revealed before the auction is cleared")
+		return
+	}
+
+	pair := match.Pair{AssetWant: match.BTC, AssetHave: match.VTCTest}
+	if _, err = s.ClearPairAuction(&pair, auctionID); err != nil {
+		t.Errorf("Error clearing auction: %s", err)
+		return
+	}
+
+	var keys []*RevealedKey
+	if keys, err = s.RevealAuctionKeys(auctionID); err != nil {
+		t.Errorf("Error revealing keys: %s", err)
+		return
+	}
+
+	if len(keys) != 2 {
+		t.Errorf("Only the keys of the 2 matched orders should be revealed, got %d", len(keys))
+		return
+	}
+
+	for _, key := range keys {
+		if key.OrderHash == unmatchedHash {
+			t.Errorf("Key of the order that didn't match should not be revealed")
+			return
+		}
+	}
+
+	if err = checkRevealedKeys(keys, encOrders); err != nil {
+		t.Errorf("Error checking revealed keys: %s", err)
+		return
+	}
+
+	return
+}
+
+// holdTestSolve puts order in the solve queue as if it were still waiting to be solved, holding it so no solve
+// worker picks it up. release takes it back out.
+func holdTestSolve(s *OpencxAuctionServer, order *match.EncryptedAuctionOrder) (release func()) {
+	s.solves.mtx.Lock()
+	heldAuction, holding := s.solves.heldAuction, s.solves.holding
+	s.solves.pending = append(s.solves.pending, &solveItem{order: order})
+	s.solves.heldAuction = order.IntendedAuction
+	s.solves.holding = true
+	s.solves.mtx.Unlock()
+
+	release = func() {
+		s.solves.mtx.Lock()
+		for i, item := range s.solves.pending {
+			if item.order == order {
+				s.solves.remove(i)
+				break
+			}
+		}
+		s.solves.heldAuction, s.solves.holding = heldAuction, holding
+		s.solves.mtx.Unlock()
+	}
+	return
+}
+
+func TestRevealKeysWaitForSolves(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestRevealKeysWaitForSolves: %s", err)
+		return
+	}
+
+	if err = s.SetRevealGrace(time.Hour); err != nil {
+		t.Errorf("Error setting reveal grace period: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	var params match.PuzzleParams
+	if auctionID, params, err = s.CurrentPuzzleParams(); err != nil {
+		t.Errorf("Error getting puzzle params: %s", err)
+		return
+	}
+
+	var encOrders map[[32]byte]*match.EncryptedAuctionOrder
+	if _, encOrders, _, err = placeTestRevealOrders(s); err != nil {
+		t.Errorf("Error placing orders: %s", err)
+		return
+	}
+
+	// One more order that is still waiting to be solved when the auction closes
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var lateOrder *match.AuctionOrder
+	if lateOrder, err = newTestContinuousOrder("sell", 500, 3.0, privkey); err != nil {
+		t.Errorf("Error creating late order: %s", err)
+		return
+	}
+	lateOrder.AuctionID = auctionID
+	if err = signTestOrder(lateOrder, privkey); err != nil {
+		t.Errorf("Error signing late order: %s", err)
+		return
+	}
+
+	var lateEncOrder *match.EncryptedAuctionOrder
+	if lateEncOrder, err = lateOrder.TurnIntoEncryptedOrderWithParams(&params); err != nil {
+		t.Errorf("Error encrypting late order: %s", err)
+		return
+	}
+
+	var lateID [32]byte
+	if lateID, err = lateEncOrder.OrderID(); err != nil {
+		t.Errorf("Error getting ID of late order: %s", err)
+		return
+	}
+	encOrders[lateID] = lateEncOrder
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error closing auction: %s", err)
+		return
+	}
+
+	release := holdTestSolve(s, lateEncOrder)
+
+	// The keys aren't ready while the late order is unsolved, and that doesn't block
+	start := time.Now()
+	if _, err = s.RevealAuctionKeys(auctionID); err == nil {
+		t.Errorf("Keys should not be revealed while an order in the auction is still being solved")
+		release()
+		return
+	}
+
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Keys that aren't ready should fail right away, but it took %s", elapsed)
+		release()
+		return
+	}
+
+	// Once the late order is solved every key is revealed together, without waiting for the grace period
+	release()
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{solveOrder(lateEncOrder)})
+
+	var keys []*RevealedKey
+	if keys, err = s.RevealAuctionKeys(auctionID); err != nil {
+		t.Errorf("Error revealing keys once every order is solved: %s", err)
+		return
+	}
+
+	if len(keys) != len(encOrders) {
+		t.Errorf("Every key should be revealed together, including the late one, got %d of %d", len(keys), len(encOrders))
+		return
+	}
+
+	if err = checkRevealedKeys(keys, encOrders); err != nil {
+		t.Errorf("Error checking revealed keys: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestRevealGracePeriodEnds(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestRevealGracePeriodEnds: %s", err)
+		return
+	}
+
+	if err = s.SetRevealGrace(-time.Second); err == nil {
+		t.Errorf("Negative reveal grace period should not be allowed")
+		return
+	}
+
+	grace := 200 * time.Millisecond
+	if err = s.SetRevealGrace(grace); err != nil {
+		t.Errorf("Error setting reveal grace period: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	var encOrders map[[32]byte]*match.EncryptedAuctionOrder
+	if auctionID, encOrders, _, err = placeTestRevealOrders(s); err != nil {
+		t.Errorf("Error placing orders: %s", err)
+		return
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error closing auction: %s", err)
+		return
+	}
+
+	// An order that never gets solved
+	release := holdTestSolve(s, &match.EncryptedAuctionOrder{IntendedAuction: auctionID})
+	defer release()
+
+	if _, err = s.RevealAuctionKeys(auctionID); err == nil {
+		t.Errorf("Keys should not be revealed during the grace period while an order is still being solved")
+		return
+	}
+
+	// Once the grace period is over the keys of the solved orders are revealed without it
+	time.Sleep(grace)
+
+	var keys []*RevealedKey
+	if keys, err = s.RevealAuctionKeys(auctionID); err != nil {
+		t.Errorf("Error revealing keys after the grace period: %s", err)
+		return
+	}
+
+	if len(keys) != len(encOrders) {
+		t.Errorf("Keys of the solved orders should be revealed, got %d of %d", len(keys), len(encOrders))
+		return
+	}
+
+	return
+}
+
+func TestRevealVDFSolution(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestRevealVDFSolution: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	var params match.PuzzleParams
+	if auctionID, params, err = s.CurrentPuzzleParams(); err != nil {
+		t.Errorf("Error getting puzzle params: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	if order, err = newTestContinuousOrder("buy", 1000, 2.0, privkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+	order.AuctionID = auctionID
+	if err = signTestOrder(order, privkey); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	var encOrder *match.EncryptedAuctionOrder
+	if encOrder, err = order.TurnIntoVDFEncryptedOrderWithParams(&params); err != nil {
+		t.Errorf("Error creating VDF encrypted order: %s", err)
+		return
+	}
+
+	if err = s.PlacePuzzledOrder(encOrder); err != nil {
+		t.Errorf("VDF order with the auction's params should be placed: %s", err)
+		return
+	}
+
+	// Keys are kept right after the order is placed
+	deadline := time.Now().Add(time.Minute)
+	for kept := 0; kept == 0; {
+		s.keysMtx.Lock()
+		if auctionKeys, found := s.orderKeys[auctionID]; found {
+			kept = len(auctionKeys.keys)
+		}
+		s.keysMtx.Unlock()
+
+		if time.Now().After(deadline) {
+			t.Errorf("Key of the VDF order was never kept")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error closing auction: %s", err)
+		return
+	}
+
+	var keys []*RevealedKey
+	if keys, err = s.RevealAuctionKeys(auctionID); err != nil {
+		t.Errorf("Error revealing keys: %s", err)
+		return
+	}
+
+	if len(keys) != 1 || keys[0].Solution == nil {
+		t.Errorf("The VDF order's key should be revealed with its solution, got %v", keys)
+		return
+	}
+
+	// Anyone can check the revealed key came from solving the puzzle, without solving it
+	var answer []byte
+	if answer, err = encOrder.OrderPuzzle.(*vdf.PuzzleVDF).Verify(keys[0].Solution); err != nil {
+		t.Errorf("Revealed VDF solution should verify: %s", err)
+		return
+	}
+
+	if !bytes.Equal(answer, keys[0].Key) {
+		t.Errorf("Revealed VDF solution gives key %x, but the revealed key is %x", answer, keys[0].Key)
+		return
+	}
+
+	return
+}
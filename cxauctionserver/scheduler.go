@@ -0,0 +1,146 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// auctionScheduler limits how many pair auctions can be running at the same time. Auctions that can't
+// start because the limit has been reached wait in line, and start in the order they were scheduled.
+type auctionScheduler struct {
+	// maxActive is the most auctions that can run at once, zero means there is no limit
+	maxActive uint64
+	active    uint64
+	queue     []chan struct{}
+	mtx       *sync.Mutex
+}
+
+// newAuctionScheduler creates a new auction scheduler that lets at most maxActive auctions run at once
+func newAuctionScheduler(maxActive uint64) (as *auctionScheduler) {
+	as = &auctionScheduler{
+		maxActive: maxActive,
+		mtx:       new(sync.Mutex),
+	}
+	return
+}
+
+// hasRoom returns whether or not another auction can start, the caller should be holding mtx
+func (as *auctionScheduler) hasRoom() bool {
+	return as.maxActive == 0 || as.active < as.maxActive
+}
+
+// startQueued starts queued auctions while there is room for them, the caller should be holding mtx
+func (as *auctionScheduler) startQueued() {
+	for len(as.queue) > 0 && as.hasRoom() {
+		close(as.queue[0])
+		as.queue = as.queue[1:]
+		as.active++
+	}
+	return
+}
+
+// acquire blocks until there is room for another auction, and then counts it as active
+func (as *auctionScheduler) acquire() {
+	as.mtx.Lock()
+	// Don't skip the line if other auctions are already waiting
+	if len(as.queue) == 0 && as.hasRoom() {
+		as.active++
+		as.mtx.Unlock()
+		return
+	}
+
+	wait := make(chan struct{})
+	as.queue = append(as.queue, wait)
+	as.mtx.Unlock()
+
+	<-wait
+	return
+}
+
+// release marks an auction as done, starting the next queued auction if there is one
+func (as *auctionScheduler) release() {
+	as.mtx.Lock()
+	as.active--
+	as.startQueued()
+	as.mtx.Unlock()
+	return
+}
+
+// setMaxActive changes the limit, starting queued auctions if the limit went up
+func (as *auctionScheduler) setMaxActive(maxActive uint64) {
+	as.mtx.Lock()
+	as.maxActive = maxActive
+	as.startQueued()
+	as.mtx.Unlock()
+	return
+}
+
+// status returns the limit, the number of active auctions, and the number of queued auctions
+func (as *auctionScheduler) status() (maxActive uint64, active uint64, queued uint64) {
+	as.mtx.Lock()
+	maxActive = as.maxActive
+	active = as.active
+	queued = uint64(len(as.queue))
+	as.mtx.Unlock()
+	return
+}
+
+// SetMaxConcurrentAuctions sets the most pair auctions that can run at the same time, to bound
+// resource use. Once the limit is reached, auctions for other pairs are queued until one finishes.
+// Zero means there is no limit, which is the default.
+func (s *OpencxAuctionServer) SetMaxConcurrentAuctions(maxAuctions uint64) {
+	s.auctionSlots.setMaxActive(maxAuctions)
+	logging.Infof("Set max concurrent auctions to %d", maxAuctions)
+	return
+}
+
+// MaxConcurrentAuctions gets the most pair auctions that can run at the same time, zero means there is no limit
+func (s *OpencxAuctionServer) MaxConcurrentAuctions() (maxAuctions uint64, err error) {
+	maxAuctions, _, _ = s.auctionSlots.status()
+	return
+}
+
+// ActiveAuctions gets the number of pair auctions that are currently running, and the number that are
+// queued waiting for a running auction to finish.
+func (s *OpencxAuctionServer) ActiveAuctions() (active uint64, queued uint64, err error) {
+	_, active, queued = s.auctionSlots.status()
+	return
+}
+
+// RunPairAuctions runs runAuction for every pair, each in its own goroutine, while making sure no more than
+// the max concurrent auctions are running at once. Pairs are started in order, and this returns once every
+// auction is done. If any of the auctions fail, the errors are returned together.
+func (s *OpencxAuctionServer) RunPairAuctions(pairs []*match.Pair, runAuction func(pair *match.Pair) error) (err error) {
+	var wg sync.WaitGroup
+	errMtx := new(sync.Mutex)
+	var auctionErrs []error
+
+	for _, pair := range pairs {
+		// Wait for our turn before starting, so pairs start in order
+		s.auctionSlots.acquire()
+
+		wg.Add(1)
+		go func(pair *match.Pair) {
+			defer wg.Done()
+			defer s.auctionSlots.release()
+
+			if auctionErr := runAuction(pair); auctionErr != nil {
+				errMtx.Lock()
+				auctionErrs = append(auctionErrs, fmt.Errorf("Error running auction for %s: %s", pair.PrettyString(), auctionErr))
+				errMtx.Unlock()
+			}
+		}(pair)
+	}
+
+	wg.Wait()
+
+	if len(auctionErrs) != 0 {
+		err = fmt.Errorf("%d of %d pair auctions failed: %v", len(auctionErrs), len(pairs), auctionErrs)
+		return
+	}
+
+	return
+}
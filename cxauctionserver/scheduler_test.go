@@ -0,0 +1,113 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestMaxConcurrentAuctions(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestMaxConcurrentAuctions: %s", err)
+		return
+	}
+
+	var pairs []*match.Pair
+	if pairs, err = match.GenerateAssetPairs(testCoins); err != nil {
+		t.Errorf("Error generating pairs for max concurrent auctions test: %s", err)
+		return
+	}
+
+	// run every pair a few times so there's always something waiting in line
+	var allPairs []*match.Pair
+	for i := 0; i < 4; i++ {
+		allPairs = append(allPairs, pairs...)
+	}
+
+	const maxAuctions = 2
+	s.SetMaxConcurrentAuctions(maxAuctions)
+
+	countMtx := new(sync.Mutex)
+	var active, mostActive, ran int
+	runAuction := func(pair *match.Pair) (err error) {
+		countMtx.Lock()
+		active++
+		if active > mostActive {
+			mostActive = active
+		}
+		ran++
+		countMtx.Unlock()
+
+		// make sure auctions overlap
+		time.Sleep(5 * time.Millisecond)
+
+		countMtx.Lock()
+		active--
+		countMtx.Unlock()
+		return
+	}
+
+	if err = s.RunPairAuctions(allPairs, runAuction); err != nil {
+		t.Errorf("Error running pair auctions: %s", err)
+		return
+	}
+
+	if mostActive > maxAuctions {
+		t.Errorf("There were %d auctions running at once, but the max is %d", mostActive, maxAuctions)
+		return
+	}
+
+	if ran != len(allPairs) {
+		t.Errorf("Only %d of %d queued auctions ran", ran, len(allPairs))
+		return
+	}
+
+	var stillActive, queued uint64
+	if stillActive, queued, err = s.ActiveAuctions(); err != nil {
+		t.Errorf("Error getting active auctions: %s", err)
+		return
+	}
+
+	if stillActive != 0 || queued != 0 {
+		t.Errorf("All auctions should be done, but %d are active and %d are queued", stillActive, queued)
+		return
+	}
+
+	return
+}
+
+func TestRunPairAuctionsErrors(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestRunPairAuctionsErrors: %s", err)
+		return
+	}
+
+	var pairs []*match.Pair
+	if pairs, err = match.GenerateAssetPairs(testCoins); err != nil {
+		t.Errorf("Error generating pairs for pair auction error test: %s", err)
+		return
+	}
+
+	s.SetMaxConcurrentAuctions(1)
+
+	failAuction := func(pair *match.Pair) (err error) {
+		err = fmt.Errorf("auction failed")
+		return
+	}
+
+	if err = s.RunPairAuctions(pairs, failAuction); err == nil {
+		t.Errorf("Failed pair auctions should return an error")
+		return
+	}
+
+	return
+}
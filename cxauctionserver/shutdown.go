@@ -0,0 +1,57 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// DefaultShutdownTimeout is how long shutting down waits for orders being solved by default
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Shutdown stops the server from taking new puzzled orders and stops the clocks of pair auctions, and waits at
+// most timeout for the orders it already took to be solved and placed in the order book, so orders aren't lost
+// when the exchange stops. Zero means it waits for as long as that takes. Any order that isn't solved in time is returned. Those are still in the
+// puzzle book, which is how the exchange commits to them, so they can be solved again after a restart.
+func (s *OpencxAuctionServer) Shutdown(timeout time.Duration) (unsolved []*match.EncryptedAuctionOrder, err error) {
+	if timeout < 0 {
+		err = fmt.Errorf("Shutdown timeout cannot be negative, got %s", timeout)
+		return
+	}
+
+	drained := s.solves.close()
+
+	// Nothing closes or clears pair auctions once the server is shutting down
+	s.stopPairAuctionClocks()
+
+	// Orders queued for the next auction aren't anywhere but memory, and there won't be a next auction
+	s.queuedMtx.Lock()
+	var dropped int
+	for auctionID, queued := range s.queuedOrders {
+		dropped += len(queued)
+		delete(s.queuedOrders, auctionID)
+	}
+	s.queuedMtx.Unlock()
+	if dropped > 0 {
+		logging.Errorf("Shutting down with %d orders queued for the next auction, they were never placed", dropped)
+	}
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case <-drained:
+		logging.Infof("Every order was solved, done shutting down")
+	case <-deadline:
+		unsolved = s.solves.unsolved()
+		logging.Errorf("Shutdown timed out after %s with %d orders not solved, they're still in the puzzle book", timeout, len(unsolved))
+	}
+
+	return
+}
@@ -0,0 +1,181 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestShutdownDrainsSolves(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestShutdownDrainsSolves: %s", err)
+		return
+	}
+
+	numOrders := 8
+	var order *match.AuctionOrder
+	var encOrder *match.EncryptedAuctionOrder
+	for i := 0; i < numOrders; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+
+		if order, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+			t.Errorf("Error creating puzzled order: %s", err)
+			return
+		}
+
+		if err = s.PlacePuzzledOrder(encOrder); err != nil {
+			t.Errorf("Error placing puzzled order: %s", err)
+			return
+		}
+	}
+
+	var unsolved []*match.EncryptedAuctionOrder
+	if unsolved, err = s.Shutdown(time.Minute); err != nil {
+		t.Errorf("Error shutting down: %s", err)
+		return
+	}
+
+	if len(unsolved) != 0 {
+		t.Errorf("Shutdown should have waited for every order to be solved, %d were not", len(unsolved))
+		return
+	}
+
+	// Once shutdown returns, the solved orders should already be in the order book
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, order.AuctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != numOrders {
+		t.Errorf("Order book should have all %d orders after shutting down, got %d", numOrders, len(buyOrders))
+		return
+	}
+
+	// No new orders once it's shutting down
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	if _, encOrder, err = newTestNextAuctionOrder(s, privkey); err != nil {
+		t.Errorf("Error creating puzzled order: %s", err)
+		return
+	}
+
+	if err = s.PlacePuzzledOrder(encOrder); err == nil {
+		t.Errorf("Placing a puzzled order after shutting down should fail")
+		return
+	}
+
+	return
+}
+
+func TestSolveQueueCloseUnsolved(t *testing.T) {
+	q := newSolveQueue(0, RejectNew)
+
+	for i := 0; i < 3; i++ {
+		if _, err := q.push(&match.EncryptedAuctionOrder{IntendedAuction: [32]byte{byte(i)}}, 0, [32]byte{}, nil); err != nil {
+			t.Errorf("Error pushing order: %s", err)
+			return
+		}
+	}
+
+	solving := q.pop()
+	drained := q.close()
+
+	if _, err := q.push(new(match.EncryptedAuctionOrder), 0, [32]byte{}, nil); err != errSolveQueueClosed {
+		t.Errorf("Closed solve queue should reject new orders, got %v", err)
+		return
+	}
+
+	select {
+	case <-drained:
+		t.Errorf("Solve queue should not be drained with orders left")
+		return
+	default:
+	}
+
+	// Nothing solves the pending orders, so they're unsolved along with the one being solved
+	unsolved := q.unsolved()
+	if len(unsolved) != 3 {
+		t.Errorf("Solve queue should have 3 unsolved orders, got %d", len(unsolved))
+		return
+	}
+
+	q.done(solving)
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Errorf("Solve queue should be drained once the last order is done")
+		return
+	}
+
+	return
+}
+
+func TestShutdownStopsPairAuctionClocks(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestShutdownStopsPairAuctionClocks: %s", err)
+		return
+	}
+
+	pair := match.Pair{
+		AssetWant: match.BTC,
+		AssetHave: match.VTCTest,
+	}
+
+	// The pair's auctions last 10 milliseconds, so its clock ticks quickly
+	if err = s.StartPairAuction(&pair, uint64(10*time.Millisecond/time.Microsecond)); err != nil {
+		t.Errorf("Error starting pair auction: %s", err)
+		return
+	}
+
+	if _, err = s.Shutdown(time.Minute); err != nil {
+		t.Errorf("Error shutting down: %s", err)
+		return
+	}
+
+	// Let a tick that was already running finish
+	time.Sleep(100 * time.Millisecond)
+
+	var stoppedAuctionID [32]byte
+	if stoppedAuctionID, _, _, err = s.CurrentPairAuctionState(&pair); err != nil {
+		t.Errorf("Error getting pair auction state: %s", err)
+		return
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	var auctionID [32]byte
+	if auctionID, _, _, err = s.CurrentPairAuctionState(&pair); err != nil {
+		t.Errorf("Error getting pair auction state: %s", err)
+		return
+	}
+
+	if auctionID != stoppedAuctionID {
+		t.Errorf("Pair auction closed after shutting down, %x became %x", stoppedAuctionID, auctionID)
+		return
+	}
+
+	otherPair := pair.Inverse()
+	if err = s.StartPairAuction(&otherPair, uint64(time.Hour/time.Microsecond)); err == nil {
+		t.Errorf("Pair auctions should not start after shutting down")
+		return
+	}
+
+	return
+}
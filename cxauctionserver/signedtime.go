@@ -0,0 +1,139 @@
+package cxauctionserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/golangcrypto/sha3"
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// SetSigningKey sets the key the server signs things like its time with, so clients can check that
+// they really came from the exchange.
+func (s *OpencxAuctionServer) SetSigningKey(privkey *koblitz.PrivateKey) (err error) {
+	if privkey == nil {
+		err = fmt.Errorf("Cannot set a nil signing key")
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.signingKey = privkey
+	s.auctionMtx.Unlock()
+	return
+}
+
+// SigningPubkey gets the pubkey that the server's signatures verify against
+func (s *OpencxAuctionServer) SigningPubkey() (pubkey [33]byte, err error) {
+	s.auctionMtx.RLock()
+	defer s.auctionMtx.RUnlock()
+
+	if s.signingKey == nil {
+		err = fmt.Errorf("Server does not have a signing key")
+		return
+	}
+
+	copy(pubkey[:], s.signingKey.PubKey().SerializeCompressed())
+	return
+}
+
+// SignedTimeSignable serializes a timestamp, in unix nanoseconds, the way the server signs it
+func SignedTimeSignable(timestamp int64) (buf []byte) {
+	var timeBytes [8]byte
+	binary.BigEndian.PutUint64(timeBytes[:], uint64(timestamp))
+
+	buf = append(buf, []byte("opencx-signedtime")...)
+	buf = append(buf, timeBytes[:]...)
+	return
+}
+
+// SignedTime gets the server's current time in unix nanoseconds, signed by the server's key. Clients
+// can compare this with the auction timing to check that auctions really take as long as advertised.
+func (s *OpencxAuctionServer) SignedTime() (timestamp int64, signature []byte, err error) {
+	s.auctionMtx.RLock()
+	defer s.auctionMtx.RUnlock()
+
+	if s.signingKey == nil {
+		err = fmt.Errorf("Error signing time, server does not have a signing key")
+		return
+	}
+
+	timestamp = time.Now().UnixNano()
+
+	// e = h(signedtime || timestamp)
+	sha3 := sha3.New256()
+	sha3.Write(SignedTimeSignable(timestamp))
+	e := sha3.Sum(nil)
+
+	if signature, err = koblitz.SignCompact(koblitz.S256(), s.signingKey, e, false); err != nil {
+		err = fmt.Errorf("Error signing time: %s", err)
+		return
+	}
+
+	return
+}
+
+// VerifySignedTime checks that signature is a signature of timestamp by the key with pubkey
+func VerifySignedTime(timestamp int64, signature []byte, pubkey [33]byte) (err error) {
+	sha3 := sha3.New256()
+	sha3.Write(SignedTimeSignable(timestamp))
+	e := sha3.Sum(nil)
+
+	var sigPubkey *koblitz.PublicKey
+	if sigPubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), signature, e); err != nil {
+		err = fmt.Errorf("Error verifying signed time, invalid signature: %s", err)
+		return
+	}
+
+	if !bytes.Equal(sigPubkey.SerializeCompressed(), pubkey[:]) {
+		err = fmt.Errorf("Signed time was signed by %x, not %x", sigPubkey.SerializeCompressed(), pubkey)
+		return
+	}
+
+	return
+}
+
+// Sign signs signable with the server's key, for replies that clients should be able to check came from
+// the exchange.
+func (s *OpencxAuctionServer) Sign(signable []byte) (signature []byte, err error) {
+	s.auctionMtx.RLock()
+	defer s.auctionMtx.RUnlock()
+
+	if s.signingKey == nil {
+		err = fmt.Errorf("Error signing, server does not have a signing key")
+		return
+	}
+
+	sha3 := sha3.New256()
+	sha3.Write(signable)
+	e := sha3.Sum(nil)
+
+	if signature, err = koblitz.SignCompact(koblitz.S256(), s.signingKey, e, false); err != nil {
+		err = fmt.Errorf("Error signing: %s", err)
+		return
+	}
+
+	return
+}
+
+// VerifyServerSignature checks that signature is a signature of signable by the key with pubkey, like one
+// made by Sign
+func VerifyServerSignature(signable []byte, signature []byte, pubkey [33]byte) (err error) {
+	sha3 := sha3.New256()
+	sha3.Write(signable)
+	e := sha3.Sum(nil)
+
+	var sigPubkey *koblitz.PublicKey
+	if sigPubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), signature, e); err != nil {
+		err = fmt.Errorf("Error verifying server signature, invalid signature: %s", err)
+		return
+	}
+
+	if !bytes.Equal(sigPubkey.SerializeCompressed(), pubkey[:]) {
+		err = fmt.Errorf("Signed by %x, not %x", sigPubkey.SerializeCompressed(), pubkey)
+		return
+	}
+
+	return
+}
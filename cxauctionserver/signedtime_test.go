@@ -0,0 +1,80 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+func TestSignedTimeVerifies(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestSignedTimeVerifies: %s", err)
+		return
+	}
+
+	if _, _, err = s.SignedTime(); err == nil {
+		t.Errorf("Server should not sign the time without a signing key")
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	if err = s.SetSigningKey(privkey); err != nil {
+		t.Errorf("Error setting signing key: %s", err)
+		return
+	}
+
+	var pubkey [33]byte
+	if pubkey, err = s.SigningPubkey(); err != nil {
+		t.Errorf("Error getting signing pubkey: %s", err)
+		return
+	}
+
+	before := time.Now().UnixNano()
+
+	var timestamp int64
+	var signature []byte
+	if timestamp, signature, err = s.SignedTime(); err != nil {
+		t.Errorf("Error getting signed time: %s", err)
+		return
+	}
+
+	if timestamp < before || timestamp > time.Now().UnixNano() {
+		t.Errorf("Signed time %d is not the current time", timestamp)
+		return
+	}
+
+	if err = VerifySignedTime(timestamp, signature, pubkey); err != nil {
+		t.Errorf("Signed time should verify against the server pubkey: %s", err)
+		return
+	}
+
+	// Someone changing the time should make it not verify
+	if err = VerifySignedTime(timestamp+1, signature, pubkey); err == nil {
+		t.Errorf("Signed time should not verify for a different timestamp")
+		return
+	}
+
+	var otherKey *koblitz.PrivateKey
+	if otherKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating other key: %s", err)
+		return
+	}
+
+	var otherPubkey [33]byte
+	copy(otherPubkey[:], otherKey.PubKey().SerializeCompressed())
+	if err = VerifySignedTime(timestamp, signature, otherPubkey); err == nil {
+		t.Errorf("Signed time should not verify against a different pubkey")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,541 @@
+package cxauctionserver
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// EvictionPolicy is what the solve queue does with a new order when it's full
+type EvictionPolicy uint8
+
+const (
+	// RejectNew rejects new orders while the solve queue is full. This is the default.
+	RejectNew EvictionPolicy = iota
+	// DropLowestPriority drops the lowest priority pending order to make room for the new one. Orders
+	// for an auction other than the current one have the lowest priority, and after that the order
+	// that has been waiting the longest.
+	DropLowestPriority
+)
+
+// String returns the name of the eviction policy
+func (p EvictionPolicy) String() string {
+	switch p {
+	case RejectNew:
+		return "reject"
+	case DropLowestPriority:
+		return "droplowest"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(p))
+}
+
+// EvictionPolicyFromString parses an eviction policy from its name
+func EvictionPolicyFromString(name string) (policy EvictionPolicy, err error) {
+	switch name {
+	case "reject":
+		policy = RejectNew
+	case "droplowest":
+		policy = DropLowestPriority
+	default:
+		err = fmt.Errorf("Unknown eviction policy %s, must be reject or droplowest", name)
+	}
+	return
+}
+
+// SolvePriority is the order that waiting orders are solved in
+type SolvePriority uint8
+
+const (
+	// SolveFIFO solves orders in the order they were submitted. This is the default.
+	SolveFIFO SolvePriority = iota
+	// SolveByFee solves the order with the highest priority fee first, and orders with the same fee in
+	// the order they were submitted. Orders that didn't give a fee have a fee of zero.
+	SolveByFee
+)
+
+// String returns the name of the solve priority
+func (p SolvePriority) String() string {
+	switch p {
+	case SolveFIFO:
+		return "fifo"
+	case SolveByFee:
+		return "fee"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(p))
+}
+
+// SolvePriorityFromString parses a solve priority from its name
+func SolvePriorityFromString(name string) (priority SolvePriority, err error) {
+	switch name {
+	case "fifo":
+		priority = SolveFIFO
+	case "fee":
+		priority = SolveByFee
+	default:
+		err = fmt.Errorf("Unknown solve priority %s, must be fifo or fee", name)
+	}
+	return
+}
+
+// SolveStart is when the orders for an auction start being solved
+type SolveStart uint8
+
+const (
+	// SolveOnArrival solves orders as soon as they're submitted. This is the default.
+	SolveOnArrival SolveStart = iota
+	// SolveOnTrigger holds the orders for the current auction until either the auction closes, or the target
+	// number of orders for it have been submitted, whichever comes first. Then they're all solved together.
+	SolveOnTrigger
+)
+
+// String returns the name of the solve start
+func (st SolveStart) String() string {
+	switch st {
+	case SolveOnArrival:
+		return "arrival"
+	case SolveOnTrigger:
+		return "trigger"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(st))
+}
+
+// SolveStartFromString parses a solve start from its name
+func SolveStartFromString(name string) (start SolveStart, err error) {
+	switch name {
+	case "arrival":
+		start = SolveOnArrival
+	case "trigger":
+		start = SolveOnTrigger
+	default:
+		err = fmt.Errorf("Unknown solve start %s, must be arrival or trigger", name)
+	}
+	return
+}
+
+// solveQueueFullError is the error for an order that is rejected because the solve queue is full
+type solveQueueFullError struct {
+	pending int
+}
+
+// Error returns how many orders are in the full solve queue
+func (e *solveQueueFullError) Error() string {
+	return fmt.Sprintf("Solve queue is full with %d orders, try again later", e.pending)
+}
+
+// errSolveQueueClosed is the error for an order that is pushed after the solve queue was closed for shutdown
+var errSolveQueueClosed = errors.New("Exchange is shutting down, not taking new orders to solve")
+
+// solveItem is an order waiting to be solved. priorityFee is the fee the submitter said they'd pay to be
+// solved sooner, it's only used to pick what to solve next. seq is when the order was pushed.
+type solveItem struct {
+	order       *match.EncryptedAuctionOrder
+	priorityFee uint64
+	seq         uint64
+}
+
+// solveQueue holds the orders that are waiting to be solved, so orders coming in faster than they can be
+// solved don't use up unbounded memory.
+type solveQueue struct {
+	// maxPending is the most orders that can wait to be solved, zero means there is no limit
+	maxPending int
+	policy     EvictionPolicy
+	priority   SolvePriority
+	pending    []*solveItem
+	// nextSeq is the seq of the next pushed order
+	nextSeq  uint64
+	evicted  uint64
+	rejected uint64
+	// start is when orders start being solved. With SolveOnTrigger, orders for heldAuction aren't solved
+	// while holding is true, which stops once triggerCount orders for it are pending. Zero means only the
+	// auction closing starts solving.
+	start        SolveStart
+	triggerCount int
+	heldAuction  [32]byte
+	holding      bool
+	// solving are the orders that have been popped but whose results haven't been handled yet
+	solving map[*match.EncryptedAuctionOrder]bool
+	// closed is whether the queue was closed for shutdown, after which drained is closed once nothing
+	// is pending or being solved
+	closed  bool
+	drained chan struct{}
+	mtx     *sync.Mutex
+	// notEmpty is signalled when an order is pushed, for workers waiting to pop
+	notEmpty *sync.Cond
+}
+
+// newSolveQueue creates a new solve queue that holds at most maxPending orders
+func newSolveQueue(maxPending int, policy EvictionPolicy) (q *solveQueue) {
+	q = &solveQueue{
+		maxPending: maxPending,
+		policy:     policy,
+		solving:    make(map[*match.EncryptedAuctionOrder]bool),
+		mtx:        new(sync.Mutex),
+	}
+	q.notEmpty = sync.NewCond(q.mtx)
+	return
+}
+
+// push adds an order to the queue. If place isn't nil, it's called once the order is sure to be accepted, and
+// if it fails the order isn't added. If the queue is full the eviction policy decides whether the order is
+// rejected, or the lowest priority order is evicted and returned. currentAuctionID and priorityFee are used to
+// find the lowest priority order.
+func (q *solveQueue) push(order *match.EncryptedAuctionOrder, priorityFee uint64, currentAuctionID [32]byte, place func() error) (evicted *match.EncryptedAuctionOrder, err error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.closed {
+		err = errSolveQueueClosed
+		return
+	}
+
+	full := q.maxPending > 0 && len(q.pending) >= q.maxPending
+	if full && q.policy == RejectNew {
+		q.rejected++
+		err = &solveQueueFullError{pending: len(q.pending)}
+		return
+	}
+
+	if place != nil {
+		if err = place(); err != nil {
+			return
+		}
+	}
+
+	// Make room, there can be more than one too many if the limit was just lowered
+	for q.maxPending > 0 && len(q.pending) >= q.maxPending {
+		evicted = q.evictLowestPriority(currentAuctionID)
+		q.evicted++
+	}
+
+	q.pending = append(q.pending, &solveItem{
+		order:       order,
+		priorityFee: priorityFee,
+		seq:         q.nextSeq,
+	})
+	q.nextSeq++
+
+	if q.holding && order.IntendedAuction == q.heldAuction && q.triggerCount > 0 && q.countFor(q.heldAuction) >= q.triggerCount {
+		logging.Infof("Got %d orders for auction %x, starting to solve them", q.triggerCount, q.heldAuction)
+		q.holding = false
+		q.notEmpty.Broadcast()
+		return
+	}
+
+	q.notEmpty.Signal()
+	return
+}
+
+// countFor returns how many pending orders are for auctionID, the caller should be holding mtx
+func (q *solveQueue) countFor(auctionID [32]byte) (count int) {
+	for _, item := range q.pending {
+		if item.order.IntendedAuction == auctionID {
+			count++
+		}
+	}
+	return
+}
+
+// unsolvedFor returns how many orders for auctionID are still waiting to be solved or are being solved
+func (q *solveQueue) unsolvedFor(auctionID [32]byte) (count int) {
+	q.mtx.Lock()
+	count = q.countFor(auctionID)
+	for order := range q.solving {
+		if order.IntendedAuction == auctionID {
+			count++
+		}
+	}
+	q.mtx.Unlock()
+	return
+}
+
+// held returns whether an order can't be solved yet because its auction's orders are being held, the
+// caller should be holding mtx
+func (q *solveQueue) held(order *match.EncryptedAuctionOrder) bool {
+	return q.holding && order.IntendedAuction == q.heldAuction
+}
+
+// evictLowestPriority removes and returns the lowest priority order, the caller should be holding mtx. Orders
+// for a stale auction go first. When solving by fee, the order with the lowest fee goes next, otherwise the
+// order that has been waiting the longest.
+func (q *solveQueue) evictLowestPriority(currentAuctionID [32]byte) (evicted *match.EncryptedAuctionOrder) {
+	lowest := 0
+	for i, item := range q.pending {
+		if item.order.IntendedAuction != currentAuctionID {
+			evicted = q.remove(i)
+			return
+		}
+		// pending is in seq order, so only a strictly lower fee replaces an older order
+		if q.priority == SolveByFee && item.priorityFee < q.pending[lowest].priorityFee {
+			lowest = i
+		}
+	}
+
+	evicted = q.remove(lowest)
+	return
+}
+
+// pop blocks until there's an order to solve, and then removes and returns the next one to solve. That's the
+// oldest order, or the one with the highest fee when solving by fee. Orders that are being held are skipped.
+// The order counts as being solved until done is called with it.
+func (q *solveQueue) pop() (order *match.EncryptedAuctionOrder) {
+	q.mtx.Lock()
+	next := q.next()
+	for next < 0 {
+		q.notEmpty.Wait()
+		next = q.next()
+	}
+
+	order = q.remove(next)
+	q.solving[order] = true
+	q.mtx.Unlock()
+	return
+}
+
+// done marks a popped order as no longer being solved, once its result has been handled
+func (q *solveQueue) done(order *match.EncryptedAuctionOrder) {
+	q.mtx.Lock()
+	delete(q.solving, order)
+	q.checkDrained()
+	q.mtx.Unlock()
+	return
+}
+
+// close stops the queue from taking new orders and starts solving any held orders. The returned channel is
+// closed once there's nothing left pending or being solved.
+func (q *solveQueue) close() (drained <-chan struct{}) {
+	q.mtx.Lock()
+	if !q.closed {
+		q.closed = true
+		q.holding = false
+		q.drained = make(chan struct{})
+		q.notEmpty.Broadcast()
+		q.checkDrained()
+	}
+	drained = q.drained
+	q.mtx.Unlock()
+	return
+}
+
+// checkDrained closes drained if the queue is closed and nothing is pending or being solved, the caller
+// should be holding mtx
+func (q *solveQueue) checkDrained() {
+	if !q.closed || len(q.pending) > 0 || len(q.solving) > 0 {
+		return
+	}
+
+	select {
+	case <-q.drained:
+	default:
+		close(q.drained)
+	}
+	return
+}
+
+// unsolved removes every pending order from the queue, and returns them along with the orders that are still
+// being solved
+func (q *solveQueue) unsolved() (orders []*match.EncryptedAuctionOrder) {
+	q.mtx.Lock()
+	for _, item := range q.pending {
+		orders = append(orders, item.order)
+	}
+	for i := range q.pending {
+		q.pending[i] = nil
+	}
+	q.pending = q.pending[:0]
+
+	for order := range q.solving {
+		orders = append(orders, order)
+	}
+	q.mtx.Unlock()
+	return
+}
+
+// isClosed returns whether the queue was closed for shutdown
+func (q *solveQueue) isClosed() (closed bool) {
+	q.mtx.Lock()
+	closed = q.closed
+	q.mtx.Unlock()
+	return
+}
+
+// next returns the index in pending of the next order to solve, or -1 if there's nothing that can be solved.
+// The caller should be holding mtx.
+func (q *solveQueue) next() (next int) {
+	next = -1
+	for i, item := range q.pending {
+		if q.held(item.order) {
+			continue
+		}
+		if next < 0 {
+			next = i
+			if q.priority != SolveByFee {
+				return
+			}
+		} else if item.priorityFee > q.pending[next].priorityFee {
+			next = i
+		}
+	}
+	return
+}
+
+// remove removes and returns the order at index i of pending, the caller should be holding mtx
+func (q *solveQueue) remove(i int) (order *match.EncryptedAuctionOrder) {
+	order = q.pending[i].order
+	copy(q.pending[i:], q.pending[i+1:])
+	q.pending[len(q.pending)-1] = nil
+	q.pending = q.pending[:len(q.pending)-1]
+	return
+}
+
+// setLimit sets the most orders that can wait to be solved, and what to do when that's reached
+func (q *solveQueue) setLimit(maxPending int, policy EvictionPolicy) {
+	q.mtx.Lock()
+	q.maxPending = maxPending
+	q.policy = policy
+	q.mtx.Unlock()
+	return
+}
+
+// setPriority sets the order that waiting orders are solved in
+func (q *solveQueue) setPriority(priority SolvePriority) {
+	q.mtx.Lock()
+	q.priority = priority
+	q.mtx.Unlock()
+	return
+}
+
+// setStart sets when orders start being solved. With SolveOnTrigger, the orders for currentAuctionID are held
+// until the auction closes or triggerCount of them are pending.
+func (q *solveQueue) setStart(start SolveStart, triggerCount int, currentAuctionID [32]byte) {
+	q.mtx.Lock()
+	q.start = start
+	q.triggerCount = triggerCount
+	q.heldAuction = currentAuctionID
+	q.holding = start == SolveOnTrigger && (triggerCount == 0 || q.countFor(currentAuctionID) < triggerCount)
+	q.notEmpty.Broadcast()
+	q.mtx.Unlock()
+	return
+}
+
+// newAuction starts solving the orders that were held for the auction that closed, and starts holding the
+// orders for newAuctionID if orders are solved on a trigger
+func (q *solveQueue) newAuction(newAuctionID [32]byte) {
+	q.mtx.Lock()
+	q.heldAuction = newAuctionID
+	q.holding = q.start == SolveOnTrigger
+	q.notEmpty.Broadcast()
+	q.mtx.Unlock()
+	return
+}
+
+// status returns the number of pending orders, and how many have been evicted or rejected
+func (q *solveQueue) status() (pending int, evicted uint64, rejected uint64) {
+	q.mtx.Lock()
+	pending = len(q.pending)
+	evicted = q.evicted
+	rejected = q.rejected
+	q.mtx.Unlock()
+	return
+}
+
+// SetMaxPendingSolves sets the most orders that can be waiting to be solved at once, and what happens to
+// new orders when that many are waiting. Zero means there is no limit.
+func (s *OpencxAuctionServer) SetMaxPendingSolves(maxPending int, policy EvictionPolicy) (err error) {
+	if maxPending < 0 {
+		err = fmt.Errorf("Max pending solves cannot be negative, got %d", maxPending)
+		return
+	}
+
+	if policy != RejectNew && policy != DropLowestPriority {
+		err = fmt.Errorf("Cannot set unknown eviction policy %s", policy)
+		return
+	}
+
+	s.solves.setLimit(maxPending, policy)
+	return
+}
+
+// SetSolvePriority sets the order that waiting orders are solved in. This only matters when orders come in
+// faster than they can be solved.
+func (s *OpencxAuctionServer) SetSolvePriority(priority SolvePriority) (err error) {
+	if priority != SolveFIFO && priority != SolveByFee {
+		err = fmt.Errorf("Cannot set unknown solve priority %s", priority)
+		return
+	}
+
+	s.solves.setPriority(priority)
+	return
+}
+
+// SetSolveStart sets when the orders for an auction start being solved. With SolveOnTrigger, solving starts
+// once the auction closes, or once triggerCount orders for it have been submitted if that comes first, which
+// keeps latency down on busy auctions. A triggerCount of zero means only the auction closing starts solving.
+func (s *OpencxAuctionServer) SetSolveStart(start SolveStart, triggerCount int) (err error) {
+	if start != SolveOnArrival && start != SolveOnTrigger {
+		err = fmt.Errorf("Cannot set unknown solve start %s", start)
+		return
+	}
+
+	if triggerCount < 0 {
+		err = fmt.Errorf("Solve trigger count cannot be negative, got %d", triggerCount)
+		return
+	}
+
+	// Hold the db so the auction doesn't change under us
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for solve start: %s", err)
+		return
+	}
+
+	s.solves.setStart(start, triggerCount, currentAuctionID)
+	return
+}
+
+// SolveQueueStatus returns the number of orders waiting to be solved, and how many orders have been evicted
+// from or rejected by the solve queue
+func (s *OpencxAuctionServer) SolveQueueStatus() (pending int, evicted uint64, rejected uint64, err error) {
+	pending, evicted, rejected = s.solves.status()
+	return
+}
+
+// queueSolve adds an order to the solve queue with the priority fee it was submitted with. If place isn't nil,
+// it's called to place the order once it's sure to be accepted. The caller should be holding dbLock.
+func (s *OpencxAuctionServer) queueSolve(order *match.EncryptedAuctionOrder, priorityFee uint64, place func() error) (err error) {
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for solve queue: %s", err)
+		return
+	}
+
+	var evicted *match.EncryptedAuctionOrder
+	if evicted, err = s.solves.push(order, priorityFee, currentAuctionID, place); err != nil {
+		return
+	}
+
+	if evicted != nil {
+		logging.Errorf("Solve queue full, dropped an order for auction %x", evicted.IntendedAuction)
+	}
+
+	return
+}
+
+// startSolveWorkers starts the workers that solve orders from the solve queue. Solving is all squaring, so
+// there's no point in solving more orders at once than there are CPUs.
+func (s *OpencxAuctionServer) startSolveWorkers() {
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		go func() {
+			for {
+				s.solveOrderIntoResChan(s.solves.pop())
+			}
+		}()
+	}
+	return
+}
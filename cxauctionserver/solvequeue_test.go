@@ -0,0 +1,398 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/opencx/match"
+)
+
+// pushOverload pushes many more orders than the queue can hold without popping any, like when orders come
+// in much faster than they can be solved. Every other order is for a stale auction, starting with the first.
+func pushOverload(q *solveQueue, numOrders int, t *testing.T) (accepted int, evicted []*match.EncryptedAuctionOrder) {
+	currentAuctionID := [32]byte{0x01}
+	for i := 0; i < numOrders; i++ {
+		order := &match.EncryptedAuctionOrder{IntendedAuction: currentAuctionID}
+		if i%2 == 0 {
+			order.IntendedAuction = [32]byte{0x02}
+		}
+
+		evictedOrder, err := q.push(order, 0, currentAuctionID, nil)
+		if err == nil {
+			accepted++
+		}
+		if evictedOrder != nil {
+			evicted = append(evicted, evictedOrder)
+		}
+
+		if pending, _, _ := q.status(); q.maxPending > 0 && pending > q.maxPending {
+			t.Errorf("Solve queue has %d pending orders, more than the max of %d", pending, q.maxPending)
+			return
+		}
+	}
+	return
+}
+
+func TestSolveQueueRejectNew(t *testing.T) {
+	q := newSolveQueue(10, RejectNew)
+
+	accepted, evicted := pushOverload(q, 1000, t)
+	if accepted != 10 {
+		t.Errorf("Solve queue accepted %d orders, expected 10", accepted)
+		return
+	}
+
+	if len(evicted) != 0 {
+		t.Errorf("Solve queue rejecting new orders should not evict any, evicted %d", len(evicted))
+		return
+	}
+
+	pending, evictedCount, rejected := q.status()
+	if pending != 10 || evictedCount != 0 || rejected != 990 {
+		t.Errorf("Solve queue status was %d pending, %d evicted, %d rejected, expected 10, 0, 990", pending, evictedCount, rejected)
+		return
+	}
+
+	// Rejections are rate limiting, so they have their own error
+	if _, err := q.push(new(match.EncryptedAuctionOrder), 0, [32]byte{}, nil); err == nil {
+		t.Errorf("Full solve queue should reject new orders")
+		return
+	} else if _, full := err.(*solveQueueFullError); !full {
+		t.Errorf("Full solve queue should reject with a solve queue full error, got %s", err)
+		return
+	}
+
+	// Solving an order makes room for another
+	q.pop()
+	if _, err := q.push(new(match.EncryptedAuctionOrder), 0, [32]byte{}, nil); err != nil {
+		t.Errorf("Solve queue should have room after popping: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestSolveQueueDropLowestPriority(t *testing.T) {
+	q := newSolveQueue(10, DropLowestPriority)
+
+	accepted, evicted := pushOverload(q, 1000, t)
+	if accepted != 1000 {
+		t.Errorf("Solve queue dropping low priority orders should accept every order, accepted %d", accepted)
+		return
+	}
+
+	if len(evicted) != 990 {
+		t.Errorf("Solve queue evicted %d orders, expected 990", len(evicted))
+		return
+	}
+
+	// Stale orders are dropped first, so the queue should end up with only current ones
+	for i := 0; i < 10; i++ {
+		if order := q.pop(); order.IntendedAuction != [32]byte{0x01} {
+			t.Errorf("Solve queue kept an order for a stale auction over current ones")
+			return
+		}
+	}
+
+	return
+}
+
+func TestSolveQueueFailedPlace(t *testing.T) {
+	q := newSolveQueue(10, RejectNew)
+
+	if _, err := q.push(new(match.EncryptedAuctionOrder), 0, [32]byte{}, func() error {
+		return fmt.Errorf("place failed")
+	}); err == nil {
+		t.Errorf("Push should fail when placing the order fails")
+		return
+	}
+
+	if pending, _, _ := q.status(); pending != 0 {
+		t.Errorf("Order that failed to be placed should not be queued, %d pending", pending)
+		return
+	}
+
+	return
+}
+
+func TestSolveQueueByFee(t *testing.T) {
+	q := newSolveQueue(0, RejectNew)
+	q.setPriority(SolveByFee)
+
+	// Orders are told apart by their intended auction, which is set to their fee and when they came in
+	fees := []uint64{5, 1, 9, 5, 0, 9}
+	for i, fee := range fees {
+		order := &match.EncryptedAuctionOrder{IntendedAuction: [32]byte{byte(fee), byte(i)}}
+		if _, err := q.push(order, fee, [32]byte{}, nil); err != nil {
+			t.Errorf("Error pushing order with fee %d: %s", fee, err)
+			return
+		}
+	}
+
+	// One worker solving one order at a time should get the highest fees first, and equal fees in the
+	// order they came in
+	expected := [][2]byte{{9, 2}, {9, 5}, {5, 0}, {5, 3}, {1, 1}, {0, 4}}
+	for _, exp := range expected {
+		if order := q.pop(); order.IntendedAuction[0] != exp[0] || order.IntendedAuction[1] != exp[1] {
+			t.Errorf("Expected order %d with fee %d to be solved next, got order %d with fee %d", exp[1], exp[0], order.IntendedAuction[1], order.IntendedAuction[0])
+			return
+		}
+	}
+
+	return
+}
+
+func TestSolveQueueByFeeEviction(t *testing.T) {
+	q := newSolveQueue(3, DropLowestPriority)
+	q.setPriority(SolveByFee)
+
+	currentAuctionID := [32]byte{0x01}
+	for _, fee := range []uint64{3, 1, 2} {
+		if _, err := q.push(&match.EncryptedAuctionOrder{IntendedAuction: currentAuctionID}, fee, currentAuctionID, nil); err != nil {
+			t.Errorf("Error pushing order with fee %d: %s", fee, err)
+			return
+		}
+	}
+
+	// The order with the lowest fee should make room for the new one
+	if _, err := q.push(&match.EncryptedAuctionOrder{IntendedAuction: currentAuctionID}, 4, currentAuctionID, nil); err != nil {
+		t.Errorf("Error pushing order to full queue: %s", err)
+		return
+	}
+
+	q.mtx.Lock()
+	var remaining []uint64
+	for _, item := range q.pending {
+		remaining = append(remaining, item.priorityFee)
+	}
+	q.mtx.Unlock()
+
+	if len(remaining) != 3 || remaining[0] != 3 || remaining[1] != 2 || remaining[2] != 4 {
+		t.Errorf("Solve queue should have evicted the lowest fee order, has fees %v", remaining)
+		return
+	}
+
+	return
+}
+
+func TestSetMaxPendingSolves(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestSetMaxPendingSolves: %s", err)
+		return
+	}
+
+	if err = s.SetMaxPendingSolves(-1, RejectNew); err == nil {
+		t.Errorf("Setting a negative max pending solves should fail")
+		return
+	}
+
+	if err = s.SetMaxPendingSolves(5, EvictionPolicy(100)); err == nil {
+		t.Errorf("Setting an unknown eviction policy should fail")
+		return
+	}
+
+	if err = s.SetMaxPendingSolves(5, DropLowestPriority); err != nil {
+		t.Errorf("Error setting max pending solves: %s", err)
+		return
+	}
+
+	for _, name := range []string{"reject", "droplowest"} {
+		var policy EvictionPolicy
+		if policy, err = EvictionPolicyFromString(name); err != nil {
+			t.Errorf("Error parsing eviction policy %s: %s", name, err)
+			return
+		}
+		if policy.String() != name {
+			t.Errorf("Eviction policy %s parsed to %s", name, policy)
+			return
+		}
+	}
+
+	if err = s.SetSolvePriority(SolvePriority(100)); err == nil {
+		t.Errorf("Setting an unknown solve priority should fail")
+		return
+	}
+
+	for _, name := range []string{"fifo", "fee"} {
+		var priority SolvePriority
+		if priority, err = SolvePriorityFromString(name); err != nil {
+			t.Errorf("Error parsing solve priority %s: %s", name, err)
+			return
+		}
+		if priority.String() != name {
+			t.Errorf("Solve priority %s parsed to %s", name, priority)
+			return
+		}
+		if err = s.SetSolvePriority(priority); err != nil {
+			t.Errorf("Error setting solve priority %s: %s", name, err)
+			return
+		}
+	}
+
+	if err = s.SetSolveStart(SolveOnTrigger, -1); err == nil {
+		t.Errorf("Setting a negative solve trigger count should fail")
+		return
+	}
+
+	for _, name := range []string{"arrival", "trigger"} {
+		var start SolveStart
+		if start, err = SolveStartFromString(name); err != nil {
+			t.Errorf("Error parsing solve start %s: %s", name, err)
+			return
+		}
+		if start.String() != name {
+			t.Errorf("Solve start %s parsed to %s", name, start)
+			return
+		}
+		if err = s.SetSolveStart(start, 10); err != nil {
+			t.Errorf("Error setting solve start %s: %s", name, err)
+			return
+		}
+	}
+
+	return
+}
+
+// startTestPopper pops orders from q and sends them on popped, like a solve worker
+func startTestPopper(q *solveQueue) (popped chan *match.EncryptedAuctionOrder) {
+	popped = make(chan *match.EncryptedAuctionOrder, 100)
+	go func() {
+		for {
+			popped <- q.pop()
+		}
+	}()
+	return
+}
+
+// receivePopped receives numOrders popped orders, failing if they aren't popped within a second
+func receivePopped(popped chan *match.EncryptedAuctionOrder, numOrders int) (err error) {
+	for i := 0; i < numOrders; i++ {
+		select {
+		case <-popped:
+		case <-time.After(time.Second):
+			err = fmt.Errorf("Only %d of %d orders were popped", i, numOrders)
+			return
+		}
+	}
+	return
+}
+
+// checkNonePopped fails if an order is popped in the next 50 milliseconds
+func checkNonePopped(popped chan *match.EncryptedAuctionOrder) (err error) {
+	select {
+	case order := <-popped:
+		err = fmt.Errorf("Order for auction %x was popped while it should be held", order.IntendedAuction)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return
+}
+
+func TestSolveQueueTriggerCount(t *testing.T) {
+	var err error
+
+	currentAuctionID := [32]byte{0x01}
+	q := newSolveQueue(0, RejectNew)
+	q.setStart(SolveOnTrigger, 3, currentAuctionID)
+	popped := startTestPopper(q)
+
+	for i := 0; i < 2; i++ {
+		if _, err = q.push(&match.EncryptedAuctionOrder{IntendedAuction: currentAuctionID}, 0, currentAuctionID, nil); err != nil {
+			t.Errorf("Error pushing order: %s", err)
+			return
+		}
+	}
+
+	if err = checkNonePopped(popped); err != nil {
+		t.Errorf("Orders should be held until the trigger count is reached: %s", err)
+		return
+	}
+
+	// Orders for other auctions aren't held
+	if _, err = q.push(&match.EncryptedAuctionOrder{IntendedAuction: [32]byte{0x02}}, 0, currentAuctionID, nil); err != nil {
+		t.Errorf("Error pushing order for other auction: %s", err)
+		return
+	}
+
+	if err = receivePopped(popped, 1); err != nil {
+		t.Errorf("Order for another auction should be solved right away: %s", err)
+		return
+	}
+
+	// The third order triggers solving all of them
+	if _, err = q.push(&match.EncryptedAuctionOrder{IntendedAuction: currentAuctionID}, 0, currentAuctionID, nil); err != nil {
+		t.Errorf("Error pushing order: %s", err)
+		return
+	}
+
+	if err = receivePopped(popped, 3); err != nil {
+		t.Errorf("Reaching the trigger count should start solving: %s", err)
+		return
+	}
+
+	// Once triggered, new orders for the auction are solved as they come in
+	if _, err = q.push(&match.EncryptedAuctionOrder{IntendedAuction: currentAuctionID}, 0, currentAuctionID, nil); err != nil {
+		t.Errorf("Error pushing order: %s", err)
+		return
+	}
+
+	if err = receivePopped(popped, 1); err != nil {
+		t.Errorf("Orders after the trigger should be solved right away: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestSolveQueueTriggerAuctionClose(t *testing.T) {
+	var err error
+
+	currentAuctionID := [32]byte{0x01}
+	q := newSolveQueue(0, RejectNew)
+	q.setStart(SolveOnTrigger, 10, currentAuctionID)
+	popped := startTestPopper(q)
+
+	for i := 0; i < 2; i++ {
+		if _, err = q.push(&match.EncryptedAuctionOrder{IntendedAuction: currentAuctionID}, 0, currentAuctionID, nil); err != nil {
+			t.Errorf("Error pushing order: %s", err)
+			return
+		}
+	}
+
+	if err = checkNonePopped(popped); err != nil {
+		t.Errorf("Orders should be held until the auction closes: %s", err)
+		return
+	}
+
+	// Closing the auction starts solving before the trigger count is reached
+	newAuctionID := [32]byte{0x02}
+	q.newAuction(newAuctionID)
+
+	if err = receivePopped(popped, 2); err != nil {
+		t.Errorf("Closing the auction should start solving: %s", err)
+		return
+	}
+
+	// The new auction's orders are held again
+	if _, err = q.push(&match.EncryptedAuctionOrder{IntendedAuction: newAuctionID}, 0, newAuctionID, nil); err != nil {
+		t.Errorf("Error pushing order: %s", err)
+		return
+	}
+
+	if err = checkNonePopped(popped); err != nil {
+		t.Errorf("Orders for the new auction should be held: %s", err)
+		return
+	}
+
+	// Solving on arrival doesn't hold anything
+	q.setStart(SolveOnArrival, 0, newAuctionID)
+	if err = receivePopped(popped, 1); err != nil {
+		t.Errorf("Solving on arrival should solve held orders: %s", err)
+		return
+	}
+
+	return
+}
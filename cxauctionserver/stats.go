@@ -0,0 +1,140 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// maxStoredAuctionStats is how many auctions we keep stats for, the oldest auction is forgotten once
+// stats for a new one are recorded.
+const maxStoredAuctionStats = 64
+
+// DefaultMaxDepthLevels is the most price levels on each side of a depth chart by default
+const DefaultMaxDepthLevels = 500
+
+// RecordAuctionImbalance computes the buy and sell volume imbalance for a pair's auction at the clearing
+// price, from the orders in the auction's order book. The imbalance is logged and kept so it can be looked up later.
+// ClearPairAuction records it when the auction clears, since that's the time the imbalance is stamped with.
+func (s *OpencxAuctionServer) RecordAuctionImbalance(pair *match.Pair, auctionID [32]byte, clearingPrice float64) (imbalance *match.AuctionImbalance, err error) {
+	var sellOrders, buyOrders []*match.AuctionOrder
+	s.dbLock.Lock()
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(pair, auctionID); err != nil {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Error viewing auction order book for imbalance: %s", err)
+		return
+	}
+	s.dbLock.Unlock()
+
+	if imbalance, err = match.ComputeImbalance(*pair, append(sellOrders, buyOrders...), clearingPrice); err != nil {
+		err = fmt.Errorf("Error computing auction imbalance: %s", err)
+		return
+	}
+
+	s.keepAuctionImbalance(auctionID, imbalance)
+
+	return
+}
+
+// keepAuctionImbalance stamps an auction's imbalance with the time it cleared, then logs and keeps it so it
+// can be looked up later
+func (s *OpencxAuctionServer) keepAuctionImbalance(auctionID [32]byte, imbalance *match.AuctionImbalance) {
+	imbalance.ClearedAtUnix = time.Now().Unix()
+
+	s.statsMtx.Lock()
+	pairStats, found := s.auctionImbalances[auctionID]
+	if !found {
+		// forget about the oldest auction if we're storing too many
+		if len(s.statsAuctions) >= maxStoredAuctionStats {
+			delete(s.auctionImbalances, s.statsAuctions[0])
+			s.statsAuctions = s.statsAuctions[1:]
+		}
+		pairStats = make(map[match.Pair]*match.AuctionImbalance)
+		s.auctionImbalances[auctionID] = pairStats
+		s.statsAuctions = append(s.statsAuctions, auctionID)
+	}
+	pairStats[imbalance.Pair] = imbalance
+	s.statsMtx.Unlock()
+
+	logging.Infof("Auction %x imbalance for %s", auctionID, imbalance)
+
+	return
+}
+
+// AuctionImbalance gets the recorded buy and sell volume imbalance for a pair's auction
+func (s *OpencxAuctionServer) AuctionImbalance(pair *match.Pair, auctionID [32]byte) (imbalance *match.AuctionImbalance, err error) {
+	s.statsMtx.Lock()
+	imbalance, found := s.auctionImbalances[auctionID][*pair]
+	s.statsMtx.Unlock()
+
+	if !found {
+		err = fmt.Errorf("No imbalance recorded for %s in auction %x", pair.PrettyString(), auctionID)
+		return
+	}
+
+	return
+}
+
+// SetMaxDepthLevels sets the most price levels on each side of a depth chart. Levels past the max are
+// aggregated into the last level.
+func (s *OpencxAuctionServer) SetMaxDepthLevels(maxLevels int) (err error) {
+	if maxLevels <= 0 {
+		err = fmt.Errorf("Max depth levels must be positive, got %d", maxLevels)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.maxDepthLevels = maxLevels
+	s.auctionMtx.Unlock()
+	return
+}
+
+// MaxDepthLevels gets the most price levels on each side of a depth chart
+func (s *OpencxAuctionServer) MaxDepthLevels() (maxLevels int) {
+	s.auctionMtx.RLock()
+	maxLevels = s.maxDepthLevels
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// OrderBookDepth aggregates a pair's auction order book into a depth chart for plotting, with at most levels
+// points per side if levels is more than zero. No matter what levels is, each side has at most the max depth
+// levels, the rest are aggregated into the last level. Orders are only revealed once an auction is over, so the
+// current auction has no depth.
+func (s *OpencxAuctionServer) OrderBookDepth(pair *match.Pair, auctionID [32]byte, levels int) (chart *match.DepthChart, err error) {
+	var sellOrders, buyOrders []*match.AuctionOrder
+	s.dbLock.Lock()
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Error getting current auction ID for depth: %s", err)
+		return
+	}
+
+	if currentAuctionID == auctionID {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Auction %x is still running, depth is only available once its orders are revealed", auctionID)
+		return
+	}
+
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(pair, auctionID); err != nil {
+		s.dbLock.Unlock()
+		err = fmt.Errorf("Error viewing auction order book for depth: %s", err)
+		return
+	}
+	s.dbLock.Unlock()
+
+	if chart, err = match.ComputeDepth(*pair, auctionID, append(sellOrders, buyOrders...), levels); err != nil {
+		err = fmt.Errorf("Error computing auction order book depth: %s", err)
+		return
+	}
+
+	if err = chart.CapLevels(s.MaxDepthLevels()); err != nil {
+		err = fmt.Errorf("Error capping auction order book depth: %s", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,293 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestRecordAuctionImbalance(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestRecordAuctionImbalance: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// Three times as much buying as selling at the clearing price
+	var orders []*match.AuctionOrder
+	for _, side := range []string{"buy", "buy", "buy", "sell"} {
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(side, 1000, 1.0, privkey); err != nil {
+			t.Errorf("Error creating %s order: %s", side, err)
+			return
+		}
+		if err = s.OpencxDB.PlaceAuctionOrder(order); err != nil {
+			t.Errorf("Error placing %s order: %s", side, err)
+			return
+		}
+		orders = append(orders, order)
+	}
+
+	pair := orders[0].TradingPair
+	auctionID := orders[0].AuctionID
+
+	if _, err = s.AuctionImbalance(&pair, auctionID); err == nil {
+		t.Errorf("There shouldn't be an imbalance before one is recorded")
+		return
+	}
+
+	beforeClearing := time.Now().Unix()
+	if _, err = s.RecordAuctionImbalance(&pair, auctionID, 1.0); err != nil {
+		t.Errorf("Error recording auction imbalance: %s", err)
+		return
+	}
+	afterClearing := time.Now().Unix()
+
+	var imbalance *match.AuctionImbalance
+	if imbalance, err = s.AuctionImbalance(&pair, auctionID); err != nil {
+		t.Errorf("Error getting auction imbalance: %s", err)
+		return
+	}
+
+	if imbalance.BuyVolume != 3000 || imbalance.SellVolume != 1000 {
+		t.Errorf("Should have 3000 buy volume and 1000 sell volume, got %s", imbalance)
+		return
+	}
+
+	if imbalance.Imbalance() != 0.5 {
+		t.Errorf("Imbalance should be 0.5, got %f", imbalance.Imbalance())
+		return
+	}
+
+	if imbalance.ClearedAtUnix < beforeClearing || imbalance.ClearedAtUnix > afterClearing {
+		t.Errorf("Auction should have cleared between %d and %d, got %d", beforeClearing, afterClearing, imbalance.ClearedAtUnix)
+		return
+	}
+
+	return
+}
+
+func TestOrderBookDepth(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestOrderBookDepth: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// Two buys at one price and a sell at another, as if they had been decrypted
+	var orders []*match.AuctionOrder
+	for _, side := range []string{"buy", "buy", "sell"} {
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(side, 1000, 1.0, privkey); err != nil {
+			t.Errorf("Error creating %s order: %s", side, err)
+			return
+		}
+		if err = s.OpencxDB.PlaceAuctionOrder(order); err != nil {
+			t.Errorf("Error placing %s order: %s", side, err)
+			return
+		}
+		orders = append(orders, order)
+	}
+
+	pair := orders[0].TradingPair
+	auctionID := orders[0].AuctionID
+
+	var chart *match.DepthChart
+	if chart, err = s.OrderBookDepth(&pair, auctionID, 0); err != nil {
+		t.Errorf("Error getting order book depth: %s", err)
+		return
+	}
+
+	var buyPrice, sellPrice float64
+	if buyPrice, err = orders[0].Price(); err != nil {
+		t.Errorf("Error getting buy price: %s", err)
+		return
+	}
+	if sellPrice, err = orders[2].Price(); err != nil {
+		t.Errorf("Error getting sell price: %s", err)
+		return
+	}
+
+	expectedBid := match.DepthPoint{Price: buyPrice, Volume: orders[0].AmountWant + orders[1].AmountWant}
+	if len(chart.Bids) != 1 || chart.Bids[0] != expectedBid {
+		t.Errorf("Depth should have one bid point %v, got %v", expectedBid, chart.Bids)
+		return
+	}
+
+	expectedAsk := match.DepthPoint{Price: sellPrice, Volume: orders[2].AmountHave}
+	if len(chart.Asks) != 1 || chart.Asks[0] != expectedAsk {
+		t.Errorf("Depth should have one ask point %v, got %v", expectedAsk, chart.Asks)
+		return
+	}
+
+	// The current auction hasn't been revealed yet
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	if _, err = s.OrderBookDepth(&pair, currentAuctionID, 0); err == nil {
+		t.Errorf("Getting the depth of the current auction should fail")
+		return
+	}
+
+	return
+}
+
+func TestOrderBookDepthMaxLevels(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestOrderBookDepthMaxLevels: %s", err)
+		return
+	}
+
+	if err = s.SetMaxDepthLevels(0); err == nil {
+		t.Errorf("Setting max depth levels to 0 should fail")
+		return
+	}
+
+	if err = s.SetMaxDepthLevels(4); err != nil {
+		t.Errorf("Error setting max depth levels: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// a batch with a lot of distinct buy prices
+	var orders []*match.AuctionOrder
+	for i := 1; i <= 20; i++ {
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder("buy", 1000, float64(i)/10, privkey); err != nil {
+			t.Errorf("Error creating buy order: %s", err)
+			return
+		}
+		if err = s.OpencxDB.PlaceAuctionOrder(order); err != nil {
+			t.Errorf("Error placing buy order: %s", err)
+			return
+		}
+		orders = append(orders, order)
+	}
+
+	pair := orders[0].TradingPair
+	auctionID := orders[0].AuctionID
+
+	var totalVolume uint64
+	for _, order := range orders {
+		totalVolume += order.AmountWant
+	}
+
+	// asking for every level still only gets the max
+	var chart *match.DepthChart
+	if chart, err = s.OrderBookDepth(&pair, auctionID, 0); err != nil {
+		t.Errorf("Error getting order book depth: %s", err)
+		return
+	}
+
+	if len(chart.Bids) != 4 {
+		t.Errorf("Depth should be capped at 4 bid levels, got %d", len(chart.Bids))
+		return
+	}
+
+	if !chart.Bids[3].Aggregated || chart.Bids[3].Volume != totalVolume {
+		t.Errorf("Last bid level should aggregate the rest of the book for a total of %d, got %v", totalVolume, chart.Bids[3])
+		return
+	}
+
+	// asking for fewer levels than the max truncates like before
+	if chart, err = s.OrderBookDepth(&pair, auctionID, 2); err != nil {
+		t.Errorf("Error getting order book depth with 2 levels: %s", err)
+		return
+	}
+
+	if len(chart.Bids) != 2 || chart.Bids[1].Aggregated {
+		t.Errorf("Depth with 2 levels should have 2 bid levels that aren't aggregated, got %v", chart.Bids)
+		return
+	}
+
+	return
+}
+
+func TestRecordAuctionNoMatch(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestRecordAuctionNoMatch: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// The buyer wants to pay less than the seller wants, so the book doesn't cross
+	var orders []*match.AuctionOrder
+	for _, test := range []struct {
+		side  string
+		price float64
+	}{{"buy", 1.0}, {"sell", 2.0}} {
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(test.side, 1000, test.price, privkey); err != nil {
+			t.Errorf("Error creating %s order: %s", test.side, err)
+			return
+		}
+		if err = s.OpencxDB.PlaceAuctionOrder(order); err != nil {
+			t.Errorf("Error placing %s order: %s", test.side, err)
+			return
+		}
+		orders = append(orders, order)
+	}
+
+	pair := orders[0].TradingPair
+	auctionID := orders[0].AuctionID
+
+	if _, err = s.RecordAuctionImbalance(&pair, auctionID, 0); err != nil {
+		t.Errorf("Error recording auction with no match: %s", err)
+		return
+	}
+
+	var imbalance *match.AuctionImbalance
+	if imbalance, err = s.AuctionImbalance(&pair, auctionID); err != nil {
+		t.Errorf("Error getting auction imbalance: %s", err)
+		return
+	}
+
+	if !imbalance.NoMatch || imbalance.ClearingPrice != 0 {
+		t.Errorf("Auction that didn't cross should be recorded as no match with no clearing price, got %s", imbalance)
+		return
+	}
+
+	if imbalance.ClearedAtUnix == 0 {
+		t.Errorf("No match result should still be stamped with when the auction cleared")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,100 @@
+package cxauctionserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// UnsafeEnableNoPuzzle lets the server accept plaintext batch orders, with no timelock puzzle. This is only
+// meant for local testing, where solving puzzles makes everything slow. Orders are visible to the exchange
+// as soon as they're submitted, so the exchange is NOT front-running resistant while this is enabled.
+// This can't be enabled on mainnet, and is disabled by default.
+func (s *OpencxAuctionServer) UnsafeEnableNoPuzzle() (err error) {
+	s.auctionMtx.Lock()
+	defer s.auctionMtx.Unlock()
+
+	if s.network == match.MainnetMagic {
+		err = fmt.Errorf("Refusing to accept orders without puzzles on mainnet")
+		return
+	}
+
+	logging.Warnf("UNSAFE: accepting plaintext orders without timelock puzzles, this is for testing only")
+	s.unsafeNoPuzzle = true
+	return
+}
+
+// UnsafeNoPuzzle returns whether or not the server accepts plaintext batch orders
+func (s *OpencxAuctionServer) UnsafeNoPuzzle() (enabled bool, err error) {
+	s.auctionMtx.RLock()
+	enabled = s.unsafeNoPuzzle && s.network != match.MainnetMagic
+	s.auctionMtx.RUnlock()
+	return
+}
+
+// PlaceUnsafePlaintextOrder places a batch order that was submitted without a timelock puzzle. The order
+// goes in the auction as if its puzzle had already been solved, so it's checked and placed like any solved
+// order, and has to be signed for the current auction or the current auction of its pair. This only works if
+// UnsafeEnableNoPuzzle has been called.
+func (s *OpencxAuctionServer) PlaceUnsafePlaintextOrder(order *match.AuctionOrder) (err error) {
+	var enabled bool
+	if enabled, err = s.UnsafeNoPuzzle(); err != nil {
+		err = fmt.Errorf("Error checking if plaintext orders are allowed: %s", err)
+		return
+	}
+
+	if !enabled {
+		err = fmt.Errorf("Plaintext orders are not accepted, orders must be submitted as timelock puzzles")
+		return
+	}
+
+	var mode MatchingMode
+	if mode, err = s.MatchingMode(); err != nil {
+		err = fmt.Errorf("Error getting matching mode for plaintext order: %s", err)
+		return
+	}
+
+	if mode != BatchMatching {
+		err = fmt.Errorf("Exchange is in %s matching mode, plaintext batch orders are only accepted in batch mode", mode)
+		return
+	}
+
+	// there's no puzzle, so there's no encrypted order to check against
+	result := &match.OrderPuzzleResult{Auction: order}
+	var signedFor [32]byte
+	if _, signedFor, err = s.checkSolvedOrder(result); err != nil {
+		s.countRejection(RejectedInvalid)
+		err = fmt.Errorf("Error validating plaintext order: %s", err)
+		return
+	}
+
+	if err = order.VerifySignatureForAuction(signedFor); err != nil {
+		s.countRejection(RejectedInvalid)
+		err = fmt.Errorf("Error verifying signature of plaintext order: %s", err)
+		return
+	}
+
+	// Hold the db lock so the auction can't close between checking the order is for it and placing it
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+
+	var currentAuctionID [32]byte
+	if currentAuctionID, err = s.CurrentAuctionID(); err != nil {
+		err = fmt.Errorf("Error getting current auction ID for plaintext order: %s", err)
+		return
+	}
+
+	if order.AuctionID != currentAuctionID && !s.isOpenPairAuction(order.AuctionID) {
+		s.countRejection(RejectedWrongAuction)
+		err = fmt.Errorf("Plaintext order is for auction %x, not the current auction %x or the current auction of a pair", order.AuctionID, currentAuctionID)
+		return
+	}
+
+	if err = s.placeSolvedOrderLocked(order, signedFor); err != nil {
+		err = fmt.Errorf("Error placing plaintext order: %s", err)
+		return
+	}
+
+	return
+}
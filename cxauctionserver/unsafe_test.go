@@ -0,0 +1,236 @@
+package cxauctionserver
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// newTestUnsafeOrder creates a buy order signed by privkey for the current auction
+func newTestUnsafeOrder(s *OpencxAuctionServer, privkey *koblitz.PrivateKey) (order *match.AuctionOrder, err error) {
+	if order, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		return
+	}
+
+	if order.AuctionID, err = s.CurrentAuctionID(); err != nil {
+		return
+	}
+
+	err = signTestOrder(order, privkey)
+	return
+}
+
+func TestUnsafeNoPuzzleOffByDefault(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestUnsafeNoPuzzleOffByDefault: %s", err)
+		return
+	}
+
+	var enabled bool
+	if enabled, err = s.UnsafeNoPuzzle(); err != nil {
+		t.Errorf("Error checking unsafe no puzzle mode: %s", err)
+		return
+	}
+
+	if enabled {
+		t.Errorf("Unsafe no puzzle mode should be off by default")
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	if order, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+
+	if err = s.PlaceUnsafePlaintextOrder(order); err == nil {
+		t.Errorf("Plaintext orders should be rejected by default")
+		return
+	}
+
+	return
+}
+
+func TestUnsafeNoPuzzleRefusedOnMainnet(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestUnsafeNoPuzzleRefusedOnMainnet: %s", err)
+		return
+	}
+
+	s.SetNetwork(match.MainnetMagic)
+	if err = s.UnsafeEnableNoPuzzle(); err == nil {
+		t.Errorf("Unsafe no puzzle mode should not be allowed on mainnet")
+		return
+	}
+
+	// Switching to mainnet after enabling it should turn it off too
+	s.SetNetwork(match.TestnetMagic)
+	if err = s.UnsafeEnableNoPuzzle(); err != nil {
+		t.Errorf("Error enabling unsafe no puzzle mode on testnet: %s", err)
+		return
+	}
+
+	s.SetNetwork(match.MainnetMagic)
+	var enabled bool
+	if enabled, err = s.UnsafeNoPuzzle(); err != nil {
+		t.Errorf("Error checking unsafe no puzzle mode: %s", err)
+		return
+	}
+
+	if enabled {
+		t.Errorf("Unsafe no puzzle mode should never be on for mainnet")
+		return
+	}
+
+	return
+}
+
+func TestUnsafeNoPuzzlePlacesOrder(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestUnsafeNoPuzzlePlacesOrder: %s", err)
+		return
+	}
+
+	if err = s.UnsafeEnableNoPuzzle(); err != nil {
+		t.Errorf("Error enabling unsafe no puzzle mode: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	if order, err = newTestUnsafeOrder(s, privkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+
+	if err = s.PlaceUnsafePlaintextOrder(order); err != nil {
+		t.Errorf("Error placing plaintext order: %s", err)
+		return
+	}
+
+	// The order should be pending in the auction right away, without solving anything
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, order.AuctionID); err != nil {
+		t.Errorf("Error viewing auction order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 1 {
+		t.Errorf("Expected 1 pending buy order but got %d", len(buyOrders))
+		return
+	}
+
+	return
+}
+
+func TestUnsafeNoPuzzleChecksLikeSolvedOrders(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestUnsafeNoPuzzleChecksLikeSolvedOrders: %s", err)
+		return
+	}
+
+	if err = s.UnsafeEnableNoPuzzle(); err != nil {
+		t.Errorf("Error enabling unsafe no puzzle mode: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	if order, err = newTestUnsafeOrder(s, privkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+
+	if err = s.PlaceUnsafePlaintextOrder(order); err != nil {
+		t.Errorf("Error placing plaintext order: %s", err)
+		return
+	}
+
+	// The same order can't be placed twice
+	if err = s.PlaceUnsafePlaintextOrder(order); err == nil {
+		t.Errorf("Placing the same plaintext order twice should fail")
+		return
+	}
+
+	// Orders have to be for the current auction
+	var otherPrivkey *koblitz.PrivateKey
+	if otherPrivkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var wrongAuctionOrder *match.AuctionOrder
+	if wrongAuctionOrder, err = newTestUnsafeOrder(s, otherPrivkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+	wrongAuctionOrder.AuctionID[0] ^= 0xff
+	if err = signTestOrder(wrongAuctionOrder, otherPrivkey); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	if err = s.PlaceUnsafePlaintextOrder(wrongAuctionOrder); err == nil {
+		t.Errorf("Plaintext order for another auction should be rejected")
+		return
+	}
+
+	// Pubkeys that cancelled everything can't place any more orders in the auction
+	var cancelled *match.AuctionOrder
+	if cancelled, err = newTestUnsafeOrder(s, otherPrivkey); err != nil {
+		t.Errorf("Error creating order: %s", err)
+		return
+	}
+
+	if _, err = s.CancelEverything(otherPrivkey.PubKey(), cancelled.AuctionID); err != nil {
+		t.Errorf("Error cancelling everything: %s", err)
+		return
+	}
+
+	if err = s.PlaceUnsafePlaintextOrder(cancelled); err == nil {
+		t.Errorf("Plaintext order from a pubkey that cancelled everything should be rejected")
+		return
+	}
+
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, order.AuctionID); err != nil {
+		t.Errorf("Error viewing auction order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 1 {
+		t.Errorf("Only the first plaintext order should be pending, got %d", len(buyOrders))
+		return
+	}
+
+	return
+}
@@ -0,0 +1,60 @@
+package cxauctionserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
+)
+
+// SetVerifyWorkers sets how many workers verify the signatures of solved orders. Zero means GOMAXPROCS
+// workers, which is the default. This starts a new verifier, so the verification metrics start over.
+func (s *OpencxAuctionServer) SetVerifyWorkers(workers int) (err error) {
+	var verifier *match.BatchVerifier
+	if verifier, err = match.NewBatchVerifier(workers); err != nil {
+		err = fmt.Errorf("Error creating batch verifier: %s", err)
+		return
+	}
+
+	s.auctionMtx.Lock()
+	s.batchVerifier = verifier
+	s.auctionMtx.Unlock()
+
+	logging.Infof("Verifying order signatures with %d workers", verifier.Workers())
+
+	return
+}
+
+// VerifyWorkers gets how many workers verify the signatures of solved orders
+func (s *OpencxAuctionServer) VerifyWorkers() (workers int, err error) {
+	var verifier *match.BatchVerifier
+	if verifier, err = s.verifier(); err != nil {
+		return
+	}
+	workers = verifier.Workers()
+	return
+}
+
+// VerifyMetrics gets the throughput metrics for verifying the signatures of solved orders
+func (s *OpencxAuctionServer) VerifyMetrics() (metrics match.VerifyMetrics, err error) {
+	var verifier *match.BatchVerifier
+	if verifier, err = s.verifier(); err != nil {
+		return
+	}
+	metrics = verifier.Metrics()
+	return
+}
+
+// verifier gets the batch verifier that solved orders are verified with
+func (s *OpencxAuctionServer) verifier() (verifier *match.BatchVerifier, err error) {
+	s.auctionMtx.RLock()
+	verifier = s.batchVerifier
+	s.auctionMtx.RUnlock()
+
+	if verifier == nil {
+		err = fmt.Errorf("Batch verifier nonexistent, set the number of verify workers first")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,451 @@
+package cxauctionserver
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+func TestSetVerifyWorkers(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestSetVerifyWorkers: %s", err)
+		return
+	}
+
+	if err = s.SetVerifyWorkers(3); err != nil {
+		t.Errorf("Error setting verify workers: %s", err)
+		return
+	}
+
+	var workers int
+	if workers, err = s.VerifyWorkers(); err != nil {
+		t.Errorf("Error getting verify workers: %s", err)
+		return
+	}
+
+	if workers != 3 {
+		t.Errorf("Set 3 verify workers but server is using %d", workers)
+		return
+	}
+
+	if err = s.SetVerifyWorkers(-1); err == nil {
+		t.Errorf("Setting a negative number of verify workers should fail")
+		return
+	}
+
+	return
+}
+
+func TestHandleSolvedOrdersVerifiesBatch(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestHandleSolvedOrdersVerifiesBatch: %s", err)
+		return
+	}
+
+	if err = s.SetVerifyWorkers(2); err != nil {
+		t.Errorf("Error setting verify workers: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var results []*match.OrderPuzzleResult
+	for _, side := range []string{"buy", "sell", "buy"} {
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(side, 1000, 1.0, privkey); err != nil {
+			t.Errorf("Error creating %s order: %s", side, err)
+			return
+		}
+		results = append(results, &match.OrderPuzzleResult{Auction: order})
+	}
+
+	// Change the last order after it was signed
+	results[2].Auction.AmountHave++
+
+	s.handleSolvedOrders(results)
+
+	var metrics match.VerifyMetrics
+	if metrics, err = s.VerifyMetrics(); err != nil {
+		t.Errorf("Error getting verify metrics: %s", err)
+		return
+	}
+
+	if metrics.OrdersVerified != 3 || metrics.InvalidOrders != 1 || metrics.Batches != 1 {
+		t.Errorf("All 3 orders should have been verified in 1 batch with 1 invalid, got %s", metrics)
+		return
+	}
+
+	var sellOrders, buyOrders []*match.AuctionOrder
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&results[0].Auction.TradingPair, results[0].Auction.AuctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(sellOrders) != 1 || len(buyOrders) != 1 {
+		t.Errorf("Only the 2 validly signed orders should be in the book, got %d sell and %d buy", len(sellOrders), len(buyOrders))
+		return
+	}
+
+	return
+}
+
+func TestHandleSolvedOrdersRecoversPanics(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestHandleSolvedOrdersRecoversPanics: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var results []*match.OrderPuzzleResult
+	for _, side := range []string{"buy", "sell"} {
+		var order *match.AuctionOrder
+		if order, err = newTestContinuousOrder(side, 1000, 1.0, privkey); err != nil {
+			t.Errorf("Error creating %s order: %s", side, err)
+			return
+		}
+		results = append(results, &match.OrderPuzzleResult{Auction: order})
+	}
+
+	// A result with no error but no order makes validation panic, put it between the good ones
+	results = append(results[:1], append([]*match.OrderPuzzleResult{new(match.OrderPuzzleResult)}, results[1:]...)...)
+
+	s.handleSolvedOrders(results)
+
+	var sellOrders, buyOrders []*match.AuctionOrder
+	if sellOrders, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&results[0].Auction.TradingPair, results[0].Auction.AuctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(sellOrders) != 1 || len(buyOrders) != 1 {
+		t.Errorf("Both good orders should be in the book despite the panicking one, got %d sell and %d buy", len(sellOrders), len(buyOrders))
+		return
+	}
+
+	return
+}
+
+func TestSolveOrderRecoversPanics(t *testing.T) {
+	// Solving a nil encrypted order panics
+	result := solveOrder(nil)
+	if result.Err == nil {
+		t.Errorf("Solving an order that panics should return an error in the result")
+		return
+	}
+
+	if result.Auction != nil {
+		t.Errorf("Solving an order that panics should not return an order")
+		return
+	}
+
+	return
+}
+
+func TestHandleSolvedOrdersDeduplicates(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestHandleSolvedOrdersDeduplicates: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	if order, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	// the same order solved twice in one batch, and then resubmitted in another
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: order}, {Auction: order.Clone()}})
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: order.Clone()}})
+
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, order.AuctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 1 {
+		t.Errorf("Order submitted 3 times should only be in the book once, got %d", len(buyOrders))
+		return
+	}
+
+	return
+}
+
+func TestHandleSolvedOrdersRejectsReusedNonce(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestHandleSolvedOrdersRejectsReusedNonce: %s", err)
+		return
+	}
+
+	var privkey, otherPrivkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+	if otherPrivkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// Different orders, all with the same nonce
+	var first, reused, otherPubkey *match.AuctionOrder
+	if first, err = newTestContinuousOrder("buy", 1000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+	if reused, err = newTestContinuousOrder("buy", 2000, 1.0, privkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+	if otherPubkey, err = newTestContinuousOrder("buy", 3000, 1.0, otherPrivkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: first}, {Auction: reused}, {Auction: otherPubkey}})
+
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&first.TradingPair, first.AuctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 2 {
+		t.Errorf("Order reusing a nonce should be rejected, but another pubkey can use it, expected 2 orders got %d", len(buyOrders))
+		return
+	}
+
+	for _, order := range buyOrders {
+		if order.AmountHave == reused.AmountHave && order.Pubkey == reused.Pubkey {
+			t.Errorf("Order reusing a nonce should not be in the book")
+			return
+		}
+	}
+
+	return
+}
+
+// newTestOrderForAuction creates a buy order signed for auctionID with the given nonce
+func newTestOrderForAuction(auctionID [32]byte, nonce [2]byte, amountHave uint64, privkey *koblitz.PrivateKey) (order *match.AuctionOrder, err error) {
+	if order, err = newTestContinuousOrder("buy", amountHave, 1.0, privkey); err != nil {
+		return
+	}
+
+	order.AuctionID = auctionID
+	order.Nonce = nonce
+	err = signTestOrder(order, privkey)
+	return
+}
+
+func TestHandleSolvedOrdersDeduplicatesAcrossClose(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestHandleSolvedOrdersDeduplicatesAcrossClose: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var order *match.AuctionOrder
+	if order, err = newTestOrderForAuction(auctionID, [2]byte{}, 1000, privkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	// One copy is solved before the auction closes, and another is solved after
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: order}})
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: order.Clone()}})
+
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&order.TradingPair, auctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 1 {
+		t.Errorf("Order solved again after its auction closed should only be in the book once, got %d", len(buyOrders))
+		return
+	}
+
+	return
+}
+
+func TestHandleSolvedOrdersRejectsReusedNonceAcrossClose(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestHandleSolvedOrdersRejectsReusedNonceAcrossClose: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// Different orders for the same auction with the same nonce
+	var first, reused *match.AuctionOrder
+	if first, err = newTestOrderForAuction(auctionID, [2]byte{0x00, 0x01}, 1000, privkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+	if reused, err = newTestOrderForAuction(auctionID, [2]byte{0x00, 0x01}, 2000, privkey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	// The second one is solved after the auction closes
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: first}})
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: reused}})
+
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&first.TradingPair, auctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 1 || buyOrders[0].AmountHave != first.AmountHave {
+		t.Errorf("Order reusing a nonce in an auction that closed should be rejected, expected only the first order, got %d orders", len(buyOrders))
+		return
+	}
+
+	return
+}
+
+func TestHandleSolvedOrdersKeepsMaxPubkeysAcrossClose(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initLongAuctionServer(); err != nil {
+		t.Errorf("Error init test server for TestHandleSolvedOrdersKeepsMaxPubkeysAcrossClose: %s", err)
+		return
+	}
+
+	if err = s.SetMaxAuctionPubkeys(1); err != nil {
+		t.Errorf("Error setting max pubkeys per auction: %s", err)
+		return
+	}
+
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting current auction ID: %s", err)
+		return
+	}
+
+	var firstKey, lateKey *koblitz.PrivateKey
+	if firstKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+	if lateKey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	var first, sameKey, late *match.AuctionOrder
+	if first, err = newTestOrderForAuction(auctionID, [2]byte{0x00, 0x01}, 1000, firstKey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+	if sameKey, err = newTestOrderForAuction(auctionID, [2]byte{0x00, 0x02}, 2000, firstKey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+	if late, err = newTestOrderForAuction(auctionID, [2]byte{0x00, 0x01}, 3000, lateKey); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	// The first pubkey fills up the auction, and the other orders are solved after it closes
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: first}})
+
+	if err = s.CommitOrdersNewAuction(); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	s.handleSolvedOrders([]*match.OrderPuzzleResult{{Auction: sameKey}, {Auction: late}})
+
+	var buyOrders []*match.AuctionOrder
+	if _, buyOrders, err = s.OpencxDB.ViewAuctionOrderBook(&first.TradingPair, auctionID); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(buyOrders) != 2 {
+		t.Errorf("Auction that closed full should only take more orders from its pubkey, expected 2 orders, got %d", len(buyOrders))
+		return
+	}
+
+	for _, order := range buyOrders {
+		if order.Pubkey == late.Pubkey {
+			t.Errorf("Order from a new pubkey solved after the full auction closed should be rejected")
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,143 @@
+package cxauctionserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/opencx/logging"
+)
+
+// DefaultWatchdogGrace is how long past the end of an auction the auction clock can be before it's
+// considered stalled
+const DefaultWatchdogGrace = 30 * time.Second
+
+// AuctionHealth is whether or not the auction clock is still advancing auctions
+type AuctionHealth struct {
+	// Healthy is false if the clock hasn't ticked within an auction time plus the grace period
+	Healthy bool
+	// LastTick is when the clock last finished a tick, or when it was started or restarted
+	LastTick time.Time
+	// Overdue is how long past the grace period the clock is, if it's unhealthy
+	Overdue time.Duration
+	// Restarts is how many times the watchdog has restarted the clock
+	Restarts uint64
+	// BatchTimeouts is how many batches took longer than the batch timeout
+	BatchTimeouts uint64
+}
+
+// SetWatchdogGrace sets how long past the end of an auction the auction clock can be before it's
+// considered stalled
+func (s *OpencxAuctionServer) SetWatchdogGrace(grace time.Duration) (err error) {
+	if grace < 0 {
+		err = fmt.Errorf("Watchdog grace period cannot be negative, got %s", grace)
+		return
+	}
+
+	s.healthMtx.Lock()
+	s.watchdogGrace = grace
+	s.healthMtx.Unlock()
+	return
+}
+
+// Health returns whether or not the auction clock is still advancing auctions
+func (s *OpencxAuctionServer) Health() (health AuctionHealth, err error) {
+	var auctionLength time.Duration
+	if auctionLength, err = s.CurrentAuctionLength(); err != nil {
+		err = fmt.Errorf("Error getting auction length for health: %s", err)
+		return
+	}
+
+	s.healthMtx.Lock()
+	health.LastTick = s.lastTick
+	health.Restarts = s.clockRestarts
+	health.BatchTimeouts = s.batchTimeouts
+	deadline := s.lastTick.Add(auctionLength + s.watchdogGrace)
+	s.healthMtx.Unlock()
+
+	if overdue := time.Since(deadline); overdue > 0 {
+		health.Overdue = overdue
+		return
+	}
+
+	health.Healthy = true
+	return
+}
+
+// StartWatchdog checks the health of the auction clock every checkInterval, logging when it has stalled.
+// If restart is true, a stalled clock is replaced with a new one. The old clock stops once it's done with
+// whatever it's stuck on.
+func (s *OpencxAuctionServer) StartWatchdog(checkInterval time.Duration, restart bool) (err error) {
+	if checkInterval <= 0 {
+		err = fmt.Errorf("Watchdog check interval must be positive, got %s", checkInterval)
+		return
+	}
+
+	s.healthMtx.Lock()
+	if s.watchdogStarted {
+		s.healthMtx.Unlock()
+		err = fmt.Errorf("Watchdog has already been started")
+		return
+	}
+	s.watchdogStarted = true
+	s.healthMtx.Unlock()
+
+	go func() {
+		for range time.Tick(checkInterval) {
+			s.checkAuctionClock(restart)
+		}
+	}()
+
+	return
+}
+
+// checkAuctionClock logs if the auction clock has stalled, and restarts it if restart is true
+func (s *OpencxAuctionServer) checkAuctionClock(restart bool) {
+	var err error
+	var health AuctionHealth
+	if health, err = s.Health(); err != nil {
+		logging.Errorf("Error checking auction clock health: %s", err)
+		return
+	}
+
+	if health.Healthy {
+		return
+	}
+
+	logging.Errorf("Auction clock has stalled, last tick was at %s and it is %s overdue", health.LastTick, health.Overdue)
+	if !restart {
+		return
+	}
+
+	s.healthMtx.Lock()
+	s.clockGen++
+	s.clockRestarts++
+	// Give the new clock a whole auction before it's considered stalled
+	s.lastTick = time.Now()
+	s.healthMtx.Unlock()
+
+	logging.Warnf("Restarting auction clock, restart %d", health.Restarts+1)
+	go s.AuctionClock()
+	return
+}
+
+// clockGeneration returns the current generation of the auction clock
+func (s *OpencxAuctionServer) clockGeneration() (generation uint64) {
+	s.healthMtx.Lock()
+	generation = s.clockGen
+	s.healthMtx.Unlock()
+	return
+}
+
+// recordTick records that the clock of the given generation finished a tick. It returns false if the clock
+// has been restarted since, so the old clock knows to stop.
+func (s *OpencxAuctionServer) recordTick(generation uint64, tickDone time.Time) (current bool) {
+	s.healthMtx.Lock()
+	defer s.healthMtx.Unlock()
+
+	if generation != s.clockGen {
+		return false
+	}
+
+	s.lastTick = tickDone
+	return true
+}
@@ -0,0 +1,144 @@
+package cxauctionserver
+
+import (
+	"testing"
+	"time"
+)
+
+// stallAuctionClock holds the db lock for stall, so the auction clock gets stuck committing the auction
+func stallAuctionClock(s *OpencxAuctionServer, stall time.Duration) {
+	s.dbLock.Lock()
+	time.Sleep(stall)
+	s.dbLock.Unlock()
+}
+
+func TestWatchdogDetectsStalledClock(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestWatchdogDetectsStalledClock: %s", err)
+		return
+	}
+
+	if err = s.SetWatchdogGrace(50 * time.Millisecond); err != nil {
+		t.Errorf("Error setting watchdog grace: %s", err)
+		return
+	}
+
+	var health AuctionHealth
+	if health, err = s.Health(); err != nil {
+		t.Errorf("Error getting health: %s", err)
+		return
+	}
+
+	if !health.Healthy {
+		t.Errorf("Auction clock should be healthy right after starting, overdue by %s", health.Overdue)
+		return
+	}
+
+	// Stall for a few auctions, without restarting the clock
+	s.dbLock.Lock()
+	time.Sleep(10 * time.Duration(testStandardAuctionTime) * time.Microsecond)
+	if health, err = s.Health(); err != nil {
+		s.dbLock.Unlock()
+		t.Errorf("Error getting health: %s", err)
+		return
+	}
+	s.checkAuctionClock(false)
+	s.dbLock.Unlock()
+
+	if health.Healthy || health.Overdue <= 0 {
+		t.Errorf("Auction clock should be unhealthy after stalling")
+		return
+	}
+
+	if s.clockGeneration() != 0 {
+		t.Errorf("Watchdog should not restart the clock if restarting is off")
+		return
+	}
+
+	// Once it's unstuck it should tick again and be healthy
+	time.Sleep(3 * time.Duration(testStandardAuctionTime) * time.Microsecond)
+	if health, err = s.Health(); err != nil {
+		t.Errorf("Error getting health: %s", err)
+		return
+	}
+
+	if !health.Healthy {
+		t.Errorf("Auction clock should be healthy once it's no longer stalled, overdue by %s", health.Overdue)
+		return
+	}
+
+	return
+}
+
+func TestWatchdogRestartsStalledClock(t *testing.T) {
+	var err error
+
+	var s *OpencxAuctionServer
+	if s, err = initTestServer(); err != nil {
+		t.Errorf("Error init test server for TestWatchdogRestartsStalledClock: %s", err)
+		return
+	}
+
+	if err = s.SetWatchdogGrace(50 * time.Millisecond); err != nil {
+		t.Errorf("Error setting watchdog grace: %s", err)
+		return
+	}
+
+	if err = s.StartWatchdog(10*time.Millisecond, true); err != nil {
+		t.Errorf("Error starting watchdog: %s", err)
+		return
+	}
+
+	if err = s.StartWatchdog(10*time.Millisecond, true); err == nil {
+		t.Errorf("Starting the watchdog twice should fail")
+		return
+	}
+
+	stallAuctionClock(s, 5*time.Duration(testStandardAuctionTime)*time.Microsecond)
+
+	var health AuctionHealth
+	if health, err = s.Health(); err != nil {
+		t.Errorf("Error getting health: %s", err)
+		return
+	}
+
+	if health.Restarts == 0 {
+		t.Errorf("Watchdog should have restarted the stalled clock")
+		return
+	}
+
+	// The restarted clock should keep advancing auctions
+	var auctionID [32]byte
+	if auctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting auction ID: %s", err)
+		return
+	}
+
+	time.Sleep(3 * time.Duration(testStandardAuctionTime) * time.Microsecond)
+
+	var newAuctionID [32]byte
+	if newAuctionID, err = s.CurrentAuctionID(); err != nil {
+		t.Errorf("Error getting auction ID: %s", err)
+		return
+	}
+
+	if newAuctionID == auctionID {
+		t.Errorf("Restarted auction clock should have started a new auction")
+		return
+	}
+
+	if health, err = s.Health(); err != nil {
+		t.Errorf("Error getting health: %s", err)
+		return
+	}
+
+	if !health.Healthy {
+		t.Errorf("Restarted auction clock should be healthy, overdue by %s", health.Overdue)
+		return
+	}
+
+	return
+}
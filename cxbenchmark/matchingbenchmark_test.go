@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/mit-dci/lit/coinparam"
 	"github.com/mit-dci/opencx/benchclient"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
 )
 
 func BenchmarkPlaceOrders(b *testing.B) {
@@ -82,3 +85,45 @@ func BenchmarkNoisePlaceOrders(b *testing.B) {
 
 	return
 }
+
+func BenchmarkMatchingOnly(b *testing.B) {
+	var err error
+
+	coinList := []*coinparam.Params{
+		&coinparam.RegressionNetParams,
+		&coinparam.LiteRegNetParams,
+	}
+
+	var pairs []*match.Pair
+	if pairs, err = match.GenerateAssetPairs(coinList); err != nil {
+		b.Errorf("Error generating pairs for matching-only benchmark: %s", err)
+		return
+	}
+
+	var pubkey [33]byte
+	runs := []int{1, 10, 100}
+	for _, varRuns := range runs {
+		b.Run(fmt.Sprintf("PlaceAndFill%d", varRuns), func(b *testing.B) {
+			var db *cxdbmemory.CXDBMatchingOnly
+			if db, err = SetupMatchingOnlyStore(coinList); err != nil {
+				b.Errorf("Error setting up matching-only store: %s", err)
+				return
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err = PlaceAndFillMatchingOnly(db, pubkey, pairs[0], varRuns); err != nil {
+					b.Errorf("Error placing and filling: %s", err)
+					return
+				}
+			}
+			b.StopTimer()
+
+			metrics := db.Metrics()
+			b.ReportMetric(metrics.OrdersPerSecond(), "orders/s")
+			b.ReportMetric(float64(metrics.Fills)/float64(b.N), "fills/op")
+		})
+	}
+
+	return
+}
@@ -0,0 +1,58 @@
+package cxbenchmark
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/opencx/cxdb/cxdbmemory"
+	"github.com/mit-dci/opencx/match"
+)
+
+// SetupMatchingOnlyStore sets up an in-memory matching-only store, so the matching engine can be
+// benchmarked without any RPC, database, or settlement overhead.
+func SetupMatchingOnlyStore(coinList []*coinparam.Params) (db *cxdbmemory.CXDBMatchingOnly, err error) {
+	db = new(cxdbmemory.CXDBMatchingOnly)
+	if err = db.SetupClient(coinList); err != nil {
+		err = fmt.Errorf("Error setting up matching-only store for benchmark: \n%s", err)
+		return
+	}
+
+	return
+}
+
+// PlaceAndFillMatchingOnly places and fills orders directly in a matching-only store, the same
+// orders that PlaceAndFill sends over RPC.
+func PlaceAndFillMatchingOnly(db *cxdbmemory.CXDBMatchingOnly, pubkey [33]byte, pair *match.Pair, howMany int) (err error) {
+	for i := 0; i < howMany; i++ {
+		orders := []struct {
+			side       string
+			amountHave uint64
+			price      float64
+		}{
+			{"buy", 1000, 1.0},
+			{"sell", 1000, 1.0},
+			{"sell", 2000, 2.0},
+			{"buy", 1000, 2.0},
+		}
+
+		for _, o := range orders {
+			order := &match.LimitOrder{
+				Pubkey:      pubkey,
+				Side:        o.side,
+				TradingPair: *pair,
+				AmountHave:  o.amountHave,
+			}
+			if err = order.SetAmountWant(o.price); err != nil {
+				err = fmt.Errorf("Error setting amount want for matching-only benchmark: \n%s", err)
+				return
+			}
+
+			if _, err = db.PlaceOrder(order); err != nil {
+				err = fmt.Errorf("Error placing order for matching-only benchmark: \n%s", err)
+				return
+			}
+		}
+	}
+
+	return
+}
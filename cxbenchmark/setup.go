@@ -3,7 +3,6 @@ package cxbenchmark
 import (
 	"fmt"
 
-	"github.com/btcsuite/golangcrypto/sha3"
 	"github.com/mit-dci/lit/coinparam"
 	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/benchclient"
@@ -11,6 +10,7 @@ import (
 	"github.com/mit-dci/opencx/cxrpc"
 	"github.com/mit-dci/opencx/cxserver"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
 )
 
 // Let these be turned into config things at some point
@@ -68,12 +68,10 @@ func SetupNoiseBenchmarkClient() (client *benchclient.BenchClient, err error) {
 
 // signBytes is used in the register method because that's an interactive process.
 // BenchClient shouldn't be responsible for interactive stuff, just providing a good
-// Go API for the RPC methods the exchange offers.
-func signBytes(client *benchclient.BenchClient, bytes []byte) (signature []byte, err error) {
+// Go API for the RPC methods the exchange offers. The bytes are signed in domain.
+func signBytes(client *benchclient.BenchClient, domain match.SigDomain, bytes []byte) (signature []byte, err error) {
 
-	sha := sha3.New256()
-	sha.Write(bytes)
-	e := sha.Sum(nil)
+	e := domain.SigHash(bytes)
 
 	if signature, err = koblitz.SignCompact(koblitz.S256(), client.PrivKey, e, false); err != nil {
 		err = fmt.Errorf("Failed to sign bytes : \n%s", err)
@@ -91,7 +89,7 @@ func registerClient(client *benchclient.BenchClient) (err error) {
 	}
 
 	var sig []byte
-	if sig, err = signBytes(client, []byte(regStringReply.RegistrationString)); err != nil {
+	if sig, err = signBytes(client, match.RegistrationDomain, []byte(regStringReply.RegistrationString)); err != nil {
 		return
 	}
 
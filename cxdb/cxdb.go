@@ -18,6 +18,8 @@ type OpencxStore interface {
 	GetBalance(*koblitz.PublicKey, *coinparam.Params) (uint64, error)
 	// GetDepositAddress gets the deposit address for a pubkey and an asset.
 	GetDepositAddress(*koblitz.PublicKey, string) (string, error)
+	// AddDepositAddress adds another deposit address for a pubkey and a coin. Deposits to the old addresses should still be found.
+	AddDepositAddress(*koblitz.PublicKey, *coinparam.Params, string) error
 	// GetPairs gets all the trading pairs that we can trade on
 	GetPairs() []*match.Pair
 	// PlaceOrder places an order in the datastore.
@@ -40,6 +42,10 @@ type OpencxStore interface {
 	GetOrdersForPubkey(*koblitz.PublicKey) ([]*match.LimitOrder, error)
 	// CancelOrder cancels an order with order id
 	CancelOrder(string) error
+	// SetFeeSchedule sets the fee taken out of amounts credited during settlement and the account it is credited to. nil means no fees.
+	SetFeeSchedule(*match.FeeSchedule) error
+	// GetFeeSchedule gets the fee taken during settlement, nil if there are no fees
+	GetFeeSchedule() *match.FeeSchedule
 }
 
 // TODO: separate out parts of the Store, like many of the account based operations (balance and whatnot), so
@@ -66,6 +72,9 @@ type OpencxAuctionStore interface {
 	PlaceAuctionOrder(*match.AuctionOrder) error
 	// ViewAuctionOrderBook takes in a trading pair and auction ID, and returns auction orders.
 	ViewAuctionOrderBook(*match.Pair, [32]byte) ([]*match.AuctionOrder, []*match.AuctionOrder, error)
+	// CancelAuctionOrders takes in a pubkey and auction ID, and removes all of the orders placed by the
+	// pubkey in that auction, returning the orders that were removed.
+	CancelAuctionOrders(*koblitz.PublicKey, [32]byte) ([]*match.AuctionOrder, error)
 	// ViewAuctionPuzzleBook takes in an auction ID, and returns encrypted auction orders, and puzzles.
 	// You don't know what auction IDs should be in the orders encrypted in the puzzle book, but this is
 	// what was submitted.
@@ -73,5 +82,18 @@ type OpencxAuctionStore interface {
 	// NewAuction takes in an auction ID, and creates a new auction, returning the "height"
 	// of the auction.
 	NewAuction([32]byte) (uint64, error)
+	// PlaceAuctionRoot stores the final root of the commitment chain for an auction that has closed.
+	PlaceAuctionRoot(*match.AuctionRoot) error
+	// ViewAuctionRoots takes in a number of auctions, and returns the roots of at most that many of the
+	// auctions that closed last, most recent first.
+	ViewAuctionRoots(uint64) ([]*match.AuctionRoot, error)
+	// PlaceAuctionFills stores the result of clearing a pair's auction and how much each of its orders
+	// filled, so clients can find out after the auction is over.
+	PlaceAuctionFills(*match.AuctionClearing, []*match.AuctionFill) error
+	// ViewAuctionFills takes in an auction ID, and returns the fills stored for it.
+	ViewAuctionFills([32]byte) ([]*match.AuctionFill, error)
+	// ViewAuctionClearings takes in an auction ID, and returns the result of clearing each of its pairs that
+	// has been cleared.
+	ViewAuctionClearings([32]byte) ([]*match.AuctionClearing, error)
 }
 
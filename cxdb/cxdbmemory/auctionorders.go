@@ -3,6 +3,7 @@ package cxdbmemory
 import (
 	"fmt"
 
+	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/logging"
 	"github.com/mit-dci/opencx/match"
 )
@@ -21,7 +22,7 @@ func (db *CXDBMemory) PlaceAuctionOrder(order *match.AuctionOrder) (err error) {
 
 	// TODO: Determine where order validation should go if not here
 	// What determines a valid order should be in one place
-	if _, err = order.Price(); err != nil {
+	if order.OrderbookPrice, err = order.Price(); err != nil {
 		err = fmt.Errorf("No price can be determined, so invalid order")
 		return
 	}
@@ -35,15 +36,9 @@ func (db *CXDBMemory) PlaceAuctionOrder(order *match.AuctionOrder) (err error) {
 // ViewAuctionOrderBook takes in a trading pair and auction ID, and returns auction orders.
 func (db *CXDBMemory) ViewAuctionOrderBook(tradingPair *match.Pair, auctionID [32]byte) (sellOrderBook []*match.AuctionOrder, buyOrderBook []*match.AuctionOrder, err error) {
 
+	// An auction nobody placed orders in has an empty order book
 	db.ordersMtx.Lock()
-	var allOrders []*match.AuctionOrder
-	var ok bool
-	if allOrders, ok = db.orders[auctionID]; !ok {
-		db.ordersMtx.Unlock()
-		err = fmt.Errorf("Could not find auctionID in the auction orderbook")
-		return
-	}
-	for _, order := range allOrders {
+	for _, order := range db.orders[auctionID] {
 		if order.TradingPair == *tradingPair {
 			if order.IsBuySide() {
 				buyOrderBook = append(buyOrderBook, order)
@@ -57,6 +52,27 @@ func (db *CXDBMemory) ViewAuctionOrderBook(tradingPair *match.Pair, auctionID [3
 	return
 }
 
+// CancelAuctionOrders takes in a pubkey and auction ID, and removes all of the orders placed by the
+// pubkey in that auction, returning the orders that were removed.
+func (db *CXDBMemory) CancelAuctionOrders(pubkey *koblitz.PublicKey, auctionID [32]byte) (cancelled []*match.AuctionOrder, err error) {
+
+	var pubkeyBytes [33]byte
+	copy(pubkeyBytes[:], pubkey.SerializeCompressed())
+
+	db.ordersMtx.Lock()
+	var remaining []*match.AuctionOrder
+	for _, order := range db.orders[auctionID] {
+		if order.Pubkey == pubkeyBytes {
+			cancelled = append(cancelled, order)
+		} else {
+			remaining = append(remaining, order)
+		}
+	}
+	db.orders[auctionID] = remaining
+	db.ordersMtx.Unlock()
+	return
+}
+
 // ViewAuctionPuzzleBook takes in an auction ID, and returns encrypted auction orders, and puzzles.
 // You don't know what auction IDs should be in the orders encrypted in the puzzle book, but this is
 // what was submitted. This also doesn't error out because if there are no orders with the auctionID
@@ -79,3 +95,55 @@ func (db *CXDBMemory) NewAuction(auctionID [32]byte) (height uint64, err error)
 	// TODO
 	return
 }
+
+// PlaceAuctionRoot stores the final root of the commitment chain for an auction that has closed.
+func (db *CXDBMemory) PlaceAuctionRoot(auctionRoot *match.AuctionRoot) (err error) {
+
+	db.rootsMtx.Lock()
+	db.roots = append(db.roots, auctionRoot)
+	db.rootsMtx.Unlock()
+	return
+}
+
+// ViewAuctionRoots takes in a number of auctions, and returns the roots of at most that many of the
+// auctions that closed last, most recent first.
+func (db *CXDBMemory) ViewAuctionRoots(numAuctions uint64) (roots []*match.AuctionRoot, err error) {
+
+	db.rootsMtx.Lock()
+	for i := len(db.roots) - 1; i >= 0 && uint64(len(roots)) < numAuctions; i-- {
+		roots = append(roots, db.roots[i])
+	}
+	db.rootsMtx.Unlock()
+	return
+}
+
+// PlaceAuctionFills stores the result of clearing a pair's auction and how much each of its orders filled.
+func (db *CXDBMemory) PlaceAuctionFills(clearing *match.AuctionClearing, fills []*match.AuctionFill) (err error) {
+
+	db.fillsMtx.Lock()
+	db.clearings[clearing.AuctionID] = append(db.clearings[clearing.AuctionID], clearing)
+	for _, fill := range fills {
+		db.fills[fill.AuctionID] = append(db.fills[fill.AuctionID], fill)
+	}
+	db.fillsMtx.Unlock()
+	return
+}
+
+// ViewAuctionFills takes in an auction ID, and returns the fills stored for it.
+func (db *CXDBMemory) ViewAuctionFills(auctionID [32]byte) (fills []*match.AuctionFill, err error) {
+
+	db.fillsMtx.Lock()
+	fills = append(fills, db.fills[auctionID]...)
+	db.fillsMtx.Unlock()
+	return
+}
+
+// ViewAuctionClearings takes in an auction ID, and returns the result of clearing each of its pairs that has
+// been cleared.
+func (db *CXDBMemory) ViewAuctionClearings(auctionID [32]byte) (clearings []*match.AuctionClearing, err error) {
+
+	db.fillsMtx.Lock()
+	clearings = append(clearings, db.clearings[auctionID]...)
+	db.fillsMtx.Unlock()
+	return
+}
@@ -2,6 +2,7 @@ package cxdbmemory
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/mit-dci/lit/coinparam"
 	"github.com/mit-dci/lit/crypto/koblitz"
@@ -18,7 +19,10 @@ func (db *CXDBMemory) RegisterUser(pubkey *koblitz.PublicKey, addressMap map[*co
 		copy(pkpair.pubkey[:], pubkey.SerializeCompressed())
 		pkpair.coin = coin
 
-		db.balances[&pkpair] = 0
+		// Registering again doesn't take away what the pubkey already has
+		if _, found := db.balances[pkpair]; !found {
+			db.balances[pkpair] = 0
+		}
 	}
 	db.balancesMtx.Unlock()
 
@@ -34,7 +38,7 @@ func (db *CXDBMemory) GetBalance(pubkey *koblitz.PublicKey, coin *coinparam.Para
 
 	db.balancesMtx.Lock()
 	var found bool
-	if amount, found = db.balances[&pkpair]; !found {
+	if amount, found = db.balances[pkpair]; !found {
 		db.balancesMtx.Unlock()
 		err = fmt.Errorf("Could not find balance, register please")
 		return
@@ -54,12 +58,17 @@ func (db *CXDBMemory) AddToBalance(pubkey *koblitz.PublicKey, amount uint64, coi
 	db.balancesMtx.Lock()
 	var found bool
 	var oldAmt uint64
-	if oldAmt, found = db.balances[&pkpair]; !found {
+	if oldAmt, found = db.balances[pkpair]; !found {
 		db.balancesMtx.Unlock()
 		err = fmt.Errorf("Could not find balance, register please")
 		return
 	}
-	db.balances[&pkpair] = oldAmt + amount
+	if oldAmt > math.MaxUint64-amount {
+		db.balancesMtx.Unlock()
+		err = fmt.Errorf("Adding %d to balance of %d would overflow", amount, oldAmt)
+		return
+	}
+	db.balances[pkpair] = oldAmt + amount
 	db.balancesMtx.Unlock()
 
 	return
@@ -75,18 +84,19 @@ func (db *CXDBMemory) Withdraw(pubkey *koblitz.PublicKey, coin *coinparam.Params
 	db.balancesMtx.Lock()
 	var found bool
 	var oldAmt uint64
-	if oldAmt, found = db.balances[&pkpair]; !found {
+	if oldAmt, found = db.balances[pkpair]; !found {
 		db.balancesMtx.Unlock()
 		err = fmt.Errorf("Could not find balance, register please")
 		return
 	}
 
 	if oldAmt < amount {
+		db.balancesMtx.Unlock()
 		err = fmt.Errorf("You do not have enough balance to withdraw this amount")
 		return
 	}
 
-	db.balances[&pkpair] = oldAmt - amount
+	db.balances[pkpair] = oldAmt - amount
 	db.balancesMtx.Unlock()
 
 	return
@@ -0,0 +1,89 @@
+package cxdbmemory
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+func TestMemoryBalances(t *testing.T) {
+	var err error
+
+	db := new(CXDBMemory)
+	if err = db.SetupClient(testMatchingCoins); err != nil {
+		t.Errorf("Error setting up memory store: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+	pubkey := privkey.PubKey()
+	coin := &coinparam.BitcoinParams
+
+	if _, err = db.GetBalance(pubkey, coin); err == nil {
+		t.Errorf("Getting the balance of a pubkey that isn't registered should fail")
+		return
+	}
+
+	if err = db.AddToBalance(pubkey, 100, coin); err == nil {
+		t.Errorf("Adding to the balance of a pubkey that isn't registered should fail")
+		return
+	}
+
+	addressMap := map[*coinparam.Params]string{
+		&coinparam.BitcoinParams:         "",
+		&coinparam.VertcoinTestNetParams: "",
+	}
+	if err = db.RegisterUser(pubkey, addressMap); err != nil {
+		t.Errorf("Error registering user: %s", err)
+		return
+	}
+
+	var balance uint64
+	if balance, err = db.GetBalance(pubkey, coin); err != nil || balance != 0 {
+		t.Errorf("Registered user should start with a balance of 0, got %d: %v", balance, err)
+		return
+	}
+
+	if err = db.AddToBalance(pubkey, 1000, coin); err != nil {
+		t.Errorf("Error adding to balance: %s", err)
+		return
+	}
+
+	if err = db.Withdraw(pubkey, coin, 300); err != nil {
+		t.Errorf("Error withdrawing: %s", err)
+		return
+	}
+
+	if err = db.Withdraw(pubkey, coin, 701); err == nil {
+		t.Errorf("Withdrawing more than the balance should fail")
+		return
+	}
+
+	// Registering again keeps the balance, and the other coin is untouched
+	if err = db.RegisterUser(pubkey, addressMap); err != nil {
+		t.Errorf("Error registering user again: %s", err)
+		return
+	}
+
+	if balance, err = db.GetBalance(pubkey, coin); err != nil || balance != 700 {
+		t.Errorf("Balance should be 700 after adding 1000 and withdrawing 300, got %d: %v", balance, err)
+		return
+	}
+
+	if balance, err = db.GetBalance(pubkey, &coinparam.VertcoinTestNetParams); err != nil || balance != 0 {
+		t.Errorf("Balance of the other coin should still be 0, got %d: %v", balance, err)
+		return
+	}
+
+	if err = db.AddToBalance(pubkey, ^uint64(0), coin); err == nil {
+		t.Errorf("Adding to a balance so it overflows should fail")
+		return
+	}
+
+	return
+}
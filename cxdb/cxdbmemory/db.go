@@ -4,21 +4,33 @@ import (
 	"sync"
 
 	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/opencx/cxdb"
 	"github.com/mit-dci/opencx/match"
 )
 
 // CXDBMemory is a super basic data store that just uses golang types for everything
 // there's no persistence, it's just used to build out the outer layers of a feature
-// before the persistent database details are worked out
+// before the persistent database details are worked out, and to run the auction server
+// in tests without a database
 type CXDBMemory struct {
-	balances    map[*pubkeyCoinPair]uint64
+	balances    map[pubkeyCoinPair]uint64
 	balancesMtx *sync.Mutex
 	puzzles     map[[32]byte][]*match.EncryptedAuctionOrder
 	puzzleMtx   *sync.Mutex
 	orders      map[[32]byte][]*match.AuctionOrder
 	ordersMtx   *sync.Mutex
+	roots       []*match.AuctionRoot
+	rootsMtx    *sync.Mutex
+	fills       map[[32]byte][]*match.AuctionFill
+	clearings   map[[32]byte][]*match.AuctionClearing
+	fillsMtx    *sync.Mutex
 }
 
+// CXDBMemory can be used anywhere the auction server needs a store
+var _ cxdb.OpencxAuctionStore = (*CXDBMemory)(nil)
+
+// pubkeyCoinPair is what a balance is kept for. It's used by value as a map key, so the same pubkey and
+// coin always find the same balance.
 type pubkeyCoinPair struct {
 	pubkey [33]byte
 	coin   *coinparam.Params
@@ -30,11 +42,17 @@ func (db *CXDBMemory) SetupClient(coins []*coinparam.Params) (err error) {
 	db.puzzles = make(map[[32]byte][]*match.EncryptedAuctionOrder)
 	db.puzzleMtx = new(sync.Mutex)
 
-	db.balances = make(map[*pubkeyCoinPair]uint64)
+	db.balances = make(map[pubkeyCoinPair]uint64)
 	db.balancesMtx = new(sync.Mutex)
 
 	db.orders = make(map[[32]byte][]*match.AuctionOrder)
 	db.ordersMtx = new(sync.Mutex)
 
+	db.rootsMtx = new(sync.Mutex)
+
+	db.fills = make(map[[32]byte][]*match.AuctionFill)
+	db.clearings = make(map[[32]byte][]*match.AuctionClearing)
+	db.fillsMtx = new(sync.Mutex)
+
 	return
 }
@@ -0,0 +1,383 @@
+package cxdbmemory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// CXDBMatchingOnly is an exchange store that only runs matching. Nothing is persisted and matched
+// orders are never settled, balances don't exist at all. This is used to benchmark the matching
+// engine without any database or settlement overhead.
+type CXDBMatchingOnly struct {
+	pairs   []*match.Pair
+	books   map[match.Pair]*matchingBook
+	orders  map[string]*match.LimitOrder
+	metrics MatchingMetrics
+	bookMtx *sync.Mutex
+}
+
+// matchingBook is the buy and sell side for a single pair. Both sides are kept in priority order,
+// so the best order is always at the front.
+type matchingBook struct {
+	buyOrders  []*match.LimitOrder
+	sellOrders []*match.LimitOrder
+}
+
+// MatchingMetrics are the throughput metrics for the matching-only store
+type MatchingMetrics struct {
+	// OrdersPlaced is the number of orders that have been placed
+	OrdersPlaced uint64
+	// OrdersFilled is the number of orders that have been completely filled
+	OrdersFilled uint64
+	// Fills is the number of times two orders have been matched against each other
+	Fills uint64
+	// MatchingTime is the total time spent placing and matching orders
+	MatchingTime time.Duration
+	// Start is when the store was set up
+	Start time.Time
+}
+
+// OrdersPerSecond is the number of orders placed per second of time spent matching
+func (m MatchingMetrics) OrdersPerSecond() (ordersPerSecond float64) {
+	if m.MatchingTime == 0 {
+		return
+	}
+	ordersPerSecond = float64(m.OrdersPlaced) / m.MatchingTime.Seconds()
+	return
+}
+
+// String returns a summary of the metrics, to be logged
+func (m MatchingMetrics) String() string {
+	return fmt.Sprintf("%d orders placed, %d orders filled, %d fills in %s of matching (%f orders/s) over %s", m.OrdersPlaced, m.OrdersFilled, m.Fills, m.MatchingTime, m.OrdersPerSecond(), time.Since(m.Start))
+}
+
+// SetupClient makes sure that whatever things need to be done before we use the datastore can be done before we need to use the datastore.
+func (db *CXDBMatchingOnly) SetupClient(coins []*coinparam.Params) (err error) {
+	if db.pairs, err = match.GenerateAssetPairs(coins); err != nil {
+		err = fmt.Errorf("Error generating asset pairs for matching-only store: %s", err)
+		return
+	}
+
+	db.books = make(map[match.Pair]*matchingBook)
+	for _, pair := range db.pairs {
+		db.books[*pair] = new(matchingBook)
+	}
+	db.orders = make(map[string]*match.LimitOrder)
+	db.metrics = MatchingMetrics{Start: time.Now()}
+	db.bookMtx = new(sync.Mutex)
+
+	return
+}
+
+// Metrics returns the current throughput metrics
+func (db *CXDBMatchingOnly) Metrics() (metrics MatchingMetrics) {
+	db.bookMtx.Lock()
+	metrics = db.metrics
+	db.bookMtx.Unlock()
+	return
+}
+
+// RegisterUser does nothing, there are no accounts in matching-only mode
+func (db *CXDBMatchingOnly) RegisterUser(pubkey *koblitz.PublicKey, addressMap map[*coinparam.Params]string) (err error) {
+	return
+}
+
+// GetBalance always returns a zero balance, there are no balances in matching-only mode
+func (db *CXDBMatchingOnly) GetBalance(pubkey *koblitz.PublicKey, coin *coinparam.Params) (balance uint64, err error) {
+	return
+}
+
+// GetDepositAddress returns an error, there are no deposits in matching-only mode
+func (db *CXDBMatchingOnly) GetDepositAddress(pubkey *koblitz.PublicKey, asset string) (addr string, err error) {
+	err = fmt.Errorf("Deposits are not supported in matching-only mode")
+	return
+}
+
+// AddDepositAddress returns an error, there are no deposits in matching-only mode
+func (db *CXDBMatchingOnly) AddDepositAddress(pubkey *koblitz.PublicKey, coin *coinparam.Params, addr string) (err error) {
+	err = fmt.Errorf("Deposits are not supported in matching-only mode")
+	return
+}
+
+// SetFeeSchedule returns an error for any fees, nothing gets settled in matching-only mode
+func (db *CXDBMatchingOnly) SetFeeSchedule(schedule *match.FeeSchedule) (err error) {
+	if schedule != nil {
+		err = fmt.Errorf("Fees are not supported in matching-only mode")
+	}
+	return
+}
+
+// GetFeeSchedule always returns nil, nothing gets settled in matching-only mode
+func (db *CXDBMatchingOnly) GetFeeSchedule() (schedule *match.FeeSchedule) {
+	return
+}
+
+// GetPairs gets all the trading pairs that we can trade on
+func (db *CXDBMatchingOnly) GetPairs() (pairArray []*match.Pair) {
+	pairArray = db.pairs
+	return
+}
+
+// PlaceOrder places an order in the in-memory book and runs matching for the order's pair
+func (db *CXDBMatchingOnly) PlaceOrder(order *match.LimitOrder) (orderid string, err error) {
+	if !order.IsBuySide() && !order.IsSellSide() {
+		err = fmt.Errorf("Order is not buy or sell, cannot place it")
+		return
+	}
+
+	// The price is fixed when the order is placed, like the price column in the SQL store
+	if order.OrderbookPrice, err = order.Price(); err != nil {
+		err = fmt.Errorf("Error getting price of order to place: %s", err)
+		return
+	}
+
+	if err = order.SetID(); err != nil {
+		err = fmt.Errorf("Error setting ID of order to place: %s", err)
+		return
+	}
+	order.Timestamp = time.Now()
+
+	db.bookMtx.Lock()
+	defer db.bookMtx.Unlock()
+
+	start := time.Now()
+	defer func() {
+		db.metrics.MatchingTime += time.Since(start)
+	}()
+
+	book, found := db.books[order.TradingPair]
+	if !found {
+		err = fmt.Errorf("Trading pair does not exist, try the other way around (e.g. ltc/btc => btc/ltc)")
+		return
+	}
+
+	if order.IsBuySide() {
+		book.buyOrders = insertByPriority(book.buyOrders, order)
+	} else {
+		book.sellOrders = insertByPriority(book.sellOrders, order)
+	}
+	db.orders[order.OrderID] = order
+	db.metrics.OrdersPlaced++
+
+	db.runMatching(book)
+
+	orderid = order.OrderID
+	return
+}
+
+// runMatching matches the best buy and sell orders in the book until the prices no longer cross.
+// This has the same fill rules as the SQL matching engine, but doesn't settle anything.
+// The book lock should be held while calling this.
+func (db *CXDBMatchingOnly) runMatching(book *matchingBook) {
+	for len(book.buyOrders) > 0 && len(book.sellOrders) > 0 {
+		currBuyOrder := book.buyOrders[0]
+		currSellOrder := book.sellOrders[0]
+
+		if currBuyOrder.OrderbookPrice < currSellOrder.OrderbookPrice {
+			return
+		}
+
+		db.metrics.Fills++
+		if currBuyOrder.AmountHave > currSellOrder.AmountWant {
+			if currBuyOrder.AmountWant < currSellOrder.AmountHave {
+				currBuyOrder.AmountWant = 0
+			} else {
+				currBuyOrder.AmountWant -= currSellOrder.AmountHave
+			}
+			currBuyOrder.AmountHave -= currSellOrder.AmountWant
+
+			book.sellOrders = book.sellOrders[1:]
+			db.removeFilled(currSellOrder)
+		} else if currBuyOrder.AmountHave < currSellOrder.AmountWant {
+			if currSellOrder.AmountHave < currBuyOrder.AmountWant {
+				currSellOrder.AmountHave = 0
+			} else {
+				currSellOrder.AmountHave -= currBuyOrder.AmountWant
+			}
+			currSellOrder.AmountWant -= currBuyOrder.AmountHave
+
+			book.buyOrders = book.buyOrders[1:]
+			db.removeFilled(currBuyOrder)
+		} else {
+			book.buyOrders = book.buyOrders[1:]
+			book.sellOrders = book.sellOrders[1:]
+			db.removeFilled(currBuyOrder)
+			db.removeFilled(currSellOrder)
+		}
+	}
+
+	return
+}
+
+// removeFilled forgets about an order that has been filled
+func (db *CXDBMatchingOnly) removeFilled(order *match.LimitOrder) {
+	delete(db.orders, order.OrderID)
+	db.metrics.OrdersFilled++
+	return
+}
+
+// insertByPriority inserts an order into one side of the book, behind every order with a better or
+// equal price. Buy orders with higher prices are better, sell orders with lower prices are better.
+func insertByPriority(side []*match.LimitOrder, order *match.LimitOrder) (newSide []*match.LimitOrder) {
+	i := sort.Search(len(side), func(i int) bool {
+		if order.IsBuySide() {
+			return side[i].OrderbookPrice < order.OrderbookPrice
+		}
+		return side[i].OrderbookPrice > order.OrderbookPrice
+	})
+
+	newSide = append(side, nil)
+	copy(newSide[i+1:], newSide[i:])
+	newSide[i] = order
+	return
+}
+
+// ViewOrderBook takes in a trading pair and returns sell orders and buy orders separately
+func (db *CXDBMatchingOnly) ViewOrderBook(pair *match.Pair) (sellOrderBook []*match.LimitOrder, buyOrderBook []*match.LimitOrder, err error) {
+	db.bookMtx.Lock()
+	defer db.bookMtx.Unlock()
+
+	book, found := db.books[*pair]
+	if !found {
+		err = fmt.Errorf("Trading pair %s does not exist", pair.String())
+		return
+	}
+
+	for _, order := range book.sellOrders {
+		orderCopy := *order
+		sellOrderBook = append(sellOrderBook, &orderCopy)
+	}
+	for _, order := range book.buyOrders {
+		orderCopy := *order
+		buyOrderBook = append(buyOrderBook, &orderCopy)
+	}
+
+	return
+}
+
+// CalculatePrice calculates the price based on the volume and side of the orders, the same way the
+// SQL store does.
+func (db *CXDBMatchingOnly) CalculatePrice(pair *match.Pair) (price float64, err error) {
+	db.bookMtx.Lock()
+	defer db.bookMtx.Unlock()
+
+	book, found := db.books[*pair]
+	if !found {
+		err = fmt.Errorf("Trading pair %s does not exist", pair.String())
+		return
+	}
+
+	var sellExpectation float64
+	var buyExpectation float64
+	var totalVolume uint64
+	for _, order := range book.buyOrders {
+		buyExpectation += float64(order.AmountHave) * order.OrderbookPrice
+		totalVolume += order.AmountHave
+	}
+	for _, order := range book.sellOrders {
+		sellExpectation += float64(order.AmountWant) * order.OrderbookPrice
+		totalVolume += order.AmountWant
+	}
+
+	if totalVolume == 0 {
+		return
+	}
+
+	price = (sellExpectation + buyExpectation) / float64(totalVolume)
+	return
+}
+
+// Withdraw returns an error, there are no balances to withdraw in matching-only mode
+func (db *CXDBMatchingOnly) Withdraw(pubkey *koblitz.PublicKey, coin *coinparam.Params, amount uint64) (err error) {
+	err = fmt.Errorf("Withdrawals are not supported in matching-only mode")
+	return
+}
+
+// UpdateDeposits does nothing, there are no deposits in matching-only mode
+func (db *CXDBMatchingOnly) UpdateDeposits(deposits []match.Deposit, blockheight uint64, coin *coinparam.Params) (err error) {
+	return
+}
+
+// AddToBalance does nothing, there are no balances in matching-only mode
+func (db *CXDBMatchingOnly) AddToBalance(pubkey *koblitz.PublicKey, amount uint64, coin *coinparam.Params) (err error) {
+	return
+}
+
+// GetDepositAddressMap returns an empty map, there are no deposit addresses in matching-only mode
+func (db *CXDBMatchingOnly) GetDepositAddressMap(coin *coinparam.Params) (depositAddressMap map[string]*koblitz.PublicKey, err error) {
+	depositAddressMap = make(map[string]*koblitz.PublicKey)
+	return
+}
+
+// GetOrder gets an order from an OrderID
+func (db *CXDBMatchingOnly) GetOrder(orderID string) (order *match.LimitOrder, err error) {
+	db.bookMtx.Lock()
+	defer db.bookMtx.Unlock()
+
+	storedOrder, found := db.orders[orderID]
+	if !found {
+		err = fmt.Errorf("Could not find order with ID %s", orderID)
+		return
+	}
+
+	orderCopy := *storedOrder
+	order = &orderCopy
+	return
+}
+
+// GetOrdersForPubkey gets orders for a specific pubkey
+func (db *CXDBMatchingOnly) GetOrdersForPubkey(pubkey *koblitz.PublicKey) (orders []*match.LimitOrder, err error) {
+	db.bookMtx.Lock()
+	defer db.bookMtx.Unlock()
+
+	var pubkeyBytes [33]byte
+	copy(pubkeyBytes[:], pubkey.SerializeCompressed())
+	for _, order := range db.orders {
+		if order.Pubkey == pubkeyBytes {
+			orderCopy := *order
+			orders = append(orders, &orderCopy)
+		}
+	}
+
+	return
+}
+
+// CancelOrder cancels an order with order id
+func (db *CXDBMatchingOnly) CancelOrder(orderID string) (err error) {
+	db.bookMtx.Lock()
+	defer db.bookMtx.Unlock()
+
+	order, found := db.orders[orderID]
+	if !found {
+		err = fmt.Errorf("Could not find order with ID %s to cancel", orderID)
+		return
+	}
+
+	book := db.books[order.TradingPair]
+	if order.IsBuySide() {
+		book.buyOrders = removeOrder(book.buyOrders, orderID)
+	} else {
+		book.sellOrders = removeOrder(book.sellOrders, orderID)
+	}
+	delete(db.orders, orderID)
+
+	return
+}
+
+// removeOrder removes the order with orderID from one side of the book
+func removeOrder(side []*match.LimitOrder, orderID string) (newSide []*match.LimitOrder) {
+	newSide = side
+	for i, order := range side {
+		if order.OrderID == orderID {
+			newSide = append(side[:i], side[i+1:]...)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,131 @@
+package cxdbmemory
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/opencx/match"
+)
+
+var (
+	testMatchingCoins = []*coinparam.Params{
+		&coinparam.BitcoinParams,
+		&coinparam.VertcoinTestNetParams,
+	}
+	testMatchingPair = match.Pair{
+		AssetWant: match.BTC,
+		AssetHave: match.VTCTest,
+	}
+)
+
+func newTestMatchingOrder(side string, amountHave uint64, price float64) (order *match.LimitOrder, err error) {
+	order = &match.LimitOrder{
+		Side:        side,
+		TradingPair: testMatchingPair,
+		AmountHave:  amountHave,
+	}
+	err = order.SetAmountWant(price)
+	return
+}
+
+func TestMatchingOnlyPartialFill(t *testing.T) {
+	var err error
+
+	db := new(CXDBMatchingOnly)
+	if err = db.SetupClient(testMatchingCoins); err != nil {
+		t.Errorf("Error setting up matching-only store: %s", err)
+		return
+	}
+
+	var buyOrder *match.LimitOrder
+	if buyOrder, err = newTestMatchingOrder("buy", 2000, 1.0); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	var sellOrder *match.LimitOrder
+	if sellOrder, err = newTestMatchingOrder("sell", 1000, 1.0); err != nil {
+		t.Errorf("Error creating sell order: %s", err)
+		return
+	}
+
+	var buyID string
+	if buyID, err = db.PlaceOrder(buyOrder); err != nil {
+		t.Errorf("Error placing buy order: %s", err)
+		return
+	}
+
+	if _, err = db.PlaceOrder(sellOrder); err != nil {
+		t.Errorf("Error placing sell order: %s", err)
+		return
+	}
+
+	var sellBook []*match.LimitOrder
+	var buyBook []*match.LimitOrder
+	if sellBook, buyBook, err = db.ViewOrderBook(&testMatchingPair); err != nil {
+		t.Errorf("Error viewing order book: %s", err)
+		return
+	}
+
+	if len(sellBook) != 0 {
+		t.Errorf("Sell order should have been filled, but there are %d sell orders", len(sellBook))
+		return
+	}
+
+	if len(buyBook) != 1 || buyBook[0].OrderID != buyID {
+		t.Errorf("Buy order should be partially filled and still in the book")
+		return
+	}
+
+	if buyBook[0].AmountHave != 1000 || buyBook[0].AmountWant != 1000 {
+		t.Errorf("Buy order should have 1000 have and 1000 want left, got %d have and %d want", buyBook[0].AmountHave, buyBook[0].AmountWant)
+		return
+	}
+
+	metrics := db.Metrics()
+	if metrics.OrdersPlaced != 2 || metrics.OrdersFilled != 1 || metrics.Fills != 1 {
+		t.Errorf("Expected 2 orders placed, 1 filled, 1 fill, got %s", metrics.String())
+		return
+	}
+
+	return
+}
+
+func TestMatchingOnlyNoCross(t *testing.T) {
+	var err error
+
+	db := new(CXDBMatchingOnly)
+	if err = db.SetupClient(testMatchingCoins); err != nil {
+		t.Errorf("Error setting up matching-only store: %s", err)
+		return
+	}
+
+	var buyOrder *match.LimitOrder
+	if buyOrder, err = newTestMatchingOrder("buy", 1000, 1.0); err != nil {
+		t.Errorf("Error creating buy order: %s", err)
+		return
+	}
+
+	var sellOrder *match.LimitOrder
+	if sellOrder, err = newTestMatchingOrder("sell", 1000, 2.0); err != nil {
+		t.Errorf("Error creating sell order: %s", err)
+		return
+	}
+
+	if _, err = db.PlaceOrder(buyOrder); err != nil {
+		t.Errorf("Error placing buy order: %s", err)
+		return
+	}
+
+	if _, err = db.PlaceOrder(sellOrder); err != nil {
+		t.Errorf("Error placing sell order: %s", err)
+		return
+	}
+
+	if metrics := db.Metrics(); metrics.Fills != 0 {
+		t.Errorf("Orders with prices that don't cross should not be matched, got %s", metrics.String())
+		return
+	}
+
+	return
+}
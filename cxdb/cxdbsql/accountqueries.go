@@ -44,7 +44,7 @@ func (db *DB) InitializeAccountBalances(pubkey *koblitz.PublicKey) (err error) {
 	}()
 
 	// Use the balance schema
-	if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.balanceSchema)); err != nil {
 		return
 	}
 
@@ -59,6 +59,17 @@ func (db *DB) InitializeAccountBalances(pubkey *koblitz.PublicKey) (err error) {
 	return nil
 }
 
+// AddDepositAddress adds another deposit address for a pubkey. The deposit table is unique on the pubkey
+// and address together, so the old addresses are kept and deposits to them are still found.
+func (db *DB) AddDepositAddress(pubkey *koblitz.PublicKey, coinType *coinparam.Params, addr string) (err error) {
+	if err = db.InsertDepositAddresses(pubkey, map[*coinparam.Params]string{coinType: addr}); err != nil {
+		err = fmt.Errorf("Error adding deposit address: \n%s", err)
+		return
+	}
+
+	return
+}
+
 // InsertDepositAddresses inserts deposit addresses based on the addressmap you give it
 func (db *DB) InsertDepositAddresses(pubkey *koblitz.PublicKey, addressMap map[*coinparam.Params]string) (err error) {
 	// begin the transaction
@@ -80,14 +91,14 @@ func (db *DB) InsertDepositAddresses(pubkey *koblitz.PublicKey, addressMap map[*
 	logging.Infof("pubkey length (serialized, compressed): %d", len(pubkey.SerializeCompressed()))
 
 	// use deposit schema
-	if _, err = tx.Exec("USE " + db.depositSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.depositSchema)); err != nil {
 		return
 	}
 
 	for chain, addr := range addressMap {
 		logging.Infof("Addr: %s, len: %d", addr, len(addr))
 		// insert into db
-		insertDepositAddrQuery := fmt.Sprintf("INSERT INTO %s VALUES ('%x', '%s') ON DUPLICATE KEY UPDATE address='%s';", chain.Name, pubkey.SerializeCompressed(), addr, addr)
+		insertDepositAddrQuery := fmt.Sprintf("INSERT INTO %s VALUES ('%x', '%s') %s;", chain.Name, pubkey.SerializeCompressed(), addr, db.dialect.OnDuplicateUpdate("pubkey, address", fmt.Sprintf("address='%s'", addr)))
 		logging.Infof("%s", insertDepositAddrQuery)
 		if _, err = tx.Exec(insertDepositAddrQuery); err != nil {
 			return
@@ -4,7 +4,11 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"math/big"
+	"strings"
+	"time"
 
+	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/logging"
 	"github.com/mit-dci/opencx/match"
 )
@@ -28,7 +32,7 @@ func (db *DB) PlaceAuctionPuzzle(encryptedOrder *match.EncryptedAuctionOrder) (e
 	}()
 
 	// We don't really care about the result when trying to use a schema
-	if _, err = tx.Exec("USE " + db.puzzleSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.puzzleSchema)); err != nil {
 		err = fmt.Errorf("Error trying to use auction schema: %s", err)
 		return
 	}
@@ -39,8 +43,8 @@ func (db *DB) PlaceAuctionPuzzle(encryptedOrder *match.EncryptedAuctionOrder) (e
 		return
 	}
 
-	// We concatenate ciphertext and puzzle and set "selected" to 1 by default
-	placeAuctionPuzzleQuery := fmt.Sprintf("INSERT INTO %s VALUES ('%x', '%x', 1);", db.puzzleTable, orderBytes, encryptedOrder.IntendedAuction)
+	// We concatenate ciphertext and puzzle and set "selected" to true by default
+	placeAuctionPuzzleQuery := fmt.Sprintf("INSERT INTO %s VALUES ('%x', '%x', TRUE);", db.puzzleTable, orderBytes, encryptedOrder.IntendedAuction)
 	if _, err = tx.Exec(placeAuctionPuzzleQuery); err != nil {
 		err = fmt.Errorf("Error adding auction puzzle to puzzle orders: %s", err)
 		return
@@ -67,7 +71,7 @@ func (db *DB) PlaceAuctionOrder(order *match.AuctionOrder) (err error) {
 		err = tx.Commit()
 	}()
 
-	if _, err = tx.Exec("USE " + db.auctionSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.auctionSchema)); err != nil {
 		err = fmt.Errorf("Error while placing solved auction order: %s", err)
 		return
 	}
@@ -85,6 +89,77 @@ func (db *DB) ViewAuctionOrderBook(tradingPair *match.Pair, auctionID [32]byte)
 	return
 }
 
+// CancelAuctionOrders takes in a pubkey and auction ID, and removes all of the orders placed by the
+// pubkey in that auction, returning the orders that were removed.
+func (db *DB) CancelAuctionOrders(pubkey *koblitz.PublicKey, auctionID [32]byte) (cancelled []*match.AuctionOrder, err error) {
+
+	var tx *sql.Tx
+	if tx, err = db.DBHandler.Begin(); err != nil {
+		err = fmt.Errorf("Error when beginning transaction for CancelAuctionOrders: %s", err)
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("Error while cancelling auction orders: \n%s", err)
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(db.dialect.UseSchema(db.auctionSchema)); err != nil {
+		err = fmt.Errorf("Error trying to use auction schema: %s", err)
+		return
+	}
+
+	var rows *sql.Rows
+	var auctionIDBytes []byte
+	var nonceBytes []byte
+	var thisOrder *match.AuctionOrder
+	for _, pair := range db.pairsArray {
+
+		// First get the orders so we can return them
+		queryCancelledOrders := fmt.Sprintf("SELECT side, price, amountHave, amountWant, auctionID, nonce FROM %s WHERE pubkey='%x' AND auctionID='%x';", pair, pubkey.SerializeCompressed(), auctionID)
+		if rows, err = tx.Query(queryCancelledOrders); err != nil {
+			err = fmt.Errorf("Error querying for orders to cancel: %s", err)
+			return
+		}
+
+		for rows.Next() {
+			thisOrder = new(match.AuctionOrder)
+			if err = rows.Scan(&thisOrder.Side, &thisOrder.OrderbookPrice, &thisOrder.AmountHave, &thisOrder.AmountWant, &auctionIDBytes, &nonceBytes); err != nil {
+				rows.Close()
+				err = fmt.Errorf("Error scanning order to cancel: %s", err)
+				return
+			}
+
+			for _, byteArray := range [][]byte{auctionIDBytes, nonceBytes} {
+				if _, err = hex.Decode(byteArray, byteArray); err != nil {
+					rows.Close()
+					err = fmt.Errorf("Error decoding bytes for order to cancel: %s", err)
+					return
+				}
+			}
+
+			copy(thisOrder.Pubkey[:], pubkey.SerializeCompressed())
+			copy(thisOrder.AuctionID[:], auctionIDBytes)
+			copy(thisOrder.Nonce[:], nonceBytes)
+			thisOrder.TradingPair = *pair
+			cancelled = append(cancelled, thisOrder)
+		}
+		rows.Close()
+
+		deleteOrdersQuery := fmt.Sprintf("DELETE FROM %s WHERE pubkey='%x' AND auctionID='%x';", pair, pubkey.SerializeCompressed(), auctionID)
+		if _, err = tx.Exec(deleteOrdersQuery); err != nil {
+			err = fmt.Errorf("Error deleting orders while cancelling: %s", err)
+			return
+		}
+	}
+
+	return
+}
+
 // ViewAuctionPuzzleBook takes in an auction ID, and returns encrypted auction orders, and puzzles.
 // You don't know what auction IDs should be in the orders encrypted in the puzzle book, but this is
 // what was submitted.
@@ -106,7 +181,7 @@ func (db *DB) ViewAuctionPuzzleBook(auctionID [32]byte) (orders []*match.Encrypt
 	}()
 
 	// We don't really care about the result when trying to use a schema
-	if _, err = tx.Exec("USE " + db.puzzleSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.puzzleSchema)); err != nil {
 		err = fmt.Errorf("Error trying to use auction schema: %s", err)
 		return
 	}
@@ -168,7 +243,7 @@ func (db *DB) NewAuction(auctionID [32]byte) (height uint64, err error) {
 	}()
 
 	// We don't really care about the result when trying to use a schema
-	if _, err = tx.Exec("USE " + db.auctionOrderSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.auctionOrderSchema)); err != nil {
 		err = fmt.Errorf("Error trying to use auction order schema: %s", err)
 		return
 	}
@@ -197,6 +272,282 @@ func (db *DB) NewAuction(auctionID [32]byte) (height uint64, err error) {
 	return
 }
 
+// PlaceAuctionRoot stores the final root of the commitment chain for an auction that has closed.
+func (db *DB) PlaceAuctionRoot(auctionRoot *match.AuctionRoot) (err error) {
+
+	var tx *sql.Tx
+	if tx, err = db.DBHandler.Begin(); err != nil {
+		err = fmt.Errorf("Error when beginning transaction for PlaceAuctionRoot: %s", err)
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("Error while placing auction root: \n%s", err)
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(db.dialect.UseSchema(db.auctionOrderSchema)); err != nil {
+		err = fmt.Errorf("Error trying to use auction order schema: %s", err)
+		return
+	}
+
+	insertRootQuery := fmt.Sprintf("INSERT INTO %s (auctionID, root, numPuzzles, closeTime) VALUES ('%x', '%x', %d, %d);", db.auctionRootTable, auctionRoot.AuctionID, auctionRoot.Root, auctionRoot.NumPuzzles, auctionRoot.Time.UnixNano())
+	if _, err = tx.Exec(insertRootQuery); err != nil {
+		err = fmt.Errorf("Error inserting auction root: %s", err)
+		return
+	}
+
+	return
+}
+
+// ViewAuctionRoots takes in a number of auctions, and returns the roots of at most that many of the
+// auctions that closed last, most recent first.
+func (db *DB) ViewAuctionRoots(numAuctions uint64) (roots []*match.AuctionRoot, err error) {
+
+	var tx *sql.Tx
+	if tx, err = db.DBHandler.Begin(); err != nil {
+		err = fmt.Errorf("Error when beginning transaction for ViewAuctionRoots: %s", err)
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("Error while viewing auction roots: \n%s", err)
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(db.dialect.UseSchema(db.auctionOrderSchema)); err != nil {
+		err = fmt.Errorf("Error trying to use auction order schema: %s", err)
+		return
+	}
+
+	var rows *sql.Rows
+	selectRootsQuery := fmt.Sprintf("SELECT auctionID, root, numPuzzles, closeTime FROM %s ORDER BY rootNumber DESC LIMIT %d;", db.auctionRootTable, numAuctions)
+	if rows, err = tx.Query(selectRootsQuery); err != nil {
+		err = fmt.Errorf("Could not query for auction roots: %s", err)
+		return
+	}
+	defer rows.Close()
+
+	var auctionIDBytes []byte
+	var rootBytes []byte
+	var closeTime int64
+	for rows.Next() {
+		thisRoot := new(match.AuctionRoot)
+		if err = rows.Scan(&auctionIDBytes, &rootBytes, &thisRoot.NumPuzzles, &closeTime); err != nil {
+			err = fmt.Errorf("Error scanning auction root: %s", err)
+			return
+		}
+
+		for _, byteArray := range [][]byte{auctionIDBytes, rootBytes} {
+			if _, err = hex.Decode(byteArray, byteArray); err != nil {
+				err = fmt.Errorf("Error decoding bytes for auction root: %s", err)
+				return
+			}
+		}
+
+		copy(thisRoot.AuctionID[:], auctionIDBytes)
+		copy(thisRoot.Root[:], rootBytes)
+		thisRoot.Time = time.Unix(0, closeTime)
+		roots = append(roots, thisRoot)
+	}
+
+	return
+}
+
+// PlaceAuctionFills stores the result of clearing a pair's auction and how much each of its orders filled,
+// so clients can find out after the auction is over.
+func (db *DB) PlaceAuctionFills(clearing *match.AuctionClearing, fills []*match.AuctionFill) (err error) {
+
+	var tx *sql.Tx
+	if tx, err = db.DBHandler.Begin(); err != nil {
+		err = fmt.Errorf("Error when beginning transaction for PlaceAuctionFills: %s", err)
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("Error while placing auction fills: \n%s", err)
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(db.dialect.UseSchema(db.auctionOrderSchema)); err != nil {
+		err = fmt.Errorf("Error trying to use auction order schema: %s", err)
+		return
+	}
+
+	// Placeholders are different for every dialect, so we ask it for them
+	var placeholders []string
+	for i := 1; i <= 8; i++ {
+		placeholders = append(placeholders, db.dialect.Placeholder(i))
+	}
+
+	// Clearing prices are stored exactly as a fraction, and empty if nothing matched
+	var clearingPriceString string
+	if clearing.ClearingPrice != nil {
+		clearingPriceString = clearing.ClearingPrice.RatString()
+	}
+	insertClearingQuery := fmt.Sprintf("INSERT INTO %s (auctionID, pair, clearingPrice, noMatch) VALUES (%s);", db.auctionClearingTable, strings.Join(placeholders[:4], ", "))
+	if _, err = tx.Exec(insertClearingQuery, hex.EncodeToString(clearing.AuctionID[:]), clearing.Pair.String(), clearingPriceString, clearing.NoMatch); err != nil {
+		err = fmt.Errorf("Error inserting auction clearing: %s", err)
+		return
+	}
+
+	insertFillQuery := fmt.Sprintf("INSERT INTO %s (auctionID, hashedOrder, pubkey, pair, side, clearingPrice, amountOrdered, amountFilled) VALUES (%s);", db.auctionFillTable, strings.Join(placeholders, ", "))
+	for _, fill := range fills {
+		var priceString string
+		if fill.ClearingPrice != nil {
+			priceString = fill.ClearingPrice.RatString()
+		}
+		if _, err = tx.Exec(insertFillQuery, hex.EncodeToString(fill.AuctionID[:]), hex.EncodeToString(fill.OrderHash[:]), hex.EncodeToString(fill.Pubkey[:]), fill.Pair.String(), fill.Side, priceString, fill.AmountOrdered, fill.AmountFilled); err != nil {
+			err = fmt.Errorf("Error inserting auction fill: %s", err)
+			return
+		}
+	}
+
+	return
+}
+
+// ViewAuctionFills takes in an auction ID, and returns the fills stored for it.
+func (db *DB) ViewAuctionFills(auctionID [32]byte) (fills []*match.AuctionFill, err error) {
+
+	var tx *sql.Tx
+	if tx, err = db.DBHandler.Begin(); err != nil {
+		err = fmt.Errorf("Error when beginning transaction for ViewAuctionFills: %s", err)
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("Error while viewing auction fills: \n%s", err)
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(db.dialect.UseSchema(db.auctionOrderSchema)); err != nil {
+		err = fmt.Errorf("Error trying to use auction order schema: %s", err)
+		return
+	}
+
+	var rows *sql.Rows
+	selectFillsQuery := fmt.Sprintf("SELECT hashedOrder, pubkey, pair, side, clearingPrice, amountOrdered, amountFilled FROM %s WHERE auctionID=%s;", db.auctionFillTable, db.dialect.Placeholder(1))
+	if rows, err = tx.Query(selectFillsQuery, hex.EncodeToString(auctionID[:])); err != nil {
+		err = fmt.Errorf("Could not query for auction fills: %s", err)
+		return
+	}
+	defer rows.Close()
+
+	var hashBytes []byte
+	var pubkeyBytes []byte
+	var pairString string
+	var priceString string
+	for rows.Next() {
+		thisFill := &match.AuctionFill{AuctionID: auctionID}
+		if err = rows.Scan(&hashBytes, &pubkeyBytes, &pairString, &thisFill.Side, &priceString, &thisFill.AmountOrdered, &thisFill.AmountFilled); err != nil {
+			err = fmt.Errorf("Error scanning auction fill: %s", err)
+			return
+		}
+
+		if priceString != "" {
+			var ok bool
+			if thisFill.ClearingPrice, ok = new(big.Rat).SetString(priceString); !ok {
+				err = fmt.Errorf("Clearing price %s for auction fill is not a number", priceString)
+				return
+			}
+		}
+
+		for _, byteArray := range [][]byte{hashBytes, pubkeyBytes} {
+			if _, err = hex.Decode(byteArray, byteArray); err != nil {
+				err = fmt.Errorf("Error decoding bytes for auction fill: %s", err)
+				return
+			}
+		}
+
+		if err = thisFill.Pair.FromString(pairString); err != nil {
+			err = fmt.Errorf("Error getting pair for auction fill: %s", err)
+			return
+		}
+
+		copy(thisFill.OrderHash[:], hashBytes)
+		copy(thisFill.Pubkey[:], pubkeyBytes)
+		fills = append(fills, thisFill)
+	}
+
+	return
+}
+
+// ViewAuctionClearings takes in an auction ID, and returns the result of clearing each of its pairs that has
+// been cleared.
+func (db *DB) ViewAuctionClearings(auctionID [32]byte) (clearings []*match.AuctionClearing, err error) {
+
+	var tx *sql.Tx
+	if tx, err = db.DBHandler.Begin(); err != nil {
+		err = fmt.Errorf("Error when beginning transaction for ViewAuctionClearings: %s", err)
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("Error while viewing auction clearings: \n%s", err)
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(db.dialect.UseSchema(db.auctionOrderSchema)); err != nil {
+		err = fmt.Errorf("Error trying to use auction order schema: %s", err)
+		return
+	}
+
+	var rows *sql.Rows
+	selectClearingsQuery := fmt.Sprintf("SELECT pair, clearingPrice, noMatch FROM %s WHERE auctionID=%s;", db.auctionClearingTable, db.dialect.Placeholder(1))
+	if rows, err = tx.Query(selectClearingsQuery, hex.EncodeToString(auctionID[:])); err != nil {
+		err = fmt.Errorf("Could not query for auction clearings: %s", err)
+		return
+	}
+	defer rows.Close()
+
+	var pairString string
+	var priceString string
+	for rows.Next() {
+		thisClearing := &match.AuctionClearing{AuctionID: auctionID}
+		if err = rows.Scan(&pairString, &priceString, &thisClearing.NoMatch); err != nil {
+			err = fmt.Errorf("Error scanning auction clearing: %s", err)
+			return
+		}
+
+		if err = thisClearing.Pair.FromString(pairString); err != nil {
+			err = fmt.Errorf("Error getting pair for auction clearing: %s", err)
+			return
+		}
+
+		if priceString != "" {
+			var ok bool
+			if thisClearing.ClearingPrice, ok = new(big.Rat).SetString(priceString); !ok {
+				err = fmt.Errorf("Clearing price %s for auction clearing is not a number", priceString)
+				return
+			}
+		}
+
+		clearings = append(clearings, thisClearing)
+	}
+
+	return
+}
+
 /*
  MatchAuction matches the auction with a specific auctionID. This is meant to be the implementation of pro-rata for just the stuff in the auction. We assume that there are orders in the auction orderbook that are ALL valid.
 
@@ -18,7 +18,7 @@ func (db *DB) GetBalance(pubkey *koblitz.PublicKey, param *coinparam.Params) (ui
 	var err error
 
 	// Use the balance schema
-	_, err = db.DBHandler.Exec("USE " + db.balanceSchema + ";")
+	_, err = db.DBHandler.Exec(db.dialect.UseSchema(db.balanceSchema))
 	if err != nil {
 		return 0, fmt.Errorf("Could not use balance schema: \n%s", err)
 	}
@@ -70,7 +70,7 @@ func (db *DB) UpdateDeposits(deposits []match.Deposit, currentBlockHeight uint64
 		err = tx.Commit()
 	}()
 
-	if _, err = tx.Exec("USE " + db.pendingDepositSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.pendingDepositSchema)); err != nil {
 		return
 	}
 
@@ -130,7 +130,7 @@ func (db *DB) UpdateDeposits(deposits []match.Deposit, currentBlockHeight uint64
 	}
 
 	// HAVE TO GO BACK TO THE DEPOSIT SCHEMA OR ELSE NOTHING WORKS -- dan right after spending 10 minutes on a dumb mistake
-	if _, err = tx.Exec("USE " + db.pendingDepositSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.pendingDepositSchema)); err != nil {
 		return
 	}
 
@@ -177,7 +177,7 @@ func (db *DB) AddToBalanceWithinTransaction(pubkey *koblitz.PublicKey, amount ui
 		}
 	}()
 
-	if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.balanceSchema)); err != nil {
 		return
 	}
 
@@ -228,7 +228,7 @@ func (db *DB) UpdateBalancesWithinTransaction(pubkeys []*koblitz.PublicKey, amou
 		return
 	}
 
-	if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.balanceSchema)); err != nil {
 		return
 	}
 
@@ -292,7 +292,7 @@ func (db *DB) GetDepositAddress(pubkey *koblitz.PublicKey, asset string) (deposi
 	}()
 
 	// Use the deposit schema
-	if _, err = tx.Exec("USE " + db.depositSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.depositSchema)); err != nil {
 		err = fmt.Errorf("Could not use deposit schema: \n%s", err)
 		return
 	}
@@ -345,7 +345,7 @@ func (db *DB) GetDepositAddressMap(coinType *coinparam.Params) (depositAddresses
 	}()
 
 	// Use the deposit schema
-	if _, err = tx.Exec("USE " + db.depositSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.depositSchema)); err != nil {
 		err = fmt.Errorf("Could not use deposit address schema: \n%s", err)
 		return
 	}
@@ -431,7 +431,7 @@ func (db *DB) Withdraw(pubkey *koblitz.PublicKey, asset *coinparam.Params, amoun
 
 	// Use the balance schema
 
-	if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.balanceSchema)); err != nil {
 		err = fmt.Errorf("Error trying to use database: \n%s", err)
 		return
 	}
@@ -469,3 +469,90 @@ func (db *DB) Withdraw(pubkey *koblitz.PublicKey, asset *coinparam.Params, amoun
 
 	return
 }
+
+// SetFeeSchedule sets the fee taken out of every amount credited during settlement, and the account it
+// goes to. The fee account gets balances if it doesn't have any yet. A nil schedule takes no fees.
+func (db *DB) SetFeeSchedule(schedule *match.FeeSchedule) (err error) {
+	if schedule != nil {
+		var feePubkey *koblitz.PublicKey
+		if feePubkey, err = schedule.AccountPubkey(); err != nil {
+			return
+		}
+
+		// fees can only be credited to an account that has balances
+		if len(db.coinList) > 0 {
+			if _, err = db.GetBalance(feePubkey, db.coinList[0]); err != nil {
+				if err = db.InitializeAccountBalances(feePubkey); err != nil {
+					err = fmt.Errorf("Error initializing fee account balances: \n%s", err)
+					return
+				}
+			}
+		}
+	}
+
+	db.feeMtx.Lock()
+	db.feeSchedule = schedule
+	db.feeMtx.Unlock()
+	return
+}
+
+// GetFeeSchedule returns the fee taken during settlement, nil if there are no fees
+func (db *DB) GetFeeSchedule() (schedule *match.FeeSchedule) {
+	db.feeMtx.Lock()
+	schedule = db.feeSchedule
+	db.feeMtx.Unlock()
+	return
+}
+
+// applySettlementCreditsWithinTransaction credits every account in credits within tx. coinTypes maps
+// each credited asset to its coin. Every account is credited by a single statement, no matter how many
+// orders it was in, so each balance is only written once.
+func (db *DB) applySettlementCreditsWithinTransaction(credits *match.SettlementCredits, coinTypes map[match.Asset]*coinparam.Params, tx *sql.Tx) (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("Error applying settlement credits within transaction: \n%s", err)
+			return
+		}
+	}()
+
+	if err = credits.Apply(func(pubkey *koblitz.PublicKey, asset match.Asset, amount uint64) (creditErr error) {
+		coinType, found := coinTypes[asset]
+		if !found {
+			creditErr = fmt.Errorf("No coin for settled asset %s", asset)
+			return
+		}
+
+		creditErr = db.creditBalanceWithinTransaction(pubkey, amount, tx, coinType)
+		return
+	}); err != nil {
+		return
+	}
+
+	return
+}
+
+// creditBalanceWithinTransaction increases the balance of pubkey by amount in a single statement
+func (db *DB) creditBalanceWithinTransaction(pubkey *koblitz.PublicKey, amount uint64, tx *sql.Tx, coinType *coinparam.Params) (err error) {
+	// nothing would change, and no rows would be affected
+	if amount == 0 {
+		return
+	}
+
+	creditBalanceQuery := fmt.Sprintf("UPDATE %s.%s SET balance=balance+%d WHERE pubkey='%x';", db.balanceSchema, coinType.Name, amount, pubkey.SerializeCompressed())
+	var res sql.Result
+	if res, err = tx.Exec(creditBalanceQuery); err != nil {
+		return
+	}
+
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
+		return
+	}
+
+	if affected == 0 {
+		err = fmt.Errorf("No balance for pubkey, please register")
+		return
+	}
+
+	return
+}
@@ -45,6 +45,9 @@ var (
 	auctionSchema        = "auctions"
 	auctionOrderSchema   = "auctionorder"
 	auctionOrderTable    = "auctionorders"
+	auctionRootTable     = "auctionroots"
+	auctionFillTable     = "auctionfills"
+	auctionClearingTable = "auctionclearings"
 	orderSchema          = "orders"
 	peerSchema           = "peers"
 	peerTableName        = "opencxpeers"
@@ -68,6 +71,9 @@ type DB struct {
 	// the SQL handler for the db
 	DBHandler *sql.DB
 
+	// dialect is the type of database we're connected to, and turns the mysql queries into its own
+	dialect Dialect
+
 	// db username and password
 	dbUsername string
 	dbPassword string
@@ -101,6 +107,12 @@ type DB struct {
 	auctionOrderSchema string
 	// name of the (auction ID => auction number) table
 	auctionOrderTable string
+	// name of the table of commitment roots for closed auctions, in the auction order schema
+	auctionRootTable string
+	// name of the table of order fills for cleared auctions, in the auction order schema
+	auctionFillTable string
+	// name of the table of clearing results for each pair of cleared auctions, in the auction order schema
+	auctionClearingTable string
 
 	// list of all coins supported, passed in from above
 	coinList []*coinparam.Params
@@ -112,6 +124,10 @@ type DB struct {
 	gPriceMap map[string]float64
 	// priceMapMtx is a lock for gPriceMap
 	priceMapMtx *sync.Mutex
+
+	// feeSchedule is the fee taken during settlement and where it goes, nil means no fees
+	feeSchedule *match.FeeSchedule
+	feeMtx      *sync.Mutex
 }
 
 // SetPrice sets the price, uses a lock since it will be written to and read from possibly at the same time (written to by server, read by client)
@@ -136,8 +152,13 @@ func (db *DB) GetPairs() (pairArray []*match.Pair) {
 	return
 }
 
-// CreateDBConnection initializes the db so the client is ready to be set up. We use TCP by default
+// CreateDBConnection initializes a mysql db so the client is ready to be set up. We use TCP by default
 func CreateDBConnection(username string, password string, host string, port uint16) (dbconn *DB, err error) {
+	return CreateDialectDBConnection(MySQLDialect, username, password, host, port)
+}
+
+// CreateDialectDBConnection initializes a db of any type so the client is ready to be set up. We use TCP by default
+func CreateDialectDBConnection(dialect Dialect, username string, password string, host string, port uint16) (dbconn *DB, err error) {
 	var dbAddr net.Addr
 	if dbAddr, err = net.ResolveTCPAddr("tcp", net.JoinHostPort(host, fmt.Sprintf("%d", port))); err != nil {
 		err = fmt.Errorf("Error resolving database address: \n%s", err)
@@ -145,14 +166,16 @@ func CreateDBConnection(username string, password string, host string, port uint
 	}
 
 	dbconn = &DB{
+		dialect:    dialect,
 		dbAddr:     dbAddr,
 		dbUsername: username,
 		dbPassword: password,
+		feeMtx:     new(sync.Mutex),
 	}
 	return
 }
 
-// SetupClient sets up the mysql client and driver
+// SetupClient sets up the sql client and driver for the db's dialect
 func (db *DB) SetupClient(coinList []*coinparam.Params) (err error) {
 	db.gPriceMap = make(map[string]float64)
 	db.balanceSchema = balanceSchema
@@ -166,6 +189,9 @@ func (db *DB) SetupClient(coinList []*coinparam.Params) (err error) {
 	db.auctionSchema = auctionSchema
 	db.auctionOrderSchema = auctionOrderSchema
 	db.auctionOrderTable = auctionOrderTable
+	db.auctionRootTable = auctionRootTable
+	db.auctionFillTable = auctionFillTable
+	db.auctionClearingTable = auctionClearingTable
 	// Create users and schemas and assign permissions to opencx
 	if err = db.rootInitSchemas(); err != nil {
 		err = fmt.Errorf("Root could not initialize schemas: \n%s", err)
@@ -173,10 +199,10 @@ func (db *DB) SetupClient(coinList []*coinparam.Params) (err error) {
 	}
 
 	// open db handle
-	openString := fmt.Sprintf("%s:%s@%s(%s)/", db.dbUsername, db.dbPassword, db.dbAddr.Network(), db.dbAddr.String())
+	openString := db.dialect.DataSourceName(db.dbUsername, db.dbPassword, db.dbAddr)
 
 	var dbHandle *sql.DB
-	if dbHandle, err = sql.Open("mysql", openString); err != nil {
+	if dbHandle, err = sql.Open(db.dialect.DriverName(), openString); err != nil {
 		err = fmt.Errorf("Error opening database: \n%s", err)
 		return
 	}
@@ -217,7 +243,7 @@ func (db *DB) SetupClient(coinList []*coinparam.Params) (err error) {
 		return
 	}
 
-	if err = db.SetupAuctionTables(db.auctionSchema, db.puzzleSchema, db.puzzleTable, db.auctionOrderSchema, db.auctionOrderTable); err != nil {
+	if err = db.SetupAuctionTables(db.auctionSchema, db.puzzleSchema, db.puzzleTable, db.auctionOrderSchema, db.auctionOrderTable, db.auctionRootTable, db.auctionFillTable, db.auctionClearingTable); err != nil {
 		err = fmt.Errorf("Error setting up auction tables: %s", err)
 		return
 	}
@@ -276,7 +302,7 @@ func (db *DB) SetupExchangeTables(orderSchema string) (err error) {
 }
 
 // SetupAuctionTables sets up the tables needed to store auction orders and puzzles for specific auctions
-func (db *DB) SetupAuctionTables(auctionSchema string, puzzleSchema string, puzzleTable string, auctionOrderSchema string, auctionOrderTable string) (err error) {
+func (db *DB) SetupAuctionTables(auctionSchema string, puzzleSchema string, puzzleTable string, auctionOrderSchema string, auctionOrderTable string, auctionRootTable string, auctionFillTable string, auctionClearingTable string) (err error) {
 
 	// Initialize auction order schema, table
 	// An auction order is identified by it's auction ID, pubkey, nonce, and other specific data.
@@ -299,6 +325,24 @@ func (db *DB) SetupAuctionTables(auctionSchema string, puzzleSchema string, puzz
 		return
 	}
 
+	// This creates the table where we'll keep the commitment roots of closed auctions, in the order they closed
+	if err = db.InitializeSingleTable(auctionOrderSchema, auctionRootTable, "rootNumber BIGINT(64) AUTO_INCREMENT, auctionID VARBINARY(64), root VARBINARY(64), numPuzzles BIGINT(64) UNSIGNED, closeTime BIGINT(64), PRIMARY KEY (rootNumber)"); err != nil {
+		err = fmt.Errorf("Could not initialize auction root table: %s", err)
+		return
+	}
+
+	// This creates the table where we'll keep how much each order filled in cleared auctions
+	if err = db.InitializeSingleTable(auctionOrderSchema, auctionFillTable, "auctionID VARBINARY(64), hashedOrder VARBINARY(64), pubkey VARBINARY(66), pair TEXT, side TEXT, clearingPrice TEXT, amountOrdered BIGINT(64) UNSIGNED, amountFilled BIGINT(64) UNSIGNED, PRIMARY KEY (auctionID, hashedOrder)"); err != nil {
+		err = fmt.Errorf("Could not initialize auction fill table: %s", err)
+		return
+	}
+
+	// This creates the table where we'll keep the clearing price of each pair in cleared auctions, or that it didn't match
+	if err = db.InitializeSingleTable(auctionOrderSchema, auctionClearingTable, "auctionID VARBINARY(64), pair TEXT, clearingPrice TEXT, noMatch BOOLEAN"); err != nil {
+		err = fmt.Errorf("Could not initialize auction clearing table: %s", err)
+		return
+	}
+
 	return
 }
 
@@ -306,11 +350,11 @@ func (db *DB) SetupAuctionTables(auctionSchema string, puzzleSchema string, puzz
 func (db *DB) InitializeSingleTable(schemaName string, tableName string, schemaSpec string) (err error) {
 
 	// Use the schema
-	if _, err = db.DBHandler.Exec("USE " + schemaName + ";"); err != nil {
+	if _, err = db.DBHandler.Exec(db.dialect.UseSchema(schemaName)); err != nil {
 		err = fmt.Errorf("Could not use %s schema: \n%s", schemaName, err)
 		return
 	}
-	tableQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", tableName, schemaSpec)
+	tableQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", tableName, db.dialect.TableSpec(schemaSpec))
 	if _, err = db.DBHandler.Exec(tableQuery); err != nil {
 		err = fmt.Errorf("Could not create table %s: \n%s", tableName, err)
 		return
@@ -322,12 +366,12 @@ func (db *DB) InitializeSingleTable(schemaName string, tableName string, schemaS
 func (db *DB) InitializeTables(schemaName string, schemaSpec string) (err error) {
 
 	// Use the schema
-	if _, err = db.DBHandler.Exec("USE " + schemaName + ";"); err != nil {
+	if _, err = db.DBHandler.Exec(db.dialect.UseSchema(schemaName)); err != nil {
 		err = fmt.Errorf("Could not use %s schema: \n%s", schemaName, err)
 		return
 	}
 	for _, chain := range db.coinList {
-		tableQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", chain.Name, schemaSpec)
+		tableQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", chain.Name, db.dialect.TableSpec(schemaSpec))
 		if _, err = db.DBHandler.Exec(tableQuery); err != nil {
 			err = fmt.Errorf("Could not create table %s: \n%s", chain.Name, err)
 			return
@@ -339,12 +383,12 @@ func (db *DB) InitializeTables(schemaName string, schemaSpec string) (err error)
 // InitializeNewTables initializes tables based on schema and clears them.
 func (db *DB) InitializeNewTables(schemaName string, schemaSpec string) (err error) {
 	// Use the schema
-	if _, err = db.DBHandler.Exec("USE " + schemaName + ";"); err != nil {
+	if _, err = db.DBHandler.Exec(db.dialect.UseSchema(schemaName)); err != nil {
 		err = fmt.Errorf("Could not use %s schema: \n%s", schemaName, err)
 		return
 	}
 	for _, chain := range db.coinList {
-		tableQuery := fmt.Sprintf("CREATE OR REPLACE TABLE %s (%s);", chain.Name, schemaSpec)
+		tableQuery := db.dialect.ReplaceTable(chain.Name, db.dialect.TableSpec(schemaSpec))
 		if _, err = db.DBHandler.Exec(tableQuery); err != nil {
 			err = fmt.Errorf("Could not create table %s: \n%s", chain.Name, err)
 			return
@@ -361,12 +405,12 @@ func (db *DB) InitializeNewTables(schemaName string, schemaSpec string) (err err
 // InitializePairTables initializes tables per pair
 func (db *DB) InitializePairTables(schemaName string, schemaSpec string) (err error) {
 	// Use the schema
-	if _, err = db.DBHandler.Exec("USE " + schemaName + ";"); err != nil {
+	if _, err = db.DBHandler.Exec(db.dialect.UseSchema(schemaName)); err != nil {
 		err = fmt.Errorf("Could not use %s schema: \n%s", schemaName, err)
 		return
 	}
 	for _, pair := range db.pairsArray {
-		tableQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", pair.String(), schemaSpec)
+		tableQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", pair.String(), db.dialect.TableSpec(schemaSpec))
 		if _, err = db.DBHandler.Exec(tableQuery); err != nil {
 			err = fmt.Errorf("Could not create table %s: \n%s", pair.String(), err)
 			return
@@ -379,9 +423,9 @@ func (db *DB) InitializePairTables(schemaName string, schemaSpec string) (err er
 func (db *DB) rootInitSchemas() (err error) {
 
 	// open db handle
-	openString := fmt.Sprintf("%s:%s@%s(%s)/", db.dbUsername, db.dbPassword, db.dbAddr.Network(), db.dbAddr.String())
+	openString := db.dialect.DataSourceName(db.dbUsername, db.dbPassword, db.dbAddr)
 	var rootHandler *sql.DB
-	if rootHandler, err = sql.Open("mysql", openString); err != nil {
+	if rootHandler, err = sql.Open(db.dialect.DriverName(), openString); err != nil {
 		err = fmt.Errorf("Error opening database: \n%s", err)
 		return
 	}
@@ -0,0 +1,160 @@
+package cxdbsql
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+
+	// postgres is just the driver, always interact with database/sql api
+	_ "github.com/lib/pq"
+)
+
+// Dialect is everything that is different between the SQL databases the DB can run on. Table specs
+// and queries are written for MySQL, and each dialect turns them into what its database understands.
+type Dialect interface {
+	// Name returns the name of the database type, as used in config files
+	Name() string
+	// DriverName returns the name of the database/sql driver for the dialect
+	DriverName() string
+	// DataSourceName returns the string passed to sql.Open to connect to the database at addr
+	DataSourceName(username string, password string, addr net.Addr) string
+	// DefaultPort returns the port the database listens on by default
+	DefaultPort() uint16
+	// Placeholder returns the placeholder for the nth argument of a query, starting at 1
+	Placeholder(n int) string
+	// UseSchema returns the statement that makes the following statements run in schema
+	UseSchema(schema string) string
+	// TableSpec converts a MySQL table spec into one for the dialect
+	TableSpec(spec string) string
+	// ReplaceTable returns the statement that creates table with spec, dropping the table if it
+	// already exists
+	ReplaceTable(table string, spec string) string
+	// OnDuplicateUpdate returns the clause added to an insert so a row that conflicts on the
+	// conflict columns is updated with update instead
+	OnDuplicateUpdate(conflict string, update string) string
+}
+
+// DialectFromString returns the dialect for a database type, either mysql or postgres
+func DialectFromString(name string) (dialect Dialect, err error) {
+	switch name {
+	case MySQLDialect.Name():
+		dialect = MySQLDialect
+	case PostgresDialect.Name():
+		dialect = PostgresDialect
+	default:
+		err = fmt.Errorf("Unknown database type %s, must be one of %s or %s", name, MySQLDialect.Name(), PostgresDialect.Name())
+	}
+	return
+}
+
+var (
+	// MySQLDialect is the dialect for MySQL and MariaDB
+	MySQLDialect Dialect = mysqlDialect{}
+	// PostgresDialect is the dialect for PostgreSQL
+	PostgresDialect Dialect = postgresDialect{}
+)
+
+// mysqlDialect is the dialect the table specs and queries are written in, so it leaves them alone
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) DriverName() string {
+	return "mysql"
+}
+
+func (mysqlDialect) DataSourceName(username string, password string, addr net.Addr) string {
+	return fmt.Sprintf("%s:%s@%s(%s)/", username, password, addr.Network(), addr.String())
+}
+
+func (mysqlDialect) DefaultPort() uint16 {
+	return 3306
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) UseSchema(schema string) string {
+	return "USE " + schema + ";"
+}
+
+func (mysqlDialect) TableSpec(spec string) string {
+	return spec
+}
+
+func (mysqlDialect) ReplaceTable(table string, spec string) string {
+	return fmt.Sprintf("CREATE OR REPLACE TABLE %s (%s);", table, spec)
+}
+
+func (mysqlDialect) OnDuplicateUpdate(conflict string, update string) string {
+	return "ON DUPLICATE KEY UPDATE " + update
+}
+
+// postgresTypes are the MySQL column types used in table specs and what they are in postgres. They
+// are replaced in order, so the longer types come before the types they start with. Postgres has no
+// unsigned integers, so unsigned columns get the next size up.
+var postgresTypes = []struct {
+	mysql    *regexp.Regexp
+	postgres string
+}{
+	{regexp.MustCompile(`BIGINT\(\d+\) AUTO_INCREMENT`), "BIGSERIAL"},
+	{regexp.MustCompile(`BIGINT\(\d+\) UNSIGNED`), "NUMERIC(20)"},
+	{regexp.MustCompile(`BIGINT\(\d+\)`), "BIGINT"},
+	{regexp.MustCompile(`INT\(\d+\) UNSIGNED`), "BIGINT"},
+	{regexp.MustCompile(`DOUBLE\((\d+),(\d+)\) UNSIGNED`), "NUMERIC($1,$2)"},
+	{regexp.MustCompile(`DOUBLE\b`), "DOUBLE PRECISION"},
+	{regexp.MustCompile(`VARBINARY\(\d+\)`), "BYTEA"},
+}
+
+// postgresDialect is the dialect for PostgreSQL, where schemas are selected with the search path
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) DriverName() string {
+	return "postgres"
+}
+
+func (postgresDialect) DataSourceName(username string, password string, addr net.Addr) string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(username, password),
+		Host:     addr.String(),
+		Path:     "/",
+		RawQuery: "sslmode=disable",
+	}
+	return dsn.String()
+}
+
+func (postgresDialect) DefaultPort() uint16 {
+	return 5432
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgresDialect) UseSchema(schema string) string {
+	return "SET search_path TO " + schema + ";"
+}
+
+func (postgresDialect) TableSpec(spec string) string {
+	for _, typ := range postgresTypes {
+		spec = typ.mysql.ReplaceAllString(spec, typ.postgres)
+	}
+	return spec
+}
+
+func (postgresDialect) ReplaceTable(table string, spec string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s; CREATE TABLE %s (%s);", table, table, spec)
+}
+
+func (postgresDialect) OnDuplicateUpdate(conflict string, update string) string {
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", conflict, update)
+}
@@ -39,7 +39,7 @@ func (db *DB) PlaceOrder(order *match.LimitOrder) (orderid string, err error) {
 	}()
 
 	// use balance schema
-	if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.balanceSchema)); err != nil {
 		return
 	}
 
@@ -83,7 +83,7 @@ func (db *DB) PlaceOrder(order *match.LimitOrder) (orderid string, err error) {
 				return
 			}
 
-			if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+			if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 				return
 			}
 
@@ -165,7 +165,7 @@ func (db *DB) CancelOrder(orderID string) (err error) {
 		err = tx.Commit()
 	}()
 
-	if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 		return
 	}
 
@@ -209,7 +209,7 @@ func (db *DB) CancelOrder(orderID string) (err error) {
 			}
 
 			// use balance schema for updating balance
-			if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+			if _, err = tx.Exec(db.dialect.UseSchema(db.balanceSchema)); err != nil {
 				return
 			}
 
@@ -232,7 +232,7 @@ func (db *DB) CancelOrder(orderID string) (err error) {
 			}
 		}
 		// use order schema at end of loop so we go back to where we were
-		if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+		if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 			return
 		}
 	}
@@ -261,7 +261,7 @@ func (db *DB) ViewOrderBook(pair *match.Pair) (sellOrderBook []*match.LimitOrder
 		err = tx.Commit()
 	}()
 
-	if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 		return
 	}
 
@@ -290,7 +290,7 @@ func (db *DB) ViewOrderBook(pair *match.Pair) (sellOrderBook []*match.LimitOrder
 
 		// logging.Infof("Matching all orders with price %f\n", price)
 
-		if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+		if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 			return
 		}
 
@@ -381,7 +381,7 @@ func (db *DB) GetOrder(orderID string) (order *match.LimitOrder, err error) {
 		err = tx.Commit()
 	}()
 
-	if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 		return
 	}
 
@@ -441,7 +441,7 @@ func (db *DB) GetOrdersForPubkey(pubkey *koblitz.PublicKey) (orders []*match.Lim
 		err = tx.Commit()
 	}()
 
-	if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 		return
 	}
 
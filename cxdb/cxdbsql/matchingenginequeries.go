@@ -57,7 +57,7 @@ func (db *DB) CalculatePrice(pair *match.Pair) (price float64, err error) {
 		err = tx.Commit()
 	}()
 
-	if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 		return
 	}
 
@@ -116,7 +116,7 @@ func (db *DB) RunMatchingBestPrices(pair *match.Pair) (err error) {
 		err = tx.Commit()
 	}()
 
-	if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 		return
 	}
 
@@ -221,10 +221,16 @@ func (db *DB) RunMatchingCrossedPricesWithinTransaction(pair *match.Pair, bestBu
 		}
 	}()
 
+	// use the same fees for the whole run, even if they change while we're matching
+	feeSchedule := db.GetFeeSchedule()
+
+	// balances are credited after everything is matched, so each one is only written once
+	credits := match.NewSettlementCredits()
+
 	// debug
 	// logging.Infof("Matching all orders with price %f\n", price)
 
-	if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.orderSchema)); err != nil {
 		return
 	}
 
@@ -312,8 +318,11 @@ func (db *DB) RunMatchingCrossedPricesWithinTransaction(pair *match.Pair, bestBu
 
 			sellOrders = sellOrders[1:]
 
-			// use the balance schema because we're ending with balance transactions
-			if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+			// the sell order is used up, and the buy order gave what the sell order wanted
+			if err = credits.Debit(pair.AssetWant, prevAmountHave); err != nil {
+				return
+			}
+			if err = credits.Debit(pair.AssetHave, prevAmountWant); err != nil {
 				return
 			}
 
@@ -328,23 +337,18 @@ func (db *DB) RunMatchingCrossedPricesWithinTransaction(pair *match.Pair, bestBu
 			}
 
 			// credit buyOrder client with sellOrder amountHave
-			if err = db.AddToBalanceWithinTransaction(buyOrderPubkey, prevAmountHave, tx, assetWantCoinType); err != nil {
+			if err = feeSchedule.CreditPairAsset(pair, pair.AssetWant, buyOrderPubkey, prevAmountHave, credits.CreditFunc(pair.AssetWant)); err != nil {
 				return
 			}
 			// credit sellOrder client with buyorder amountWant
-			if err = db.AddToBalanceWithinTransaction(sellOrderPubkey, prevAmountWant, tx, assetHaveCoinType); err != nil {
-				return
-			}
-
-			// logging.Infof("done all greater")
-			// making sure we're going back in the order db, we're going to be making lots of order queries
-			if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+			if err = feeSchedule.CreditPairAsset(pair, pair.AssetHave, sellOrderPubkey, prevAmountWant, credits.CreditFunc(pair.AssetHave)); err != nil {
 				return
 			}
 		} else if currBuyOrder.AmountHave < currSellOrder.AmountWant {
 
 			prevAmountHave := currBuyOrder.AmountHave
 			prevAmountWant := currBuyOrder.AmountWant
+			prevSellAmountHave := currSellOrder.AmountHave
 
 			// this partial fulfillment / uint underflow quick fix needs to be looked into. Are we losing any money here?
 			if currSellOrder.AmountHave < currBuyOrder.AmountWant {
@@ -367,8 +371,12 @@ func (db *DB) RunMatchingCrossedPricesWithinTransaction(pair *match.Pair, bestBu
 
 			// logging.Infof("done delete")
 			buyOrders = buyOrders[1:]
-			// use the balance schema because we're ending with balance transactions
-			if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+
+			// the buy order is used up, and the sell order gave however much it went down by
+			if err = credits.Debit(pair.AssetHave, prevAmountHave); err != nil {
+				return
+			}
+			if err = credits.Debit(pair.AssetWant, prevSellAmountHave-currSellOrder.AmountHave); err != nil {
 				return
 			}
 
@@ -383,17 +391,11 @@ func (db *DB) RunMatchingCrossedPricesWithinTransaction(pair *match.Pair, bestBu
 			}
 
 			// credit buyOrder client with sellOrder amountHave
-			if err = db.AddToBalanceWithinTransaction(buyOrderPubkey, prevAmountWant, tx, assetWantCoinType); err != nil {
+			if err = feeSchedule.CreditPairAsset(pair, pair.AssetWant, buyOrderPubkey, prevAmountWant, credits.CreditFunc(pair.AssetWant)); err != nil {
 				return
 			}
 			// credit sellOrder client with buyorder amountWant
-			if err = db.AddToBalanceWithinTransaction(sellOrderPubkey, prevAmountHave, tx, assetHaveCoinType); err != nil {
-				return
-			}
-
-			// logging.Infof("done lesser")
-			// making sure we're going back in the order db, we're going to be making lots of order queries
-			if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
+			if err = feeSchedule.CreditPairAsset(pair, pair.AssetHave, sellOrderPubkey, prevAmountHave, credits.CreditFunc(pair.AssetHave)); err != nil {
 				return
 			}
 		} else if currBuyOrder.AmountHave == currSellOrder.AmountWant {
@@ -414,8 +416,11 @@ func (db *DB) RunMatchingCrossedPricesWithinTransaction(pair *match.Pair, bestBu
 			sellOrders = sellOrders[1:]
 			buyOrders = buyOrders[1:]
 
-			// use the balance schema because we're ending with balance transactions
-			if _, err = tx.Exec("USE " + db.balanceSchema + ";"); err != nil {
+			// both orders are used up
+			if err = credits.Debit(pair.AssetWant, currSellOrder.AmountHave); err != nil {
+				return
+			}
+			if err = credits.Debit(pair.AssetHave, currBuyOrder.AmountHave); err != nil {
 				return
 			}
 
@@ -430,21 +435,27 @@ func (db *DB) RunMatchingCrossedPricesWithinTransaction(pair *match.Pair, bestBu
 			}
 
 			// credit buyOrder client with sellOrder amountHave
-			if err = db.AddToBalanceWithinTransaction(buyOrderPubkey, currBuyOrder.AmountWant, tx, assetWantCoinType); err != nil {
+			if err = feeSchedule.CreditPairAsset(pair, pair.AssetWant, buyOrderPubkey, currBuyOrder.AmountWant, credits.CreditFunc(pair.AssetWant)); err != nil {
 				return
 			}
 			// credit sellOrder client with buyorder amountWant
-			if err = db.AddToBalanceWithinTransaction(sellOrderPubkey, currBuyOrder.AmountHave, tx, assetHaveCoinType); err != nil {
+			if err = feeSchedule.CreditPairAsset(pair, pair.AssetHave, sellOrderPubkey, currBuyOrder.AmountHave, credits.CreditFunc(pair.AssetHave)); err != nil {
 				return
 			}
+		}
+	}
 
-			// logging.Infof("done update")
+	coinTypes := map[match.Asset]*coinparam.Params{
+		pair.AssetWant: assetWantCoinType,
+		pair.AssetHave: assetHaveCoinType,
+	}
+	if err = db.applySettlementCreditsWithinTransaction(credits, coinTypes, tx); err != nil {
+		return
+	}
 
-			// making sure we're going back in the order db, we're going to be making lots of order queries
-			if _, err = tx.Exec("USE " + db.orderSchema + ";"); err != nil {
-				return
-			}
-		}
+	// Whatever came out of the orders should have gone to someone, otherwise there's an accounting bug
+	if balanceErr := credits.CheckBalanced(); balanceErr != nil {
+		logging.Warnf("Alert: settlement of %s failed its self-check: %s", pair.String(), balanceErr)
 	}
 
 	return
@@ -26,7 +26,7 @@ func (db *DB) GetPeerAddrs() (lnAddresses []lncore.LnAddr, err error) {
 	}()
 
 	// use peer schema
-	if _, err = tx.Exec("USE " + db.peerSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.peerSchema)); err != nil {
 		return
 	}
 
@@ -80,7 +80,7 @@ func (db *DB) GetPeerInfo(addr lncore.LnAddr) (peerInfo *lncore.PeerInfo, err er
 	}()
 
 	// use peer schema
-	if _, err = tx.Exec("USE " + db.peerSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.peerSchema)); err != nil {
 		return
 	}
 
@@ -144,7 +144,7 @@ func (db *DB) GetPeerInfos() (peerInfos map[lncore.LnAddr]lncore.PeerInfo, err e
 	}()
 
 	// use peer schema
-	if _, err = tx.Exec("USE " + db.peerSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.peerSchema)); err != nil {
 		return
 	}
 
@@ -214,7 +214,7 @@ func (db *DB) AddPeer(addr lncore.LnAddr, pi lncore.PeerInfo) (err error) {
 	}()
 
 	// use peer schema
-	if _, err = tx.Exec("USE " + db.peerSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.peerSchema)); err != nil {
 		return
 	}
 
@@ -244,7 +244,7 @@ func (db *DB) UpdatePeer(addr lncore.LnAddr, pi *lncore.PeerInfo) (err error) {
 	}()
 
 	// use peer schema
-	if _, err = tx.Exec("USE " + db.peerSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.peerSchema)); err != nil {
 		return
 	}
 
@@ -275,7 +275,7 @@ func (db *DB) DeletePeer(addr lncore.LnAddr) (err error) {
 	}()
 
 	// use peer schema
-	if _, err = tx.Exec("USE " + db.peerSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.peerSchema)); err != nil {
 		return
 	}
 
@@ -306,7 +306,7 @@ func (db *DB) GetUniquePeerIdx() (peerIdx uint32, err error) {
 	}()
 
 	// use peer schema
-	if _, err = tx.Exec("USE " + db.peerSchema + ";"); err != nil {
+	if _, err = tx.Exec(db.dialect.UseSchema(db.peerSchema)); err != nil {
 		return
 	}
 
@@ -0,0 +1,156 @@
+// +build postgres
+
+package cxdbsql
+
+import (
+	"crypto/rand"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/opencx/match"
+)
+
+// These tests run against a local postgres, with a user that can create schemas, when run with
+// go test -tags postgres
+var (
+	testPostgresUsername = "opencx"
+	testPostgresPassword = "testpass"
+	testPostgresHost     = "localhost"
+)
+
+func setupTestPostgres(t *testing.T) (db *DB) {
+	var err error
+	if db, err = CreateDialectDBConnection(PostgresDialect, testPostgresUsername, testPostgresPassword, testPostgresHost, PostgresDialect.DefaultPort()); err != nil {
+		t.Fatalf("Error creating postgres connection: %s", err)
+	}
+
+	if err = db.SetupClient([]*coinparam.Params{&coinparam.BitcoinParams, &coinparam.VertcoinTestNetParams}); err != nil {
+		t.Fatalf("Error setting up postgres client: %s", err)
+	}
+	return
+}
+
+func TestPostgresAuctionRoots(t *testing.T) {
+	var err error
+
+	db := setupTestPostgres(t)
+	defer db.DBHandler.Close()
+
+	var auctionID [32]byte
+	if _, err = rand.Read(auctionID[:]); err != nil {
+		t.Errorf("Error creating auction ID: %s", err)
+		return
+	}
+
+	var firstHeight uint64
+	if firstHeight, err = db.NewAuction(auctionID); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	var secondHeight uint64
+	if secondHeight, err = db.NewAuction(auctionID); err != nil {
+		t.Errorf("Error creating new auction: %s", err)
+		return
+	}
+
+	if secondHeight != firstHeight+1 {
+		t.Errorf("Auction numbers should go up by one, got %d then %d", firstHeight, secondHeight)
+		return
+	}
+
+	root := &match.AuctionRoot{
+		AuctionID:  auctionID,
+		NumPuzzles: 1 << 40,
+		Time:       time.Unix(0, time.Now().UnixNano()),
+	}
+	if _, err = rand.Read(root.Root[:]); err != nil {
+		t.Errorf("Error creating root: %s", err)
+		return
+	}
+
+	if err = db.PlaceAuctionRoot(root); err != nil {
+		t.Errorf("Error placing auction root: %s", err)
+		return
+	}
+
+	var roots []*match.AuctionRoot
+	if roots, err = db.ViewAuctionRoots(1); err != nil {
+		t.Errorf("Error viewing auction roots: %s", err)
+		return
+	}
+
+	if len(roots) != 1 || roots[0].AuctionID != root.AuctionID || roots[0].Root != root.Root || roots[0].NumPuzzles != root.NumPuzzles || !roots[0].Time.Equal(root.Time) {
+		t.Errorf("The latest root should be the one we placed, got %+v", roots)
+		return
+	}
+
+	return
+}
+
+func TestPostgresAuctionFills(t *testing.T) {
+	var err error
+
+	db := setupTestPostgres(t)
+	defer db.DBHandler.Close()
+
+	var auctionID [32]byte
+	if _, err = rand.Read(auctionID[:]); err != nil {
+		t.Errorf("Error creating auction ID: %s", err)
+		return
+	}
+
+	fill := &match.AuctionFill{
+		AuctionID:     auctionID,
+		Pair:          match.Pair{AssetWant: match.BTC, AssetHave: match.VTCTest},
+		Side:          "buy",
+		ClearingPrice: big.NewRat(3, 2),
+		AmountOrdered: 1 << 62,
+		AmountFilled:  1000,
+	}
+	if _, err = rand.Read(fill.OrderHash[:]); err != nil {
+		t.Errorf("Error creating order hash: %s", err)
+		return
+	}
+	if _, err = rand.Read(fill.Pubkey[:]); err != nil {
+		t.Errorf("Error creating pubkey: %s", err)
+		return
+	}
+
+	clearing := &match.AuctionClearing{
+		AuctionID:     auctionID,
+		Pair:          fill.Pair,
+		ClearingPrice: fill.ClearingPrice,
+	}
+	if err = db.PlaceAuctionFills(clearing, []*match.AuctionFill{fill}); err != nil {
+		t.Errorf("Error placing auction fills: %s", err)
+		return
+	}
+
+	var fills []*match.AuctionFill
+	if fills, err = db.ViewAuctionFills(auctionID); err != nil {
+		t.Errorf("Error viewing auction fills: %s", err)
+		return
+	}
+
+	if len(fills) != 1 || !reflect.DeepEqual(fills[0], fill) {
+		t.Errorf("Should get back the fill we placed, got %+v", fills)
+		return
+	}
+
+	var clearings []*match.AuctionClearing
+	if clearings, err = db.ViewAuctionClearings(auctionID); err != nil {
+		t.Errorf("Error viewing auction clearings: %s", err)
+		return
+	}
+
+	if len(clearings) != 1 || !reflect.DeepEqual(clearings[0], clearing) {
+		t.Errorf("Should get back the clearing we placed, got %+v", clearings)
+		return
+	}
+
+	return
+}
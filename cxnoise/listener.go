@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/util"
 )
 
 // defaultHandshakes is the maximum number of handshakes that can be done in
@@ -22,7 +23,7 @@ const defaultHandshakes = 1000
 type Listener struct {
 	localStatic *koblitz.PrivateKey
 
-	tcp *net.TCPListener
+	tcp net.Listener
 
 	handshakeSema chan struct{}
 	conns         chan maybeConn
@@ -36,15 +37,17 @@ var _ net.Listener = (*Listener)(nil)
 // during both initial connection establishment and data transfer.
 func NewListener(localStatic *koblitz.PrivateKey, port int) (*Listener,
 	error) {
+	return NewListenerWithConfig(localStatic, port, util.ListenerConfig{})
+}
+
+// NewListenerWithConfig is the same as NewListener, but the underlying tcp
+// listener is set up with the backlog and keep-alive from conf.
+func NewListenerWithConfig(localStatic *koblitz.PrivateKey, port int,
+	conf util.ListenerConfig) (*Listener, error) {
 	// since this is a listener, it is sufficient that we just pass the
 	// port and then add the later stuff here
 	str := ":" + strconv.Itoa(port) // colonize!
-	addr, err := net.ResolveTCPAddr("tcp", str)
-	if err != nil {
-		return nil, err
-	}
-
-	l, err := net.ListenTCP("tcp", addr)
+	l, err := util.ListenTCP(str, conf)
 	if err != nil {
 		return nil, err
 	}
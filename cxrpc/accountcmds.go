@@ -3,7 +3,6 @@ package cxrpc
 import (
 	"fmt"
 
-	"github.com/mit-dci/lit/coinparam"
 	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/logging"
 )
@@ -32,23 +31,10 @@ func (cl *OpencxRPC) Register(args RegisterArgs, reply *RegisterReply) (err erro
 		}
 	}()
 
-	// go through each enabled wallet in the server and create a new address for them.
-	addrMap := make(map[*coinparam.Params]string)
-	for param := range cl.Server.WalletMap {
-		if addrMap[param], err = cl.Server.GetAddrForCoin(param, pubkey); err != nil {
-			return
-		}
-	}
-
-	// Do all this locking just cause
-	cl.Server.LockIngests()
-	// Insert them into the DB
-	if err = cl.Server.OpencxDB.RegisterUser(pubkey, addrMap); err != nil {
-		// gotta put these here cause if it errors out then oops just locked everything
-		cl.Server.UnlockIngests()
+	// create an address for every enabled wallet and put them in the db
+	if err = cl.Server.RegisterPubkey(pubkey); err != nil {
 		return
 	}
-	cl.Server.UnlockIngests()
 
 	logging.Infof("Registering user with pubkey %x\n", pubkey.SerializeCompressed())
 	// put this in database
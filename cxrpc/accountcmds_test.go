@@ -0,0 +1,60 @@
+package cxrpc
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxdb"
+	"github.com/mit-dci/opencx/cxserver"
+	"github.com/mit-dci/opencx/match"
+)
+
+// registerCountStore only implements RegisterUser, and counts registrations
+type registerCountStore struct {
+	cxdb.OpencxStore
+	registrations int
+}
+
+// RegisterUser counts the registration
+func (db *registerCountStore) RegisterUser(pubkey *koblitz.PublicKey, addressMap map[*coinparam.Params]string) (err error) {
+	db.registrations++
+	return
+}
+
+func TestRegisterWithoutWallets(t *testing.T) {
+	var err error
+
+	testDB := new(registerCountStore)
+	rpc1 := &OpencxRPC{
+		Server: cxserver.InitServer(testDB, "", 0, nil),
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key for registration: %s", err)
+		return
+	}
+
+	// e = h(domain || registrationstring)
+	e := match.RegistrationDomain.SigHash([]byte(rpc1.Server.GetRegistrationString()))
+
+	var sig []byte
+	if sig, err = koblitz.SignCompact(koblitz.S256(), privkey, e, false); err != nil {
+		t.Errorf("Error signing registration string: %s", err)
+		return
+	}
+
+	// An account with no deposit addresses would be useless, so it shouldn't be made
+	if err = rpc1.Register(RegisterArgs{Signature: sig}, new(RegisterReply)); err == nil {
+		t.Errorf("Registering on an exchange without wallets should fail")
+		return
+	}
+
+	if testDB.registrations != 0 {
+		t.Errorf("Nothing should be registered without wallets, got %d registrations", testDB.registrations)
+		return
+	}
+
+	return
+}
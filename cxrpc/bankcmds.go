@@ -1,6 +1,7 @@
 package cxrpc
 
 import (
+	"encoding/binary"
 	"fmt"
 
 	"github.com/mit-dci/opencx/util"
@@ -42,13 +43,13 @@ func (cl *OpencxRPC) GetBalance(args GetBalanceArgs, reply *GetBalanceReply) (er
 		return
 	}
 
-	cl.Server.LockIngests()
-	if reply.Amount, err = cl.Server.OpencxDB.GetBalance(pubkey, param); err != nil {
-		cl.Server.UnlockIngests()
+	if err = cl.withIngestLock(func() (balanceErr error) {
+		reply.Amount, balanceErr = cl.Server.OpencxDB.GetBalance(pubkey, param)
+		return
+	}); err != nil {
 		err = fmt.Errorf("Error with getbalance command: \n%s", err)
 		return
 	}
-	cl.Server.UnlockIngests()
 
 	return
 }
@@ -78,14 +79,65 @@ func (cl *OpencxRPC) GetDepositAddress(args GetDepositAddressArgs, reply *GetDep
 		return
 	}
 
-	cl.Server.LockIngests()
-	if reply.Address, err = cl.Server.OpencxDB.GetDepositAddress(pubkey, args.Asset); err != nil {
-		// gotta put these here cause if it errors out then oops just locked everything
-		cl.Server.UnlockIngests()
+	var param *coinparam.Params
+	if param, err = util.GetParamFromName(args.Asset); err != nil {
+		err = fmt.Errorf("Error getting coin type from name, pass in a different asset")
+		return
+	}
+
+	if reply.Address, err = cl.Server.DepositAddress(pubkey, param); err != nil {
 		err = fmt.Errorf("Error with getdepositaddress command: \n%s", err)
 		return
 	}
-	cl.Server.UnlockIngests()
+
+	return
+}
+
+// GetDepositAddressesArgs hold the arguments for GetDepositAddresses
+type GetDepositAddressesArgs struct {
+	Asset     string
+	Count     uint64
+	Signature []byte
+}
+
+// GetDepositAddressesReply holds the reply for GetDepositAddresses
+type GetDepositAddressesReply struct {
+	Addresses []string
+}
+
+// GetDepositAddressesSigHash is the hash that is signed for GetDepositAddresses, h(asset || count)
+func GetDepositAddressesSigHash(asset string, count uint64) (e []byte) {
+	countBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(countBytes, count)
+
+	sha3 := sha3.New256()
+	sha3.Write([]byte(asset))
+	sha3.Write(countBytes)
+	e = sha3.Sum(nil)
+	return
+}
+
+// GetDepositAddresses is the RPC Interface for GetDepositAddresses
+func (cl *OpencxRPC) GetDepositAddresses(args GetDepositAddressesArgs, reply *GetDepositAddressesReply) (err error) {
+
+	e := GetDepositAddressesSigHash(args.Asset, args.Count)
+
+	pubkey, _, err := koblitz.RecoverCompact(koblitz.S256(), args.Signature, e)
+	if err != nil {
+		err = fmt.Errorf("Error verifying deposit addresses request, invalid signature: \n%s", err)
+		return
+	}
+
+	var param *coinparam.Params
+	if param, err = util.GetParamFromName(args.Asset); err != nil {
+		err = fmt.Errorf("Error getting coin type from name, pass in a different asset")
+		return
+	}
+
+	if reply.Addresses, err = cl.Server.GetDepositAddresses(pubkey, param, args.Count); err != nil {
+		err = fmt.Errorf("Error with getdepositaddresses command: \n%s", err)
+		return
+	}
 
 	return
 }
@@ -104,10 +156,8 @@ type WithdrawReply struct {
 // Withdraw is the RPC Interface for Withdraw
 func (cl *OpencxRPC) Withdraw(args WithdrawArgs, reply *WithdrawReply) (err error) {
 
-	// e = h(asset)
-	sha3 := sha3.New256()
-	sha3.Write(args.Withdrawal.Serialize())
-	e := sha3.Sum(nil)
+	// e = h(domain || withdrawal)
+	e := match.WithdrawalDomain.SigHash(args.Withdrawal.Serialize())
 
 	pubkey, _, err := koblitz.RecoverCompact(koblitz.S256(), args.Signature, e)
 	if err != nil {
@@ -140,3 +190,39 @@ func (cl *OpencxRPC) Withdraw(args WithdrawArgs, reply *WithdrawReply) (err erro
 
 	return
 }
+
+// GetFeeAccountArgs holds the args for the GetFeeAccount command
+type GetFeeAccountArgs struct {
+	// empty
+}
+
+// GetFeeAccountReply holds the reply for the GetFeeAccount command
+type GetFeeAccountReply struct {
+	// Rate is the fee in basis points taken out of every amount credited during settlement
+	Rate uint64
+	// Account is the compressed pubkey of the account that collects fees, empty if there are no fees.
+	// The fee account is a normal account, so whoever has its key can check its balance and withdraw.
+	Account []byte
+	// PairFeeAssets is which asset fees are taken in for each pair, by pretty pair string
+	PairFeeAssets map[string]string
+}
+
+// GetFeeAccount gets the settlement fee rate and the account fees are collected in
+func (cl *OpencxRPC) GetFeeAccount(args GetFeeAccountArgs, reply *GetFeeAccountReply) (err error) {
+	var account *koblitz.PublicKey
+	if reply.Rate, account, err = cl.Server.GetFeeAccount(); err != nil {
+		err = fmt.Errorf("Error getting fee account: \n%s", err)
+		return
+	}
+
+	if account != nil {
+		reply.Account = account.SerializeCompressed()
+	}
+
+	reply.PairFeeAssets = make(map[string]string)
+	for _, pair := range cl.Server.OpencxDB.GetPairs() {
+		reply.PairFeeAssets[pair.PrettyString()] = cl.Server.GetPairFeeAsset(pair).String()
+	}
+
+	return
+}
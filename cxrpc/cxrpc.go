@@ -1,9 +1,24 @@
 package cxrpc
 
-import "github.com/mit-dci/opencx/cxserver"
+import (
+	"github.com/mit-dci/opencx/cxserver"
+	"github.com/mit-dci/opencx/util"
+)
 
 // OpencxRPC is a listener for RPC commands
 type OpencxRPC struct {
 	Server    *cxserver.OpencxServer
 	OffButton chan bool
+
+	// ListenerConfig is the backlog and keep-alive the RPC listeners are set up with
+	ListenerConfig util.ListenerConfig
+}
+
+// withIngestLock runs f while holding the server's ingest lock. The lock is always released when
+// f returns, so callers don't need to remember to unlock on every error path.
+func (cl *OpencxRPC) withIngestLock(f func() error) (err error) {
+	cl.Server.LockIngests()
+	defer cl.Server.UnlockIngests()
+	err = f()
+	return
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/logging"
 	"github.com/mit-dci/opencx/match"
+	"github.com/mit-dci/opencx/util"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -25,9 +26,7 @@ type SubmitOrderReply struct {
 func (cl *OpencxRPC) SubmitOrder(args SubmitOrderArgs, reply *SubmitOrderReply) (err error) {
 
 	// hash order.
-	sha3 := sha3.New256()
-	sha3.Write(args.Order.Serialize())
-	e := sha3.Sum(nil)
+	e := match.LimitOrderDomain.SigHash(args.Order.Serialize())
 
 	var sigPubKey *koblitz.PublicKey
 	if sigPubKey, _, err = koblitz.RecoverCompact(koblitz.S256(), args.Signature, e); err != nil {
@@ -51,14 +50,13 @@ func (cl *OpencxRPC) SubmitOrder(args SubmitOrderArgs, reply *SubmitOrderReply)
 	// place an order on their exchange, even with a nonce, and then send it over to the other exchange. When you submit an order on one exchange,
 	// you essentially submit an order to all of them. But like once we have channels for orders then this isn't a thing anymore because the channel
 	// tx's are signed and funding stuff is published on chain
-	cl.Server.LockIngests()
-	if reply.OrderID, err = cl.Server.OpencxDB.PlaceOrder(args.Order); err != nil {
-		// gotta put these here cause if it errors out then oops just locked everything
-		cl.Server.UnlockIngests()
+	if err = cl.withIngestLock(func() (placeErr error) {
+		reply.OrderID, placeErr = cl.Server.OpencxDB.PlaceOrder(args.Order)
+		return
+	}); err != nil {
 		err = fmt.Errorf("Error placing order while submitting order: \n%s", err)
 		return
 	}
-	cl.Server.UnlockIngests()
 
 	logging.Infof("User %x submitted OrderID %s", sigPubKey.SerializeCompressed(), reply.OrderID)
 
@@ -79,12 +77,20 @@ type ViewOrderBookReply struct {
 // ViewOrderBook handles the vieworderbook command
 func (cl *OpencxRPC) ViewOrderBook(args ViewOrderBookArgs, reply *ViewOrderBookReply) (err error) {
 
-	cl.Server.LockIngests()
-	if reply.SellOrderBook, reply.BuyOrderBook, err = cl.Server.OpencxDB.ViewOrderBook(args.TradingPair); err != nil {
-		cl.Server.UnlockIngests()
+	if err = cl.withIngestLock(func() (viewErr error) {
+		reply.SellOrderBook, reply.BuyOrderBook, viewErr = cl.Server.OpencxDB.ViewOrderBook(args.TradingPair)
+		return
+	}); err != nil {
+		return
+	}
+
+	if err = setOrderbookPrices(reply.SellOrderBook); err != nil {
+		return
+	}
+
+	if err = setOrderbookPrices(reply.BuyOrderBook); err != nil {
 		return
 	}
-	cl.Server.UnlockIngests()
 
 	return
 }
@@ -101,15 +107,15 @@ type GetPriceReply struct {
 
 // GetPrice returns the price for the specified asset
 func (cl *OpencxRPC) GetPrice(args GetPriceArgs, reply *GetPriceReply) (err error) {
-	cl.Server.LockIngests()
 	// reply.Price = cl.Server.OpencxDB.GetPrice(args.TradingPair.String())
 
-	if reply.Price, err = cl.Server.OpencxDB.CalculatePrice(args.TradingPair); err != nil {
-		cl.Server.UnlockIngests()
+	if err = cl.withIngestLock(func() (priceErr error) {
+		reply.Price, priceErr = cl.Server.OpencxDB.CalculatePrice(args.TradingPair)
+		return
+	}); err != nil {
 		err = fmt.Errorf("Error calculating price: \n%s", err)
 		return
 	}
-	cl.Server.UnlockIngests()
 	return
 }
 
@@ -141,12 +147,13 @@ func (cl *OpencxRPC) CancelOrder(args CancelOrderArgs, reply *CancelOrderReply)
 		return
 	}
 
-	cl.Server.LockIngests()
 	var order *match.LimitOrder
-	if order, err = cl.Server.OpencxDB.GetOrder(args.OrderID); err != nil {
+	if err = cl.withIngestLock(func() (getErr error) {
+		order, getErr = cl.Server.OpencxDB.GetOrder(args.OrderID)
+		return
+	}); err != nil {
 		return
 	}
-	cl.Server.LockIngests()
 
 	// try to parse the order pubkey into koblitz
 	var orderPubKey *koblitz.PublicKey
@@ -160,12 +167,11 @@ func (cl *OpencxRPC) CancelOrder(args CancelOrderArgs, reply *CancelOrderReply)
 		return
 	}
 
-	cl.Server.LockIngests()
-	if err = cl.Server.OpencxDB.CancelOrder(args.OrderID); err != nil {
-		cl.Server.UnlockIngests()
+	if err = cl.withIngestLock(func() error {
+		return cl.Server.OpencxDB.CancelOrder(args.OrderID)
+	}); err != nil {
 		return
 	}
-	cl.Server.UnlockIngests()
 
 	return
 }
@@ -215,11 +221,16 @@ func (cl *OpencxRPC) GetOrder(args GetOrderArgs, reply *GetOrderReply) (err erro
 		return
 	}
 
-	cl.Server.LockIngests()
-	if reply.Order, err = cl.Server.OpencxDB.GetOrder(args.OrderID); err != nil {
+	if err = cl.withIngestLock(func() (getErr error) {
+		reply.Order, getErr = cl.Server.OpencxDB.GetOrder(args.OrderID)
+		return
+	}); err != nil {
+		return
+	}
+
+	if err = reply.Order.SetOrderbookPrice(); err != nil {
 		return
 	}
-	cl.Server.UnlockIngests()
 
 	// try to parse the order pubkey into koblitz
 	var orderPubKey *koblitz.PublicKey
@@ -239,25 +250,51 @@ func (cl *OpencxRPC) GetOrder(args GetOrderArgs, reply *GetOrderReply) (err erro
 // GetOrdersForPubkeyArgs holds the args for the GetOrdersForPubkey command
 type GetOrdersForPubkeyArgs struct {
 	Signature []byte
+	// Offset and Limit are the page of orders to get, a Limit of zero gets every order after Offset
+	Offset uint64
+	Limit  uint64
 }
 
 // GetOrdersForPubkeyReply holds the reply for the GetOrdersForPubkey command
 type GetOrdersForPubkeyReply struct {
 	Orders []*match.LimitOrder
+	// TotalCount is how many orders the pubkey has, across every page
+	TotalCount uint64
 }
 
-// GetOrdersForPubkey gets the orders for the pubkey which has signed the getOrdersString
+// GetOrdersForPubkey gets a page of the orders for the pubkey which has signed the getOrdersString
 func (cl *OpencxRPC) GetOrdersForPubkey(args GetOrdersForPubkeyArgs, reply *GetOrdersForPubkeyReply) (err error) {
 	var pubkey *koblitz.PublicKey
 	if pubkey, err = cl.Server.GetOrdersStringVerify(args.Signature); err != nil {
 		return
 	}
 
-	cl.Server.LockIngests()
-	if reply.Orders, err = cl.Server.OpencxDB.GetOrdersForPubkey(pubkey); err != nil {
+	if err = cl.withIngestLock(func() (getErr error) {
+		reply.Orders, getErr = cl.Server.OpencxDB.GetOrdersForPubkey(pubkey)
+		return
+	}); err != nil {
 		return
 	}
-	cl.Server.UnlockIngests()
+
+	reply.TotalCount = uint64(len(reply.Orders))
+	start, end := util.PageBounds(len(reply.Orders), args.Offset, args.Limit)
+	reply.Orders = reply.Orders[start:end]
+
+	if err = setOrderbookPrices(reply.Orders); err != nil {
+		return
+	}
+
+	return
+}
+
+// setOrderbookPrices makes sure every order being returned to a client has its orderbook price set
+func setOrderbookPrices(orders []*match.LimitOrder) (err error) {
+	for _, order := range orders {
+		if err = order.SetOrderbookPrice(); err != nil {
+			err = fmt.Errorf("Error setting orderbook price for order %s: \n%s", order.OrderID, err)
+			return
+		}
+	}
 
 	return
 }
@@ -0,0 +1,168 @@
+package cxrpc
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxdb"
+	"github.com/mit-dci/opencx/cxserver"
+	"github.com/mit-dci/opencx/match"
+	"golang.org/x/crypto/sha3"
+)
+
+// fixedOrdersStore only implements GetOrdersForPubkey, and returns the same orders every time
+type fixedOrdersStore struct {
+	cxdb.OpencxStore
+	orders []*match.LimitOrder
+}
+
+// GetOrdersForPubkey returns the fixed orders
+func (db *fixedOrdersStore) GetOrdersForPubkey(pubkey *koblitz.PublicKey) (orders []*match.LimitOrder, err error) {
+	orders = db.orders
+	return
+}
+
+func TestGetOrdersForPubkeyOrderbookPrice(t *testing.T) {
+	var err error
+
+	// The first order has no price set by the store, so it should be calculated from the amounts.
+	// The second has been partially filled, so the store's price should be kept.
+	testDB := &fixedOrdersStore{
+		orders: []*match.LimitOrder{
+			{
+				Side:       "buy",
+				AmountHave: 1000,
+				AmountWant: 2000,
+			},
+			{
+				Side:           "sell",
+				AmountHave:     999,
+				AmountWant:     250,
+				OrderbookPrice: 4.0,
+			},
+		},
+	}
+	expectedPrices := []float64{2.0, 4.0}
+
+	rpc1 := &OpencxRPC{
+		Server: cxserver.InitServer(testDB, "", 0, nil),
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key: %s", err)
+		return
+	}
+
+	// e = h(getOrders)
+	sha3 := sha3.New256()
+	sha3.Write([]byte(rpc1.Server.GetOrdersString()))
+	e := sha3.Sum(nil)
+
+	var sig []byte
+	if sig, err = koblitz.SignCompact(koblitz.S256(), privkey, e, false); err != nil {
+		t.Errorf("Error signing get orders string: %s", err)
+		return
+	}
+
+	reply := new(GetOrdersForPubkeyReply)
+	if err = rpc1.GetOrdersForPubkey(GetOrdersForPubkeyArgs{Signature: sig}, reply); err != nil {
+		t.Errorf("Error getting orders for pubkey: %s", err)
+		return
+	}
+
+	if len(reply.Orders) != len(expectedPrices) {
+		t.Errorf("Expected %d orders but got %d", len(expectedPrices), len(reply.Orders))
+		return
+	}
+
+	for i, order := range reply.Orders {
+		if order.OrderbookPrice != expectedPrices[i] {
+			t.Errorf("Expected order %d to have orderbook price %f but got %f", i, expectedPrices[i], order.OrderbookPrice)
+		}
+	}
+
+	return
+}
+
+func TestGetOrdersForPubkeyPaging(t *testing.T) {
+	var err error
+
+	// Each order is told apart by how much it has
+	testDB := new(fixedOrdersStore)
+	for i := 0; i < 10; i++ {
+		testDB.orders = append(testDB.orders, &match.LimitOrder{
+			Side:       "buy",
+			AmountHave: uint64(i + 1),
+			AmountWant: 1000,
+		})
+	}
+
+	rpc1 := &OpencxRPC{
+		Server: cxserver.InitServer(testDB, "", 0, nil),
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key: %s", err)
+		return
+	}
+
+	// e = h(getOrders)
+	sha3 := sha3.New256()
+	sha3.Write([]byte(rpc1.Server.GetOrdersString()))
+	e := sha3.Sum(nil)
+
+	var sig []byte
+	if sig, err = koblitz.SignCompact(koblitz.S256(), privkey, e, false); err != nil {
+		t.Errorf("Error signing get orders string: %s", err)
+		return
+	}
+
+	// Pages of 3 should get every order exactly once, in order, and then an empty page
+	var paged []*match.LimitOrder
+	for offset := uint64(0); offset <= uint64(len(testDB.orders)); offset += 3 {
+		reply := new(GetOrdersForPubkeyReply)
+		if err = rpc1.GetOrdersForPubkey(GetOrdersForPubkeyArgs{Signature: sig, Offset: offset, Limit: 3}, reply); err != nil {
+			t.Errorf("Error getting page of orders at offset %d: %s", offset, err)
+			return
+		}
+
+		if reply.TotalCount != uint64(len(testDB.orders)) {
+			t.Errorf("Total count should be %d on every page, got %d", len(testDB.orders), reply.TotalCount)
+			return
+		}
+
+		if len(reply.Orders) > 3 {
+			t.Errorf("Page should have at most 3 orders, got %d", len(reply.Orders))
+			return
+		}
+		paged = append(paged, reply.Orders...)
+	}
+
+	if len(paged) != len(testDB.orders) {
+		t.Errorf("Paging should get all %d orders, got %d", len(testDB.orders), len(paged))
+		return
+	}
+
+	for i, order := range paged {
+		if order.AmountHave != uint64(i+1) {
+			t.Errorf("Order %d from paging is out of order, it has %d", i, order.AmountHave)
+			return
+		}
+	}
+
+	// No limit gets every order
+	reply := new(GetOrdersForPubkeyReply)
+	if err = rpc1.GetOrdersForPubkey(GetOrdersForPubkeyArgs{Signature: sig}, reply); err != nil {
+		t.Errorf("Error getting every order: %s", err)
+		return
+	}
+
+	if len(reply.Orders) != len(testDB.orders) {
+		t.Errorf("No limit should get all %d orders, got %d", len(testDB.orders), len(reply.Orders))
+		return
+	}
+
+	return
+}
@@ -0,0 +1,138 @@
+package cxserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// MaxDepositAddresses is the most fresh deposit addresses that can be created in one request
+const MaxDepositAddresses = 100
+
+// AddressPolicy is whether users get the same deposit address every time, or a new one for every deposit
+type AddressPolicy string
+
+const (
+	// ReuseAddress gives users the address they registered with for every deposit. This is simpler but
+	// links all of a user's deposits together on chain.
+	ReuseAddress AddressPolicy = "reuse"
+	// FreshAddress derives a new address every time a user asks for a deposit address. The old addresses
+	// are still watched, so late deposits to them are still credited.
+	FreshAddress AddressPolicy = "fresh"
+)
+
+// String returns the name of the policy
+func (p AddressPolicy) String() string {
+	return string(p)
+}
+
+// AddressPolicyFromString returns the address policy with the name policyString
+func AddressPolicyFromString(policyString string) (policy AddressPolicy, err error) {
+	switch AddressPolicy(policyString) {
+	case ReuseAddress, FreshAddress:
+		policy = AddressPolicy(policyString)
+	default:
+		err = fmt.Errorf("Unknown address policy %s, must be %s or %s", policyString, ReuseAddress, FreshAddress)
+	}
+	return
+}
+
+// SetAddressPolicy sets whether deposit addresses are reused or fresh for every deposit
+func (server *OpencxServer) SetAddressPolicy(policy AddressPolicy) (err error) {
+	if _, err = AddressPolicyFromString(policy.String()); err != nil {
+		return
+	}
+
+	server.addressPolicyMtx.Lock()
+	server.addressPolicy = policy
+	server.addressPolicyMtx.Unlock()
+	return
+}
+
+// GetAddressPolicy returns whether deposit addresses are reused or fresh for every deposit
+func (server *OpencxServer) GetAddressPolicy() (policy AddressPolicy) {
+	server.addressPolicyMtx.Lock()
+	policy = server.addressPolicy
+	server.addressPolicyMtx.Unlock()
+	return
+}
+
+// DepositAddress gets the address pubkey should deposit coinType to, according to the address policy.
+// The pubkey has to be registered either way.
+func (server *OpencxServer) DepositAddress(pubkey *koblitz.PublicKey, coinType *coinparam.Params) (addr string, err error) {
+	if coinType == nil {
+		err = ErrUnsupportedCoin
+		return
+	}
+
+	// This also makes sure that the pubkey is registered
+	if err = server.withIngestLock(func() (addrErr error) {
+		addr, addrErr = server.OpencxDB.GetDepositAddress(pubkey, coinType.Name)
+		return
+	}); err != nil {
+		err = fmt.Errorf("Error getting deposit address: \n%s", err)
+		return
+	}
+
+	if server.GetAddressPolicy() == ReuseAddress {
+		return
+	}
+
+	addr, err = server.freshDepositAddress(pubkey, coinType)
+	return
+}
+
+// GetDepositAddresses creates count fresh deposit addresses for pubkey to deposit coinType to, no matter
+// what the address policy is. This is for clients that want a batch of addresses up front. The pubkey
+// has to be registered.
+func (server *OpencxServer) GetDepositAddresses(pubkey *koblitz.PublicKey, coinType *coinparam.Params, count uint64) (addrs []string, err error) {
+	if coinType == nil {
+		err = ErrUnsupportedCoin
+		return
+	}
+
+	if count == 0 || count > MaxDepositAddresses {
+		err = fmt.Errorf("Can only get between 1 and %d deposit addresses at once, not %d", MaxDepositAddresses, count)
+		return
+	}
+
+	// This makes sure that the pubkey is registered
+	if err = server.withIngestLock(func() (addrErr error) {
+		_, addrErr = server.OpencxDB.GetDepositAddress(pubkey, coinType.Name)
+		return
+	}); err != nil {
+		err = fmt.Errorf("Error getting deposit addresses: \n%s", err)
+		return
+	}
+
+	for i := uint64(0); i < count; i++ {
+		var addr string
+		if addr, err = server.freshDepositAddress(pubkey, coinType); err != nil {
+			addrs = nil
+			return
+		}
+		addrs = append(addrs, addr)
+	}
+
+	return
+}
+
+// freshDepositAddress creates a new deposit address for pubkey and stores it
+func (server *OpencxServer) freshDepositAddress(pubkey *koblitz.PublicKey, coinType *coinparam.Params) (addr string, err error) {
+	if addr, err = server.newAddress(coinType, pubkey); err != nil {
+		err = fmt.Errorf("Error creating fresh deposit address: \n%s", err)
+		return
+	}
+
+	// The address has to be stored before we give it out, so deposits to it are found
+	if err = server.withIngestLock(func() error {
+		return server.OpencxDB.AddDepositAddress(pubkey, coinType, addr)
+	}); err != nil {
+		err = fmt.Errorf("Error storing fresh deposit address: \n%s", err)
+		addr = ""
+		return
+	}
+
+	return
+}
@@ -0,0 +1,236 @@
+package cxserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxdb"
+)
+
+// depositAddressStore only implements the deposit address methods, and keeps every address for a pubkey
+type depositAddressStore struct {
+	cxdb.OpencxStore
+	addresses map[string][]string
+}
+
+// GetDepositAddress returns the first address that was stored for the pubkey
+func (db *depositAddressStore) GetDepositAddress(pubkey *koblitz.PublicKey, asset string) (addr string, err error) {
+	addrs, found := db.addresses[fmt.Sprintf("%x%s", pubkey.SerializeCompressed(), asset)]
+	if !found {
+		err = fmt.Errorf("Cannot find deposit address. Make sure you've registered")
+		return
+	}
+	addr = addrs[0]
+	return
+}
+
+// AddDepositAddress stores another address for the pubkey
+func (db *depositAddressStore) AddDepositAddress(pubkey *koblitz.PublicKey, coinType *coinparam.Params, addr string) (err error) {
+	key := fmt.Sprintf("%x%s", pubkey.SerializeCompressed(), coinType.Name)
+	db.addresses[key] = append(db.addresses[key], addr)
+	return
+}
+
+// initAddressPolicyServer creates a server with a registered pubkey, whose fresh addresses are just numbered
+func initAddressPolicyServer() (server *OpencxServer, store *depositAddressStore, pubkey *koblitz.PublicKey, err error) {
+	store = &depositAddressStore{addresses: make(map[string][]string)}
+	server = InitServer(store, "", 0, []*coinparam.Params{&coinparam.TestNet3Params})
+
+	numAddresses := 0
+	server.newAddress = func(coinType *coinparam.Params, pubkey *koblitz.PublicKey) (addr string, err error) {
+		numAddresses++
+		addr = fmt.Sprintf("address%d", numAddresses)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		return
+	}
+	pubkey = privkey.PubKey()
+
+	// register with the first address
+	var regAddr string
+	if regAddr, err = server.newAddress(&coinparam.TestNet3Params, pubkey); err != nil {
+		return
+	}
+	err = store.AddDepositAddress(pubkey, &coinparam.TestNet3Params, regAddr)
+	return
+}
+
+func TestReuseAddressPolicy(t *testing.T) {
+	var err error
+	var server *OpencxServer
+	var store *depositAddressStore
+	var pubkey *koblitz.PublicKey
+	if server, store, pubkey, err = initAddressPolicyServer(); err != nil {
+		t.Errorf("Error initializing server for address policy test: %s", err)
+		return
+	}
+
+	if server.GetAddressPolicy() != ReuseAddress {
+		t.Errorf("Default address policy should be %s, got %s", ReuseAddress, server.GetAddressPolicy())
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		var addr string
+		if addr, err = server.DepositAddress(pubkey, &coinparam.TestNet3Params); err != nil {
+			t.Errorf("Error getting deposit address: %s", err)
+			return
+		}
+		if addr != "address1" {
+			t.Errorf("Reuse policy should always give the registered address address1, got %s", addr)
+			return
+		}
+	}
+
+	key := fmt.Sprintf("%x%s", pubkey.SerializeCompressed(), coinparam.TestNet3Params.Name)
+	if len(store.addresses[key]) != 1 {
+		t.Errorf("Reuse policy should not store new addresses, there are %d", len(store.addresses[key]))
+		return
+	}
+
+	return
+}
+
+func TestFreshAddressPolicy(t *testing.T) {
+	var err error
+	var server *OpencxServer
+	var store *depositAddressStore
+	var pubkey *koblitz.PublicKey
+	if server, store, pubkey, err = initAddressPolicyServer(); err != nil {
+		t.Errorf("Error initializing server for address policy test: %s", err)
+		return
+	}
+
+	if err = server.SetAddressPolicy(FreshAddress); err != nil {
+		t.Errorf("Error setting address policy: %s", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		var addr string
+		if addr, err = server.DepositAddress(pubkey, &coinparam.TestNet3Params); err != nil {
+			t.Errorf("Error getting deposit address: %s", err)
+			return
+		}
+		if addr == "address1" || seen[addr] {
+			t.Errorf("Fresh policy should give a new address every time, got %s again", addr)
+			return
+		}
+		seen[addr] = true
+	}
+
+	// every address handed out has to be stored, so deposits to it are found
+	key := fmt.Sprintf("%x%s", pubkey.SerializeCompressed(), coinparam.TestNet3Params.Name)
+	if len(store.addresses[key]) != 4 {
+		t.Errorf("Fresh policy should have stored the registered address and 3 fresh ones, there are %d", len(store.addresses[key]))
+		return
+	}
+
+	return
+}
+
+func TestFreshAddressPolicyUnregistered(t *testing.T) {
+	var err error
+	var server *OpencxServer
+	if server, _, _, err = initAddressPolicyServer(); err != nil {
+		t.Errorf("Error initializing server for address policy test: %s", err)
+		return
+	}
+
+	if err = server.SetAddressPolicy(FreshAddress); err != nil {
+		t.Errorf("Error setting address policy: %s", err)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key: %s", err)
+		return
+	}
+
+	if _, err = server.DepositAddress(privkey.PubKey(), &coinparam.TestNet3Params); err == nil {
+		t.Errorf("Unregistered pubkeys should not get fresh deposit addresses")
+		return
+	}
+
+	if err = server.SetAddressPolicy(AddressPolicy("sometimes")); err == nil {
+		t.Errorf("Setting an unknown address policy should fail")
+		return
+	}
+
+	return
+}
+
+func TestGetDepositAddresses(t *testing.T) {
+	var err error
+	var server *OpencxServer
+	var store *depositAddressStore
+	var pubkey *koblitz.PublicKey
+	if server, store, pubkey, err = initAddressPolicyServer(); err != nil {
+		t.Errorf("Error initializing server for deposit addresses test: %s", err)
+		return
+	}
+
+	// This works even when addresses are reused for single deposits
+	var addrs []string
+	if addrs, err = server.GetDepositAddresses(pubkey, &coinparam.TestNet3Params, 5); err != nil {
+		t.Errorf("Error getting deposit addresses: %s", err)
+		return
+	}
+
+	if len(addrs) != 5 {
+		t.Errorf("Asked for 5 deposit addresses, got %d", len(addrs))
+		return
+	}
+
+	seen := map[string]bool{"address1": true}
+	for _, addr := range addrs {
+		if seen[addr] {
+			t.Errorf("Deposit address %s was given out more than once", addr)
+			return
+		}
+		seen[addr] = true
+	}
+
+	// They all have to be stored for the pubkey, so deposits to them are credited to it
+	key := fmt.Sprintf("%x%s", pubkey.SerializeCompressed(), coinparam.TestNet3Params.Name)
+	stored := make(map[string]bool)
+	for _, addr := range store.addresses[key] {
+		stored[addr] = true
+	}
+	for _, addr := range addrs {
+		if !stored[addr] {
+			t.Errorf("Deposit address %s was not attributed to the pubkey", addr)
+			return
+		}
+	}
+
+	if _, err = server.GetDepositAddresses(pubkey, &coinparam.TestNet3Params, 0); err == nil {
+		t.Errorf("Getting 0 deposit addresses should fail")
+		return
+	}
+
+	if _, err = server.GetDepositAddresses(pubkey, &coinparam.TestNet3Params, MaxDepositAddresses+1); err == nil {
+		t.Errorf("Getting more than %d deposit addresses should fail", MaxDepositAddresses)
+		return
+	}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key: %s", err)
+		return
+	}
+
+	if _, err = server.GetDepositAddresses(privkey.PubKey(), &coinparam.TestNet3Params, 2); err == nil {
+		t.Errorf("Unregistered pubkeys should not get deposit addresses")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,97 @@
+package cxserver
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/match"
+)
+
+// SetFeeAccount sets the fee, in basis points, taken out of every amount credited during settlement,
+// and the account the fees are collected in. This resets which asset every pair takes fees in.
+func (server *OpencxServer) SetFeeAccount(rate uint64, account *koblitz.PublicKey) (err error) {
+	var schedule *match.FeeSchedule
+	if schedule, err = match.NewFeeSchedule(rate, account); err != nil {
+		return
+	}
+
+	if err = server.withIngestLock(func() error {
+		return server.OpencxDB.SetFeeSchedule(schedule)
+	}); err != nil {
+		err = fmt.Errorf("Error setting fee account: \n%s", err)
+		return
+	}
+
+	return
+}
+
+// SetPairFeeAsset sets which asset of pair fees are taken in. A fee account has to be set first.
+func (server *OpencxServer) SetPairFeeAsset(pair *match.Pair, feeAsset match.FeeAsset) (err error) {
+	if err = server.withIngestLock(func() (setErr error) {
+		current := server.OpencxDB.GetFeeSchedule()
+		if current == nil {
+			setErr = fmt.Errorf("No fee account has been set")
+			return
+		}
+
+		var schedule *match.FeeSchedule
+		if schedule, setErr = current.WithPairFeeAsset(pair, feeAsset); setErr != nil {
+			return
+		}
+
+		setErr = server.OpencxDB.SetFeeSchedule(schedule)
+		return
+	}); err != nil {
+		err = fmt.Errorf("Error setting fee asset for pair %s: \n%s", pair, err)
+		return
+	}
+
+	return
+}
+
+// GetPairFeeAsset returns which asset of pair fees are taken in
+func (server *OpencxServer) GetPairFeeAsset(pair *match.Pair) (feeAsset match.FeeAsset) {
+	feeAsset = server.OpencxDB.GetFeeSchedule().PairFeeAsset(pair)
+	return
+}
+
+// GetFeeAccount returns the fee rate in basis points and the account fees are collected in. The account
+// is nil if no fees are being taken.
+func (server *OpencxServer) GetFeeAccount() (rate uint64, account *koblitz.PublicKey, err error) {
+	schedule := server.OpencxDB.GetFeeSchedule()
+	if schedule == nil {
+		return
+	}
+
+	if account, err = schedule.AccountPubkey(); err != nil {
+		return
+	}
+
+	rate = schedule.Rate
+	return
+}
+
+// CollectedFees returns the balance of the fee account for coinType, which is all of the fees that have
+// been collected in that coin and not withdrawn.
+func (server *OpencxServer) CollectedFees(coinType *coinparam.Params) (fees uint64, err error) {
+	var account *koblitz.PublicKey
+	if _, account, err = server.GetFeeAccount(); err != nil {
+		return
+	}
+
+	if account == nil {
+		err = fmt.Errorf("Error getting collected fees, no fee account has been set")
+		return
+	}
+
+	if err = server.withIngestLock(func() (balErr error) {
+		fees, balErr = server.OpencxDB.GetBalance(account, coinType)
+		return
+	}); err != nil {
+		err = fmt.Errorf("Error getting collected fees: \n%s", err)
+		return
+	}
+
+	return
+}
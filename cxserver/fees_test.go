@@ -0,0 +1,180 @@
+package cxserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/cxdb"
+	"github.com/mit-dci/opencx/match"
+)
+
+// feeStore only implements balances and the fee schedule, and settles amounts the same way settlement does
+type feeStore struct {
+	cxdb.OpencxStore
+	balances    map[string]uint64
+	feeSchedule *match.FeeSchedule
+}
+
+// GetBalance returns the balance of the pubkey, which is zero if nothing has been credited
+func (db *feeStore) GetBalance(pubkey *koblitz.PublicKey, coinType *coinparam.Params) (balance uint64, err error) {
+	balance = db.balances[fmt.Sprintf("%x%s", pubkey.SerializeCompressed(), coinType.Name)]
+	return
+}
+
+// SetFeeSchedule sets the fee schedule
+func (db *feeStore) SetFeeSchedule(schedule *match.FeeSchedule) (err error) {
+	db.feeSchedule = schedule
+	return
+}
+
+// GetFeeSchedule gets the fee schedule
+func (db *feeStore) GetFeeSchedule() (schedule *match.FeeSchedule) {
+	schedule = db.feeSchedule
+	return
+}
+
+// settle credits pubkey with amount, taking out fees
+func (db *feeStore) settle(pubkey *koblitz.PublicKey, amount uint64, coinType *coinparam.Params) (err error) {
+	return db.feeSchedule.Credit(pubkey, amount, func(creditPubkey *koblitz.PublicKey, creditAmount uint64) error {
+		db.balances[fmt.Sprintf("%x%s", creditPubkey.SerializeCompressed(), coinType.Name)] += creditAmount
+		return nil
+	})
+}
+
+func TestCollectedFeesSumIntoFeeAccount(t *testing.T) {
+	var err error
+	store := &feeStore{balances: make(map[string]uint64)}
+	server := InitServer(store, "", 0, []*coinparam.Params{&coinparam.TestNet3Params, &coinparam.VertcoinTestNetParams})
+
+	if _, err = server.CollectedFees(&coinparam.TestNet3Params); err == nil {
+		t.Errorf("Getting collected fees should fail when there is no fee account")
+		return
+	}
+
+	var feePrivkey *koblitz.PrivateKey
+	if feePrivkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating fee account key: %s", err)
+		return
+	}
+
+	// 30 basis points
+	if err = server.SetFeeAccount(30, feePrivkey.PubKey()); err != nil {
+		t.Errorf("Error setting fee account: %s", err)
+		return
+	}
+
+	var rate uint64
+	var account *koblitz.PublicKey
+	if rate, account, err = server.GetFeeAccount(); err != nil {
+		t.Errorf("Error getting fee account: %s", err)
+		return
+	}
+
+	if rate != 30 || !account.IsEqual(feePrivkey.PubKey()) {
+		t.Errorf("Fee account should be %x at 30 basis points, got %x at %d", feePrivkey.PubKey().SerializeCompressed(), account.SerializeCompressed(), rate)
+		return
+	}
+
+	var traders []*koblitz.PublicKey
+	for i := 0; i < 4; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating trader key: %s", err)
+			return
+		}
+		traders = append(traders, privkey.PubKey())
+	}
+
+	// settle a bunch of fills in both coins, some small enough that the fee rounds down to nothing
+	coins := []*coinparam.Params{&coinparam.TestNet3Params, &coinparam.VertcoinTestNetParams}
+	settled := make(map[*coinparam.Params]uint64)
+	expectedFees := make(map[*coinparam.Params]uint64)
+	for i := uint64(0); i < 100; i++ {
+		coinType := coins[i%2]
+		amount := 100 + i*i*997
+		if err = store.settle(traders[i%4], amount, coinType); err != nil {
+			t.Errorf("Error settling fill: %s", err)
+			return
+		}
+		settled[coinType] += amount
+		expectedFees[coinType] += amount * 30 / match.MaxFeeRate
+	}
+
+	for _, coinType := range coins {
+		var fees uint64
+		if fees, err = server.CollectedFees(coinType); err != nil {
+			t.Errorf("Error getting collected fees: %s", err)
+			return
+		}
+
+		var traderTotal uint64
+		for _, trader := range traders {
+			var balance uint64
+			if balance, err = store.GetBalance(trader, coinType); err != nil {
+				t.Errorf("Error getting trader balance: %s", err)
+				return
+			}
+			traderTotal += balance
+		}
+
+		if fees != expectedFees[coinType] {
+			t.Errorf("Fee account should have collected %d of %s, got %d", expectedFees[coinType], coinType.Name, fees)
+			return
+		}
+
+		// nothing is made or lost, everything that was settled went to a trader or the fee account
+		if traderTotal+fees != settled[coinType] {
+			t.Errorf("Traders got %d and the fee account got %d of %s, but %d was settled", traderTotal, fees, coinType.Name, settled[coinType])
+			return
+		}
+	}
+
+	return
+}
+
+func TestSetPairFeeAsset(t *testing.T) {
+	var err error
+	store := &feeStore{balances: make(map[string]uint64)}
+	server := InitServer(store, "", 0, []*coinparam.Params{&coinparam.TestNet3Params, &coinparam.VertcoinTestNetParams})
+
+	pair := &match.Pair{AssetWant: match.BTCTest, AssetHave: match.VTCTest}
+	if err = server.SetPairFeeAsset(pair, match.FeeInQuote); err == nil {
+		t.Errorf("Setting a pair fee asset should fail when there is no fee account")
+		return
+	}
+
+	var feePrivkey *koblitz.PrivateKey
+	if feePrivkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating fee account key: %s", err)
+		return
+	}
+
+	if err = server.SetFeeAccount(30, feePrivkey.PubKey()); err != nil {
+		t.Errorf("Error setting fee account: %s", err)
+		return
+	}
+
+	if server.GetPairFeeAsset(pair) != match.FeeInReceived {
+		t.Errorf("Pairs should take fees in the asset received by default, got %s", server.GetPairFeeAsset(pair))
+		return
+	}
+
+	if err = server.SetPairFeeAsset(pair, match.FeeInQuote); err != nil {
+		t.Errorf("Error setting pair fee asset: %s", err)
+		return
+	}
+
+	if server.GetPairFeeAsset(pair) != match.FeeInQuote {
+		t.Errorf("Pair should take fees in %s, got %s", match.FeeInQuote, server.GetPairFeeAsset(pair))
+		return
+	}
+
+	if err = server.SetPairFeeAsset(pair, match.FeeAsset("everything")); err == nil {
+		t.Errorf("Setting an unknown pair fee asset should fail")
+		return
+	}
+
+	return
+}
@@ -29,14 +29,13 @@ func (server *OpencxServer) ingestTransactionListAndHeight(txList []*wire.MsgTx,
 		}
 	}()
 	var addressesWeOwn map[string]*koblitz.PublicKey
-	server.LockIngests()
-	if addressesWeOwn, err = server.OpencxDB.GetDepositAddressMap(coinType); err != nil {
-		// if errors out, unlock
-		server.UnlockIngests()
+	if err = server.withIngestLock(func() (addrErr error) {
+		addressesWeOwn, addrErr = server.OpencxDB.GetDepositAddressMap(coinType)
+		return
+	}); err != nil {
 		logging.Errorf("Error getting deposit address map")
 		return
 	}
-	server.UnlockIngests()
 
 	var deposits []match.Deposit
 
@@ -72,14 +71,12 @@ func (server *OpencxServer) ingestTransactionListAndHeight(txList []*wire.MsgTx,
 		}
 	}
 
-	server.LockIngests()
-	if err = server.OpencxDB.UpdateDeposits(deposits, height, coinType); err != nil {
-		// if errors out, unlock
-		server.UnlockIngests()
+	if err = server.withIngestLock(func() error {
+		return server.OpencxDB.UpdateDeposits(deposits, height, coinType)
+	}); err != nil {
 		logging.Errorf("Error updating deposits")
 		return
 	}
-	server.UnlockIngests()
 
 	logging.Debugf("Finished ingesting %s block at height %d", coinType.Name, height)
 	if height%10000 == 0 {
@@ -97,14 +94,12 @@ func (server *OpencxServer) ingestChannelPush(pushAmt uint64, pubkey *koblitz.Pu
 	}
 
 	logging.Infof("Confirmed channel from pubkey %x\n", pubkey.SerializeCompressed())
-	server.LockIngests()
-
-	if err = server.OpencxDB.AddToBalance(pubkey, pushAmt, param); err != nil {
+	if err = server.withIngestLock(func() error {
+		return server.OpencxDB.AddToBalance(pubkey, pushAmt, param)
+	}); err != nil {
 		return
 	}
 
-	server.UnlockIngests()
-
 	return
 }
 
@@ -118,14 +113,12 @@ func (server *OpencxServer) ingestChannelConfirm(state *qln.StatCom, pubkey *kob
 	}
 
 	logging.Infof("Confirmed channel from pubkey %x\n", pubkey.SerializeCompressed())
-	server.LockIngests()
-
-	if err = server.OpencxDB.AddToBalance(pubkey, uint64(state.MyAmt), param); err != nil {
+	if err = server.withIngestLock(func() error {
+		return server.OpencxDB.AddToBalance(pubkey, uint64(state.MyAmt), param)
+	}); err != nil {
 		return
 	}
 
-	server.UnlockIngests()
-
 	return
 }
 
@@ -133,21 +126,11 @@ func (server *OpencxServer) ingestChannelConfirm(state *qln.StatCom, pubkey *kob
 func (server *OpencxServer) ingestChannelFund(state *qln.StatCom, pubkey *koblitz.PublicKey, coinType uint32, qchanID uint32) (err error) {
 	logging.Infof("Pubkey %x funded a channel to give me %d of cointype %d\n", pubkey.SerializeCompressed(), state.MyAmt, coinType)
 
-	var addrMap map[*coinparam.Params]string
-	if addrMap, err = server.GetAddressMap(pubkey); err != nil {
-		return
-	}
-
 	logging.Infof("Registering user with pubkey %x\n", pubkey.SerializeCompressed())
-	server.LockIngests()
-
-	if err = server.OpencxDB.RegisterUser(pubkey, addrMap); err != nil {
-		server.UnlockIngests()
+	if err = server.RegisterPubkey(pubkey); err != nil {
 		return
 	}
 
-	server.UnlockIngests()
-
 	if err = server.SetupFundBack(pubkey, coinType, server.defaultCapacity); err != nil {
 		return
 	}
@@ -164,3 +147,11 @@ func (server *OpencxServer) LockIngests() {
 func (server *OpencxServer) UnlockIngests() {
 	server.ingestMutex.Unlock()
 }
+
+// withIngestLock runs f while holding the ingest lock, and makes sure the lock is released when f returns
+func (server *OpencxServer) withIngestLock(f func() error) (err error) {
+	server.LockIngests()
+	defer server.UnlockIngests()
+	err = f()
+	return
+}
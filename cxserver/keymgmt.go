@@ -1,18 +1,44 @@
 package cxserver
 
 import (
-	"fmt"
+	"errors"
 
 	"github.com/mit-dci/lit/btcutil/base58"
 	"github.com/mit-dci/lit/coinparam"
 	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/lit/wallit"
 )
 
-// GetAddrForCoin gets an address based on a wallet and pubkey
-func (server *OpencxServer) GetAddrForCoin(coinType *coinparam.Params, pubkey *koblitz.PublicKey) (addr string, err error) {
+// ErrUnsupportedCoin is returned when a coin is requested that the exchange doesn't have a wallet for
+var ErrUnsupportedCoin = errors.New("coin is not supported by this exchange")
+
+// ErrNoWallets is returned when the exchange needs a wallet but none are enabled
+var ErrNoWallets = errors.New("no wallets are enabled, at least one coin has to be enabled")
+
+// walletForCoin gets the wallet for a coin, or ErrUnsupportedCoin if the coin isn't enabled
+func (server *OpencxServer) walletForCoin(coinType *coinparam.Params) (wallet *wallit.Wallit, err error) {
+	if coinType == nil {
+		err = ErrUnsupportedCoin
+		return
+	}
+
+	server.walletMtx.Lock()
 	wallet, found := server.WalletMap[coinType]
-	if !found {
-		err = fmt.Errorf("Could not find wallet to create address for")
+	server.walletMtx.Unlock()
+	if !found || wallet == nil {
+		err = ErrUnsupportedCoin
+		return
+	}
+
+	return
+}
+
+// GetAddrForCoin gets an address based on a wallet and pubkey. If there is no wallet for the coin then
+// ErrUnsupportedCoin is returned.
+func (server *OpencxServer) GetAddrForCoin(coinType *coinparam.Params, pubkey *koblitz.PublicKey) (addr string, err error) {
+	var wallet *wallit.Wallit
+	if wallet, err = server.walletForCoin(coinType); err != nil {
+		return
 	}
 
 	pubKeyHashAddrID := wallet.Param.PubKeyHashAddrID
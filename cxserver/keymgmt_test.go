@@ -0,0 +1,61 @@
+package cxserver
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/lit/wallit"
+)
+
+func TestWalletForSupportedCoin(t *testing.T) {
+	var err error
+	server := InitServer(nil, "", 0, []*coinparam.Params{&coinparam.TestNet3Params})
+
+	// The wallet never gets used, we only care that the right one is found
+	testWallet := &wallit.Wallit{Param: &coinparam.TestNet3Params}
+	server.WalletMap[&coinparam.TestNet3Params] = testWallet
+
+	var wallet *wallit.Wallit
+	if wallet, err = server.walletForCoin(&coinparam.TestNet3Params); err != nil {
+		t.Errorf("Error getting wallet for supported coin: %s", err)
+		return
+	}
+
+	if wallet != testWallet {
+		t.Errorf("Got the wrong wallet for a supported coin")
+		return
+	}
+
+	return
+}
+
+func TestGetAddrForUnsupportedCoin(t *testing.T) {
+	var err error
+	server := InitServer(nil, "", 0, []*coinparam.Params{&coinparam.TestNet3Params})
+	server.WalletMap[&coinparam.TestNet3Params] = &wallit.Wallit{Param: &coinparam.TestNet3Params}
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key: %s", err)
+		return
+	}
+
+	var addr string
+	if addr, err = server.GetAddrForCoin(&coinparam.VertcoinTestNetParams, privkey.PubKey()); err != ErrUnsupportedCoin {
+		t.Errorf("Getting an address for a coin without a wallet should return ErrUnsupportedCoin, got %v", err)
+		return
+	}
+
+	if addr != "" {
+		t.Errorf("Getting an address for an unsupported coin should not return an address, got %s", addr)
+		return
+	}
+
+	if _, err = server.GetAddrForCoin(nil, privkey.PubKey()); err != ErrUnsupportedCoin {
+		t.Errorf("Getting an address for a nil coin should return ErrUnsupportedCoin, got %v", err)
+		return
+	}
+
+	return
+}
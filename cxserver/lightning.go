@@ -402,15 +402,13 @@ func (server *OpencxServer) CreateChannel(pubkey *koblitz.PublicKey, initSend in
 
 	if initSend != 0 {
 		logging.Debugf("Checking withdraw lock...")
-		server.LockIngests()
-		logging.Debugf("Locked ingests, withdrawing")
-		if err = server.OpencxDB.Withdraw(pubkey, params, uint64(initSend)); err != nil {
-			// if errors out, unlock
+		if err = server.withIngestLock(func() error {
+			logging.Debugf("Locked ingests, withdrawing")
+			return server.OpencxDB.Withdraw(pubkey, params, uint64(initSend))
+		}); err != nil {
 			logging.Errorf("Error while withdrawing from db: %s\n", err)
-			server.UnlockIngests()
 			return
 		}
-		server.UnlockIngests()
 	}
 
 	var utxoDump []*portxo.PorTxo
@@ -19,6 +19,7 @@ import (
 
 	"github.com/mit-dci/opencx/cxdb"
 	"github.com/mit-dci/opencx/logging"
+	"github.com/mit-dci/opencx/match"
 )
 
 // OpencxServer is what orchestrates the exchange. It's where you plug everything into basically.
@@ -49,6 +50,12 @@ type OpencxServer struct {
 	// This is how we're going to easily add multiple coins
 	CoinList []*coinparam.Params
 
+	// addressPolicy is whether deposit addresses are reused or fresh for every deposit
+	addressPolicy    AddressPolicy
+	addressPolicyMtx *sync.Mutex
+	// newAddress is how fresh deposit addresses are made, this is only changed by tests
+	newAddress func(coinType *coinparam.Params, pubkey *koblitz.PublicKey) (string, error)
+
 	// default Capacity is the default capacity that we send back to people.
 	// remove this when we have some sense of how much money the exchange has and/or some fancy
 	// algorithms to determine this number based on reputation or something
@@ -77,7 +84,11 @@ func InitServer(db cxdb.OpencxStore, homedir string, rpcport uint16, coinList []
 
 		CoinList:        coinList,
 		defaultCapacity: 1000000,
+
+		addressPolicy:    ReuseAddress,
+		addressPolicyMtx: new(sync.Mutex),
 	}
+	server.newAddress = server.GetAddrForCoin
 
 	return
 }
@@ -106,10 +117,8 @@ func (server *OpencxServer) GetRegistrationString() (regStr string) {
 
 // RegistrationStringVerify verifies a signature for a registration string and returns a pubkey
 func (server *OpencxServer) RegistrationStringVerify(sig []byte) (pubkey *koblitz.PublicKey, err error) {
-	// e = h(registrationstring)
-	sha3 := sha3.New256()
-	sha3.Write([]byte(server.GetRegistrationString()))
-	e := sha3.Sum(nil)
+	// e = h(domain || registrationstring)
+	e := match.RegistrationDomain.SigHash([]byte(server.GetRegistrationString()))
 
 	if pubkey, _, err = koblitz.RecoverCompact(koblitz.S256(), sig, e); err != nil {
 		err = fmt.Errorf("Error verifying registration string, invalid signature: \n%s", err)
@@ -140,14 +149,46 @@ func (server *OpencxServer) GetOrdersStringVerify(sig []byte) (pubkey *koblitz.P
 	return
 }
 
-// GetAddressMap gets an address map for a pubkey. This is so we can register multiple ways.
+// GetAddressMap gets an address map for a pubkey. This is so we can register multiple ways. If no wallets
+// are enabled then ErrNoWallets is returned, since the pubkey couldn't deposit anything.
 func (server *OpencxServer) GetAddressMap(pubkey *koblitz.PublicKey) (addrMap map[*coinparam.Params]string, err error) {
+	server.walletMtx.Lock()
+	var params []*coinparam.Params
+	for param := range server.WalletMap {
+		params = append(params, param)
+	}
+	server.walletMtx.Unlock()
+
+	if len(params) == 0 {
+		err = ErrNoWallets
+		return
+	}
+
 	// go through each enabled wallet in the server and create a new address for them.
 	addrMap = make(map[*coinparam.Params]string)
-	for param := range server.WalletMap {
-		if addrMap[param], err = server.GetAddrForCoin(param, pubkey); err != nil {
+	for _, param := range params {
+		if addrMap[param], err = server.newAddress(param, pubkey); err != nil {
 			return
 		}
 	}
 	return
 }
+
+// RegisterPubkey registers pubkey with an address for every enabled wallet. The pubkey should already
+// be verified, like with RegistrationStringVerify.
+func (server *OpencxServer) RegisterPubkey(pubkey *koblitz.PublicKey) (err error) {
+	var addrMap map[*coinparam.Params]string
+	if addrMap, err = server.GetAddressMap(pubkey); err != nil {
+		err = fmt.Errorf("Error getting addresses for registration: \n%s", err)
+		return
+	}
+
+	// Insert them into the DB while holding the ingest lock
+	if err = server.withIngestLock(func() error {
+		return server.OpencxDB.RegisterUser(pubkey, addrMap)
+	}); err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,116 @@
+package cxserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mit-dci/lit/coinparam"
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/lit/wallit"
+	"github.com/mit-dci/opencx/cxdb"
+	"github.com/mit-dci/opencx/util"
+)
+
+// registerCountStore only implements RegisterUser, and doesn't do any locking of its own. If
+// registrations aren't serialized by the ingest lock, the race detector will catch it.
+type registerCountStore struct {
+	cxdb.OpencxStore
+	registrations int
+}
+
+// RegisterUser counts the registration
+func (db *registerCountStore) RegisterUser(pubkey *koblitz.PublicKey, addressMap map[*coinparam.Params]string) (err error) {
+	db.registrations++
+	return
+}
+
+// initRegisterServer creates a server with a testnet wallet, whose addresses are just numbered
+func initRegisterServer(store cxdb.OpencxStore) (server *OpencxServer) {
+	server = InitServer(store, "", 0, []*coinparam.Params{&coinparam.TestNet3Params})
+	server.WalletMap[&coinparam.TestNet3Params] = &wallit.Wallit{Param: &coinparam.TestNet3Params}
+	server.newAddress = func(coinType *coinparam.Params, pubkey *koblitz.PublicKey) (addr string, err error) {
+		addr = fmt.Sprintf("%s-%x", coinType.Name, pubkey.SerializeCompressed())
+		return
+	}
+	return
+}
+
+func TestConcurrentRegister(t *testing.T) {
+	var err error
+
+	numRegistrations := 32
+
+	testDB := new(registerCountStore)
+	server := initRegisterServer(testDB)
+
+	var pubkeys []*koblitz.PublicKey
+	for i := 0; i < numRegistrations; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating private key for registration: %s", err)
+			return
+		}
+		pubkeys = append(pubkeys, privkey.PubKey())
+	}
+
+	errChan := make(chan error, numRegistrations)
+	for _, pubkey := range pubkeys {
+		go func(pubkey *koblitz.PublicKey) {
+			errChan <- server.RegisterPubkey(pubkey)
+		}(pubkey)
+	}
+
+	for i := 0; i < numRegistrations; i++ {
+		if err = <-errChan; err != nil {
+			t.Errorf("Error registering concurrently: %s", err)
+		}
+	}
+
+	if testDB.registrations != numRegistrations {
+		t.Errorf("Expected %d registrations but got %d", numRegistrations, testDB.registrations)
+	}
+
+	return
+}
+
+func TestRegisterWithoutWallets(t *testing.T) {
+	var err error
+
+	testDB := new(registerCountStore)
+	server := InitServer(testDB, "", 0, []*coinparam.Params{&coinparam.TestNet3Params})
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key for registration: %s", err)
+		return
+	}
+
+	if _, err = server.GetAddressMap(privkey.PubKey()); err != ErrNoWallets {
+		t.Errorf("Getting an address map without wallets should fail with %s, got %v", ErrNoWallets, err)
+		return
+	}
+
+	if err = server.RegisterPubkey(privkey.PubKey()); err == nil {
+		t.Errorf("Registering without any wallets should fail")
+		return
+	}
+
+	if testDB.registrations != 0 {
+		t.Errorf("Nothing should be registered without wallets, got %d registrations", testDB.registrations)
+		return
+	}
+
+	return
+}
+
+func TestSetupAllWalletsWithoutWallets(t *testing.T) {
+	var err error
+
+	server := InitServer(nil, "", 0, nil)
+	if err = server.SetupAllWallets(util.HostParamList{}, "wallit/", false); err != ErrNoWallets {
+		t.Errorf("Setting up wallets without any enabled should fail with %s, got %v", ErrNoWallets, err)
+		return
+	}
+
+	return
+}
@@ -56,6 +56,12 @@ func (server *OpencxServer) SetupWallet(errChan chan error, subDirName string, p
 
 // SetupAllWallets sets up all wallets with parameters as specified in the hostParamList
 func (server *OpencxServer) SetupAllWallets(hostParamList util.HostParamList, subDirName string, resync bool) (err error) {
+	// the exchange is useless without any wallets, nobody could deposit
+	if len(hostParamList) == 0 {
+		err = ErrNoWallets
+		return
+	}
+
 	hpLen := len(hostParamList)
 	errChan := make(chan error, hpLen)
 	for _, hostParam := range hostParamList {
@@ -15,6 +15,7 @@ import (
 	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/lit/portxo"
 
+	"github.com/mit-dci/lit/wallit"
 	"github.com/mit-dci/lit/wire"
 
 	"github.com/mit-dci/lit/btcutil/txscript"
@@ -77,9 +78,8 @@ func (server *OpencxServer) WithdrawLightning(pubkey *koblitz.PublicKey, amount
 func (server *OpencxServer) withdrawFromChain(params *coinparam.Params) (withdrawFunction func(string, *koblitz.PublicKey, uint64) (string, error), err error) {
 
 	// Try to get correct wallet
-	wallet, found := server.WalletMap[params]
-	if !found {
-		err = fmt.Errorf("Could not find wallet for those coin params")
+	var wallet *wallit.Wallit
+	if wallet, err = server.walletForCoin(params); err != nil {
 		return
 	}
 
@@ -90,13 +90,11 @@ func (server *OpencxServer) withdrawFromChain(params *coinparam.Params) (withdra
 			return
 		}
 
-		server.LockIngests()
-		if err = server.OpencxDB.Withdraw(pubkey, params, amount); err != nil {
-			// if errors out, unlock
-			server.UnlockIngests()
+		if err = server.withIngestLock(func() error {
+			return server.OpencxDB.Withdraw(pubkey, params, amount)
+		}); err != nil {
 			return
 		}
-		server.UnlockIngests()
 
 		// Decoding given address
 		var addr btcutil.Address
@@ -195,15 +193,13 @@ func (server *OpencxServer) withdrawFromLightning(params *coinparam.Params) (wit
 		// TODO: this should only happen when we get a proof that the other person actually took the withdraw / updated the state. We don't have a guarantee that they will always accept
 
 		logging.Infof("Checking withdraw lock...")
-		server.LockIngests()
-		logging.Infof("Locked ingests, withdrawing")
-		if err = server.OpencxDB.Withdraw(pubkey, params, uint64(amount)); err != nil {
-			// if errors out, unlock
+		if err = server.withIngestLock(func() error {
+			logging.Infof("Locked ingests, withdrawing")
+			return server.OpencxDB.Withdraw(pubkey, params, uint64(amount))
+		}); err != nil {
 			logging.Errorf("Error while withdrawing from db: %s\n", err)
-			server.UnlockIngests()
 			return
 		}
-		server.UnlockIngests()
 
 		// check if any of the channels are of the correct param and have enough capacity (-[min+fee])
 
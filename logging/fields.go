@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Fields are key value pairs attached to every log printed by a Logger
+type Fields map[string]interface{}
+
+// Logger prints logs at a level, with fields attached. Logs are only printed if the global log level
+// includes their level, just like the package level functions.
+type Logger interface {
+	// Debugf prints debug logs with a formatting directive.
+	Debugf(format string, args ...interface{})
+	// Infof prints info logs with a formatting directive.
+	Infof(format string, args ...interface{})
+	// Warnf prints warning logs with a formatting directive.
+	Warnf(format string, args ...interface{})
+	// Errorf prints error logs with a formatting directive.
+	Errorf(format string, args ...interface{})
+	// Fatalf prints a message with a formatting directive, then calls os.Exit(1).
+	Fatalf(format string, args ...interface{})
+	// WithFields returns a Logger that attaches fields as well as the fields already attached. Fields
+	// with the same key replace the ones already attached.
+	WithFields(fields Fields) Logger
+}
+
+// fieldLogger is a Logger that attaches its fields to the end of every log
+type fieldLogger struct {
+	fields Fields
+}
+
+// WithFields returns a Logger that attaches fields to every log it prints
+func WithFields(fields Fields) Logger {
+	return new(fieldLogger).WithFields(fields)
+}
+
+// WithFields returns a Logger with both the fields of fl and fields. fl isn't changed.
+func (fl *fieldLogger) WithFields(fields Fields) Logger {
+	merged := make(Fields, len(fl.fields)+len(fields))
+	for key, value := range fl.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &fieldLogger{fields: merged}
+}
+
+// String returns the fields as key=value pairs, sorted by key so logs are printed the same every time
+func (fields Fields) String() string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", key, fields[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// logf prints a log of level with the fields attached, if the global log level includes it
+func (fl *fieldLogger) logf(level LogLevel, format string, args ...interface{}) {
+	if !enabled(level) {
+		return
+	}
+	log.Print(fl.line(level.String(), format, args...))
+}
+
+// line returns the log line for a log tagged with tag, with the fields attached
+func (fl *fieldLogger) line(tag string, format string, args ...interface{}) string {
+	msg := fmt.Sprintf("%s %s", getPrefix(tag), fmt.Sprintf(format, args...))
+	if len(fl.fields) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s %s", msg, fl.fields)
+}
+
+// Debugf prints debug logs with a formatting directive.
+func (fl *fieldLogger) Debugf(format string, args ...interface{}) {
+	fl.logf(LogLevelDebug, format, args...)
+}
+
+// Infof prints info logs with a formatting directive.
+func (fl *fieldLogger) Infof(format string, args ...interface{}) {
+	fl.logf(LogLevelInfo, format, args...)
+}
+
+// Warnf prints warning logs with a formatting directive.
+func (fl *fieldLogger) Warnf(format string, args ...interface{}) {
+	fl.logf(LogLevelWarning, format, args...)
+}
+
+// Errorf prints error logs with a formatting directive.
+func (fl *fieldLogger) Errorf(format string, args ...interface{}) {
+	fl.logf(LogLevelError, format, args...)
+}
+
+// Fatalf prints a message with a formatting directive, then calls os.Exit(1).
+func (fl *fieldLogger) Fatalf(format string, args ...interface{}) {
+	log.Fatal(fl.line("FATAL", format, args...))
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureLogs sends logs to a buffer at level until the returned function is called
+func captureLogs(level LogLevel) (buf *bytes.Buffer, restore func()) {
+	buf = new(bytes.Buffer)
+	oldLevel := Level()
+	oldFlags := log.Flags()
+	SetLevel(level)
+	log.SetFlags(0)
+	log.SetOutput(buf)
+	restore = func() {
+		SetLevel(oldLevel)
+		log.SetFlags(oldFlags)
+		log.SetOutput(os.Stderr)
+	}
+	return
+}
+
+func TestLevelFiltering(t *testing.T) {
+	buf, restore := captureLogs(LogLevelWarning)
+	defer restore()
+
+	Debugf("debug %d", 1)
+	Infof("info %d", 2)
+	Warnf("warn %d", 3)
+	Errorf("error %d", 4)
+
+	logger := WithFields(Fields{"pair": "btc_vtc"})
+	logger.Debugf("debug %d", 5)
+	logger.Infof("info %d", 6)
+	logger.Warnf("warn %d", 7)
+	logger.Errorf("error %d", 8)
+
+	expected := "[WARN] warn 3\n[ERROR] error 4\n[WARN] warn 7 pair=btc_vtc\n[ERROR] error 8 pair=btc_vtc\n"
+	if buf.String() != expected {
+		t.Errorf("Only warnings and errors should be printed at the warning level, got:\n%s", buf.String())
+		return
+	}
+
+	buf.Reset()
+	SetLevel(LogLevelDebug)
+	logger.Debugf("debug %d", 9)
+	Debugf("debug %d", 10)
+	if buf.String() != "[DEBUG] debug 9 pair=btc_vtc\n[DEBUG] debug 10\n" {
+		t.Errorf("Debug logs should be printed at the debug level, got:\n%s", buf.String())
+		return
+	}
+
+	return
+}
+
+func TestWithFields(t *testing.T) {
+	buf, restore := captureLogs(LogLevelInfo)
+	defer restore()
+
+	parent := WithFields(Fields{"pair": "btc_vtc", "height": 5})
+	child := parent.WithFields(Fields{"height": 6, "auction": "ab"})
+
+	parent.Infof("parent")
+	child.Infof("child")
+	WithFields(nil).Infof("no fields")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Errorf("Expected 3 log lines, got %d:\n%s", len(lines), buf.String())
+		return
+	}
+
+	// Fields are sorted by key, and attaching fields to the child doesn't change the parent
+	if lines[0] != "[INFO] parent height=5 pair=btc_vtc" {
+		t.Errorf("Parent logger printed the wrong fields: %s", lines[0])
+		return
+	}
+
+	if lines[1] != "[INFO] child auction=ab height=6 pair=btc_vtc" {
+		t.Errorf("Child logger should have both sets of fields, with its own replacing the parent's: %s", lines[1])
+		return
+	}
+
+	if lines[2] != "[INFO] no fields" {
+		t.Errorf("Logger without fields should print just the message: %s", lines[2])
+		return
+	}
+
+	return
+}
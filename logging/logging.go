@@ -19,6 +19,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"sync"
 )
 
 // LogLevel indicates what to log based on the method called
@@ -35,11 +36,49 @@ const (
 	LogLevelDebug LogLevel = 3
 )
 
-var logLevel = LogLevelError // the default
+var (
+	logLevel    = LogLevelError // the default
+	logLevelMtx = new(sync.RWMutex)
+)
+
+// String returns the tag logs of this level are printed with
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelError:
+		return "ERROR"
+	case LogLevelWarning:
+		return "WARN"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelDebug:
+		return "DEBUG"
+	}
+	return fmt.Sprintf("LEVEL%d", int(l))
+}
 
 // SetLogLevel sets the global log level
 func SetLogLevel(newLevel int) {
-	logLevel = LogLevel(newLevel)
+	SetLevel(LogLevel(newLevel))
+}
+
+// SetLevel sets the global log level
+func SetLevel(newLevel LogLevel) {
+	logLevelMtx.Lock()
+	logLevel = newLevel
+	logLevelMtx.Unlock()
+}
+
+// Level returns the global log level
+func Level() (level LogLevel) {
+	logLevelMtx.RLock()
+	level = logLevel
+	logLevelMtx.RUnlock()
+	return
+}
+
+// enabled returns whether or not logs of level are printed at the global log level
+func enabled(level LogLevel) bool {
+	return Level() >= level
 }
 
 // SetLogFile sets a file to write to in addition to standard output.
@@ -70,35 +109,35 @@ func Fatal(args ...interface{}) {
 
 // Debugf prints debug logs with a formatting directive.
 func Debugf(format string, args ...interface{}) {
-	if logLevel >= LogLevelDebug {
+	if enabled(LogLevelDebug) {
 		log.Printf(fmt.Sprintf("%s %s", getPrefix("DEBUG"), format), args...)
 	}
 }
 
 // Infof prints info logs with a formatting directive.
 func Infof(format string, args ...interface{}) {
-	if logLevel >= LogLevelInfo {
+	if enabled(LogLevelInfo) {
 		log.Printf(fmt.Sprintf("%s %s", getPrefix("INFO"), format), args...)
 	}
 }
 
 // Warnf prints warning logs with a formatting directive.
 func Warnf(format string, args ...interface{}) {
-	if logLevel >= LogLevelWarning {
+	if enabled(LogLevelWarning) {
 		log.Printf(fmt.Sprintf("%s %s", getPrefix("WARN"), format), args...)
 	}
 }
 
 // Errorf prints error logs with a formatting directive.
 func Errorf(format string, args ...interface{}) {
-	if logLevel >= LogLevelError {
+	if enabled(LogLevelError) {
 		log.Printf(fmt.Sprintf("%s %s", getPrefix("ERROR"), format), args...)
 	}
 }
 
 // Debugln prints debug logs, followed by a new line.
 func Debugln(args ...interface{}) {
-	if logLevel >= LogLevelDebug {
+	if enabled(LogLevelDebug) {
 		args = append([]interface{}{getPrefix("DEBUG")}, args...)
 		log.Println(args...)
 	}
@@ -106,7 +145,7 @@ func Debugln(args ...interface{}) {
 
 // Infoln prints info logs, followed by a new line.
 func Infoln(args ...interface{}) {
-	if logLevel >= LogLevelInfo {
+	if enabled(LogLevelInfo) {
 		args = append([]interface{}{getPrefix("INFO")}, args...)
 		log.Println(args...)
 	}
@@ -114,7 +153,7 @@ func Infoln(args ...interface{}) {
 
 // Warnln prints warning logs, followed by a new line.
 func Warnln(args ...interface{}) {
-	if logLevel >= LogLevelWarning {
+	if enabled(LogLevelWarning) {
 		args = append([]interface{}{getPrefix("WARN")}, args...)
 		log.Println(args...)
 	}
@@ -122,7 +161,7 @@ func Warnln(args ...interface{}) {
 
 // Errorln prints error logs, followed by a new line.
 func Errorln(args ...interface{}) {
-	if logLevel >= LogLevelError {
+	if enabled(LogLevelError) {
 		args = append([]interface{}{getPrefix("ERROR")}, args...)
 		log.Println(args...)
 	}
@@ -130,7 +169,7 @@ func Errorln(args ...interface{}) {
 
 // Debug prints debug logs.
 func Debug(args ...interface{}) {
-	if logLevel >= LogLevelDebug {
+	if enabled(LogLevelDebug) {
 		args = append([]interface{}{getPrefix("DEBUG")}, args...)
 		log.Print(args...)
 	}
@@ -138,7 +177,7 @@ func Debug(args ...interface{}) {
 
 // Info prints info logs.
 func Info(args ...interface{}) {
-	if logLevel >= LogLevelInfo {
+	if enabled(LogLevelInfo) {
 		args = append([]interface{}{getPrefix("INFO")}, args...)
 		log.Print(args...)
 	}
@@ -146,7 +185,7 @@ func Info(args ...interface{}) {
 
 // Warn prints warning logs.
 func Warn(args ...interface{}) {
-	if logLevel >= LogLevelWarning {
+	if enabled(LogLevelWarning) {
 		args = append([]interface{}{getPrefix("WARN")}, args...)
 		log.Print(args...)
 	}
@@ -154,7 +193,7 @@ func Warn(args ...interface{}) {
 
 // Error prints error logs.
 func Error(args ...interface{}) {
-	if logLevel >= LogLevelError {
+	if enabled(LogLevelError) {
 		args = append([]interface{}{getPrefix("ERROR")}, args...)
 		log.Print(args...)
 	}
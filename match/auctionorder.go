@@ -2,67 +2,362 @@ package match
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
 
+	"github.com/mit-dci/lit/crypto/koblitz"
 	"github.com/mit-dci/opencx/crypto"
 	"github.com/mit-dci/opencx/crypto/hashtimelock"
 	"github.com/mit-dci/opencx/crypto/rsw"
 	"github.com/mit-dci/opencx/crypto/timelockencoders"
+	"github.com/mit-dci/opencx/crypto/vdf"
 )
 
+// CipherType is the cipher that the order ciphertext in an encrypted order is encrypted with. The key
+// for the cipher is the solution to the order's puzzle.
+type CipherType byte
+
+const (
+	// CipherRC5 is RC5. This is zero so encrypted orders from before the cipher type was serialized
+	// are still solved as RC5.
+	CipherRC5 CipherType = 0x00
+	// CipherRC6 is RC6
+	CipherRC6 CipherType = 0x01
+	// CipherAES is AES
+	CipherAES CipherType = 0x02
+)
+
+// String returns the name of the cipher
+func (c CipherType) String() string {
+	switch c {
+	case CipherRC5:
+		return "RC5"
+	case CipherRC6:
+		return "RC6"
+	case CipherAES:
+		return "AES"
+	}
+	return fmt.Sprintf("unknown(%d)", byte(c))
+}
+
+// KeySizes returns the key sizes, in bytes, that the cipher accepts
+func (c CipherType) KeySizes() (sizes []int, err error) {
+	switch c {
+	case CipherRC5:
+		sizes = timelockencoders.RC5KeySizes
+	case CipherRC6:
+		sizes = timelockencoders.RC6KeySizes
+	case CipherAES:
+		sizes = timelockencoders.AESKeySizes
+	default:
+		err = fmt.Errorf("Unknown cipher type %s", c)
+	}
+	return
+}
+
+// BlockSize returns the block size of the cipher, in bytes
+func (c CipherType) BlockSize() (size int, err error) {
+	switch c {
+	case CipherRC5:
+		size = timelockencoders.RC5BlockSize
+	case CipherRC6:
+		size = timelockencoders.RC6BlockSize
+	case CipherAES:
+		size = timelockencoders.AESBlockSize
+	default:
+		err = fmt.Errorf("Unknown cipher type %s", c)
+	}
+	return
+}
+
 // EncryptedAuctionOrder represents an encrypted Auction Order, so a ciphertext and a puzzle whos solution is a key, and an intended auction.
 type EncryptedAuctionOrder struct {
 	OrderCiphertext []byte
 	OrderPuzzle     crypto.Puzzle
 	IntendedAuction [32]byte
+	// CipherType is what OrderCiphertext is encrypted with, so the solver knows how to decrypt it
+	CipherType CipherType
+}
+
+// VerifyPuzzle checks, without solving the puzzle, that the encrypted order could actually be
+// decrypted. The ciphertext has to have room for an IV and at least one block, and if the puzzle can
+// tell how big its answer will be, then that has to be a valid key size for the cipher.
+func (e *EncryptedAuctionOrder) VerifyPuzzle() (err error) {
+	if e.OrderPuzzle == nil {
+		err = fmt.Errorf("Encrypted order has no puzzle")
+		return
+	}
+
+	var keySizes []int
+	if keySizes, err = e.CipherType.KeySizes(); err != nil {
+		err = fmt.Errorf("Error getting key sizes for encrypted order: %s", err)
+		return
+	}
+
+	var blockSize int
+	if blockSize, err = e.CipherType.BlockSize(); err != nil {
+		err = fmt.Errorf("Error getting block size for encrypted order: %s", err)
+		return
+	}
+
+	if len(e.OrderCiphertext) < 2*blockSize {
+		err = fmt.Errorf("Ciphertext of %d bytes is too short for a %s encrypted order, it must be at least %d bytes", len(e.OrderCiphertext), e.CipherType, 2*blockSize)
+		return
+	}
+
+	// If the puzzle can't tell how big its answer is then we'll only find out when we solve it
+	sizer, ok := e.OrderPuzzle.(crypto.AnswerSizer)
+	if !ok {
+		return
+	}
+
+	var minSize, maxSize int
+	if minSize, maxSize, err = sizer.AnswerSizeRange(); err != nil {
+		err = fmt.Errorf("Error getting answer size of order puzzle: %s", err)
+		return
+	}
+
+	for _, keySize := range keySizes {
+		if minSize <= keySize && keySize <= maxSize {
+			return
+		}
+	}
+
+	if minSize == maxSize {
+		err = fmt.Errorf("Puzzle answer is %d bytes, which is not a valid %s key size %v", minSize, e.CipherType, keySizes)
+		return
+	}
+
+	err = fmt.Errorf("Puzzle answer is between %d and %d bytes, which can't be a valid %s key size %v", minSize, maxSize, e.CipherType, keySizes)
+	return
+}
+
+// Solve solves the order puzzle and decrypts the ciphertext with the cipher from the cipher type,
+// returning the serialized auction order. The puzzle is checked against the cipher before it's solved.
+func (e *EncryptedAuctionOrder) Solve() (orderBytes []byte, err error) {
+	_, orderBytes, err = e.SolveWithKey()
+	return
+}
+
+// SolveWithKey solves the puzzle and decrypts the order like Solve, but also returns the key the order was
+// decrypted with. The key can be revealed once the auction is over, so anyone can decrypt the order with
+// DecryptWithKey instead of solving the puzzle.
+func (e *EncryptedAuctionOrder) SolveWithKey() (key []byte, orderBytes []byte, err error) {
+	key, _, orderBytes, err = e.SolveWithProof()
+	return
+}
+
+// SolveWithProof solves the puzzle and decrypts the order like SolveWithKey, and if the puzzle is a VDF
+// puzzle also returns the solution with its proof, so anyone can check the puzzle was solved with
+// vdf.PuzzleVDF.Verify. The solution is nil for other puzzles.
+func (e *EncryptedAuctionOrder) SolveWithProof() (key []byte, solution *vdf.Solution, orderBytes []byte, err error) {
+	if err = e.VerifyPuzzle(); err != nil {
+		err = fmt.Errorf("Invalid encrypted order, not solving: %s", err)
+		return
+	}
+
+	if vdfPuzzle, ok := e.OrderPuzzle.(*vdf.PuzzleVDF); ok {
+		key, solution, err = vdfPuzzle.SolveWithProof()
+	} else {
+		key, err = e.OrderPuzzle.Solve()
+	}
+	if err != nil {
+		err = fmt.Errorf("Error solving %s puzzle for auction order: %s", e.CipherType, err)
+		return
+	}
+
+	orderBytes, err = e.DecryptWithKey(key)
+	return
+}
+
+// DecryptWithKey decrypts the ciphertext with a key that's already known, like a key revealed after the
+// puzzle was solved, returning the serialized auction order. The puzzle isn't solved.
+func (e *EncryptedAuctionOrder) DecryptWithKey(key []byte) (orderBytes []byte, err error) {
+	switch e.CipherType {
+	case CipherRC5:
+		orderBytes, err = timelockencoders.DecryptRC5(e.OrderCiphertext, key)
+	case CipherRC6:
+		orderBytes, err = timelockencoders.DecryptRC6(e.OrderCiphertext, key)
+	case CipherAES:
+		orderBytes, err = timelockencoders.DecryptAES(e.OrderCiphertext, key)
+	default:
+		err = fmt.Errorf("Unknown cipher type %s", e.CipherType)
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Error decrypting %s auction order with key: %s", e.CipherType, err)
+		return
+	}
+
+	return
 }
 
 // SolveRC5AuctionOrderAsync solves order puzzles and creates auction orders from them. This should be run in a goroutine.
 func SolveRC5AuctionOrderAsync(e *EncryptedAuctionOrder, puzzleResChan chan *OrderPuzzleResult) {
+	SolveRC5AuctionOrderWithContext(context.Background(), e, puzzleResChan)
+	return
+}
+
+// SolveRC5AuctionOrderWithContext solves order puzzles and creates auction orders from them like
+// SolveRC5AuctionOrderAsync, but stops squaring soon after ctx is done, like when the auction has closed or
+// the caller is shutting down. A result is still sent when it's cancelled, with the PuzzleCancelled reason.
+func SolveRC5AuctionOrderWithContext(ctx context.Context, e *EncryptedAuctionOrder, puzzleResChan chan *OrderPuzzleResult) {
+	puzzleResChan <- solveRC5AuctionOrder(ctx, e)
+	return
+}
+
+// SolveRC5AuctionOrderWithProgress solves order puzzles and creates auction orders from them like
+// SolveRC5AuctionOrderWithContext, calling progress with how much of the puzzle is solved, from 0 to 1,
+// every `every` squarings. This can be used to show how long is left.
+func SolveRC5AuctionOrderWithProgress(ctx context.Context, e *EncryptedAuctionOrder, every uint64, progress func(fraction float64), puzzleResChan chan *OrderPuzzleResult) {
+	puzzleResChan <- solveRC5AuctionOrderWithProgress(ctx, e, every, progress)
+	return
+}
+
+// SolveAuctionOrderBatch solves a whole batch of order puzzles with a fixed number of workers, so a big batch
+// doesn't start a goroutine for every order. If workers isn't positive, GOMAXPROCS workers are used. results[i]
+// is the result of solving orders[i].
+func SolveAuctionOrderBatch(orders []*EncryptedAuctionOrder, workers int) (results []*OrderPuzzleResult) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(orders) {
+		workers = len(orders)
+	}
+
+	results = make([]*OrderPuzzleResult, len(orders))
+
+	// Workers pull order indexes off the channel, and each index is only written to by one worker
+	indexChan := make(chan int, len(orders))
+	for i := range orders {
+		indexChan <- i
+	}
+	close(indexChan)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexChan {
+				results[i] = solveRC5AuctionOrder(context.Background(), orders[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return
+}
+
+// DefaultProgressInterval is how many squarings are done between progress updates when solving orders
+const DefaultProgressInterval = 1 << 14
+
+// solveRC5AuctionOrder solves an order puzzle and creates the auction order from it, stopping if ctx is done
+func solveRC5AuctionOrder(ctx context.Context, e *EncryptedAuctionOrder) (result *OrderPuzzleResult) {
+	return solveRC5AuctionOrderWithProgress(ctx, e, DefaultProgressInterval, nil)
+}
+
+// solveRC5AuctionOrderWithProgress solves an order puzzle and creates the auction order from it, stopping
+// if ctx is done. The result's progress is kept up to date, and progress is called too if it isn't nil.
+func solveRC5AuctionOrderWithProgress(ctx context.Context, e *EncryptedAuctionOrder, every uint64, progress func(fraction float64)) (result *OrderPuzzleResult) {
 	var err error
-	result := new(OrderPuzzleResult)
+	result = new(OrderPuzzleResult)
 	result.Encrypted = e
 
+	if e == nil {
+		result.Err = NewPuzzleResultError(PuzzleDecryptFailed, "Cannot solve nil encrypted order")
+		return
+	}
+
+	if e.CipherType != CipherRC5 {
+		result.Err = NewPuzzleResultError(PuzzleDecryptFailed, "Cannot solve %s encrypted order as RC5", e.CipherType)
+		return
+	}
+
+	if err = e.VerifyPuzzle(); err != nil {
+		result.Err = NewPuzzleResultError(PuzzleDecryptFailed, "Invalid encrypted order, not solving: %s", err)
+		return
+	}
+
 	var orderBytes []byte
-	if orderBytes, err = timelockencoders.SolvePuzzleRC5(e.OrderCiphertext, e.OrderPuzzle); err != nil {
-		result.Err = fmt.Errorf("Error solving RC5 puzzle for auction order: %s", err)
-		puzzleResChan <- result
+	if orderBytes, err = timelockencoders.SolvePuzzleRC5WithProgress(ctx, e.OrderCiphertext, e.OrderPuzzle, every, func(done uint64, total uint64) {
+		result.Progress = float64(done) / float64(total)
+		if progress != nil {
+			progress(result.Progress)
+		}
+	}); err != nil {
+		reason := PuzzleDecryptFailed
+		if ctx.Err() != nil {
+			reason = PuzzleCancelled
+		}
+		result.Err = NewPuzzleResultError(reason, "Error solving auction order: %s", err)
 		return
 	}
 
+	// puzzles with no squarings to do never report progress
+	result.Progress = 1
+
 	result.Auction = new(AuctionOrder)
 	if err = result.Auction.Deserialize(orderBytes); err != nil {
-		result.Err = fmt.Errorf("Error deserializing order gotten from puzzle: %s", err)
-		puzzleResChan <- result
+		result.Err = NewPuzzleResultError(PuzzleDeserializeFailed, "Error deserializing order gotten from puzzle: %s", err)
 		return
 	}
 
-	puzzleResChan <- result
-
 	return
 }
 
-// Serialize serializes the encrypted order using gob
-func (e *EncryptedAuctionOrder) Serialize() (raw []byte, err error) {
-	var b bytes.Buffer
+var (
+	// registerGobOnce makes sure the puzzle and order types are only registered with gob once
+	registerGobOnce sync.Once
+	// gobBufferPool holds buffers that get reused when encoding encrypted orders
+	gobBufferPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+)
 
-	// register the rsw puzzle and hashtimelock puzzle
-	gob.Register(new(rsw.PuzzleRSW))
+// registerGobTypes registers everything we need for gob to encode and decode encrypted orders.
+// Registration only needs to happen once, and is relatively expensive, so it's done the first time
+// this is called.
+func registerGobTypes() {
+	registerGobOnce.Do(func() {
+		// register the rsw puzzle and hashtimelock puzzle
+		gob.Register(new(rsw.PuzzleRSW))
 
-	// register the hashtimelock (puzzle and timelock are same thing)
-	gob.Register(new(hashtimelock.HashTimelock))
+		// register the hashtimelock (puzzle and timelock are same thing)
+		gob.Register(new(hashtimelock.HashTimelock))
 
-	// register the puzzle interface
-	gob.RegisterName("puzzle", new(crypto.Puzzle))
+		// register the vdf puzzle, whose solutions can be verified without solving it again
+		gob.Register(new(vdf.PuzzleVDF))
 
-	// register the encrypted auction order interface with gob
-	gob.RegisterName("order", new(EncryptedAuctionOrder))
+		// register the puzzle interface
+		gob.RegisterName("puzzle", new(crypto.Puzzle))
+
+		// register the encrypted auction order interface with gob
+		gob.RegisterName("order", new(EncryptedAuctionOrder))
+	})
+}
+
+// Serialize serializes the encrypted order using gob
+func (e *EncryptedAuctionOrder) Serialize() (raw []byte, err error) {
+	registerGobTypes()
+
+	// get a buffer from the pool, and make sure it goes back when we're done
+	b := gobBufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	defer gobBufferPool.Put(b)
 
 	// create a new encoder writing to our buffer
-	enc := gob.NewEncoder(&b)
+	enc := gob.NewEncoder(b)
 
 	// encode the encrypted auction order in the buffer
 	if err = enc.Encode(e); err != nil {
@@ -70,47 +365,58 @@ func (e *EncryptedAuctionOrder) Serialize() (raw []byte, err error) {
 		return
 	}
 
-	// Get the bytes finally
-	raw = b.Bytes()
+	// Copy the bytes out, since the buffer is going to be reused
+	raw = make([]byte, b.Len())
+	copy(raw, b.Bytes())
 
 	return
 }
 
 // Deserialize deserializes the raw bytes into the encrypted auction order receiver
 func (e *EncryptedAuctionOrder) Deserialize(raw []byte) (err error) {
-	var b *bytes.Buffer
-	b = bytes.NewBuffer(raw)
-
-	// register the rsw puzzle and hashtimelock puzzle
-	gob.Register(new(rsw.PuzzleRSW))
-
-	// register the hashtimelock (puzzle and timelock are same thing)
-	gob.Register(new(hashtimelock.HashTimelock))
+	registerGobTypes()
 
-	// register the puzzle interface
-	gob.RegisterName("puzzle", new(crypto.Puzzle))
-
-	// register the encrypted auction order interface with gob
-	gob.RegisterName("order", new(EncryptedAuctionOrder))
-
-	// create a new decoder writing to the buffer
-	dec := gob.NewDecoder(b)
+	// create a new decoder reading from the raw bytes
+	dec := gob.NewDecoder(bytes.NewReader(raw))
 
 	// decode the encrypted auction order in the buffer
 	if err = dec.Decode(e); err != nil {
-		err = fmt.Errorf("Error decoding encrypted auction order: %s", err)
+		err = newDeserializeError(ErrBadEncoding, "error decoding encrypted auction order: %s", err)
+		return
+	}
+
+	return
+}
+
+// OrderID returns the sha256 of the serialized encrypted order. Nobody can know the ID of the order inside
+// until the puzzle is solved, so this is how an encrypted order is identified until then.
+func (e *EncryptedAuctionOrder) OrderID() (id [32]byte, err error) {
+	var raw []byte
+	if raw, err = e.Serialize(); err != nil {
+		err = fmt.Errorf("Error serializing encrypted order for order ID: %s", err)
 		return
 	}
 
+	id = sha256.Sum256(raw)
 	return
 }
 
 // OrderPuzzleResult is a struct that is used as the type for a channel so we can atomically
-// receive the original encrypted order, decrypted order, and an error
+// receive the original encrypted order, decrypted order, and an error. Err is a PuzzleResultError
+// when the reason it failed is known.
 type OrderPuzzleResult struct {
 	Encrypted *EncryptedAuctionOrder
 	Auction   *AuctionOrder
 	Err       error
+	// Progress is how much of the puzzle was solved, from 0 to 1, when the result was made. It's 1 for
+	// orders that were solved, and can be less for ones that were cancelled.
+	Progress float64
+	// Key is the key the order was decrypted with, if it was solved. It's kept so it can be revealed once the
+	// auction is over.
+	Key []byte
+	// Solution is the output of the VDF and its proof if the order's puzzle is a VDF puzzle, and nil otherwise.
+	// It's revealed along with the key, so anyone can check the puzzle was solved.
+	Solution *vdf.Solution
 }
 
 // AuctionOrder represents a batch order
@@ -122,15 +428,19 @@ type AuctionOrder struct {
 	AmountHave uint64 `json:"amounthave"`
 	// amount of assetWant the user wants for their assetHave
 	AmountWant uint64 `json:"amountwant"`
-	// only exists for returning orders back
+	// OrderbookPrice is the price the order executes at, or would execute at if it's still in the book.
+	// This only exists for returning orders back, it isn't serialized or signed.
 	OrderbookPrice float64 `json:"orderbookprice"`
 	// IntendedAuction as the auctionID this should be in. We need this to protect against
 	// the exchange withholding an order.
 	AuctionID [32]byte `json:"auctionid"`
 	// 2 byte nonce (So there can be max 2^16 of the same-looking orders by the same pubkey in the same batch)
 	// This is used to protect against the exchange trying to replay a bunch of orders
-	Nonce     [2]byte `json:"nonce"`
-	Signature []byte  `json:"signature"`
+	Nonce [2]byte `json:"nonce"`
+	// Network is the network magic of the exchange this order is meant for. This is signed so the order
+	// can't be replayed on an exchange running on a different network.
+	Network   NetworkMagic `json:"network"`
+	Signature []byte       `json:"signature"`
 }
 
 // TurnIntoEncryptedOrder creates a puzzle for this auction order given the time. We make no assumptions about whether or not the order is signed.
@@ -142,6 +452,49 @@ func (a *AuctionOrder) TurnIntoEncryptedOrder(t uint64) (encrypted *EncryptedAuc
 	}
 	// make sure they match
 	encrypted.IntendedAuction = a.AuctionID
+	encrypted.CipherType = CipherRC5
+	return
+}
+
+// Clone returns a deep copy of the order, so the copy can be changed without changing the original
+func (a *AuctionOrder) Clone() (clone *AuctionOrder) {
+	clone = new(AuctionOrder)
+	*clone = *a
+	if a.Signature != nil {
+		clone.Signature = make([]byte, len(a.Signature))
+		copy(clone.Signature, a.Signature)
+	}
+	return
+}
+
+// Hash returns the sha3 hash of the signable part of the order, which identifies it. The signature isn't
+// included, so the same order signed differently has the same hash.
+func (a *AuctionOrder) Hash() (hash [32]byte) {
+	copy(hash[:], sha3Sum(a.SerializeSignable()))
+	return
+}
+
+// OrderID returns the sha256 of the signable part of the order followed by the signature. This identifies a
+// signed order, so it can be used as a map key, in logs, or to notice the same order being submitted twice.
+// Unsigned orders don't have an ID.
+func (a *AuctionOrder) OrderID() (id [32]byte, err error) {
+	if len(a.Signature) == 0 {
+		err = fmt.Errorf("Order is not signed, so it has no order ID")
+		return
+	}
+
+	hasher := sha256.New()
+	hasher.Write(a.SerializeSignable())
+	hasher.Write(a.Signature)
+	copy(id[:], hasher.Sum(nil))
+	return
+}
+
+// TieBreakHash returns the sha3 hash of the whole serialized order, signature included. Whoever submits the
+// order can't know it before signing, but anyone with the order can compute it, so it's used to break ties
+// between orders when there's no random beacon.
+func (a *AuctionOrder) TieBreakHash() (hash [32]byte) {
+	copy(hash[:], sha3Sum(a.Serialize()))
 	return
 }
 
@@ -165,6 +518,67 @@ func (a *AuctionOrder) OppositeSide() (sideStr string) {
 	return
 }
 
+// Validate makes sure the order is well formed, so it can be matched. It has to be buy or sell side, ask for
+// and offer a nonzero amount, have a pubkey that is a valid point, and be on a pair of known assets. This
+// doesn't verify the signature.
+func (a *AuctionOrder) Validate() (err error) {
+	if !a.IsBuySide() && !a.IsSellSide() {
+		err = fmt.Errorf("Order side must be buy or sell, not %q", a.Side)
+		return
+	}
+
+	if a.AmountHave == 0 {
+		err = fmt.Errorf("Order must offer a nonzero amount")
+		return
+	}
+
+	if a.AmountWant == 0 {
+		err = fmt.Errorf("Order must ask for a nonzero amount")
+		return
+	}
+
+	if _, err = a.ParsePubkey(); err != nil {
+		return
+	}
+
+	if err = a.TradingPair.Validate(); err != nil {
+		err = fmt.Errorf("Order is not on a valid pair: %s", err)
+		return
+	}
+
+	return
+}
+
+// CheckAmountRatio makes sure neither amount in the order is more than maxRatio times the other. An order with
+// an extreme ratio has an absurd price, which is probably a mistake or an attack. A maxRatio of 0 means there
+// is no limit.
+func (a *AuctionOrder) CheckAmountRatio(maxRatio uint64) (err error) {
+	if maxRatio == 0 {
+		return
+	}
+
+	if a.AmountHave == 0 || a.AmountWant == 0 {
+		err = fmt.Errorf("Order with a zero amount has no amount ratio")
+		return
+	}
+
+	// compare exactly, amounts times the max ratio can overflow
+	max := new(big.Int).SetUint64(maxRatio)
+	have := new(big.Int).SetUint64(a.AmountHave)
+	want := new(big.Int).SetUint64(a.AmountWant)
+	if have.Cmp(new(big.Int).Mul(want, max)) > 0 {
+		err = fmt.Errorf("Order offers %d for %d, more than the max ratio of %d", a.AmountHave, a.AmountWant, maxRatio)
+		return
+	}
+
+	if want.Cmp(new(big.Int).Mul(have, max)) > 0 {
+		err = fmt.Errorf("Order asks for %d for %d, more than the max ratio of %d", a.AmountWant, a.AmountHave, maxRatio)
+		return
+	}
+
+	return
+}
+
 // Price gets a float price for the order. This determines how it will get matched. The exchange should figure out if it can take some of the
 func (a *AuctionOrder) Price() (price float64, err error) {
 	if a.AmountWant == 0 {
@@ -176,15 +590,110 @@ func (a *AuctionOrder) Price() (price float64, err error) {
 		return
 	} else if a.IsSellSide() {
 		price = float64(a.AmountHave) / float64(a.AmountWant)
+		return
 	}
 	err = fmt.Errorf("Order is not buy or sell, cannot calculate price")
 	return
 }
 
+// PriceRational gets the exact price of the order, with the same meaning as Price. Price loses precision
+// for large amounts, so this is what orders should be compared with, that way every node orders them
+// the same way.
+func (a *AuctionOrder) PriceRational() (price *big.Rat, err error) {
+	if a.AmountWant == 0 {
+		err = fmt.Errorf("The amount requested in the order is 0, so no price can be calculated. Consider it a donation")
+		return
+	}
+	if a.IsBuySide() {
+		if a.AmountHave == 0 {
+			err = fmt.Errorf("The amount offered in the buy order is 0, so no price can be calculated")
+			return
+		}
+		price = new(big.Rat).SetFrac(new(big.Int).SetUint64(a.AmountWant), new(big.Int).SetUint64(a.AmountHave))
+		return
+	} else if a.IsSellSide() {
+		price = new(big.Rat).SetFrac(new(big.Int).SetUint64(a.AmountHave), new(big.Int).SetUint64(a.AmountWant))
+		return
+	}
+	err = fmt.Errorf("Order is not buy or sell, cannot calculate price")
+	return
+}
+
+// ComparePrices compares the exact prices of two orders, returning -1 if a's price is lower than b's,
+// 0 if they're the same, and 1 if a's price is higher
+func ComparePrices(a *AuctionOrder, b *AuctionOrder) (cmp int, err error) {
+	var aPrice, bPrice *big.Rat
+	if aPrice, err = a.PriceRational(); err != nil {
+		return
+	}
+
+	if bPrice, err = b.PriceRational(); err != nil {
+		return
+	}
+
+	cmp = aPrice.Cmp(bPrice)
+	return
+}
+
+// ratFromPrice returns the exact value of a float price, like a clearing price, so it can be compared
+// with the exact price of an order
+func ratFromPrice(price float64) (rat *big.Rat, err error) {
+	if rat = new(big.Rat).SetFloat64(price); rat == nil {
+		err = fmt.Errorf("Price %f is not a finite number", price)
+		return
+	}
+	return
+}
+
+// InvertedPrice returns the price of the order quoted in the inverse of its pair, which is the
+// reciprocal of Price. In the inverse pair a buy order is a sell order, and a sell order is a buy order.
+func (a *AuctionOrder) InvertedPrice() (price float64, err error) {
+	if a.AmountHave == 0 {
+		err = fmt.Errorf("The amount offered in the order is 0, so no inverted price can be calculated")
+		return
+	}
+
+	if price, err = a.Price(); err != nil {
+		return
+	}
+
+	price = 1 / price
+	return
+}
+
+// SetOrderbookPrice sets OrderbookPrice to the price the order executes at, or would execute at.
+// Until the auction is cleared, that's the order's own price. If the store has already set the
+// price then it's left alone.
+func (a *AuctionOrder) SetOrderbookPrice() (err error) {
+	if a.OrderbookPrice != 0 {
+		return
+	}
+
+	if a.OrderbookPrice, err = a.Price(); err != nil {
+		err = fmt.Errorf("Error setting orderbook price: %s", err)
+		return
+	}
+
+	return
+}
+
+// These are the versions of the auction order serialization. The version is the first byte, so a change to
+// the fields, like adding an expiry, can't be misread as the old layout.
+const (
+	// AuctionOrderVersion0 is the layout of every field up to and including the signature
+	AuctionOrderVersion0 byte = 0
+	// AuctionOrderVersion1 is reserved for the next layout
+	AuctionOrderVersion1 byte = 1
+)
+
+// AuctionOrderVersion is the version that auction orders are serialized with
+const AuctionOrderVersion = AuctionOrderVersion0
+
 // Serialize serializes an order, possible replay attacks here since this is what you're signing?
-// but anyways this is the order: [33 byte pubkey] pair amountHave amountWant <length side> side [32 byte auctionid]
+// but anyways this is the order: version [33 byte pubkey] pair amountHave amountWant <length side> side [32 byte auctionid]
 func (a *AuctionOrder) Serialize() (buf []byte) {
 	// serializable fields:
+	// version [1 byte]
 	// public key (compressed) [33 bytes]
 	// trading pair [2 bytes]
 	// amounthave [8 bytes]
@@ -193,8 +702,10 @@ func (a *AuctionOrder) Serialize() (buf []byte) {
 	// side [len side]
 	// auctionID [32 bytes]
 	// nonce [2 bytes]
+	// network [4 bytes]
 	// len sig [8 bytes]
 	// sig [len sig bytes]
+	buf = append(buf, AuctionOrderVersion)
 	buf = append(buf, a.Pubkey[:]...)
 	buf = append(buf, a.TradingPair.Serialize()...)
 
@@ -214,6 +725,10 @@ func (a *AuctionOrder) Serialize() (buf []byte) {
 	buf = append(buf, a.AuctionID[:]...)
 	buf = append(buf, a.Nonce[:]...)
 
+	networkBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(networkBytes, uint32(a.Network))
+	buf = append(buf, networkBytes[:]...)
+
 	lenSigBytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(lenSigBytes, uint64(len(a.Signature)))
 	buf = append(buf, lenSigBytes[:]...)
@@ -234,6 +749,7 @@ func (a *AuctionOrder) SerializeSignable() (buf []byte) {
 	// side [len side]
 	// auctionID [32 bytes]
 	// nonce [2 bytes]
+	// network [4 bytes]
 	buf = append(buf, a.Pubkey[:]...)
 	buf = append(buf, a.TradingPair.Serialize()...)
 
@@ -252,24 +768,49 @@ func (a *AuctionOrder) SerializeSignable() (buf []byte) {
 	buf = append(buf, []byte(a.Side)...)
 	buf = append(buf, a.AuctionID[:]...)
 	buf = append(buf, a.Nonce[:]...)
+
+	networkBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(networkBytes, uint32(a.Network))
+	buf = append(buf, networkBytes[:]...)
 	return
 }
 
-// Deserialize deserializes an order into the struct ptr it's being called on
+// Deserialize deserializes an order into the struct ptr it's being called on. The layout is picked by the
+// version byte, and orders with a version we don't know about are rejected instead of being misread.
 func (a *AuctionOrder) Deserialize(data []byte) (err error) {
-	// 33 for pubkey, 26 for the rest, 8 for len side, 4 for min side ("sell" is 4 bytes), 32 for auctionID, 2 for nonce, 8 for siglen
+	if len(data) == 0 {
+		err = newDeserializeError(ErrTruncated, "auction order is empty, it has no version")
+		return
+	}
+
+	switch data[0] {
+	case AuctionOrderVersion0:
+		err = a.deserializeV0(data[1:])
+	default:
+		err = newDeserializeError(ErrUnknownVersion, "auction order has version %d, only versions up to %d are known", data[0], AuctionOrderVersion)
+	}
+	return
+}
+
+// deserializeV0 deserializes the fields of a version 0 auction order, after the version byte
+func (a *AuctionOrder) deserializeV0(data []byte) (err error) {
+	// 33 for pubkey, 2 for pair, 16 for amounts, 8 for len side, 32 for auctionID, 2 for nonce, 4 for network, 8 for siglen
 	// bucket is where we put all of the non byte stuff so we can get their length
+	// Malformed input returns a DeserializeError, so callers can tell it apart from other failures
 
 	// TODO: remove all of this serialization code entirely and use protobufs or something else
+	lenSize := binary.Size(uint64(0))
 	minimumDataLength := len(a.Nonce) +
+		binary.Size(a.Network) +
 		len(a.AuctionID) +
-		binary.Size(a.OrderbookPrice) +
+		lenSize +
 		binary.Size(a.AmountWant) +
 		binary.Size(a.AmountHave) +
 		a.TradingPair.Size() +
-		len(a.Pubkey)
+		len(a.Pubkey) +
+		lenSize
 	if len(data) < minimumDataLength {
-		err = fmt.Errorf("Auction order cannot be less than %d bytes: %s", len(data), err)
+		err = newDeserializeError(ErrTruncated, "auction order is %d bytes, it cannot be less than %d bytes", len(data), minimumDataLength)
 		return
 	}
 
@@ -278,7 +819,11 @@ func (a *AuctionOrder) Deserialize(data []byte) (err error) {
 	var tradingPairBytes [2]byte
 	copy(tradingPairBytes[:], data[:2])
 	if err = a.TradingPair.Deserialize(tradingPairBytes[:]); err != nil {
-		err = fmt.Errorf("Could not deserialize trading pair while deserializing auction order: %s", err)
+		err = newDeserializeError(ErrBadTradingPair, "could not deserialize trading pair while deserializing auction order: %s", err)
+		return
+	}
+	if err = a.TradingPair.Validate(); err != nil {
+		err = newDeserializeError(ErrBadTradingPair, "%s", err)
 		return
 	}
 	data = data[2:]
@@ -288,14 +833,29 @@ func (a *AuctionOrder) Deserialize(data []byte) (err error) {
 	data = data[8:]
 	sideLen := binary.LittleEndian.Uint64(data[:8])
 	data = data[8:]
+	// the auction ID, nonce, network, and signature length come after the side
+	if sideLen > uint64(len(data)) {
+		err = newDeserializeError(ErrSideTooLong, "side length %d exceeds remaining %d bytes of the auction order", sideLen, len(data))
+		return
+	}
+	if uint64(len(data))-sideLen < uint64(len(a.AuctionID)+len(a.Nonce)+binary.Size(a.Network)+lenSize) {
+		err = newDeserializeError(ErrTruncated, "auction order ends %d bytes after the side, before the fields after it", uint64(len(data))-sideLen)
+		return
+	}
 	a.Side = string(data[:sideLen])
 	data = data[sideLen:]
 	copy(a.AuctionID[:], data[:32])
 	data = data[32:]
 	copy(a.Nonce[:], data[:2])
 	data = data[2:]
+	a.Network = NetworkMagic(binary.LittleEndian.Uint32(data[:4]))
+	data = data[4:]
 	sigLen := binary.LittleEndian.Uint64(data[:8])
 	data = data[8:]
+	if sigLen > uint64(len(data)) {
+		err = newDeserializeError(ErrSignatureTooLong, "signature length %d exceeds remaining %d bytes of the auction order", sigLen, len(data))
+		return
+	}
 	a.Signature = data[:sigLen]
 	data = data[sigLen:]
 
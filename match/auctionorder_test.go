@@ -1,6 +1,23 @@
 package match
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/gob"
+	"hash"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+	"github.com/mit-dci/opencx/crypto"
+	"github.com/mit-dci/opencx/crypto/hashtimelock"
+	"github.com/mit-dci/opencx/crypto/rsw"
+	"github.com/mit-dci/opencx/crypto/vdf"
+)
 
 func TestIsBuySide(t *testing.T) {
 
@@ -103,6 +120,79 @@ func solveVariableRC5AuctionOrder(howMany uint64, timeToSolve uint64, t *testing
 	return
 }
 
+func TestSolveRC5AuctionOrderWithContextCancel(t *testing.T) {
+	// This would take far longer than the test to solve
+	encOrder, err := goldenAuctionOrder().TurnIntoEncryptedOrder(1 << 40)
+	if err != nil {
+		t.Errorf("Error creating encrypted order: %s", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	puzzleResChan := make(chan *OrderPuzzleResult, 1)
+	doneChan := make(chan struct{})
+	go func() {
+		SolveRC5AuctionOrderWithContext(ctx, encOrder, puzzleResChan)
+		close(doneChan)
+	}()
+
+	// Let it get into squaring before cancelling
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-doneChan:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Solving should have stopped soon after being cancelled")
+		return
+	}
+
+	res := <-puzzleResChan
+	if PuzzleFailureReason(res.Err) != PuzzleCancelled {
+		t.Errorf("Cancelled solve should fail with %s, got %v", PuzzleCancelled, res.Err)
+		return
+	}
+
+	if res.Auction != nil || res.Encrypted != encOrder {
+		t.Errorf("Cancelled solve should have the encrypted order and no auction order")
+		return
+	}
+
+	return
+}
+
+func TestSolveRC5AuctionOrderWithProgress(t *testing.T) {
+	encOrder, err := goldenAuctionOrder().TurnIntoEncryptedOrder(1000)
+	if err != nil {
+		t.Errorf("Error creating encrypted order: %s", err)
+		return
+	}
+
+	var fractions []float64
+	puzzleResChan := make(chan *OrderPuzzleResult, 1)
+	SolveRC5AuctionOrderWithProgress(context.Background(), encOrder, 100, func(fraction float64) {
+		fractions = append(fractions, fraction)
+	}, puzzleResChan)
+
+	res := <-puzzleResChan
+	if res.Err != nil {
+		t.Errorf("Error solving order with progress: %s", res.Err)
+		return
+	}
+
+	if res.Progress != 1.0 {
+		t.Errorf("Solved order should have a progress of 1, got %f", res.Progress)
+		return
+	}
+
+	if len(fractions) != 10 || fractions[0] != 0.1 || fractions[len(fractions)-1] != 1.0 {
+		t.Errorf("Progress should go from 0.1 to 1 in 10 steps, got %v", fractions)
+		return
+	}
+
+	return
+}
+
 // This should be super quick. Takes 0.1 seconds on an i7 8700k, most of the time is probably
 // spent creating the test to solve.
 func TestConcurrentSolvesN10_T10000(t *testing.T) {
@@ -121,3 +211,841 @@ func TestConcurrentSolvesN10_T1000000(t *testing.T) {
 	solveVariableRC5AuctionOrder(uint64(10), uint64(1000000), t)
 	return
 }
+
+func TestSolveAuctionOrderBatch(t *testing.T) {
+	var err error
+
+	origOrder := goldenAuctionOrder()
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = origOrder.TurnIntoEncryptedOrder(1000); err != nil {
+		t.Errorf("Error creating encrypted order: %s", err)
+		return
+	}
+
+	// A bad order in the middle shouldn't change where the other results go
+	wrongCipher := *encOrder
+	wrongCipher.CipherType = CipherAES
+	orders := []*EncryptedAuctionOrder{encOrder, encOrder, &wrongCipher, encOrder, nil, encOrder}
+
+	for _, workers := range []int{0, 1, 2, 100} {
+		results := SolveAuctionOrderBatch(orders, workers)
+		if len(results) != len(orders) {
+			t.Errorf("Got %d results for %d orders with %d workers", len(results), len(orders), workers)
+			return
+		}
+
+		for i, res := range results {
+			if res.Encrypted != orders[i] {
+				t.Errorf("Result %d with %d workers is not for order %d", i, workers, i)
+				return
+			}
+
+			if orders[i] != encOrder {
+				if res.Err == nil {
+					t.Errorf("Result %d with %d workers should have failed", i, workers)
+					return
+				}
+				continue
+			}
+
+			if res.Err != nil {
+				t.Errorf("Error solving order %d with %d workers: %s", i, workers, res.Err)
+				return
+			}
+
+			if !bytes.Equal(res.Auction.Serialize(), origOrder.Serialize()) {
+				t.Errorf("Order %d solved with %d workers is not the original order", i, workers)
+				return
+			}
+		}
+	}
+
+	if results := SolveAuctionOrderBatch(nil, 4); len(results) != 0 {
+		t.Errorf("Solving an empty batch should give no results, got %d", len(results))
+		return
+	}
+
+	return
+}
+
+// benchmarkSolveOrders creates an encrypted order to solve howMany times in a benchmark
+func benchmarkSolveOrders(howMany int, b *testing.B) (orders []*EncryptedAuctionOrder) {
+	encOrder, err := goldenAuctionOrder().TurnIntoEncryptedOrder(10000)
+	if err != nil {
+		b.Fatalf("Error creating encrypted order for benchmark: %s", err)
+	}
+
+	// Creating the puzzle is much slower than solving it, so the same one is solved over and over
+	for i := 0; i < howMany; i++ {
+		orders = append(orders, encOrder)
+	}
+	return
+}
+
+func BenchmarkSolveOrdersUnbounded1000(b *testing.B) {
+	orders := benchmarkSolveOrders(1000, b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		puzzleResChan := make(chan *OrderPuzzleResult, len(orders))
+		for _, order := range orders {
+			go SolveRC5AuctionOrderAsync(order, puzzleResChan)
+		}
+		for range orders {
+			<-puzzleResChan
+		}
+	}
+
+	return
+}
+
+func BenchmarkSolveOrdersBatch1000(b *testing.B) {
+	orders := benchmarkSolveOrders(1000, b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SolveAuctionOrderBatch(orders, 0)
+	}
+
+	return
+}
+
+// testEncryptedOrder creates an encrypted order to be used for serialization tests and benchmarks
+func testEncryptedOrder() (encOrder *EncryptedAuctionOrder, err error) {
+	origOrder := &AuctionOrder{
+		Side: "buy",
+		TradingPair: Pair{
+			AssetWant: BTC,
+			AssetHave: VTC,
+		},
+		AmountHave: 100000000,
+		AmountWant: 100000000,
+		Nonce:      [2]byte{0xff, 0x12},
+	}
+
+	encOrder, err = origOrder.TurnIntoEncryptedOrder(10000)
+	return
+}
+
+// serializeRegisterEveryTime is how encrypted orders used to be serialized, registering every type
+// with gob and allocating a new buffer on every call. It's only here to compare against.
+func serializeRegisterEveryTime(e *EncryptedAuctionOrder) (raw []byte, err error) {
+	var b bytes.Buffer
+	gob.Register(new(rsw.PuzzleRSW))
+	gob.Register(new(hashtimelock.HashTimelock))
+	gob.RegisterName("puzzle", new(crypto.Puzzle))
+	gob.RegisterName("order", new(EncryptedAuctionOrder))
+	enc := gob.NewEncoder(&b)
+	if err = enc.Encode(e); err != nil {
+		return
+	}
+	raw = b.Bytes()
+	return
+}
+
+func TestEncryptedOrderSerializeRoundTrip(t *testing.T) {
+	var err error
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = testEncryptedOrder(); err != nil {
+		t.Errorf("Error creating encrypted order for serialization test: %s", err)
+		return
+	}
+
+	var raw []byte
+	if raw, err = encOrder.Serialize(); err != nil {
+		t.Errorf("Error serializing encrypted order: %s", err)
+		return
+	}
+
+	// Serialize something else so the pooled buffer gets reused, this shouldn't affect raw
+	if _, err = encOrder.Serialize(); err != nil {
+		t.Errorf("Error serializing encrypted order a second time: %s", err)
+		return
+	}
+
+	decOrder := new(EncryptedAuctionOrder)
+	if err = decOrder.Deserialize(raw); err != nil {
+		t.Errorf("Error deserializing encrypted order: %s", err)
+		return
+	}
+
+	if !bytes.Equal(decOrder.OrderCiphertext, encOrder.OrderCiphertext) {
+		t.Errorf("Ciphertext did not survive serialization round trip")
+		return
+	}
+
+	if decOrder.IntendedAuction != encOrder.IntendedAuction {
+		t.Errorf("Intended auction did not survive serialization round trip")
+		return
+	}
+
+	return
+}
+
+func TestEncryptedOrderVDFPuzzleRoundTrip(t *testing.T) {
+	var err error
+
+	var timelock crypto.Timelock
+	if timelock, err = vdf.New([]byte("order key"), 2, 1024); err != nil {
+		t.Errorf("Error creating vdf timelock: %s", err)
+		return
+	}
+
+	var puzzle crypto.Puzzle
+	if puzzle, _, err = timelock.SetupTimelockPuzzle(1000); err != nil {
+		t.Errorf("Error creating vdf puzzle: %s", err)
+		return
+	}
+
+	encOrder := &EncryptedAuctionOrder{
+		OrderCiphertext: []byte{0x01, 0x02, 0x03},
+		OrderPuzzle:     puzzle,
+		IntendedAuction: [32]byte{0x04},
+	}
+
+	var raw []byte
+	if raw, err = encOrder.Serialize(); err != nil {
+		t.Errorf("Error serializing encrypted order with vdf puzzle: %s", err)
+		return
+	}
+
+	decOrder := new(EncryptedAuctionOrder)
+	if err = decOrder.Deserialize(raw); err != nil {
+		t.Errorf("Error deserializing encrypted order with vdf puzzle: %s", err)
+		return
+	}
+
+	if _, ok := decOrder.OrderPuzzle.(*vdf.PuzzleVDF); !ok {
+		t.Errorf("Puzzle should still be a vdf puzzle after serialization round trip, got %T", decOrder.OrderPuzzle)
+		return
+	}
+
+	return
+}
+
+func TestEncryptedOrderCipherTypeRoundTrip(t *testing.T) {
+	var err error
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = testEncryptedOrder(); err != nil {
+		t.Errorf("Error creating encrypted order for cipher type test: %s", err)
+		return
+	}
+
+	if encOrder.CipherType != CipherRC5 {
+		t.Errorf("Orders turned into encrypted orders should be RC5, got %s", encOrder.CipherType)
+		return
+	}
+
+	for _, cipherType := range []CipherType{CipherRC5, CipherRC6, CipherAES} {
+		encOrder.CipherType = cipherType
+
+		var raw []byte
+		if raw, err = encOrder.Serialize(); err != nil {
+			t.Errorf("Error serializing %s encrypted order: %s", cipherType, err)
+			return
+		}
+
+		decOrder := new(EncryptedAuctionOrder)
+		if err = decOrder.Deserialize(raw); err != nil {
+			t.Errorf("Error deserializing %s encrypted order: %s", cipherType, err)
+			return
+		}
+
+		if decOrder.CipherType != cipherType {
+			t.Errorf("Cipher type %s did not survive serialization round trip, got %s", cipherType, decOrder.CipherType)
+			return
+		}
+	}
+
+	// Unknown ciphers can't be solved
+	encOrder.CipherType = CipherType(0xff)
+	if _, err = encOrder.Solve(); err == nil {
+		t.Errorf("Solving an order with an unknown cipher type should fail")
+		return
+	}
+
+	return
+}
+
+// testHashPuzzle creates a hash timelock puzzle whose answer is the size of the hash function output
+func testHashPuzzle(hashFunction hash.Hash) (puzzle crypto.Puzzle, err error) {
+	puzzle, _, err = hashtimelock.New([]byte("opencx puzzle test"), hashFunction).SetupTimelockPuzzle(10)
+	return
+}
+
+func TestEncryptedOrderVerifyPuzzle(t *testing.T) {
+	var err error
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = testEncryptedOrder(); err != nil {
+		t.Errorf("Error creating encrypted order for verify puzzle test: %s", err)
+		return
+	}
+
+	var sha256Puzzle crypto.Puzzle
+	if sha256Puzzle, err = testHashPuzzle(sha256.New()); err != nil {
+		t.Errorf("Error creating sha256 puzzle for verify puzzle test: %s", err)
+		return
+	}
+
+	var md5Puzzle crypto.Puzzle
+	if md5Puzzle, err = testHashPuzzle(md5.New()); err != nil {
+		t.Errorf("Error creating md5 puzzle for verify puzzle test: %s", err)
+		return
+	}
+
+	// ck is much longer than n, so the answer is going to be 40 bytes no matter what
+	hugeAnswerPuzzle := &rsw.PuzzleRSW{
+		N:  big.NewInt(1000003),
+		A:  big.NewInt(2),
+		T:  big.NewInt(10),
+		CK: new(big.Int).Lsh(big.NewInt(1), 319),
+	}
+
+	var testCases = []struct {
+		name       string
+		puzzle     crypto.Puzzle
+		cipherType CipherType
+		ciphertext []byte
+		valid      bool
+	}{
+		{"rsw rc5", encOrder.OrderPuzzle, CipherRC5, encOrder.OrderCiphertext, true},
+		{"rsw aes", encOrder.OrderPuzzle, CipherAES, encOrder.OrderCiphertext, true},
+		{"sha256 aes", sha256Puzzle, CipherAES, encOrder.OrderCiphertext, true},
+		{"sha256 rc5", sha256Puzzle, CipherRC5, encOrder.OrderCiphertext, false},
+		{"sha256 rc6", sha256Puzzle, CipherRC6, encOrder.OrderCiphertext, false},
+		{"md5 rc5", md5Puzzle, CipherRC5, encOrder.OrderCiphertext, true},
+		{"huge answer rc5", hugeAnswerPuzzle, CipherRC5, encOrder.OrderCiphertext, false},
+		{"huge answer aes", hugeAnswerPuzzle, CipherAES, encOrder.OrderCiphertext, false},
+		{"unknown cipher", encOrder.OrderPuzzle, CipherType(0xff), encOrder.OrderCiphertext, false},
+		{"short ciphertext", encOrder.OrderPuzzle, CipherAES, encOrder.OrderCiphertext[:16], false},
+		{"no puzzle", nil, CipherRC5, encOrder.OrderCiphertext, false},
+	}
+
+	for _, testCase := range testCases {
+		testOrder := &EncryptedAuctionOrder{
+			OrderCiphertext: testCase.ciphertext,
+			OrderPuzzle:     testCase.puzzle,
+			IntendedAuction: encOrder.IntendedAuction,
+			CipherType:      testCase.cipherType,
+		}
+
+		err = testOrder.VerifyPuzzle()
+		if testCase.valid && err != nil {
+			t.Errorf("Puzzle for %s should have been valid, got error: %s", testCase.name, err)
+			return
+		}
+		if !testCase.valid && err == nil {
+			t.Errorf("Puzzle for %s should have been rejected", testCase.name)
+			return
+		}
+	}
+
+	// mismatched orders should never make it to solving
+	encOrder.OrderPuzzle = sha256Puzzle
+	if _, err = encOrder.Solve(); err == nil {
+		t.Errorf("Solving an RC5 order with a sha256 puzzle should fail")
+		return
+	}
+
+	return
+}
+
+func TestEncryptedOrderSerializeAllocs(t *testing.T) {
+	var err error
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = testEncryptedOrder(); err != nil {
+		t.Errorf("Error creating encrypted order for allocation test: %s", err)
+		return
+	}
+
+	before := testing.AllocsPerRun(100, func() {
+		serializeRegisterEveryTime(encOrder)
+	})
+	after := testing.AllocsPerRun(100, func() {
+		encOrder.Serialize()
+	})
+
+	t.Logf("Allocations per serialize: %.1f before, %.1f after", before, after)
+	if after >= before {
+		t.Errorf("Serialize should allocate less than registering every time, but allocated %.1f vs %.1f", after, before)
+		return
+	}
+
+	return
+}
+
+func BenchmarkEncryptedOrderSerialize(b *testing.B) {
+	var err error
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = testEncryptedOrder(); err != nil {
+		b.Errorf("Error creating encrypted order for benchmark: %s", err)
+		return
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = encOrder.Serialize(); err != nil {
+			b.Errorf("Error serializing encrypted order: %s", err)
+			return
+		}
+	}
+
+	return
+}
+
+func BenchmarkEncryptedOrderDeserialize(b *testing.B) {
+	var err error
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = testEncryptedOrder(); err != nil {
+		b.Errorf("Error creating encrypted order for benchmark: %s", err)
+		return
+	}
+
+	var raw []byte
+	if raw, err = encOrder.Serialize(); err != nil {
+		b.Errorf("Error serializing encrypted order for benchmark: %s", err)
+		return
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = new(EncryptedAuctionOrder).Deserialize(raw); err != nil {
+			b.Errorf("Error deserializing encrypted order: %s", err)
+			return
+		}
+	}
+
+	return
+}
+
+func TestAuctionOrderVersion0RoundTrip(t *testing.T) {
+	var err error
+
+	var orders []*AuctionOrder
+	if orders, err = signedTestOrders(3); err != nil {
+		t.Errorf("Error creating orders for version round trip: %s", err)
+		return
+	}
+
+	unsigned := goldenAuctionOrder()
+	unsigned.Signature = nil
+	orders = append(orders, goldenAuctionOrder(), unsigned)
+
+	for i, order := range orders {
+		raw := order.Serialize()
+		if raw[0] != AuctionOrderVersion0 {
+			t.Errorf("Order %d should serialize as version %d, got version %d", i, AuctionOrderVersion0, raw[0])
+			return
+		}
+
+		decOrder := new(AuctionOrder)
+		if err = decOrder.Deserialize(raw); err != nil {
+			t.Errorf("Error deserializing version 0 order %d: %s", i, err)
+			return
+		}
+
+		if !bytes.Equal(decOrder.Serialize(), raw) || !bytes.Equal(decOrder.SerializeSignable(), order.SerializeSignable()) {
+			t.Errorf("Version 0 order %d did not survive a serialization round trip", i)
+			return
+		}
+	}
+
+	// The version isn't signed, so it doesn't change whether the signature verifies
+	var valid bool
+	if valid, err = orders[0].VerifySignature(); err != nil || !valid {
+		t.Errorf("Signed order should still verify: %v", err)
+		return
+	}
+
+	return
+}
+
+func TestAuctionOrderClone(t *testing.T) {
+	order := goldenAuctionOrder()
+	originalSig := append([]byte{}, order.Signature...)
+
+	clone := order.Clone()
+	if !bytes.Equal(clone.Serialize(), order.Serialize()) {
+		t.Errorf("Clone should serialize the same as the original")
+		return
+	}
+
+	clone.Signature[0] ^= 0xff
+	clone.AmountHave++
+	clone.OrderbookPrice = 5.0
+	if !bytes.Equal(order.Signature, originalSig) {
+		t.Errorf("Changing the clone's signature should not change the original's signature")
+		return
+	}
+
+	if order.AmountHave == clone.AmountHave || order.OrderbookPrice == clone.OrderbookPrice {
+		t.Errorf("Changing the clone should not change the original")
+		return
+	}
+
+	// An unsigned order should stay unsigned
+	order.Signature = nil
+	if order.Clone().Signature != nil {
+		t.Errorf("Clone of an unsigned order should not have a signature")
+		return
+	}
+
+	return
+}
+
+func TestAuctionOrderSetOrderbookPrice(t *testing.T) {
+	var err error
+
+	buyOrder := &AuctionOrder{
+		Side:       "buy",
+		AmountHave: 1000,
+		AmountWant: 500,
+	}
+	if err = buyOrder.SetOrderbookPrice(); err != nil {
+		t.Errorf("Error setting orderbook price for buy order: %s", err)
+		return
+	}
+	if buyOrder.OrderbookPrice != 0.5 {
+		t.Errorf("Expected buy order orderbook price 0.5 but got %f", buyOrder.OrderbookPrice)
+		return
+	}
+
+	sellOrder := &AuctionOrder{
+		Side:       "sell",
+		AmountHave: 1000,
+		AmountWant: 500,
+	}
+	if err = sellOrder.SetOrderbookPrice(); err != nil {
+		t.Errorf("Error setting orderbook price for sell order: %s", err)
+		return
+	}
+	if sellOrder.OrderbookPrice != 2.0 {
+		t.Errorf("Expected sell order orderbook price 2.0 but got %f", sellOrder.OrderbookPrice)
+		return
+	}
+
+	// A price that's already been set by the store should be kept
+	storedOrder := &AuctionOrder{
+		Side:           "sell",
+		AmountHave:     1000,
+		AmountWant:     500,
+		OrderbookPrice: 3.0,
+	}
+	if err = storedOrder.SetOrderbookPrice(); err != nil {
+		t.Errorf("Error setting orderbook price for stored order: %s", err)
+		return
+	}
+	if storedOrder.OrderbookPrice != 3.0 {
+		t.Errorf("Expected stored orderbook price 3.0 to be kept but got %f", storedOrder.OrderbookPrice)
+		return
+	}
+
+	return
+}
+
+// validTestAuctionOrder creates an auction order that passes Validate, with a real pubkey
+func validTestAuctionOrder() (order *AuctionOrder, err error) {
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		return
+	}
+
+	order = goldenAuctionOrder()
+	copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+	return
+}
+
+func TestAuctionOrderValidate(t *testing.T) {
+	var err error
+
+	var order *AuctionOrder
+	if order, err = validTestAuctionOrder(); err != nil {
+		t.Errorf("Error creating valid test order: %s", err)
+		return
+	}
+
+	if err = order.Validate(); err != nil {
+		t.Errorf("Valid order should pass validation: %s", err)
+		return
+	}
+
+	var tests = []struct {
+		name   string
+		breaks func(order *AuctionOrder)
+	}{
+		{"bad side", func(order *AuctionOrder) { order.Side = "idk" }},
+		{"empty side", func(order *AuctionOrder) { order.Side = "" }},
+		{"zero amount have", func(order *AuctionOrder) { order.AmountHave = 0 }},
+		{"zero amount want", func(order *AuctionOrder) { order.AmountWant = 0 }},
+		{"zero pubkey", func(order *AuctionOrder) { order.Pubkey = [33]byte{} }},
+		{"bad pubkey prefix", func(order *AuctionOrder) { order.Pubkey[0] = 0x05 }},
+		{"unknown asset", func(order *AuctionOrder) { order.TradingPair.AssetHave = Asset(0xff) }},
+		{"same asset on both sides", func(order *AuctionOrder) { order.TradingPair.AssetHave = order.TradingPair.AssetWant }},
+	}
+
+	for _, test := range tests {
+		var malformed *AuctionOrder
+		if malformed, err = validTestAuctionOrder(); err != nil {
+			t.Errorf("Error creating test order for %s: %s", test.name, err)
+			return
+		}
+		test.breaks(malformed)
+
+		if err = malformed.Validate(); err == nil {
+			t.Errorf("Order with %s should not pass validation", test.name)
+			return
+		}
+	}
+
+	return
+}
+
+func TestAuctionOrderInvertedPrice(t *testing.T) {
+	var err error
+
+	for _, side := range []string{"buy", "sell"} {
+		order := &AuctionOrder{
+			Side:        side,
+			TradingPair: Pair{AssetWant: BTCTest, AssetHave: LTCTest},
+			AmountHave:  400,
+			AmountWant:  100,
+		}
+
+		var price float64
+		if price, err = order.Price(); err != nil {
+			t.Errorf("Error getting %s price: %s", side, err)
+			return
+		}
+
+		var invertedPrice float64
+		if invertedPrice, err = order.InvertedPrice(); err != nil {
+			t.Errorf("Error getting inverted %s price: %s", side, err)
+			return
+		}
+
+		if invertedPrice != 1/price {
+			t.Errorf("Inverted %s price should be 1/%f, got %f", side, price, invertedPrice)
+			return
+		}
+
+		// The order is on the other side of the inverse pair, so its price there is the inverted price
+		inverseOrder := &AuctionOrder{
+			TradingPair: order.TradingPair.Inverse(),
+			AmountHave:  order.AmountHave,
+			AmountWant:  order.AmountWant,
+		}
+		if order.IsBuySide() {
+			inverseOrder.Side = "sell"
+		} else {
+			inverseOrder.Side = "buy"
+		}
+
+		var inversePrice float64
+		if inversePrice, err = inverseOrder.Price(); err != nil {
+			t.Errorf("Error getting price in inverse pair: %s", err)
+			return
+		}
+
+		if inversePrice != invertedPrice {
+			t.Errorf("Inverted %s price should be the price on the other side of the inverse pair, expected %f got %f", side, inversePrice, invertedPrice)
+			return
+		}
+	}
+
+	if _, err = (&AuctionOrder{Side: "buy", AmountWant: 100}).InvertedPrice(); err == nil {
+		t.Errorf("Order that offers nothing should not have an inverted price")
+		return
+	}
+
+	return
+}
+
+func TestPriceRationalExact(t *testing.T) {
+	var err error
+
+	// float64 can't tell 2^53 and 2^53 + 1 apart
+	exact := uint64(1 << 53)
+	pair := Pair{AssetWant: BTCTest, AssetHave: LTCTest}
+	lowSell := &AuctionOrder{Side: "sell", TradingPair: pair, AmountHave: exact, AmountWant: 1}
+	highSell := &AuctionOrder{Side: "sell", TradingPair: pair, AmountHave: exact + 1, AmountWant: 1}
+
+	var lowFloat, highFloat float64
+	if lowFloat, err = lowSell.Price(); err != nil {
+		t.Errorf("Error getting float price: %s", err)
+		return
+	}
+	if highFloat, err = highSell.Price(); err != nil {
+		t.Errorf("Error getting float price: %s", err)
+		return
+	}
+
+	if lowFloat != highFloat {
+		t.Errorf("Float prices should have lost the difference between the orders, test amounts are wrong")
+		return
+	}
+
+	var highPrice *big.Rat
+	if highPrice, err = highSell.PriceRational(); err != nil {
+		t.Errorf("Error getting rational price: %s", err)
+		return
+	}
+
+	if highPrice.Cmp(new(big.Rat).SetInt(new(big.Int).SetUint64(exact+1))) != 0 {
+		t.Errorf("Rational price should be exactly %d, got %s", exact+1, highPrice.RatString())
+		return
+	}
+
+	var cmp int
+	if cmp, err = ComparePrices(highSell, lowSell); err != nil {
+		t.Errorf("Error comparing prices: %s", err)
+		return
+	}
+
+	if cmp != 1 {
+		t.Errorf("Order with the higher exact price should compare higher, got %d", cmp)
+		return
+	}
+
+	// Buy prices are want / have, check a ratio where both amounts are past 2^53
+	buy := &AuctionOrder{Side: "buy", TradingPair: pair, AmountHave: exact + 1, AmountWant: exact + 3}
+	var buyPrice *big.Rat
+	if buyPrice, err = buy.PriceRational(); err != nil {
+		t.Errorf("Error getting rational buy price: %s", err)
+		return
+	}
+
+	if buyPrice.Cmp(big.NewRat(1, 1)) <= 0 {
+		t.Errorf("Buy order wanting more than it has should have a price above 1, got %s", buyPrice.RatString())
+		return
+	}
+
+	if _, err = (&AuctionOrder{Side: "buy", AmountWant: 1}).PriceRational(); err == nil {
+		t.Errorf("Buy order offering nothing should not have a rational price")
+		return
+	}
+
+	// The sell priced at exactly 2^53 clears at a 2^53 clearing price, the one just above it doesn't,
+	// even though their float prices are the same
+	var imbalance *AuctionImbalance
+	if imbalance, err = ComputeImbalance(pair, []*AuctionOrder{lowSell, highSell}, float64(exact)); err != nil {
+		t.Errorf("Error computing imbalance: %s", err)
+		return
+	}
+
+	if imbalance.SellVolume != exact {
+		t.Errorf("Only the sell at the clearing price should count, expected sell volume %d got %d", exact, imbalance.SellVolume)
+		return
+	}
+
+	return
+}
+
+func TestAuctionOrderCheckAmountRatio(t *testing.T) {
+	var err error
+
+	var tests = []struct {
+		have     uint64
+		want     uint64
+		maxRatio uint64
+		valid    bool
+	}{
+		{100, 100, 1, true},
+		{100, 101, 1, false},
+		{1000, 1, 1000, true},
+		{1001, 1, 1000, false},
+		{1, 1000, 1000, true},
+		{1, 1001, 1000, false},
+		{2001, 2, 1000, false},
+		{math.MaxUint64, 1, math.MaxUint64, true},
+		{math.MaxUint64, 2, math.MaxUint64 / 2, false},
+		// no limit
+		{math.MaxUint64, 1, 0, true},
+	}
+
+	for _, test := range tests {
+		var order *AuctionOrder
+		if order, err = validTestAuctionOrder(); err != nil {
+			t.Errorf("Error creating valid test order: %s", err)
+			return
+		}
+		order.AmountHave = test.have
+		order.AmountWant = test.want
+
+		err = order.CheckAmountRatio(test.maxRatio)
+		if test.valid && err != nil {
+			t.Errorf("Order with have %d and want %d should be within a max ratio of %d: %s", test.have, test.want, test.maxRatio, err)
+			return
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Order with have %d and want %d should be beyond a max ratio of %d", test.have, test.want, test.maxRatio)
+			return
+		}
+	}
+
+	return
+}
+
+func TestAuctionOrderID(t *testing.T) {
+	var err error
+
+	var order *AuctionOrder
+	if order, err = validTestAuctionOrder(); err != nil {
+		t.Errorf("Error creating valid test order: %s", err)
+		return
+	}
+	order.Signature = []byte{0x01, 0x02, 0x03}
+
+	var id [32]byte
+	if id, err = order.OrderID(); err != nil {
+		t.Errorf("Error getting order ID: %s", err)
+		return
+	}
+
+	var sameID [32]byte
+	if sameID, err = order.Clone().OrderID(); err != nil {
+		t.Errorf("Error getting order ID of identical order: %s", err)
+		return
+	}
+
+	if id != sameID {
+		t.Errorf("Identical orders should have the same ID, got %x and %x", id, sameID)
+		return
+	}
+
+	if expected := sha256.Sum256(append(order.SerializeSignable(), order.Signature...)); id != expected {
+		t.Errorf("Order ID should be the sha256 of the signable order and the signature")
+		return
+	}
+
+	changes := map[string]func(order *AuctionOrder){
+		"nonce":     func(order *AuctionOrder) { order.Nonce[1]++ },
+		"signature": func(order *AuctionOrder) { order.Signature[0]++ },
+	}
+	for name, change := range changes {
+		changed := order.Clone()
+		change(changed)
+
+		var changedID [32]byte
+		if changedID, err = changed.OrderID(); err != nil {
+			t.Errorf("Error getting order ID of order with changed %s: %s", name, err)
+			return
+		}
+
+		if changedID == id {
+			t.Errorf("Changing the %s should change the order ID", name)
+			return
+		}
+	}
+
+	order.Signature = nil
+	if _, err = order.OrderID(); err == nil {
+		t.Errorf("Unsigned order should not have an order ID")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,178 @@
+package match
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// pricedOrder is an order along with its exact price, so the price is only computed once
+type pricedOrder struct {
+	order *AuctionOrder
+	price *big.Rat
+}
+
+// ComputeClearingPrice computes the uniform price a batch of auction orders for a single pair clears at.
+// Buy orders fill if their price is at or above the clearing price, and sell orders if their price is at
+// or below it, like in ComputeImbalance. The clearing price is the order price that matches the most
+// volume, in AssetWant. If more than one does, the one with the smallest imbalance between buy and sell
+// volume is used, and after that the lowest.
+// matched is every order that fills at the clearing price, buys from highest to lowest price and then
+// sells from lowest to highest. The side with more volume can't be filled entirely, so its orders at the
+// clearing price are filled pro-rata. If the book doesn't cross, including when it only has one side,
+// price and matched are nil.
+func ComputeClearingPrice(orders []*AuctionOrder) (price *big.Rat, matched []*AuctionOrder, err error) {
+	var buys, sells []*pricedOrder
+	for _, order := range orders {
+		if order.TradingPair != orders[0].TradingPair {
+			err = fmt.Errorf("Orders for %s and %s can't clear together", order.TradingPair.PrettyString(), orders[0].TradingPair.PrettyString())
+			return
+		}
+
+		priced := &pricedOrder{order: order}
+		if priced.price, err = order.PriceRational(); err != nil {
+			err = fmt.Errorf("Error getting order price for clearing price: %s", err)
+			return
+		}
+
+		if order.IsBuySide() {
+			buys = append(buys, priced)
+		} else {
+			sells = append(sells, priced)
+		}
+	}
+
+	// best prices first, orders with the same price stay in the order they were given
+	sort.SliceStable(buys, func(i, j int) bool {
+		return buys[i].price.Cmp(buys[j].price) > 0
+	})
+	sort.SliceStable(sells, func(i, j int) bool {
+		return sells[i].price.Cmp(sells[j].price) < 0
+	})
+
+	var bestVolume, bestImbalance uint64
+	for _, candidate := range append(append([]*pricedOrder{}, buys...), sells...) {
+		var buyVolume, sellVolume uint64
+		if buyVolume, sellVolume, err = clearingVolumes(buys, sells, candidate.price); err != nil {
+			price = nil
+			return
+		}
+
+		volume, imbalance := buyVolume, sellVolume-buyVolume
+		if sellVolume < buyVolume {
+			volume, imbalance = sellVolume, buyVolume-sellVolume
+		}
+
+		if volume == 0 {
+			continue
+		}
+
+		better := price == nil ||
+			volume > bestVolume ||
+			(volume == bestVolume && imbalance < bestImbalance) ||
+			(volume == bestVolume && imbalance == bestImbalance && candidate.price.Cmp(price) < 0)
+		if better {
+			price, bestVolume, bestImbalance = candidate.price, volume, imbalance
+		}
+	}
+
+	if price == nil {
+		return
+	}
+
+	for _, buy := range buys {
+		if buy.price.Cmp(price) >= 0 {
+			matched = append(matched, buy.order)
+		}
+	}
+	for _, sell := range sells {
+		if sell.price.Cmp(price) <= 0 {
+			matched = append(matched, sell.order)
+		}
+	}
+
+	// don't give out the order's price, it could be changed
+	price = new(big.Rat).Set(price)
+	return
+}
+
+// clearingVolumes returns the buy and sell volume, in AssetWant, that would fill at price. Buys want
+// AssetWant and sells have it. If either side's volume doesn't fit in a uint64, an error is returned.
+func clearingVolumes(buys []*pricedOrder, sells []*pricedOrder, price *big.Rat) (buyVolume uint64, sellVolume uint64, err error) {
+	for _, buy := range buys {
+		if buy.price.Cmp(price) < 0 {
+			break
+		}
+		if buyVolume > math.MaxUint64-buy.order.AmountWant {
+			err = fmt.Errorf("Buy volume of %s at %s overflows", buy.order.TradingPair.PrettyString(), price.FloatString(8))
+			return
+		}
+		buyVolume += buy.order.AmountWant
+	}
+
+	for _, sell := range sells {
+		if sell.price.Cmp(price) > 0 {
+			break
+		}
+		if sellVolume > math.MaxUint64-sell.order.AmountHave {
+			err = fmt.Errorf("Sell volume of %s at %s overflows", sell.order.TradingPair.PrettyString(), price.FloatString(8))
+			return
+		}
+		sellVolume += sell.order.AmountHave
+	}
+
+	return
+}
+
+// ExpectedVolume returns the volume, in AssetWant, that orders would match if they cleared now, like in
+// ComputeClearingPrice. It's for watching an auction while its orders are still being decrypted, so it
+// works on whatever part of the orders has been decrypted so far. Orders should be for a single pair.
+// Orders for a different pair than the first one, and orders that have no price, aren't counted, and if
+// nothing crosses the volume is zero. If the volume doesn't fit in a uint64, an error is returned.
+func ExpectedVolume(orders []*AuctionOrder) (volume uint64, err error) {
+	var counted []*AuctionOrder
+	for _, order := range orders {
+		if order.TradingPair != orders[0].TradingPair {
+			continue
+		}
+		if _, priceErr := order.PriceRational(); priceErr != nil {
+			continue
+		}
+		counted = append(counted, order)
+	}
+
+	if len(counted) == 0 {
+		return
+	}
+
+	var matched []*AuctionOrder
+	if _, matched, err = ComputeClearingPrice(counted); err != nil {
+		err = fmt.Errorf("Error computing clearing price for expected volume: %s", err)
+		return
+	}
+
+	var buyVolume, sellVolume uint64
+	for _, order := range matched {
+		if order.IsBuySide() {
+			if buyVolume > math.MaxUint64-order.AmountWant {
+				err = fmt.Errorf("Expected buy volume of %s overflows", order.TradingPair.PrettyString())
+				return
+			}
+			buyVolume += order.AmountWant
+		} else {
+			if sellVolume > math.MaxUint64-order.AmountHave {
+				err = fmt.Errorf("Expected sell volume of %s overflows", order.TradingPair.PrettyString())
+				return
+			}
+			sellVolume += order.AmountHave
+		}
+	}
+
+	// the side with more volume can only fill as much as the other side has
+	volume = buyVolume
+	if sellVolume < buyVolume {
+		volume = sellVolume
+	}
+	return
+}
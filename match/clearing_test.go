@@ -0,0 +1,219 @@
+package match
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestComputeClearingPrice(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+
+	// buy price is want / have, sell price is have / want, volume is in AssetWant
+	b1 := &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 100, AmountWant: 300}
+	b2 := &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 100, AmountWant: 200}
+	b3 := &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 100, AmountWant: 100}
+	s1 := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 100, AmountWant: 100}
+	s2 := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 200, AmountWant: 100}
+	s3 := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 400, AmountWant: 100}
+
+	// At 1: 600 buy, 100 sell. At 2: 500 buy, 300 sell. At 3: 300 buy, 300 sell. At 4: nothing buys.
+	// 2 and 3 both match 300, but 3 has no imbalance.
+	var price *big.Rat
+	var matched []*AuctionOrder
+	if price, matched, err = ComputeClearingPrice([]*AuctionOrder{s3, b3, s1, b1, s2, b2}); err != nil {
+		t.Errorf("Error computing clearing price: %s", err)
+		return
+	}
+
+	if price == nil || price.Cmp(big.NewRat(3, 1)) != 0 {
+		t.Errorf("Clearing price should be 3, got %v", price)
+		return
+	}
+
+	expected := []*AuctionOrder{b1, s1, s2}
+	if len(matched) != len(expected) {
+		t.Errorf("Expected %d matched orders, got %d", len(expected), len(matched))
+		return
+	}
+	for i := range expected {
+		if matched[i] != expected[i] {
+			t.Errorf("Matched order %d is wrong, expected buys from best to worst then sells from best to worst", i)
+			return
+		}
+	}
+
+	return
+}
+
+func TestComputeClearingPriceProRataTie(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+
+	// Everything is at 2, 300 buy volume and 400 sell volume. Both sells are at the clearing price on
+	// the heavier side, so they fill pro-rata, but they're both matched.
+	buy := &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 150, AmountWant: 300}
+	sell1 := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 200, AmountWant: 100}
+	sell2 := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 200, AmountWant: 100}
+
+	var price *big.Rat
+	var matched []*AuctionOrder
+	if price, matched, err = ComputeClearingPrice([]*AuctionOrder{sell1, buy, sell2}); err != nil {
+		t.Errorf("Error computing clearing price: %s", err)
+		return
+	}
+
+	if price == nil || price.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("Clearing price should be 2, got %v", price)
+		return
+	}
+
+	if len(matched) != 3 || matched[0] != buy || matched[1] != sell1 || matched[2] != sell2 {
+		t.Errorf("Every order should be matched, sells at the same price in the order they were given")
+		return
+	}
+
+	// At 1 and at 2 there's 100 buy and 100 sell volume. Ties in volume and imbalance go to the lowest price.
+	orders := []*AuctionOrder{
+		{TradingPair: pair, Side: "buy", AmountHave: 50, AmountWant: 100},
+		{TradingPair: pair, Side: "sell", AmountHave: 100, AmountWant: 100},
+	}
+	if price, _, err = ComputeClearingPrice(orders); err != nil {
+		t.Errorf("Error computing clearing price: %s", err)
+		return
+	}
+
+	if price == nil || price.Cmp(big.NewRat(1, 1)) != 0 {
+		t.Errorf("Clearing price should be 1, got %v", price)
+		return
+	}
+
+	return
+}
+
+func TestComputeClearingPriceNoCross(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+	buy := &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 100, AmountWant: 100}
+	sell := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 200, AmountWant: 100}
+
+	for _, orders := range [][]*AuctionOrder{
+		// buys at 1, sells at 2
+		{buy, sell},
+		// single sided books
+		{buy},
+		{sell},
+		{},
+	} {
+		var price *big.Rat
+		var matched []*AuctionOrder
+		if price, matched, err = ComputeClearingPrice(orders); err != nil {
+			t.Errorf("Error computing clearing price for book that doesn't cross: %s", err)
+			return
+		}
+
+		if price != nil || matched != nil {
+			t.Errorf("Book with %d orders that doesn't cross should have no clearing price, got %v", len(orders), price)
+			return
+		}
+	}
+
+	other := &AuctionOrder{TradingPair: pair.Inverse(), Side: "sell", AmountHave: 100, AmountWant: 100}
+	if _, _, err = ComputeClearingPrice([]*AuctionOrder{buy, other}); err == nil {
+		t.Errorf("Orders on different pairs should not clear together")
+		return
+	}
+
+	return
+}
+
+func TestExpectedVolume(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+
+	// Same book as TestComputeClearingPrice, which clears at 3 with 300 on each side
+	b1 := &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 100, AmountWant: 300}
+	b3 := &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 100, AmountWant: 100}
+	s1 := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 100, AmountWant: 100}
+	s2 := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 200, AmountWant: 100}
+	s3 := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 400, AmountWant: 100}
+
+	// Orders for another pair, and orders with no price, aren't counted
+	other := &AuctionOrder{TradingPair: pair.Inverse(), Side: "sell", AmountHave: 1000, AmountWant: 1}
+	noPrice := &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 1000, AmountWant: 0}
+
+	// The orders are decrypted one at a time, and the volume changes as they are
+	for _, test := range []struct {
+		orders []*AuctionOrder
+		volume uint64
+	}{
+		{[]*AuctionOrder{}, 0},
+		{[]*AuctionOrder{b1}, 0},
+		// buys at 3, sells at 4, doesn't cross
+		{[]*AuctionOrder{b1, s3}, 0},
+		// s1 sells 100 at 1, b1 wants more than that
+		{[]*AuctionOrder{b1, s3, s1}, 100},
+		{[]*AuctionOrder{b1, s3, s1, b3}, 100},
+		// at 2 and 3, b1 wants 300 and s1 and s2 sell 300
+		{[]*AuctionOrder{b1, s3, s1, b3, s2}, 300},
+		{[]*AuctionOrder{b1, s3, s1, other, b3, noPrice, s2}, 300},
+	} {
+		var volume uint64
+		if volume, err = ExpectedVolume(test.orders); err != nil {
+			t.Errorf("Error getting expected volume of %d orders: %s", len(test.orders), err)
+			return
+		}
+
+		if volume != test.volume {
+			t.Errorf("Expected volume of %d for %d orders, got %d", test.volume, len(test.orders), volume)
+			return
+		}
+	}
+
+	return
+}
+
+func TestClearingVolumeOverflow(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+
+	// Both buys want 1 at a price of 1, and together they want more than fits in a uint64
+	orders := []*AuctionOrder{
+		{TradingPair: pair, Side: "buy", AmountHave: math.MaxUint64, AmountWant: math.MaxUint64},
+		{TradingPair: pair, Side: "buy", AmountHave: 1, AmountWant: 1},
+		{TradingPair: pair, Side: "sell", AmountHave: 1, AmountWant: 1},
+	}
+
+	if _, _, err = ComputeClearingPrice(orders); err == nil {
+		t.Errorf("Clearing orders whose volume overflows should fail")
+		return
+	}
+
+	if _, err = ExpectedVolume(orders); err == nil {
+		t.Errorf("Expected volume of orders whose volume overflows should fail")
+		return
+	}
+
+	return
+}
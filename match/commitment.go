@@ -0,0 +1,187 @@
+package match
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// CommitmentChain is a running commitment to the puzzles placed in an auction. Each puzzle extends the chain,
+// starting from c_0 = auctionID, with c_i = h(c_{i-1} || h(puzzle_i)). So the root after some puzzles commits to
+// exactly those puzzles in order, and every later root extends it. When the auction closes, the ID of the next auction is h(c_n || n).
+type CommitmentChain struct {
+	auctionID    [32]byte
+	root         [32]byte
+	puzzleHashes [][32]byte
+}
+
+// InterimCommitment is a snapshot of the commitment chain for an auction that is still open. Clients can use it
+// to check that their puzzle is in the auction before it closes.
+type InterimCommitment struct {
+	AuctionID [32]byte `json:"auctionid"`
+	Root      [32]byte `json:"root"`
+	// PuzzleHashes are the hashes of every puzzle in the chain so far, in order
+	PuzzleHashes [][32]byte `json:"puzzlehashes"`
+	Time         time.Time  `json:"time"`
+}
+
+// AuctionRoot is the final root of the commitment chain for an auction that has closed. Watchdogs can archive
+// these, and later check that the puzzles in an auction are the ones that were committed to.
+type AuctionRoot struct {
+	AuctionID  [32]byte  `json:"auctionid"`
+	Root       [32]byte  `json:"root"`
+	NumPuzzles uint64    `json:"numpuzzles"`
+	Time       time.Time `json:"time"`
+}
+
+// Closing returns the closing commitment of the auction, which is the ID of the auction after it
+func (ar *AuctionRoot) Closing() (closing [32]byte) {
+	return ClosingCommitment(ar.Root, ar.NumPuzzles)
+}
+
+// NewCommitmentChain creates an empty commitment chain for the auction with auctionID
+func NewCommitmentChain(auctionID [32]byte) (chain *CommitmentChain) {
+	chain = &CommitmentChain{
+		auctionID: auctionID,
+		root:      auctionID,
+	}
+	return
+}
+
+// PuzzleHash hashes a serialized encrypted order, this is what goes in the commitment chain
+func PuzzleHash(puzzle *EncryptedAuctionOrder) (puzzleHash [32]byte, err error) {
+	var raw []byte
+	if raw, err = puzzle.Serialize(); err != nil {
+		err = fmt.Errorf("Error serializing puzzle for commitment: %s", err)
+		return
+	}
+
+	copy(puzzleHash[:], sha3Sum(raw))
+	return
+}
+
+// ExtendCommitment returns the root of the chain after the puzzle with puzzleHash is added to root
+func ExtendCommitment(root [32]byte, puzzleHash [32]byte) (newRoot [32]byte) {
+	copy(newRoot[:], sha3Sum(root[:], puzzleHash[:]))
+	return
+}
+
+// ClosingCommitment returns the commitment to a closed auction whose chain has root after numPuzzles puzzles.
+// This is the ID of the next auction.
+func ClosingCommitment(root [32]byte, numPuzzles uint64) (closing [32]byte) {
+	var lenBytes [8]byte
+	binary.BigEndian.PutUint64(lenBytes[:], numPuzzles)
+	copy(closing[:], sha3Sum(root[:], lenBytes[:]))
+	return
+}
+
+// sha3Sum is the sha3-256 of all of the parts concatenated
+func sha3Sum(parts ...[]byte) (sum []byte) {
+	hasher := sha3.New256()
+	for _, part := range parts {
+		hasher.Write(part)
+	}
+	sum = hasher.Sum(nil)
+	return
+}
+
+// Add adds a puzzle to the end of the chain
+func (c *CommitmentChain) Add(puzzle *EncryptedAuctionOrder) (err error) {
+	var puzzleHash [32]byte
+	if puzzleHash, err = PuzzleHash(puzzle); err != nil {
+		err = fmt.Errorf("Error adding puzzle to commitment chain: %s", err)
+		return
+	}
+
+	c.root = ExtendCommitment(c.root, puzzleHash)
+	c.puzzleHashes = append(c.puzzleHashes, puzzleHash)
+	return
+}
+
+// Root returns the current root of the chain
+func (c *CommitmentChain) Root() (root [32]byte) {
+	return c.root
+}
+
+// Len returns the number of puzzles in the chain
+func (c *CommitmentChain) Len() (length uint64) {
+	return uint64(len(c.puzzleHashes))
+}
+
+// Closing returns the commitment to the auction if it were closed now
+func (c *CommitmentChain) Closing() (closing [32]byte) {
+	return ClosingCommitment(c.root, c.Len())
+}
+
+// Snapshot returns an interim commitment for the chain as it is now
+func (c *CommitmentChain) Snapshot() (interim *InterimCommitment) {
+	interim = &InterimCommitment{
+		AuctionID:    c.auctionID,
+		Root:         c.root,
+		PuzzleHashes: append([][32]byte{}, c.puzzleHashes...),
+		Time:         time.Now(),
+	}
+	return
+}
+
+// Close returns the final root of the chain, for when the auction closes
+func (c *CommitmentChain) Close() (auctionRoot *AuctionRoot) {
+	auctionRoot = &AuctionRoot{
+		AuctionID:  c.auctionID,
+		Root:       c.root,
+		NumPuzzles: c.Len(),
+		Time:       time.Now(),
+	}
+	return
+}
+
+// Verify checks that the puzzle hashes really make up the root
+func (ic *InterimCommitment) Verify() (err error) {
+	root := ic.AuctionID
+	for _, puzzleHash := range ic.PuzzleHashes {
+		root = ExtendCommitment(root, puzzleHash)
+	}
+
+	if root != ic.Root {
+		err = fmt.Errorf("Puzzle hashes make root %x, not the committed root %x", root, ic.Root)
+		return
+	}
+
+	return
+}
+
+// Includes returns true if the puzzle with puzzleHash is in the interim commitment. This only means something
+// if the interim commitment verifies.
+func (ic *InterimCommitment) Includes(puzzleHash [32]byte) (included bool) {
+	for _, hash := range ic.PuzzleHashes {
+		if hash == puzzleHash {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyClosing checks that the closing commitment of an auction extends this interim commitment, given the
+// hashes of the puzzles that were added after it. If it does, every puzzle in the interim commitment is also
+// in the closed auction.
+func (ic *InterimCommitment) VerifyClosing(laterPuzzleHashes [][32]byte, closing [32]byte) (err error) {
+	if err = ic.Verify(); err != nil {
+		err = fmt.Errorf("Interim commitment is invalid: %s", err)
+		return
+	}
+
+	root := ic.Root
+	for _, puzzleHash := range laterPuzzleHashes {
+		root = ExtendCommitment(root, puzzleHash)
+	}
+
+	numPuzzles := uint64(len(ic.PuzzleHashes) + len(laterPuzzleHashes))
+	if expected := ClosingCommitment(root, numPuzzles); expected != closing {
+		err = fmt.Errorf("Closing commitment %x does not extend the interim commitment, expected %x", closing, expected)
+		return
+	}
+
+	return
+}
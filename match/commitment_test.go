@@ -0,0 +1,111 @@
+package match
+
+import (
+	"testing"
+)
+
+// commitmentTestPuzzles makes n different puzzles to put in a commitment chain
+func commitmentTestPuzzles(n int) (puzzles []*EncryptedAuctionOrder) {
+	for i := 0; i < n; i++ {
+		puzzle := goldenEncryptedAuctionOrder()
+		puzzle.OrderCiphertext[0] = byte(i)
+		puzzles = append(puzzles, puzzle)
+	}
+	return
+}
+
+func TestInterimCommitmentsExtendToClosing(t *testing.T) {
+	var err error
+	puzzles := commitmentTestPuzzles(6)
+	chain := NewCommitmentChain(goldenAuctionOrder().AuctionID)
+
+	// take an interim commitment after every puzzle
+	var interims []*InterimCommitment
+	var puzzleHashes [][32]byte
+	for _, puzzle := range puzzles {
+		if err = chain.Add(puzzle); err != nil {
+			t.Errorf("Error adding puzzle to chain: %s", err)
+			return
+		}
+		var puzzleHash [32]byte
+		if puzzleHash, err = PuzzleHash(puzzle); err != nil {
+			t.Errorf("Error hashing puzzle: %s", err)
+			return
+		}
+		puzzleHashes = append(puzzleHashes, puzzleHash)
+		interims = append(interims, chain.Snapshot())
+	}
+
+	closing := chain.Closing()
+	for i, interim := range interims {
+		if err = interim.Verify(); err != nil {
+			t.Errorf("Interim commitment %d does not verify: %s", i, err)
+			return
+		}
+		if !interim.Includes(puzzleHashes[i]) {
+			t.Errorf("Interim commitment %d does not include the puzzle added before it", i)
+			return
+		}
+		if err = interim.VerifyClosing(puzzleHashes[i+1:], closing); err != nil {
+			t.Errorf("Closing commitment does not extend interim commitment %d: %s", i, err)
+			return
+		}
+	}
+
+	return
+}
+
+func TestInterimCommitmentTampering(t *testing.T) {
+	var err error
+	chain := NewCommitmentChain(goldenAuctionOrder().AuctionID)
+	var puzzleHashes [][32]byte
+	for _, puzzle := range commitmentTestPuzzles(4) {
+		if err = chain.Add(puzzle); err != nil {
+			t.Errorf("Error adding puzzle to chain: %s", err)
+			return
+		}
+		var puzzleHash [32]byte
+		if puzzleHash, err = PuzzleHash(puzzle); err != nil {
+			t.Errorf("Error hashing puzzle: %s", err)
+			return
+		}
+		puzzleHashes = append(puzzleHashes, puzzleHash)
+	}
+
+	interim := &InterimCommitment{
+		AuctionID:    goldenAuctionOrder().AuctionID,
+		Root:         ExtendCommitment(ExtendCommitment(goldenAuctionOrder().AuctionID, puzzleHashes[0]), puzzleHashes[1]),
+		PuzzleHashes: puzzleHashes[:2],
+	}
+	closing := chain.Closing()
+	if err = interim.VerifyClosing(puzzleHashes[2:], closing); err != nil {
+		t.Errorf("Honest interim commitment should extend to the closing commitment: %s", err)
+		return
+	}
+
+	// leaving a puzzle out of the closed auction should not verify
+	if err = interim.VerifyClosing(puzzleHashes[3:], closing); err == nil {
+		t.Errorf("Closing commitment missing a puzzle should not verify")
+		return
+	}
+
+	// reordering puzzles that were already committed to should not verify
+	swapped := &InterimCommitment{
+		AuctionID:    interim.AuctionID,
+		Root:         interim.Root,
+		PuzzleHashes: [][32]byte{puzzleHashes[1], puzzleHashes[0]},
+	}
+	if err = swapped.Verify(); err == nil {
+		t.Errorf("Interim commitment with reordered puzzles should not verify")
+		return
+	}
+
+	// a different closing commitment should not verify
+	closing[0] ^= 0xff
+	if err = interim.VerifyClosing(puzzleHashes[2:], closing); err == nil {
+		t.Errorf("Tampered closing commitment should not verify")
+		return
+	}
+
+	return
+}
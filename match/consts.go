@@ -33,6 +33,38 @@ const (
 	LTCReg Asset = 0x08
 )
 
+// NetworkMagic identifies the network an exchange is running on. It's signed as part of orders so an order made
+// for an exchange on one network can't be replayed on another network, even if the pairs and auction IDs collide.
+type NetworkMagic uint32
+
+const (
+	// MainnetMagic is the network magic for exchanges trading mainnet assets ("OCXM")
+	MainnetMagic NetworkMagic = 0x4f43584d
+	// TestnetMagic is the network magic for exchanges trading testnet assets ("OCXT")
+	TestnetMagic NetworkMagic = 0x4f435854
+	// RegtestMagic is the network magic for exchanges trading regtest assets ("OCXR")
+	RegtestMagic NetworkMagic = 0x4f435852
+)
+
+// NetworkMagicFromString returns the network magic for a network name, which can be mainnet, testnet, or regtest
+func NetworkMagicFromString(name string) (magic NetworkMagic, err error) {
+	// create map for that O(1) access
+	magicMap := map[string]NetworkMagic{
+		"mainnet": MainnetMagic,
+		"testnet": TestnetMagic,
+		"regtest": RegtestMagic,
+	}
+
+	// grab from map
+	var found bool
+	if magic, found = magicMap[name]; !found {
+		err = fmt.Errorf("Unknown network %s, should be mainnet, testnet, or regtest", name)
+		return
+	}
+
+	return
+}
+
 // largeAssetList is something used for testing the generateassetpairs function, this should be put into a unit test once tests are written
 func largeAssetList() []Asset {
 	return []Asset{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a}
@@ -0,0 +1,124 @@
+package match
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DepthPoint is a point on a depth chart. Volume is cumulative, in units of the pair's AssetWant like
+// AuctionImbalance. For bids it's the volume willing to buy at Price or higher, for asks it's the
+// volume willing to sell at Price or lower.
+type DepthPoint struct {
+	Price  float64 `json:"price"`
+	Volume uint64  `json:"volume"`
+	// Aggregated is true if this is the last point, and it includes the volume at every price past the
+	// point before it because there were too many price levels. Price is the worst of those prices.
+	Aggregated bool `json:"aggregated,omitempty"`
+}
+
+// DepthChart is the aggregated depth of a pair's auction order book, ready to be plotted. Bids are
+// ordered from the highest price down and asks from the lowest price up, so both start at the spread.
+type DepthChart struct {
+	Pair      Pair         `json:"pair"`
+	AuctionID [32]byte     `json:"auctionid"`
+	Bids      []DepthPoint `json:"bids"`
+	Asks      []DepthPoint `json:"asks"`
+}
+
+// ComputeDepth aggregates the orders in a pair's auction into a depth chart, with one point for every
+// distinct price. If levels is more than zero, each side only has the levels points closest to the spread.
+func ComputeDepth(pair Pair, auctionID [32]byte, orders []*AuctionOrder, levels int) (chart *DepthChart, err error) {
+	if levels < 0 {
+		err = fmt.Errorf("Number of depth levels cannot be negative")
+		return
+	}
+
+	bidVolume := make(map[float64]uint64)
+	askVolume := make(map[float64]uint64)
+	for _, order := range orders {
+		if order.TradingPair != pair {
+			err = fmt.Errorf("Order for %s can't be part of the depth for %s", order.TradingPair.PrettyString(), pair.PrettyString())
+			return
+		}
+
+		var price float64
+		if price, err = order.Price(); err != nil {
+			err = fmt.Errorf("Error getting order price for depth: %s", err)
+			return
+		}
+
+		// buy orders want AssetWant, sell orders have AssetWant
+		if order.IsBuySide() {
+			bidVolume[price] += order.AmountWant
+		} else if order.IsSellSide() {
+			askVolume[price] += order.AmountHave
+		}
+	}
+
+	chart = &DepthChart{
+		Pair:      pair,
+		AuctionID: auctionID,
+		Bids:      cumulativeDepth(bidVolume, true, levels),
+		Asks:      cumulativeDepth(askVolume, false, levels),
+	}
+	return
+}
+
+// cumulativeDepth turns the volume at each price into cumulative points, starting from the best price.
+// Higher prices are better if descending is true.
+func cumulativeDepth(volumeAtPrice map[float64]uint64, descending bool, levels int) (points []DepthPoint) {
+	var prices []float64
+	for price := range volumeAtPrice {
+		prices = append(prices, price)
+	}
+
+	if descending {
+		sort.Sort(sort.Reverse(sort.Float64Slice(prices)))
+	} else {
+		sort.Float64s(prices)
+	}
+
+	if levels > 0 && len(prices) > levels {
+		prices = prices[:levels]
+	}
+
+	var cumulative uint64
+	for _, price := range prices {
+		cumulative += volumeAtPrice[price]
+		points = append(points, DepthPoint{
+			Price:  price,
+			Volume: cumulative,
+		})
+	}
+
+	return
+}
+
+// CapLevels makes sure each side of the chart has at most maxLevels points, so a batch with a lot of
+// distinct prices can't make the chart huge. Instead of dropping the points past the cap, they're
+// aggregated into the last point, so the chart still has all of the volume.
+func (dc *DepthChart) CapLevels(maxLevels int) (err error) {
+	if maxLevels <= 0 {
+		err = fmt.Errorf("Max number of depth levels must be positive")
+		return
+	}
+
+	dc.Bids = capDepthPoints(dc.Bids, maxLevels)
+	dc.Asks = capDepthPoints(dc.Asks, maxLevels)
+	return
+}
+
+// capDepthPoints aggregates every point from maxLevels on into the last point. The volume is already
+// cumulative, so the aggregated point is just the last point.
+func capDepthPoints(points []DepthPoint, maxLevels int) (capped []DepthPoint) {
+	if len(points) <= maxLevels {
+		capped = points
+		return
+	}
+
+	capped = append(capped, points[:maxLevels-1]...)
+	lastPoint := points[len(points)-1]
+	lastPoint.Aggregated = true
+	capped = append(capped, lastPoint)
+	return
+}
@@ -0,0 +1,171 @@
+package match
+
+import (
+	"testing"
+)
+
+func TestComputeDepth(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+
+	// buy price is want / have, sell price is have / want. Volume is counted in AssetWant, which is
+	// AmountWant for buys and AmountHave for sells.
+	orders := []*AuctionOrder{
+		// buys at 2.0, 1.0, and another at 2.0
+		{TradingPair: pair, Side: "buy", AmountHave: 1000, AmountWant: 2000},
+		{TradingPair: pair, Side: "buy", AmountHave: 3000, AmountWant: 3000},
+		{TradingPair: pair, Side: "buy", AmountHave: 2000, AmountWant: 4000},
+		// sells at 0.5 and 2.0
+		{TradingPair: pair, Side: "sell", AmountHave: 8000, AmountWant: 4000},
+		{TradingPair: pair, Side: "sell", AmountHave: 1000, AmountWant: 2000},
+	}
+
+	var chart *DepthChart
+	if chart, err = ComputeDepth(pair, [32]byte{}, orders, 0); err != nil {
+		t.Errorf("Error computing depth: %s", err)
+		return
+	}
+
+	expectedBids := []DepthPoint{{Price: 2.0, Volume: 6000}, {Price: 1.0, Volume: 9000}}
+	if len(chart.Bids) != len(expectedBids) {
+		t.Errorf("Should have %d bid points, got %d", len(expectedBids), len(chart.Bids))
+		return
+	}
+	for i, point := range expectedBids {
+		if chart.Bids[i] != point {
+			t.Errorf("Bid point %d should be %v, got %v", i, point, chart.Bids[i])
+			return
+		}
+	}
+
+	expectedAsks := []DepthPoint{{Price: 0.5, Volume: 1000}, {Price: 2.0, Volume: 9000}}
+	if len(chart.Asks) != len(expectedAsks) {
+		t.Errorf("Should have %d ask points, got %d", len(expectedAsks), len(chart.Asks))
+		return
+	}
+	for i, point := range expectedAsks {
+		if chart.Asks[i] != point {
+			t.Errorf("Ask point %d should be %v, got %v", i, point, chart.Asks[i])
+			return
+		}
+	}
+
+	// Only the best level on each side
+	if chart, err = ComputeDepth(pair, [32]byte{}, orders, 1); err != nil {
+		t.Errorf("Error computing depth with 1 level: %s", err)
+		return
+	}
+
+	if len(chart.Bids) != 1 || chart.Bids[0] != expectedBids[0] || len(chart.Asks) != 1 || chart.Asks[0] != expectedAsks[0] {
+		t.Errorf("Depth with 1 level should only have the best bid and ask, got %v and %v", chart.Bids, chart.Asks)
+		return
+	}
+
+	// Orders from another pair don't belong
+	orders[0].TradingPair = Pair{AssetWant: LTCReg, AssetHave: BTCReg}
+	if _, err = ComputeDepth(pair, [32]byte{}, orders, 0); err == nil {
+		t.Errorf("Computing depth with an order from another pair should fail")
+		return
+	}
+
+	return
+}
+
+func TestDepthCapLevels(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+
+	// ten buys and ten sells at distinct prices, 1000 AssetWant each
+	var orders []*AuctionOrder
+	for i := uint64(1); i <= 10; i++ {
+		// buy price is want / have, so this buys at 10/i
+		orders = append(orders, &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 100 * i, AmountWant: 1000})
+		// sell price is have / want, so this sells at i/10
+		orders = append(orders, &AuctionOrder{TradingPair: pair, Side: "sell", AmountHave: 1000, AmountWant: 10000 / i})
+	}
+
+	var chart *DepthChart
+	if chart, err = ComputeDepth(pair, [32]byte{}, orders, 0); err != nil {
+		t.Errorf("Error computing depth: %s", err)
+		return
+	}
+
+	if len(chart.Bids) != 10 || len(chart.Asks) != 10 {
+		t.Errorf("Should have 10 bid and ask points before capping, got %d and %d", len(chart.Bids), len(chart.Asks))
+		return
+	}
+
+	uncappedBids := append([]DepthPoint{}, chart.Bids...)
+	uncappedAsks := append([]DepthPoint{}, chart.Asks...)
+
+	if err = chart.CapLevels(0); err == nil {
+		t.Errorf("Capping depth at 0 levels should fail")
+		return
+	}
+
+	// a cap above the number of levels changes nothing
+	if err = chart.CapLevels(10); err != nil {
+		t.Errorf("Error capping depth at 10 levels: %s", err)
+		return
+	}
+
+	if len(chart.Bids) != 10 || len(chart.Asks) != 10 || chart.Bids[9].Aggregated || chart.Asks[9].Aggregated {
+		t.Errorf("Capping depth at the number of levels should not change it, got %v and %v", chart.Bids, chart.Asks)
+		return
+	}
+
+	if err = chart.CapLevels(3); err != nil {
+		t.Errorf("Error capping depth at 3 levels: %s", err)
+		return
+	}
+
+	for _, side := range []struct {
+		name     string
+		capped   []DepthPoint
+		uncapped []DepthPoint
+	}{
+		{"bid", chart.Bids, uncappedBids},
+		{"ask", chart.Asks, uncappedAsks},
+	} {
+		if len(side.capped) != 3 {
+			t.Errorf("Should have 3 %s points after capping, got %d", side.name, len(side.capped))
+			return
+		}
+
+		// the best levels are kept as they were
+		for i := 0; i < 2; i++ {
+			if side.capped[i] != side.uncapped[i] {
+				t.Errorf("%s point %d should still be %v after capping, got %v", side.name, i, side.uncapped[i], side.capped[i])
+				return
+			}
+		}
+
+		// the rest are aggregated into the last point, at the worst price with all of the volume
+		expectedTail := DepthPoint{Price: side.uncapped[9].Price, Volume: 10000, Aggregated: true}
+		if side.capped[2] != expectedTail {
+			t.Errorf("Last %s point should be the aggregated tail %v, got %v", side.name, expectedTail, side.capped[2])
+			return
+		}
+	}
+
+	// capping at 1 level leaves only the aggregated point
+	if err = chart.CapLevels(1); err != nil {
+		t.Errorf("Error capping depth at 1 level: %s", err)
+		return
+	}
+
+	if len(chart.Bids) != 1 || !chart.Bids[0].Aggregated || chart.Bids[0].Volume != 10000 {
+		t.Errorf("Depth capped at 1 level should only have the aggregated bid point, got %v", chart.Bids)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,58 @@
+package match
+
+import (
+	"errors"
+	"fmt"
+)
+
+// These are the kinds of malformed input that deserializing an order can fail on. A peer sending
+// these is sending garbage, rather than running into a temporary failure.
+var (
+	// ErrTruncated means the input ended before all of the fields were read
+	ErrTruncated = errors.New("input is truncated")
+	// ErrSideTooLong means the side length is longer than the rest of the input
+	ErrSideTooLong = errors.New("side is too long")
+	// ErrSignatureTooLong means the signature length is longer than the rest of the input
+	ErrSignatureTooLong = errors.New("signature is too long")
+	// ErrBadTradingPair means the trading pair has an unknown asset, or the same asset on both sides
+	ErrBadTradingPair = errors.New("bad trading pair")
+	// ErrBadEncoding means the input couldn't be decoded at all
+	ErrBadEncoding = errors.New("bad encoding")
+	// ErrUnknownVersion means the input is in a serialization version we don't know how to read
+	ErrUnknownVersion = errors.New("unknown version")
+)
+
+// DeserializeError is returned when an order can't be deserialized because the input is malformed. Kind is
+// one of the malformed input errors, like ErrTruncated, so callers can check what was wrong with it.
+type DeserializeError struct {
+	Kind   error
+	Detail string
+}
+
+// Error returns the kind of malformed input along with the details
+func (e *DeserializeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Kind, e.Detail)
+}
+
+// Unwrap returns the kind of malformed input, so errors.Is works with the malformed input errors
+func (e *DeserializeError) Unwrap() error {
+	return e.Kind
+}
+
+// newDeserializeError creates a deserialize error of the given kind, with a formatted detail message
+func newDeserializeError(kind error, format string, args ...interface{}) (err error) {
+	err = &DeserializeError{
+		Kind:   kind,
+		Detail: fmt.Sprintf(format, args...),
+	}
+	return
+}
+
+// DeserializeErrorKind returns the kind of malformed input that err is from, or nil if err isn't a
+// deserialize error.
+func DeserializeErrorKind(err error) (kind error) {
+	if deserializeErr, ok := err.(*DeserializeError); ok {
+		kind = deserializeErr.Kind
+	}
+	return
+}
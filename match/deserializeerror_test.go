@@ -0,0 +1,163 @@
+package match
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// Offsets into the serialization of the golden auction order, which has a 3 byte side
+const (
+	testVersionOffset = 0
+	testPairOffset    = 34
+	testSideLenOffset = 52
+	testSigLenOffset  = 101
+)
+
+func TestAuctionOrderDeserializeErrors(t *testing.T) {
+	valid := goldenAuctionOrder().Serialize()
+
+	var tests = []struct {
+		name   string
+		tamper func(raw []byte) []byte
+		kind   error
+	}{
+		{"valid", func(raw []byte) []byte { return raw }, nil},
+		{"truncated", func(raw []byte) []byte { return raw[:50] }, ErrTruncated},
+		{"empty", func(raw []byte) []byte { return nil }, ErrTruncated},
+		{"truncated after side", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint64(raw[testSideLenOffset:], 74)
+			return raw
+		}, ErrTruncated},
+		{"side too long", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint64(raw[testSideLenOffset:], 1000)
+			return raw
+		}, ErrSideTooLong},
+		{"signature too long", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint64(raw[testSigLenOffset:], 1000)
+			return raw
+		}, ErrSignatureTooLong},
+		{"unknown asset", func(raw []byte) []byte {
+			raw[testPairOffset] = 0xff
+			return raw
+		}, ErrBadTradingPair},
+		{"same asset", func(raw []byte) []byte {
+			raw[testPairOffset+1] = raw[testPairOffset]
+			return raw
+		}, ErrBadTradingPair},
+		{"reserved version", func(raw []byte) []byte {
+			raw[testVersionOffset] = AuctionOrderVersion1
+			return raw
+		}, ErrUnknownVersion},
+		{"unknown version", func(raw []byte) []byte {
+			raw[testVersionOffset] = 0xff
+			return raw
+		}, ErrUnknownVersion},
+	}
+
+	for _, test := range tests {
+		raw := test.tamper(append([]byte{}, valid...))
+
+		err := new(AuctionOrder).Deserialize(raw)
+		if test.kind == nil {
+			if err != nil {
+				t.Errorf("Deserializing %s order should succeed, got %s", test.name, err)
+				return
+			}
+			continue
+		}
+
+		if _, ok := err.(*DeserializeError); !ok {
+			t.Errorf("Deserializing %s order should return a DeserializeError, got %v", test.name, err)
+			return
+		}
+
+		if kind := DeserializeErrorKind(err); kind != test.kind {
+			t.Errorf("Deserializing %s order returned kind %v, expected %v", test.name, kind, test.kind)
+			return
+		}
+	}
+
+	return
+}
+
+func TestEncryptedAuctionOrderDeserializeError(t *testing.T) {
+	err := new(EncryptedAuctionOrder).Deserialize([]byte{0x01, 0x02, 0x03})
+	if kind := DeserializeErrorKind(err); kind != ErrBadEncoding {
+		t.Errorf("Deserializing garbage encrypted order returned kind %v, expected %v", kind, ErrBadEncoding)
+		return
+	}
+
+	return
+}
+
+func TestDeserializeErrorKindOtherErrors(t *testing.T) {
+	if kind := DeserializeErrorKind(fmt.Errorf("some other error")); kind != nil {
+		t.Errorf("Errors that aren't deserialize errors should not have a kind, got %v", kind)
+		return
+	}
+
+	if kind := DeserializeErrorKind(nil); kind != nil {
+		t.Errorf("A nil error should not have a kind, got %v", kind)
+		return
+	}
+
+	return
+}
+
+func TestAuctionOrderDeserializeTruncatedAtEveryField(t *testing.T) {
+	valid := goldenAuctionOrder().Serialize()
+
+	// The golden order has a 3 byte side and a 65 byte signature
+	var boundaries = []struct {
+		field  string
+		offset int
+	}{
+		{"version", testVersionOffset},
+		{"pubkey", testVersionOffset + 1},
+		{"pair", testPairOffset},
+		{"amount have", testPairOffset + 2},
+		{"amount want", testPairOffset + 2 + 8},
+		{"side length", testSideLenOffset},
+		{"side", testSideLenOffset + 8},
+		{"auction ID", testSideLenOffset + 8 + 3},
+		{"nonce", testSideLenOffset + 8 + 3 + 32},
+		{"network", testSideLenOffset + 8 + 3 + 32 + 2},
+		{"signature length", testSigLenOffset},
+		{"signature", testSigLenOffset + 8},
+	}
+
+	for _, boundary := range boundaries {
+		// cut off right at the start of the field, and in the middle of it
+		for _, cut := range []int{boundary.offset, boundary.offset + 1} {
+			if kind := deserializeKindWithoutPanic(valid[:cut], t); kind != ErrTruncated && kind != ErrSignatureTooLong && kind != ErrSideTooLong {
+				t.Errorf("Order truncated at %d bytes, in the %s, should fail as truncated, got kind %v", cut, boundary.field, kind)
+				return
+			}
+		}
+	}
+
+	// Every other length should fail cleanly too, and never panic
+	for cut := 0; cut < len(valid); cut++ {
+		if kind := deserializeKindWithoutPanic(valid[:cut], t); kind == nil {
+			t.Errorf("Order truncated at %d of %d bytes should not deserialize", cut, len(valid))
+			return
+		}
+	}
+
+	return
+}
+
+// deserializeKindWithoutPanic deserializes raw into an auction order and returns the kind of deserialize
+// error, failing the test if deserializing panics
+func deserializeKindWithoutPanic(raw []byte, t *testing.T) (kind error) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Deserializing %d bytes panicked: %v", len(raw), r)
+			kind = fmt.Errorf("panicked")
+		}
+	}()
+
+	kind = DeserializeErrorKind(new(AuctionOrder).Deserialize(raw))
+	return
+}
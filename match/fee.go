@@ -0,0 +1,196 @@
+package match
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// MaxFeeRate is the largest fee rate, in basis points, that a fee schedule can have. This is the whole amount.
+const MaxFeeRate = 10000
+
+// FeeAsset is which side of a pair's settlement fees are taken out of. The base asset of a pair is
+// AssetWant, and the quote asset is AssetHave.
+type FeeAsset string
+
+const (
+	// FeeInReceived takes fees out of both sides, so everyone pays in the asset they receive. This is
+	// the default for pairs that aren't configured.
+	FeeInReceived FeeAsset = "received"
+	// FeeInBase only takes fees out of the base asset, which buyers receive
+	FeeInBase FeeAsset = "base"
+	// FeeInQuote only takes fees out of the quote asset, which sellers receive
+	FeeInQuote FeeAsset = "quote"
+)
+
+// String returns the name of the fee asset
+func (f FeeAsset) String() string {
+	return string(f)
+}
+
+// FeeAssetFromString returns the fee asset with the name feeAssetString
+func FeeAssetFromString(feeAssetString string) (feeAsset FeeAsset, err error) {
+	switch FeeAsset(feeAssetString) {
+	case FeeInReceived, FeeInBase, FeeInQuote:
+		feeAsset = FeeAsset(feeAssetString)
+	default:
+		err = fmt.Errorf("Unknown fee asset %s, must be %s, %s or %s", feeAssetString, FeeInReceived, FeeInBase, FeeInQuote)
+	}
+	return
+}
+
+// ParsePairFeeAsset parses a pair and fee asset from a string like "asset1/asset2:base". This is for
+// user input only, hence the slash.
+func ParsePairFeeAsset(feeAssetString string) (pair *Pair, feeAsset FeeAsset, err error) {
+	strSplit := strings.Split(feeAssetString, ":")
+	if len(strSplit) != 2 {
+		err = fmt.Errorf("Fee asset %s should look like asset1/asset2:base or asset1/asset2:quote", feeAssetString)
+		return
+	}
+
+	pair = new(Pair)
+	if err = pair.FromString(strSplit[0]); err != nil {
+		err = fmt.Errorf("Error parsing pair for fee asset: %s", err)
+		return
+	}
+
+	if feeAsset, err = FeeAssetFromString(strSplit[1]); err != nil {
+		return
+	}
+
+	return
+}
+
+// FeeSchedule is the fee that is taken out of amounts credited during settlement, and the account
+// the fees go to. A fee schedule should not be modified once it's being used for settlement, use
+// WithPairFeeAsset to make a new one instead.
+type FeeSchedule struct {
+	// Rate is the fee in basis points (hundredths of a percent) of every credited amount
+	Rate uint64
+	// Account is the pubkey of the account that collects the fees
+	Account [33]byte
+	// PairFeeAssets is which asset fees are taken in for each pair, by pair string. Pairs that
+	// aren't in here take fees in the asset received.
+	PairFeeAssets map[string]FeeAsset
+}
+
+// NewFeeSchedule creates a fee schedule that sends rate basis points of every credited amount to account
+func NewFeeSchedule(rate uint64, account *koblitz.PublicKey) (schedule *FeeSchedule, err error) {
+	if rate > MaxFeeRate {
+		err = fmt.Errorf("Error creating fee schedule, rate of %d basis points is more than %d", rate, MaxFeeRate)
+		return
+	}
+
+	if account == nil {
+		err = fmt.Errorf("Error creating fee schedule, fee account cannot be nil")
+		return
+	}
+
+	schedule = &FeeSchedule{Rate: rate}
+	copy(schedule.Account[:], account.SerializeCompressed())
+	return
+}
+
+// WithPairFeeAsset returns a copy of the fee schedule that takes fees for pair in feeAsset
+func (fs *FeeSchedule) WithPairFeeAsset(pair *Pair, feeAsset FeeAsset) (schedule *FeeSchedule, err error) {
+	if _, err = FeeAssetFromString(feeAsset.String()); err != nil {
+		return
+	}
+
+	schedule = &FeeSchedule{
+		Rate:          fs.Rate,
+		Account:       fs.Account,
+		PairFeeAssets: make(map[string]FeeAsset),
+	}
+	for pairString, pairFeeAsset := range fs.PairFeeAssets {
+		schedule.PairFeeAssets[pairString] = pairFeeAsset
+	}
+	schedule.PairFeeAssets[pair.String()] = feeAsset
+	return
+}
+
+// PairFeeAsset returns which asset fees are taken in for pair
+func (fs *FeeSchedule) PairFeeAsset(pair *Pair) (feeAsset FeeAsset) {
+	var found bool
+	if fs == nil {
+		feeAsset = FeeInReceived
+	} else if feeAsset, found = fs.PairFeeAssets[pair.String()]; !found {
+		feeAsset = FeeInReceived
+	}
+	return
+}
+
+// AccountPubkey parses the pubkey of the fee account
+func (fs *FeeSchedule) AccountPubkey() (pubkey *koblitz.PublicKey, err error) {
+	if pubkey, err = koblitz.ParsePubKey(fs.Account[:], koblitz.S256()); err != nil {
+		err = fmt.Errorf("Error parsing fee account pubkey: %s", err)
+		return
+	}
+	return
+}
+
+// Split splits amount into what the trader is credited and the fee. The fee is rounded down, so
+// credited + fee is always amount. A nil schedule takes no fees.
+func (fs *FeeSchedule) Split(amount uint64) (credited uint64, fee uint64) {
+	if fs == nil {
+		credited = amount
+		return
+	}
+
+	// split it up so amount * rate can't overflow
+	fee = (amount/MaxFeeRate)*fs.Rate + ((amount%MaxFeeRate)*fs.Rate)/MaxFeeRate
+	credited = amount - fee
+	return
+}
+
+// Credit credits pubkey with amount minus the fee, and credits the fee to the fee account. credit is
+// what actually changes balances, so settlement can do it however it needs to.
+func (fs *FeeSchedule) Credit(pubkey *koblitz.PublicKey, amount uint64, credit func(pubkey *koblitz.PublicKey, amount uint64) error) (err error) {
+	credited, fee := fs.Split(amount)
+	if err = credit(pubkey, credited); err != nil {
+		err = fmt.Errorf("Error crediting settled amount: %s", err)
+		return
+	}
+
+	if fee == 0 {
+		return
+	}
+
+	var feePubkey *koblitz.PublicKey
+	if feePubkey, err = fs.AccountPubkey(); err != nil {
+		return
+	}
+
+	if err = credit(feePubkey, fee); err != nil {
+		err = fmt.Errorf("Error crediting fee to fee account: %s", err)
+		return
+	}
+
+	return
+}
+
+// CreditPairAsset credits pubkey with amount of asset, which is one side of a settlement in pair. Fees
+// are only taken out if the pair takes fees in that asset, otherwise the whole amount is credited.
+func (fs *FeeSchedule) CreditPairAsset(pair *Pair, asset Asset, pubkey *koblitz.PublicKey, amount uint64, credit func(pubkey *koblitz.PublicKey, amount uint64) error) (err error) {
+	if asset != pair.AssetWant && asset != pair.AssetHave {
+		err = fmt.Errorf("Error crediting settled amount, %s is not in pair %s", asset, pair)
+		return
+	}
+
+	switch fs.PairFeeAsset(pair) {
+	case FeeInBase:
+		if asset != pair.AssetWant {
+			err = credit(pubkey, amount)
+			return
+		}
+	case FeeInQuote:
+		if asset != pair.AssetHave {
+			err = credit(pubkey, amount)
+			return
+		}
+	}
+
+	err = fs.Credit(pubkey, amount, credit)
+	return
+}
@@ -0,0 +1,191 @@
+package match
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+func TestNewFeeScheduleRejectsBadRates(t *testing.T) {
+	var err error
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating fee account key: %s", err)
+		return
+	}
+
+	if _, err = NewFeeSchedule(MaxFeeRate+1, privkey.PubKey()); err == nil {
+		t.Errorf("Fee schedule with a rate over %d basis points should not be created", MaxFeeRate)
+		return
+	}
+
+	if _, err = NewFeeSchedule(10, nil); err == nil {
+		t.Errorf("Fee schedule without a fee account should not be created")
+		return
+	}
+
+	var schedule *FeeSchedule
+	if schedule, err = NewFeeSchedule(MaxFeeRate, privkey.PubKey()); err != nil {
+		t.Errorf("Error creating fee schedule with the max rate: %s", err)
+		return
+	}
+
+	var feePubkey *koblitz.PublicKey
+	if feePubkey, err = schedule.AccountPubkey(); err != nil {
+		t.Errorf("Error parsing fee account: %s", err)
+		return
+	}
+
+	if !feePubkey.IsEqual(privkey.PubKey()) {
+		t.Errorf("Fee account pubkey does not match the one the schedule was created with")
+		return
+	}
+
+	return
+}
+
+func TestFeeScheduleSplit(t *testing.T) {
+	var splitTests = []struct {
+		rate     uint64
+		amount   uint64
+		credited uint64
+		fee      uint64
+	}{
+		{0, 1000, 1000, 0},
+		{25, 1000000, 997500, 2500},
+		// fees are rounded down
+		{25, 399, 399, 0},
+		{25, 401, 400, 1},
+		{MaxFeeRate, 1234, 0, 1234},
+		// amount * rate would overflow
+		{MaxFeeRate, math.MaxUint64, 0, math.MaxUint64},
+		{5000, math.MaxUint64, math.MaxUint64 - math.MaxUint64/2, math.MaxUint64 / 2},
+	}
+
+	for _, test := range splitTests {
+		schedule := &FeeSchedule{Rate: test.rate}
+		credited, fee := schedule.Split(test.amount)
+		if credited != test.credited || fee != test.fee {
+			t.Errorf("Splitting %d at %d basis points should credit %d with fee %d, got %d and %d", test.amount, test.rate, test.credited, test.fee, credited, fee)
+			return
+		}
+	}
+
+	// no schedule means no fees
+	var noFees *FeeSchedule
+	if credited, fee := noFees.Split(1000); credited != 1000 || fee != 0 {
+		t.Errorf("A nil fee schedule should not take fees, got credited %d and fee %d", credited, fee)
+		return
+	}
+
+	return
+}
+
+// settleFill credits both sides of a fill in pair using schedule, and returns the balances by pubkey and asset
+func settleFill(schedule *FeeSchedule, pair *Pair, buyer *koblitz.PublicKey, baseAmount uint64, seller *koblitz.PublicKey, quoteAmount uint64) (balances map[string]uint64, err error) {
+	balances = make(map[string]uint64)
+	creditAsset := func(asset Asset) func(*koblitz.PublicKey, uint64) error {
+		return func(pubkey *koblitz.PublicKey, amount uint64) error {
+			balances[fmt.Sprintf("%x%s", pubkey.SerializeCompressed(), asset)] += amount
+			return nil
+		}
+	}
+
+	// buyers get the base asset, sellers get the quote asset
+	if err = schedule.CreditPairAsset(pair, pair.AssetWant, buyer, baseAmount, creditAsset(pair.AssetWant)); err != nil {
+		return
+	}
+
+	if err = schedule.CreditPairAsset(pair, pair.AssetHave, seller, quoteAmount, creditAsset(pair.AssetHave)); err != nil {
+		return
+	}
+
+	return
+}
+
+func TestFeesChargedInBaseAndQuote(t *testing.T) {
+	var err error
+	var keys []*koblitz.PublicKey
+	for i := 0; i < 3; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+		keys = append(keys, privkey.PubKey())
+	}
+	feeAccount, buyer, seller := keys[0], keys[1], keys[2]
+
+	var schedule *FeeSchedule
+	if schedule, err = NewFeeSchedule(100, feeAccount); err != nil {
+		t.Errorf("Error creating fee schedule: %s", err)
+		return
+	}
+
+	pair := &Pair{AssetWant: BTCTest, AssetHave: LTCTest}
+	balanceKey := func(pubkey *koblitz.PublicKey, asset Asset) string {
+		return fmt.Sprintf("%x%s", pubkey.SerializeCompressed(), asset)
+	}
+
+	var feeAssetTests = []struct {
+		feeAsset    FeeAsset
+		buyerBase   uint64
+		sellerQuote uint64
+		feeBase     uint64
+		feeQuote    uint64
+	}{
+		// 1% of 10000 base and 1% of 50000 quote
+		{FeeInReceived, 9900, 49500, 100, 500},
+		{FeeInBase, 9900, 50000, 100, 0},
+		{FeeInQuote, 10000, 49500, 0, 500},
+	}
+
+	for _, test := range feeAssetTests {
+		var pairSchedule *FeeSchedule
+		if pairSchedule, err = schedule.WithPairFeeAsset(pair, test.feeAsset); err != nil {
+			t.Errorf("Error setting fee asset %s: %s", test.feeAsset, err)
+			return
+		}
+
+		if pairSchedule.PairFeeAsset(pair) != test.feeAsset {
+			t.Errorf("Pair should take fees in %s, got %s", test.feeAsset, pairSchedule.PairFeeAsset(pair))
+			return
+		}
+
+		var balances map[string]uint64
+		if balances, err = settleFill(pairSchedule, pair, buyer, 10000, seller, 50000); err != nil {
+			t.Errorf("Error settling fill with fees in %s: %s", test.feeAsset, err)
+			return
+		}
+
+		if balances[balanceKey(buyer, pair.AssetWant)] != test.buyerBase || balances[balanceKey(seller, pair.AssetHave)] != test.sellerQuote {
+			t.Errorf("With fees in %s the buyer should get %d base and the seller %d quote, got %d and %d", test.feeAsset, test.buyerBase, test.sellerQuote, balances[balanceKey(buyer, pair.AssetWant)], balances[balanceKey(seller, pair.AssetHave)])
+			return
+		}
+
+		if balances[balanceKey(feeAccount, pair.AssetWant)] != test.feeBase || balances[balanceKey(feeAccount, pair.AssetHave)] != test.feeQuote {
+			t.Errorf("With fees in %s the fee account should get %d base and %d quote, got %d and %d", test.feeAsset, test.feeBase, test.feeQuote, balances[balanceKey(feeAccount, pair.AssetWant)], balances[balanceKey(feeAccount, pair.AssetHave)])
+			return
+		}
+	}
+
+	// the original schedule isn't changed, and other pairs still take fees in what they receive
+	if schedule.PairFeeAsset(pair) != FeeInReceived {
+		t.Errorf("Setting a pair fee asset should not change the original schedule")
+		return
+	}
+
+	if _, err = settleFill(schedule, &Pair{AssetWant: BTCTest, AssetHave: VTCTest}, buyer, 100, seller, 100); err != nil {
+		t.Errorf("Error settling fill in a pair without a fee asset: %s", err)
+		return
+	}
+
+	if err = schedule.CreditPairAsset(pair, VTCTest, buyer, 100, func(*koblitz.PublicKey, uint64) error { return nil }); err == nil {
+		t.Errorf("Crediting an asset that isn't in the pair should fail")
+		return
+	}
+
+	return
+}
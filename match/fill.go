@@ -0,0 +1,140 @@
+package match
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// AuctionFill is how much of an order filled in an auction. Amounts are in the pair's AssetWant, like the
+// volumes in AllocateProRata: what a buy wants, and what a sell has.
+type AuctionFill struct {
+	AuctionID [32]byte `json:"auctionid"`
+	// OrderHash is the hash of the order that filled, from Hash
+	OrderHash [32]byte `json:"orderhash"`
+	Pubkey    [33]byte `json:"pubkey"`
+	Pair      Pair     `json:"pair"`
+	Side      string   `json:"side"`
+	// ClearingPrice is the exact price the order's pair cleared at. It's nil if the book didn't cross.
+	ClearingPrice *big.Rat `json:"clearingprice"`
+	// AmountOrdered is how much the order could have filled, and AmountFilled is how much it did
+	AmountOrdered uint64 `json:"amountordered"`
+	AmountFilled  uint64 `json:"amountfilled"`
+}
+
+// AuctionClearing is the result of clearing a pair's auction. It's stored along with the auction's fills, so an
+// auction that cleared without anything matching can be told apart from one that hasn't been cleared.
+type AuctionClearing struct {
+	AuctionID [32]byte `json:"auctionid"`
+	Pair      Pair     `json:"pair"`
+	// ClearingPrice is the exact price the pair cleared at. It's nil if nothing matched.
+	ClearingPrice *big.Rat `json:"clearingprice"`
+	// NoMatch is true if the book didn't cross, so nothing matched and the clearing price is undefined
+	NoMatch bool `json:"nomatch"`
+}
+
+// String returns a summary of the clearing, to be logged
+func (ac *AuctionClearing) String() string {
+	if ac.NoMatch {
+		return fmt.Sprintf("%s no match, clearing price undefined", ac.Pair.PrettyString())
+	}
+	return fmt.Sprintf("%s at clearing price %s", ac.Pair.PrettyString(), ac.ClearingPrice.RatString())
+}
+
+// Partial returns whether the order filled, but not completely
+func (af *AuctionFill) Partial() bool {
+	return af.AmountFilled > 0 && af.AmountFilled < af.AmountOrdered
+}
+
+// ClearingPriceFloat returns the clearing price as a float, for display only. It's zero if the book didn't
+// cross.
+func (af *AuctionFill) ClearingPriceFloat() (price float64) {
+	if af.ClearingPrice == nil {
+		return
+	}
+	price, _ = af.ClearingPrice.Float64()
+	return
+}
+
+// String returns a summary of the fill, to be logged
+func (af *AuctionFill) String() string {
+	return fmt.Sprintf("%s %s order %x filled %d of %d at clearing price %f", af.Pair.PrettyString(), af.Side, af.OrderHash, af.AmountFilled, af.AmountOrdered, af.ClearingPriceFloat())
+}
+
+// ComputeAuctionFills clears a pair's auction and allocates fills to its orders, with ComputeClearingPrice
+// and AllocateProRata. There is a fill for every order, in the order they were given, including the orders
+// that don't fill at all. If the book doesn't cross, nothing fills and the clearing price is nil.
+func ComputeAuctionFills(auctionID [32]byte, orders []*AuctionOrder) (fills []*AuctionFill, clearingPrice *big.Rat, err error) {
+	if len(orders) == 0 {
+		return
+	}
+
+	var matched []*AuctionOrder
+	if clearingPrice, matched, err = ComputeClearingPrice(orders); err != nil {
+		err = fmt.Errorf("Error computing clearing price for fills: %s", err)
+		return
+	}
+
+	var filled map[[32]byte]uint64
+	if clearingPrice != nil {
+		if filled, err = AllocateProRata(matched, clearingPrice); err != nil {
+			err = fmt.Errorf("Error allocating fills: %s", err)
+			return
+		}
+	}
+
+	for _, order := range orders {
+		fill := &AuctionFill{
+			AuctionID:     auctionID,
+			OrderHash:     order.Hash(),
+			Pubkey:        order.Pubkey,
+			Pair:          order.TradingPair,
+			Side:          order.Side,
+			AmountOrdered: order.AmountHave,
+		}
+		if clearingPrice != nil {
+			fill.ClearingPrice = new(big.Rat).Set(clearingPrice)
+		}
+		if order.IsBuySide() {
+			fill.AmountOrdered = order.AmountWant
+		}
+		fill.AmountFilled = filled[fill.OrderHash]
+		fills = append(fills, fill)
+	}
+
+	return
+}
+
+// CheckFillsBalance returns an error if, for any pair, the buy orders didn't fill the same amount as the sell
+// orders. Fills are in AssetWant, so what buyers get has to be exactly what sellers give.
+func CheckFillsBalance(fills []*AuctionFill) (err error) {
+	buyFilled := make(map[Pair]uint64)
+	sellFilled := make(map[Pair]uint64)
+	var pairs []Pair
+	for _, fill := range fills {
+		if _, found := buyFilled[fill.Pair]; !found {
+			buyFilled[fill.Pair] = 0
+			sellFilled[fill.Pair] = 0
+			pairs = append(pairs, fill.Pair)
+		}
+
+		filled := sellFilled
+		if fill.Side == "buy" {
+			filled = buyFilled
+		}
+		if filled[fill.Pair] > math.MaxUint64-fill.AmountFilled {
+			err = fmt.Errorf("Total %s fills of %s overflow", fill.Side, fill.Pair.PrettyString())
+			return
+		}
+		filled[fill.Pair] += fill.AmountFilled
+	}
+
+	for _, pair := range pairs {
+		if buyFilled[pair] != sellFilled[pair] {
+			err = fmt.Errorf("Fills of %s do not balance, buys filled %d but sells filled %d", pair.PrettyString(), buyFilled[pair], sellFilled[pair])
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,101 @@
+package match
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestComputeAuctionFills(t *testing.T) {
+	var err error
+
+	// 100 buy volume and 70 sell volume at 2, so the buys at 2 fill partially and the buy at 1 doesn't fill
+	orders := []*AuctionOrder{
+		proRataTestOrder("buy", 15, 30, 0),
+		proRataTestOrder("sell", 70, 35, 1),
+		proRataTestOrder("buy", 15, 30, 2),
+		proRataTestOrder("buy", 10, 10, 3),
+		proRataTestOrder("buy", 20, 40, 4),
+	}
+
+	var auctionID [32]byte
+	auctionID[0] = 0x01
+
+	var fills []*AuctionFill
+	var clearingPrice *big.Rat
+	if fills, clearingPrice, err = ComputeAuctionFills(auctionID, orders); err != nil {
+		t.Errorf("Error computing auction fills: %s", err)
+		return
+	}
+
+	if clearingPrice == nil || clearingPrice.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("Clearing price should be exactly 2, got %v", clearingPrice)
+		return
+	}
+
+	if len(fills) != len(orders) {
+		t.Errorf("There should be a fill for every order, expected %d got %d", len(orders), len(fills))
+		return
+	}
+
+	expected := []uint64{21, 70, 21, 0, 28}
+	for i, fill := range fills {
+		if fill.OrderHash != orders[i].Hash() || fill.AuctionID != auctionID || fill.ClearingPrice.Cmp(clearingPrice) != 0 {
+			t.Errorf("Fill %d is for the wrong order: %s", i, fill)
+			return
+		}
+
+		if fill.AmountFilled != expected[i] {
+			t.Errorf("Order %d should have filled %d, got %d", i, expected[i], fill.AmountFilled)
+			return
+		}
+	}
+
+	if !fills[0].Partial() || fills[1].Partial() || fills[3].Partial() {
+		t.Errorf("Only the buys at the clearing price should be partial fills")
+		return
+	}
+
+	// A book that doesn't cross has no clearing price, and nothing fills
+	if fills, clearingPrice, err = ComputeAuctionFills(auctionID, orders[2:4]); err != nil {
+		t.Errorf("Error computing auction fills for book that doesn't cross: %s", err)
+		return
+	}
+
+	if clearingPrice != nil || len(fills) != 2 || fills[0].AmountFilled != 0 || fills[1].AmountFilled != 0 || fills[0].ClearingPrice != nil {
+		t.Errorf("Nothing should fill if the book doesn't cross")
+		return
+	}
+
+	return
+}
+
+func TestCheckFillsBalance(t *testing.T) {
+	var err error
+
+	orders := []*AuctionOrder{
+		proRataTestOrder("buy", 15, 30, 0),
+		proRataTestOrder("sell", 70, 35, 1),
+		proRataTestOrder("buy", 15, 30, 2),
+		proRataTestOrder("buy", 20, 40, 3),
+	}
+
+	var fills []*AuctionFill
+	if fills, _, err = ComputeAuctionFills([32]byte{0x01}, orders); err != nil {
+		t.Errorf("Error computing auction fills: %s", err)
+		return
+	}
+
+	if err = CheckFillsBalance(fills); err != nil {
+		t.Errorf("Computed fills should balance: %s", err)
+		return
+	}
+
+	// A buy filling more than the sells gave is an accounting bug
+	fills[0].AmountFilled++
+	if err = CheckFillsBalance(fills); err == nil {
+		t.Errorf("Fills where buys get more than sells give should fail the self-check")
+		return
+	}
+
+	return
+}
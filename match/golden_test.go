@@ -0,0 +1,130 @@
+package match
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mit-dci/opencx/crypto/rsw"
+)
+
+// If the wire format is changed on purpose, run go test with -update to rewrite the golden files
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenAuctionOrder creates the fixed auction order whose serialization is in the golden file
+func goldenAuctionOrder() (order *AuctionOrder) {
+	order = &AuctionOrder{
+		Side: "buy",
+		TradingPair: Pair{
+			AssetWant: BTCTest,
+			AssetHave: VTCTest,
+		},
+		AmountHave: 100000000,
+		AmountWant: 50000000,
+		Nonce:      [2]byte{0x01, 0x02},
+		Network:    TestnetMagic,
+		Signature:  make([]byte, 65),
+	}
+	for i := range order.Pubkey {
+		order.Pubkey[i] = byte(i)
+	}
+	for i := range order.AuctionID {
+		order.AuctionID[i] = byte(0xa0 + i)
+	}
+	for i := range order.Signature {
+		order.Signature[i] = byte(0xff - i)
+	}
+	return
+}
+
+// goldenEncryptedAuctionOrder creates the fixed encrypted auction order whose serialization is in the golden file.
+// The puzzle is tiny since it never gets solved, we only care about how it's encoded.
+func goldenEncryptedAuctionOrder() (encOrder *EncryptedAuctionOrder) {
+	encOrder = &EncryptedAuctionOrder{
+		OrderCiphertext: make([]byte, 48),
+		OrderPuzzle: &rsw.PuzzleRSW{
+			N:  big.NewInt(3233),
+			A:  big.NewInt(2),
+			T:  big.NewInt(1000),
+			CK: big.NewInt(1234),
+		},
+		IntendedAuction: goldenAuctionOrder().AuctionID,
+		CipherType:      CipherRC6,
+	}
+	for i := range encOrder.OrderCiphertext {
+		encOrder.OrderCiphertext[i] = byte(i * 3)
+	}
+	return
+}
+
+// checkGolden compares raw against the hex encoded golden file with the given name, or rewrites the golden
+// file if -update is set.
+func checkGolden(name string, raw []byte, t *testing.T) {
+	goldenPath := filepath.Join("testdata", name+".golden")
+
+	if *updateGolden {
+		if err := ioutil.WriteFile(goldenPath, []byte(hex.EncodeToString(raw)+"\n"), 0644); err != nil {
+			t.Errorf("Error updating golden file %s: %s", goldenPath, err)
+		}
+		return
+	}
+
+	goldenHex, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Errorf("Error reading golden file %s: %s", goldenPath, err)
+		return
+	}
+
+	var golden []byte
+	if golden, err = hex.DecodeString(strings.TrimSpace(string(goldenHex))); err != nil {
+		t.Errorf("Error decoding golden file %s: %s", goldenPath, err)
+		return
+	}
+
+	if !bytes.Equal(raw, golden) {
+		t.Errorf("Serialization of %s does not match %s, the wire format changed. If this is on purpose, rerun with -update.\ngot:  %x\nwant: %x", name, goldenPath, raw, golden)
+		return
+	}
+
+	return
+}
+
+func TestAuctionOrderGolden(t *testing.T) {
+	order := goldenAuctionOrder()
+	checkGolden("auctionorder", order.Serialize(), t)
+
+	if *updateGolden {
+		return
+	}
+
+	// The golden bytes should also still deserialize into the same order
+	decOrder := new(AuctionOrder)
+	if err := decOrder.Deserialize(order.Serialize()); err != nil {
+		t.Errorf("Error deserializing golden auction order: %s", err)
+		return
+	}
+
+	if !bytes.Equal(decOrder.Serialize(), order.Serialize()) {
+		t.Errorf("Golden auction order did not survive a serialization round trip")
+		return
+	}
+
+	return
+}
+
+func TestEncryptedAuctionOrderGolden(t *testing.T) {
+	var err error
+	var raw []byte
+	if raw, err = goldenEncryptedAuctionOrder().Serialize(); err != nil {
+		t.Errorf("Error serializing golden encrypted auction order: %s", err)
+		return
+	}
+
+	checkGolden("encryptedauctionorder", raw, t)
+	return
+}
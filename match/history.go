@@ -0,0 +1,63 @@
+package match
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// HistoricalOrderVersion is the version of the historical order export format. It changes whenever the
+// format does, so backtests can tell old exports apart.
+const HistoricalOrderVersion = 1
+
+// HistoricalOrder is a decrypted order from an auction that has closed, along with how much it filled, for
+// backtesting matching algorithms. Exports are newline delimited JSON, one historical order per line.
+type HistoricalOrder struct {
+	Version   uint8    `json:"version"`
+	AuctionID [32]byte `json:"auctionid"`
+	// ClosedAtUnix is when the order's auction closed, in unix nanoseconds
+	ClosedAtUnix int64         `json:"closedatunix"`
+	Order        *AuctionOrder `json:"order"`
+	// Fill is how much of the order filled, it's nil if the auction hasn't been cleared
+	Fill *AuctionFill `json:"fill,omitempty"`
+}
+
+// WriteHistoricalOrders writes historical orders to w as newline delimited JSON, in the order they're given
+func WriteHistoricalOrders(w io.Writer, orders []*HistoricalOrder) (err error) {
+	encoder := json.NewEncoder(w)
+	for i, order := range orders {
+		if err = encoder.Encode(order); err != nil {
+			err = fmt.Errorf("Error writing historical order %d: %s", i, err)
+			return
+		}
+	}
+	return
+}
+
+// ReadHistoricalOrders reads newline delimited JSON historical orders from r until it ends. Every order has
+// to be in the current version of the format.
+func ReadHistoricalOrders(r io.Reader) (orders []*HistoricalOrder, err error) {
+	decoder := json.NewDecoder(r)
+	for {
+		order := new(HistoricalOrder)
+		if err = decoder.Decode(order); err == io.EOF {
+			err = nil
+			return
+		} else if err != nil {
+			err = fmt.Errorf("Error reading historical order %d: %s", len(orders), err)
+			return
+		}
+
+		if order.Version != HistoricalOrderVersion {
+			err = fmt.Errorf("Historical order %d has version %d, only version %d can be read", len(orders), order.Version, HistoricalOrderVersion)
+			return
+		}
+
+		if order.Order == nil {
+			err = fmt.Errorf("Historical order %d has no order", len(orders))
+			return
+		}
+
+		orders = append(orders, order)
+	}
+}
@@ -0,0 +1,76 @@
+package match
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistoricalOrdersRoundTrip(t *testing.T) {
+	var err error
+
+	order := goldenAuctionOrder()
+	orders := []*HistoricalOrder{
+		{
+			Version:      HistoricalOrderVersion,
+			AuctionID:    order.AuctionID,
+			ClosedAtUnix: 1234,
+			Order:        order,
+			Fill: &AuctionFill{
+				AuctionID:     order.AuctionID,
+				OrderHash:     order.Hash(),
+				Pubkey:        order.Pubkey,
+				Pair:          order.TradingPair,
+				Side:          order.Side,
+				ClearingPrice: big.NewRat(3, 2),
+				AmountOrdered: 100,
+				AmountFilled:  50,
+			},
+		},
+		{
+			Version:      HistoricalOrderVersion,
+			AuctionID:    order.AuctionID,
+			ClosedAtUnix: 1234,
+			Order:        order.Clone(),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err = WriteHistoricalOrders(&buf, orders); err != nil {
+		t.Errorf("Error writing historical orders: %s", err)
+		return
+	}
+
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != len(orders) {
+		t.Errorf("Each historical order should be on its own line, expected %d lines got %d", len(orders), len(lines))
+		return
+	}
+
+	var readOrders []*HistoricalOrder
+	if readOrders, err = ReadHistoricalOrders(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Errorf("Error reading historical orders: %s", err)
+		return
+	}
+
+	if !reflect.DeepEqual(readOrders, orders) {
+		t.Errorf("Historical orders did not survive a round trip")
+		return
+	}
+
+	// Exports in another version can't be read
+	orders[0].Version = HistoricalOrderVersion + 1
+	buf.Reset()
+	if err = WriteHistoricalOrders(&buf, orders); err != nil {
+		t.Errorf("Error writing historical orders: %s", err)
+		return
+	}
+
+	if _, err = ReadHistoricalOrders(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Errorf("Historical orders in another version should not be read")
+		return
+	}
+
+	return
+}
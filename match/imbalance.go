@@ -0,0 +1,105 @@
+package match
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// AuctionImbalance is how lopsided the buy and sell volume in an auction was at the clearing price.
+// Volumes are in units of the pair's AssetWant, so the buy and sell side can be compared. A batch
+// that's very one-sided can be a sign that someone is trying to push the clearing price around.
+type AuctionImbalance struct {
+	Pair          Pair    `json:"pair"`
+	ClearingPrice float64 `json:"clearingprice"`
+	// BuyVolume is the volume of buy orders willing to trade at the clearing price
+	BuyVolume uint64 `json:"buyvolume"`
+	// SellVolume is the volume of sell orders willing to trade at the clearing price
+	SellVolume uint64 `json:"sellvolume"`
+	// ClearedAtUnix is when the auction cleared, in unix seconds, so clients can tell how stale this is.
+	// It's zero until the clearing is recorded.
+	ClearedAtUnix int64 `json:"clearedatunix"`
+	// NoMatch is true if the book didn't cross, so nothing matched and there is no clearing price. This
+	// is so an auction with no matches can't be mistaken for one whose results are missing. The volumes
+	// are then the whole volume on each side.
+	NoMatch bool `json:"nomatch"`
+}
+
+// Imbalance is the difference between buy and sell volume as a fraction of the total volume. It's
+// between -1 (only sells) and 1 (only buys), and 0 if the sides are balanced or there's no volume.
+func (ai *AuctionImbalance) Imbalance() (imbalance float64) {
+	total := ai.BuyVolume + ai.SellVolume
+	if total == 0 {
+		return
+	}
+	imbalance = (float64(ai.BuyVolume) - float64(ai.SellVolume)) / float64(total)
+	return
+}
+
+// String returns a summary of the imbalance, to be logged
+func (ai *AuctionImbalance) String() string {
+	if ai.NoMatch {
+		return fmt.Sprintf("%s no match, clearing price undefined: %d buy volume, %d sell volume, imbalance %f", ai.Pair.PrettyString(), ai.BuyVolume, ai.SellVolume, ai.Imbalance())
+	}
+	return fmt.Sprintf("%s at clearing price %f: %d buy volume, %d sell volume, imbalance %f", ai.Pair.PrettyString(), ai.ClearingPrice, ai.BuyVolume, ai.SellVolume, ai.Imbalance())
+}
+
+// ComputeImbalance computes the buy and sell volume imbalance for the orders in a pair's auction at the
+// clearing price. Buy orders count if their price is at or above the clearing price, sell orders if their
+// price is at or below it. A clearing price of zero means the auction didn't clear, which is only allowed
+// if the book doesn't cross. The result is then a no match result, where every order counts.
+func ComputeImbalance(pair Pair, orders []*AuctionOrder, clearingPrice float64) (imbalance *AuctionImbalance, err error) {
+	if clearingPrice < 0 {
+		err = fmt.Errorf("Clearing price cannot be negative to compute imbalance")
+		return
+	}
+
+	imbalance = &AuctionImbalance{
+		Pair:          pair,
+		ClearingPrice: clearingPrice,
+		NoMatch:       clearingPrice == 0,
+	}
+
+	// prices are compared exactly, so every node agrees on which orders count
+	var clearing *big.Rat
+	if clearing, err = ratFromPrice(clearingPrice); err != nil {
+		err = fmt.Errorf("Error getting exact clearing price for imbalance: %s", err)
+		return
+	}
+
+	// the highest buy price and lowest sell price, to check that a book without a clearing price doesn't cross
+	var bestBuy, bestSell *big.Rat
+
+	for _, order := range orders {
+		if order.TradingPair != pair {
+			err = fmt.Errorf("Order for %s can't be part of the imbalance for %s", order.TradingPair.PrettyString(), pair.PrettyString())
+			return
+		}
+
+		var price *big.Rat
+		if price, err = order.PriceRational(); err != nil {
+			err = fmt.Errorf("Error getting order price for imbalance: %s", err)
+			return
+		}
+
+		if order.IsBuySide() && (bestBuy == nil || price.Cmp(bestBuy) > 0) {
+			bestBuy = price
+		} else if order.IsSellSide() && (bestSell == nil || price.Cmp(bestSell) < 0) {
+			bestSell = price
+		}
+
+		// buy orders want AssetWant, sell orders have AssetWant
+		if order.IsBuySide() && (imbalance.NoMatch || price.Cmp(clearing) >= 0) {
+			imbalance.BuyVolume += order.AmountWant
+		} else if order.IsSellSide() && (imbalance.NoMatch || price.Cmp(clearing) <= 0) {
+			imbalance.SellVolume += order.AmountHave
+		}
+	}
+
+	if imbalance.NoMatch && bestBuy != nil && bestSell != nil && bestBuy.Cmp(bestSell) >= 0 {
+		err = fmt.Errorf("Book for %s crosses at buy price %s and sell price %s, it can't have no clearing price", pair.PrettyString(), bestBuy.RatString(), bestSell.RatString())
+		imbalance = nil
+		return
+	}
+
+	return
+}
@@ -0,0 +1,132 @@
+package match
+
+import (
+	"testing"
+)
+
+func TestComputeImbalanceSkewedBatch(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+
+	// buy price is want / have, sell price is have / want. Volume is counted in AssetWant, which is
+	// AmountWant for buys and AmountHave for sells.
+	orders := []*AuctionOrder{
+		// buys at or above 1.0, these count
+		{TradingPair: pair, Side: "buy", AmountHave: 3000, AmountWant: 3000},
+		{TradingPair: pair, Side: "buy", AmountHave: 1000, AmountWant: 2000},
+		{TradingPair: pair, Side: "buy", AmountHave: 2000, AmountWant: 4000},
+		// buy below 1.0, doesn't count
+		{TradingPair: pair, Side: "buy", AmountHave: 10000, AmountWant: 5000},
+		// sell at or below 1.0, counts
+		{TradingPair: pair, Side: "sell", AmountHave: 1000, AmountWant: 1000},
+		// sell above 1.0, doesn't count
+		{TradingPair: pair, Side: "sell", AmountHave: 8000, AmountWant: 4000},
+	}
+
+	var imbalance *AuctionImbalance
+	if imbalance, err = ComputeImbalance(pair, orders, 1.0); err != nil {
+		t.Errorf("Error computing imbalance: %s", err)
+		return
+	}
+
+	if imbalance.BuyVolume != 9000 {
+		t.Errorf("Buy volume should be 9000, got %d", imbalance.BuyVolume)
+		return
+	}
+
+	if imbalance.SellVolume != 1000 {
+		t.Errorf("Sell volume should be 1000, got %d", imbalance.SellVolume)
+		return
+	}
+
+	// (9000 - 1000) / 10000
+	if imbalance.Imbalance() != 0.8 {
+		t.Errorf("Imbalance should be 0.8, got %f", imbalance.Imbalance())
+		return
+	}
+
+	// A batch with nothing willing to trade is balanced
+	if imbalance, err = ComputeImbalance(pair, nil, 1.0); err != nil {
+		t.Errorf("Error computing imbalance of empty batch: %s", err)
+		return
+	}
+
+	if imbalance.Imbalance() != 0 {
+		t.Errorf("Empty batch should have an imbalance of 0, got %f", imbalance.Imbalance())
+		return
+	}
+
+	// Orders for other pairs don't belong in the batch
+	otherPair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: VTCReg,
+	}
+	if _, err = ComputeImbalance(otherPair, orders, 1.0); err == nil {
+		t.Errorf("Computing imbalance with orders for a different pair should fail")
+		return
+	}
+
+	return
+}
+
+func TestComputeImbalanceNoMatch(t *testing.T) {
+	var err error
+
+	pair := Pair{
+		AssetWant: BTCReg,
+		AssetHave: LTCReg,
+	}
+
+	// The best buy is at 0.5 and the best sell at 2.0, so nothing can match
+	orders := []*AuctionOrder{
+		{TradingPair: pair, Side: "buy", AmountHave: 2000, AmountWant: 1000},
+		{TradingPair: pair, Side: "buy", AmountHave: 4000, AmountWant: 1000},
+		{TradingPair: pair, Side: "sell", AmountHave: 3000, AmountWant: 1500},
+	}
+
+	var imbalance *AuctionImbalance
+	if imbalance, err = ComputeImbalance(pair, orders, 0); err != nil {
+		t.Errorf("Error computing imbalance of batch that didn't cross: %s", err)
+		return
+	}
+
+	if !imbalance.NoMatch {
+		t.Errorf("Batch that didn't cross should be an explicit no match result")
+		return
+	}
+
+	if imbalance.BuyVolume != 2000 || imbalance.SellVolume != 3000 {
+		t.Errorf("No match result should count the whole volume on each side, 2000 buy and 3000 sell, got %s", imbalance)
+		return
+	}
+
+	// An empty batch doesn't cross either
+	if imbalance, err = ComputeImbalance(pair, nil, 0); err != nil || !imbalance.NoMatch {
+		t.Errorf("Empty batch should be an explicit no match result, got %v", err)
+		return
+	}
+
+	// A batch that does clear is not a no match result
+	if imbalance, err = ComputeImbalance(pair, orders, 1.0); err != nil || imbalance.NoMatch {
+		t.Errorf("Batch with a clearing price should not be a no match result, got %v", err)
+		return
+	}
+
+	// If the book crosses, something should have matched
+	crossed := append(orders, &AuctionOrder{TradingPair: pair, Side: "buy", AmountHave: 1000, AmountWant: 3000})
+	if _, err = ComputeImbalance(pair, crossed, 0); err == nil {
+		t.Errorf("Batch that crosses should not be allowed to have no clearing price")
+		return
+	}
+
+	if _, err = ComputeImbalance(pair, orders, -1.0); err == nil {
+		t.Errorf("Computing imbalance with a negative clearing price should fail")
+		return
+	}
+
+	return
+}
@@ -24,7 +24,8 @@ type LimitOrder struct {
 	AmountWant uint64    `json:"amountwant"`
 	Timestamp  time.Time `json:"timestamp"`
 	OrderID    string    `json:"id"`
-	// only exists for returning orders back
+	// OrderbookPrice is the price the order executes at, or would execute at if it's still in the book.
+	// This only exists for returning orders back, the exchange sets it and ignores it on orders that are placed.
 	OrderbookPrice float64 `json:"orderbookprice"`
 }
 
@@ -61,6 +62,22 @@ func (l *LimitOrder) Price() (float64, error) {
 	return 0, fmt.Errorf("Order is not buy or sell, cannot calculate price")
 }
 
+// SetOrderbookPrice sets OrderbookPrice to the price the order executes at. An order that's still in the
+// book executes at its own price. Stores that keep the price the order was placed at set it themselves,
+// since partial fills change the amounts, so the price is only calculated here if it hasn't been set.
+func (l *LimitOrder) SetOrderbookPrice() (err error) {
+	if l.OrderbookPrice != 0 {
+		return
+	}
+
+	if l.OrderbookPrice, err = l.Price(); err != nil {
+		err = fmt.Errorf("Error setting orderbook price: %s", err)
+		return
+	}
+
+	return
+}
+
 // SetID sets an ID for the order, it's going to be different if you call it twice but we're only ever going to call it once
 func (l *LimitOrder) SetID() error {
 	s1 := rand.NewSource(time.Now().UnixNano())
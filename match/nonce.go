@@ -0,0 +1,160 @@
+package match
+
+import (
+	"fmt"
+)
+
+// MaxOrdersPerPubkey is the most orders a single pubkey can have in one auction. A pubkey only has 2^16
+// nonces per auction, so this is well under that, and keeps one pubkey from filling a batch.
+const MaxOrdersPerPubkey = 1024
+
+// NonceScope is what a nonce has to be unique within
+type NonceScope uint8
+
+const (
+	// NoncePerAuction makes a pubkey's nonces unique across every pair in an auction. This is the default.
+	NoncePerAuction NonceScope = iota
+	// NoncePerPair makes a pubkey's nonces unique per pair in an auction, so a pubkey trading more than one
+	// pair can use the same nonce on each of them. The order IDs still differ, since the pair is signed.
+	NoncePerPair
+)
+
+// String returns the name of the nonce scope
+func (scope NonceScope) String() string {
+	switch scope {
+	case NoncePerAuction:
+		return "auction"
+	case NoncePerPair:
+		return "pair"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(scope))
+}
+
+// NonceScopeFromString parses a nonce scope from its name
+func NonceScopeFromString(name string) (scope NonceScope, err error) {
+	switch name {
+	case "auction":
+		scope = NoncePerAuction
+	case "pair":
+		scope = NoncePerPair
+	default:
+		err = fmt.Errorf("Unknown nonce scope %s, must be auction or pair", name)
+	}
+	return
+}
+
+// pubkeyInAuction is a single pubkey in a single auction
+type pubkeyInAuction struct {
+	auctionID [32]byte
+	pubkey    [33]byte
+}
+
+// usedNonce is a nonce used by a pubkey in an auction. pair is only set when nonces are scoped per pair.
+type usedNonce struct {
+	pubkeyInAuction
+	pair  Pair
+	nonce [2]byte
+}
+
+// NonceSet keeps track of the nonces each pubkey has used in each auction, so an order can't be replayed
+// in an auction with the same nonce. It is not safe for concurrent use.
+type NonceSet struct {
+	scope  NonceScope
+	used   map[usedNonce]bool
+	orders map[pubkeyInAuction]uint64
+}
+
+// NewNonceSet returns a NonceSet with no nonces used, where nonces are unique per auction
+func NewNonceSet() (ns *NonceSet) {
+	ns = NewScopedNonceSet(NoncePerAuction)
+	return
+}
+
+// NewScopedNonceSet returns a NonceSet with no nonces used, where nonces are unique within scope. The limit
+// of MaxOrdersPerPubkey is per auction either way.
+func NewScopedNonceSet(scope NonceScope) (ns *NonceSet) {
+	ns = &NonceSet{
+		scope:  scope,
+		used:   make(map[usedNonce]bool),
+		orders: make(map[pubkeyInAuction]uint64),
+	}
+	return
+}
+
+// Scope returns what nonces are unique within in the set
+func (ns *NonceSet) Scope() (scope NonceScope) {
+	scope = ns.scope
+	return
+}
+
+// nonceKey returns the key for the order's nonce
+func (ns *NonceSet) nonceKey(order *AuctionOrder) (key usedNonce) {
+	key.auctionID = order.AuctionID
+	key.pubkey = order.Pubkey
+	key.nonce = order.Nonce
+	if ns.scope == NoncePerPair {
+		key.pair = order.TradingPair
+	}
+	return
+}
+
+// Used returns true if the order's nonce has already been used by its pubkey in its auction, or on its
+// pair in its auction if nonces are scoped per pair
+func (ns *NonceSet) Used(order *AuctionOrder) bool {
+	return ns.used[ns.nonceKey(order)]
+}
+
+// Check returns an error if the order's nonce has already been used in its scope, or if the pubkey already
+// has MaxOrdersPerPubkey orders in the auction. It doesn't add the nonce.
+func (ns *NonceSet) Check(order *AuctionOrder) (err error) {
+	key := ns.nonceKey(order)
+	if ns.used[key] {
+		if ns.scope == NoncePerPair {
+			err = fmt.Errorf("Pubkey %x has already used nonce %x on pair %s in auction %x", order.Pubkey, order.Nonce, order.TradingPair.String(), order.AuctionID)
+			return
+		}
+		err = fmt.Errorf("Pubkey %x has already used nonce %x in auction %x", order.Pubkey, order.Nonce, order.AuctionID)
+		return
+	}
+
+	if ns.orders[key.pubkeyInAuction] >= MaxOrdersPerPubkey {
+		err = fmt.Errorf("Pubkey %x already has the maximum of %d orders in auction %x", order.Pubkey, MaxOrdersPerPubkey, order.AuctionID)
+		return
+	}
+
+	return
+}
+
+// Add checks the order's nonce and then marks it as used in its scope
+func (ns *NonceSet) Add(order *AuctionOrder) (err error) {
+	if err = ns.Check(order); err != nil {
+		return
+	}
+
+	key := ns.nonceKey(order)
+	ns.used[key] = true
+	ns.orders[key.pubkeyInAuction]++
+
+	return
+}
+
+// CheckNonceUnique returns an error if two orders in the batch are from the same pubkey with the same nonce
+// in the same auction, or if a pubkey has more than MaxOrdersPerPubkey orders in an auction.
+func CheckNonceUnique(orders []*AuctionOrder) (err error) {
+	err = CheckScopedNonceUnique(orders, NoncePerAuction)
+	return
+}
+
+// CheckScopedNonceUnique returns an error if two orders in the batch are from the same pubkey with the same
+// nonce in the same scope, or if a pubkey has more than MaxOrdersPerPubkey orders in an auction.
+func CheckScopedNonceUnique(orders []*AuctionOrder, scope NonceScope) (err error) {
+	ns := NewScopedNonceSet(scope)
+	for i, order := range orders {
+		if err = ns.Add(order); err != nil {
+			err = fmt.Errorf("Error with nonce of order %d in batch: %s", i, err)
+			return
+		}
+	}
+
+	return
+}
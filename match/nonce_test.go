@@ -0,0 +1,175 @@
+package match
+
+import (
+	"testing"
+)
+
+// nonceTestOrder returns an order from pubkey with nonce in auction
+func nonceTestOrder(pubkey byte, nonce uint16, auction byte) (order *AuctionOrder) {
+	order = goldenAuctionOrder()
+	order.Pubkey[0] = 0x02
+	order.Pubkey[1] = pubkey
+	order.Nonce = [2]byte{byte(nonce >> 8), byte(nonce)}
+	order.AuctionID = [32]byte{auction}
+	return
+}
+
+func TestCheckNonceUniqueCollision(t *testing.T) {
+	orders := []*AuctionOrder{
+		nonceTestOrder(1, 5, 1),
+		nonceTestOrder(1, 6, 1),
+		nonceTestOrder(1, 5, 1),
+	}
+
+	if err := CheckNonceUnique(orders); err == nil {
+		t.Errorf("Batch with two orders from the same pubkey with the same nonce should be rejected")
+		return
+	}
+
+	// Even if the rest of the order is different
+	orders[2].AmountHave++
+	orders[2].Side = "sell"
+	if err := CheckNonceUnique(orders); err == nil {
+		t.Errorf("Batch with a nonce reused by the same pubkey in a different order should be rejected")
+		return
+	}
+
+	return
+}
+
+func TestCheckNonceUniqueNoCollision(t *testing.T) {
+	orders := []*AuctionOrder{
+		nonceTestOrder(1, 5, 1),
+		nonceTestOrder(1, 6, 1),
+		nonceTestOrder(1, 0, 1),
+		// the same nonce in a different auction is fine
+		nonceTestOrder(1, 5, 2),
+	}
+
+	if err := CheckNonceUnique(orders); err != nil {
+		t.Errorf("Batch with unique nonces should be accepted: %s", err)
+		return
+	}
+
+	if err := CheckNonceUnique(nil); err != nil {
+		t.Errorf("Empty batch should be accepted: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestCheckNonceUniqueCrossPubkey(t *testing.T) {
+	orders := []*AuctionOrder{
+		nonceTestOrder(1, 5, 1),
+		nonceTestOrder(2, 5, 1),
+		nonceTestOrder(3, 5, 1),
+	}
+
+	if err := CheckNonceUnique(orders); err != nil {
+		t.Errorf("Different pubkeys should be able to use the same nonce: %s", err)
+		return
+	}
+
+	return
+}
+
+func TestCheckNonceUniqueCap(t *testing.T) {
+	var orders []*AuctionOrder
+	for i := 0; i < MaxOrdersPerPubkey; i++ {
+		orders = append(orders, nonceTestOrder(1, uint16(i), 1))
+	}
+
+	if err := CheckNonceUnique(orders); err != nil {
+		t.Errorf("Pubkey should be able to have %d orders in an auction: %s", MaxOrdersPerPubkey, err)
+		return
+	}
+
+	// The cap is per pubkey
+	orders = append(orders, nonceTestOrder(2, uint16(MaxOrdersPerPubkey), 1))
+	if err := CheckNonceUnique(orders); err != nil {
+		t.Errorf("Another pubkey's orders should not count toward the cap: %s", err)
+		return
+	}
+
+	orders = append(orders, nonceTestOrder(1, uint16(MaxOrdersPerPubkey), 1))
+	if err := CheckNonceUnique(orders); err == nil {
+		t.Errorf("Pubkey with more than %d orders in an auction should be rejected", MaxOrdersPerPubkey)
+		return
+	}
+
+	return
+}
+
+func TestCheckScopedNonceUniquePair(t *testing.T) {
+	otherPair := nonceTestOrder(1, 5, 1)
+	otherPair.TradingPair.AssetHave = LTCTest
+	orders := []*AuctionOrder{
+		nonceTestOrder(1, 5, 1),
+		otherPair,
+	}
+
+	// The same nonce on a different pair collides when nonces are unique per auction
+	if err := CheckScopedNonceUnique(orders, NoncePerAuction); err == nil {
+		t.Errorf("Nonce reused on a different pair should be rejected when nonces are per auction")
+		return
+	}
+
+	if err := CheckScopedNonceUnique(orders, NoncePerPair); err != nil {
+		t.Errorf("Nonce reused on a different pair should be accepted when nonces are per pair: %s", err)
+		return
+	}
+
+	// It still can't be reused on the same pair
+	orders = append(orders, nonceTestOrder(1, 5, 1))
+	if err := CheckScopedNonceUnique(orders, NoncePerPair); err == nil {
+		t.Errorf("Nonce reused on the same pair should be rejected when nonces are per pair")
+		return
+	}
+
+	return
+}
+
+func TestScopedNonceSetCap(t *testing.T) {
+	ns := NewScopedNonceSet(NoncePerPair)
+	for i := 0; i < MaxOrdersPerPubkey; i++ {
+		order := nonceTestOrder(1, uint16(i), 1)
+		if i%2 == 0 {
+			order.TradingPair.AssetHave = LTCTest
+		}
+		if err := ns.Add(order); err != nil {
+			t.Errorf("Error adding order %d: %s", i, err)
+			return
+		}
+	}
+
+	// The cap is per auction, not per pair
+	order := nonceTestOrder(1, 0, 1)
+	if err := ns.Check(order); err == nil {
+		t.Errorf("Pubkey with more than %d orders in an auction across pairs should be rejected", MaxOrdersPerPubkey)
+		return
+	}
+
+	return
+}
+
+func TestNonceScopeFromString(t *testing.T) {
+	for _, scope := range []NonceScope{NoncePerAuction, NoncePerPair} {
+		parsed, err := NonceScopeFromString(scope.String())
+		if err != nil {
+			t.Errorf("Error parsing nonce scope %s: %s", scope, err)
+			return
+		}
+		if parsed != scope {
+			t.Errorf("Parsed nonce scope %s, expected %s", parsed, scope)
+			return
+		}
+	}
+
+	if _, err := NonceScopeFromString("pubkey"); err == nil {
+		t.Errorf("Parsing an unknown nonce scope should fail")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,181 @@
+package match
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Oracle is an external source of prices for pairs, that reserve prices and price bands can be based on.
+// Prices are in the same units as order prices.
+type Oracle interface {
+	// Price gets the current price for the pair
+	Price(pair Pair) (*big.Rat, error)
+}
+
+// StaticOracle is an oracle whose prices are set by hand, like from a config file
+type StaticOracle struct {
+	prices map[Pair]*big.Rat
+	mtx    *sync.Mutex
+}
+
+// NewStaticOracle creates a static oracle without any prices
+func NewStaticOracle() (oracle *StaticOracle) {
+	oracle = &StaticOracle{
+		prices: make(map[Pair]*big.Rat),
+		mtx:    new(sync.Mutex),
+	}
+	return
+}
+
+// SetPrice sets the price the oracle gives for pair
+func (so *StaticOracle) SetPrice(pair Pair, price *big.Rat) (err error) {
+	if price.Sign() <= 0 {
+		err = fmt.Errorf("Oracle price for %s must be positive", pair.PrettyString())
+		return
+	}
+
+	so.mtx.Lock()
+	so.prices[pair] = new(big.Rat).Set(price)
+	so.mtx.Unlock()
+	return
+}
+
+// Price gets the price that was set for pair
+func (so *StaticOracle) Price(pair Pair) (price *big.Rat, err error) {
+	so.mtx.Lock()
+	setPrice, found := so.prices[pair]
+	so.mtx.Unlock()
+
+	if !found {
+		err = fmt.Errorf("No oracle price set for %s", pair.PrettyString())
+		return
+	}
+
+	price = new(big.Rat).Set(setPrice)
+	return
+}
+
+// HTTPOracle gets prices from an HTTP endpoint. For each pair it does a GET request to the URL with the
+// pair in the pair query parameter, like asset1/asset2, and expects a JSON reply like {"price": "1.25"}.
+// The price is a string so it isn't rounded on the way.
+type HTTPOracle struct {
+	URL    string
+	Client *http.Client
+}
+
+// httpOracleReply is what an HTTP oracle replies with
+type httpOracleReply struct {
+	Price string `json:"price"`
+}
+
+// NewHTTPOracle creates an HTTP oracle for the endpoint at oracleURL, that gives up on requests after timeout
+func NewHTTPOracle(oracleURL string, timeout time.Duration) (oracle *HTTPOracle, err error) {
+	if _, err = url.ParseRequestURI(oracleURL); err != nil {
+		err = fmt.Errorf("Error parsing oracle URL: %s", err)
+		return
+	}
+
+	oracle = &HTTPOracle{
+		URL:    oracleURL,
+		Client: &http.Client{Timeout: timeout},
+	}
+	return
+}
+
+// Price requests the current price for pair from the endpoint
+func (ho *HTTPOracle) Price(pair Pair) (price *big.Rat, err error) {
+	var requestURL *url.URL
+	if requestURL, err = url.Parse(ho.URL); err != nil {
+		err = fmt.Errorf("Error parsing oracle URL: %s", err)
+		return
+	}
+
+	query := requestURL.Query()
+	query.Set("pair", pair.PrettyString())
+	requestURL.RawQuery = query.Encode()
+
+	var resp *http.Response
+	if resp, err = ho.Client.Get(requestURL.String()); err != nil {
+		err = fmt.Errorf("Error requesting oracle price for %s: %s", pair.PrettyString(), err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Error requesting oracle price for %s, oracle replied with status %s", pair.PrettyString(), resp.Status)
+		return
+	}
+
+	reply := new(httpOracleReply)
+	if err = json.NewDecoder(resp.Body).Decode(reply); err != nil {
+		err = fmt.Errorf("Error decoding oracle price for %s: %s", pair.PrettyString(), err)
+		return
+	}
+
+	var ok bool
+	if price, ok = new(big.Rat).SetString(reply.Price); !ok {
+		err = fmt.Errorf("Oracle price %s for %s is not a number", reply.Price, pair.PrettyString())
+		price = nil
+		return
+	}
+
+	if price.Sign() <= 0 {
+		err = fmt.Errorf("Oracle price %s for %s must be positive", reply.Price, pair.PrettyString())
+		price = nil
+		return
+	}
+
+	return
+}
+
+// OracleBand creates the price band that is within maxDeviation of the oracle price, where maxDeviation is
+// a fraction of the oracle price. For example a max deviation of 0.1 allows prices 10% below or above.
+func OracleBand(oraclePrice *big.Rat, maxDeviation float64) (band *PriceBand, err error) {
+	if maxDeviation < 0 || maxDeviation > 1 {
+		err = fmt.Errorf("Max deviation from the oracle price must be between 0 and 1, got %f", maxDeviation)
+		return
+	}
+
+	if oraclePrice.Sign() <= 0 {
+		err = fmt.Errorf("Oracle price must be positive")
+		return
+	}
+
+	price, _ := oraclePrice.Float64()
+	band = &PriceBand{
+		MinPrice: price * (1 - maxDeviation),
+		MaxPrice: price * (1 + maxDeviation),
+	}
+	return
+}
+
+// ParsePairOraclePrice parses a pair and price from a string like "asset1/asset2:price", for setting static
+// oracle prices. This is for user input only, hence the slash.
+func ParsePairOraclePrice(priceString string) (pair *Pair, price *big.Rat, err error) {
+	strSplit := strings.Split(priceString, ":")
+	if len(strSplit) != 2 {
+		err = fmt.Errorf("Oracle price %s should look like asset1/asset2:price", priceString)
+		return
+	}
+
+	pair = new(Pair)
+	if err = pair.FromString(strSplit[0]); err != nil {
+		err = fmt.Errorf("Error parsing pair for oracle price: %s", err)
+		return
+	}
+
+	var ok bool
+	if price, ok = new(big.Rat).SetString(strSplit[1]); !ok {
+		err = fmt.Errorf("Oracle price %s is not a number", strSplit[1])
+		price = nil
+		return
+	}
+
+	return
+}
@@ -0,0 +1,153 @@
+package match
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStaticOracle(t *testing.T) {
+	var err error
+	pair := Pair{AssetWant: BTCReg, AssetHave: LTCReg}
+
+	oracle := NewStaticOracle()
+	if _, err = oracle.Price(pair); err == nil {
+		t.Errorf("Static oracle should not have a price before one is set")
+		return
+	}
+
+	if err = oracle.SetPrice(pair, big.NewRat(-1, 2)); err == nil {
+		t.Errorf("Static oracle should not accept a negative price")
+		return
+	}
+
+	if err = oracle.SetPrice(pair, big.NewRat(3, 2)); err != nil {
+		t.Errorf("Error setting static oracle price: %s", err)
+		return
+	}
+
+	var price *big.Rat
+	if price, err = oracle.Price(pair); err != nil {
+		t.Errorf("Error getting static oracle price: %s", err)
+		return
+	}
+
+	if price.Cmp(big.NewRat(3, 2)) != 0 {
+		t.Errorf("Static oracle price should be 3/2, got %s", price.RatString())
+		return
+	}
+
+	// changing the price we got back shouldn't change the oracle
+	price.SetInt64(100)
+	if price, err = oracle.Price(pair); err != nil || price.Cmp(big.NewRat(3, 2)) != 0 {
+		t.Errorf("Static oracle price should not change when the returned price does")
+		return
+	}
+
+	return
+}
+
+func TestHTTPOracle(t *testing.T) {
+	var err error
+	pair := Pair{AssetWant: BTCReg, AssetHave: LTCReg}
+	badPricePair := Pair{AssetWant: LTCReg, AssetHave: BTCReg}
+
+	oracleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("pair") {
+		case pair.PrettyString():
+			fmt.Fprint(w, `{"price": "1.25"}`)
+		case badPricePair.PrettyString():
+			fmt.Fprint(w, `{"price": "not a price"}`)
+		default:
+			http.Error(w, "unknown pair", http.StatusNotFound)
+		}
+	}))
+	defer oracleServer.Close()
+
+	var oracle *HTTPOracle
+	if oracle, err = NewHTTPOracle(oracleServer.URL, time.Second); err != nil {
+		t.Errorf("Error creating HTTP oracle: %s", err)
+		return
+	}
+
+	var price *big.Rat
+	if price, err = oracle.Price(pair); err != nil {
+		t.Errorf("Error getting HTTP oracle price: %s", err)
+		return
+	}
+
+	if price.Cmp(big.NewRat(5, 4)) != 0 {
+		t.Errorf("HTTP oracle price should be 5/4, got %s", price.RatString())
+		return
+	}
+
+	if _, err = oracle.Price(Pair{AssetWant: BTCReg, AssetHave: VTCReg}); err == nil {
+		t.Errorf("HTTP oracle should fail for a pair the endpoint doesn't know")
+		return
+	}
+
+	if _, err = oracle.Price(badPricePair); err == nil {
+		t.Errorf("HTTP oracle should fail if the endpoint doesn't give a number")
+		return
+	}
+
+	if _, err = NewHTTPOracle("not a url", time.Second); err == nil {
+		t.Errorf("Creating an HTTP oracle with a bad URL should fail")
+		return
+	}
+
+	return
+}
+
+func TestOracleBand(t *testing.T) {
+	var err error
+
+	var band *PriceBand
+	if band, err = OracleBand(big.NewRat(2, 1), 0.1); err != nil {
+		t.Errorf("Error creating oracle band: %s", err)
+		return
+	}
+
+	for _, price := range []float64{1.81, 2.0, 2.19} {
+		if !band.Contains(price) {
+			t.Errorf("Price %f should be within 10%% of 2, band is %s", price, band)
+			return
+		}
+	}
+
+	for _, price := range []float64{1.79, 2.21} {
+		if band.Contains(price) {
+			t.Errorf("Price %f should not be within 10%% of 2, band is %s", price, band)
+			return
+		}
+	}
+
+	if _, err = OracleBand(big.NewRat(2, 1), 1.5); err == nil {
+		t.Errorf("Oracle band with a deviation over 1 should fail")
+		return
+	}
+
+	if _, err = OracleBand(new(big.Rat), 0.1); err == nil {
+		t.Errorf("Oracle band around a zero price should fail")
+		return
+	}
+
+	expectedPair := Pair{AssetWant: BTCReg, AssetHave: LTCReg}
+
+	var pair *Pair
+	var price *big.Rat
+	if pair, price, err = ParsePairOraclePrice(expectedPair.PrettyString() + ":1.5"); err != nil {
+		t.Errorf("Error parsing oracle price: %s", err)
+		return
+	}
+
+	if *pair != expectedPair || price.Cmp(big.NewRat(3, 2)) != 0 {
+		t.Errorf("Parsed oracle price should be 3/2 for %s, got %s for %s", expectedPair.PrettyString(), price.RatString(), pair.PrettyString())
+		return
+	}
+
+	return
+}
@@ -63,6 +63,15 @@ func GenerateAssetPairs(coinList []*coinparam.Params) (pairList []*Pair, err err
 	return
 }
 
+// Inverse returns the pair with its assets swapped, like LTC/BTC for BTC/LTC. A buy order in one is a
+// sell order in the other, and prices in one are the reciprocal of prices in the other.
+func (p Pair) Inverse() Pair {
+	return Pair{
+		AssetWant: p.AssetHave,
+		AssetHave: p.AssetWant,
+	}
+}
+
 // Size returns the size of the pair
 func (p *Pair) Size() int {
 	return len([]byte{byte(p.AssetWant)}) + len([]byte{byte(p.AssetHave)})
@@ -81,6 +90,10 @@ func (p *Pair) String() string {
 // FromString creates a pair object from a string. This is for user input only, hence the slash
 func (p *Pair) FromString(pairString string) (err error) {
 	strSplit := strings.Split(pairString, "/")
+	if len(strSplit) != 2 {
+		err = fmt.Errorf("Pair must be two assets separated by a slash, got %s", pairString)
+		return
+	}
 
 	if p.AssetWant, err = AssetFromString(strSplit[0]); err != nil {
 		return
@@ -102,8 +115,29 @@ func (p *Pair) Serialize() []byte {
 func (p *Pair) Deserialize(buf []byte) (err error) {
 	if len(buf) != 2 {
 		err = fmt.Errorf("Tried to deserialize, byte array length should be 2 but isn't")
+		return
 	}
 	p.AssetWant = Asset(buf[0])
 	p.AssetHave = Asset(buf[1])
 	return
 }
+
+// Validate makes sure both assets in the pair are known, and that they're different
+func (p *Pair) Validate() (err error) {
+	if _, err = p.AssetWant.CoinParamFromAsset(); err != nil {
+		err = fmt.Errorf("Unknown asset %d in pair: %s", p.AssetWant, err)
+		return
+	}
+
+	if _, err = p.AssetHave.CoinParamFromAsset(); err != nil {
+		err = fmt.Errorf("Unknown asset %d in pair: %s", p.AssetHave, err)
+		return
+	}
+
+	if p.AssetWant == p.AssetHave {
+		err = fmt.Errorf("Pair cannot have %s on both sides", p.AssetWant)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,25 @@
+package match
+
+import (
+	"testing"
+)
+
+func TestPairInverse(t *testing.T) {
+	pair := Pair{
+		AssetWant: BTCTest,
+		AssetHave: LTCTest,
+	}
+
+	inverse := pair.Inverse()
+	if inverse.AssetWant != LTCTest || inverse.AssetHave != BTCTest {
+		t.Errorf("Inverse of %s should be %s/%s, got %s", pair.PrettyString(), LTCTest, BTCTest, inverse.PrettyString())
+		return
+	}
+
+	if pair.Inverse().Inverse() != pair {
+		t.Errorf("Inverse of the inverse of %s should be itself", pair.PrettyString())
+		return
+	}
+
+	return
+}
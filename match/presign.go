@@ -0,0 +1,71 @@
+package match
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// unsignedSigLenOffset returns where the signature length is in a serialized auction order that has no
+// signature yet. The signature length has to be the last thing in it, and it has to be zero.
+func unsignedSigLenOffset(unsigned []byte) (offset int, err error) {
+	// The serialization has to be read far enough to know where the side ends, so the signature length
+	// is found by its place and not just assumed to be the last 8 bytes.
+	order := new(AuctionOrder)
+	if err = order.Deserialize(unsigned); err != nil {
+		err = fmt.Errorf("Error reading unsigned auction order: %s", err)
+		return
+	}
+
+	if len(order.Signature) != 0 {
+		err = fmt.Errorf("Auction order already has a %d byte signature", len(order.Signature))
+		return
+	}
+
+	// version, pubkey, pair, amounts, side length, side, auction ID, nonce, network
+	lenSize := binary.Size(uint64(0))
+	offset = binary.Size(AuctionOrderVersion) +
+		len(order.Pubkey) +
+		order.TradingPair.Size() +
+		binary.Size(order.AmountHave) +
+		binary.Size(order.AmountWant) +
+		lenSize +
+		len(order.Side) +
+		len(order.AuctionID) +
+		len(order.Nonce) +
+		binary.Size(order.Network)
+	if offset+lenSize != len(unsigned) {
+		err = fmt.Errorf("Unsigned auction order has %d extra bytes after it", len(unsigned)-offset-lenSize)
+		return
+	}
+
+	return
+}
+
+// UnsignedSigHash returns the hash that an external signer should sign for an auction order that was
+// serialized without a signature. This is the same as the order's SigHash.
+func UnsignedSigHash(unsigned []byte) (e []byte, err error) {
+	var offset int
+	if offset, err = unsignedSigLenOffset(unsigned); err != nil {
+		return
+	}
+
+	// The version isn't signed, so it's skipped
+	e = AuctionOrderDomain.SigHash(unsigned[binary.Size(AuctionOrderVersion):offset])
+	return
+}
+
+// AttachSignature attaches sig to an auction order that was serialized without a signature, like one that
+// was built and then sent to an external signer. The rest of the order is copied as is, it isn't
+// serialized again. The result is the same as serializing the order with the signature set.
+func AttachSignature(unsigned []byte, sig []byte) (signed []byte, err error) {
+	var offset int
+	if offset, err = unsignedSigLenOffset(unsigned); err != nil {
+		return
+	}
+
+	signed = make([]byte, offset+binary.Size(uint64(0)), offset+binary.Size(uint64(0))+len(sig))
+	copy(signed, unsigned[:offset])
+	binary.LittleEndian.PutUint64(signed[offset:], uint64(len(sig)))
+	signed = append(signed, sig...)
+	return
+}
@@ -0,0 +1,120 @@
+package match
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+func TestSerializeUnsignedOrder(t *testing.T) {
+	var err error
+
+	order := goldenAuctionOrder()
+	order.Signature = nil
+
+	unsigned := order.Serialize()
+	if unsigned[0] != AuctionOrderVersion || !bytes.Equal(unsigned[1:len(unsigned)-8], order.SerializeSignable()) || !bytes.Equal(unsigned[len(unsigned)-8:], make([]byte, 8)) {
+		t.Errorf("Unsigned order should serialize as the version, the signable part, and a zero signature length")
+		return
+	}
+
+	decOrder := new(AuctionOrder)
+	if err = decOrder.Deserialize(unsigned); err != nil {
+		t.Errorf("Error deserializing unsigned order: %s", err)
+		return
+	}
+
+	if len(decOrder.Signature) != 0 {
+		t.Errorf("Unsigned order should deserialize without a signature, got %d bytes", len(decOrder.Signature))
+		return
+	}
+
+	if !bytes.Equal(decOrder.Serialize(), unsigned) {
+		t.Errorf("Unsigned order did not survive a serialization round trip")
+		return
+	}
+
+	return
+}
+
+func TestPreSignAttachRoundTrip(t *testing.T) {
+	var err error
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	order := goldenAuctionOrder()
+	order.Signature = nil
+	copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+	unsigned := order.Serialize()
+
+	// The external signer only gets the serialized order
+	var e []byte
+	if e, err = UnsignedSigHash(unsigned); err != nil {
+		t.Errorf("Error getting hash of unsigned order: %s", err)
+		return
+	}
+
+	if !bytes.Equal(e, order.SigHash()) {
+		t.Errorf("Hash of unsigned order should be the order's sig hash")
+		return
+	}
+
+	var sig []byte
+	if sig, err = koblitz.SignCompact(koblitz.S256(), privkey, e, false); err != nil {
+		t.Errorf("Error signing unsigned order: %s", err)
+		return
+	}
+
+	var signed []byte
+	if signed, err = AttachSignature(unsigned, sig); err != nil {
+		t.Errorf("Error attaching signature: %s", err)
+		return
+	}
+
+	// Should be the same as if the order was signed before serializing
+	order.Signature = sig
+	if !bytes.Equal(signed, order.Serialize()) {
+		t.Errorf("Attaching a signature should give the same bytes as serializing the signed order")
+		return
+	}
+
+	decOrder := new(AuctionOrder)
+	if err = decOrder.Deserialize(signed); err != nil {
+		t.Errorf("Error deserializing signed order: %s", err)
+		return
+	}
+
+	var valid bool
+	if valid, err = decOrder.VerifySignature(); err != nil || !valid {
+		t.Errorf("Order with attached signature should verify: %v", err)
+		return
+	}
+
+	// The unsigned bytes shouldn't have been touched
+	if !bytes.Equal(unsigned[len(unsigned)-8:], make([]byte, 8)) {
+		t.Errorf("Attaching a signature should not change the unsigned order")
+		return
+	}
+
+	if _, err = AttachSignature(signed, sig); err == nil {
+		t.Errorf("Attaching a signature to an order that is already signed should fail")
+		return
+	}
+
+	if _, err = AttachSignature(append(unsigned, 0x00), sig); err == nil {
+		t.Errorf("Attaching a signature to an order with extra bytes after it should fail")
+		return
+	}
+
+	if _, err = AttachSignature(unsigned[:20], sig); err == nil {
+		t.Errorf("Attaching a signature to a truncated order should fail")
+		return
+	}
+
+	return
+}
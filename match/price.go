@@ -40,3 +40,50 @@ func (p *Price) Cmp(otherPrice *Price) (compIndicator int) {
 	compIndicator = price1.Cmp(price2)
 	return
 }
+
+// MaxPricePrecision is the most decimal places a price can be formatted with
+const MaxPricePrecision = 32
+
+// ToRat converts the price to an exact fraction
+func (p *Price) ToRat() (price *big.Rat, err error) {
+	if p.AmountHave == 0 {
+		err = fmt.Errorf("AmountHave cannot be 0 to convert to rat")
+		return
+	}
+	price = new(big.Rat).SetFrac(new(big.Int).SetUint64(p.AmountWant), new(big.Int).SetUint64(p.AmountHave))
+	return
+}
+
+// FormattedPrice is a price rounded to a fixed number of decimal places, along with the exact fraction
+// it was rounded from, so clients can use whichever they need.
+type FormattedPrice struct {
+	// Decimal is the price with Precision decimal places
+	Decimal   string `json:"decimal"`
+	Precision uint   `json:"precision"`
+	// Numerator and Denominator are the exact price, in lowest terms
+	Numerator   string `json:"numerator"`
+	Denominator string `json:"denominator"`
+}
+
+// FormatPrice formats the price with precision decimal places. The last decimal place is rounded to
+// the nearest value, and halves are rounded away from zero, so 0.125 with 2 decimal places is 0.13.
+func FormatPrice(price *big.Rat, precision uint) (formatted *FormattedPrice, err error) {
+	if price == nil {
+		err = fmt.Errorf("Cannot format a nil price")
+		return
+	}
+
+	if precision > MaxPricePrecision {
+		err = fmt.Errorf("Price precision %d is more than the max of %d decimal places", precision, MaxPricePrecision)
+		return
+	}
+
+	// FloatString rounds to nearest with halves away from zero
+	formatted = &FormattedPrice{
+		Decimal:     price.FloatString(int(precision)),
+		Precision:   precision,
+		Numerator:   price.Num().String(),
+		Denominator: price.Denom().String(),
+	}
+	return
+}
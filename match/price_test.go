@@ -0,0 +1,89 @@
+package match
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormatPricePrecision(t *testing.T) {
+	var tests = []struct {
+		price     *big.Rat
+		precision uint
+		decimal   string
+		num       string
+		denom     string
+	}{
+		{big.NewRat(1, 3), 0, "0", "1", "3"},
+		{big.NewRat(1, 3), 2, "0.33", "1", "3"},
+		{big.NewRat(2, 3), 2, "0.67", "2", "3"},
+		{big.NewRat(2, 3), 8, "0.66666667", "2", "3"},
+		{big.NewRat(50000000, 100000000), 4, "0.5000", "1", "2"},
+		// halves round away from zero
+		{big.NewRat(1, 8), 2, "0.13", "1", "8"},
+		{big.NewRat(-1, 8), 2, "-0.13", "-1", "8"},
+		{big.NewRat(5, 2), 0, "3", "5", "2"},
+		{big.NewRat(7, 1), 3, "7.000", "7", "1"},
+		{big.NewRat(1, 3), MaxPricePrecision, "0.33333333333333333333333333333333", "1", "3"},
+	}
+
+	for _, test := range tests {
+		formatted, err := FormatPrice(test.price, test.precision)
+		if err != nil {
+			t.Errorf("Error formatting price %s with precision %d: %s", test.price, test.precision, err)
+			return
+		}
+
+		if formatted.Decimal != test.decimal {
+			t.Errorf("Price %s with precision %d formatted as %s, expected %s", test.price, test.precision, formatted.Decimal, test.decimal)
+			return
+		}
+
+		if formatted.Numerator != test.num || formatted.Denominator != test.denom {
+			t.Errorf("Price %s had exact value %s/%s, expected %s/%s", test.price, formatted.Numerator, formatted.Denominator, test.num, test.denom)
+			return
+		}
+
+		if formatted.Precision != test.precision {
+			t.Errorf("Formatted price had precision %d, expected %d", formatted.Precision, test.precision)
+			return
+		}
+	}
+
+	return
+}
+
+func TestFormatPriceInvalid(t *testing.T) {
+	if _, err := FormatPrice(nil, 2); err == nil {
+		t.Errorf("Formatting a nil price should fail")
+		return
+	}
+
+	if _, err := FormatPrice(big.NewRat(1, 2), MaxPricePrecision+1); err == nil {
+		t.Errorf("Formatting a price with more than %d decimal places should fail", MaxPricePrecision)
+		return
+	}
+
+	return
+}
+
+func TestPriceToRat(t *testing.T) {
+	price := &Price{AmountWant: 50000000, AmountHave: 150000000}
+
+	rat, err := price.ToRat()
+	if err != nil {
+		t.Errorf("Error converting price to rat: %s", err)
+		return
+	}
+
+	if rat.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("Price converted to %s, expected 1/3", rat)
+		return
+	}
+
+	if _, err = (&Price{AmountWant: 1}).ToRat(); err == nil {
+		t.Errorf("Converting a price with AmountHave 0 should fail")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,54 @@
+package match
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PriceBand is an absolute range of clearing prices that are acceptable for a pair. If an auction
+// clears outside of the band, the auction should be voided. This is useful for pegged or stable pairs,
+// where a clearing price far from the peg is much more likely to be manipulation than a real price.
+type PriceBand struct {
+	MinPrice float64 `json:"minprice"`
+	MaxPrice float64 `json:"maxprice"`
+}
+
+// Contains returns true if the price is within the band, inclusive
+func (b *PriceBand) Contains(price float64) bool {
+	return price >= b.MinPrice && price <= b.MaxPrice
+}
+
+// String is the tostring function for a price band
+func (b *PriceBand) String() string {
+	return fmt.Sprintf("[%f, %f]", b.MinPrice, b.MaxPrice)
+}
+
+// ParsePairPriceBand parses a pair and price band from a string like "asset1/asset2:min:max". This is for
+// user input only, hence the slash.
+func ParsePairPriceBand(bandString string) (pair *Pair, band *PriceBand, err error) {
+	strSplit := strings.Split(bandString, ":")
+	if len(strSplit) != 3 {
+		err = fmt.Errorf("Price band %s should look like asset1/asset2:min:max", bandString)
+		return
+	}
+
+	pair = new(Pair)
+	if err = pair.FromString(strSplit[0]); err != nil {
+		err = fmt.Errorf("Error parsing pair for price band: %s", err)
+		return
+	}
+
+	band = new(PriceBand)
+	if band.MinPrice, err = strconv.ParseFloat(strSplit[1], 64); err != nil {
+		err = fmt.Errorf("Error parsing minimum price for price band: %s", err)
+		return
+	}
+
+	if band.MaxPrice, err = strconv.ParseFloat(strSplit[2], 64); err != nil {
+		err = fmt.Errorf("Error parsing maximum price for price band: %s", err)
+		return
+	}
+
+	return
+}
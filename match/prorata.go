@@ -0,0 +1,167 @@
+package match
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// proRataShare is an order on the side with more volume, and its share of what that side gets
+type proRataShare struct {
+	order     *AuctionOrder
+	hash      [32]byte
+	tieBreak  [32]byte
+	price     *big.Rat
+	volume    uint64
+	fill      uint64
+	remainder *big.Int
+}
+
+// AllocateProRata decides how much of every matched order fills at the clearing price. Sizes and fills are
+// in AssetWant, like the volumes in ComputeClearingPrice: what a buy wants, and what a sell has. Every order
+// has to be at or through the clearing price.
+// The side with less volume is the scarce side, and fills entirely. The other side shares that volume in
+// price priority: orders through the clearing price fill first, and an order's share of what is left at its
+// price is proportional to its size. Shares are rounded down, and what rounding leaves over is given out one
+// unit at a time by the largest remainder method. Ties go to the larger order, and then, since there's no
+// random beacon, to the order with the lower tie-break hash. Submitters can't choose where their order lands
+// in a tie, and fills don't depend on the order orders are given in, so every node gets the same fills.
+// fills has every matched order in it, keyed by order hash, including orders that don't fill at all.
+func AllocateProRata(matched []*AuctionOrder, clearing *big.Rat) (fills map[[32]byte]uint64, err error) {
+	if clearing == nil || clearing.Sign() <= 0 {
+		err = fmt.Errorf("Clearing price must be positive to allocate fills")
+		return
+	}
+
+	fills = make(map[[32]byte]uint64)
+	var buys, sells []*proRataShare
+	var buyVolume, sellVolume uint64
+	for _, order := range matched {
+		if order.TradingPair != matched[0].TradingPair {
+			err = fmt.Errorf("Orders for %s and %s can't be allocated together", order.TradingPair.PrettyString(), matched[0].TradingPair.PrettyString())
+			return
+		}
+
+		share := &proRataShare{
+			order:    order,
+			hash:     order.Hash(),
+			tieBreak: order.TieBreakHash(),
+		}
+		if _, found := fills[share.hash]; found {
+			err = fmt.Errorf("Order %x is matched more than once", share.hash)
+			return
+		}
+		fills[share.hash] = 0
+
+		if share.price, err = order.PriceRational(); err != nil {
+			err = fmt.Errorf("Error getting order price for pro-rata allocation: %s", err)
+			return
+		}
+
+		if order.IsBuySide() {
+			if share.price.Cmp(clearing) < 0 {
+				err = fmt.Errorf("Buy order %x has price %s, below the clearing price %s", share.hash, share.price.RatString(), clearing.RatString())
+				return
+			}
+			share.volume = order.AmountWant
+			if buyVolume+share.volume < buyVolume {
+				err = fmt.Errorf("Buy volume overflows allocating fills")
+				return
+			}
+			buyVolume += share.volume
+			buys = append(buys, share)
+		} else {
+			if share.price.Cmp(clearing) > 0 {
+				err = fmt.Errorf("Sell order %x has price %s, above the clearing price %s", share.hash, share.price.RatString(), clearing.RatString())
+				return
+			}
+			share.volume = order.AmountHave
+			if sellVolume+share.volume < sellVolume {
+				err = fmt.Errorf("Sell volume overflows allocating fills")
+				return
+			}
+			sellVolume += share.volume
+			sells = append(sells, share)
+		}
+	}
+
+	// the scarce side fills entirely, and the other side shares its volume
+	scarce, shared, volume := buys, sells, buyVolume
+	if sellVolume < buyVolume {
+		scarce, shared, volume = sells, buys, sellVolume
+	}
+
+	for _, share := range scarce {
+		share.fill = share.volume
+	}
+
+	// price priority, buys with higher prices and sells with lower prices first
+	sort.Slice(shared, func(i, j int) bool {
+		if cmp := shared[i].price.Cmp(shared[j].price); cmp != 0 {
+			return (cmp > 0) == shared[i].order.IsBuySide()
+		}
+		return bytes.Compare(shared[i].tieBreak[:], shared[j].tieBreak[:]) < 0
+	})
+
+	remaining := volume
+	for start := 0; start < len(shared) && remaining > 0; {
+		end := start
+		var levelVolume uint64
+		for end < len(shared) && shared[end].price.Cmp(shared[start].price) == 0 {
+			levelVolume += shared[end].volume
+			end++
+		}
+
+		level := shared[start:end]
+		if levelVolume <= remaining {
+			for _, share := range level {
+				share.fill = share.volume
+			}
+			remaining -= levelVolume
+		} else {
+			allocateLevel(level, remaining, levelVolume)
+			remaining = 0
+		}
+		start = end
+	}
+
+	for _, share := range append(scarce, shared...) {
+		fills[share.hash] = share.fill
+	}
+
+	return
+}
+
+// allocateLevel shares amount between orders at the same price, proportional to their volume, which adds up
+// to levelVolume. amount has to be less than levelVolume.
+func allocateLevel(level []*proRataShare, amount uint64, levelVolume uint64) {
+	// amount * volume can overflow, so shares are computed exactly
+	bigAmount := new(big.Int).SetUint64(amount)
+	bigLevelVolume := new(big.Int).SetUint64(levelVolume)
+
+	leftOver := amount
+	for _, share := range level {
+		quo, rem := new(big.Int).QuoRem(new(big.Int).Mul(bigAmount, new(big.Int).SetUint64(share.volume)), bigLevelVolume, new(big.Int))
+		share.fill = quo.Uint64()
+		share.remainder = rem
+		leftOver -= share.fill
+	}
+
+	// less than one unit is left over per order, give it to the largest remainders
+	byRemainder := make([]*proRataShare, len(level))
+	copy(byRemainder, level)
+	sort.Slice(byRemainder, func(i, j int) bool {
+		if cmp := byRemainder[i].remainder.Cmp(byRemainder[j].remainder); cmp != 0 {
+			return cmp > 0
+		}
+		if byRemainder[i].volume != byRemainder[j].volume {
+			return byRemainder[i].volume > byRemainder[j].volume
+		}
+		return bytes.Compare(byRemainder[i].tieBreak[:], byRemainder[j].tieBreak[:]) < 0
+	})
+
+	for i := uint64(0); i < leftOver; i++ {
+		byRemainder[i].fill++
+	}
+}
@@ -0,0 +1,224 @@
+package match
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+// proRataTestOrder creates an order on the same pair as every other pro-rata test order. The nonce makes
+// orders that are otherwise the same different.
+func proRataTestOrder(side string, amountHave uint64, amountWant uint64, nonce byte) (order *AuctionOrder) {
+	order = &AuctionOrder{
+		TradingPair: Pair{
+			AssetWant: BTCReg,
+			AssetHave: LTCReg,
+		},
+		Side:       side,
+		AmountHave: amountHave,
+		AmountWant: amountWant,
+		Nonce:      [2]byte{nonce},
+	}
+	return
+}
+
+func TestAllocateProRata(t *testing.T) {
+	var err error
+
+	clearing := big.NewRat(2, 1)
+
+	// 100 buy volume and 70 sell volume at 2, so the buys share 70
+	buys := []*AuctionOrder{
+		proRataTestOrder("buy", 15, 30, 0),
+		proRataTestOrder("buy", 15, 30, 1),
+		proRataTestOrder("buy", 20, 40, 2),
+	}
+	sell := proRataTestOrder("sell", 70, 35, 3)
+
+	var fills map[[32]byte]uint64
+	if fills, err = AllocateProRata(append(buys, sell), clearing); err != nil {
+		t.Errorf("Error allocating fills: %s", err)
+		return
+	}
+
+	expected := map[*AuctionOrder]uint64{
+		buys[0]: 21,
+		buys[1]: 21,
+		buys[2]: 28,
+		sell:    70,
+	}
+	if len(fills) != len(expected) {
+		t.Errorf("Expected fills for %d orders, got %d", len(expected), len(fills))
+		return
+	}
+	for order, fill := range expected {
+		if fills[order.Hash()] != fill {
+			t.Errorf("Order %x should fill %d, got %d", order.Hash(), fill, fills[order.Hash()])
+			return
+		}
+	}
+
+	// orders through the clearing price fill first, then what's left is shared at the clearing price
+	through := proRataTestOrder("buy", 10, 30, 4)
+	atClearing := []*AuctionOrder{
+		proRataTestOrder("buy", 10, 20, 5),
+		proRataTestOrder("buy", 10, 20, 6),
+	}
+	sell = proRataTestOrder("sell", 40, 20, 7)
+	if fills, err = AllocateProRata([]*AuctionOrder{atClearing[0], sell, atClearing[1], through}, clearing); err != nil {
+		t.Errorf("Error allocating fills: %s", err)
+		return
+	}
+
+	if fills[through.Hash()] != 30 || fills[atClearing[0].Hash()] != 5 || fills[atClearing[1].Hash()] != 5 || fills[sell.Hash()] != 40 {
+		t.Errorf("Order through the clearing price should fill entirely before orders at it share the rest")
+		return
+	}
+
+	// when both sides have the same volume everything fills, and one sided books don't fill at all
+	if fills, err = AllocateProRata([]*AuctionOrder{buys[0], proRataTestOrder("sell", 30, 15, 8)}, clearing); err != nil {
+		t.Errorf("Error allocating fills: %s", err)
+		return
+	}
+	for _, fill := range fills {
+		if fill != 30 {
+			t.Errorf("Orders with the same volume on both sides should fill entirely, got %d", fill)
+			return
+		}
+	}
+
+	if fills, err = AllocateProRata(buys, clearing); err != nil {
+		t.Errorf("Error allocating fills: %s", err)
+		return
+	}
+	for _, fill := range fills {
+		if fill != 0 {
+			t.Errorf("One sided book should not fill, got %d", fill)
+			return
+		}
+	}
+
+	return
+}
+
+func TestAllocateProRataRemainders(t *testing.T) {
+	var err error
+
+	clearing := big.NewRat(1, 1)
+
+	// Everything is at the clearing price. Sells of 1 and 2 share 2: 2 * 1 / 3 is 0 remainder 2, and 2 * 2 / 3 is 1 remainder 1. The unit
+	// left over goes to the largest remainder, the smaller order.
+	small := proRataTestOrder("sell", 1, 1, 0)
+	large := proRataTestOrder("sell", 2, 2, 1)
+	buy := proRataTestOrder("buy", 2, 2, 2)
+
+	var fills map[[32]byte]uint64
+	if fills, err = AllocateProRata([]*AuctionOrder{small, large, buy}, clearing); err != nil {
+		t.Errorf("Error allocating fills: %s", err)
+		return
+	}
+
+	if fills[small.Hash()] != 1 || fills[large.Hash()] != 1 || fills[buy.Hash()] != 2 {
+		t.Errorf("Left over unit should go to the order with the largest remainder, got %d and %d", fills[small.Hash()], fills[large.Hash()])
+		return
+	}
+
+	// Sells of 1 and 3 share 2: 2 * 1 / 4 is 0 remainder 2, and 2 * 3 / 4 is 1 remainder 2. The remainders
+	// are the same, so the left over unit goes to the larger order.
+	large = proRataTestOrder("sell", 3, 3, 3)
+	if fills, err = AllocateProRata([]*AuctionOrder{small, large, buy}, clearing); err != nil {
+		t.Errorf("Error allocating fills: %s", err)
+		return
+	}
+
+	if fills[small.Hash()] != 0 || fills[large.Hash()] != 2 {
+		t.Errorf("Left over unit should go to the larger order when remainders tie, got %d and %d", fills[small.Hash()], fills[large.Hash()])
+		return
+	}
+
+	// Three sells of 10 share 20: each gets 6 remainder 20, so 2 units are left over. Remainders and sizes
+	// tie, so they go to the two orders with the lowest tie-break hashes.
+	sells := []*AuctionOrder{
+		proRataTestOrder("sell", 10, 10, 4),
+		proRataTestOrder("sell", 10, 10, 5),
+		proRataTestOrder("sell", 10, 10, 6),
+	}
+	buy = proRataTestOrder("buy", 20, 20, 7)
+
+	highest := sells[0]
+	for _, sell := range sells[1:] {
+		if hash, highestHash := sell.TieBreakHash(), highest.TieBreakHash(); bytes.Compare(hash[:], highestHash[:]) > 0 {
+			highest = sell
+		}
+	}
+
+	// the same fills no matter what order the orders are in
+	for _, orders := range [][]*AuctionOrder{
+		{sells[0], sells[1], sells[2], buy},
+		{buy, sells[2], sells[1], sells[0]},
+		{sells[1], buy, sells[0], sells[2]},
+	} {
+		if fills, err = AllocateProRata(orders, clearing); err != nil {
+			t.Errorf("Error allocating fills: %s", err)
+			return
+		}
+
+		var total uint64
+		for _, sell := range sells {
+			expected := uint64(7)
+			if sell == highest {
+				expected = 6
+			}
+			if fills[sell.Hash()] != expected {
+				t.Errorf("Sell %x should fill %d, got %d", sell.Hash(), expected, fills[sell.Hash()])
+				return
+			}
+			total += fills[sell.Hash()]
+		}
+
+		if total != fills[buy.Hash()] {
+			t.Errorf("Sells should fill %d in total, the buy volume, got %d", fills[buy.Hash()], total)
+			return
+		}
+	}
+
+	return
+}
+
+func TestAllocateProRataInvalid(t *testing.T) {
+	var err error
+
+	clearing := big.NewRat(2, 1)
+	buy := proRataTestOrder("buy", 10, 20, 0)
+	sell := proRataTestOrder("sell", 20, 10, 1)
+
+	if _, err = AllocateProRata([]*AuctionOrder{buy, sell}, nil); err == nil {
+		t.Errorf("Allocating without a clearing price should fail")
+		return
+	}
+
+	// buy at 1 is below the clearing price, and sell at 4 is above it
+	if _, err = AllocateProRata([]*AuctionOrder{proRataTestOrder("buy", 10, 10, 2), sell}, clearing); err == nil {
+		t.Errorf("Allocating to a buy below the clearing price should fail")
+		return
+	}
+
+	if _, err = AllocateProRata([]*AuctionOrder{buy, proRataTestOrder("sell", 40, 10, 3)}, clearing); err == nil {
+		t.Errorf("Allocating to a sell above the clearing price should fail")
+		return
+	}
+
+	if _, err = AllocateProRata([]*AuctionOrder{buy, sell, buy}, clearing); err == nil {
+		t.Errorf("Allocating to the same order twice should fail")
+		return
+	}
+
+	other := proRataTestOrder("sell", 20, 10, 4)
+	other.TradingPair = other.TradingPair.Inverse()
+	if _, err = AllocateProRata([]*AuctionOrder{buy, other}, clearing); err == nil {
+		t.Errorf("Allocating to orders on different pairs should fail")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,49 @@
+package match
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// InvalidPubkeyError is returned when an order's pubkey isn't a compressed point on the curve. Pubkeys come
+// straight from whoever made the order, so this is the order being garbage rather than a crypto failure.
+type InvalidPubkeyError struct {
+	Pubkey [33]byte
+	Reason string
+}
+
+// Error returns the pubkey along with why it's invalid
+func (e *InvalidPubkeyError) Error() string {
+	return fmt.Sprintf("invalid pubkey %x: %s", e.Pubkey, e.Reason)
+}
+
+// IsInvalidPubkey returns true if err is from an order with an invalid pubkey
+func IsInvalidPubkey(err error) bool {
+	_, ok := err.(*InvalidPubkeyError)
+	return ok
+}
+
+// ParsePubkey parses the order's pubkey, returning an *InvalidPubkeyError if it isn't a compressed point on
+// the curve. If parsing panics, that's an invalid pubkey too.
+func (a *AuctionOrder) ParsePubkey() (pubkey *koblitz.PublicKey, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pubkey = nil
+			err = &InvalidPubkeyError{Pubkey: a.Pubkey, Reason: fmt.Sprintf("parsing panicked: %v", r)}
+		}
+	}()
+
+	// Only compressed pubkeys fit, the first byte says which of the two possible points it is
+	if a.Pubkey[0] != 0x02 && a.Pubkey[0] != 0x03 {
+		err = &InvalidPubkeyError{Pubkey: a.Pubkey, Reason: fmt.Sprintf("prefix %x is not a compressed pubkey prefix", a.Pubkey[0])}
+		return
+	}
+
+	if pubkey, err = koblitz.ParsePubKey(a.Pubkey[:], koblitz.S256()); err != nil {
+		err = &InvalidPubkeyError{Pubkey: a.Pubkey, Reason: err.Error()}
+		return
+	}
+
+	return
+}
@@ -0,0 +1,83 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+func TestParsePubkeyGarbage(t *testing.T) {
+	var err error
+
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating key: %s", err)
+		return
+	}
+
+	// sign a good order so the only thing wrong with the others is their pubkey
+	order := goldenAuctionOrder()
+	copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+	if order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, order.SigHash(), false); err != nil {
+		t.Errorf("Error signing order: %s", err)
+		return
+	}
+
+	if _, err = order.ParsePubkey(); err != nil {
+		t.Errorf("Order with a valid pubkey should parse: %s", err)
+		return
+	}
+
+	var garbage, notOnCurve, uncompressedPrefix [33]byte
+	for i := range garbage {
+		garbage[i] = byte(0xa5 ^ i)
+	}
+	// x is bigger than the field, so there is no point with it
+	notOnCurve[0] = 0x02
+	for i := 1; i < len(notOnCurve); i++ {
+		notOnCurve[i] = 0xff
+	}
+	copy(uncompressedPrefix[:], order.Pubkey[:])
+	uncompressedPrefix[0] = 0x04
+
+	for _, badPubkey := range []struct {
+		name   string
+		pubkey [33]byte
+	}{
+		{"garbage", garbage},
+		{"all zero", [33]byte{}},
+		{"not on the curve", notOnCurve},
+		{"uncompressed prefix", uncompressedPrefix},
+	} {
+		badOrder := order.Clone()
+		badOrder.Pubkey = badPubkey.pubkey
+
+		if _, err = badOrder.ParsePubkey(); !IsInvalidPubkey(err) {
+			t.Errorf("Parsing %s pubkey should give an invalid pubkey error, got %v", badPubkey.name, err)
+			return
+		}
+
+		if _, err = badOrder.VerifySignature(); !IsInvalidPubkey(err) {
+			t.Errorf("Verifying order with %s pubkey should give an invalid pubkey error, got %v", badPubkey.name, err)
+			return
+		}
+
+		if err = badOrder.Validate(); !IsInvalidPubkey(err) {
+			t.Errorf("Validating order with %s pubkey should give an invalid pubkey error, got %v", badPubkey.name, err)
+			return
+		}
+
+		var valid []bool
+		if valid, err = VerifyOrdersBatch([]*AuctionOrder{order, badOrder}); err != nil {
+			t.Errorf("Error verifying batch with %s pubkey: %s", badPubkey.name, err)
+			return
+		}
+
+		if !valid[0] || valid[1] {
+			t.Errorf("Only the order with %s pubkey should be invalid in the batch, got %v", badPubkey.name, valid)
+			return
+		}
+	}
+
+	return
+}
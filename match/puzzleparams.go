@@ -0,0 +1,133 @@
+package match
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/mit-dci/opencx/crypto"
+	"github.com/mit-dci/opencx/crypto/rsw"
+	"github.com/mit-dci/opencx/crypto/timelockencoders"
+	"github.com/mit-dci/opencx/crypto/vdf"
+)
+
+// DefaultModulusBits is the default minimum size of the RSW modulus in timelock puzzles
+const DefaultModulusBits = 2048
+
+// PuzzleParams are the parameters that the timelock puzzles for an auction have to use. The exchange
+// picks new ones for every auction, so work done on the puzzles of one auction doesn't help with the
+// puzzles of another. The modulus N is still made by whoever creates the puzzle, since anyone who
+// knows its factors can solve the puzzle right away.
+type PuzzleParams struct {
+	// A is the base that gets repeatedly squared
+	A int64 `json:"a"`
+	// T is the number of squarings
+	T uint64 `json:"t"`
+	// ModulusBits is the minimum size of N
+	ModulusBits int `json:"modulusbits"`
+}
+
+// NewPuzzleParams creates puzzle parameters with time t and a fresh random base
+func NewPuzzleParams(t uint64, modulusBits int) (params *PuzzleParams, err error) {
+	if modulusBits <= 0 {
+		err = fmt.Errorf("Modulus size must be positive, not %d bits", modulusBits)
+		return
+	}
+
+	var aBytes [8]byte
+	if _, err = rand.Read(aBytes[:]); err != nil {
+		err = fmt.Errorf("Error getting random base for puzzle params: %s", err)
+		return
+	}
+
+	// A has to fit in an int64 and can't be 0 or 1, since squaring those doesn't take any work
+	params = &PuzzleParams{
+		A:           int64(binary.BigEndian.Uint64(aBytes[:])>>2) + 2,
+		T:           t,
+		ModulusBits: modulusBits,
+	}
+	return
+}
+
+// CheckPuzzle makes sure the puzzle is an RSW or VDF puzzle that uses these parameters. Both are solved by
+// repeatedly squaring A mod N, so they're held to the same base, time, and modulus size.
+func (p *PuzzleParams) CheckPuzzle(puzzle crypto.Puzzle) (err error) {
+	var n, a, t *big.Int
+	switch pz := puzzle.(type) {
+	case *rsw.PuzzleRSW:
+		n, a, t = pz.N, pz.A, pz.T
+	case *vdf.PuzzleVDF:
+		n, a, t = pz.N, pz.A, pz.T
+	default:
+		err = fmt.Errorf("Puzzle is not an RSW or VDF puzzle")
+		return
+	}
+
+	if n == nil || a == nil || t == nil {
+		err = fmt.Errorf("Puzzle is missing N, A, or T")
+		return
+	}
+
+	if !a.IsInt64() || a.Int64() != p.A {
+		err = fmt.Errorf("Puzzle base %s is not the auction's base %d", a, p.A)
+		return
+	}
+
+	if !t.IsUint64() || t.Uint64() != p.T {
+		err = fmt.Errorf("Puzzle time %s is not the auction's time %d", t, p.T)
+		return
+	}
+
+	if n.BitLen() < p.ModulusBits {
+		err = fmt.Errorf("Puzzle modulus is %d bits, it should be at least %d bits", n.BitLen(), p.ModulusBits)
+		return
+	}
+
+	return
+}
+
+// String is the tostring function for puzzle params
+func (p *PuzzleParams) String() string {
+	return fmt.Sprintf("{A: %d, T: %d, ModulusBits: %d}", p.A, p.T, p.ModulusBits)
+}
+
+// TurnIntoEncryptedOrderWithParams creates a puzzle for this auction order that uses the auction's puzzle
+// parameters. We make no assumptions about whether or not the order is signed.
+func (a *AuctionOrder) TurnIntoEncryptedOrderWithParams(params *PuzzleParams) (encrypted *EncryptedAuctionOrder, err error) {
+	return a.turnIntoEncryptedOrderWithTimelock(params, rsw.New)
+}
+
+// TurnIntoVDFEncryptedOrderWithParams creates a VDF puzzle for this auction order that uses the auction's
+// puzzle parameters. Solving it also gives a proof, so anyone can check the exchange solved it without
+// solving it again.
+func (a *AuctionOrder) TurnIntoVDFEncryptedOrderWithParams(params *PuzzleParams) (encrypted *EncryptedAuctionOrder, err error) {
+	return a.turnIntoEncryptedOrderWithTimelock(params, vdf.New)
+}
+
+// turnIntoEncryptedOrderWithTimelock creates a puzzle for this auction order with the auction's puzzle
+// parameters, using newTimelock to make the timelock the puzzle comes from
+func (a *AuctionOrder) turnIntoEncryptedOrderWithTimelock(params *PuzzleParams, newTimelock func(key []byte, a int64, rsaKeyBits int) (crypto.Timelock, error)) (encrypted *EncryptedAuctionOrder, err error) {
+	if params == nil {
+		err = fmt.Errorf("Cannot create puzzle with nil puzzle params")
+		return
+	}
+
+	puzzleCreator := func(t uint64, key []byte) (puzzle crypto.Puzzle, answer []byte, err error) {
+		var timelock crypto.Timelock
+		if timelock, err = newTimelock(key, params.A, params.ModulusBits); err != nil {
+			err = fmt.Errorf("Error creating timelock with puzzle params: %s", err)
+			return
+		}
+		return timelock.SetupTimelockPuzzle(t)
+	}
+
+	encrypted = new(EncryptedAuctionOrder)
+	if encrypted.OrderCiphertext, encrypted.OrderPuzzle, err = timelockencoders.CreatePuzzleRC5(params.T, a.Serialize(), puzzleCreator); err != nil {
+		err = fmt.Errorf("Error creating puzzle from auction order: %s", err)
+		return
+	}
+	encrypted.IntendedAuction = a.AuctionID
+	encrypted.CipherType = CipherRC5
+	return
+}
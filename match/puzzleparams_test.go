@@ -0,0 +1,166 @@
+package match
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mit-dci/opencx/crypto/vdf"
+)
+
+func TestPuzzleParamsFresh(t *testing.T) {
+	var err error
+
+	var first, second *PuzzleParams
+	if first, err = NewPuzzleParams(1000, DefaultModulusBits); err != nil {
+		t.Errorf("Error creating puzzle params: %s", err)
+		return
+	}
+	if second, err = NewPuzzleParams(1000, DefaultModulusBits); err != nil {
+		t.Errorf("Error creating puzzle params: %s", err)
+		return
+	}
+
+	if first.A == second.A {
+		t.Errorf("Two sets of puzzle params had the same base %d", first.A)
+		return
+	}
+
+	if first.A < 2 || second.A < 2 {
+		t.Errorf("Puzzle bases %d and %d should be at least 2", first.A, second.A)
+		return
+	}
+
+	if _, err = NewPuzzleParams(1000, 0); err == nil {
+		t.Errorf("Puzzle params with a 0 bit modulus should fail")
+		return
+	}
+
+	return
+}
+
+func TestPuzzleParamsCheckPuzzle(t *testing.T) {
+	var err error
+
+	var params *PuzzleParams
+	if params, err = NewPuzzleParams(1000, 1024); err != nil {
+		t.Errorf("Error creating puzzle params: %s", err)
+		return
+	}
+
+	origOrder := goldenAuctionOrder()
+
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = origOrder.TurnIntoEncryptedOrderWithParams(params); err != nil {
+		t.Errorf("Error creating encrypted order with puzzle params: %s", err)
+		return
+	}
+
+	if err = params.CheckPuzzle(encOrder.OrderPuzzle); err != nil {
+		t.Errorf("Puzzle made with the params should pass the check: %s", err)
+		return
+	}
+
+	var orderBytes []byte
+	if orderBytes, err = encOrder.Solve(); err != nil {
+		t.Errorf("Error solving puzzle made with params: %s", err)
+		return
+	}
+
+	if !bytes.Equal(orderBytes, origOrder.Serialize()) {
+		t.Errorf("Puzzle made with params solved to the wrong order")
+		return
+	}
+
+	// A puzzle from another auction shouldn't pass
+	var otherParams *PuzzleParams
+	if otherParams, err = NewPuzzleParams(1000, 1024); err != nil {
+		t.Errorf("Error creating other puzzle params: %s", err)
+		return
+	}
+
+	if err = otherParams.CheckPuzzle(encOrder.OrderPuzzle); err == nil {
+		t.Errorf("Puzzle made with other params should not pass the check")
+		return
+	}
+
+	// Neither should one with a smaller modulus than required
+	biggerParams := *params
+	biggerParams.ModulusBits = 2048
+	if err = biggerParams.CheckPuzzle(encOrder.OrderPuzzle); err == nil {
+		t.Errorf("Puzzle with a 1024 bit modulus should not pass a check for 2048 bits")
+		return
+	}
+
+	return
+}
+
+func TestPuzzleParamsCheckVDFPuzzle(t *testing.T) {
+	var err error
+
+	var params *PuzzleParams
+	if params, err = NewPuzzleParams(1000, 1024); err != nil {
+		t.Errorf("Error creating puzzle params: %s", err)
+		return
+	}
+
+	origOrder := goldenAuctionOrder()
+
+	var encOrder *EncryptedAuctionOrder
+	if encOrder, err = origOrder.TurnIntoVDFEncryptedOrderWithParams(params); err != nil {
+		t.Errorf("Error creating VDF encrypted order with puzzle params: %s", err)
+		return
+	}
+
+	if err = params.CheckPuzzle(encOrder.OrderPuzzle); err != nil {
+		t.Errorf("VDF puzzle made with the params should pass the check: %s", err)
+		return
+	}
+
+	var otherParams *PuzzleParams
+	if otherParams, err = NewPuzzleParams(1000, 1024); err != nil {
+		t.Errorf("Error creating other puzzle params: %s", err)
+		return
+	}
+
+	if err = otherParams.CheckPuzzle(encOrder.OrderPuzzle); err == nil {
+		t.Errorf("VDF puzzle made with other params should not pass the check")
+		return
+	}
+
+	// Solving keeps the proof, which gives the same key without solving again
+	var key, orderBytes []byte
+	var solution *vdf.Solution
+	if key, solution, orderBytes, err = encOrder.SolveWithProof(); err != nil {
+		t.Errorf("Error solving VDF puzzle made with params: %s", err)
+		return
+	}
+
+	if !bytes.Equal(orderBytes, origOrder.Serialize()) {
+		t.Errorf("VDF puzzle made with params solved to the wrong order")
+		return
+	}
+
+	var verifiedKey []byte
+	if verifiedKey, err = encOrder.OrderPuzzle.(*vdf.PuzzleVDF).Verify(solution); err != nil {
+		t.Errorf("Solution of VDF puzzle should verify: %s", err)
+		return
+	}
+
+	if !bytes.Equal(verifiedKey, key) {
+		t.Errorf("Verified VDF solution gives key %x, solving gave %x", verifiedKey, key)
+		return
+	}
+
+	// RSW puzzles don't have a proof
+	if encOrder, err = origOrder.TurnIntoEncryptedOrderWithParams(params); err != nil {
+		t.Errorf("Error creating encrypted order with puzzle params: %s", err)
+		return
+	}
+
+	if _, solution, _, err = encOrder.SolveWithProof(); err != nil || solution != nil {
+		t.Errorf("RSW puzzle should solve without a solution, got %v, %v", solution, err)
+		return
+	}
+
+	return
+}
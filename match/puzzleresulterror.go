@@ -0,0 +1,85 @@
+package match
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PuzzleFailure is the reason an order puzzle result failed, so failures can be counted by why they happened
+type PuzzleFailure string
+
+const (
+	// PuzzleDecryptFailed means the puzzle couldn't be solved or the order couldn't be decrypted
+	PuzzleDecryptFailed PuzzleFailure = "decrypt-failed"
+	// PuzzleDeserializeFailed means the order was decrypted but isn't a valid auction order
+	PuzzleDeserializeFailed PuzzleFailure = "deserialize-failed"
+	// PuzzleAuctionMismatch means the decrypted order doesn't belong in the auction it was sent for
+	PuzzleAuctionMismatch PuzzleFailure = "auction-mismatch"
+	// PuzzleCancelled means solving was stopped before the puzzle was solved
+	PuzzleCancelled PuzzleFailure = "cancelled"
+)
+
+// String returns the name of the failure reason
+func (p PuzzleFailure) String() string {
+	return string(p)
+}
+
+// PuzzleResultError is the error in an order puzzle result. Reason is why the order failed, and Err is
+// the underlying error.
+type PuzzleResultError struct {
+	Reason PuzzleFailure
+	Err    error
+}
+
+// Error returns the reason along with the underlying error
+func (e *PuzzleResultError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PuzzleResultError) Unwrap() error {
+	return e.Err
+}
+
+// NewPuzzleResultError creates a puzzle result error with the given reason, with a formatted underlying error
+func NewPuzzleResultError(reason PuzzleFailure, format string, args ...interface{}) (err error) {
+	err = &PuzzleResultError{
+		Reason: reason,
+		Err:    fmt.Errorf(format, args...),
+	}
+	return
+}
+
+// PuzzleFailureReason returns the reason err failed, or an empty reason if err isn't a puzzle result error
+func PuzzleFailureReason(err error) (reason PuzzleFailure) {
+	if resultErr, ok := err.(*PuzzleResultError); ok {
+		reason = resultErr.Reason
+	}
+	return
+}
+
+// PuzzleFailureTally is how many results in a batch failed for each reason
+type PuzzleFailureTally map[PuzzleFailure]int
+
+// TallyPuzzleFailures counts the failed results by reason. Failed results that don't have a reason are
+// not counted.
+func TallyPuzzleFailures(results []*OrderPuzzleResult) (tally PuzzleFailureTally) {
+	tally = make(PuzzleFailureTally)
+	for _, result := range results {
+		if reason := PuzzleFailureReason(result.Err); reason != "" {
+			tally[reason]++
+		}
+	}
+	return
+}
+
+// String returns the counts for every reason, sorted so it reads the same every time
+func (t PuzzleFailureTally) String() string {
+	var counts []string
+	for reason, count := range t {
+		counts = append(counts, fmt.Sprintf("%s: %d", reason, count))
+	}
+	sort.Strings(counts)
+	return strings.Join(counts, ", ")
+}
@@ -0,0 +1,80 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/mit-dci/opencx/crypto/timelockencoders"
+)
+
+// solveForResult solves encOrder the same way the exchange would, and returns the result
+func solveForResult(encOrder *EncryptedAuctionOrder) (result *OrderPuzzleResult) {
+	resChan := make(chan *OrderPuzzleResult, 1)
+	SolveRC5AuctionOrderAsync(encOrder, resChan)
+	result = <-resChan
+	return
+}
+
+func TestSolveRC5AuctionOrderFailureReasons(t *testing.T) {
+	var err error
+
+	// Not RC5 at all
+	if reason := PuzzleFailureReason(solveForResult(goldenEncryptedAuctionOrder()).Err); reason != PuzzleDecryptFailed {
+		t.Errorf("Solving an RC6 order as RC5 should fail with %s, got %q", PuzzleDecryptFailed, reason)
+		return
+	}
+
+	// RC5, but there's no puzzle to solve
+	noPuzzle := &EncryptedAuctionOrder{
+		OrderCiphertext: make([]byte, 48),
+		CipherType:      CipherRC5,
+	}
+	if reason := PuzzleFailureReason(solveForResult(noPuzzle).Err); reason != PuzzleDecryptFailed {
+		t.Errorf("Solving an order without a puzzle should fail with %s, got %q", PuzzleDecryptFailed, reason)
+		return
+	}
+
+	// The puzzle solves fine but what's inside isn't an order
+	garbage := &EncryptedAuctionOrder{CipherType: CipherRC5}
+	if garbage.OrderCiphertext, garbage.OrderPuzzle, err = timelockencoders.CreateRSW2048A2PuzzleRC5(1000, []byte("definitely not an auction order")); err != nil {
+		t.Errorf("Error creating puzzle for garbage order: %s", err)
+		return
+	}
+
+	var result *OrderPuzzleResult
+	if result = solveForResult(garbage); PuzzleFailureReason(result.Err) != PuzzleDeserializeFailed {
+		t.Errorf("Solving a puzzle that isn't an order should fail with %s, got %v", PuzzleDeserializeFailed, result.Err)
+		return
+	}
+
+	// The deserialize error should still be in there
+	if result.Err.(*PuzzleResultError).Err == nil {
+		t.Errorf("Puzzle result error should keep the underlying error")
+		return
+	}
+
+	return
+}
+
+func TestTallyPuzzleFailures(t *testing.T) {
+	results := []*OrderPuzzleResult{
+		{Err: NewPuzzleResultError(PuzzleDecryptFailed, "bad puzzle")},
+		{Err: NewPuzzleResultError(PuzzleDeserializeFailed, "bad order")},
+		{Err: NewPuzzleResultError(PuzzleDecryptFailed, "another bad puzzle")},
+		{Err: NewPuzzleResultError(PuzzleAuctionMismatch, "wrong auction")},
+		// this one succeeded
+		{Auction: goldenAuctionOrder()},
+	}
+
+	tally := TallyPuzzleFailures(results)
+	if tally[PuzzleDecryptFailed] != 2 || tally[PuzzleDeserializeFailed] != 1 || tally[PuzzleAuctionMismatch] != 1 {
+		t.Errorf("Expected 2 decrypt, 1 deserialize, and 1 auction mismatch failure, got %s", tally)
+		return
+	}
+
+	if tally.String() != "auction-mismatch: 1, decrypt-failed: 2, deserialize-failed: 1" {
+		t.Errorf("Tally string %q is not sorted by reason", tally.String())
+		return
+	}
+
+	return
+}
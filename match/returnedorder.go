@@ -0,0 +1,89 @@
+package match
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ReturnReason is why an order didn't match in an auction and was returned to the user
+type ReturnReason byte
+
+const (
+	// ReturnPriceTooLow is for buy orders whose price was below the clearing price
+	ReturnPriceTooLow ReturnReason = 0x00
+	// ReturnPriceTooHigh is for sell orders whose price was above the clearing price
+	ReturnPriceTooHigh ReturnReason = 0x01
+	// ReturnCarriedForward is for orders whose price was good enough, but there wasn't enough volume on
+	// the other side to fill them. These are carried forward into the next auction.
+	ReturnCarriedForward ReturnReason = 0x02
+	// ReturnExpired is for orders in an auction that didn't clear at all
+	ReturnExpired ReturnReason = 0x03
+)
+
+// String returns a description of the reason
+func (r ReturnReason) String() string {
+	switch r {
+	case ReturnPriceTooLow:
+		return "price too low"
+	case ReturnPriceTooHigh:
+		return "price too high"
+	case ReturnCarriedForward:
+		return "carried forward"
+	case ReturnExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("unknown(%d)", byte(r))
+}
+
+// ReturnedOrder is an order that didn't match in an auction, along with why it didn't match
+type ReturnedOrder struct {
+	Order  *AuctionOrder `json:"order"`
+	Reason ReturnReason  `json:"reason"`
+}
+
+// ReturnUnmatchedOrders works out why each of the unmatched orders from a pair's auction didn't match,
+// given the auction's clearing price. A clearing price of zero means the auction didn't clear.
+func ReturnUnmatchedOrders(unmatched []*AuctionOrder, clearingPrice float64) (returned []*ReturnedOrder, err error) {
+	if clearingPrice < 0 {
+		err = fmt.Errorf("Clearing price cannot be negative")
+		return
+	}
+
+	// prices are compared exactly, so every node returns orders for the same reasons
+	var clearing *big.Rat
+	if clearing, err = ratFromPrice(clearingPrice); err != nil {
+		err = fmt.Errorf("Error getting exact clearing price: %s", err)
+		return
+	}
+
+	for _, order := range unmatched {
+		returnedOrder := &ReturnedOrder{
+			Order: order,
+		}
+
+		if clearingPrice == 0 {
+			returnedOrder.Reason = ReturnExpired
+			returned = append(returned, returnedOrder)
+			continue
+		}
+
+		var price *big.Rat
+		if price, err = order.PriceRational(); err != nil {
+			err = fmt.Errorf("Error getting price of unmatched order: %s", err)
+			return
+		}
+
+		switch {
+		case order.IsBuySide() && price.Cmp(clearing) < 0:
+			returnedOrder.Reason = ReturnPriceTooLow
+		case order.IsSellSide() && price.Cmp(clearing) > 0:
+			returnedOrder.Reason = ReturnPriceTooHigh
+		default:
+			returnedOrder.Reason = ReturnCarriedForward
+		}
+
+		returned = append(returned, returnedOrder)
+	}
+
+	return
+}
@@ -0,0 +1,60 @@
+package match
+
+import (
+	"testing"
+)
+
+func TestReturnUnmatchedOrders(t *testing.T) {
+	var err error
+
+	// buy price is want / have, sell price is have / want
+	var testCases = []struct {
+		name          string
+		order         *AuctionOrder
+		clearingPrice float64
+		reason        ReturnReason
+	}{
+		{"buy below clearing price", &AuctionOrder{Side: "buy", AmountHave: 1000, AmountWant: 500}, 1.0, ReturnPriceTooLow},
+		{"sell above clearing price", &AuctionOrder{Side: "sell", AmountHave: 2000, AmountWant: 1000}, 1.0, ReturnPriceTooHigh},
+		{"buy above clearing price", &AuctionOrder{Side: "buy", AmountHave: 1000, AmountWant: 2000}, 1.0, ReturnCarriedForward},
+		{"sell below clearing price", &AuctionOrder{Side: "sell", AmountHave: 500, AmountWant: 1000}, 1.0, ReturnCarriedForward},
+		{"buy at clearing price", &AuctionOrder{Side: "buy", AmountHave: 1000, AmountWant: 1000}, 1.0, ReturnCarriedForward},
+		{"buy in auction that didn't clear", &AuctionOrder{Side: "buy", AmountHave: 1000, AmountWant: 500}, 0, ReturnExpired},
+		{"sell in auction that didn't clear", &AuctionOrder{Side: "sell", AmountHave: 2000, AmountWant: 1000}, 0, ReturnExpired},
+	}
+
+	for _, testCase := range testCases {
+		var returned []*ReturnedOrder
+		if returned, err = ReturnUnmatchedOrders([]*AuctionOrder{testCase.order}, testCase.clearingPrice); err != nil {
+			t.Errorf("Error returning order for %s: %s", testCase.name, err)
+			return
+		}
+
+		if len(returned) != 1 {
+			t.Errorf("Should have returned 1 order for %s, returned %d", testCase.name, len(returned))
+			return
+		}
+
+		if returned[0].Order != testCase.order {
+			t.Errorf("Returned order for %s is not the unmatched order", testCase.name)
+			return
+		}
+
+		if returned[0].Reason != testCase.reason {
+			t.Errorf("Order for %s should have been returned with reason %s, got %s", testCase.name, testCase.reason, returned[0].Reason)
+			return
+		}
+	}
+
+	if _, err = ReturnUnmatchedOrders([]*AuctionOrder{{Side: "buy", AmountHave: 1000}}, 1.0); err == nil {
+		t.Errorf("Returning an order without a price should fail")
+		return
+	}
+
+	if _, err = ReturnUnmatchedOrders(nil, -1.0); err == nil {
+		t.Errorf("Returning orders with a negative clearing price should fail")
+		return
+	}
+
+	return
+}
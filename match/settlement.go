@@ -0,0 +1,174 @@
+package match
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// settlementAccount is a single balance that can be credited during settlement
+type settlementAccount struct {
+	pubkey [33]byte
+	asset  Asset
+}
+
+// settlementCredit is everything credited to a single account during settlement
+type settlementCredit struct {
+	pubkey *koblitz.PublicKey
+	asset  Asset
+	amount uint64
+}
+
+// SettlementCredits collects what every account is credited during settlement, so each balance is only
+// written once, no matter how many orders it was in.
+type SettlementCredits struct {
+	accounts map[settlementAccount]*settlementCredit
+	// credits are in the order accounts were first credited, so they are applied in the same order every time
+	credits []*settlementCredit
+	// debits are how much of each asset was taken out of orders, to check the credits against
+	debits map[Asset]uint64
+	mtx    *sync.Mutex
+}
+
+// NewSettlementCredits returns an empty set of settlement credits
+func NewSettlementCredits() (sc *SettlementCredits) {
+	sc = &SettlementCredits{
+		accounts: make(map[settlementAccount]*settlementCredit),
+		debits:   make(map[Asset]uint64),
+		mtx:      new(sync.Mutex),
+	}
+	return
+}
+
+// Add adds amount of asset to what pubkey is credited
+func (sc *SettlementCredits) Add(pubkey *koblitz.PublicKey, asset Asset, amount uint64) (err error) {
+	var account settlementAccount
+	copy(account.pubkey[:], pubkey.SerializeCompressed())
+	account.asset = asset
+
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+
+	credit, found := sc.accounts[account]
+	if !found {
+		credit = &settlementCredit{
+			pubkey: pubkey,
+			asset:  asset,
+		}
+		sc.accounts[account] = credit
+		sc.credits = append(sc.credits, credit)
+	}
+
+	if credit.amount > math.MaxUint64-amount {
+		err = fmt.Errorf("Crediting %d %s to %x would overflow its settlement credit of %d", amount, asset, account.pubkey, credit.amount)
+		return
+	}
+	credit.amount += amount
+
+	return
+}
+
+// Debit adds amount of asset to what was taken out of orders during settlement. Whatever is taken out of
+// orders should be credited to someone, which CheckBalanced checks.
+func (sc *SettlementCredits) Debit(asset Asset, amount uint64) (err error) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+
+	if sc.debits[asset] > math.MaxUint64-amount {
+		err = fmt.Errorf("Debiting %d %s would overflow the settlement debit of %d", amount, asset, sc.debits[asset])
+		return
+	}
+	sc.debits[asset] += amount
+
+	return
+}
+
+// CheckBalanced returns an error if the amount of any asset credited isn't the amount debited. Fees are
+// credited to the fee account, so they count as credits. Anything else means settlement created or lost
+// money, which is an accounting bug.
+func (sc *SettlementCredits) CheckBalanced() (err error) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+
+	credited := make(map[Asset]uint64)
+	for _, credit := range sc.credits {
+		if credited[credit.asset] > math.MaxUint64-credit.amount {
+			err = fmt.Errorf("Total settlement credits of %s overflow", credit.asset)
+			return
+		}
+		credited[credit.asset] += credit.amount
+	}
+
+	var assets []Asset
+	for asset := range credited {
+		assets = append(assets, asset)
+	}
+	for asset := range sc.debits {
+		if _, found := credited[asset]; !found {
+			assets = append(assets, asset)
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i] < assets[j]
+	})
+
+	for _, asset := range assets {
+		if credited[asset] != sc.debits[asset] {
+			err = fmt.Errorf("Settlement does not balance, %d %s was taken out of orders but %d was credited", sc.debits[asset], asset, credited[asset])
+			return
+		}
+	}
+
+	return
+}
+
+// CreditFunc returns a function that adds credits of asset, so it can be passed to FeeSchedule.Credit
+func (sc *SettlementCredits) CreditFunc(asset Asset) func(pubkey *koblitz.PublicKey, amount uint64) error {
+	return func(pubkey *koblitz.PublicKey, amount uint64) error {
+		return sc.Add(pubkey, asset, amount)
+	}
+}
+
+// Amount returns how much of asset pubkey has been credited
+func (sc *SettlementCredits) Amount(pubkey *koblitz.PublicKey, asset Asset) (amount uint64) {
+	var account settlementAccount
+	copy(account.pubkey[:], pubkey.SerializeCompressed())
+	account.asset = asset
+
+	sc.mtx.Lock()
+	if credit, found := sc.accounts[account]; found {
+		amount = credit.amount
+	}
+	sc.mtx.Unlock()
+	return
+}
+
+// Len returns how many accounts have been credited
+func (sc *SettlementCredits) Len() (accounts int) {
+	sc.mtx.Lock()
+	accounts = len(sc.credits)
+	sc.mtx.Unlock()
+	return
+}
+
+// Apply calls credit once for every account with everything it was credited, in the order the accounts were
+// first credited. credit is what actually writes the balance. Once a credit fails no more are applied, and the
+// error is returned, so the caller can roll back whatever was written.
+func (sc *SettlementCredits) Apply(credit func(pubkey *koblitz.PublicKey, asset Asset, amount uint64) error) (err error) {
+	sc.mtx.Lock()
+	credits := make([]*settlementCredit, len(sc.credits))
+	copy(credits, sc.credits)
+	sc.mtx.Unlock()
+
+	for _, thisCredit := range credits {
+		if err = credit(thisCredit.pubkey, thisCredit.asset, thisCredit.amount); err != nil {
+			err = fmt.Errorf("Error applying settlement credit of %d %s: %s", thisCredit.amount, thisCredit.asset, err)
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,246 @@
+package match
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// testBalanceStore is a balance store whose writes read the balance and write it back later, like
+// a select then an update. Concurrent writes to the same balance would lose credits.
+type testBalanceStore struct {
+	balances map[settlementAccount]uint64
+	writing  map[settlementAccount]bool
+	mtx      *sync.Mutex
+	// delay is how long a write takes between reading and writing the balance
+	delay time.Duration
+}
+
+func newTestBalanceStore(delay time.Duration) (store *testBalanceStore) {
+	store = &testBalanceStore{
+		balances: make(map[settlementAccount]uint64),
+		writing:  make(map[settlementAccount]bool),
+		mtx:      new(sync.Mutex),
+		delay:    delay,
+	}
+	return
+}
+
+// credit adds amount to the balance, failing if the balance is already being written
+func (store *testBalanceStore) credit(pubkey *koblitz.PublicKey, asset Asset, amount uint64) (err error) {
+	var account settlementAccount
+	copy(account.pubkey[:], pubkey.SerializeCompressed())
+	account.asset = asset
+
+	store.mtx.Lock()
+	if store.writing[account] {
+		store.mtx.Unlock()
+		err = fmt.Errorf("Balance for %x is already being written", account.pubkey)
+		return
+	}
+	store.writing[account] = true
+	balance := store.balances[account]
+	store.mtx.Unlock()
+
+	time.Sleep(store.delay)
+
+	store.mtx.Lock()
+	store.balances[account] = balance + amount
+	store.writing[account] = false
+	store.mtx.Unlock()
+	return
+}
+
+func TestSettlementCreditsApply(t *testing.T) {
+	var err error
+
+	var pubkeys []*koblitz.PublicKey
+	for i := 0; i < 20; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+		pubkeys = append(pubkeys, privkey.PubKey())
+	}
+
+	// Every account is credited by many orders, from many goroutines, in both assets
+	credits := NewSettlementCredits()
+	var wg sync.WaitGroup
+	for i := 0; i < 500; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if addErr := credits.CreditFunc(BTCTest)(pubkeys[i%len(pubkeys)], uint64(i)); addErr != nil {
+				t.Errorf("Error adding credit: %s", addErr)
+			}
+			if addErr := credits.Add(pubkeys[i%len(pubkeys)], VTCTest, 1); addErr != nil {
+				t.Errorf("Error adding credit: %s", addErr)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if credits.Len() != 2*len(pubkeys) {
+		t.Errorf("There should be a credit for every account, expected %d got %d", 2*len(pubkeys), credits.Len())
+		return
+	}
+
+	store := newTestBalanceStore(time.Millisecond)
+	if err = credits.Apply(store.credit); err != nil {
+		t.Errorf("Error applying settlement credits: %s", err)
+		return
+	}
+
+	for i, pubkey := range pubkeys {
+		var expected uint64
+		for j := i; j < 500; j += len(pubkeys) {
+			expected += uint64(j)
+		}
+
+		var account settlementAccount
+		copy(account.pubkey[:], pubkey.SerializeCompressed())
+		account.asset = BTCTest
+		if store.balances[account] != expected || credits.Amount(pubkey, BTCTest) != expected {
+			t.Errorf("Account %d should have been credited %d, got %d", i, expected, store.balances[account])
+			return
+		}
+
+		account.asset = VTCTest
+		if store.balances[account] != uint64(500/len(pubkeys)) {
+			t.Errorf("Account %d should have been credited %d, got %d", i, 500/len(pubkeys), store.balances[account])
+			return
+		}
+	}
+
+	return
+}
+
+func TestSettlementCreditsApplyStopsOnError(t *testing.T) {
+	var err error
+
+	credits := NewSettlementCredits()
+	for i := 0; i < 50; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+		if err = credits.Add(privkey.PubKey(), BTCTest, 1); err != nil {
+			t.Errorf("Error adding credit: %s", err)
+			return
+		}
+	}
+
+	calls := 0
+	callsMtx := new(sync.Mutex)
+	if err = credits.Apply(func(pubkey *koblitz.PublicKey, asset Asset, amount uint64) (err error) {
+		callsMtx.Lock()
+		calls++
+		callsMtx.Unlock()
+		err = fmt.Errorf("db is down")
+		return
+	}); err == nil {
+		t.Errorf("Applying settlement credits should fail if a credit fails")
+		return
+	}
+
+	if calls != 1 {
+		t.Errorf("No more credits should be applied after one fails, got %d calls", calls)
+		return
+	}
+
+	return
+}
+
+func TestSettlementCreditsCheckBalanced(t *testing.T) {
+	var err error
+
+	var buyKey, sellKey, feeKey *koblitz.PrivateKey
+	for _, key := range []**koblitz.PrivateKey{&buyKey, &sellKey, &feeKey} {
+		if *key, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			t.Errorf("Error creating key: %s", err)
+			return
+		}
+	}
+
+	var fees *FeeSchedule
+	if fees, err = NewFeeSchedule(100, feeKey.PubKey()); err != nil {
+		t.Errorf("Error creating fee schedule: %s", err)
+		return
+	}
+
+	// The buyer gives 2000 VTC for the seller's 1000 BTC, with fees taken out of both
+	pair := &Pair{AssetWant: BTCTest, AssetHave: VTCTest}
+	credits := NewSettlementCredits()
+	if err = fees.CreditPairAsset(pair, pair.AssetWant, buyKey.PubKey(), 1000, credits.CreditFunc(pair.AssetWant)); err != nil {
+		t.Errorf("Error crediting buyer: %s", err)
+		return
+	}
+	if err = fees.CreditPairAsset(pair, pair.AssetHave, sellKey.PubKey(), 2000, credits.CreditFunc(pair.AssetHave)); err != nil {
+		t.Errorf("Error crediting seller: %s", err)
+		return
+	}
+	if err = credits.Debit(pair.AssetWant, 1000); err != nil {
+		t.Errorf("Error debiting %s: %s", pair.AssetWant, err)
+		return
+	}
+	if err = credits.Debit(pair.AssetHave, 2000); err != nil {
+		t.Errorf("Error debiting %s: %s", pair.AssetHave, err)
+		return
+	}
+
+	// Fees go to the fee account, so they still balance
+	if err = credits.CheckBalanced(); err != nil {
+		t.Errorf("Settlement with fees should balance: %s", err)
+		return
+	}
+
+	// Crediting more than came out of the orders makes money
+	if err = credits.Add(buyKey.PubKey(), pair.AssetWant, 1); err != nil {
+		t.Errorf("Error adding credit: %s", err)
+		return
+	}
+	if err = credits.CheckBalanced(); err == nil {
+		t.Errorf("Settlement that credits more than was debited should fail its self-check")
+		return
+	}
+
+	// Taking out of orders what isn't credited loses money
+	credits = NewSettlementCredits()
+	if err = credits.Debit(LTCTest, 5); err != nil {
+		t.Errorf("Error debiting %s: %s", LTCTest, err)
+		return
+	}
+	if err = credits.CheckBalanced(); err == nil {
+		t.Errorf("Settlement that debits what it doesn't credit should fail its self-check")
+		return
+	}
+
+	return
+}
+
+// BenchmarkSettlementCredits applies credits to 1000 accounts, each write taking 100 microseconds
+func BenchmarkSettlementCredits(b *testing.B) {
+	credits := NewSettlementCredits()
+	for i := 0; i < 1000; i++ {
+		privkey, err := koblitz.NewPrivateKey(koblitz.S256())
+		if err != nil {
+			b.Fatalf("Error creating key: %s", err)
+		}
+		if err = credits.Add(privkey.PubKey(), BTCTest, uint64(i)); err != nil {
+			b.Fatalf("Error adding credit: %s", err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store := newTestBalanceStore(100 * time.Microsecond)
+		if err := credits.Apply(store.credit); err != nil {
+			b.Fatalf("Error applying settlement credits: %s", err)
+		}
+	}
+}
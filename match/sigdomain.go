@@ -0,0 +1,36 @@
+package match
+
+import (
+	"encoding/binary"
+)
+
+// SigDomain is a domain separation tag that is hashed in before anything that gets signed, so a signature
+// made in one part of the protocol can't be replayed as a valid signature in another part.
+type SigDomain string
+
+const (
+	// AuctionOrderDomain is the domain for signatures of auction orders
+	AuctionOrderDomain SigDomain = "opencx-order-v1"
+	// LimitOrderDomain is the domain for signatures of limit orders on the continuous exchange
+	LimitOrderDomain SigDomain = "opencx-limitorder-v1"
+	// RegistrationDomain is the domain for signatures of the registration string
+	RegistrationDomain SigDomain = "opencx-register-v1"
+	// WithdrawalDomain is the domain for signatures of withdrawals
+	WithdrawalDomain SigDomain = "opencx-withdraw-v1"
+)
+
+// SigHash returns the hash that should be signed for msg in this domain, which is
+// h(len(tag) || tag || msg). The tag length is included so no tag can be a prefix of another
+// tag plus a message.
+func (d SigDomain) SigHash(msg []byte) (e []byte) {
+	var lenTagBytes [8]byte
+	binary.BigEndian.PutUint64(lenTagBytes[:], uint64(len(d)))
+	e = sha3Sum(lenTagBytes[:], []byte(d), msg)
+	return
+}
+
+// SigHash returns the hash that the order's signature should be made over
+func (a *AuctionOrder) SigHash() (e []byte) {
+	e = AuctionOrderDomain.SigHash(a.SerializeSignable())
+	return
+}
@@ -0,0 +1,90 @@
+package match
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// signedDomainTestOrder creates an order with privkey's pubkey, signed in the order domain
+func signedDomainTestOrder(privkey *koblitz.PrivateKey) (order *AuctionOrder, err error) {
+	order = goldenAuctionOrder()
+	copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+	order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, order.SigHash(), false)
+	return
+}
+
+func TestSigDomainsDiffer(t *testing.T) {
+	msg := goldenAuctionOrder().SerializeSignable()
+	domains := []SigDomain{AuctionOrderDomain, LimitOrderDomain, RegistrationDomain, WithdrawalDomain}
+	for i := range domains {
+		for j := i + 1; j < len(domains); j++ {
+			if bytes.Equal(domains[i].SigHash(msg), domains[j].SigHash(msg)) {
+				t.Errorf("Domains %s and %s give the same hash for the same message", domains[i], domains[j])
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func TestOrderSignatureNotValidForRegistration(t *testing.T) {
+	var err error
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key: %s", err)
+		return
+	}
+
+	var order *AuctionOrder
+	if order, err = signedDomainTestOrder(privkey); err != nil {
+		t.Errorf("Error signing test order: %s", err)
+		return
+	}
+
+	var valid bool
+	if valid, err = order.VerifySignature(); err != nil || !valid {
+		t.Errorf("Order signed in the order domain should verify: %v", err)
+		return
+	}
+
+	// The registration check recovers a pubkey from the signature, so it should not recover ours
+	var recovered *koblitz.PublicKey
+	if recovered, _, err = koblitz.RecoverCompact(koblitz.S256(), order.Signature, RegistrationDomain.SigHash(order.SerializeSignable())); err == nil && recovered.IsEqual(privkey.PubKey()) {
+		t.Errorf("Order signature should not be a valid registration signature for the order's pubkey")
+		return
+	}
+
+	return
+}
+
+func TestRegistrationSignatureNotValidForOrder(t *testing.T) {
+	var err error
+	var privkey *koblitz.PrivateKey
+	if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+		t.Errorf("Error creating private key: %s", err)
+		return
+	}
+
+	var order *AuctionOrder
+	if order, err = signedDomainTestOrder(privkey); err != nil {
+		t.Errorf("Error signing test order: %s", err)
+		return
+	}
+
+	// Sign the exact same bytes as a registration, and try to pass it off as the order's signature
+	if order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, RegistrationDomain.SigHash(order.SerializeSignable()), false); err != nil {
+		t.Errorf("Error signing registration: %s", err)
+		return
+	}
+
+	var valid bool
+	if valid, err = order.VerifySignature(); err == nil && valid {
+		t.Errorf("Registration signature should not verify as an order signature")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,269 @@
+package match
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// compactSigSize is the size of a compact signature, which is a 1 byte header, then 32 byte R and 32 byte S
+const compactSigSize = 65
+
+// CheckLowS makes sure a compact signature is canonical, meaning its S value is at most half of the curve order.
+// For any valid signature (R, S) there is another valid signature (R, N - S) for the same message and pubkey,
+// so if we didn't enforce this then anyone could make a different valid signature for the same order.
+func CheckLowS(compactSig []byte) (err error) {
+	if len(compactSig) != compactSigSize {
+		err = fmt.Errorf("Compact signature should be %d bytes, but is %d bytes", compactSigSize, len(compactSig))
+		return
+	}
+
+	sigS := new(big.Int).SetBytes(compactSig[33:])
+	halfOrder := new(big.Int).Rsh(koblitz.S256().Params().N, 1)
+	if sigS.Cmp(halfOrder) > 0 {
+		err = fmt.Errorf("Signature has a high S value, only canonical low S signatures are accepted")
+		return
+	}
+
+	return
+}
+
+// VerifySignature checks that the order's signature is canonical, and that it was made by the order's pubkey
+// over the signable part of the order. valid is false if the signature is well formed but wasn't made by the
+// pubkey over this order, like if a field was changed after signing. An error means the signature couldn't be
+// checked at all, because the pubkey or signature is malformed.
+func (a *AuctionOrder) VerifySignature() (valid bool, err error) {
+	// We could use pub key hashes here but there might not be any reason for it
+	var orderPublicKey *koblitz.PublicKey
+	if orderPublicKey, err = a.ParsePubkey(); err != nil {
+		return
+	}
+
+	// Reject malleable signatures, otherwise the same order could show up with different signatures
+	if err = CheckLowS(a.Signature); err != nil {
+		err = fmt.Errorf("Orders with a non canonical signature are invalid: %s", err)
+		return
+	}
+
+	// e = h(domain || order)
+	e := a.SigHash()
+
+	var recoveredPublickey *koblitz.PublicKey
+	if recoveredPublickey, _, err = koblitz.RecoverCompact(koblitz.S256(), a.Signature, e); err != nil {
+		err = fmt.Errorf("Orders whose signature cannot be verified with pubkey recovery are invalid: %s", err)
+		return
+	}
+
+	valid = recoveredPublickey.IsEqual(orderPublicKey)
+	return
+}
+
+// checkSignature checks the order's signature like VerifySignature, but a signature that isn't valid is an
+// error too
+func (a *AuctionOrder) checkSignature() (err error) {
+	var valid bool
+	if valid, err = a.VerifySignature(); err != nil {
+		return
+	}
+
+	if !valid {
+		err = fmt.Errorf("Signature was not made by pubkey %x over the order", a.Pubkey)
+		return
+	}
+
+	return
+}
+
+// VerifySignatureForAuction checks the order's signature like VerifySignature, and that it was signed for
+// auctionID. The auction ID is part of what's signed, so an order can't be moved into a different auction,
+// even if its ciphertext is sent again for that auction. Unlike VerifySignature, an invalid signature is an
+// error.
+func (a *AuctionOrder) VerifySignatureForAuction(auctionID [32]byte) (err error) {
+	if a.AuctionID != auctionID {
+		err = fmt.Errorf("Order was signed for auction %x, not auction %x", a.AuctionID, auctionID)
+		return
+	}
+
+	if err = a.checkSignature(); err != nil {
+		return
+	}
+
+	return
+}
+
+// VerifyMetrics are the throughput metrics for a batch verifier
+type VerifyMetrics struct {
+	// OrdersVerified is the number of orders whose signatures have been checked
+	OrdersVerified uint64
+	// InvalidOrders is the number of orders whose signatures were invalid
+	InvalidOrders uint64
+	// Batches is the number of batches that have been verified
+	Batches uint64
+	// VerifyTime is the total time spent verifying batches
+	VerifyTime time.Duration
+}
+
+// OrdersPerSecond is the number of orders verified per second of time spent verifying
+func (m VerifyMetrics) OrdersPerSecond() (ordersPerSecond float64) {
+	if m.VerifyTime == 0 {
+		return
+	}
+	ordersPerSecond = float64(m.OrdersVerified) / m.VerifyTime.Seconds()
+	return
+}
+
+// String returns a summary of the metrics, to be logged
+func (m VerifyMetrics) String() string {
+	return fmt.Sprintf("%d orders verified (%d invalid) in %d batches in %s (%f orders/s)", m.OrdersVerified, m.InvalidOrders, m.Batches, m.VerifyTime, m.OrdersPerSecond())
+}
+
+// BatchVerifier verifies the signatures of batches of orders with a fixed number of workers, and keeps
+// track of how fast it's verifying.
+type BatchVerifier struct {
+	workers int
+	// verify is what each worker runs on an order, this is only changed by tests
+	verify     func(order *AuctionOrder) error
+	metrics    VerifyMetrics
+	metricsMtx *sync.Mutex
+}
+
+// NewBatchVerifier creates a batch verifier that verifies with the given number of workers. If workers
+// is zero, GOMAXPROCS workers are used.
+func NewBatchVerifier(workers int) (bv *BatchVerifier, err error) {
+	if workers < 0 {
+		err = fmt.Errorf("Number of verification workers cannot be negative")
+		return
+	}
+
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	bv = &BatchVerifier{
+		workers: workers,
+		verify: func(order *AuctionOrder) error {
+			return order.checkSignature()
+		},
+		metricsMtx: new(sync.Mutex),
+	}
+	return
+}
+
+// Workers returns the number of workers the verifier verifies with
+func (bv *BatchVerifier) Workers() int {
+	return bv.workers
+}
+
+// Metrics returns the current throughput metrics
+func (bv *BatchVerifier) Metrics() (metrics VerifyMetrics) {
+	bv.metricsMtx.Lock()
+	metrics = bv.metrics
+	bv.metricsMtx.Unlock()
+	return
+}
+
+// Verify verifies the signatures of a whole batch of orders, spreading the work over the verifier's workers.
+// valid[i] is whether or not orders[i] has a valid signature. An error is only returned if the batch itself
+// can't be verified, like if one of the orders is nil.
+func (bv *BatchVerifier) Verify(orders []*AuctionOrder) (valid []bool, err error) {
+	return bv.verifyBatch(orders, nil)
+}
+
+// VerifyForAuctions verifies the signatures of a whole batch of orders like Verify, and also checks that
+// orders[i] was signed for auctionIDs[i], like VerifySignatureForAuction.
+func (bv *BatchVerifier) VerifyForAuctions(orders []*AuctionOrder, auctionIDs [][32]byte) (valid []bool, err error) {
+	if len(auctionIDs) != len(orders) {
+		err = fmt.Errorf("Error verifying order batch, got %d auction IDs for %d orders", len(auctionIDs), len(orders))
+		return
+	}
+
+	return bv.verifyBatch(orders, auctionIDs)
+}
+
+// verifyBatch verifies a batch of orders with the verifier's workers. If auctionIDs isn't nil, orders[i]
+// also has to be signed for auctionIDs[i].
+func (bv *BatchVerifier) verifyBatch(orders []*AuctionOrder, auctionIDs [][32]byte) (valid []bool, err error) {
+	for i, order := range orders {
+		if order == nil {
+			err = fmt.Errorf("Error verifying order batch, order %d is nil", i)
+			return
+		}
+	}
+
+	start := time.Now()
+	valid = make([]bool, len(orders))
+
+	numWorkers := bv.workers
+	if numWorkers > len(orders) {
+		numWorkers = len(orders)
+	}
+
+	// Workers pull order indexes off the channel, and each index is only written to by one worker
+	indexChan := make(chan int, len(orders))
+	for i := range orders {
+		indexChan <- i
+	}
+	close(indexChan)
+
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexChan {
+				// an order signed for the wrong auction is invalid no matter what its signature is
+				if auctionIDs != nil && orders[i].AuctionID != auctionIDs[i] {
+					continue
+				}
+				valid[i] = bv.verifyOne(orders[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var invalid uint64
+	for _, orderValid := range valid {
+		if !orderValid {
+			invalid++
+		}
+	}
+
+	bv.metricsMtx.Lock()
+	bv.metrics.OrdersVerified += uint64(len(orders))
+	bv.metrics.InvalidOrders += invalid
+	bv.metrics.Batches++
+	bv.metrics.VerifyTime += time.Since(start)
+	bv.metricsMtx.Unlock()
+
+	return
+}
+
+// verifyOne verifies a single order. If verifying the order panics then it's invalid, so one bad order
+// doesn't take down the whole batch.
+func (bv *BatchVerifier) verifyOne(order *AuctionOrder) (valid bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			valid = false
+		}
+	}()
+
+	valid = bv.verify(order) == nil
+	return
+}
+
+// VerifyOrdersBatch verifies the signatures of a whole batch of orders, spreading the work over GOMAXPROCS
+// workers. valid[i] is whether or not orders[i] has a valid signature. An error is only returned if the
+// batch itself can't be verified, like if one of the orders is nil.
+func VerifyOrdersBatch(orders []*AuctionOrder) (valid []bool, err error) {
+	var bv *BatchVerifier
+	if bv, err = NewBatchVerifier(0); err != nil {
+		err = fmt.Errorf("Error creating batch verifier: %s", err)
+		return
+	}
+
+	return bv.Verify(orders)
+}
@@ -0,0 +1,357 @@
+package match
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/lit/crypto/koblitz"
+)
+
+// signedTestOrders creates howMany orders signed by random keys
+func signedTestOrders(howMany int) (orders []*AuctionOrder, err error) {
+	for i := 0; i < howMany; i++ {
+		var privkey *koblitz.PrivateKey
+		if privkey, err = koblitz.NewPrivateKey(koblitz.S256()); err != nil {
+			err = fmt.Errorf("Error creating private key for test order: %s", err)
+			return
+		}
+
+		order := &AuctionOrder{
+			Side: "buy",
+			TradingPair: Pair{
+				AssetWant: BTCReg,
+				AssetHave: LTCReg,
+			},
+			AmountHave: uint64(1000 + i),
+			AmountWant: 1000,
+			Nonce:      [2]byte{byte(i >> 8), byte(i)},
+			Network:    RegtestMagic,
+		}
+		copy(order.Pubkey[:], privkey.PubKey().SerializeCompressed())
+
+		if order.Signature, err = koblitz.SignCompact(koblitz.S256(), privkey, order.SigHash(), false); err != nil {
+			err = fmt.Errorf("Error signing test order: %s", err)
+			return
+		}
+
+		orders = append(orders, order)
+	}
+	return
+}
+
+func TestVerifyOrdersBatch(t *testing.T) {
+	var err error
+
+	var orders []*AuctionOrder
+	if orders, err = signedTestOrders(50); err != nil {
+		t.Errorf("Error creating orders for batch verify test: %s", err)
+		return
+	}
+
+	expected := make([]bool, len(orders))
+	for i := range expected {
+		expected[i] = true
+	}
+
+	// Mix in some invalid orders: changed after signing, signed by someone else, and a garbage signature
+	orders[3].AmountWant++
+	expected[3] = false
+
+	orders[17].Pubkey = orders[18].Pubkey
+	expected[17] = false
+
+	orders[42].Signature = orders[42].Signature[:10]
+	expected[42] = false
+
+	var valid []bool
+	if valid, err = VerifyOrdersBatch(orders); err != nil {
+		t.Errorf("Error verifying order batch: %s", err)
+		return
+	}
+
+	if len(valid) != len(orders) {
+		t.Errorf("Got %d results for %d orders", len(valid), len(orders))
+		return
+	}
+
+	for i := range orders {
+		if valid[i] != expected[i] {
+			t.Errorf("Order %d should have had validity %t, got %t", i, expected[i], valid[i])
+			return
+		}
+
+		// The batch should always agree with verifying on its own
+		if singleValid, singleErr := orders[i].VerifySignature(); valid[i] != (singleValid && singleErr == nil) {
+			t.Errorf("Batch verification and single verification disagree on order %d", i)
+			return
+		}
+	}
+
+	if _, err = VerifyOrdersBatch([]*AuctionOrder{orders[0], nil}); err == nil {
+		t.Errorf("Verifying a batch with a nil order should fail")
+		return
+	}
+
+	if valid, err = VerifyOrdersBatch(nil); err != nil || len(valid) != 0 {
+		t.Errorf("Verifying an empty batch should return no results and no error")
+		return
+	}
+
+	return
+}
+
+func TestVerifySignatureTamperedFields(t *testing.T) {
+	var err error
+
+	// A known key, so a failure here can be reproduced
+	var keyBytes [32]byte
+	for i := range keyBytes {
+		keyBytes[i] = byte(i + 1)
+	}
+	privkey, _ := koblitz.PrivKeyFromBytes(koblitz.S256(), keyBytes[:])
+
+	var order *AuctionOrder
+	if order, err = signedDomainTestOrder(privkey); err != nil {
+		t.Errorf("Error signing test order: %s", err)
+		return
+	}
+
+	var valid bool
+	if valid, err = order.VerifySignature(); err != nil || !valid {
+		t.Errorf("Order signed with a known key should verify: %v", err)
+		return
+	}
+
+	var tests = []struct {
+		name   string
+		tamper func(order *AuctionOrder)
+	}{
+		{"side", func(order *AuctionOrder) { order.Side = order.OppositeSide() }},
+		{"amount have", func(order *AuctionOrder) { order.AmountHave++ }},
+		{"amount want", func(order *AuctionOrder) { order.AmountWant-- }},
+		{"pair", func(order *AuctionOrder) { order.TradingPair.AssetWant++ }},
+		{"auction ID", func(order *AuctionOrder) { order.AuctionID[0] ^= 0x01 }},
+		{"nonce", func(order *AuctionOrder) { order.Nonce[1]++ }},
+		{"network", func(order *AuctionOrder) { order.Network = MainnetMagic }},
+		{"signature", func(order *AuctionOrder) { order.Signature[40] ^= 0x01 }},
+	}
+
+	for _, test := range tests {
+		tampered := *order
+		tampered.Signature = append([]byte{}, order.Signature...)
+		test.tamper(&tampered)
+
+		// A changed field is still a well formed signature, it just isn't valid for the order
+		if valid, err = tampered.VerifySignature(); err != nil || valid {
+			t.Errorf("Order with a changed %s should be well formed but not verify, got valid %t and error %v", test.name, valid, err)
+			return
+		}
+	}
+
+	return
+}
+
+func TestVerifySignatureForAuction(t *testing.T) {
+	var err error
+
+	var orders []*AuctionOrder
+	if orders, err = signedTestOrders(3); err != nil {
+		t.Errorf("Error creating orders: %s", err)
+		return
+	}
+
+	signedAuction := orders[0].AuctionID
+	otherAuction := signedAuction
+	otherAuction[0]++
+
+	if err = orders[0].VerifySignatureForAuction(signedAuction); err != nil {
+		t.Errorf("Order should verify for the auction it was signed for: %s", err)
+		return
+	}
+
+	if err = orders[0].VerifySignatureForAuction(otherAuction); err == nil {
+		t.Errorf("Order should not verify for an auction it wasn't signed for")
+		return
+	}
+
+	// Replaying it into the other auction by changing its auction ID breaks the signature
+	replayed := orders[0].Clone()
+	replayed.AuctionID = otherAuction
+	if err = replayed.VerifySignatureForAuction(otherAuction); err == nil {
+		t.Errorf("Order moved to another auction should not verify")
+		return
+	}
+
+	var bv *BatchVerifier
+	if bv, err = NewBatchVerifier(2); err != nil {
+		t.Errorf("Error creating batch verifier: %s", err)
+		return
+	}
+
+	var valid []bool
+	if valid, err = bv.VerifyForAuctions([]*AuctionOrder{orders[1], orders[2], replayed}, [][32]byte{signedAuction, otherAuction, otherAuction}); err != nil {
+		t.Errorf("Error verifying batch for auctions: %s", err)
+		return
+	}
+
+	if !valid[0] || valid[1] || valid[2] {
+		t.Errorf("Only the order signed for its auction should be valid, got %v", valid)
+		return
+	}
+
+	if metrics := bv.Metrics(); metrics.InvalidOrders != 2 {
+		t.Errorf("Orders for the wrong auction should be counted as invalid, got %d invalid", metrics.InvalidOrders)
+		return
+	}
+
+	if _, err = bv.VerifyForAuctions(orders, [][32]byte{signedAuction}); err == nil {
+		t.Errorf("Verifying with a different number of auction IDs than orders should fail")
+		return
+	}
+
+	return
+}
+
+func TestBatchVerifierWorkers(t *testing.T) {
+	var err error
+
+	var bv *BatchVerifier
+	if bv, err = NewBatchVerifier(0); err != nil {
+		t.Errorf("Error creating default batch verifier: %s", err)
+		return
+	}
+
+	if bv.Workers() != runtime.GOMAXPROCS(0) {
+		t.Errorf("Default batch verifier should have GOMAXPROCS (%d) workers, has %d", runtime.GOMAXPROCS(0), bv.Workers())
+		return
+	}
+
+	if _, err = NewBatchVerifier(-1); err == nil {
+		t.Errorf("Creating a batch verifier with negative workers should fail")
+		return
+	}
+
+	const workers = 3
+	if bv, err = NewBatchVerifier(workers); err != nil {
+		t.Errorf("Error creating batch verifier with %d workers: %s", workers, err)
+		return
+	}
+
+	// Keep track of how many verifications are happening at once
+	countMtx := new(sync.Mutex)
+	var active, mostActive int
+	bv.verify = func(order *AuctionOrder) error {
+		countMtx.Lock()
+		active++
+		if active > mostActive {
+			mostActive = active
+		}
+		countMtx.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		countMtx.Lock()
+		active--
+		countMtx.Unlock()
+		return nil
+	}
+
+	orders := make([]*AuctionOrder, 10*workers)
+	for i := range orders {
+		orders[i] = new(AuctionOrder)
+	}
+
+	if _, err = bv.Verify(orders); err != nil {
+		t.Errorf("Error verifying batch: %s", err)
+		return
+	}
+
+	if mostActive != workers {
+		t.Errorf("Batch verifier with %d workers had %d verifications running at once", workers, mostActive)
+		return
+	}
+
+	metrics := bv.Metrics()
+	if metrics.OrdersVerified != uint64(len(orders)) || metrics.Batches != 1 {
+		t.Errorf("Metrics should show %d orders in 1 batch, got %s", len(orders), metrics)
+		return
+	}
+
+	return
+}
+
+func BenchmarkVerifyOrdersBatch1000(b *testing.B) {
+	orders, err := signedTestOrders(1000)
+	if err != nil {
+		b.Fatalf("Error creating orders for batch verify benchmark: %s", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = VerifyOrdersBatch(orders); err != nil {
+			b.Fatalf("Error verifying order batch: %s", err)
+		}
+	}
+}
+
+func BenchmarkVerifyOrdersSequential1000(b *testing.B) {
+	orders, err := signedTestOrders(1000)
+	if err != nil {
+		b.Fatalf("Error creating orders for sequential verify benchmark: %s", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, order := range orders {
+			order.VerifySignature()
+		}
+	}
+}
+
+func TestBatchVerifierRecoversPanics(t *testing.T) {
+	var err error
+
+	var orders []*AuctionOrder
+	if orders, err = signedTestOrders(5); err != nil {
+		t.Errorf("Error creating signed test orders: %s", err)
+		return
+	}
+
+	var bv *BatchVerifier
+	if bv, err = NewBatchVerifier(2); err != nil {
+		t.Errorf("Error creating batch verifier: %s", err)
+		return
+	}
+
+	// Verifying the middle order panics, the rest should still be verified
+	panicOrder := orders[2]
+	bv.verify = func(order *AuctionOrder) error {
+		if order == panicOrder {
+			panic("malformed order")
+		}
+		return order.checkSignature()
+	}
+
+	var valid []bool
+	if valid, err = bv.Verify(orders); err != nil {
+		t.Errorf("Error verifying batch with a panicking order: %s", err)
+		return
+	}
+
+	for i, orderValid := range valid {
+		if orderValid == (i == 2) {
+			t.Errorf("Order %d should be valid unless it panicked, got valid = %t", i, orderValid)
+			return
+		}
+	}
+
+	if metrics := bv.Metrics(); metrics.OrdersVerified != 5 || metrics.InvalidOrders != 1 {
+		t.Errorf("Metrics should show 5 orders verified with 1 invalid, got %s", metrics)
+		return
+	}
+
+	return
+}
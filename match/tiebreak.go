@@ -0,0 +1,20 @@
+package match
+
+import (
+	"bytes"
+	"sort"
+)
+
+// SortByTieBreak sorts orders by their tie-break hash, lowest first. The order only depends on the orders
+// themselves, not the order they're given in, so every node that has the same orders sorts them the same.
+func SortByTieBreak(orders []*AuctionOrder) {
+	hashes := make(map[*AuctionOrder][32]byte, len(orders))
+	for _, order := range orders {
+		hashes[order] = order.TieBreakHash()
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		iHash, jHash := hashes[orders[i]], hashes[orders[j]]
+		return bytes.Compare(iHash[:], jHash[:]) < 0
+	})
+}
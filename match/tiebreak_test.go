@@ -0,0 +1,101 @@
+package match
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestSortByTieBreakReproducible(t *testing.T) {
+	var err error
+
+	// the same order signed differently, and some other orders
+	var orders []*AuctionOrder
+	for i := 0; i < 6; i++ {
+		order := proRataTestOrder("sell", 10, 10, byte(i/2))
+		order.Signature = []byte{byte(i), 0x01, 0x02}
+		orders = append(orders, order)
+	}
+
+	if orders[0].Hash() != orders[1].Hash() || orders[0].TieBreakHash() == orders[1].TieBreakHash() {
+		t.Errorf("Tie-break hash should depend on the signature, and the order hash shouldn't")
+		return
+	}
+
+	sorted := append([]*AuctionOrder{}, orders...)
+	SortByTieBreak(sorted)
+	for i := 1; i < len(sorted); i++ {
+		prev, curr := sorted[i-1].TieBreakHash(), sorted[i].TieBreakHash()
+		if bytes.Compare(prev[:], curr[:]) >= 0 {
+			t.Errorf("Orders should be sorted by tie-break hash, lowest first")
+			return
+		}
+	}
+
+	// Copies of the orders, like another node would have after getting them over the wire, in a different
+	// order, should sort the same
+	var copies []*AuctionOrder
+	for i := len(orders) - 1; i >= 0; i-- {
+		decoded := new(AuctionOrder)
+		if err = decoded.Deserialize(orders[i].Serialize()); err != nil {
+			t.Errorf("Error deserializing order: %s", err)
+			return
+		}
+		copies = append(copies, decoded)
+	}
+	copies[0], copies[3] = copies[3], copies[0]
+
+	SortByTieBreak(copies)
+	for i := range sorted {
+		if !bytes.Equal(copies[i].Serialize(), sorted[i].Serialize()) {
+			t.Errorf("Order %d should be the same no matter what order the orders were given in", i)
+			return
+		}
+	}
+
+	// sorting again doesn't change anything
+	resorted := append([]*AuctionOrder{}, sorted...)
+	SortByTieBreak(resorted)
+	for i := range sorted {
+		if resorted[i] != sorted[i] {
+			t.Errorf("Sorting sorted orders should not change their order")
+			return
+		}
+	}
+
+	return
+}
+
+func TestAllocateProRataTieBreakBySignature(t *testing.T) {
+	var err error
+
+	clearing := big.NewRat(1, 1)
+
+	// Two sells of the same size at the same price share 1, so the remainders and sizes tie, and the
+	// signatures decide.
+	first := proRataTestOrder("sell", 10, 10, 0)
+	first.Signature = []byte{0x01}
+	second := proRataTestOrder("sell", 10, 10, 1)
+	second.Signature = []byte{0x02}
+	buy := proRataTestOrder("buy", 1, 1, 2)
+
+	winner, loser := first, second
+	if firstHash, secondHash := first.TieBreakHash(), second.TieBreakHash(); bytes.Compare(secondHash[:], firstHash[:]) < 0 {
+		winner, loser = second, first
+	}
+
+	for _, orders := range [][]*AuctionOrder{{first, second, buy}, {buy, second, first}} {
+		var fills map[[32]byte]uint64
+		if fills, err = AllocateProRata(orders, clearing); err != nil {
+			t.Errorf("Error allocating fills: %s", err)
+			return
+		}
+
+		if fills[winner.Hash()] != 1 || fills[loser.Hash()] != 0 {
+			t.Errorf("Left over unit should go to the order with the lowest tie-break hash")
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,82 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// ListenerConfig is how TCP listeners for RPC are set up. The zero value uses the OS and Go defaults.
+type ListenerConfig struct {
+	// Backlog is the most connections that can be waiting to be accepted. 0 means the OS default.
+	Backlog int
+	// KeepAlive is the TCP keep-alive period for accepted connections. 0 means the Go default, and a
+	// negative period turns keep-alive off.
+	KeepAlive time.Duration
+}
+
+// ListenTCP listens for TCP connections on addr, with the backlog and keep-alive from conf
+func ListenTCP(addr string, conf ListenerConfig) (listener net.Listener, err error) {
+	if conf.Backlog < 0 {
+		err = fmt.Errorf("Listen backlog cannot be negative")
+		return
+	}
+
+	var tcpAddr *net.TCPAddr
+	if tcpAddr, err = net.ResolveTCPAddr("tcp", addr); err != nil {
+		err = fmt.Errorf("Error resolving listen address: %s", err)
+		return
+	}
+
+	var tcpListener *net.TCPListener
+	if conf.Backlog == 0 {
+		if tcpListener, err = net.ListenTCP("tcp", tcpAddr); err != nil {
+			return
+		}
+	} else {
+		if tcpListener, err = listenTCPBacklog(tcpAddr, conf.Backlog); err != nil {
+			err = fmt.Errorf("Error listening with backlog %d: %s", conf.Backlog, err)
+			return
+		}
+	}
+
+	if conf.KeepAlive == 0 {
+		listener = tcpListener
+		return
+	}
+
+	listener = &keepAliveListener{
+		TCPListener: tcpListener,
+		period:      conf.KeepAlive,
+	}
+	return
+}
+
+// keepAliveListener sets the keep-alive of every connection it accepts
+type keepAliveListener struct {
+	*net.TCPListener
+	period time.Duration
+}
+
+// Accept accepts a connection and sets its keep-alive
+func (l *keepAliveListener) Accept() (conn net.Conn, err error) {
+	var tcpConn *net.TCPConn
+	if tcpConn, err = l.AcceptTCP(); err != nil {
+		return
+	}
+
+	if l.period < 0 {
+		err = tcpConn.SetKeepAlive(false)
+	} else if err = tcpConn.SetKeepAlive(true); err == nil {
+		err = tcpConn.SetKeepAlivePeriod(l.period)
+	}
+
+	if err != nil {
+		tcpConn.Close()
+		err = fmt.Errorf("Error setting keep-alive on accepted connection: %s", err)
+		return
+	}
+
+	conn = tcpConn
+	return
+}
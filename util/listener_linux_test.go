@@ -0,0 +1,129 @@
+package util
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// acceptedSockopt dials listener, and gets the int socket option level, opt on the connection it accepts
+func acceptedSockopt(listener net.Listener, level int, opt int) (value int, err error) {
+	var clientConn net.Conn
+	if clientConn, err = net.Dial("tcp", listener.Addr().String()); err != nil {
+		return
+	}
+	defer clientConn.Close()
+
+	var conn net.Conn
+	if conn, err = listener.Accept(); err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var rawConn syscall.RawConn
+	if rawConn, err = conn.(*net.TCPConn).SyscallConn(); err != nil {
+		return
+	}
+
+	var sockoptErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		value, sockoptErr = syscall.GetsockoptInt(int(fd), level, opt)
+	}); err != nil {
+		return
+	}
+
+	err = sockoptErr
+	return
+}
+
+func TestListenTCPKeepAlive(t *testing.T) {
+	var err error
+
+	var listener net.Listener
+	if listener, err = ListenTCP("127.0.0.1:0", ListenerConfig{KeepAlive: 42 * time.Second}); err != nil {
+		t.Errorf("Error listening with keep-alive: %s", err)
+		return
+	}
+	defer listener.Close()
+
+	var keepAlive int
+	if keepAlive, err = acceptedSockopt(listener, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE); err != nil {
+		t.Errorf("Error getting keep-alive of accepted connection: %s", err)
+		return
+	}
+
+	if keepAlive != 1 {
+		t.Errorf("Keep-alive should be on for accepted connections")
+		return
+	}
+
+	var keepIdle int
+	if keepIdle, err = acceptedSockopt(listener, syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE); err != nil {
+		t.Errorf("Error getting keep-alive period of accepted connection: %s", err)
+		return
+	}
+
+	if keepIdle != 42 {
+		t.Errorf("Keep-alive period of accepted connections should be 42s, got %ds", keepIdle)
+		return
+	}
+
+	// negative turns it off
+	var noKeepAliveListener net.Listener
+	if noKeepAliveListener, err = ListenTCP("127.0.0.1:0", ListenerConfig{KeepAlive: -1}); err != nil {
+		t.Errorf("Error listening without keep-alive: %s", err)
+		return
+	}
+	defer noKeepAliveListener.Close()
+
+	if keepAlive, err = acceptedSockopt(noKeepAliveListener, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE); err != nil {
+		t.Errorf("Error getting keep-alive of accepted connection: %s", err)
+		return
+	}
+
+	if keepAlive != 0 {
+		t.Errorf("Keep-alive should be off for accepted connections")
+		return
+	}
+
+	return
+}
+
+func TestListenTCPBacklog(t *testing.T) {
+	var err error
+
+	backlog := 2
+	var listener net.Listener
+	if listener, err = ListenTCP("127.0.0.1:0", ListenerConfig{Backlog: backlog}); err != nil {
+		t.Errorf("Error listening with backlog: %s", err)
+		return
+	}
+	defer listener.Close()
+
+	// Without accepting, linux queues backlog + 1 connections and then drops new ones, so dialing
+	// times out. With the default backlog of at least 128 none of these would time out.
+	var queued int
+	for ; queued < 64; queued++ {
+		var conn net.Conn
+		if conn, err = net.DialTimeout("tcp", listener.Addr().String(), 200*time.Millisecond); err != nil {
+			break
+		}
+		defer conn.Close()
+	}
+
+	if queued != backlog+1 {
+		t.Errorf("Listener with backlog %d should queue %d connections, queued %d", backlog, backlog+1, queued)
+		return
+	}
+
+	// the queued connections can still be accepted
+	var conn net.Conn
+	if conn, err = listener.Accept(); err != nil {
+		t.Errorf("Error accepting queued connection: %s", err)
+		return
+	}
+	conn.Close()
+
+	return
+}
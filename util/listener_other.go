@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package util
+
+import (
+	"fmt"
+	"net"
+)
+
+// listenTCPBacklog can't set the backlog on this platform, so it returns an error instead of silently
+// using the default
+func listenTCPBacklog(addr *net.TCPAddr, backlog int) (listener *net.TCPListener, err error) {
+	err = fmt.Errorf("Setting the listen backlog is not supported on this platform")
+	return
+}
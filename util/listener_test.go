@@ -0,0 +1,29 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestListenTCPDefaults(t *testing.T) {
+	var err error
+
+	if _, err = ListenTCP("127.0.0.1:0", ListenerConfig{Backlog: -1}); err == nil {
+		t.Errorf("Listening with a negative backlog should fail")
+		return
+	}
+
+	// the zero config should be a plain TCP listener
+	listener, err := ListenTCP("127.0.0.1:0", ListenerConfig{})
+	if err != nil {
+		t.Errorf("Error listening with the default config: %s", err)
+		return
+	}
+	defer listener.Close()
+
+	if _, ok := listener.(*keepAliveListener); ok {
+		t.Errorf("Listener with the default config should not change keep-alive")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,71 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package util
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenTCPBacklog listens on addr with the given backlog. The net package always uses the OS default
+// backlog, so the socket is set up by hand and then handed to the net package.
+func listenTCPBacklog(addr *net.TCPAddr, backlog int) (listener *net.TCPListener, err error) {
+	var family int
+	var sockaddr syscall.Sockaddr
+	if ip4 := addr.IP.To4(); addr.IP == nil || ip4 != nil {
+		family = syscall.AF_INET
+		inet4 := &syscall.SockaddrInet4{Port: addr.Port}
+		copy(inet4.Addr[:], ip4)
+		sockaddr = inet4
+	} else {
+		family = syscall.AF_INET6
+		inet6 := &syscall.SockaddrInet6{Port: addr.Port}
+		copy(inet6.Addr[:], addr.IP.To16())
+		sockaddr = inet6
+	}
+
+	var fd int
+	if fd, err = syscall.Socket(family, syscall.SOCK_STREAM, syscall.IPPROTO_TCP); err != nil {
+		err = fmt.Errorf("Error creating socket: %s", err)
+		return
+	}
+	syscall.CloseOnExec(fd)
+
+	// the file owns the socket from here on, and the net package makes its own copy
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("tcp:%s", addr))
+	defer file.Close()
+
+	// the same as the net package, so restarts don't have to wait for old connections to time out
+	if err = syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
+		err = fmt.Errorf("Error setting reuse address on socket: %s", err)
+		return
+	}
+
+	if err = syscall.Bind(fd, sockaddr); err != nil {
+		err = fmt.Errorf("Error binding socket to %s: %s", addr, err)
+		return
+	}
+
+	if err = syscall.Listen(fd, backlog); err != nil {
+		err = fmt.Errorf("Error listening on socket: %s", err)
+		return
+	}
+
+	var fileListener net.Listener
+	if fileListener, err = net.FileListener(file); err != nil {
+		err = fmt.Errorf("Error creating listener from socket: %s", err)
+		return
+	}
+
+	var ok bool
+	if listener, ok = fileListener.(*net.TCPListener); !ok {
+		fileListener.Close()
+		err = fmt.Errorf("Listener from socket is not a TCP listener")
+		return
+	}
+
+	return
+}
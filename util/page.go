@@ -0,0 +1,17 @@
+package util
+
+// PageBounds returns where the page of a list of total items that starts at offset, with at most limit items,
+// starts and ends, so the page is list[start:end]. A limit of zero means the rest of the list, and an offset
+// past the end of the list gives an empty page.
+func PageBounds(total int, offset uint64, limit uint64) (start int, end int) {
+	if total <= 0 || offset >= uint64(total) {
+		return
+	}
+
+	start = int(offset)
+	end = total
+	if limit != 0 && limit < uint64(total-start) {
+		end = start + int(limit)
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestPageBounds(t *testing.T) {
+	tests := []struct {
+		total  int
+		offset uint64
+		limit  uint64
+		start  int
+		end    int
+	}{
+		{total: 10, offset: 0, limit: 0, start: 0, end: 10},
+		{total: 10, offset: 0, limit: 3, start: 0, end: 3},
+		{total: 10, offset: 9, limit: 3, start: 9, end: 10},
+		{total: 10, offset: 4, limit: 0, start: 4, end: 10},
+		{total: 10, offset: 10, limit: 3, start: 0, end: 0},
+		{total: 10, offset: 1 << 63, limit: 1 << 63, start: 0, end: 0},
+		{total: 10, offset: 2, limit: 1 << 63, start: 2, end: 10},
+		{total: 0, offset: 0, limit: 5, start: 0, end: 0},
+	}
+
+	for _, test := range tests {
+		start, end := PageBounds(test.total, test.offset, test.limit)
+		if start != test.start || end != test.end {
+			t.Errorf("Page of %d items at offset %d with limit %d should be [%d:%d], got [%d:%d]", test.total, test.offset, test.limit, test.start, test.end, start, end)
+			return
+		}
+	}
+
+	return
+}